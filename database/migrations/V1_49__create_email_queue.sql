@@ -0,0 +1,51 @@
+-- Persistente outbox voor uitgaande emails
+CREATE TABLE IF NOT EXISTS email_queue (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel VARCHAR(20) NOT NULL DEFAULT 'default',
+    from_address VARCHAR(255),
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    email_type VARCHAR(100),
+    test_mode BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Partiële index voor het claimen van openstaande jobs
+CREATE INDEX IF NOT EXISTS idx_email_queue_pending ON email_queue(next_attempt_at)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_queue_status ON email_queue(status);
+CREATE INDEX IF NOT EXISTS idx_email_queue_recipient ON email_queue(recipient);
+
+-- Permissies voor het beheren van de email queue
+INSERT INTO permissions (resource, action, description, is_system_permission) VALUES
+('email_queue', 'read', 'Email queue en dead-letter items bekijken', true),
+('email_queue', 'write', 'Email queue items opnieuw in de wachtrij plaatsen', true)
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND r.is_system_role = true
+  AND p.resource = 'email_queue'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'staff' AND r.is_system_role = true
+  AND p.resource = 'email_queue' AND p.action = 'read'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.49.0', 'Create email queue table', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
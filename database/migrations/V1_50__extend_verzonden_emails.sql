@@ -0,0 +1,15 @@
+-- Breid verzonden_emails uit zodat elke uitgaande email kan worden vastgelegd
+ALTER TABLE verzonden_emails
+    ADD COLUMN IF NOT EXISTS email_type VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS template_naam VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_verzonden_emails_email_type ON verzonden_emails(email_type);
+
+-- Koppel queue items aan hun regel in verzonden_emails
+ALTER TABLE email_queue
+    ADD COLUMN IF NOT EXISTS verzonden_email_id UUID REFERENCES verzonden_emails(id) ON DELETE SET NULL;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.50.0', 'Extend verzonden_emails for outgoing email tracking', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
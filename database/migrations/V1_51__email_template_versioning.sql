@@ -0,0 +1,61 @@
+-- Migratie: V1_51__email_template_versioning.sql
+-- Beschrijving: Versiebeheer en RBAC voor email templates die vanuit de database worden beheerd
+-- Versie: 1.51.0
+
+ALTER TABLE email_templates ADD COLUMN IF NOT EXISTS versie INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE email_templates ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL;
+
+-- Historie van eerdere versies, één regel per wijziging
+CREATE TABLE IF NOT EXISTS email_template_versies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id UUID NOT NULL REFERENCES email_templates(id) ON DELETE CASCADE,
+    versie INTEGER NOT NULL,
+    onderwerp VARCHAR(255) NOT NULL,
+    inhoud TEXT NOT NULL,
+    beschrijving TEXT,
+    is_actief BOOLEAN NOT NULL DEFAULT FALSE,
+    gewijzigd_door UUID REFERENCES gebruikers(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (template_id, versie)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_template_versies_template_id ON email_template_versies(template_id);
+
+-- De seed templates uit 002 zijn placeholders. Nu database templates voorrang krijgen
+-- op de bestanden in templates/ worden ze eenmalig gedeactiveerd, zodat de bestaande
+-- emails ongewijzigd blijven totdat een beheerder bewust een template activeert.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM migraties WHERE versie = '1.51.0') THEN
+        UPDATE email_templates
+        SET is_actief = FALSE
+        WHERE naam IN ('contact_admin_email', 'contact_email', 'aanmelding_admin_email', 'aanmelding_email')
+          AND versie = 1;
+    END IF;
+END $$;
+
+-- Permissies voor het beheren van email templates
+INSERT INTO permissions (resource, action, description, is_system_permission) VALUES
+('email_template', 'read', 'Email templates en versies bekijken', true),
+('email_template', 'write', 'Email templates aanmaken, wijzigen en activeren', true),
+('email_template', 'delete', 'Email templates verwijderen', true)
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND r.is_system_role = true
+  AND p.resource = 'email_template'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'staff' AND r.is_system_role = true
+  AND p.resource = 'email_template' AND p.action = 'read'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.51.0', 'Email template versioning', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,29 @@
+-- Migratie: V1_52__incoming_email_attachments.sql
+-- Beschrijving: Bijlagen en tekstversie van inkomende e-mails opslaan
+-- Versie: 1.52.0
+
+-- Gedecodeerde onderwerpen en afzenders kunnen langer zijn dan 255 tekens
+ALTER TABLE incoming_emails ALTER COLUMN subject TYPE TEXT;
+ALTER TABLE incoming_emails ALTER COLUMN "from" TYPE TEXT;
+
+-- Platte tekst versie naast de (gesanitizede) HTML body
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS text_body TEXT;
+
+CREATE TABLE IF NOT EXISTS incoming_email_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email_id VARCHAR(255) NOT NULL REFERENCES incoming_emails(id) ON DELETE CASCADE,
+    filename VARCHAR(512) NOT NULL,
+    content_type VARCHAR(255),
+    content_id VARCHAR(512),
+    is_inline BOOLEAN NOT NULL DEFAULT FALSE,
+    size BIGINT NOT NULL DEFAULT 0,
+    data BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_email_attachments_email_id ON incoming_email_attachments(email_id);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.52.0', 'Create incoming email attachments table', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
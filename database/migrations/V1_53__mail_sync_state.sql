@@ -0,0 +1,32 @@
+-- Migratie: V1_53__mail_sync_state.sql
+-- Beschrijving: Persistente IMAP synchronisatiestatus per account en mailbox
+-- Versie: 1.53.0
+
+CREATE TABLE IF NOT EXISTS mail_sync_states (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_type VARCHAR(50) NOT NULL,
+    username VARCHAR(255),
+    mailbox VARCHAR(255) NOT NULL,
+    uid_validity BIGINT NOT NULL DEFAULT 0,
+    last_uid BIGINT NOT NULL DEFAULT 0,
+    last_sync_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_mail_sync_states_account_mailbox UNIQUE (account_type, mailbox)
+);
+
+-- Mailbox en UIDVALIDITY bij elke inkomende e-mail vastleggen
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS mailbox VARCHAR(255) NOT NULL DEFAULT 'INBOX';
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS uid_validity BIGINT NOT NULL DEFAULT 0;
+
+-- UIDs zijn alleen uniek binnen een mailbox van één account (en één UIDVALIDITY),
+-- dus de globale unieke index op uid wordt vervangen door een samengestelde index
+ALTER TABLE incoming_emails DROP CONSTRAINT IF EXISTS incoming_emails_uid_key;
+DROP INDEX IF EXISTS idx_incoming_emails_uid;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incoming_emails_account_mailbox_uid
+    ON incoming_emails(account_type, mailbox, uid_validity, uid);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.53.0', 'Create mail sync state table', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
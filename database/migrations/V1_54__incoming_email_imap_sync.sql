@@ -0,0 +1,13 @@
+-- Migratie: V1_54__incoming_email_imap_sync.sql
+-- Beschrijving: Bijhouden welke acties nog naar de mailserver moeten worden teruggeschreven
+-- Versie: 1.54.0
+
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS imap_sync_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_imap_sync_pending
+    ON incoming_emails(imap_sync_pending) WHERE imap_sync_pending = TRUE;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.54.0', 'Add IMAP sync pending flag to incoming emails', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,29 @@
+-- Migratie: V1_55__mail_threading.sql
+-- Beschrijving: Threading van inkomende emails en antwoorden vanuit het dashboard
+-- Versie: 1.55.0
+
+-- Threading headers voor inkomende emails
+ALTER TABLE incoming_emails
+    ADD COLUMN IF NOT EXISTS in_reply_to TEXT,
+    ADD COLUMN IF NOT EXISTS "references" TEXT,
+    ADD COLUMN IF NOT EXISTS thread_id TEXT;
+
+-- Bestaande emails vormen elk hun eigen gesprek
+UPDATE incoming_emails SET thread_id = message_id WHERE thread_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_thread_id ON incoming_emails(thread_id);
+
+-- Antwoorden en doorgestuurde emails vanuit het dashboard
+ALTER TABLE verzonden_emails
+    ADD COLUMN IF NOT EXISTS incoming_email_id VARCHAR(255) REFERENCES incoming_emails(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS message_id TEXT,
+    ADD COLUMN IF NOT EXISTS in_reply_to TEXT,
+    ADD COLUMN IF NOT EXISTS thread_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_verzonden_emails_incoming_email_id ON verzonden_emails(incoming_email_id);
+CREATE INDEX IF NOT EXISTS idx_verzonden_emails_thread_id ON verzonden_emails(thread_id);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.55.0', 'Add mail threading for replies from the dashboard', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,21 @@
+-- Migratie: V1_56__incoming_email_links.sql
+-- Beschrijving: Koppel inkomende emails aan aanmeldingen en contactformulieren
+-- Versie: 1.56.0
+
+ALTER TABLE incoming_emails
+    ADD COLUMN IF NOT EXISTS aanmelding_id UUID REFERENCES aanmeldingen(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS contact_id UUID REFERENCES contact_formulieren(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_aanmelding_id ON incoming_emails(aanmelding_id);
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_contact_id ON incoming_emails(contact_id);
+
+-- Extra headers (zoals Message-ID) van emails in de wachtrij
+ALTER TABLE email_queue ADD COLUMN IF NOT EXISTS headers TEXT;
+
+-- Opzoeken van verzonden emails op Message-ID bij het herkennen van antwoorden
+CREATE INDEX IF NOT EXISTS idx_verzonden_emails_message_id ON verzonden_emails(message_id);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.56.0', 'Link incoming emails to aanmeldingen and contact forms', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
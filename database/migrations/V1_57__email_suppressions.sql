@@ -0,0 +1,29 @@
+-- Migratie: V1_57__email_suppressions.sql
+-- Beschrijving: Bounce verwerking en suppressielijst voor onbestelbare adressen
+-- Versie: 1.57.0
+
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL,
+    bounce_type VARCHAR(20) NOT NULL,
+    status_code VARCHAR(20),
+    diagnostic_code TEXT,
+    bounce_count INTEGER NOT NULL DEFAULT 0,
+    actief BOOLEAN NOT NULL DEFAULT FALSE,
+    incoming_email_id VARCHAR(255) REFERENCES incoming_emails(id) ON DELETE SET NULL,
+    last_bounce_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Adressen worden altijd in kleine letters opgeslagen
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_suppressions_email ON email_suppressions(email);
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_actief ON email_suppressions(actief) WHERE actief = TRUE;
+
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS is_bounce BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_is_bounce ON incoming_emails(is_bounce);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.57.0', 'Add email suppression list for bounces', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
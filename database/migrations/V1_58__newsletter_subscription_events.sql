@@ -0,0 +1,22 @@
+-- Migratie: V1_58__newsletter_subscription_events.sql
+-- Beschrijving: Audit van nieuwsbrief aan- en afmeldingen
+-- Versie: 1.58.0
+
+CREATE TABLE IF NOT EXISTS newsletter_subscription_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    gebruiker_id UUID REFERENCES gebruikers(id) ON DELETE SET NULL,
+    email TEXT NOT NULL,
+    actie VARCHAR(20) NOT NULL,
+    bron VARCHAR(20) NOT NULL,
+    ip_adres VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_subscription_events_gebruiker_id ON newsletter_subscription_events(gebruiker_id);
+CREATE INDEX IF NOT EXISTS idx_newsletter_subscription_events_email ON newsletter_subscription_events(email);
+CREATE INDEX IF NOT EXISTS idx_newsletter_subscription_events_created_at ON newsletter_subscription_events(created_at DESC);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.58.0', 'Add newsletter subscription audit', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
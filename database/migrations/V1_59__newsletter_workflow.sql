@@ -0,0 +1,34 @@
+-- Migratie: V1_59__newsletter_workflow.sql
+-- Beschrijving: Statussen, goedkeuring en inplannen van nieuwsbrieven
+-- Versie: 1.59.0
+
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL;
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS approved_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL;
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS fout_bericht TEXT;
+
+-- Reeds verzonden nieuwsbrieven krijgen de status sent
+UPDATE newsletters SET status = 'sent' WHERE sent_at IS NOT NULL AND status = 'draft';
+
+CREATE INDEX IF NOT EXISTS idx_newsletters_status ON newsletters(status);
+-- De scheduler zoekt ingeplande nieuwsbrieven waarvan het tijdstip is verstreken
+CREATE INDEX IF NOT EXISTS idx_newsletters_scheduled ON newsletters(scheduled_at) WHERE status = 'scheduled';
+
+-- Goedkeuren is een aparte permissie, zodat schrijven en goedkeuren gescheiden kunnen worden
+INSERT INTO permissions (resource, action, description, is_system_permission) VALUES
+('newsletter', 'approve', 'Nieuwsbrieven goedkeuren en inplannen', true)
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND r.is_system_role = true
+  AND p.resource = 'newsletter' AND p.action = 'approve'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.59.0', 'Newsletter draft/approve/schedule workflow', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,27 @@
+-- Migratie: V1_60__newsletter_segments.sql
+-- Beschrijving: Opgeslagen doelgroepen (segmenten) voor de nieuwsbrief
+-- Versie: 1.60.0
+
+CREATE TABLE IF NOT EXISTS newsletter_segments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    naam TEXT NOT NULL,
+    beschrijving TEXT,
+    filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletter_segments_naam ON newsletter_segments(naam);
+
+-- Een nieuwsbrief kan aan een segment gericht zijn; zonder segment gaat hij naar alle subscribers
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS segment_id UUID REFERENCES newsletter_segments(id) ON DELETE SET NULL;
+
+-- Segmenten filteren op rol, afstand en jaar van aanmelding
+CREATE INDEX IF NOT EXISTS idx_aanmeldingen_rol_lower ON aanmeldingen(LOWER(rol));
+CREATE INDEX IF NOT EXISTS idx_aanmeldingen_afstand_lower ON aanmeldingen(LOWER(afstand));
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.60.0', 'Add newsletter segments', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,29 @@
+-- Migratie: V1_61__newsletter_deliveries.sql
+-- Beschrijving: Aflevering, opens en kliks van nieuwsbrieven per ontvanger
+-- Versie: 1.61.0
+
+CREATE TABLE IF NOT EXISTS newsletter_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    newsletter_id UUID NOT NULL REFERENCES newsletters(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    fout_bericht TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    bounced_at TIMESTAMP WITH TIME ZONE,
+    opened_at TIMESTAMP WITH TIME ZONE,
+    open_count INTEGER NOT NULL DEFAULT 0,
+    clicked_at TIMESTAMP WITH TIME ZONE,
+    click_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Een ontvanger krijgt een nieuwsbrief maar één keer
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletter_deliveries_newsletter_email ON newsletter_deliveries(newsletter_id, email);
+CREATE INDEX IF NOT EXISTS idx_newsletter_deliveries_email ON newsletter_deliveries(email, sent_at DESC);
+CREATE INDEX IF NOT EXISTS idx_newsletter_deliveries_status ON newsletter_deliveries(status);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.61.0', 'Add newsletter delivery tracking', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,26 @@
+-- Migratie: V1_62__newsletter_subscribers.sql
+-- Beschrijving: Nieuwsbrief abonnees zonder account met double opt-in en AVG toestemming
+-- Versie: 1.62.0
+
+CREATE TABLE IF NOT EXISTS newsletter_subscribers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email TEXT NOT NULL,
+    naam TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    consent_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consent_ip VARCHAR(64),
+    confirmation_sent_at TIMESTAMP WITH TIME ZONE,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    confirmed_ip VARCHAR(64),
+    unsubscribed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletter_subscribers_email ON newsletter_subscribers(email);
+CREATE INDEX IF NOT EXISTS idx_newsletter_subscribers_status ON newsletter_subscribers(status);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.62.0', 'Add newsletter subscribers with double opt-in', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
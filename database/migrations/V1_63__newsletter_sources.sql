@@ -0,0 +1,36 @@
+-- Migratie: V1_63__newsletter_sources.sql
+-- Beschrijving: Configureerbare bronnen voor de automatische nieuwsbrief en verzonden items (de-duplicatie)
+-- Versie: 1.63.0
+
+CREATE TABLE IF NOT EXISTS newsletter_sources (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    naam TEXT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    url TEXT,
+    categorie TEXT,
+    max_items INTEGER NOT NULL DEFAULT 10,
+    max_age_days INTEGER NOT NULL DEFAULT 7,
+    is_actief BOOLEAN NOT NULL DEFAULT TRUE,
+    last_fetched_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_sources_is_actief ON newsletter_sources(is_actief);
+
+CREATE TABLE IF NOT EXISTS newsletter_sent_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_key TEXT NOT NULL,
+    title TEXT,
+    link TEXT,
+    source TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletter_sent_items_item_key ON newsletter_sent_items(item_key);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.63.0', 'Add newsletter sources and sent items', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
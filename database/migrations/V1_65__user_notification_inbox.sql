@@ -0,0 +1,36 @@
+-- Migratie: V1_65__user_notification_inbox.sql
+-- Beschrijving: Persoonlijke inbox met notificaties per gebruiker en voorkeuren per type notificatie
+-- Versie: 1.65.0
+
+-- Persoonlijke notificaties hebben geen systeemnotificatie; de inhoud staat in de inbox zelf
+ALTER TABLE user_notifications ALTER COLUMN notification_id DROP NOT NULL;
+ALTER TABLE user_notifications ADD COLUMN IF NOT EXISTS type VARCHAR(50);
+ALTER TABLE user_notifications ADD COLUMN IF NOT EXISTS title VARCHAR(255);
+ALTER TABLE user_notifications ADD COLUMN IF NOT EXISTS message TEXT;
+ALTER TABLE user_notifications ADD COLUMN IF NOT EXISTS link TEXT;
+
+UPDATE user_notifications un
+SET type = n.type, title = n.title, message = n.message
+FROM notifications n
+WHERE un.notification_id = n.id AND un.title IS NULL;
+
+UPDATE user_notifications SET type = 'system' WHERE type IS NULL;
+UPDATE user_notifications SET title = '' WHERE title IS NULL;
+ALTER TABLE user_notifications ALTER COLUMN type SET NOT NULL;
+ALTER TABLE user_notifications ALTER COLUMN title SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_notifications_unread ON user_notifications(user_id, created_at DESC) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES gebruikers(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    push BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type)
+);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.65.0', 'Add personal notification inbox and notification preferences', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
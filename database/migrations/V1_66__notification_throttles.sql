@@ -0,0 +1,22 @@
+-- Migratie: V1_66__notification_throttles.sql
+-- Beschrijving: Persistente throttling van notificaties met digests van onderdrukte notificaties
+-- Versie: 1.66.0
+
+CREATE TABLE IF NOT EXISTS notification_throttles (
+    key VARCHAR(255) PRIMARY KEY,
+    window_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    type VARCHAR(50),
+    priority VARCHAR(20),
+    suppressed INTEGER NOT NULL DEFAULT 0,
+    titles JSONB NOT NULL DEFAULT '[]'::jsonb,
+    digest_due_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_throttles_digest_due_at ON notification_throttles(digest_due_at);
+CREATE INDEX IF NOT EXISTS idx_notification_throttles_window_end ON notification_throttles(window_end);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.66.0', 'Add persistent notification throttling', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
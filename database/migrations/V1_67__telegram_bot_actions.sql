@@ -0,0 +1,15 @@
+-- Migratie: V1_67__telegram_bot_actions.sql
+-- Beschrijving: Acties vanuit de Telegram bot op contactformulieren en aanmeldingen
+-- Versie: 1.67.0
+
+-- Gekoppeld Telegram account; acties in de bot gaan via de permissies van deze gebruiker
+ALTER TABLE gebruikers ADD COLUMN IF NOT EXISTS telegram_user_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_gebruikers_telegram_user_id ON gebruikers(telegram_user_id) WHERE telegram_user_id IS NOT NULL;
+
+-- Het contactformulier of de aanmelding waar een notificatie over gaat, voor de knoppen in Telegram
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS reference_id VARCHAR(255);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.67.0', 'Add Telegram bot actions', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,19 @@
+-- Migratie: V1_68__telegram_chats.sql
+-- Beschrijving: Register van Telegram chats met abonnementen op notificatie types en toegestane commando's
+-- Versie: 1.68.0
+
+CREATE TABLE IF NOT EXISTS telegram_chats (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chat_id BIGINT NOT NULL UNIQUE,
+    naam VARCHAR(255) NOT NULL,
+    subscriptions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    allowed_commands JSONB NOT NULL DEFAULT '[]'::jsonb,
+    is_actief BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.68.0', 'Add Telegram chat registry', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
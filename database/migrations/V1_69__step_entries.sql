@@ -0,0 +1,34 @@
+-- Migratie: V1_69__step_entries.sql
+-- Beschrijving: Stappen grootboek; het totaal in aanmeldingen.steps wordt afgeleid van de regels
+-- Versie: 1.69.0
+
+CREATE TABLE IF NOT EXISTS step_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    aanmelding_id UUID NOT NULL REFERENCES aanmeldingen(id) ON DELETE CASCADE,
+    delta INTEGER NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    entered_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL,
+    reason TEXT,
+    idempotency_key VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_step_entries_correction_reason CHECK (source <> 'correction' OR reason IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_step_entries_aanmelding_recorded ON step_entries(aanmelding_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_step_entries_recorded_at ON step_entries(recorded_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_step_entries_idempotency
+    ON step_entries(aanmelding_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
+
+-- Het bestaande totaal wordt de eerste regel van elke deelnemer
+INSERT INTO step_entries (aanmelding_id, delta, source, recorded_at, reason)
+SELECT id, steps, 'migratie', COALESCE(updated_at, created_at), 'Totaal van voor het stappen grootboek'
+FROM aanmeldingen
+WHERE steps <> 0
+  AND NOT EXISTS (SELECT 1 FROM step_entries se WHERE se.aanmelding_id = aanmeldingen.id);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.69.0', 'Add append-only step ledger', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,10 @@
+-- Migratie: V1_70__step_entry_metadata.sql
+-- Beschrijving: Herkomst van geïmporteerde activiteiten (GPX, TCX, FIT, CSV) in het stappen grootboek
+-- Versie: 1.70.0
+
+ALTER TABLE step_entries ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.70.0', 'Add activity metadata to step entries', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,33 @@
+-- Migratie: V1_71__step_teams_leaderboards.sql
+-- Beschrijving: Teams, klassementen en mijlpalen voor het stappen systeem
+-- Versie: 1.71.0
+
+CREATE TABLE IF NOT EXISTS step_teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    naam VARCHAR(255) NOT NULL UNIQUE,
+    type VARCHAR(30) NOT NULL DEFAULT 'organisatie',
+    beschrijving TEXT,
+    is_actief BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Een deelnemer hoort bij hoogstens één team en kiest zelf of zijn naam openbaar op het klassement staat
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS team_id UUID REFERENCES step_teams(id) ON DELETE SET NULL;
+ALTER TABLE aanmeldingen ADD COLUMN IF NOT EXISTS leaderboard_zichtbaar BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_aanmeldingen_team_id ON aanmeldingen(team_id);
+
+CREATE TABLE IF NOT EXISTS step_milestones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    aanmelding_id UUID NOT NULL REFERENCES aanmeldingen(id) ON DELETE CASCADE,
+    milestone INTEGER NOT NULL,
+    reached_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    email_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_step_milestones_aanmelding_milestone UNIQUE (aanmelding_id, milestone)
+);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.71.0', 'Add step teams, leaderboards and milestones', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,37 @@
+-- Migratie: V1_72__events_fundraising.sql
+-- Beschrijving: Edities (events) met eigen fondsenpot en verdeelstrategie; route_funds per editie
+-- Versie: 1.72.0
+
+CREATE TABLE IF NOT EXISTS events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    jaar INTEGER NOT NULL UNIQUE,
+    naam VARCHAR(255) NOT NULL,
+    total_funds INTEGER NOT NULL DEFAULT 0 CHECK (total_funds >= 0),
+    allocation_strategy VARCHAR(20) NOT NULL DEFAULT 'participants'
+        CHECK (allocation_strategy IN ('equal', 'participants', 'steps')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- De bestaande route bedragen horen bij de editie 2025; de pot is hun som, zoals de oude totalX
+INSERT INTO events (jaar, naam, total_funds, allocation_strategy)
+SELECT 2025, 'De Koninklijke Loop 2025', COALESCE(SUM(amount), 0), 'participants'
+FROM route_funds
+ON CONFLICT (jaar) DO NOTHING;
+
+ALTER TABLE route_funds ADD COLUMN IF NOT EXISTS event_id UUID REFERENCES events(id) ON DELETE CASCADE;
+
+UPDATE route_funds
+SET event_id = (SELECT id FROM events WHERE jaar = 2025)
+WHERE event_id IS NULL;
+
+ALTER TABLE route_funds ALTER COLUMN event_id SET NOT NULL;
+
+-- Een route is uniek per editie in plaats van globaal
+ALTER TABLE route_funds DROP CONSTRAINT IF EXISTS route_funds_route_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_funds_event_route ON route_funds(event_id, route);
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.72.0', 'Add events with fundraising pot and edition scoped route funds', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,53 @@
+-- Migratie: V1_73__pledges.sql
+-- Beschrijving: Toezeggingen van sponsors per deelnemer met betaalstatus
+-- Versie: 1.73.0
+
+CREATE TABLE IF NOT EXISTS pledges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    aanmelding_id UUID NOT NULL REFERENCES aanmeldingen(id) ON DELETE CASCADE,
+    sponsor_naam VARCHAR(255) NOT NULL,
+    sponsor_email VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('fixed', 'per_km', 'per_10k_steps')),
+    amount NUMERIC(10,2) NOT NULL CHECK (amount > 0),
+    max_amount NUMERIC(10,2) CHECK (max_amount IS NULL OR max_amount > 0),
+    bericht TEXT,
+    anoniem BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'cancelled')),
+    paid_amount NUMERIC(10,2),
+    paid_at TIMESTAMP WITH TIME ZONE,
+    payment_reference VARCHAR(255),
+    confirmation_sent_at TIMESTAMP WITH TIME ZONE,
+    finished_email_sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pledges_aanmelding_id ON pledges(aanmelding_id);
+CREATE INDEX IF NOT EXISTS idx_pledges_sponsor_email ON pledges(sponsor_email);
+CREATE INDEX IF NOT EXISTS idx_pledges_status ON pledges(status);
+
+-- Permissies voor het beheer van toezeggingen; 'sponsor' is al in gebruik voor de sponsorlogo's
+INSERT INTO permissions (resource, action, description, is_system_permission) VALUES
+('pledge', 'read', 'Sponsortoezeggingen bekijken', true),
+('pledge', 'write', 'Betaalstatus van sponsortoezeggingen bijwerken en eindmails versturen', true)
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND r.is_system_role = true
+  AND p.resource = 'pledge'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'staff' AND r.is_system_role = true
+  AND p.resource = 'pledge'
+  AND p.action = 'read'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.73.0', 'Add sponsoring pledges', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Migratie: V1_74__incoming_email_imap_delete_pending.sql
+-- Beschrijving: Emails die nog van de mailserver moeten worden verwijderd bewaren tot dat gelukt is
+-- Versie: 1.74.0
+
+ALTER TABLE incoming_emails ADD COLUMN IF NOT EXISTS imap_delete_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_incoming_emails_imap_delete_pending
+    ON incoming_emails(imap_delete_pending) WHERE imap_delete_pending = TRUE;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.74.0', 'Add IMAP delete pending flag to incoming emails', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
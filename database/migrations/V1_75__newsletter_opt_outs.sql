@@ -0,0 +1,32 @@
+-- Migratie: V1_75__newsletter_opt_outs.sql
+-- Beschrijving: Blijvende afmelding voor de nieuwsbrief op de suppressielijst, voor elk adres en elke doelgroep
+-- Versie: 1.75.0
+
+ALTER TABLE email_suppressions ADD COLUMN IF NOT EXISTS nieuwsbrief_afgemeld BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE email_suppressions ADD COLUMN IF NOT EXISTS afgemeld_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_nieuwsbrief_afgemeld
+    ON email_suppressions(nieuwsbrief_afgemeld) WHERE nieuwsbrief_afgemeld = TRUE;
+
+-- Bestaande afmeldingen van abonnees zonder account overnemen
+INSERT INTO email_suppressions (email, bounce_type, nieuwsbrief_afgemeld, afgemeld_at)
+SELECT LOWER(email), '', TRUE, COALESCE(unsubscribed_at, CURRENT_TIMESTAMP)
+FROM newsletter_subscribers
+WHERE status = 'unsubscribed'
+ON CONFLICT (email) DO UPDATE SET nieuwsbrief_afgemeld = TRUE, afgemeld_at = EXCLUDED.afgemeld_at;
+
+-- Adressen waarvan de laatste wijziging in de audit een afmelding is
+INSERT INTO email_suppressions (email, bounce_type, nieuwsbrief_afgemeld, afgemeld_at)
+SELECT email, '', TRUE, created_at
+FROM (
+    SELECT DISTINCT ON (LOWER(email)) LOWER(email) AS email, actie, created_at
+    FROM newsletter_subscription_events
+    ORDER BY LOWER(email), created_at DESC
+) laatste
+WHERE actie = 'afgemeld'
+ON CONFLICT (email) DO UPDATE SET nieuwsbrief_afgemeld = TRUE, afgemeld_at = EXCLUDED.afgemeld_at;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.75.0', 'Add durable newsletter opt-outs to email suppressions', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
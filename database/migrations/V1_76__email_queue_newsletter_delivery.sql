@@ -0,0 +1,14 @@
+-- Migratie: V1_76__email_queue_newsletter_delivery.sql
+-- Beschrijving: Koppel queue items aan de aflevering van een nieuwsbrief, zodat de aflevering pas na de definitieve verzending wordt bijgewerkt
+-- Versie: 1.76.0
+
+ALTER TABLE email_queue
+    ADD COLUMN IF NOT EXISTS newsletter_delivery_id UUID REFERENCES newsletter_deliveries(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_email_queue_newsletter_delivery_id
+    ON email_queue(newsletter_delivery_id) WHERE newsletter_delivery_id IS NOT NULL;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.76.0', 'Link email queue items to newsletter deliveries', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
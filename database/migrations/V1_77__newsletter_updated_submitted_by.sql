@@ -0,0 +1,11 @@
+-- Migratie: V1_77__newsletter_updated_submitted_by.sql
+-- Beschrijving: Leg de laatste bewerker en de indiener van een nieuwsbrief vast, zodat zij hem niet zelf kunnen goedkeuren
+-- Versie: 1.77.0
+
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL;
+ALTER TABLE newsletters ADD COLUMN IF NOT EXISTS submitted_by UUID REFERENCES gebruikers(id) ON DELETE SET NULL;
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.77.0', 'Track newsletter editor and submitter for four-eyes approval', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
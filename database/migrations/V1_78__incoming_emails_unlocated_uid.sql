@@ -0,0 +1,16 @@
+-- Migratie: V1_78__incoming_emails_unlocated_uid.sql
+-- Beschrijving: Een verplaatst bericht dat niet in de doelmap is teruggevonden krijgt een lege UID
+-- Versie: 1.78.0
+
+-- Eerder kregen deze berichten een placeholder die bij elke serveractie faalde
+UPDATE incoming_emails SET uid = '' WHERE uid LIKE 'verplaatst:%';
+
+-- Meerdere berichten zonder UID in dezelfde map mogen naast elkaar bestaan
+DROP INDEX IF EXISTS idx_incoming_emails_account_mailbox_uid;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incoming_emails_account_mailbox_uid
+    ON incoming_emails(account_type, mailbox, uid_validity, uid) WHERE uid <> '';
+
+-- Registreer de migratie
+INSERT INTO migraties (versie, naam, toegepast)
+VALUES ('1.78.0', 'Allow incoming emails without a server UID', CURRENT_TIMESTAMP)
+ON CONFLICT (versie) DO NOTHING;
@@ -18,6 +18,7 @@ type AanmeldingHandler struct {
 	emailService           *services.EmailService
 	authService            services.AuthService
 	permissionService      services.PermissionService
+	incomingEmailRepo      repository.IncomingEmailRepository
 }
 
 // NewAanmeldingHandler maakt een nieuwe aanmelding handler
@@ -37,6 +38,11 @@ func NewAanmeldingHandler(
 	}
 }
 
+// SetIncomingEmailRepository stelt de repository in waarmee gekoppelde inkomende emails worden opgehaald
+func (h *AanmeldingHandler) SetIncomingEmailRepository(repo repository.IncomingEmailRepository) {
+	h.incomingEmailRepo = repo
+}
+
 // RegisterRoutes registreert de routes voor aanmelding beheer
 func (h *AanmeldingHandler) RegisterRoutes(app *fiber.App) {
 	// Groep voor aanmelding beheer routes
@@ -108,7 +114,7 @@ func (h *AanmeldingHandler) ListAanmeldingen(c *fiber.Ctx) error {
 
 // GetAanmelding haalt een specifieke aanmelding op
 // @Summary Details van een specifieke aanmelding ophalen
-// @Description Haalt de details van een specifieke aanmelding op, inclusief antwoorden
+// @Description Haalt de details van een specifieke aanmelding op, inclusief antwoorden en gekoppelde inkomende emails
 // @Tags Aanmelding
 // @Accept json
 // @Produce json
@@ -166,6 +172,16 @@ func (h *AanmeldingHandler) GetAanmelding(c *fiber.Ctx) error {
 	// Voeg antwoorden toe aan aanmelding
 	aanmelding.Antwoorden = aanmeldingAntwoorden
 
+	// Voeg gekoppelde inkomende emails toe
+	if h.incomingEmailRepo != nil {
+		emails, err := h.incomingEmailRepo.FindByAanmeldingID(ctx, id)
+		if err != nil {
+			logger.Error("Fout bij ophalen gekoppelde emails", "error", err, "aanmelding_id", id)
+		} else {
+			aanmelding.InkomendeEmails = emails
+		}
+	}
+
 	// Stuur resultaat terug
 	return c.JSON(aanmelding)
 }
@@ -408,7 +424,7 @@ func (h *AanmeldingHandler) AddAanmeldingAntwoord(c *fiber.Ctx) error {
 
 	// Stuur e-mail met antwoord (in de achtergrond)
 	go func() {
-		if err := h.emailService.SendEmail(aanmelding.Email, "Antwoord op uw aanmelding", antwoordData.Tekst); err != nil {
+		if err := h.emailService.SendEmailWithMetadata(aanmelding.Email, "Antwoord op uw aanmelding", antwoordData.Tekst, services.EmailMetadata{Type: "aanmelding_antwoord", AanmeldingID: &aanmeldingID}); err != nil {
 			logger.Error("Fout bij verzenden antwoord e-mail", "error", err, "aanmelding_id", aanmeldingID)
 		} else {
 			// Update e-mail verzonden status
@@ -153,7 +153,7 @@ func (h *AdminMailHandler) HandleSendMail(c *fiber.Ctx) error {
 	var err error
 	if req.TemplateName != "" {
 		// Send using template, pass the validated 'from' address (can be empty)
-		err = h.emailService.SendTemplateEmail(req.To, req.Subject, req.TemplateName, req.TemplateVariables, actualFromAddress)
+		err = h.emailService.SendTemplateEmailWithMetadata(req.To, req.Subject, req.TemplateName, req.TemplateVariables, services.EmailMetadata{KeepSubject: req.Subject != ""}, actualFromAddress)
 	} else {
 		// Send using plain body, pass the validated 'from' address (can be empty)
 		err = h.emailService.SendEmail(req.To, req.Subject, req.Body, actualFromAddress)
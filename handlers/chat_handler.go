@@ -22,6 +22,7 @@ type ChatHandler struct {
 	permissionService services.PermissionService
 	imageService      *services.ImageService
 	hub               *services.Hub // global, if needed
+	inbox             *services.NotificationInbox
 	mutex             sync.Mutex
 	channelHubs       map[string]*services.Hub
 }
@@ -50,6 +51,11 @@ func (h *ChatHandler) getChannelHub(channelID string) *services.Hub {
 	return hub
 }
 
+// SetNotificationInbox enables inbox notifications, such as when someone replies to a message
+func (h *ChatHandler) SetNotificationInbox(inbox *services.NotificationInbox) {
+	h.inbox = inbox
+}
+
 // SetChannelHubCallback sets the callback for dynamic channel joining in WebSocket
 func (h *ChatHandler) SetChannelHubCallback() {
 	h.hub.GetChannelHub = h.getChannelHub
@@ -355,9 +361,46 @@ func (h *ChatHandler) handleTextMessage(c *fiber.Ctx, userID, channelID string)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+
+	if message.ReplyToID != nil && h.inbox != nil {
+		go h.notifyReply(message)
+	}
 	return c.JSON(message)
 }
 
+// notifyReply puts a notification in the inbox of the author of the message that was replied to
+func (h *ChatHandler) notifyReply(reply models.ChatMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	original, err := h.chatService.GetMessage(ctx, *reply.ReplyToID)
+	if err != nil || original == nil || original.UserID == "" || original.UserID == reply.UserID {
+		return
+	}
+	channel, err := h.chatService.GetChannel(ctx, reply.ChannelID)
+	if err != nil || channel == nil {
+		return
+	}
+
+	sender := "Iemand"
+	if user, err := h.authService.GetUser(ctx, reply.UserID); err == nil && user != nil {
+		sender = user.Naam
+	}
+
+	preview := []rune(reply.Content)
+	if len(preview) > 140 {
+		preview = append(preview[:139], '…')
+	}
+
+	title := fmt.Sprintf("%s reageerde in #%s", sender, channel.Name)
+	if channel.Type == "direct" {
+		title = fmt.Sprintf("%s reageerde op je bericht", sender)
+	}
+	if _, err := h.inbox.Notify(ctx, original.UserID, models.NotificationTypeChat, title, string(preview), ""); err != nil {
+		logger.Error("Failed to create reply notification", "error", err, "message_id", reply.ID)
+	}
+}
+
 // handleImageMessage handles image uploads in chat
 func (h *ChatHandler) handleImageMessage(c *fiber.Ctx, userID, channelID string) error {
 	// Check if image service is available
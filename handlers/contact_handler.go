@@ -20,6 +20,7 @@ type ContactHandler struct {
 	authService         services.AuthService
 	permissionService   services.PermissionService
 	notificationService services.NotificationService
+	incomingEmailRepo   repository.IncomingEmailRepository
 }
 
 // NewContactHandler maakt een nieuwe contact handler
@@ -41,6 +42,11 @@ func NewContactHandler(
 	}
 }
 
+// SetIncomingEmailRepository stelt de repository in waarmee gekoppelde inkomende emails worden opgehaald
+func (h *ContactHandler) SetIncomingEmailRepository(repo repository.IncomingEmailRepository) {
+	h.incomingEmailRepo = repo
+}
+
 // RegisterRoutes registreert de routes voor contact beheer
 func (h *ContactHandler) RegisterRoutes(app *fiber.App) {
 	// Groep voor contact beheer routes
@@ -112,7 +118,7 @@ func (h *ContactHandler) ListContactFormulieren(c *fiber.Ctx) error {
 
 // GetContactFormulier haalt een specifiek contactformulier op
 // @Summary Details van een specifiek contactformulier ophalen
-// @Description Haalt de details van een specifiek contactformulier op, inclusief antwoorden
+// @Description Haalt de details van een specifiek contactformulier op, inclusief antwoorden en gekoppelde inkomende emails
 // @Tags Contact
 // @Accept json
 // @Produce json
@@ -170,6 +176,16 @@ func (h *ContactHandler) GetContactFormulier(c *fiber.Ctx) error {
 	// Voeg antwoorden toe aan contactformulier
 	contact.Antwoorden = contactAntwoorden
 
+	// Voeg gekoppelde inkomende emails toe
+	if h.incomingEmailRepo != nil {
+		emails, err := h.incomingEmailRepo.FindByContactID(ctx, id)
+		if err != nil {
+			logger.Error("Fout bij ophalen gekoppelde emails", "error", err, "contact_id", id)
+		} else {
+			contact.InkomendeEmails = emails
+		}
+	}
+
 	// Stuur resultaat terug
 	return c.JSON(contact)
 }
@@ -422,7 +438,7 @@ func (h *ContactHandler) AddContactAntwoord(c *fiber.Ctx) error {
 	// Stuur e-mail met antwoord (in de achtergrond)
 	go func() {
 		// Gebruik de juiste methode voor het verzenden van e-mail
-		if err := h.emailService.SendEmail(contact.Email, "Antwoord op uw contactformulier", antwoordData.Tekst); err != nil {
+		if err := h.emailService.SendEmailWithMetadata(contact.Email, "Antwoord op uw contactformulier", antwoordData.Tekst, services.EmailMetadata{Type: "contact_antwoord", ContactID: &contactID}); err != nil {
 			logger.Error("Fout bij verzenden antwoord e-mail", "error", err, "contact_id", contactID)
 		} else {
 			// Update e-mail verzonden status
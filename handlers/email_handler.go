@@ -338,9 +338,10 @@ func (h *EmailHandler) HandleAanmeldingEmail(c *fiber.Ctx) error {
 
 	// Stuur email naar admin
 	adminEmailData := &models.AanmeldingEmailData{
-		ToAdmin:    true,
-		Aanmelding: &aanmelding,
-		AdminEmail: adminEmail,
+		ToAdmin:      true,
+		Aanmelding:   &aanmelding,
+		AdminEmail:   adminEmail,
+		AanmeldingID: nieuweAanmelding.ID,
 	}
 
 	// In testmodus sturen we geen echte emails
@@ -367,8 +368,9 @@ func (h *EmailHandler) HandleAanmeldingEmail(c *fiber.Ctx) error {
 
 	// Stuur bevestigingsemail naar gebruiker
 	userEmailData := &models.AanmeldingEmailData{
-		ToAdmin:    false,
-		Aanmelding: &aanmelding,
+		ToAdmin:      false,
+		Aanmelding:   &aanmelding,
+		AanmeldingID: nieuweAanmelding.ID,
 	}
 
 	// In testmodus sturen we geen echte emails
@@ -410,7 +412,7 @@ func (h *EmailHandler) HandleAanmeldingEmail(c *fiber.Ctx) error {
 	}
 
 	// Stuur een notificatie voor een nieuwe aanmelding
-	h.sendAanmeldingNotification(&aanmelding, testMode)
+	h.sendAanmeldingNotification(&aanmelding, nieuweAanmelding.ID, testMode)
 
 	// Return success
 	if testMode {
@@ -464,14 +466,26 @@ func (h *EmailHandler) sendContactNotification(contact *models.ContactFormulier,
 			"<b>Bericht:</b>\n" + contact.Bericht
 	}
 
-	// Maak een notificatie aan
-	_, err := h.notificationService.CreateNotification(
-		context.Background(),
-		models.NotificationTypeContact,
-		priority,
-		title,
-		message,
-	)
+	// Maak een notificatie aan; met een ID krijgt hij in Telegram knoppen om het formulier af te handelen
+	var err error
+	if contact.ID != "" {
+		_, err = h.notificationService.CreateReferencedNotification(
+			context.Background(),
+			models.NotificationTypeContact,
+			priority,
+			title,
+			message,
+			contact.ID,
+		)
+	} else {
+		_, err = h.notificationService.CreateNotification(
+			context.Background(),
+			models.NotificationTypeContact,
+			priority,
+			title,
+			message,
+		)
+	}
 
 	if err != nil {
 		logger.Error("Fout bij aanmaken contact notificatie",
@@ -482,7 +496,7 @@ func (h *EmailHandler) sendContactNotification(contact *models.ContactFormulier,
 }
 
 // Stuur een notificatie voor een nieuwe aanmelding
-func (h *EmailHandler) sendAanmeldingNotification(aanmelding *models.AanmeldingFormulier, isTestMode bool) {
+func (h *EmailHandler) sendAanmeldingNotification(aanmelding *models.AanmeldingFormulier, aanmeldingID string, isTestMode bool) {
 	// Skip als de notification service niet beschikbaar is of als we in test mode zijn
 	if h.notificationService == nil || isTestMode {
 		return
@@ -511,12 +525,13 @@ func (h *EmailHandler) sendAanmeldingNotification(aanmelding *models.AanmeldingF
 	}
 
 	// Maak een notificatie aan
-	_, err := h.notificationService.CreateNotification(
+	_, err := h.notificationService.CreateReferencedNotification(
 		context.Background(),
 		models.NotificationTypeAanmelding,
 		priority,
 		title,
 		message,
+		aanmeldingID,
 	)
 
 	if err != nil {
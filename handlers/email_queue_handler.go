@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EmailQueueHandler bevat handlers voor het inspecteren en beheren van de email outbox
+type EmailQueueHandler struct {
+	queueRepo         repository.EmailQueueRepository
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewEmailQueueHandler maakt een nieuwe email queue handler
+func NewEmailQueueHandler(
+	queueRepo repository.EmailQueueRepository,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *EmailQueueHandler {
+	return &EmailQueueHandler{
+		queueRepo:         queueRepo,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// RegisterRoutes registreert de email queue routes
+func (h *EmailQueueHandler) RegisterRoutes(app *fiber.App) {
+	queueGroup := app.Group("/api/admin/mail/queue", AuthMiddleware(h.authService))
+
+	readGroup := queueGroup.Group("", PermissionMiddleware(h.permissionService, "email_queue", "read"))
+	readGroup.Get("/", h.ListQueue)
+	readGroup.Get("/stats", h.GetQueueStats)
+	readGroup.Get("/dead", h.ListDeadLetters)
+	readGroup.Get("/:id", h.GetQueueItem)
+
+	writeGroup := queueGroup.Group("", PermissionMiddleware(h.permissionService, "email_queue", "write"))
+	writeGroup.Post("/:id/requeue", h.RequeueItem)
+}
+
+// ListQueue haalt queue items op, optioneel gefilterd op status
+// @Summary Lijst van email queue items
+// @Description Haalt een gepagineerde lijst van items uit de email outbox op
+// @Tags EmailQueue
+// @Produce json
+// @Param status query string false "Filter op status (pending, processing, sent, dead)"
+// @Param limit query int false "Aantal items (standaard 20)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {array} models.EmailQueueItem
+// @Router /api/admin/mail/queue [get]
+// @Security BearerAuth
+func (h *EmailQueueHandler) ListQueue(c *fiber.Ctx) error {
+	return h.listByStatus(c, c.Query("status"))
+}
+
+// ListDeadLetters haalt de definitief mislukte emails op
+// @Summary Lijst van dead-letter emails
+// @Description Haalt emails op die na het maximale aantal pogingen niet verzonden konden worden
+// @Tags EmailQueue
+// @Produce json
+// @Success 200 {array} models.EmailQueueItem
+// @Router /api/admin/mail/queue/dead [get]
+// @Security BearerAuth
+func (h *EmailQueueHandler) ListDeadLetters(c *fiber.Ctx) error {
+	return h.listByStatus(c, models.EmailQueueStatusDead)
+}
+
+func (h *EmailQueueHandler) listByStatus(c *fiber.Ctx, status string) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	switch status {
+	case "", models.EmailQueueStatusPending, models.EmailQueueStatusProcessing,
+		models.EmailQueueStatusSent, models.EmailQueueStatusDead:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige status",
+		})
+	}
+
+	items, err := h.queueRepo.List(c.Context(), status, limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen email queue", "error", err, "status", status)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon email queue niet ophalen",
+		})
+	}
+
+	return c.JSON(items)
+}
+
+// GetQueueStats geeft het aantal items per status terug
+// @Summary Email queue statistieken
+// @Description Geeft het aantal items per status in de email outbox terug
+// @Tags EmailQueue
+// @Produce json
+// @Success 200 {object} map[string]int64
+// @Router /api/admin/mail/queue/stats [get]
+// @Security BearerAuth
+func (h *EmailQueueHandler) GetQueueStats(c *fiber.Ctx) error {
+	counts, err := h.queueRepo.CountByStatus(c.Context())
+	if err != nil {
+		logger.Error("Fout bij ophalen email queue statistieken", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon statistieken niet ophalen",
+		})
+	}
+
+	return c.JSON(counts)
+}
+
+// GetQueueItem haalt één queue item op
+// @Summary Email queue item ophalen
+// @Tags EmailQueue
+// @Produce json
+// @Param id path string true "Queue item ID"
+// @Success 200 {object} models.EmailQueueItem
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/admin/mail/queue/{id} [get]
+// @Security BearerAuth
+func (h *EmailQueueHandler) GetQueueItem(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID is verplicht",
+		})
+	}
+
+	item, err := h.queueRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen email queue item", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon queue item niet ophalen",
+		})
+	}
+
+	if item == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Queue item niet gevonden",
+		})
+	}
+
+	return c.JSON(item)
+}
+
+// RequeueItem plaatst een mislukt of dead-letter item opnieuw in de wachtrij
+// @Summary Email opnieuw in de wachtrij plaatsen
+// @Description Reset het aantal pogingen en plant een directe nieuwe verzendpoging
+// @Tags EmailQueue
+// @Produce json
+// @Param id path string true "Queue item ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/admin/mail/queue/{id}/requeue [post]
+// @Security BearerAuth
+func (h *EmailQueueHandler) RequeueItem(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID is verplicht",
+		})
+	}
+
+	item, err := h.queueRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen email queue item", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon queue item niet ophalen",
+		})
+	}
+
+	if item == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Queue item niet gevonden",
+		})
+	}
+
+	if item.Status == models.EmailQueueStatusProcessing {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Queue item wordt momenteel verwerkt",
+		})
+	}
+
+	if item.Status == models.EmailQueueStatusSent {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Queue item is al verzonden",
+		})
+	}
+
+	if err := h.queueRepo.Requeue(c.Context(), id); err != nil {
+		logger.Error("Fout bij opnieuw in wachtrij plaatsen", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon queue item niet opnieuw in de wachtrij plaatsen",
+		})
+	}
+
+	userID, _ := c.Locals("userID").(string)
+	logger.Info("Email queue item opnieuw in wachtrij geplaatst",
+		"id", id,
+		"recipient", item.Recipient,
+		"previous_status", item.Status,
+		"user_id", userID)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Email opnieuw in de wachtrij geplaatst",
+	})
+}
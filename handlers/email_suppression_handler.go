@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EmailSuppressionHandler bevat handlers voor het beheren van de suppressielijst
+type EmailSuppressionHandler struct {
+	suppressionRepo   repository.EmailSuppressionRepository
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewEmailSuppressionHandler maakt een nieuwe email suppressie handler
+func NewEmailSuppressionHandler(
+	suppressionRepo repository.EmailSuppressionRepository,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *EmailSuppressionHandler {
+	return &EmailSuppressionHandler{
+		suppressionRepo:   suppressionRepo,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// RegisterRoutes registreert de suppressielijst routes
+func (h *EmailSuppressionHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/email-suppressions", AuthMiddleware(h.authService))
+
+	group.Get("/", PermissionMiddleware(h.permissionService, "email", "read"), h.ListSuppressions)
+	group.Delete("/:email", PermissionMiddleware(h.permissionService, "email", "delete"), h.DeleteSuppression)
+}
+
+// ListSuppressions haalt de adressen op die gebounced zijn
+// @Summary Lijst van gebouncede adressen
+// @Description Haalt de suppressielijst op met het type bounce, de statuscode en het aantal bounces per adres
+// @Tags EmailSuppressions
+// @Produce json
+// @Param actief query bool false "Alleen adressen waarnaar niet meer verzonden wordt"
+// @Param limit query int false "Aantal resultaten (standaard 20)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email-suppressions [get]
+// @Security BearerAuth
+func (h *EmailSuppressionHandler) ListSuppressions(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	suppressions, total, err := h.suppressionRepo.List(c.Context(), c.QueryBool("actief", false), limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen suppressielijst", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon suppressielijst niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"suppressions": suppressions,
+		"total":        total,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// DeleteSuppression haalt een adres van de suppressielijst zodat er weer naar verzonden wordt
+// @Summary Adres van suppressielijst halen
+// @Description Verwijdert de suppressie van een adres, bijvoorbeeld nadat de ontvanger de mailbox heeft hersteld. Een afmelding voor de nieuwsbrief blijft behouden.
+// @Tags EmailSuppressions
+// @Produce json
+// @Param email path string true "Emailadres"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/email-suppressions/{email} [delete]
+// @Security BearerAuth
+func (h *EmailSuppressionHandler) DeleteSuppression(c *fiber.Ctx) error {
+	email, err := url.PathUnescape(c.Params("email"))
+	if err != nil || email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Emailadres is verplicht",
+		})
+	}
+
+	existing, err := h.suppressionRepo.GetByEmail(c.Context(), email)
+	if err != nil {
+		logger.Error("Fout bij ophalen suppressie", "error", err, "email", email)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon suppressie niet ophalen",
+		})
+	}
+	if existing == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Adres staat niet op de suppressielijst",
+		})
+	}
+
+	// Een afmelding voor de nieuwsbrief blijft staan; alleen de bounce wordt opgeheven
+	if existing.NieuwsbriefAfgemeld {
+		existing.Actief = false
+		existing.BounceCount = 0
+		err = h.suppressionRepo.Save(c.Context(), existing)
+	} else {
+		err = h.suppressionRepo.Delete(c.Context(), email)
+	}
+	if err != nil {
+		logger.Error("Fout bij verwijderen suppressie", "error", err, "email", email)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon suppressie niet verwijderen",
+		})
+	}
+
+	logger.Info("Adres van suppressielijst gehaald", "email", email)
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Adres van suppressielijst gehaald",
+	})
+}
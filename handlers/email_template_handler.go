@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EmailTemplateHandler bevat handlers voor het beheren van email templates in de database
+type EmailTemplateHandler struct {
+	templateService   *services.EmailTemplateService
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewEmailTemplateHandler maakt een nieuwe email template handler
+func NewEmailTemplateHandler(
+	templateService *services.EmailTemplateService,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *EmailTemplateHandler {
+	return &EmailTemplateHandler{
+		templateService:   templateService,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// RegisterRoutes registreert de email template routes
+func (h *EmailTemplateHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/email-templates", AuthMiddleware(h.authService))
+
+	readGroup := group.Group("", PermissionMiddleware(h.permissionService, "email_template", "read"))
+	readGroup.Get("/", h.ListTemplates)
+	readGroup.Post("/preview", h.PreviewTemplate)
+	readGroup.Get("/:id", h.GetTemplate)
+	readGroup.Get("/:id/versies", h.ListVersies)
+	readGroup.Get("/:id/preview", h.PreviewStoredTemplate)
+
+	writeGroup := group.Group("", PermissionMiddleware(h.permissionService, "email_template", "write"))
+	writeGroup.Post("/", h.CreateTemplate)
+	writeGroup.Put("/:id", h.UpdateTemplate)
+	writeGroup.Post("/:id/activeren", h.ActivateTemplate)
+	writeGroup.Post("/:id/deactiveren", h.DeactivateTemplate)
+	writeGroup.Post("/:id/versies/:versie/herstellen", h.RestoreVersie)
+
+	deleteGroup := group.Group("", PermissionMiddleware(h.permissionService, "email_template", "delete"))
+	deleteGroup.Delete("/:id", h.DeleteTemplate)
+}
+
+// ListTemplates haalt een lijst van email templates op
+// @Summary Lijst van email templates
+// @Description Haalt de email templates uit de database op
+// @Tags EmailTemplates
+// @Produce json
+// @Param limit query int false "Aantal resultaten (standaard 50)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {array} models.EmailTemplate
+// @Router /api/email-templates [get]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) ListTemplates(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	templates, err := h.templateService.List(c.Context(), limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen email templates", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon email templates niet ophalen",
+		})
+	}
+
+	return c.JSON(templates)
+}
+
+// GetTemplate haalt een email template op
+// @Summary Email template ophalen
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/email-templates/{id} [get]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) GetTemplate(c *fiber.Ctx) error {
+	tmpl, err := h.templateService.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon email template niet ophalen")
+	}
+	return c.JSON(tmpl)
+}
+
+// CreateTemplate maakt een nieuw email template aan
+// @Summary Email template aanmaken
+// @Description Maakt een nieuw template aan, standaard inactief. Een actief template moet met voorbeelddata kunnen renderen; zijn onderwerp vervangt dat van de verzending.
+// @Tags EmailTemplates
+// @Accept json
+// @Produce json
+// @Param template body models.EmailTemplate true "Template"
+// @Success 201 {object} models.EmailTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/email-templates [post]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) CreateTemplate(c *fiber.Ctx) error {
+	var tmpl models.EmailTemplate
+	if err := c.BodyParser(&tmpl); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	if err := h.templateService.Create(c.Context(), &tmpl, c.Locals("userID").(string)); err != nil {
+		return h.handleServiceError(c, err, "Kon email template niet aanmaken")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(tmpl)
+}
+
+// UpdateTemplate wijzigt een email template en slaat de vorige versie op
+// @Summary Email template bijwerken
+// @Tags EmailTemplates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body services.EmailTemplateUpdate true "Wijzigingen"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/email-templates/{id} [put]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) UpdateTemplate(c *fiber.Ctx) error {
+	var update services.EmailTemplateUpdate
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	tmpl, err := h.templateService.Update(c.Context(), c.Params("id"), update, c.Locals("userID").(string))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon email template niet bijwerken")
+	}
+
+	return c.JSON(tmpl)
+}
+
+// ActivateTemplate activeert een email template na validatie met voorbeelddata
+// @Summary Email template activeren
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email-templates/{id}/activeren [post]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) ActivateTemplate(c *fiber.Ctx) error {
+	return h.setActief(c, true)
+}
+
+// DeactivateTemplate deactiveert een email template; het bestand in templates/ wordt dan weer gebruikt
+// @Summary Email template deactiveren
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.EmailTemplate
+// @Router /api/email-templates/{id}/deactiveren [post]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) DeactivateTemplate(c *fiber.Ctx) error {
+	return h.setActief(c, false)
+}
+
+func (h *EmailTemplateHandler) setActief(c *fiber.Ctx, actief bool) error {
+	tmpl, err := h.templateService.SetActief(c.Context(), c.Params("id"), actief, c.Locals("userID").(string))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon status van email template niet wijzigen")
+	}
+	return c.JSON(tmpl)
+}
+
+// DeleteTemplate verwijdert een email template
+// @Summary Email template verwijderen
+// @Tags EmailTemplates
+// @Param id path string true "Template ID"
+// @Success 204
+// @Router /api/email-templates/{id} [delete]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) DeleteTemplate(c *fiber.Ctx) error {
+	if err := h.templateService.Delete(c.Context(), c.Params("id")); err != nil {
+		return h.handleServiceError(c, err, "Kon email template niet verwijderen")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListVersies haalt de versiegeschiedenis van een email template op
+// @Summary Versies van een email template
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {array} models.EmailTemplateVersie
+// @Router /api/email-templates/{id}/versies [get]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) ListVersies(c *fiber.Ctx) error {
+	versies, err := h.templateService.ListVersies(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon versies niet ophalen")
+	}
+	return c.JSON(versies)
+}
+
+// RestoreVersie zet een eerdere versie terug als nieuwe versie
+// @Summary Versie van een email template herstellen
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param versie path int true "Versienummer"
+// @Success 200 {object} models.EmailTemplate
+// @Router /api/email-templates/{id}/versies/{versie}/herstellen [post]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) RestoreVersie(c *fiber.Ctx) error {
+	versie, err := strconv.Atoi(c.Params("versie"))
+	if err != nil || versie < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig versienummer",
+		})
+	}
+
+	tmpl, err := h.templateService.RestoreVersie(c.Context(), c.Params("id"), versie, c.Locals("userID").(string))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon versie niet herstellen")
+	}
+	return c.JSON(tmpl)
+}
+
+// PreviewTemplate rendert niet-opgeslagen template inhoud met voorbeelddata
+// @Summary Preview van email template inhoud
+// @Description Rendert onderwerp en inhoud met voorbeelddata die past bij de template naam (aanmelding, contact, wfc_order, newsletter)
+// @Tags EmailTemplates
+// @Accept json
+// @Produce json
+// @Success 200 {object} services.EmailTemplatePreview
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/email-templates/preview [post]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) PreviewTemplate(c *fiber.Ctx) error {
+	var req struct {
+		Naam      string `json:"naam"`
+		Onderwerp string `json:"onderwerp"`
+		Inhoud    string `json:"inhoud"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	if req.Naam == "" || req.Inhoud == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Naam en inhoud zijn verplicht",
+		})
+	}
+
+	preview, err := h.templateService.Preview(req.Naam, req.Onderwerp, req.Inhoud)
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon preview niet genereren")
+	}
+	return c.JSON(preview)
+}
+
+// PreviewStoredTemplate rendert een opgeslagen template met voorbeelddata
+// @Summary Preview van een opgeslagen email template
+// @Tags EmailTemplates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} services.EmailTemplatePreview
+// @Router /api/email-templates/{id}/preview [get]
+// @Security BearerAuth
+func (h *EmailTemplateHandler) PreviewStoredTemplate(c *fiber.Ctx) error {
+	tmpl, err := h.templateService.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon email template niet ophalen")
+	}
+
+	preview, err := h.templateService.Preview(tmpl.Naam, tmpl.Onderwerp, tmpl.Inhoud)
+	if err != nil {
+		return h.handleServiceError(c, err, "Kon preview niet genereren")
+	}
+	return c.JSON(preview)
+}
+
+// handleServiceError vertaalt fouten van de template service naar een HTTP response
+func (h *EmailTemplateHandler) handleServiceError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Email template niet gevonden",
+		})
+	case errors.Is(err, services.ErrTemplateExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrTemplateInvalid):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	logger.Error(message, "error", err, "id", c.Params("id"))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"dklautomationgo/models"
 	"dklautomationgo/repository"
 	"dklautomationgo/services"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
 	"strings"
@@ -24,6 +26,7 @@ type MailResponse struct {
 	To          string     `json:"to"`
 	Subject     string     `json:"subject"`
 	HTML        string     `json:"html"` // Gedecodeerde/gesanitized HTML body
+	Text        string     `json:"text,omitempty"`
 	ContentType string     `json:"content_type"`
 	ReceivedAt  time.Time  `json:"received_at"`
 	UID         string     `json:"uid"`
@@ -32,6 +35,8 @@ type MailResponse struct {
 	ProcessedAt *time.Time `json:"processed_at"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Attachments []*models.IncomingEmailAttachment `json:"attachments,omitempty"`
 }
 
 // Helper functie om IncomingEmail naar MailResponse te mappen
@@ -46,6 +51,7 @@ func mapEmailToResponse(email *models.IncomingEmail) *MailResponse {
 		To:          email.To,
 		Subject:     email.Subject,
 		HTML:        email.Body, // Map Body -> HTML
+		Text:        email.TextBody,
 		ContentType: email.ContentType,
 		ReceivedAt:  email.ReceivedAt,
 		UID:         email.UID,
@@ -77,6 +83,8 @@ type PaginatedMailResponse struct {
 type MailHandler struct {
 	mailFetcher       *services.MailFetcher
 	incomingEmailRepo repository.IncomingEmailRepository
+	attachmentRepo    repository.IncomingEmailAttachmentRepository
+	replyService      *services.MailReplyService
 	authService       services.AuthService
 	permissionService services.PermissionService
 	lastRun           time.Time
@@ -86,12 +94,16 @@ type MailHandler struct {
 func NewMailHandler(
 	mailFetcher *services.MailFetcher,
 	incomingEmailRepo repository.IncomingEmailRepository,
+	attachmentRepo repository.IncomingEmailAttachmentRepository,
+	replyService *services.MailReplyService,
 	authService services.AuthService,
 	permissionService services.PermissionService,
 ) *MailHandler {
 	return &MailHandler{
 		mailFetcher:       mailFetcher,
 		incomingEmailRepo: incomingEmailRepo,
+		attachmentRepo:    attachmentRepo,
+		replyService:      replyService,
 		authService:       authService,
 		permissionService: permissionService,
 		lastRun:           time.Now().Add(-24 * time.Hour),
@@ -173,7 +185,13 @@ func (h *MailHandler) RegisterRoutes(app *fiber.App) {
 
 	// Mail beheer routes
 	mailGroup.Get("/", h.ListEmails)
+	mailGroup.Get("/sync-state", h.GetSyncState)
 	mailGroup.Get("/:id", h.GetEmail)
+	mailGroup.Get("/:id/attachments", h.ListAttachments)
+	mailGroup.Get("/:id/attachments/:attachmentId", h.DownloadAttachment)
+	mailGroup.Get("/:id/thread", h.GetThread)
+	mailGroup.Post("/:id/reply", h.ReplyToEmail)
+	mailGroup.Post("/:id/forward", h.ForwardEmail)
 	mailGroup.Put("/:id/processed", h.MarkAsProcessed)
 	mailGroup.Delete("/:id", h.DeleteEmail)
 	mailGroup.Post("/fetch", h.FetchEmails)
@@ -260,12 +278,95 @@ func (h *MailHandler) GetEmail(c *fiber.Ctx) error {
 	}
 
 	response := mapEmailToResponse(email)
+	if h.attachmentRepo != nil {
+		attachments, err := h.attachmentRepo.ListByEmailID(ctx, id)
+		if err != nil {
+			logger.Warn("Kon bijlagen niet ophalen", "error", err, "id", id)
+		}
+		response.Attachments = attachments
+	}
+
 	// Log the response just before sending
 	logger.Info("GetEmail response wordt verzonden", "email_id", id, "response_html_preview", getFirstNChars(response.HTML, 100)) // Log first 100 chars of HTML
 
 	return c.JSON(response)
 }
 
+// ListAttachments haalt de bijlagen van een email op
+// @Summary Bijlagen van een email ophalen
+// @Description Haalt de metadata van alle bijlagen van een email op (zonder inhoud)
+// @Tags Mail
+// @Produce json
+// @Param id path string true "Email ID"
+// @Success 200 {array} models.IncomingEmailAttachment
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/mail/{id}/attachments [get]
+// @Security BearerAuth
+func (h *MailHandler) ListAttachments(c *fiber.Ctx) error {
+	id := c.Params("id")
+	ctx := c.Context()
+
+	email, err := h.incomingEmailRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mail", "error", err, "id", id)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon email niet ophalen"})
+	}
+	if email == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "E-mail niet gevonden"})
+	}
+
+	attachments, err := h.attachmentRepo.ListByEmailID(ctx, id)
+	if err != nil {
+		logger.Error("Fout bij ophalen bijlagen", "error", err, "id", id)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon bijlagen niet ophalen"})
+	}
+
+	return c.JSON(attachments)
+}
+
+// DownloadAttachment downloadt een bijlage van een email
+// @Summary Bijlage downloaden
+// @Description Geeft de inhoud van een bijlage terug met het originele content type
+// @Tags Mail
+// @Produce octet-stream
+// @Param id path string true "Email ID"
+// @Param attachmentId path string true "Bijlage ID"
+// @Param inline query bool false "Toon de bijlage in de browser in plaats van te downloaden"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/mail/{id}/attachments/{attachmentId} [get]
+// @Security BearerAuth
+func (h *MailHandler) DownloadAttachment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	attachmentID := c.Params("attachmentId")
+
+	attachment, err := h.attachmentRepo.GetByID(c.Context(), id, attachmentID)
+	if err != nil {
+		logger.Error("Fout bij ophalen bijlage", "error", err, "id", id, "attachment_id", attachmentID)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon bijlage niet ophalen"})
+	}
+	if attachment == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Bijlage niet gevonden"})
+	}
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if c.QueryBool("inline", false) {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.Send(attachment.Data)
+}
+
 // MarkAsProcessed markeert een email als verwerkt
 // @Summary Email als verwerkt markeren
 // @Description Markeert een email als verwerkt om aan te geven dat deze is afgehandeld
@@ -301,6 +402,17 @@ func (h *MailHandler) MarkAsProcessed(c *fiber.Ctx) error {
 	email.IsProcessed = true
 	email.ProcessedAt = &now
 
+	// Zet \Seen en verplaats het bericht op de mailserver; lukt dat niet, dan probeert
+	// de auto fetcher het later opnieuw
+	if h.mailFetcher != nil {
+		if err := h.mailFetcher.MarkProcessedOnServer(email); err != nil {
+			logger.Warn("Kon verwerkte email niet naar mailserver terugschrijven", "error", err, "id", id)
+			email.ImapSyncPending = true
+		} else {
+			email.ImapSyncPending = false
+		}
+	}
+
 	err = h.incomingEmailRepo.Update(ctx, email)
 	if err != nil {
 		logger.Error("Fout bij markeren email als verwerkt", "error", err, "id", id)
@@ -311,14 +423,126 @@ func (h *MailHandler) MarkAsProcessed(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": fmt.Sprintf("Email %s gemarkeerd als verwerkt", id)})
 }
 
+// MailReplyRequest is het verzoek om een inkomende email te beantwoorden of door te sturen
+type MailReplyRequest struct {
+	Body string `json:"body"`
+	To   string `json:"to,omitempty"` // Alleen voor doorsturen
+}
+
+// ReplyToEmail beantwoordt een inkomende email
+// @Summary Email beantwoorden
+// @Description Verstuurt een antwoord via het account waarop de email binnenkwam, met In-Reply-To/References en het geciteerde origineel
+// @Tags Mail
+// @Accept json
+// @Produce json
+// @Param id path string true "Email ID"
+// @Param request body MailReplyRequest true "Tekst van het antwoord"
+// @Success 201 {object} models.VerzondEmail
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/mail/{id}/reply [post]
+// @Security BearerAuth
+func (h *MailHandler) ReplyToEmail(c *fiber.Ctx) error {
+	var req MailReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Ongeldig verzoek"})
+	}
+
+	sent, err := h.replyService.Reply(c.Context(), c.Params("id"), req.Body)
+	if err != nil {
+		return h.handleReplyError(c, err, sent)
+	}
+
+	return c.Status(http.StatusCreated).JSON(sent)
+}
+
+// ForwardEmail stuurt een inkomende email door
+// @Summary Email doorsturen
+// @Description Stuurt een inkomende email door naar een ander adres via het account waarop de email binnenkwam
+// @Tags Mail
+// @Accept json
+// @Produce json
+// @Param id path string true "Email ID"
+// @Param request body MailReplyRequest true "Ontvanger en optionele begeleidende tekst"
+// @Success 201 {object} models.VerzondEmail
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/mail/{id}/forward [post]
+// @Security BearerAuth
+func (h *MailHandler) ForwardEmail(c *fiber.Ctx) error {
+	var req MailReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Ongeldig verzoek"})
+	}
+	if req.To == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Ontvanger is verplicht"})
+	}
+
+	sent, err := h.replyService.Forward(c.Context(), c.Params("id"), req.To, req.Body)
+	if err != nil {
+		return h.handleReplyError(c, err, sent)
+	}
+
+	return c.Status(http.StatusCreated).JSON(sent)
+}
+
+// GetThread haalt het gesprek op waar een email bij hoort
+// @Summary Gesprek ophalen
+// @Description Haalt alle inkomende emails en verstuurde antwoorden van een gesprek op, gegroepeerd op References
+// @Tags Mail
+// @Produce json
+// @Param id path string true "Email ID"
+// @Success 200 {object} services.MailThread
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/mail/{id}/thread [get]
+// @Security BearerAuth
+func (h *MailHandler) GetThread(c *fiber.Ctx) error {
+	thread, err := h.replyService.Thread(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleReplyError(c, err, nil)
+	}
+
+	return c.JSON(thread)
+}
+
+// handleReplyError vertaalt fouten van de reply service naar een HTTP response
+func (h *MailHandler) handleReplyError(c *fiber.Ctx, err error, sent *models.VerzondEmail) error {
+	switch {
+	case errors.Is(err, services.ErrMailNotFound):
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Email niet gevonden"})
+	case errors.Is(err, services.ErrInvalidRecipient), errors.Is(err, services.ErrEmptyReply):
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrRecipientSuppressed):
+		return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{"error": services.ErrRecipientSuppressed.Error(), "verzonden_email": sent})
+	case sent != nil:
+		// De email is vastgelegd als mislukt; geef de regel terug zodat de fout zichtbaar is
+		return c.Status(http.StatusBadGateway).JSON(fiber.Map{"error": "Versturen mislukt", "verzonden_email": sent})
+	default:
+		logger.Error("Fout bij verwerken van antwoord", "error", err, "id", c.Params("id"))
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon verzoek niet verwerken"})
+	}
+}
+
 // DeleteEmail verwijdert een email
 // @Summary Email verwijderen
-// @Description Verwijdert een email uit het systeem
+// @Description Verwijdert een email uit het systeem en van de mailserver (of verplaatst deze naar de prullenbak). Is de mailserver niet bereikbaar, dan wordt de email verborgen en later opnieuw verwijderd (202).
 // @Tags Mail
 // @Accept json
 // @Produce json
 // @Param id path string true "Email ID"
 // @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
@@ -333,6 +557,30 @@ func (h *MailHandler) DeleteEmail(c *fiber.Ctx) error {
 	}
 
 	ctx := c.Context()
+	email, err := h.incomingEmailRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Fout bij ophalen email voor verwijderen", "error", err, "id", id)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon email niet ophalen"})
+	}
+	if email == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Email niet gevonden"})
+	}
+
+	// Lukt verwijderen op de mailserver niet, dan blijft de regel gemarkeerd staan en
+	// probeert de reconciliatie het later opnieuw; anders zou de mail bij een volledige
+	// synchronisatie gewoon terugkomen
+	if h.mailFetcher != nil {
+		if err := h.mailFetcher.DeleteOnServer(email); err != nil {
+			logger.Warn("Kon email niet van mailserver verwijderen, later opnieuw proberen", "error", err, "id", id)
+			email.ImapDeletePending = true
+			if err := h.incomingEmailRepo.Update(ctx, email); err != nil {
+				logger.Error("Fout bij markeren email voor verwijderen", "error", err, "id", id)
+				return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon email niet verwijderen"})
+			}
+			return c.Status(http.StatusAccepted).JSON(fiber.Map{"success": true, "message": "Email wordt verwijderd zodra de mailserver bereikbaar is"})
+		}
+	}
+
 	if err := h.incomingEmailRepo.Delete(ctx, id); err != nil {
 		logger.Error("Fout bij verwijderen email", "error", err, "id", id)
 		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon email niet verwijderen"})
@@ -354,22 +602,18 @@ func (h *MailHandler) DeleteEmail(c *fiber.Ctx) error {
 // @Router /api/mail/fetch [post]
 // @Security BearerAuth
 func (h *MailHandler) FetchEmails(c *fiber.Ctx) error {
-	// Haal nieuwe emails op
-	emails, err := h.mailFetcher.FetchMails()
-	if err != nil {
-		logger.Error("Fout bij handmatig ophalen mails", "error", err)
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Fout bij ophalen emails: " + err.Error()})
-	}
-
-	// Sla opgehaalde emails op in de database
-	var savedCount int
-	for _, email := range emails {
-		if err := h.incomingEmailRepo.Create(c.Context(), email); err != nil {
-			logger.Error("Fout bij opslaan opgehaalde email", "error", err, "messageID", email.MessageID)
-			// Ga door met de volgende email
-		} else {
-			savedCount++
-		}
+	// Haal nieuwe emails op; bij een fout in één folder komen de overige emails toch mee
+	emails, fetchErr := h.mailFetcher.FetchMails()
+
+	// Sla opgehaalde emails op in de database; de synchronisatiestatus schuift pas na het opslaan op
+	savedCount := h.mailFetcher.StoreFetchedEmails(c.Context(), h.incomingEmailRepo, emails)
+
+	if fetchErr != nil {
+		logger.Error("Fout bij handmatig ophalen mails", "error", fetchErr, "saved", savedCount)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Fout bij ophalen emails: " + fetchErr.Error(),
+			"saved": savedCount,
+		})
 	}
 
 	return c.JSON(fiber.Map{
@@ -378,6 +622,24 @@ func (h *MailHandler) FetchEmails(c *fiber.Ctx) error {
 	})
 }
 
+// GetSyncState haalt de IMAP synchronisatiestatus per account en mailbox op
+// @Summary IMAP synchronisatiestatus ophalen
+// @Description Geeft per account en mailbox de UIDVALIDITY, laatst opgehaalde UID en laatste synchronisatie terug
+// @Tags Mail
+// @Produce json
+// @Success 200 {array} models.MailSyncState
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/mail/sync-state [get]
+// @Security BearerAuth
+func (h *MailHandler) GetSyncState(c *fiber.Ctx) error {
+	states, err := h.mailFetcher.GetSyncStates(c.Context())
+	if err != nil {
+		logger.Error("Fout bij ophalen synchronisatiestatus", "error", err)
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Kon synchronisatiestatus niet ophalen"})
+	}
+	return c.JSON(states)
+}
+
 // ListUnprocessedEmails haalt alle onverwerkte emails op
 // @Summary Onverwerkte emails ophalen
 // @Description Haalt een lijst van alle onverwerkte emails op
@@ -42,6 +42,7 @@ func (h *MetricsHandler) HandleGetEmailMetrics(c *fiber.Ctx) error {
 		"total_emails":   total,
 		"success_rate":   successRate,
 		"emails_by_type": byType,
+		"bounces":        h.emailMetrics.GetBounceCounts(),
 		"generated_at":   time.Now(),
 	})
 }
@@ -85,6 +86,7 @@ func (h *MetricsHandler) GetEmailMetrics(w http.ResponseWriter, r *http.Request)
 		"total_emails":   h.emailMetrics.GetTotalEmails(),
 		"success_rate":   h.emailMetrics.GetSuccessRate(),
 		"emails_by_type": h.emailMetrics.GetEmailsByType(),
+		"bounces":        h.emailMetrics.GetBounceCounts(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
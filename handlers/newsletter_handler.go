@@ -5,6 +5,8 @@ import (
 	"dklautomationgo/models"
 	"dklautomationgo/repository"
 	"dklautomationgo/services"
+	"errors"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,6 +15,7 @@ import (
 type NewsletterHandler struct {
 	newsletterRepo    repository.NewsletterRepository
 	newsletterSvc     *services.NewsletterSender
+	workflow          *services.NewsletterWorkflow
 	authService       services.AuthService
 	permissionService services.PermissionService
 }
@@ -21,12 +24,14 @@ type NewsletterHandler struct {
 func NewNewsletterHandler(
 	newsletterRepo repository.NewsletterRepository,
 	newsletterSvc *services.NewsletterSender,
+	workflow *services.NewsletterWorkflow,
 	authService services.AuthService,
 	permissionService services.PermissionService,
 ) *NewsletterHandler {
 	return &NewsletterHandler{
 		newsletterRepo:    newsletterRepo,
 		newsletterSvc:     newsletterSvc,
+		workflow:          workflow,
 		authService:       authService,
 		permissionService: permissionService,
 	}
@@ -47,6 +52,15 @@ func (h *NewsletterHandler) RegisterRoutes(app *fiber.App) {
 	writeGroup := newsletterGroup.Group("", PermissionMiddleware(h.permissionService, "newsletter", "write"))
 	writeGroup.Post("/", h.CreateNewsletter)
 	writeGroup.Put("/:id", h.UpdateNewsletter)
+	writeGroup.Post("/:id/submit", h.SubmitNewsletter)
+	writeGroup.Post("/:id/test-send", h.TestSendNewsletter)
+
+	// Workflow routes (require newsletter approve): goedkeuren en inplannen door een tweede persoon
+	approveGroup := newsletterGroup.Group("", PermissionMiddleware(h.permissionService, "newsletter", "approve"))
+	approveGroup.Post("/:id/approve", h.ApproveNewsletter)
+	approveGroup.Post("/:id/reject", h.RejectNewsletter)
+	approveGroup.Post("/:id/schedule", h.ScheduleNewsletter)
+	approveGroup.Post("/:id/unschedule", h.UnscheduleNewsletter)
 
 	// Delete routes (require newsletter delete)
 	deleteGroup := newsletterGroup.Group("", PermissionMiddleware(h.permissionService, "newsletter", "delete"))
@@ -89,8 +103,9 @@ func (h *NewsletterHandler) ListNewsletters(c *fiber.Ctx) error {
 // CreateNewsletter maakt een nieuwe nieuwsbrief aan
 func (h *NewsletterHandler) CreateNewsletter(c *fiber.Ctx) error {
 	var req struct {
-		Subject string `json:"subject"`
-		Content string `json:"content"`
+		Subject   string `json:"subject"`
+		Content   string `json:"content"`
+		SegmentID string `json:"segment_id"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -108,6 +123,13 @@ func (h *NewsletterHandler) CreateNewsletter(c *fiber.Ctx) error {
 	nl := &models.Newsletter{
 		Subject: req.Subject,
 		Content: req.Content,
+		Status:  models.NewsletterStatusDraft,
+	}
+	if req.SegmentID != "" {
+		nl.SegmentID = &req.SegmentID
+	}
+	if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+		nl.CreatedBy = &userID
 	}
 
 	ctx := c.Context()
@@ -158,8 +180,9 @@ func (h *NewsletterHandler) UpdateNewsletter(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Subject string `json:"subject"`
-		Content string `json:"content"`
+		Subject   string  `json:"subject"`
+		Content   string  `json:"content"`
+		SegmentID *string `json:"segment_id"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -168,39 +191,11 @@ func (h *NewsletterHandler) UpdateNewsletter(c *fiber.Ctx) error {
 		})
 	}
 
-	ctx := c.Context()
-	nl, err := h.newsletterRepo.GetByID(ctx, id)
+	// Wijzigen na indienen zet de nieuwsbrief terug naar draft, zodat hij opnieuw wordt goedgekeurd
+	userID, _ := c.Locals("userID").(string)
+	nl, err := h.workflow.Edit(c.Context(), id, req.Subject, req.Content, req.SegmentID, userID)
 	if err != nil {
-		logger.Error("Fout bij ophalen nieuwsbrief", "error", err, "id", id)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon nieuwsbrief niet ophalen",
-		})
-	}
-
-	if nl == nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Nieuwsbrief niet gevonden",
-		})
-	}
-
-	if nl.SentAt != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Nieuwsbrief is al verzonden en kan niet meer worden bijgewerkt",
-		})
-	}
-
-	if req.Subject != "" {
-		nl.Subject = req.Subject
-	}
-	if req.Content != "" {
-		nl.Content = req.Content
-	}
-
-	if err := h.newsletterRepo.Update(ctx, nl); err != nil {
-		logger.Error("Fout bij bijwerken nieuwsbrief", "error", err, "id", id)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon nieuwsbrief niet bijwerken",
-		})
+		return h.handleWorkflowError(c, err, id)
 	}
 
 	return c.JSON(nl)
@@ -230,7 +225,7 @@ func (h *NewsletterHandler) DeleteNewsletter(c *fiber.Ctx) error {
 		})
 	}
 
-	if nl.SentAt != nil {
+	if nl.SentAt != nil || nl.Status == models.NewsletterStatusSending {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Nieuwsbrief is al verzonden en kan niet meer worden verwijderd",
 		})
@@ -261,10 +256,27 @@ func (h *NewsletterHandler) SendNewsletter(c *fiber.Ctx) error {
 		})
 	}
 
-	logger.Info("SendNewsletter: Starting send for newsletter", "id", id)
+	// De nieuwsbrief gaat naar het goedgekeurde segment of naar alle subscribers; een
+	// segment_id in de body moet daarmee overeenkomen
+	var req struct {
+		SegmentID string `json:"segment_id"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Ongeldige gegevens",
+			})
+		}
+	}
+
+	logger.Info("SendNewsletter: Starting send for newsletter", "id", id, "segment_id", req.SegmentID)
 
 	ctx := c.Context()
-	if err := h.newsletterSvc.SendManual(ctx, id); err != nil {
+	if err := h.newsletterSvc.SendManual(ctx, id, req.SegmentID); err != nil {
+		if errors.Is(err, services.ErrNewsletterNotFound) || errors.Is(err, services.ErrNewsletterInvalidStatus) ||
+			errors.Is(err, services.ErrNewsletterSegmentNotFound) || errors.Is(err, services.ErrNewsletterSegmentMismatch) {
+			return h.handleWorkflowError(c, err, id)
+		}
 		logger.Error("SendNewsletter: Fout bij verzenden nieuwsbrief", "error", err, "id", id)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Kon nieuwsbrief niet verzenden",
@@ -278,3 +290,171 @@ func (h *NewsletterHandler) SendNewsletter(c *fiber.Ctx) error {
 		"message": "Nieuwsbrief wordt verzonden naar subscribers",
 	})
 }
+
+// SubmitNewsletter dient een nieuwsbrief in ter goedkeuring
+// @Summary Nieuwsbrief indienen
+// @Description Zet een draft nieuwsbrief op in_review zodat een tweede persoon hem kan goedkeuren
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} models.Newsletter
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/submit [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) SubmitNewsletter(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+	nl, err := h.workflow.Submit(c.Context(), c.Params("id"), userID)
+	if err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+	return c.JSON(nl)
+}
+
+// ApproveNewsletter keurt een ingediende nieuwsbrief goed
+// @Summary Nieuwsbrief goedkeuren
+// @Description Keurt een nieuwsbrief in in_review goed; opsteller, laatste bewerker en indiener kunnen hem niet goedkeuren
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} models.Newsletter
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/approve [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) ApproveNewsletter(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok || userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Niet geautoriseerd",
+		})
+	}
+
+	nl, err := h.workflow.Approve(c.Context(), c.Params("id"), userID)
+	if err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+	return c.JSON(nl)
+}
+
+// RejectNewsletter stuurt een nieuwsbrief terug naar draft
+// @Summary Nieuwsbrief afkeuren
+// @Description Zet een ingediende, goedgekeurde of ingeplande nieuwsbrief terug naar draft
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} models.Newsletter
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/reject [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) RejectNewsletter(c *fiber.Ctx) error {
+	nl, err := h.workflow.Reject(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+	return c.JSON(nl)
+}
+
+// ScheduleNewsletter plant een goedgekeurde nieuwsbrief in
+// @Summary Nieuwsbrief inplannen
+// @Description Plant een goedgekeurde nieuwsbrief in; de scheduler verzendt hem op scheduled_at (RFC 3339)
+// @Tags Newsletter
+// @Accept json
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Param body body object true "Tijdstip van verzending in scheduled_at (RFC 3339)"
+// @Success 200 {object} models.Newsletter
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/schedule [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) ScheduleNewsletter(c *fiber.Ctx) error {
+	var req struct {
+		ScheduledAt time.Time `json:"scheduled_at"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.ScheduledAt.IsZero() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "scheduled_at is verplicht (RFC 3339)",
+		})
+	}
+
+	nl, err := h.workflow.Schedule(c.Context(), c.Params("id"), req.ScheduledAt)
+	if err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+	return c.JSON(nl)
+}
+
+// UnscheduleNewsletter haalt een nieuwsbrief uit de planning
+// @Summary Planning van nieuwsbrief annuleren
+// @Description Haalt een ingeplande nieuwsbrief uit de planning; hij blijft goedgekeurd
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} models.Newsletter
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/unschedule [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) UnscheduleNewsletter(c *fiber.Ctx) error {
+	nl, err := h.workflow.Unschedule(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+	return c.JSON(nl)
+}
+
+// TestSendNewsletter verstuurt een voorbeeld van de nieuwsbrief naar de ingelogde medewerker
+// @Summary Testversie van nieuwsbrief versturen
+// @Description Verstuurt de nieuwsbrief alleen naar het adres van de aanvrager, ongeacht de status
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/test-send [post]
+// @Security BearerAuth
+func (h *NewsletterHandler) TestSendNewsletter(c *fiber.Ctx) error {
+	userID, ok := c.Locals("userID").(string)
+	if !ok || userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Niet geautoriseerd",
+		})
+	}
+
+	user, err := h.authService.GetUser(c.Context(), userID)
+	if err != nil || user == nil {
+		logger.Error("Kon gebruiker voor testversie niet ophalen", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon gebruiker niet ophalen",
+		})
+	}
+
+	if err := h.workflow.TestSend(c.Context(), c.Params("id"), user.Email); err != nil {
+		return h.handleWorkflowError(c, err, c.Params("id"))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Testversie verzonden naar " + user.Email,
+	})
+}
+
+// handleWorkflowError vertaalt fouten uit de nieuwsbrief workflow naar een HTTP response
+func (h *NewsletterHandler) handleWorkflowError(c *fiber.Ctx, err error, id string) error {
+	switch {
+	case errors.Is(err, services.ErrNewsletterNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Nieuwsbrief niet gevonden"})
+	case errors.Is(err, services.ErrNewsletterInvalidStatus):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNewsletterSelfApproval):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNewsletterSegmentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Segment niet gevonden"})
+	case errors.Is(err, services.ErrNewsletterSegmentMismatch):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrNewsletterScheduleInPast):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		logger.Error("Fout in nieuwsbrief workflow", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Kon nieuwsbrief niet bijwerken"})
+	}
+}
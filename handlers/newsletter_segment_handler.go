@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewsletterSegmentHandler bevat handlers voor het beheren van nieuwsbrief segmenten
+type NewsletterSegmentHandler struct {
+	segmentRepo       repository.NewsletterSegmentRepository
+	newsletterSvc     *services.NewsletterSender
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewNewsletterSegmentHandler maakt een nieuwe nieuwsbrief segment handler
+func NewNewsletterSegmentHandler(
+	segmentRepo repository.NewsletterSegmentRepository,
+	newsletterSvc *services.NewsletterSender,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *NewsletterSegmentHandler {
+	return &NewsletterSegmentHandler{
+		segmentRepo:       segmentRepo,
+		newsletterSvc:     newsletterSvc,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// segmentRequest is de body voor het aanmaken en bijwerken van een segment
+type segmentRequest struct {
+	Naam         string               `json:"naam"`
+	Beschrijving string               `json:"beschrijving"`
+	Filter       models.SegmentFilter `json:"filter"`
+}
+
+// RegisterRoutes registreert de segment routes
+func (h *NewsletterSegmentHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/newsletter-segments", AuthMiddleware(h.authService))
+
+	readGroup := group.Group("", PermissionMiddleware(h.permissionService, "newsletter", "read"))
+	readGroup.Get("/", h.ListSegments)
+	readGroup.Post("/preview", h.PreviewFilter)
+	readGroup.Get("/:id", h.GetSegment)
+	readGroup.Get("/:id/preview", h.PreviewSegment)
+
+	writeGroup := group.Group("", PermissionMiddleware(h.permissionService, "newsletter", "write"))
+	writeGroup.Post("/", h.CreateSegment)
+	writeGroup.Put("/:id", h.UpdateSegment)
+	writeGroup.Delete("/:id", h.DeleteSegment)
+}
+
+// ListSegments haalt alle segmenten op
+// @Summary Lijst van nieuwsbrief segmenten
+// @Description Haalt alle opgeslagen doelgroepen voor de nieuwsbrief op
+// @Tags NewsletterSegments
+// @Produce json
+// @Success 200 {array} models.NewsletterSegment
+// @Router /api/newsletter-segments [get]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) ListSegments(c *fiber.Ctx) error {
+	segments, err := h.segmentRepo.List(c.Context())
+	if err != nil {
+		logger.Error("Fout bij ophalen segmenten", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon segmenten niet ophalen",
+		})
+	}
+
+	return c.JSON(segments)
+}
+
+// GetSegment haalt een segment op
+// @Summary Nieuwsbrief segment ophalen
+// @Tags NewsletterSegments
+// @Produce json
+// @Param id path string true "Segment ID"
+// @Success 200 {object} models.NewsletterSegment
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/newsletter-segments/{id} [get]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) GetSegment(c *fiber.Ctx) error {
+	segment, err := h.getSegment(c)
+	if err != nil || segment == nil {
+		return err
+	}
+	return c.JSON(segment)
+}
+
+// CreateSegment maakt een nieuw segment aan
+// @Summary Nieuwsbrief segment aanmaken
+// @Description Slaat een doelgroep op als filter over aanmeldingen, gebruikers en een lijst met adressen
+// @Tags NewsletterSegments
+// @Accept json
+// @Produce json
+// @Param segment body segmentRequest true "Segment"
+// @Success 201 {object} models.NewsletterSegment
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter-segments [post]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) CreateSegment(c *fiber.Ctx) error {
+	var req segmentRequest
+	if err := h.parseRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	segment := &models.NewsletterSegment{
+		Naam:         req.Naam,
+		Beschrijving: req.Beschrijving,
+		Filter:       req.Filter,
+	}
+	if userID, ok := c.Locals("userID").(string); ok && userID != "" {
+		segment.CreatedBy = &userID
+	}
+
+	if err := h.segmentRepo.Create(c.Context(), segment); err != nil {
+		logger.Error("Fout bij aanmaken segment", "error", err, "naam", req.Naam)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon segment niet aanmaken",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(segment)
+}
+
+// UpdateSegment werkt een segment bij
+// @Summary Nieuwsbrief segment bijwerken
+// @Tags NewsletterSegments
+// @Accept json
+// @Produce json
+// @Param id path string true "Segment ID"
+// @Param segment body segmentRequest true "Segment"
+// @Success 200 {object} models.NewsletterSegment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/newsletter-segments/{id} [put]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) UpdateSegment(c *fiber.Ctx) error {
+	segment, err := h.getSegment(c)
+	if err != nil || segment == nil {
+		return err
+	}
+
+	var req segmentRequest
+	if err := h.parseRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	segment.Naam = req.Naam
+	segment.Beschrijving = req.Beschrijving
+	segment.Filter = req.Filter
+
+	if err := h.segmentRepo.Update(c.Context(), segment); err != nil {
+		logger.Error("Fout bij bijwerken segment", "error", err, "id", segment.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon segment niet bijwerken",
+		})
+	}
+
+	return c.JSON(segment)
+}
+
+// DeleteSegment verwijdert een segment
+// @Summary Nieuwsbrief segment verwijderen
+// @Description Verwijdert een segment; nieuwsbrieven met dit segment gaan daarna naar alle subscribers
+// @Tags NewsletterSegments
+// @Produce json
+// @Param id path string true "Segment ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/newsletter-segments/{id} [delete]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) DeleteSegment(c *fiber.Ctx) error {
+	if err := h.segmentRepo.Delete(c.Context(), c.Params("id")); err != nil {
+		logger.Error("Fout bij verwijderen segment", "error", err, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon segment niet verwijderen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Segment succesvol verwijderd",
+	})
+}
+
+// PreviewSegment telt de ontvangers van een opgeslagen segment zonder te verzenden
+// @Summary Dry-run van een segment
+// @Description Telt de unieke ontvangers van een segment, na het overslaan van onderdrukte en afgemelde adressen
+// @Tags NewsletterSegments
+// @Produce json
+// @Param id path string true "Segment ID"
+// @Success 200 {object} services.SegmentPreview
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/newsletter-segments/{id}/preview [get]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) PreviewSegment(c *fiber.Ctx) error {
+	segment, err := h.getSegment(c)
+	if err != nil || segment == nil {
+		return err
+	}
+	return h.preview(c, segment.Filter)
+}
+
+// PreviewFilter telt de ontvangers van een filter zonder het op te slaan of te verzenden
+// @Summary Dry-run van een filter
+// @Description Telt de unieke ontvangers van een filter, zodat een segment gecontroleerd kan worden voor het opslaan
+// @Tags NewsletterSegments
+// @Accept json
+// @Produce json
+// @Param filter body models.SegmentFilter true "Filter"
+// @Success 200 {object} services.SegmentPreview
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter-segments/preview [post]
+// @Security BearerAuth
+func (h *NewsletterSegmentHandler) PreviewFilter(c *fiber.Ctx) error {
+	var filter models.SegmentFilter
+	if err := c.BodyParser(&filter); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+	if filter.IsEmpty() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Filter moet aanmeldingen, gebruikers of emails bevatten",
+		})
+	}
+	return h.preview(c, filter)
+}
+
+// preview voert de dry-run uit en stuurt het resultaat terug
+func (h *NewsletterSegmentHandler) preview(c *fiber.Ctx, filter models.SegmentFilter) error {
+	result, err := h.newsletterSvc.PreviewSegment(c.Context(), filter)
+	if err != nil {
+		logger.Error("Fout bij dry-run van segment", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon ontvangers niet tellen",
+		})
+	}
+	return c.JSON(result)
+}
+
+// getSegment haalt het segment uit de route op. Als het niet gevonden wordt of het ophalen
+// mislukt, is het foutantwoord al verstuurd en is het segment nil.
+func (h *NewsletterSegmentHandler) getSegment(c *fiber.Ctx) (*models.NewsletterSegment, error) {
+	id := c.Params("id")
+	segment, err := h.segmentRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen segment", "error", err, "id", id)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon segment niet ophalen",
+		})
+	}
+	if segment == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Segment niet gevonden",
+		})
+	}
+	return segment, nil
+}
+
+// parseRequest leest en valideert de body van een segment
+func (h *NewsletterSegmentHandler) parseRequest(c *fiber.Ctx, req *segmentRequest) error {
+	if err := c.BodyParser(req); err != nil {
+		return errors.New("Ongeldige gegevens")
+	}
+
+	req.Naam = strings.TrimSpace(req.Naam)
+	if req.Naam == "" {
+		return errors.New("Naam is verplicht")
+	}
+	if req.Filter.IsEmpty() {
+		return errors.New("Filter moet aanmeldingen, gebruikers of emails bevatten")
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewsletterSourceHandler bevat handlers voor het beheren van de bronnen van de automatische nieuwsbrief
+type NewsletterSourceHandler struct {
+	sourceRepo        repository.NewsletterSourceRepository
+	fetcher           *services.NewsletterFetcher
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewNewsletterSourceHandler maakt een nieuwe nieuwsbrief bron handler
+func NewNewsletterSourceHandler(
+	sourceRepo repository.NewsletterSourceRepository,
+	fetcher *services.NewsletterFetcher,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *NewsletterSourceHandler {
+	return &NewsletterSourceHandler{
+		sourceRepo:        sourceRepo,
+		fetcher:           fetcher,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// sourceRequest is de body voor het aanmaken en bijwerken van een bron
+type sourceRequest struct {
+	Naam       string `json:"naam"`
+	Type       string `json:"type"`
+	URL        string `json:"url"`
+	Categorie  string `json:"categorie"`
+	MaxItems   int    `json:"max_items"`
+	MaxAgeDays int    `json:"max_age_days"`
+	IsActief   *bool  `json:"is_actief"`
+}
+
+// RegisterRoutes registreert de bron routes
+func (h *NewsletterSourceHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/newsletter-sources", AuthMiddleware(h.authService))
+
+	readGroup := group.Group("", PermissionMiddleware(h.permissionService, "newsletter", "read"))
+	readGroup.Get("/", h.ListSources)
+	readGroup.Get("/:id/preview", h.PreviewSource)
+
+	writeGroup := group.Group("", PermissionMiddleware(h.permissionService, "newsletter", "write"))
+	writeGroup.Post("/", h.CreateSource)
+	writeGroup.Put("/:id", h.UpdateSource)
+	writeGroup.Delete("/:id", h.DeleteSource)
+}
+
+// ListSources haalt alle bronnen op
+// @Summary Lijst van nieuwsbrief bronnen
+// @Description Haalt de feeds en eigen content op waaruit de automatische nieuwsbrief wordt samengesteld
+// @Tags NewsletterSources
+// @Produce json
+// @Success 200 {array} models.NewsletterSource
+// @Router /api/newsletter-sources [get]
+// @Security BearerAuth
+func (h *NewsletterSourceHandler) ListSources(c *fiber.Ctx) error {
+	sources, err := h.sourceRepo.List(c.Context())
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief bronnen", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon bronnen niet ophalen",
+		})
+	}
+
+	return c.JSON(sources)
+}
+
+// CreateSource maakt een nieuwe bron aan
+// @Summary Nieuwsbrief bron aanmaken
+// @Description Voegt een RSS, Atom of JSON feed of eigen content (photos, albums, videos, radio, program) toe
+// @Tags NewsletterSources
+// @Accept json
+// @Produce json
+// @Param source body sourceRequest true "Bron"
+// @Success 201 {object} models.NewsletterSource
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter-sources [post]
+// @Security BearerAuth
+func (h *NewsletterSourceHandler) CreateSource(c *fiber.Ctx) error {
+	var req sourceRequest
+	if err := h.parseRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	source := &models.NewsletterSource{IsActief: true}
+	req.apply(source)
+
+	if err := h.sourceRepo.Create(c.Context(), source); err != nil {
+		logger.Error("Fout bij aanmaken nieuwsbrief bron", "error", err, "naam", req.Naam)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon bron niet aanmaken",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(source)
+}
+
+// UpdateSource werkt een bron bij
+// @Summary Nieuwsbrief bron bijwerken
+// @Tags NewsletterSources
+// @Accept json
+// @Produce json
+// @Param id path string true "Bron ID"
+// @Param source body sourceRequest true "Bron"
+// @Success 200 {object} models.NewsletterSource
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/newsletter-sources/{id} [put]
+// @Security BearerAuth
+func (h *NewsletterSourceHandler) UpdateSource(c *fiber.Ctx) error {
+	source, err := h.getSource(c)
+	if err != nil || source == nil {
+		return err
+	}
+
+	var req sourceRequest
+	if err := h.parseRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	req.apply(source)
+
+	if err := h.sourceRepo.Update(c.Context(), source); err != nil {
+		logger.Error("Fout bij bijwerken nieuwsbrief bron", "error", err, "id", source.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon bron niet bijwerken",
+		})
+	}
+
+	return c.JSON(source)
+}
+
+// DeleteSource verwijdert een bron
+// @Summary Nieuwsbrief bron verwijderen
+// @Tags NewsletterSources
+// @Produce json
+// @Param id path string true "Bron ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/newsletter-sources/{id} [delete]
+// @Security BearerAuth
+func (h *NewsletterSourceHandler) DeleteSource(c *fiber.Ctx) error {
+	if err := h.sourceRepo.Delete(c.Context(), c.Params("id")); err != nil {
+		logger.Error("Fout bij verwijderen nieuwsbrief bron", "error", err, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon bron niet verwijderen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Bron succesvol verwijderd",
+	})
+}
+
+// PreviewSource haalt de items van een bron op zonder een nieuwsbrief te verzenden
+// @Summary Test een nieuwsbrief bron
+// @Description Haalt de items van een bron op met de instellingen van de bron, om de configuratie te controleren
+// @Tags NewsletterSources
+// @Produce json
+// @Param id path string true "Bron ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/newsletter-sources/{id}/preview [get]
+// @Security BearerAuth
+func (h *NewsletterSourceHandler) PreviewSource(c *fiber.Ctx) error {
+	source, err := h.getSource(c)
+	if err != nil || source == nil {
+		return err
+	}
+
+	items, err := h.fetcher.FetchSource(c.Context(), source)
+	if err != nil {
+		logger.Warn("Nieuwsbrief bron kon niet worden opgehaald", "error", err, "id", source.ID)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Kon bron niet ophalen: " + err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// getSource haalt de bron uit de route op. Als hij niet gevonden wordt of het ophalen
+// mislukt, is het foutantwoord al verstuurd en is de bron nil.
+func (h *NewsletterSourceHandler) getSource(c *fiber.Ctx) (*models.NewsletterSource, error) {
+	id := c.Params("id")
+	source, err := h.sourceRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief bron", "error", err, "id", id)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon bron niet ophalen",
+		})
+	}
+	if source == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bron niet gevonden",
+		})
+	}
+	return source, nil
+}
+
+// parseRequest leest en valideert de body van een bron
+func (h *NewsletterSourceHandler) parseRequest(c *fiber.Ctx, req *sourceRequest) error {
+	if err := c.BodyParser(req); err != nil {
+		return errors.New("Ongeldige gegevens")
+	}
+
+	req.Naam = strings.TrimSpace(req.Naam)
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	req.URL = strings.TrimSpace(req.URL)
+	if req.Naam == "" {
+		return errors.New("Naam is verplicht")
+	}
+	if !models.IsValidNewsletterSourceType(req.Type) {
+		return errors.New("Ongeldig type, kies rss, atom, json, photos, albums, videos, radio of program")
+	}
+
+	internal := (&models.NewsletterSource{Type: req.Type}).IsInternal()
+	if req.URL == "" && (!internal || req.Type == models.NewsletterSourceProgram) {
+		return errors.New("URL is verplicht voor dit type")
+	}
+	if req.URL != "" {
+		parsed, err := url.Parse(req.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.New("URL moet een geldige http(s) link zijn")
+		}
+	}
+	if req.MaxItems < 0 || req.MaxAgeDays < 0 {
+		return errors.New("Max items en max leeftijd mogen niet negatief zijn")
+	}
+	return nil
+}
+
+// apply neemt de waarden uit het verzoek over in de bron
+func (req *sourceRequest) apply(source *models.NewsletterSource) {
+	source.Naam = req.Naam
+	source.Type = req.Type
+	source.URL = req.URL
+	source.Categorie = strings.TrimSpace(req.Categorie)
+	source.MaxItems = req.MaxItems
+	if source.MaxItems == 0 {
+		source.MaxItems = 10
+	}
+	source.MaxAgeDays = req.MaxAgeDays
+	if source.MaxAgeDays == 0 {
+		source.MaxAgeDays = 7
+	}
+	if req.IsActief != nil {
+		source.IsActief = *req.IsActief
+	}
+}
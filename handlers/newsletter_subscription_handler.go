@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"fmt"
+	"html"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewsletterSubscriptionHandler bevat de publieke aan- en afmeldroutes voor de nieuwsbrief en de audit
+type NewsletterSubscriptionHandler struct {
+	subscriptionSvc   *services.NewsletterSubscriptionService
+	rateLimiter       services.RateLimiterService
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewNewsletterSubscriptionHandler maakt een nieuwe nieuwsbrief subscription handler
+func NewNewsletterSubscriptionHandler(
+	subscriptionSvc *services.NewsletterSubscriptionService,
+	rateLimiter services.RateLimiterService,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *NewsletterSubscriptionHandler {
+	return &NewsletterSubscriptionHandler{
+		subscriptionSvc:   subscriptionSvc,
+		rateLimiter:       rateLimiter,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// subscribeRequest is de body voor een publieke aanmelding voor de nieuwsbrief
+type subscribeRequest struct {
+	Email   string `json:"email"`
+	Naam    string `json:"naam"`
+	Consent bool   `json:"consent"`
+}
+
+// RegisterRoutes registreert de routes. Deze moeten vóór de NewsletterHandler worden
+// geregistreerd, anders vallen ze onder diens auth middleware en /:id routes.
+func (h *NewsletterSubscriptionHandler) RegisterRoutes(app *fiber.App) {
+	// Publiek: aanmelden via de website en de bevestigingslink uit de email
+	app.Post("/api/newsletter/subscribe", RateLimitMiddleware(h.rateLimiter, "newsletter_subscribe"), h.Subscribe)
+	app.Get("/api/newsletter/confirm", h.ShowConfirm)
+	app.Post("/api/newsletter/confirm", h.Confirm)
+
+	// Publiek: de link in de nieuwsbrief en RFC 8058 one-click vanuit de mailclient
+	app.Get("/api/newsletter/unsubscribe", h.ShowUnsubscribe)
+	app.Post("/api/newsletter/unsubscribe", h.Unsubscribe)
+
+	app.Get("/api/newsletter/subscription-events",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "newsletter", "read"),
+		h.ListSubscriptionEvents)
+	app.Get("/api/newsletter/subscribers",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "newsletter", "read"),
+		h.ListSubscribers)
+}
+
+// Subscribe meldt een bezoeker van de website aan voor de nieuwsbrief
+// @Summary Aanmelden voor de nieuwsbrief
+// @Description Meldt een adres aan zonder account (double opt-in). Toestemming is verplicht en wordt met tijdstip en IP adres vastgelegd; de nieuwsbrief wordt pas verzonden na bevestiging via de link in de email.
+// @Tags Newsletter
+// @Accept json
+// @Produce json
+// @Param aanmelding body subscribeRequest true "Email, naam en toestemming"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /api/newsletter/subscribe [post]
+func (h *NewsletterSubscriptionHandler) Subscribe(c *fiber.Ctx) error {
+	var req subscribeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	err := h.subscriptionSvc.Subscribe(c.Context(), req.Email, req.Naam, req.Consent, c.IP())
+	switch {
+	case errors.Is(err, services.ErrNewsletterConsentRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Toestemming voor het ontvangen van de nieuwsbrief is verplicht",
+		})
+	case errors.Is(err, services.ErrNewsletterInvalidEmail):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig email adres",
+		})
+	case errors.Is(err, services.ErrNewsletterSignupDisabled):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Aanmelden voor de nieuwsbrief is tijdelijk niet mogelijk",
+		})
+	case err != nil:
+		logger.Error("Fout bij aanmelden voor nieuwsbrief", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Aanmelden is mislukt, probeer het later opnieuw",
+		})
+	}
+
+	// Altijd hetzelfde antwoord, zodat niet te achterhalen is wie er al is aangemeld
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Controleer je inbox om je aanmelding te bevestigen",
+	})
+}
+
+// ShowConfirm toont de bevestigingspagina voor een aanmelding. Er wordt nog niets gewijzigd,
+// zodat link-scanners van mailproviders niemand bevestigen zonder dat de ontvanger klikt.
+// @Summary Bevestigingspagina nieuwsbrief
+// @Description Toont een pagina met een knop om de aanmelding voor de nieuwsbrief te bevestigen
+// @Tags Newsletter
+// @Produce html
+// @Param token query string true "Ondertekend bevestigingstoken"
+// @Success 200 {string} string "HTML pagina"
+// @Router /api/newsletter/confirm [get]
+func (h *NewsletterSubscriptionHandler) ShowConfirm(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return h.page(c, fiber.StatusBadRequest, "Ongeldige bevestigingslink", "Deze bevestigingslink is onvolledig.")
+	}
+
+	body := fmt.Sprintf(`<p>Bevestig dat je de nieuwsbrief van De Koninklijke Loop wilt ontvangen.</p>
+<form method="post" action="/api/newsletter/confirm?token=%s"><button type="submit">Aanmelding bevestigen</button></form>`,
+		html.EscapeString(token))
+	return h.page(c, fiber.StatusOK, "Aanmelding bevestigen", body)
+}
+
+// Confirm bevestigt de aanmelding uit het token
+// @Summary Aanmelding nieuwsbrief bevestigen
+// @Description Bevestigt de aanmelding (double opt-in); daarna ontvangt het adres de nieuwsbrief
+// @Tags Newsletter
+// @Produce html
+// @Param token query string true "Ondertekend bevestigingstoken"
+// @Success 200 {string} string "HTML pagina"
+// @Failure 400 {string} string "Ongeldig of verlopen token"
+// @Router /api/newsletter/confirm [post]
+func (h *NewsletterSubscriptionHandler) Confirm(c *fiber.Ctx) error {
+	_, err := h.subscriptionSvc.Confirm(c.Context(), c.Query("token"), c.IP())
+	if errors.Is(err, services.ErrInvalidSignedToken) {
+		return h.page(c, fiber.StatusBadRequest, "Ongeldige bevestigingslink", "Deze bevestigingslink is ongeldig of verlopen. Meld je opnieuw aan via de website.")
+	}
+	if err != nil {
+		logger.Error("Fout bij bevestigen van nieuwsbrief aanmelding", "error", err)
+		return h.page(c, fiber.StatusInternalServerError, "Bevestigen mislukt", "Er ging iets mis, probeer het later opnieuw.")
+	}
+
+	return h.page(c, fiber.StatusOK, "Aanmelding bevestigd", "Bedankt! Je ontvangt voortaan de nieuwsbrief van De Koninklijke Loop.")
+}
+
+// ShowUnsubscribe toont de bevestigingspagina voor afmelden. Er wordt nog niets gewijzigd,
+// zodat link-scanners van mailproviders niemand per ongeluk afmelden.
+// @Summary Afmeldpagina nieuwsbrief
+// @Description Toont een bevestigingspagina met een knop om af te melden voor de nieuwsbrief
+// @Tags Newsletter
+// @Produce html
+// @Param token query string true "Ondertekend afmeldtoken"
+// @Success 200 {string} string "HTML pagina"
+// @Router /api/newsletter/unsubscribe [get]
+func (h *NewsletterSubscriptionHandler) ShowUnsubscribe(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return h.page(c, fiber.StatusBadRequest, "Ongeldige afmeldlink", "Deze afmeldlink is onvolledig.")
+	}
+
+	body := fmt.Sprintf(`<p>Wil je de nieuwsbrief van De Koninklijke Loop niet meer ontvangen?</p>
+<form method="post" action="/api/newsletter/unsubscribe?token=%s"><button type="submit">Afmelden</button></form>`,
+		html.EscapeString(token))
+	return h.page(c, fiber.StatusOK, "Afmelden voor de nieuwsbrief", body)
+}
+
+// Unsubscribe meldt de ontvanger uit het token af voor de nieuwsbrief
+// @Summary Afmelden voor de nieuwsbrief
+// @Description Meldt af via de knop op de afmeldpagina of via RFC 8058 one-click (List-Unsubscribe=One-Click)
+// @Tags Newsletter
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param token query string true "Ondertekend afmeldtoken"
+// @Success 200 {string} string "HTML pagina"
+// @Failure 400 {string} string "Ongeldig token"
+// @Router /api/newsletter/unsubscribe [post]
+func (h *NewsletterSubscriptionHandler) Unsubscribe(c *fiber.Ctx) error {
+	bron := models.NewsletterBronLink
+	if c.FormValue("List-Unsubscribe") == "One-Click" {
+		bron = models.NewsletterBronOneClick
+	}
+
+	_, err := h.subscriptionSvc.Unsubscribe(c.Context(), c.Query("token"), bron, c.IP())
+	if errors.Is(err, services.ErrInvalidSignedToken) {
+		return h.page(c, fiber.StatusBadRequest, "Ongeldige afmeldlink", "Deze afmeldlink is ongeldig.")
+	}
+	if err != nil {
+		logger.Error("Fout bij afmelden voor nieuwsbrief", "error", err)
+		return h.page(c, fiber.StatusInternalServerError, "Afmelden mislukt", "Er ging iets mis, probeer het later opnieuw.")
+	}
+
+	return h.page(c, fiber.StatusOK, "Afgemeld", "Je bent afgemeld en ontvangt de nieuwsbrief niet meer.")
+}
+
+// ListSubscriptionEvents haalt de audit van aan- en afmeldingen op
+// @Summary Audit nieuwsbrief aan- en afmeldingen
+// @Description Haalt de aan- en afmeldingen voor de nieuwsbrief op, nieuwste eerst
+// @Tags Newsletter
+// @Produce json
+// @Param email query string false "Alleen voor dit adres"
+// @Param limit query int false "Aantal resultaten (standaard 20)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter/subscription-events [get]
+// @Security BearerAuth
+func (h *NewsletterSubscriptionHandler) ListSubscriptionEvents(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	events, total, err := h.subscriptionSvc.ListEvents(c.Context(), c.Query("email"), limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief audit", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon nieuwsbrief audit niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"events": events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// ListSubscribers haalt de abonnees zonder account op
+// @Summary Abonnees nieuwsbrief
+// @Description Haalt de abonnees op die zich via de website hebben aangemeld, met hun toestemming (tijdstip en IP)
+// @Tags Newsletter
+// @Produce json
+// @Param status query string false "Alleen deze status (pending, confirmed, unsubscribed)"
+// @Param limit query int false "Aantal resultaten (standaard 20)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter/subscribers [get]
+// @Security BearerAuth
+func (h *NewsletterSubscriptionHandler) ListSubscribers(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.NewsletterSubscriberPending, models.NewsletterSubscriberConfirmed, models.NewsletterSubscriberUnsubscribed:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige status",
+		})
+	}
+
+	subscribers, total, err := h.subscriptionSvc.ListSubscribers(c.Context(), status, limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief abonnees", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon abonnees niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"subscribers": subscribers,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// page stuurt een eenvoudige HTML pagina terug voor ontvangers van de nieuwsbrief
+func (h *NewsletterSubscriptionHandler) page(c *fiber.Ctx, status int, title, body string) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).SendString(fmt.Sprintf(`<!DOCTYPE html>
+<html lang="nl"><head><meta charset="UTF-8"><meta name="viewport" content="width=device-width, initial-scale=1.0"><title>%s</title></head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 40px auto; padding: 0 20px;"><h1>%s</h1>%s</body></html>`,
+		html.EscapeString(title), html.EscapeString(title), body))
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// trackingPixel is een transparante GIF van 1x1 pixel
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// NewsletterTrackingHandler bevat de publieke tracking routes en de statistieken van nieuwsbrieven
+type NewsletterTrackingHandler struct {
+	tracker           *services.NewsletterTracker
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewNewsletterTrackingHandler maakt een nieuwe nieuwsbrief tracking handler
+func NewNewsletterTrackingHandler(
+	tracker *services.NewsletterTracker,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *NewsletterTrackingHandler {
+	return &NewsletterTrackingHandler{
+		tracker:           tracker,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// RegisterRoutes registreert de routes. Deze moeten vóór de NewsletterHandler worden
+// geregistreerd, anders vallen ze onder diens auth middleware en /:id routes.
+func (h *NewsletterTrackingHandler) RegisterRoutes(app *fiber.App) {
+	// Publiek: de tracking pixel en de redirect links in de nieuwsbrief
+	app.Get("/api/newsletter/track/open", h.TrackOpen)
+	app.Get("/api/newsletter/track/click", h.TrackClick)
+
+	app.Get("/api/newsletter/:id/stats",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "newsletter", "read"),
+		h.GetStats)
+	app.Get("/api/newsletter/:id/deliveries",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "newsletter", "read"),
+		h.ListDeliveries)
+}
+
+// TrackOpen registreert het openen van een nieuwsbrief en geeft een transparante pixel terug
+// @Summary Tracking pixel nieuwsbrief
+// @Description Registreert dat een ontvanger de nieuwsbrief heeft geopend. Geeft altijd een pixel terug.
+// @Tags Newsletter
+// @Produce image/gif
+// @Param t query string true "Ondertekend tracking token"
+// @Success 200 {file} binary
+// @Router /api/newsletter/track/open [get]
+func (h *NewsletterTrackingHandler) TrackOpen(c *fiber.Ctx) error {
+	if err := h.tracker.Open(c.Context(), c.Query("t")); err != nil {
+		if errors.Is(err, services.ErrInvalidSignedToken) {
+			logger.Debug("Ongeldig tracking token voor nieuwsbrief open", "ip", c.IP())
+		} else {
+			logger.Error("Fout bij registreren open van nieuwsbrief", "error", err)
+		}
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store, no-cache, must-revalidate")
+	c.Set(fiber.HeaderContentType, "image/gif")
+	return c.Send(trackingPixel)
+}
+
+// TrackClick registreert een klik op een link in een nieuwsbrief en stuurt door naar de link
+// @Summary Klik tracking nieuwsbrief
+// @Description Registreert een klik en stuurt door naar de oorspronkelijke link uit het ondertekende token
+// @Tags Newsletter
+// @Param t query string true "Ondertekend tracking token"
+// @Success 302 {string} string "Redirect naar de oorspronkelijke link"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter/track/click [get]
+func (h *NewsletterTrackingHandler) TrackClick(c *fiber.Ctx) error {
+	target, err := h.tracker.Click(c.Context(), c.Query("t"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige link",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(target, fiber.StatusFound)
+}
+
+// GetStats haalt de statistieken van een nieuwsbrief op
+// @Summary Statistieken nieuwsbrief
+// @Description Aantal ontvangers per status (queued, sent, failed, bounced) en het aantal opens en kliks
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Success 200 {object} models.NewsletterStats
+// @Router /api/newsletter/{id}/stats [get]
+// @Security BearerAuth
+func (h *NewsletterTrackingHandler) GetStats(c *fiber.Ctx) error {
+	stats, err := h.tracker.Stats(c.Context(), c.Params("id"))
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief statistieken", "error", err, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon statistieken niet ophalen",
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// ListDeliveries haalt de afleveringen van een nieuwsbrief per ontvanger op
+// @Summary Afleveringen nieuwsbrief
+// @Description Haalt per ontvanger de status, foutmelding, opens en kliks van een nieuwsbrief op
+// @Tags Newsletter
+// @Produce json
+// @Param id path string true "Nieuwsbrief ID"
+// @Param status query string false "Alleen deze status (queued, sent, failed, bounced)"
+// @Param limit query int false "Aantal resultaten (standaard 50)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/newsletter/{id}/deliveries [get]
+// @Security BearerAuth
+func (h *NewsletterTrackingHandler) ListDeliveries(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.NewsletterDeliveryQueued, models.NewsletterDeliverySent, models.NewsletterDeliveryFailed, models.NewsletterDeliveryBounced:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige status",
+		})
+	}
+
+	deliveries, total, err := h.tracker.ListDeliveries(c.Context(), c.Params("id"), status, limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen nieuwsbrief afleveringen", "error", err, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon afleveringen niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
@@ -14,6 +14,7 @@ type NotificationHandler struct {
 	notificationRepo    repository.NotificationRepository
 	notificationService services.NotificationService
 	authService         services.AuthService
+	deliveryRepo        repository.NotificationDeliveryRepository
 }
 
 // NewNotificationHandler maakt een nieuwe NotificationHandler
@@ -29,6 +30,11 @@ func NewNotificationHandler(
 	}
 }
 
+// SetDeliveryRepository stelt de afleveringen per kanaal in, voor het overzicht per notificatie
+func (h *NotificationHandler) SetDeliveryRepository(deliveryRepo repository.NotificationDeliveryRepository) {
+	h.deliveryRepo = deliveryRepo
+}
+
 // RegisterRoutes registreert de routes voor de NotificationHandler
 func (h *NotificationHandler) RegisterRoutes(app *fiber.App) {
 	// Groepeer routes onder /api/v1/notifications met auth middleware
@@ -38,6 +44,7 @@ func (h *NotificationHandler) RegisterRoutes(app *fiber.App) {
 	notificationGroup.Get("/", h.ListNotifications)
 	notificationGroup.Post("/", h.CreateNotification)
 	notificationGroup.Get("/:id", h.GetNotification)
+	notificationGroup.Get("/:id/deliveries", h.GetNotificationDeliveries)
 	notificationGroup.Delete("/:id", h.DeleteNotification)
 	notificationGroup.Post("/reprocess-all", h.ReprocessAllNotifications)
 }
@@ -175,6 +182,43 @@ func (h *NotificationHandler) GetNotification(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(notification)
 }
 
+// GetNotificationDeliveries haalt de status van een notificatie per kanaal op
+func (h *NotificationHandler) GetNotificationDeliveries(c *fiber.Ctx) error {
+	if h.deliveryRepo == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Afleveringen per kanaal zijn niet beschikbaar",
+		})
+	}
+
+	id := c.Params("id")
+	notification, err := h.notificationRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen notificatie", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Er is een fout opgetreden bij het ophalen van de notificatie",
+		})
+	}
+
+	if notification == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Notificatie niet gevonden",
+		})
+	}
+
+	deliveries, err := h.deliveryRepo.ListByNotification(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen afleveringen", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Er is een fout opgetreden bij het ophalen van de afleveringen",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"notification": notification,
+		"deliveries":   deliveries,
+	})
+}
+
 // DeleteNotification verwijdert een notificatie
 func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"bufio"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// inboxKeepAliveInterval is hoe vaak een live verbinding een teken van leven krijgt, zodat
+// proxies hem niet sluiten
+const inboxKeepAliveInterval = 25 * time.Second
+
+// NotificationInboxHandler bevat handlers voor de persoonlijke inbox van de ingelogde gebruiker
+type NotificationInboxHandler struct {
+	inbox       *services.NotificationInbox
+	authService services.AuthService
+}
+
+// NewNotificationInboxHandler maakt een nieuwe inbox handler
+func NewNotificationInboxHandler(inbox *services.NotificationInbox, authService services.AuthService) *NotificationInboxHandler {
+	return &NotificationInboxHandler{
+		inbox:       inbox,
+		authService: authService,
+	}
+}
+
+// markReadRequest is de body voor het als gelezen markeren van notificaties
+type markReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// RegisterRoutes registreert de inbox routes. Elke gebruiker ziet alleen zijn eigen inbox,
+// daarom is inloggen genoeg en is er geen extra permissie nodig.
+func (h *NotificationInboxHandler) RegisterRoutes(app *fiber.App) {
+	// EventSource in de browser kan geen headers meesturen; het token mag daarom ook in de query
+	app.Get("/api/inbox/stream", tokenFromQuery, AuthMiddleware(h.authService), h.Stream)
+
+	group := app.Group("/api/inbox", AuthMiddleware(h.authService))
+	group.Get("/", h.List)
+	group.Get("/unread-count", h.UnreadCount)
+	group.Post("/read", h.MarkRead)
+	group.Post("/read-all", h.MarkAllRead)
+	group.Get("/preferences", h.GetPreferences)
+	group.Put("/preferences", h.UpdatePreferences)
+	group.Delete("/:id", h.Delete)
+}
+
+// tokenFromQuery zet een token uit de query in de Authorization header als die ontbreekt
+func tokenFromQuery(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" && c.Query("token") != "" {
+		c.Request().Header.Set("Authorization", "Bearer "+c.Query("token"))
+	}
+	return c.Next()
+}
+
+// List haalt de notificaties van de ingelogde gebruiker op
+// @Summary Persoonlijke notificaties
+// @Description Haalt de notificaties in de inbox van de ingelogde gebruiker op, nieuwste eerst
+// @Tags Inbox
+// @Produce json
+// @Param unread query bool false "Alleen ongelezen notificaties"
+// @Param limit query int false "Aantal resultaten (standaard 20, max 100)"
+// @Param offset query int false "Offset voor paginering"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/inbox [get]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) List(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	userID := c.Locals("userID").(string)
+	notifications, err := h.inbox.List(c.Context(), userID, c.QueryBool("unread", false), limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen inbox", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon notificaties niet ophalen",
+		})
+	}
+
+	unread, err := h.inbox.UnreadCount(c.Context(), userID)
+	if err != nil {
+		logger.Error("Fout bij tellen ongelezen notificaties", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon notificaties niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"notifications": notifications,
+		"unread":        unread,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+// UnreadCount geeft het aantal ongelezen notificaties
+// @Summary Aantal ongelezen notificaties
+// @Tags Inbox
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/inbox/unread-count [get]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) UnreadCount(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	unread, err := h.inbox.UnreadCount(c.Context(), userID)
+	if err != nil {
+		logger.Error("Fout bij tellen ongelezen notificaties", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon ongelezen notificaties niet tellen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"unread": unread,
+	})
+}
+
+// MarkRead markeert een of meer notificaties als gelezen
+// @Summary Notificaties als gelezen markeren
+// @Tags Inbox
+// @Accept json
+// @Produce json
+// @Param request body markReadRequest true "IDs van de notificaties"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/inbox/read [post]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) MarkRead(c *fiber.Ctx) error {
+	var req markReadRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Geef tussen 1 en 100 notificaties op",
+		})
+	}
+
+	userID := c.Locals("userID").(string)
+	updated, err := h.inbox.MarkRead(c.Context(), userID, req.IDs)
+	if err != nil {
+		logger.Error("Fout bij markeren notificaties als gelezen", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon notificaties niet bijwerken",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"updated": updated,
+	})
+}
+
+// MarkAllRead markeert alle notificaties als gelezen
+// @Summary Alle notificaties als gelezen markeren
+// @Tags Inbox
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/inbox/read-all [post]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) MarkAllRead(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	updated, err := h.inbox.MarkAllRead(c.Context(), userID)
+	if err != nil {
+		logger.Error("Fout bij markeren notificaties als gelezen", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon notificaties niet bijwerken",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"updated": updated,
+	})
+}
+
+// Delete verwijdert een notificatie uit de inbox
+// @Summary Notificatie verwijderen
+// @Tags Inbox
+// @Produce json
+// @Param id path string true "Notificatie ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/inbox/{id} [delete]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) Delete(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	deleted, err := h.inbox.Delete(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		logger.Error("Fout bij verwijderen notificatie", "error", err, "user_id", userID, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon notificatie niet verwijderen",
+		})
+	}
+	if !deleted {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Notificatie niet gevonden",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Notificatie succesvol verwijderd",
+	})
+}
+
+// GetPreferences haalt de voorkeuren per type notificatie op
+// @Summary Notificatie voorkeuren
+// @Description Per type notificatie: in de inbox (in_app) en live doorsturen (push)
+// @Tags Inbox
+// @Produce json
+// @Success 200 {array} models.NotificationPreference
+// @Router /api/inbox/preferences [get]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) GetPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	preferences, err := h.inbox.Preferences(c.Context(), userID)
+	if err != nil {
+		logger.Error("Fout bij ophalen notificatie voorkeuren", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon voorkeuren niet ophalen",
+		})
+	}
+
+	return c.JSON(preferences)
+}
+
+// UpdatePreferences slaat voorkeuren per type notificatie op
+// @Summary Notificatie voorkeuren bijwerken
+// @Tags Inbox
+// @Accept json
+// @Produce json
+// @Param preferences body []models.NotificationPreference true "Voorkeuren per type"
+// @Success 200 {array} models.NotificationPreference
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/inbox/preferences [put]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) UpdatePreferences(c *fiber.Ctx) error {
+	var preferences []*models.NotificationPreference
+	if err := c.BodyParser(&preferences); err != nil || len(preferences) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	userID := c.Locals("userID").(string)
+	for _, preference := range preferences {
+		preference.UserID = userID
+		if err := h.inbox.SetPreference(c.Context(), preference); err != nil {
+			if errors.Is(err, services.ErrUnknownNotificationType) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("Onbekend type notificatie: %s", preference.Type),
+				})
+			}
+			logger.Error("Fout bij opslaan notificatie voorkeur", "error", err, "user_id", userID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Kon voorkeuren niet opslaan",
+			})
+		}
+	}
+
+	return h.GetPreferences(c)
+}
+
+// Stream stuurt nieuwe notificaties live door met Server-Sent Events
+// @Summary Live notificaties
+// @Description Server-Sent Events met de gebeurtenissen notification (nieuwe notificatie) en read (gelezen). Het token mag in de query staan.
+// @Tags Inbox
+// @Produce text/event-stream
+// @Param token query string false "JWT token, voor EventSource zonder headers"
+// @Router /api/inbox/stream [get]
+// @Security BearerAuth
+func (h *NotificationInboxHandler) Stream(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	unread, err := h.inbox.UnreadCount(c.Context(), userID)
+	if err != nil {
+		logger.Error("Fout bij tellen ongelezen notificaties", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon ongelezen notificaties niet tellen",
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	events, unsubscribe := h.inbox.Subscribe(userID)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		fmt.Fprintf(w, "event: ready\ndata: {\"unread\":%d}\n\n", unread)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(inboxKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+
+			// Een mislukte flush betekent dat de verbinding is gesloten
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PledgeHandler bevat het publieke sponsorformulier per deelnemer en het beheer van toezeggingen
+type PledgeHandler struct {
+	pledgeService     *services.PledgeService
+	rateLimiter       services.RateLimiterService
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewPledgeHandler maakt een nieuwe pledge handler
+func NewPledgeHandler(
+	pledgeService *services.PledgeService,
+	rateLimiter services.RateLimiterService,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *PledgeHandler {
+	return &PledgeHandler{
+		pledgeService:     pledgeService,
+		rateLimiter:       rateLimiter,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// updatePledgeStatusRequest is de body voor het bijwerken van de betaalstatus
+type updatePledgeStatusRequest struct {
+	Status           string   `json:"status"`
+	PaidAmount       *float64 `json:"paid_amount"`
+	PaymentReference string   `json:"payment_reference"`
+}
+
+// RegisterRoutes registreert de routes voor sponsortoezeggingen
+func (h *PledgeHandler) RegisterRoutes(app *fiber.App) {
+	// Publiek: het sponsorformulier dat een deelnemer met vrienden en familie deelt
+	app.Get("/api/pledges/participant/:id", h.GetParticipant)
+	app.Post("/api/pledges/participant/:id", RateLimitMiddleware(h.rateLimiter, "pledge"), h.CreatePledge)
+
+	// Beheer: betaalstatus bijhouden en de sponsors na afloop informeren
+	app.Get("/api/pledges",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "pledge", "read"),
+		h.ListPledges)
+	app.Put("/api/pledges/:id/status",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "pledge", "write"),
+		h.UpdateStatus)
+	app.Post("/api/pledges/participant/:id/finished",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "pledge", "write"),
+		h.NotifyFinished)
+}
+
+// pledgeError zet een fout uit de pledge service om naar een response
+func pledgeError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrPledgeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Toezegging niet gevonden",
+		})
+	case errors.Is(err, services.ErrInvalidPledge):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return stepsError(c, err, message)
+}
+
+// GetParticipant geeft de gegevens voor het sponsorformulier van een deelnemer
+// @Summary Deelnemer voor het sponsorformulier
+// @Description Geeft voornaam en initiaal, route en stappen van een deelnemer voor het publieke sponsorformulier
+// @Tags Pledges
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Success 200 {object} models.PledgeParticipant
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/pledges/participant/{id} [get]
+func (h *PledgeHandler) GetParticipant(c *fiber.Ctx) error {
+	participant, err := h.pledgeService.GetPublicParticipant(c.Params("id"))
+	if err != nil {
+		return pledgeError(c, err, "Kon deelnemer niet ophalen")
+	}
+	return c.JSON(participant)
+}
+
+// CreatePledge legt een toezegging van een sponsor vast
+// @Summary Deelnemer sponsoren
+// @Description Legt een toezegging vast (fixed, per_km of per_10k_steps) en stuurt de sponsor een bevestiging. Bij per_km en per_10k_steps kan een maximum worden opgegeven.
+// @Tags Pledges
+// @Accept json
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Param pledge body services.PledgeInput true "Toezegging"
+// @Success 201 {object} models.Pledge
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/pledges/participant/{id} [post]
+func (h *PledgeHandler) CreatePledge(c *fiber.Ctx) error {
+	var input services.PledgeInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	pledge, err := h.pledgeService.CreatePledge(c.Params("id"), &input)
+	if err != nil {
+		return pledgeError(c, err, "Kon toezegging niet opslaan")
+	}
+	return c.Status(fiber.StatusCreated).JSON(pledge)
+}
+
+// ListPledges haalt toezeggingen op voor het beheer
+// @Summary Toezeggingen ophalen
+// @Description Haalt toezeggingen op met het verwachte bedrag, nieuwste eerst
+// @Tags Pledges
+// @Produce json
+// @Param aanmelding_id query string false "Alleen voor deze deelnemer"
+// @Param status query string false "pending, paid of cancelled"
+// @Param limit query int false "Aantal (1-100, standaard 20)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/pledges [get]
+// @Security BearerAuth
+func (h *PledgeHandler) ListPledges(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	pledges, total, err := h.pledgeService.List(models.PledgeFilter{
+		AanmeldingID: c.Query("aanmelding_id"),
+		Status:       c.Query("status"),
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		return pledgeError(c, err, "Kon toezeggingen niet ophalen")
+	}
+
+	return c.JSON(fiber.Map{
+		"pledges": pledges,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// UpdateStatus werkt de betaalstatus van een toezegging bij
+// @Summary Betaalstatus bijwerken
+// @Description Zet een toezegging op pending, paid of cancelled. Bij paid wordt het betaalde bedrag vastgelegd, standaard het verwachte bedrag.
+// @Tags Pledges
+// @Accept json
+// @Produce json
+// @Param id path string true "Toezegging ID"
+// @Param request body updatePledgeStatusRequest true "Status"
+// @Success 200 {object} models.Pledge
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/pledges/{id}/status [put]
+// @Security BearerAuth
+func (h *PledgeHandler) UpdateStatus(c *fiber.Ctx) error {
+	var req updatePledgeStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige gegevens",
+		})
+	}
+
+	pledge, err := h.pledgeService.UpdateStatus(c.Params("id"), req.Status, req.PaidAmount, req.PaymentReference)
+	if err != nil {
+		return pledgeError(c, err, "Kon betaalstatus niet bijwerken")
+	}
+	logger.Info("Betaalstatus toezegging bijgewerkt", "pledge_id", pledge.ID, "status", pledge.Status, "user_id", c.Locals("userID"))
+	return c.JSON(pledge)
+}
+
+// NotifyFinished laat de sponsors van een deelnemer weten dat die de finish heeft gehaald
+// @Summary Sponsors informeren na afloop
+// @Description Stuurt elke sponsor van de deelnemer één keer een email met het bedrag dat de toezegging heeft opgeleverd
+// @Tags Pledges
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Success 200 {object} object{sent=int}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/pledges/participant/{id}/finished [post]
+// @Security BearerAuth
+func (h *PledgeHandler) NotifyFinished(c *fiber.Ctx) error {
+	sent, err := h.pledgeService.NotifyFinished(c.Params("id"))
+	if err != nil {
+		return pledgeError(c, err, "Kon sponsors niet informeren")
+	}
+	return c.JSON(fiber.Map{
+		"sent": sent,
+	})
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// queryEventYear leest het jaar van de editie uit de query; 0 betekent de meest recente editie
+func queryEventYear(c *fiber.Ctx) (int, error) {
+	value := c.Query("year")
+	if value == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year <= 0 {
+		return 0, errors.New("ongeldig jaar")
+	}
+	return year, nil
+}
+
+// paramEventYear leest het jaar van de editie uit het pad
+func paramEventYear(c *fiber.Ctx) (int, error) {
+	year, err := strconv.Atoi(c.Params("year"))
+	if err != nil || year <= 0 {
+		return 0, errors.New("ongeldig jaar")
+	}
+	return year, nil
+}
+
+// fundsError zet een fout uit de edities en route bedragen om naar een response
+func fundsError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrEventNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Editie niet gevonden",
+		})
+	case errors.Is(err, services.ErrRouteFundNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Route niet gevonden in deze editie",
+		})
+	case errors.Is(err, services.ErrInvalidEvent):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return stepsError(c, err, message)
+}
+
+// ListEvents haalt alle edities op (admin only)
+// @Summary Edities ophalen
+// @Description Haalt alle edities met fondsenpot en verdeelstrategie op, nieuwste eerst
+// @Tags Steps Admin
+// @Produce json
+// @Success 200 {array} models.Event
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/admin/events [get]
+// @Security BearerAuth
+func (h *StepsHandler) ListEvents(c *fiber.Ctx) error {
+	events, err := h.stepsService.ListEvents()
+	if err != nil {
+		return fundsError(c, err, "Kon edities niet ophalen")
+	}
+	return c.JSON(events)
+}
+
+// GetEvent haalt een editie op met haar route bedragen (admin only)
+// @Summary Editie ophalen
+// @Tags Steps Admin
+// @Produce json
+// @Param year path int true "Jaar van de editie"
+// @Success 200 {object} models.Event
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/admin/events/{year} [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetEvent(c *fiber.Ctx) error {
+	year, err := paramEventYear(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
+		})
+	}
+
+	event, err := h.stepsService.GetEvent(year)
+	if err != nil {
+		return fundsError(c, err, "Kon editie niet ophalen")
+	}
+	routeFunds, err := h.stepsService.GetRouteFunds(year)
+	if err != nil {
+		return fundsError(c, err, "Kon editie niet ophalen")
+	}
+	event.RouteFunds = make([]models.RouteFund, 0, len(routeFunds))
+	for _, routeFund := range routeFunds {
+		event.RouteFunds = append(event.RouteFunds, *routeFund)
+	}
+	return c.JSON(event)
+}
+
+// CreateEvent maakt een nieuwe editie aan (admin only)
+// @Summary Editie aanmaken
+// @Description Maakt een editie aan met fondsenpot, verdeelstrategie (equal, participants of steps) en optioneel de routes met hun bedragen
+// @Tags Steps Admin
+// @Accept json
+// @Produce json
+// @Param request body object{jaar=int,naam=string,total_funds=int,allocation_strategy=string,route_funds=[]models.RouteFundRequest} true "Editie"
+// @Success 201 {object} models.Event
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/admin/events [post]
+// @Security BearerAuth
+func (h *StepsHandler) CreateEvent(c *fiber.Ctx) error {
+	var event models.Event
+	if err := c.BodyParser(&event); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request data",
+		})
+	}
+
+	if err := h.stepsService.CreateEvent(&event); err != nil {
+		return fundsError(c, err, "Kon editie niet aanmaken")
+	}
+	return c.Status(fiber.StatusCreated).JSON(event)
+}
+
+// UpdateEvent werkt een editie bij (admin only)
+// @Summary Editie bijwerken
+// @Description Werkt de naam, fondsenpot en verdeelstrategie van een editie bij; routes gaan via /api/steps/admin/route-funds?year=
+// @Tags Steps Admin
+// @Accept json
+// @Produce json
+// @Param year path int true "Jaar van de editie"
+// @Param request body object{naam=string,total_funds=int,allocation_strategy=string} true "Editie"
+// @Success 200 {object} models.Event
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/admin/events/{year} [put]
+// @Security BearerAuth
+func (h *StepsHandler) UpdateEvent(c *fiber.Ctx) error {
+	year, err := paramEventYear(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
+		})
+	}
+
+	var update models.Event
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request data",
+		})
+	}
+
+	event, err := h.stepsService.UpdateEvent(year, &update)
+	if err != nil {
+		return fundsError(c, err, "Kon editie niet bijwerken")
+	}
+	return c.JSON(event)
+}
+
+// DeleteEvent verwijdert een editie met haar route bedragen (admin only)
+// @Summary Editie verwijderen
+// @Tags Steps Admin
+// @Param year path int true "Jaar van de editie"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/admin/events/{year} [delete]
+// @Security BearerAuth
+func (h *StepsHandler) DeleteEvent(c *fiber.Ctx) error {
+	year, err := paramEventYear(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
+		})
+	}
+
+	if err := h.stepsService.DeleteEvent(year); err != nil {
+		return fundsError(c, err, "Kon editie niet verwijderen")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -4,7 +4,10 @@ import (
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
 	"dklautomationgo/services"
+	"errors"
+	"io"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -14,6 +17,7 @@ type StepsHandler struct {
 	stepsService      *services.StepsService
 	authService       services.AuthService
 	permissionService services.PermissionService
+	pledgeService     *services.PledgeService
 }
 
 // NewStepsHandler maakt een nieuwe steps handler
@@ -29,21 +33,49 @@ func NewStepsHandler(
 	}
 }
 
+// SetPledgeService zet de sponsortotalen op het dashboard en de sponsorlijst van de deelnemer aan
+func (h *StepsHandler) SetPledgeService(pledgeService *services.PledgeService) {
+	h.pledgeService = pledgeService
+}
+
 // RegisterRoutes registreert de routes voor stappen beheer
 func (h *StepsHandler) RegisterRoutes(app *fiber.App) {
 	// Groep voor stappen routes
 	stepsGroup := app.Group("/api")
 
+	// Klassementen, teams en opt-in voor het openbare klassement
+	h.registerLeaderboardRoutes(stepsGroup)
+
 	// POST /api/steps - Update stappen voor ingelogde deelnemer (geen ID nodig!)
-	// POST /api/steps/:id - Update stappen voor specifieke deelnemer (admin/staff)
+	// POST /api/steps/:id - Update stappen voor specifieke deelnemer (eigen deelnemer of steps:write_all)
 	stepsGroup.Post("/steps", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write"), h.UpdateSteps)
+
+	// POST /api/steps/import - Activiteitenbestand (GPX, TCX, FIT, CSV) importeren voor ingelogde deelnemer
+	// POST /api/steps/:id/import - Importeren voor specifieke deelnemer (eigen deelnemer of steps:write_all)
+	stepsGroup.Post("/steps/import", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write"), h.ImportActivities)
+	stepsGroup.Post("/steps/:id/import", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write"), h.ImportActivities)
+
 	stepsGroup.Post("/steps/:id", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write"), h.UpdateSteps)
 
+	// Correcties en regels uit het grootboek (admin/staff)
+	stepsGroup.Post("/steps/:id/corrections", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write_all"), h.CreateCorrection)
+	stepsGroup.Get("/steps/:id/entries", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read_all"), h.GetEntries)
+
 	// GET /api/participant/dashboard - Dashboard voor ingelogde deelnemer (geen ID nodig!)
-	// GET /api/participant/:id/dashboard - Dashboard voor specifieke deelnemer (admin/staff)
+	// GET /api/participant/:id/dashboard - Dashboard voor specifieke deelnemer (eigen deelnemer of steps:read_all)
 	stepsGroup.Get("/participant/dashboard", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetParticipantDashboard)
 	stepsGroup.Get("/participant/:id/dashboard", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetParticipantDashboard)
 
+	// GET /api/participant/history - Stappen per dag voor ingelogde deelnemer
+	// GET /api/participant/:id/history - Stappen per dag voor specifieke deelnemer (eigen deelnemer of steps:read_all)
+	stepsGroup.Get("/participant/history", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetHistory)
+	stepsGroup.Get("/participant/:id/history", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetHistory)
+
+	// GET /api/participant/pledges - Sponsors en sponsortotalen voor ingelogde deelnemer
+	// GET /api/participant/:id/pledges - Voor specifieke deelnemer (eigen deelnemer of steps:read_all)
+	stepsGroup.Get("/participant/pledges", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetParticipantPledges)
+	stepsGroup.Get("/participant/:id/pledges", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.GetParticipantPledges)
+
 	// GET /api/total-steps - Totaal aantal stappen (alle deelnemers mogen dit zien)
 	stepsGroup.Get("/total-steps", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read_total"), h.GetTotalSteps)
 
@@ -56,16 +88,76 @@ func (h *StepsHandler) RegisterRoutes(app *fiber.App) {
 	adminGroup.Post("/route-funds", h.CreateRouteFund)
 	adminGroup.Put("/route-funds/:route", h.UpdateRouteFund)
 	adminGroup.Delete("/route-funds/:route", h.DeleteRouteFund)
+
+	// Edities met fondsenpot en verdeelstrategie (admin)
+	eventsGroup := adminGroup.Group("/events", PermissionMiddleware(h.permissionService, "steps", "manage"))
+	eventsGroup.Get("/", h.ListEvents)
+	eventsGroup.Post("/", h.CreateEvent)
+	eventsGroup.Get("/:year", h.GetEvent)
+	eventsGroup.Put("/:year", h.UpdateEvent)
+	eventsGroup.Delete("/:year", h.DeleteEvent)
+}
+
+// errStepsForbidden geeft aan dat de gebruiker een andere deelnemer probeert te benaderen
+var errStepsForbidden = errors.New("geen toegang tot deze deelnemer")
+
+// resolveParticipant bepaalt voor welke deelnemer een verzoek is. Zonder :id is dat de deelnemer
+// van de ingelogde gebruiker. Met :id mag het de eigen deelnemer zijn; voor een andere deelnemer
+// is de _all variant van de permissie nodig. own geeft aan of het de eigen deelnemer is.
+func (h *StepsHandler) resolveParticipant(c *fiber.Ctx, action string) (participant *models.Aanmelding, own bool, err error) {
+	userID, _ := c.Locals("userID").(string)
+
+	id := c.Params("id")
+	if id == "" {
+		participant, err = h.stepsService.GetParticipantByUserID(userID)
+		return participant, true, err
+	}
+
+	participant, err = h.stepsService.GetParticipant(id)
+	if err != nil {
+		return nil, false, err
+	}
+	if participant.GebruikerID != nil && *participant.GebruikerID == userID {
+		return participant, true, nil
+	}
+	if !h.permissionService.HasPermission(c.Context(), userID, "steps", action+"_all") {
+		return nil, false, errStepsForbidden
+	}
+	return participant, false, nil
+}
+
+// stepsError zet een fout uit de steps service om naar een response
+func stepsError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrParticipantNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Deelnemer niet gevonden",
+		})
+	case errors.Is(err, errStepsForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Geen toegang tot deze deelnemer",
+		})
+	case errors.Is(err, services.ErrCorrectionReasonRequired), errors.Is(err, services.ErrInvalidStepsEntry),
+		errors.Is(err, services.ErrUnsupportedActivityFile):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.Error(message, "error", err, "path", c.Path())
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
 }
 
 // UpdateSteps werkt stappen bij voor een deelnemer
 // @Summary Stappen bijwerken voor deelnemer
-// @Description Voegt stappen toe aan een deelnemer (delta)
+// @Description Voegt stappen toe aan een deelnemer (delta) als regel in het stappen grootboek. Een herhaald verzoek met dezelfde Idempotency-Key telt maar één keer.
 // @Tags Steps
 // @Accept json
 // @Produce json
 // @Param id path string true "Deelnemer ID"
-// @Param request body object{steps=int} true "Stappen delta"
+// @Param Idempotency-Key header string false "Unieke sleutel per invoer"
+// @Param request body object{steps=int,recorded_at=string,idempotency_key=string} true "Stappen delta"
 // @Success 200 {object} models.Aanmelding
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -75,12 +167,13 @@ func (h *StepsHandler) RegisterRoutes(app *fiber.App) {
 // @Router /api/steps/{id} [post]
 // @Security BearerAuth
 func (h *StepsHandler) UpdateSteps(c *fiber.Ctx) error {
-	// Probeer eerst gebruiker ID uit de context (voor ingelogde gebruikers)
-	userID, ok := c.Locals("userID").(string)
+	userID, _ := c.Locals("userID").(string)
 
 	// Parse request body
 	var req struct {
-		Steps int `json:"steps"`
+		Steps          int        `json:"steps"`
+		RecordedAt     *time.Time `json:"recorded_at"`
+		IdempotencyKey string     `json:"idempotency_key"`
 	}
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -88,36 +181,219 @@ func (h *StepsHandler) UpdateSteps(c *fiber.Ctx) error {
 		})
 	}
 
-	// Als er een gebruiker is ingelogd, update dan hun stappen
-	if ok && userID != "" {
-		participant, err := h.stepsService.UpdateStepsByUserID(userID, req.Steps)
-		if err != nil {
-			logger.Error("Fout bij bijwerken stappen", "error", err, "user_id", userID)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Kon stappen niet bijwerken",
-			})
-		}
-		return c.JSON(participant)
+	participant, own, err := h.resolveParticipant(c, "write")
+	if err != nil {
+		return stepsError(c, err, "Kon stappen niet bijwerken")
 	}
 
-	// Fallback: gebruik ID uit URL parameter (voor admin/staff toegang)
-	id := c.Params("id")
-	if id == "" {
+	opts := services.StepEntryOptions{
+		Source:         models.StepSourceApp,
+		EnteredBy:      userID,
+		IdempotencyKey: req.IdempotencyKey,
+		RecordedAt:     req.RecordedAt,
+	}
+	if key := c.Get("Idempotency-Key"); key != "" {
+		opts.IdempotencyKey = key
+	}
+	if !own {
+		opts.Source = models.StepSourceAdmin
+	}
+
+	participant, err = h.stepsService.UpdateSteps(participant.ID, req.Steps, opts)
+	if err != nil {
+		return stepsError(c, err, "Kon stappen niet bijwerken")
+	}
+
+	return c.JSON(participant)
+}
+
+// ImportActivities importeert stappen uit een activiteitenbestand
+// @Summary Activiteitenbestand importeren
+// @Description Leest een GPX, TCX of FIT bestand van een horloge of een CSV export van Google Fit of een gezondheidsapp en boekt elke activiteit als stappen. Een bestand dat opnieuw wordt geüpload telt niet dubbel.
+// @Tags Steps
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string false "Deelnemer ID"
+// @Param file formData file true "Activiteitenbestand (max 4MB)"
+// @Success 200 {object} services.StepsImportResult
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/{id}/import [post]
+// @Security BearerAuth
+func (h *StepsHandler) ImportActivities(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+
+	file, err := c.FormFile("file")
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "ID is verplicht",
+			"error": "Geen bestand ontvangen",
+		})
+	}
+	if file.Size > services.MaxStepsImportSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": "Bestand is te groot, maximaal 4MB",
 		})
 	}
 
-	// Update stappen via aanmelding ID
-	participant, err := h.stepsService.UpdateSteps(id, req.Steps)
+	src, err := file.Open()
 	if err != nil {
-		logger.Error("Fout bij bijwerken stappen", "error", err, "id", id)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon stappen niet bijwerken",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Kon bestand niet openen",
+		})
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, services.MaxStepsImportSize))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Kon bestand niet lezen",
 		})
 	}
 
-	return c.JSON(participant)
+	participant, _, err := h.resolveParticipant(c, "write")
+	if err != nil {
+		return stepsError(c, err, "Kon activiteiten niet importeren")
+	}
+
+	result, err := h.stepsService.ImportActivities(participant.ID, file.Filename, data, userID)
+	if err != nil {
+		return stepsError(c, err, "Kon activiteiten niet importeren")
+	}
+
+	return c.JSON(result)
+}
+
+// CreateCorrection corrigeert de stappen van een deelnemer
+// @Summary Stappen corrigeren
+// @Description Voegt een correctie toe aan het stappen grootboek van een deelnemer; een reden is verplicht
+// @Tags Steps Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Param request body object{steps=int,reason=string,recorded_at=string} true "Correctie"
+// @Success 201 {object} models.Aanmelding
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/{id}/corrections [post]
+// @Security BearerAuth
+func (h *StepsHandler) CreateCorrection(c *fiber.Ctx) error {
+	userID, _ := c.Locals("userID").(string)
+
+	var req struct {
+		Steps      int        `json:"steps"`
+		Reason     string     `json:"reason"`
+		RecordedAt *time.Time `json:"recorded_at"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request data",
+		})
+	}
+
+	participant, err := h.stepsService.RecordCorrection(c.Params("id"), req.Steps, req.Reason, userID, req.RecordedAt)
+	if err != nil {
+		return stepsError(c, err, "Kon correctie niet opslaan")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(participant)
+}
+
+// GetEntries haalt de regels uit het stappen grootboek van een deelnemer op
+// @Summary Stappen regels van deelnemer
+// @Description Haalt de regels uit het stappen grootboek op, nieuwste eerst, met bron en invoerder
+// @Tags Steps Admin
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Param limit query int false "Aantal regels (standaard 50, maximaal 200)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} models.StepEntry
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/{id}/entries [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetEntries(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, err := h.stepsService.ListEntries(c.Params("id"), limit, offset)
+	if err != nil {
+		return stepsError(c, err, "Kon stappen regels niet ophalen")
+	}
+
+	return c.JSON(entries)
+}
+
+// GetHistory haalt de stappen per dag op voor een deelnemer
+// @Summary Stappen geschiedenis per dag
+// @Description Haalt de stappen per dag op voor het dashboard; dagen zonder stappen staan er met 0 in
+// @Tags Steps
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Param from query string false "Eerste dag (YYYY-MM-DD, standaard 30 dagen terug)"
+// @Param to query string false "Laatste dag (YYYY-MM-DD, standaard vandaag)"
+// @Success 200 {object} object{days=[]models.StepDayTotal,total=int}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/participant/{id}/history [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetHistory(c *fiber.Ctx) error {
+	from, err := h.queryDay(c, "from")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige datum voor from, gebruik YYYY-MM-DD",
+		})
+	}
+	to, err := h.queryDay(c, "to")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige datum voor to, gebruik YYYY-MM-DD",
+		})
+	}
+
+	participant, _, err := h.resolveParticipant(c, "read")
+	if err != nil {
+		return stepsError(c, err, "Kon stappen geschiedenis niet ophalen")
+	}
+
+	days, err := h.stepsService.GetHistory(participant.ID, from, to)
+	if err != nil {
+		return stepsError(c, err, "Kon stappen geschiedenis niet ophalen")
+	}
+
+	return c.JSON(fiber.Map{
+		"days":  days,
+		"total": participant.Steps,
+	})
+}
+
+// queryDay leest een optionele dag (YYYY-MM-DD) uit de query
+func (h *StepsHandler) queryDay(c *fiber.Ctx, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	day, err := h.stepsService.ParseDay(value)
+	if err != nil {
+		return nil, err
+	}
+	return &day, nil
 }
 
 // GetParticipantDashboard haalt dashboard data op voor een deelnemer
@@ -136,51 +412,67 @@ func (h *StepsHandler) UpdateSteps(c *fiber.Ctx) error {
 // @Router /api/participant/{id}/dashboard [get]
 // @Security BearerAuth
 func (h *StepsHandler) GetParticipantDashboard(c *fiber.Ctx) error {
-	// Probeer eerst gebruiker ID uit de context (voor ingelogde gebruikers)
-	userID, ok := c.Locals("userID").(string)
+	participant, _, err := h.resolveParticipant(c, "read")
+	if err != nil {
+		return stepsError(c, err, "Kon dashboard data niet ophalen")
+	}
 
-	// Als er een gebruiker is ingelogd, gebruik dan hun dashboard
-	if ok && userID != "" {
-		participant, allocatedFunds, err := h.stepsService.GetParticipantDashboardByUserID(userID)
+	milestones, err := h.stepsService.GetMilestones(participant.ID)
+	if err != nil {
+		return stepsError(c, err, "Kon dashboard data niet ophalen")
+	}
+
+	dashboard := fiber.Map{
+		"steps":                participant.Steps,
+		"route":                participant.Afstand,
+		"allocatedFunds":       h.stepsService.CalculateAllocatedFunds(participant),
+		"naam":                 participant.Naam,
+		"email":                participant.Email,
+		"milestones":           milestones,
+		"leaderboardZichtbaar": participant.LeaderboardZichtbaar,
+	}
+	if h.pledgeService != nil {
+		sponsoring, err := h.pledgeService.Summary(participant)
 		if err != nil {
-			logger.Error("Fout bij ophalen dashboard", "error", err, "id", userID)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Kon dashboard data niet ophalen",
-			})
+			return stepsError(c, err, "Kon dashboard data niet ophalen")
 		}
-
-		return c.JSON(fiber.Map{
-			"steps":          participant.Steps,
-			"route":          participant.Afstand,
-			"allocatedFunds": allocatedFunds,
-			"naam":           participant.Naam,
-			"email":          participant.Email,
-		})
+		dashboard["sponsoring"] = sponsoring
 	}
+	return c.JSON(dashboard)
+}
 
-	// Fallback: gebruik ID uit URL parameter (voor admin/staff toegang)
-	id := c.Params("id")
-	if id == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "ID is verplicht",
+// GetParticipantPledges haalt de sponsors van een deelnemer op
+// @Summary Sponsors van een deelnemer
+// @Description Haalt de toezeggingen met verwacht bedrag en betaalstatus op, met de totalen. De deelnemer zelf ziet geen emailadressen en geen namen van anonieme sponsors.
+// @Tags Steps
+// @Produce json
+// @Param id path string true "Deelnemer ID"
+// @Success 200 {object} object{pledges=[]models.Pledge,summary=models.PledgeSummary}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/participant/{id}/pledges [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetParticipantPledges(c *fiber.Ctx) error {
+	if h.pledgeService == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Sponsoring is niet geconfigureerd",
 		})
 	}
-
-	// Haal dashboard data op
-	participant, allocatedFunds, err := h.stepsService.GetParticipantDashboard(id)
+	participant, own, err := h.resolveParticipant(c, "read")
 	if err != nil {
-		logger.Error("Fout bij ophalen dashboard", "error", err, "id", id)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon dashboard data niet ophalen",
-		})
+		return stepsError(c, err, "Kon sponsors niet ophalen")
 	}
 
+	pledges, summary, err := h.pledgeService.ListByParticipant(participant.ID, own)
+	if err != nil {
+		return stepsError(c, err, "Kon sponsors niet ophalen")
+	}
 	return c.JSON(fiber.Map{
-		"steps":          participant.Steps,
-		"route":          participant.Afstand,
-		"allocatedFunds": allocatedFunds,
-		"naam":           participant.Naam,
-		"email":          participant.Email,
+		"pledges": pledges,
+		"summary": summary,
 	})
 }
 
@@ -223,38 +515,57 @@ func (h *StepsHandler) GetTotalSteps(c *fiber.Ctx) error {
 
 // GetFundsDistribution haalt fondsverdeling op
 // @Summary Fondsverdeling
-// @Description Haalt verdeling van fondsen over routes op
+// @Description Berekent de verdeling van de fondsenpot van een editie over de routes volgens de verdeelstrategie van die editie (equal, participants of steps)
 // @Tags Steps
 // @Accept json
 // @Produce json
-// @Success 200 {object} object{totalX=int,routes=map[string]int}
+// @Param year query int false "Jaar van de editie (standaard de meest recente editie)"
+// @Success 200 {object} object{event_id=string,jaar=int,naam=string,total_funds=int,strategy=string,configured_strategy=string,breakdown=[]models.RouteAllocation,totalX=int,routes=map[string]int}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/funds-distribution [get]
 // @Security BearerAuth
 func (h *StepsHandler) GetFundsDistribution(c *fiber.Ctx) error {
-	// Haal fondsverdeling op (proportioneel)
-	distribution, totalX, err := h.stepsService.GetFundsDistributionProportional()
+	year, err := queryEventYear(c)
 	if err != nil {
-		logger.Error("Fout bij ophalen fondsverdeling", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon fondsverdeling niet ophalen",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
 		})
 	}
 
+	distribution, err := h.stepsService.GetFundsDistribution(year)
+	if err != nil {
+		return fundsError(c, err, "Kon fondsverdeling niet ophalen")
+	}
+
+	// totalX en routes blijven bestaan voor clients van voor de edities
+	routes := make(map[string]int, len(distribution.Routes))
+	for _, route := range distribution.Routes {
+		routes[route.Route] = route.Allocated
+	}
 	return c.JSON(fiber.Map{
-		"totalX": totalX,
-		"routes": distribution,
+		"event_id":            distribution.EventID,
+		"jaar":                distribution.Jaar,
+		"naam":                distribution.Naam,
+		"total_funds":         distribution.TotalFunds,
+		"strategy":            distribution.Strategy,
+		"configured_strategy": distribution.ConfiguredStrategy,
+		"breakdown":           distribution.Routes,
+		"totalX":              distribution.TotalFunds,
+		"routes":              routes,
 	})
 }
 
 // GetRouteFunds haalt alle route fondsallocaties op (admin only)
 // @Summary Route fondsallocaties ophalen
-// @Description Haalt alle route fondsallocaties op voor beheer
+// @Description Haalt alle route fondsallocaties van een editie op voor beheer
 // @Tags Steps Admin
 // @Accept json
 // @Produce json
+// @Param year query int false "Jaar van de editie (standaard de meest recente editie)"
 // @Success 200 {array} models.RouteFund
 // @Failure 401 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
@@ -262,23 +573,28 @@ func (h *StepsHandler) GetFundsDistribution(c *fiber.Ctx) error {
 // @Router /api/steps/admin/route-funds [get]
 // @Security BearerAuth
 func (h *StepsHandler) GetRouteFunds(c *fiber.Ctx) error {
-	routeFunds, err := h.stepsService.GetRouteFunds()
+	year, err := queryEventYear(c)
 	if err != nil {
-		logger.Error("Fout bij ophalen route funds", "error", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon route funds niet ophalen",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
 		})
 	}
 
+	routeFunds, err := h.stepsService.GetRouteFunds(year)
+	if err != nil {
+		return fundsError(c, err, "Kon route funds niet ophalen")
+	}
+
 	return c.JSON(routeFunds)
 }
 
 // CreateRouteFund maakt een nieuwe route fondsallocatie aan (admin only)
 // @Summary Route fondsallocatie aanmaken
-// @Description Maakt een nieuwe route fondsallocatie aan
+// @Description Voegt een route met fondsallocatie toe aan een editie
 // @Tags Steps Admin
 // @Accept json
 // @Produce json
+// @Param year query int false "Jaar van de editie (standaard de meest recente editie)"
 // @Param request body models.RouteFundRequest true "Route fund data"
 // @Success 201 {object} models.RouteFund
 // @Failure 400 {object} map[string]interface{}
@@ -309,24 +625,29 @@ func (h *StepsHandler) CreateRouteFund(c *fiber.Ctx) error {
 		})
 	}
 
-	routeFund, err := h.stepsService.CreateRouteFund(req.Route, req.Amount)
+	year, err := queryEventYear(c)
 	if err != nil {
-		logger.Error("Fout bij aanmaken route fund", "error", err, "route", req.Route)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon route fund niet aanmaken",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
 		})
 	}
 
+	routeFund, err := h.stepsService.CreateRouteFund(year, req.Route, req.Amount)
+	if err != nil {
+		return fundsError(c, err, "Kon route fund niet aanmaken")
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(routeFund)
 }
 
 // UpdateRouteFund werkt een route fondsallocatie bij (admin only)
 // @Summary Route fondsallocatie bijwerken
-// @Description Werkt een bestaande route fondsallocatie bij
+// @Description Werkt een bestaande route fondsallocatie van een editie bij
 // @Tags Steps Admin
 // @Accept json
 // @Produce json
 // @Param route path string true "Route naam"
+// @Param year query int false "Jaar van de editie (standaard de meest recente editie)"
 // @Param request body models.RouteFundRequest true "Route fund data"
 // @Success 200 {object} models.RouteFund
 // @Failure 400 {object} map[string]interface{}
@@ -357,24 +678,29 @@ func (h *StepsHandler) UpdateRouteFund(c *fiber.Ctx) error {
 		})
 	}
 
-	routeFund, err := h.stepsService.UpdateRouteFund(route, req.Amount)
+	year, err := queryEventYear(c)
 	if err != nil {
-		logger.Error("Fout bij bijwerken route fund", "error", err, "route", route)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon route fund niet bijwerken",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
 		})
 	}
 
+	routeFund, err := h.stepsService.UpdateRouteFund(year, route, req.Amount)
+	if err != nil {
+		return fundsError(c, err, "Kon route fund niet bijwerken")
+	}
+
 	return c.JSON(routeFund)
 }
 
 // DeleteRouteFund verwijdert een route fondsallocatie (admin only)
 // @Summary Route fondsallocatie verwijderen
-// @Description Verwijdert een route fondsallocatie
+// @Description Verwijdert een route fondsallocatie uit een editie
 // @Tags Steps Admin
 // @Accept json
 // @Produce json
 // @Param route path string true "Route naam"
+// @Param year query int false "Jaar van de editie (standaard de meest recente editie)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
@@ -391,13 +717,17 @@ func (h *StepsHandler) DeleteRouteFund(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.stepsService.DeleteRouteFund(route); err != nil {
-		logger.Error("Fout bij verwijderen route fund", "error", err, "route", route)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Kon route fund niet verwijderen",
+	year, err := queryEventYear(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldig jaar",
 		})
 	}
 
+	if err := h.stepsService.DeleteRouteFund(year, route); err != nil {
+		return fundsError(c, err, "Kon route fund niet verwijderen")
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Route fund succesvol verwijderd",
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerLeaderboardRoutes registreert de routes voor klassementen, teams en de opt-in voor het
+// openbare klassement. Moet vóór /steps/:id worden aangeroepen zodat /steps/teams niet als
+// deelnemer ID wordt gezien.
+func (h *StepsHandler) registerLeaderboardRoutes(stepsGroup fiber.Router) {
+	// Openbare, geanonimiseerde klassementen voor de website
+	stepsGroup.Get("/leaderboard", h.GetPublicLeaderboard)
+	stepsGroup.Get("/leaderboard/teams", h.GetPublicTeamLeaderboard)
+
+	// Volledige klassementen met namen en ID's (admin/staff)
+	stepsGroup.Get("/steps/leaderboard", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read_all"), h.GetLeaderboard)
+	stepsGroup.Get("/steps/leaderboard/teams", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read_all"), h.GetTeamLeaderboard)
+
+	// Teambeheer
+	stepsGroup.Get("/steps/teams", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "read"), h.ListTeams)
+	teamsGroup := stepsGroup.Group("/steps/teams", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "manage"))
+	teamsGroup.Post("/", h.CreateTeam)
+	teamsGroup.Put("/:id", h.UpdateTeam)
+	teamsGroup.Delete("/:id", h.DeleteTeam)
+	teamsGroup.Get("/:id/members", h.ListTeamMembers)
+	teamsGroup.Post("/:id/members", h.AddTeamMembers)
+	teamsGroup.Delete("/:id/members/:aanmeldingId", h.RemoveTeamMember)
+
+	// PUT /api/participant/leaderboard - Opt-in/opt-out voor het openbare klassement
+	stepsGroup.Put("/participant/leaderboard", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "steps", "write"), h.UpdateLeaderboardVisibility)
+}
+
+// leaderboardFilter leest jaar, route, periode, team, sortering en limiet uit de query
+func (h *StepsHandler) leaderboardFilter(c *fiber.Ctx) (models.LeaderboardFilter, error) {
+	filter := h.stepsService.LeaderboardFilter(c.QueryInt("year", time.Now().Year()))
+	filter.Route = c.Query("route")
+	filter.TeamID = c.Query("team_id")
+	filter.SortByAverage = c.Query("sort") == "average"
+
+	limit := c.QueryInt("limit", services.DefaultLeaderboardLimit)
+	if limit <= 0 || limit > services.MaxLeaderboardLimit {
+		limit = services.DefaultLeaderboardLimit
+	}
+	filter.Limit = limit
+
+	from, err := h.queryDay(c, "from")
+	if err != nil {
+		return filter, err
+	}
+	to, err := h.queryDay(c, "to")
+	if err != nil {
+		return filter, err
+	}
+	filter.From = from
+	if to != nil {
+		// to is inclusief; het klassement telt tot het begin van de volgende dag
+		end := to.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	return filter, nil
+}
+
+// leaderboardError zet een fout uit de klassementen of teams om naar een response
+func leaderboardError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrTeamNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Team niet gevonden",
+		})
+	case errors.Is(err, services.ErrInvalidTeam):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrLeaderboardsDisabled):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Klassementen zijn niet beschikbaar",
+		})
+	}
+	return stepsError(c, err, message)
+}
+
+// GetPublicLeaderboard geeft het openbare klassement van deelnemers
+// @Summary Openbaar klassement
+// @Description Geeft de deelnemers met de meeste stappen. Alleen deelnemers die daarvoor kiezen staan er met voornaam en initiaal op; de rest is anoniem.
+// @Tags Steps
+// @Produce json
+// @Param year query int false "Evenementjaar (standaard huidig jaar)"
+// @Param route query string false "Afstand, bijvoorbeeld 10 KM"
+// @Param from query string false "Eerste dag (YYYY-MM-DD)"
+// @Param to query string false "Laatste dag (YYYY-MM-DD)"
+// @Param limit query int false "Aantal plaatsen (standaard 10, max 100)"
+// @Success 200 {object} object{entries=[]models.LeaderboardEntry}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/leaderboard [get]
+func (h *StepsHandler) GetPublicLeaderboard(c *fiber.Ctx) error {
+	return h.leaderboard(c, true)
+}
+
+// GetLeaderboard geeft het volledige klassement van deelnemers
+// @Summary Klassement (admin)
+// @Description Geeft de deelnemers met de meeste stappen met volledige naam, aanmelding ID en team.
+// @Tags Steps
+// @Produce json
+// @Param year query int false "Evenementjaar (standaard huidig jaar)"
+// @Param route query string false "Afstand, bijvoorbeeld 10 KM"
+// @Param team_id query string false "Alleen leden van dit team"
+// @Param from query string false "Eerste dag (YYYY-MM-DD)"
+// @Param to query string false "Laatste dag (YYYY-MM-DD)"
+// @Param limit query int false "Aantal plaatsen (standaard 10, max 100)"
+// @Success 200 {object} object{entries=[]models.LeaderboardEntry}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/leaderboard [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetLeaderboard(c *fiber.Ctx) error {
+	return h.leaderboard(c, false)
+}
+
+func (h *StepsHandler) leaderboard(c *fiber.Ctx, public bool) error {
+	filter, err := h.leaderboardFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige datum, gebruik YYYY-MM-DD",
+		})
+	}
+	if public {
+		filter.TeamID = ""
+	}
+
+	entries, err := h.stepsService.Leaderboard(filter, public)
+	if err != nil {
+		return leaderboardError(c, err, "Kon klassement niet ophalen")
+	}
+	return c.JSON(fiber.Map{
+		"entries": entries,
+	})
+}
+
+// GetPublicTeamLeaderboard geeft het openbare klassement van teams
+// @Summary Openbaar teamklassement
+// @Description Geeft de actieve teams met de meeste stappen, op totaal of met sort=average op gemiddelde per lid.
+// @Tags Steps
+// @Produce json
+// @Param year query int false "Evenementjaar (standaard huidig jaar)"
+// @Param route query string false "Afstand, bijvoorbeeld 10 KM"
+// @Param sort query string false "total (standaard) of average"
+// @Param limit query int false "Aantal plaatsen (standaard 10, max 100)"
+// @Success 200 {object} object{entries=[]models.TeamLeaderboardEntry}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/leaderboard/teams [get]
+func (h *StepsHandler) GetPublicTeamLeaderboard(c *fiber.Ctx) error {
+	return h.GetTeamLeaderboard(c)
+}
+
+// GetTeamLeaderboard geeft het klassement van teams
+// @Summary Teamklassement (admin)
+// @Description Geeft de actieve teams met de meeste stappen.
+// @Tags Steps
+// @Produce json
+// @Success 200 {object} object{entries=[]models.TeamLeaderboardEntry}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/leaderboard/teams [get]
+// @Security BearerAuth
+func (h *StepsHandler) GetTeamLeaderboard(c *fiber.Ctx) error {
+	filter, err := h.leaderboardFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige datum, gebruik YYYY-MM-DD",
+		})
+	}
+	filter.TeamID = ""
+
+	entries, err := h.stepsService.TeamLeaderboard(filter)
+	if err != nil {
+		return leaderboardError(c, err, "Kon teamklassement niet ophalen")
+	}
+	return c.JSON(fiber.Map{
+		"entries": entries,
+	})
+}
+
+// UpdateLeaderboardVisibility legt vast of de ingelogde deelnemer met naam op het openbare klassement wil
+// @Summary Zichtbaarheid op klassement
+// @Description Opt-in of opt-out voor vermelding met voornaam en initiaal op het openbare klassement.
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param request body object{zichtbaar=bool} true "Zichtbaarheid"
+// @Success 200 {object} object{zichtbaar=bool}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/participant/leaderboard [put]
+// @Security BearerAuth
+func (h *StepsHandler) UpdateLeaderboardVisibility(c *fiber.Ctx) error {
+	var req struct {
+		Zichtbaar *bool `json:"zichtbaar"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Zichtbaar == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "zichtbaar (true of false) is verplicht",
+		})
+	}
+
+	participant, _, err := h.resolveParticipant(c, "write")
+	if err != nil {
+		return stepsError(c, err, "Kon zichtbaarheid niet bijwerken")
+	}
+	if err := h.stepsService.SetLeaderboardVisibility(participant.ID, *req.Zichtbaar); err != nil {
+		return leaderboardError(c, err, "Kon zichtbaarheid niet bijwerken")
+	}
+	return c.JSON(fiber.Map{
+		"zichtbaar": *req.Zichtbaar,
+	})
+}
+
+// ListTeams haalt alle teams op
+// @Summary Teams ophalen
+// @Tags Steps
+// @Produce json
+// @Success 200 {array} models.StepTeam
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams [get]
+// @Security BearerAuth
+func (h *StepsHandler) ListTeams(c *fiber.Ctx) error {
+	teams, err := h.stepsService.ListTeams()
+	if err != nil {
+		return leaderboardError(c, err, "Kon teams niet ophalen")
+	}
+	return c.JSON(teams)
+}
+
+// CreateTeam maakt een nieuw team aan
+// @Summary Team aanmaken
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param request body object{naam=string,type=string,beschrijving=string} true "Team"
+// @Success 201 {object} models.StepTeam
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams [post]
+// @Security BearerAuth
+func (h *StepsHandler) CreateTeam(c *fiber.Ctx) error {
+	var team models.StepTeam
+	if err := c.BodyParser(&team); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request body",
+		})
+	}
+	team.ID = ""
+
+	if err := h.stepsService.CreateTeam(&team); err != nil {
+		return leaderboardError(c, err, "Kon team niet aanmaken")
+	}
+	logger.Info("Team aangemaakt", "team_id", team.ID, "naam", team.Naam, "user_id", c.Locals("userID"))
+	return c.Status(fiber.StatusCreated).JSON(team)
+}
+
+// UpdateTeam werkt een team bij
+// @Summary Team bijwerken
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param id path string true "Team ID"
+// @Param request body object{naam=string,type=string,beschrijving=string,is_actief=bool} true "Team"
+// @Success 200 {object} models.StepTeam
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams/{id} [put]
+// @Security BearerAuth
+func (h *StepsHandler) UpdateTeam(c *fiber.Ctx) error {
+	update := models.StepTeam{IsActief: true}
+	if err := c.BodyParser(&update); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request body",
+		})
+	}
+
+	team, err := h.stepsService.UpdateTeam(c.Params("id"), &update)
+	if err != nil {
+		return leaderboardError(c, err, "Kon team niet bijwerken")
+	}
+	return c.JSON(team)
+}
+
+// DeleteTeam verwijdert een team
+// @Summary Team verwijderen
+// @Description Verwijdert een team; de leden blijven deelnemer zonder team.
+// @Tags Steps
+// @Param id path string true "Team ID"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams/{id} [delete]
+// @Security BearerAuth
+func (h *StepsHandler) DeleteTeam(c *fiber.Ctx) error {
+	if err := h.stepsService.DeleteTeam(c.Params("id")); err != nil {
+		return leaderboardError(c, err, "Kon team niet verwijderen")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListTeamMembers haalt de leden van een team op
+// @Summary Teamleden ophalen
+// @Tags Steps
+// @Produce json
+// @Param id path string true "Team ID"
+// @Success 200 {array} models.Aanmelding
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams/{id}/members [get]
+// @Security BearerAuth
+func (h *StepsHandler) ListTeamMembers(c *fiber.Ctx) error {
+	members, err := h.stepsService.ListTeamMembers(c.Params("id"))
+	if err != nil {
+		return leaderboardError(c, err, "Kon teamleden niet ophalen")
+	}
+	return c.JSON(members)
+}
+
+// AddTeamMembers zet deelnemers in een team
+// @Summary Teamleden toevoegen
+// @Description Zet deelnemers in een team. Een deelnemer die al in een ander team zat wordt verplaatst.
+// @Tags Steps
+// @Accept json
+// @Produce json
+// @Param id path string true "Team ID"
+// @Param request body object{aanmelding_ids=[]string} true "Deelnemers"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams/{id}/members [post]
+// @Security BearerAuth
+func (h *StepsHandler) AddTeamMembers(c *fiber.Ctx) error {
+	var req struct {
+		AanmeldingIDs []string `json:"aanmelding_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Ongeldige request body",
+		})
+	}
+
+	if err := h.stepsService.AddTeamMembers(c.Params("id"), req.AanmeldingIDs); err != nil {
+		return leaderboardError(c, err, "Kon teamleden niet toevoegen")
+	}
+	return c.JSON(fiber.Map{
+		"message": "Teamleden toegevoegd",
+		"count":   len(req.AanmeldingIDs),
+	})
+}
+
+// RemoveTeamMember haalt een deelnemer uit een team
+// @Summary Teamlid verwijderen
+// @Tags Steps
+// @Param id path string true "Team ID"
+// @Param aanmeldingId path string true "Deelnemer ID"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/steps/teams/{id}/members/{aanmeldingId} [delete]
+// @Security BearerAuth
+func (h *StepsHandler) RemoveTeamMember(c *fiber.Ctx) error {
+	if err := h.stepsService.RemoveTeamMember(c.Params("id"), c.Params("aanmeldingId")); err != nil {
+		return leaderboardError(c, err, "Kon teamlid niet verwijderen")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
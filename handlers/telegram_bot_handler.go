@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TelegramBotHandler bevat handlers voor de webhook van de Telegram bot, het register van chats
+// en het koppelen van een Telegram account aan de ingelogde gebruiker
+type TelegramBotHandler struct {
+	botService        *services.TelegramBotService
+	chatRepo          repository.TelegramChatRepository
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewTelegramBotHandler maakt een nieuwe Telegram bot handler
+func NewTelegramBotHandler(
+	botService *services.TelegramBotService,
+	chatRepo repository.TelegramChatRepository,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *TelegramBotHandler {
+	return &TelegramBotHandler{
+		botService:        botService,
+		chatRepo:          chatRepo,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// telegramChatRequest is de body voor het registreren en bijwerken van een chat
+type telegramChatRequest struct {
+	ChatID          int64                   `json:"chat_id"`
+	Naam            string                  `json:"naam"`
+	Subscriptions   models.TelegramChatList `json:"subscriptions"`
+	AllowedCommands models.TelegramChatList `json:"allowed_commands"`
+	IsActief        *bool                   `json:"is_actief"`
+}
+
+// RegisterRoutes registreert de Telegram routes. De webhook is publiek en wordt beveiligd met het
+// geheim dat Telegram meestuurt. Iedereen koppelt alleen zijn eigen account; het register van
+// chats vraagt de notification permissies.
+func (h *TelegramBotHandler) RegisterRoutes(app *fiber.App) {
+	app.Post(services.TelegramWebhookPath, h.Webhook)
+
+	link := app.Group("/api/v1/telegrambot/link", AuthMiddleware(h.authService))
+	link.Post("/", h.CreateLinkCode)
+	link.Delete("/", h.Unlink)
+
+	chats := app.Group("/api/v1/telegrambot/chats", AuthMiddleware(h.authService))
+	chats.Get("/", PermissionMiddleware(h.permissionService, "notification", "read"), h.ListChats)
+	chats.Post("/", PermissionMiddleware(h.permissionService, "notification", "write"), h.CreateChat)
+	chats.Put("/:id", PermissionMiddleware(h.permissionService, "notification", "write"), h.UpdateChat)
+	chats.Delete("/:id", PermissionMiddleware(h.permissionService, "notification", "write"), h.DeleteChat)
+}
+
+// Webhook ontvangt updates van Telegram
+// @Summary Telegram webhook
+// @Description Ontvangt updates van Telegram in webhook modus. Telegram stuurt het geheim mee in de header X-Telegram-Bot-Api-Secret-Token.
+// @Tags Telegram
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/webhook [post]
+func (h *TelegramBotHandler) Webhook(c *fiber.Ctx) error {
+	err := h.botService.HandleWebhook(c.Get(services.TelegramWebhookSecretHeader), c.Body())
+	if errors.Is(err, services.ErrInvalidWebhookSecret) {
+		logger.Warn("Telegram webhook met ongeldig geheim geweigerd", "ip", c.IP())
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+	if err != nil {
+		// Telegram blijft een update herhalen zolang hij geen 200 krijgt; een onleesbare update heeft daar niets aan
+		logger.Error("Fout bij verwerken telegram webhook", "error", err)
+	}
+	return c.JSON(fiber.Map{"ok": true})
+}
+
+// CreateLinkCode maakt een koppelcode voor de ingelogde gebruiker
+// @Summary Telegram koppelcode aanmaken
+// @Description Maakt een koppelcode die de gebruiker als "/koppel <code>" naar de bot stuurt. De code is 15 minuten geldig.
+// @Tags Telegram
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/link [post]
+// @Security BearerAuth
+func (h *TelegramBotHandler) CreateLinkCode(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	code, expiresAt, err := h.botService.CreateLinkCode(userID)
+	if err != nil {
+		logger.Error("Fout bij aanmaken telegram koppelcode", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon geen koppelcode aanmaken",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"code":       code,
+		"command":    "/koppel " + code,
+		"expires_at": expiresAt,
+	})
+}
+
+// Unlink verbreekt de koppeling met het Telegram account van de ingelogde gebruiker
+// @Summary Telegram koppeling verbreken
+// @Description Verbreekt de koppeling tussen de ingelogde gebruiker en zijn Telegram account
+// @Tags Telegram
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/link [delete]
+// @Security BearerAuth
+func (h *TelegramBotHandler) Unlink(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+
+	if err := h.botService.UnlinkAccount(c.Context(), userID); err != nil {
+		logger.Error("Fout bij verbreken telegram koppeling", "error", err, "user_id", userID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon de koppeling niet verbreken",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Telegram koppeling verbroken",
+	})
+}
+
+// ListChats haalt alle geregistreerde chats op
+// @Summary Telegram chats
+// @Description Haalt de chats op waarin de bot actief is, met hun abonnementen en toegestane commando's
+// @Tags Telegram
+// @Produce json
+// @Success 200 {array} models.TelegramChat
+// @Router /api/v1/telegrambot/chats [get]
+// @Security BearerAuth
+func (h *TelegramBotHandler) ListChats(c *fiber.Ctx) error {
+	chats, err := h.chatRepo.List(c.Context())
+	if err != nil {
+		logger.Error("Fout bij ophalen telegram chats", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chats niet ophalen",
+		})
+	}
+	return c.JSON(chats)
+}
+
+// CreateChat registreert een chat
+// @Summary Telegram chat registreren
+// @Description Registreert een chat met de notificatie types waarop hij geabonneerd is en de commando's die er gebruikt mogen worden. Het chat ID geeft de bot als antwoord op /start.
+// @Tags Telegram
+// @Accept json
+// @Produce json
+// @Param chat body telegramChatRequest true "Chat"
+// @Success 201 {object} models.TelegramChat
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/chats [post]
+// @Security BearerAuth
+func (h *TelegramBotHandler) CreateChat(c *fiber.Ctx) error {
+	var req telegramChatRequest
+	if err := h.parseChatRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	existing, err := h.chatRepo.GetByChatID(c.Context(), req.ChatID)
+	if err != nil {
+		logger.Error("Fout bij ophalen telegram chat", "error", err, "chat_id", req.ChatID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chat niet registreren",
+		})
+	}
+	if existing != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Deze chat staat al in het register",
+		})
+	}
+
+	chat := &models.TelegramChat{IsActief: true}
+	applyTelegramChatRequest(chat, &req)
+	if err := h.chatRepo.Create(c.Context(), chat); err != nil {
+		logger.Error("Fout bij registreren telegram chat", "error", err, "chat_id", req.ChatID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chat niet registreren",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(chat)
+}
+
+// UpdateChat werkt een chat bij
+// @Summary Telegram chat bijwerken
+// @Tags Telegram
+// @Accept json
+// @Produce json
+// @Param id path string true "Chat ID in het register"
+// @Param chat body telegramChatRequest true "Chat"
+// @Success 200 {object} models.TelegramChat
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/chats/{id} [put]
+// @Security BearerAuth
+func (h *TelegramBotHandler) UpdateChat(c *fiber.Ctx) error {
+	id := c.Params("id")
+	chat, err := h.chatRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen telegram chat", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chat niet ophalen",
+		})
+	}
+	if chat == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Chat niet gevonden",
+		})
+	}
+
+	var req telegramChatRequest
+	if err := h.parseChatRequest(c, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	applyTelegramChatRequest(chat, &req)
+	if err := h.chatRepo.Update(c.Context(), chat); err != nil {
+		logger.Error("Fout bij bijwerken telegram chat", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chat niet bijwerken",
+		})
+	}
+	return c.JSON(chat)
+}
+
+// DeleteChat verwijdert een chat uit het register
+// @Summary Telegram chat verwijderen
+// @Description Verwijdert een chat uit het register; de bot reageert daarna niet meer op commando's uit die chat
+// @Tags Telegram
+// @Produce json
+// @Param id path string true "Chat ID in het register"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/telegrambot/chats/{id} [delete]
+// @Security BearerAuth
+func (h *TelegramBotHandler) DeleteChat(c *fiber.Ctx) error {
+	if err := h.chatRepo.Delete(c.Context(), c.Params("id")); err != nil {
+		logger.Error("Fout bij verwijderen telegram chat", "error", err, "id", c.Params("id"))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon chat niet verwijderen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Chat succesvol verwijderd",
+	})
+}
+
+// parseChatRequest leest en valideert de body van een chat
+func (h *TelegramBotHandler) parseChatRequest(c *fiber.Ctx, req *telegramChatRequest) error {
+	if err := c.BodyParser(req); err != nil {
+		return errors.New("Ongeldige gegevens")
+	}
+
+	req.Naam = strings.TrimSpace(req.Naam)
+	if req.Naam == "" {
+		return errors.New("Naam is verplicht")
+	}
+	if req.ChatID == 0 {
+		return errors.New("Chat ID is verplicht")
+	}
+
+	for _, subscription := range req.Subscriptions {
+		if subscription != models.TelegramChatAll && !isNotificationType(subscription) {
+			return fmt.Errorf("Onbekend notificatie type: %s", subscription)
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, command := range h.botService.GetCommands() {
+		known[command.Command] = true
+	}
+	for i, command := range req.AllowedCommands {
+		command = strings.TrimPrefix(command, "/")
+		if command != models.TelegramChatAll && !known[command] {
+			return fmt.Errorf("Onbekend commando: %s", command)
+		}
+		req.AllowedCommands[i] = command
+	}
+	return nil
+}
+
+// applyTelegramChatRequest zet de gegevens uit het verzoek op de chat
+func applyTelegramChatRequest(chat *models.TelegramChat, req *telegramChatRequest) {
+	chat.ChatID = req.ChatID
+	chat.Naam = req.Naam
+	chat.Subscriptions = req.Subscriptions
+	chat.AllowedCommands = req.AllowedCommands
+	if chat.Subscriptions == nil {
+		chat.Subscriptions = models.TelegramChatList{}
+	}
+	if chat.AllowedCommands == nil {
+		chat.AllowedCommands = models.TelegramChatList{}
+	}
+	if req.IsActief != nil {
+		chat.IsActief = *req.IsActief
+	}
+}
+
+// isNotificationType controleert of een type notificatie bestaat
+func isNotificationType(value string) bool {
+	for _, notificationType := range models.NotificationTypes {
+		if string(notificationType) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -15,6 +15,7 @@ type UserHandler struct {
 	authService       services.AuthService
 	permissionService services.PermissionService
 	userRoleRepo      repository.UserRoleRepository
+	subscriptionSvc   *services.NewsletterSubscriptionService
 }
 
 func NewUserHandler(authService services.AuthService, permissionService services.PermissionService, userRoleRepo repository.UserRoleRepository) *UserHandler {
@@ -25,6 +26,12 @@ func NewUserHandler(authService services.AuthService, permissionService services
 	}
 }
 
+// SetNewsletterSubscriptionService stelt de service in waarmee wijzigingen in de nieuwsbrief
+// inschrijving door een beheerder in de audit worden vastgelegd
+func (h *UserHandler) SetNewsletterSubscriptionService(svc *services.NewsletterSubscriptionService) {
+	h.subscriptionSvc = svc
+}
+
 func (h *UserHandler) RegisterRoutes(app *fiber.App) {
 	app.Get("/api/users", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "user", "read"), h.ListUsers)
 	app.Get("/api/users/:id", AuthMiddleware(h.authService), PermissionMiddleware(h.permissionService, "user", "read"), h.GetUser)
@@ -72,6 +79,9 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	if gebruiker.NewsletterSubscribed && h.subscriptionSvc != nil {
+		h.subscriptionSvc.RecordChange(c.Context(), gebruiker, true, models.NewsletterBronAdmin, c.IP())
+	}
 	return c.JSON(gebruiker)
 }
 
@@ -118,6 +128,7 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	if req.IsActief != nil {
 		user.IsActief = *req.IsActief
 	}
+	newsletterChanged := req.NewsletterSubscribed != nil && *req.NewsletterSubscribed != user.NewsletterSubscribed
 	if req.NewsletterSubscribed != nil {
 		user.NewsletterSubscribed = *req.NewsletterSubscribed
 	}
@@ -126,6 +137,9 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	if newsletterChanged && h.subscriptionSvc != nil {
+		h.subscriptionSvc.RecordChange(c.Context(), user, user.NewsletterSubscribed, models.NewsletterBronAdmin, c.IP())
+	}
 	return c.JSON(user)
 }
 
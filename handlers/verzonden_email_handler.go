@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerzondEmailHandler bevat handlers voor het inzien van verzonden emails
+type VerzondEmailHandler struct {
+	verzondEmailRepo  repository.VerzondEmailRepository
+	authService       services.AuthService
+	permissionService services.PermissionService
+}
+
+// NewVerzondEmailHandler maakt een nieuwe verzonden email handler
+func NewVerzondEmailHandler(
+	verzondEmailRepo repository.VerzondEmailRepository,
+	authService services.AuthService,
+	permissionService services.PermissionService,
+) *VerzondEmailHandler {
+	return &VerzondEmailHandler{
+		verzondEmailRepo:  verzondEmailRepo,
+		authService:       authService,
+		permissionService: permissionService,
+	}
+}
+
+// RegisterRoutes registreert de verzonden email routes
+func (h *VerzondEmailHandler) RegisterRoutes(app *fiber.App) {
+	group := app.Group("/api/verzonden-emails",
+		AuthMiddleware(h.authService),
+		PermissionMiddleware(h.permissionService, "email", "read"))
+
+	group.Get("/", h.ListVerzondenEmails)
+	group.Get("/:id", h.GetVerzondEmail)
+}
+
+// ListVerzondenEmails haalt een gefilterde lijst van verzonden emails op
+// @Summary Lijst van verzonden emails
+// @Description Haalt verzonden emails op, filterbaar op ontvanger, status, type, template, contact en aanmelding
+// @Tags VerzondenEmails
+// @Produce json
+// @Param ontvanger query string false "Zoek op (deel van) ontvanger adres"
+// @Param status query string false "Status (in_wachtrij, verzonden, mislukt)"
+// @Param email_type query string false "Email type (bijv. aanmelding_email, contact_email, newsletter)"
+// @Param template_naam query string false "Naam van het gebruikte template"
+// @Param contact_id query string false "Contactformulier ID"
+// @Param aanmelding_id query string false "Aanmelding ID"
+// @Param van query string false "Vanaf datum (YYYY-MM-DD)"
+// @Param tot query string false "Tot datum (YYYY-MM-DD, exclusief)"
+// @Param limit query int false "Aantal resultaten (standaard 20)"
+// @Param offset query int false "Offset (standaard 0)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/verzonden-emails [get]
+// @Security BearerAuth
+func (h *VerzondEmailHandler) ListVerzondenEmails(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit moet tussen 1 en 100 liggen",
+		})
+	}
+
+	if offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offset mag niet negatief zijn",
+		})
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"ontvanger", "status", "email_type", "template_naam", "contact_id", "aanmelding_id"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	for _, key := range []string{"van", "tot"} {
+		if value := c.Query(key); value != "" {
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Ongeldige datum voor " + key + ", gebruik YYYY-MM-DD",
+				})
+			}
+			filters[key] = date
+		}
+	}
+
+	emails, total, err := h.verzondEmailRepo.ListFiltered(c.Context(), filters, limit, offset)
+	if err != nil {
+		logger.Error("Fout bij ophalen verzonden emails", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon verzonden emails niet ophalen",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"emails": emails,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetVerzondEmail haalt de details van een verzonden email op
+// @Summary Verzonden email ophalen
+// @Description Haalt een verzonden email inclusief inhoud en eventuele foutmelding op
+// @Tags VerzondenEmails
+// @Produce json
+// @Param id path string true "Verzonden email ID"
+// @Success 200 {object} models.VerzondEmail
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/verzonden-emails/{id} [get]
+// @Security BearerAuth
+func (h *VerzondEmailHandler) GetVerzondEmail(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID is verplicht",
+		})
+	}
+
+	email, err := h.verzondEmailRepo.GetByID(c.Context(), id)
+	if err != nil {
+		logger.Error("Fout bij ophalen verzonden email", "error", err, "id", id)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Kon verzonden email niet ophalen",
+		})
+	}
+
+	if email == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Verzonden email niet gevonden",
+		})
+	}
+
+	return c.JSON(email)
+}
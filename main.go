@@ -99,7 +99,7 @@ func ValidateEnv() error {
 	enableNewsletter := os.Getenv("ENABLE_NEWSLETTER") == "true"
 	if enableNewsletter {
 		if os.Getenv("NEWSLETTER_SOURCES") == "" {
-			logger.Warn("ENABLE_NEWSLETTER is true maar NEWSLETTER_SOURCES is leeg")
+			logger.Info("NEWSLETTER_SOURCES is leeg, alleen de nieuwsbrief bronnen uit de database worden gebruikt")
 		}
 	}
 
@@ -207,7 +207,12 @@ func main() {
 	serviceFactory := services.NewServiceFactory(repoFactory)
 
 	// Initialiseer steps service
-	stepsService := services.NewStepsService(db, repoFactory.Aanmelding, repoFactory.RouteFund)
+	stepsService := services.NewStepsService(db, repoFactory.Aanmelding, repoFactory.RouteFund, repoFactory.StepEntry, repoFactory.Event)
+	stepsService.EnableLeaderboards(repoFactory.StepTeam, repoFactory.Leaderboard)
+	stepsService.EnableMilestones(repoFactory.StepMilestone, serviceFactory.EmailService)
+
+	// Initialiseer sponsortoezeggingen per deelnemer
+	pledgeService := services.NewPledgeService(repoFactory.Pledge, repoFactory.Aanmelding, serviceFactory.EmailService)
 
 	// Start Newsletter service indien geconfigureerd
 	if serviceFactory.NewsletterService != nil {
@@ -237,6 +242,7 @@ func main() {
 		serviceFactory.NotificationService,
 		serviceFactory.AuthService,
 	)
+	notificationHandler.SetDeliveryRepository(repoFactory.NotificationDelivery)
 
 	// Initialiseer nieuwe handlers voor contact en aanmelding beheer
 	contactHandler := handlers.NewContactHandler(
@@ -255,6 +261,8 @@ func main() {
 		serviceFactory.AuthService,
 		serviceFactory.PermissionService,
 	)
+	contactHandler.SetIncomingEmailRepository(repoFactory.IncomingEmail)
+	aanmeldingHandler.SetIncomingEmailRepository(repoFactory.IncomingEmail)
 
 	// Initialiseer steps handler
 	stepsHandler := handlers.NewStepsHandler(
@@ -262,18 +270,30 @@ func main() {
 		serviceFactory.AuthService,
 		serviceFactory.PermissionService,
 	)
+	stepsHandler.SetPledgeService(pledgeService)
 
 	// Initialiseer newsletter handler
 	newsletterHandler := handlers.NewNewsletterHandler(
 		repoFactory.Newsletter,
 		serviceFactory.NewsletterSender,
+		serviceFactory.NewsletterWorkflow,
 		serviceFactory.AuthService,
 		serviceFactory.PermissionService,
 	)
 
 	// Configureer en initialiseer de mail fetcher service
 	mailFetcher := initializeMailFetcher(serviceFactory.EmailMetrics)
-	mailHandler := handlers.NewMailHandler(mailFetcher, repoFactory.IncomingEmail, serviceFactory.AuthService, serviceFactory.PermissionService)
+	mailFetcher.SetSyncStateRepository(repoFactory.MailSyncState)
+	mailFetcher.SetBounceProcessor(serviceFactory.BounceProcessor)
+	mailFetcher.SetMailLinker(services.NewMailLinker(
+		repoFactory.IncomingEmail,
+		repoFactory.VerzondEmail,
+		repoFactory.Aanmelding,
+		repoFactory.Contact,
+		os.Getenv("MAIL_LINK_REOPEN_STATUS") != "false",
+	))
+	mailReplyService := services.NewMailReplyService(serviceFactory.EmailService, repoFactory.IncomingEmail, repoFactory.VerzondEmail)
+	mailHandler := handlers.NewMailHandler(mailFetcher, repoFactory.IncomingEmail, repoFactory.IncomingAttachment, mailReplyService, serviceFactory.AuthService, serviceFactory.PermissionService)
 
 	// Maak een EmailAutoFetcher aan voor automatisch ophalen van emails
 	emailAutoFetcher := services.NewEmailAutoFetcher(mailFetcher, repoFactory.IncomingEmail)
@@ -445,10 +465,88 @@ func main() {
 				{"path": "/api/title_section_content", "method": "POST", "description": "Create title section content (requires admin auth)"},
 				{"path": "/api/title_section_content", "method": "PUT", "description": "Update title section content (requires admin auth)"},
 				{"path": "/api/title_section_content/:id", "method": "DELETE", "description": "Delete title section content (requires admin auth)"},
-				{"path": "/api/steps/:id", "method": "POST", "description": "Update steps for participant (requires steps write permission)"},
+				{"path": "/api/steps/:id", "method": "POST", "description": "Update steps for participant (requires steps write permission, write_all for other participants)"},
+				{"path": "/api/steps/:id/import", "method": "POST", "description": "Import GPX, TCX, FIT or CSV activity file as steps (requires steps write permission)"},
+				{"path": "/api/steps/:id/corrections", "method": "POST", "description": "Add a step correction with reason (requires steps write_all permission)"},
+				{"path": "/api/steps/:id/entries", "method": "GET", "description": "List step ledger entries (requires steps read_all permission)"},
+				{"path": "/api/participant/:id/history", "method": "GET", "description": "Get steps per day for participant (requires steps read permission)"},
 				{"path": "/api/participant/:id/dashboard", "method": "GET", "description": "Get participant dashboard (requires steps read permission)"},
 				{"path": "/api/total-steps", "method": "GET", "description": "Get total steps for year (requires steps read permission)"},
-				{"path": "/api/funds-distribution", "method": "GET", "description": "Get funds distribution (requires steps read permission)"},
+				{"path": "/api/funds-distribution", "method": "GET", "description": "Get funds distribution for an edition with the allocation strategy used (requires steps read permission). Supports ?year="},
+				{"path": "/api/steps/admin/events", "method": "GET", "description": "List event editions (requires steps manage permission)"},
+				{"path": "/api/steps/admin/events", "method": "POST", "description": "Create event edition with fundraising pot, strategy and routes (requires steps manage permission)"},
+				{"path": "/api/steps/admin/events/:year", "method": "GET", "description": "Get event edition with route funds (requires steps manage permission)"},
+				{"path": "/api/steps/admin/events/:year", "method": "PUT", "description": "Update event edition (requires steps manage permission)"},
+				{"path": "/api/steps/admin/events/:year", "method": "DELETE", "description": "Delete event edition (requires steps manage permission)"},
+				{"path": "/api/leaderboard", "method": "GET", "description": "Public anonymised participant leaderboard. Supports ?year=&route=&from=&to=&limit="},
+				{"path": "/api/leaderboard/teams", "method": "GET", "description": "Public team leaderboard. Supports ?year=&route=&sort=average&limit="},
+				{"path": "/api/steps/leaderboard", "method": "GET", "description": "Full participant leaderboard (requires steps read_all permission)"},
+				{"path": "/api/steps/leaderboard/teams", "method": "GET", "description": "Full team leaderboard (requires steps read_all permission)"},
+				{"path": "/api/steps/teams", "method": "GET", "description": "List teams (requires steps read permission)"},
+				{"path": "/api/steps/teams", "method": "POST", "description": "Create team (requires steps manage permission)"},
+				{"path": "/api/steps/teams/:id", "method": "PUT", "description": "Update team (requires steps manage permission)"},
+				{"path": "/api/steps/teams/:id", "method": "DELETE", "description": "Delete team (requires steps manage permission)"},
+				{"path": "/api/steps/teams/:id/members", "method": "GET", "description": "List team members (requires steps manage permission)"},
+				{"path": "/api/steps/teams/:id/members", "method": "POST", "description": "Add participants to team (requires steps manage permission)"},
+				{"path": "/api/steps/teams/:id/members/:aanmeldingId", "method": "DELETE", "description": "Remove participant from team (requires steps manage permission)"},
+				{"path": "/api/participant/leaderboard", "method": "PUT", "description": "Opt in or out of the public leaderboard (requires steps write permission)"},
+				{"path": "/api/participant/:id/pledges", "method": "GET", "description": "List sponsors and sponsoring totals of a participant (requires steps read permission)"},
+				{"path": "/api/pledges/participant/:id", "method": "GET", "description": "Participant details for the public pledge form (public)"},
+				{"path": "/api/pledges/participant/:id", "method": "POST", "description": "Pledge a fixed, per-km or per-10k-steps amount to a participant (public, rate limited)"},
+				{"path": "/api/pledges", "method": "GET", "description": "List pledges with expected amounts. Supports ?aanmelding_id=&status=&limit=&offset= (requires pledge read permission)"},
+				{"path": "/api/pledges/:id/status", "method": "PUT", "description": "Update pledge payment status (requires pledge write permission)"},
+				{"path": "/api/pledges/participant/:id/finished", "method": "POST", "description": "Email the sponsors of a participant the final pledge amounts (requires pledge write permission)"},
+				{"path": "/api/admin/mail/queue", "method": "GET", "description": "List email queue items (requires email_queue read permission)"},
+				{"path": "/api/admin/mail/queue/stats", "method": "GET", "description": "Email queue counts per status (requires email_queue read permission)"},
+				{"path": "/api/admin/mail/queue/dead", "method": "GET", "description": "List dead-letter emails (requires email_queue read permission)"},
+				{"path": "/api/admin/mail/queue/:id/requeue", "method": "POST", "description": "Requeue email (requires email_queue write permission)"},
+				{"path": "/api/verzonden-emails", "method": "GET", "description": "List sent emails with filters (requires email read permission)"},
+				{"path": "/api/verzonden-emails/:id", "method": "GET", "description": "Get sent email details (requires email read permission)"},
+				{"path": "/api/newsletter/subscribe", "method": "POST", "description": "Public double opt-in newsletter signup with consent (rate limited)"},
+				{"path": "/api/newsletter/confirm", "method": "GET", "description": "Newsletter signup confirmation page (public, signed token)"},
+				{"path": "/api/newsletter/confirm", "method": "POST", "description": "Confirm a newsletter signup (public, signed token)"},
+				{"path": "/api/newsletter/unsubscribe", "method": "GET", "description": "Newsletter unsubscribe confirmation page (public, signed token)"},
+				{"path": "/api/newsletter/unsubscribe", "method": "POST", "description": "Unsubscribe from the newsletter, supports RFC 8058 one-click (public, signed token)"},
+				{"path": "/api/newsletter/subscription-events", "method": "GET", "description": "Audit of newsletter subscribe/unsubscribe events (requires newsletter read permission)"},
+				{"path": "/api/newsletter/subscribers", "method": "GET", "description": "Newsletter subscribers without an account, with consent records (requires newsletter read permission)"},
+				{"path": "/api/newsletter/track/open", "method": "GET", "description": "Newsletter open tracking pixel (public, signed token)"},
+				{"path": "/api/newsletter/track/click", "method": "GET", "description": "Newsletter click tracking redirect (public, signed token)"},
+				{"path": "/api/newsletter/:id/stats", "method": "GET", "description": "Newsletter delivery, open and click statistics (requires newsletter read permission)"},
+				{"path": "/api/newsletter/:id/deliveries", "method": "GET", "description": "Per-recipient newsletter deliveries (requires newsletter read permission)"},
+				{"path": "/api/newsletter/:id/submit", "method": "POST", "description": "Submit newsletter for review (requires newsletter write permission)"},
+				{"path": "/api/newsletter/:id/test-send", "method": "POST", "description": "Send a preview of the newsletter to the requesting user (requires newsletter write permission)"},
+				{"path": "/api/newsletter/:id/approve", "method": "POST", "description": "Approve newsletter, not by its author (requires newsletter approve permission)"},
+				{"path": "/api/newsletter/:id/reject", "method": "POST", "description": "Send newsletter back to draft (requires newsletter approve permission)"},
+				{"path": "/api/newsletter/:id/schedule", "method": "POST", "description": "Schedule approved newsletter at scheduled_at (requires newsletter approve permission)"},
+				{"path": "/api/newsletter/:id/unschedule", "method": "POST", "description": "Cancel newsletter schedule (requires newsletter approve permission)"},
+				{"path": "/api/newsletter-segments", "method": "GET", "description": "List saved newsletter audience segments (requires newsletter read permission)"},
+				{"path": "/api/newsletter-segments", "method": "POST", "description": "Create newsletter segment (requires newsletter write permission)"},
+				{"path": "/api/newsletter-segments/preview", "method": "POST", "description": "Dry-run recipient count for a segment filter (requires newsletter read permission)"},
+				{"path": "/api/newsletter-segments/:id/preview", "method": "GET", "description": "Dry-run recipient count for a saved segment (requires newsletter read permission)"},
+				{"path": "/api/newsletter-segments/:id", "method": "PUT", "description": "Update newsletter segment (requires newsletter write permission)"},
+				{"path": "/api/newsletter-segments/:id", "method": "DELETE", "description": "Delete newsletter segment (requires newsletter write permission)"},
+				{"path": "/api/newsletter-sources", "method": "GET", "description": "List feed and internal content sources of the automatic newsletter (requires newsletter read permission)"},
+				{"path": "/api/newsletter-sources", "method": "POST", "description": "Create newsletter source (requires newsletter write permission)"},
+				{"path": "/api/newsletter-sources/:id/preview", "method": "GET", "description": "Fetch the items of a newsletter source without sending (requires newsletter read permission)"},
+				{"path": "/api/newsletter-sources/:id", "method": "PUT", "description": "Update newsletter source (requires newsletter write permission)"},
+				{"path": "/api/newsletter-sources/:id", "method": "DELETE", "description": "Delete newsletter source (requires newsletter write permission)"},
+				{"path": "/api/inbox", "method": "GET", "description": "List notifications in the personal inbox of the current user (requires auth)"},
+				{"path": "/api/inbox/unread-count", "method": "GET", "description": "Count unread inbox notifications (requires auth)"},
+				{"path": "/api/inbox/read", "method": "POST", "description": "Mark inbox notifications as read (requires auth)"},
+				{"path": "/api/inbox/read-all", "method": "POST", "description": "Mark all inbox notifications as read (requires auth)"},
+				{"path": "/api/inbox/:id", "method": "DELETE", "description": "Delete inbox notification (requires auth)"},
+				{"path": "/api/inbox/preferences", "method": "GET", "description": "Get notification preferences per type (requires auth)"},
+				{"path": "/api/inbox/preferences", "method": "PUT", "description": "Update notification preferences per type (requires auth)"},
+				{"path": "/api/inbox/stream", "method": "GET", "description": "Live inbox notifications via Server-Sent Events (requires auth, token may be passed as query)"},
+				{"path": "/api/email-suppressions", "method": "GET", "description": "List bounced and suppressed addresses (requires email read permission)"},
+				{"path": "/api/email-suppressions/:email", "method": "DELETE", "description": "Remove an address from the suppression list (requires email delete permission)"},
+				{"path": "/api/email-templates", "method": "GET", "description": "List email templates (requires email_template read permission)"},
+				{"path": "/api/email-templates", "method": "POST", "description": "Create email template (requires email_template write permission)"},
+				{"path": "/api/email-templates/:id", "method": "PUT", "description": "Update email template, previous version is kept (requires email_template write permission)"},
+				{"path": "/api/email-templates/:id", "method": "DELETE", "description": "Delete email template (requires email_template delete permission)"},
+				{"path": "/api/email-templates/:id/activeren", "method": "POST", "description": "Validate and activate email template (requires email_template write permission)"},
+				{"path": "/api/email-templates/:id/versies", "method": "GET", "description": "List email template versions (requires email_template read permission)"},
+				{"path": "/api/email-templates/preview", "method": "POST", "description": "Render template content with sample data (requires email_template read permission)"},
 				{"path": "/metrics", "method": "GET", "description": "Prometheus metrics"},
 			},
 		})
@@ -490,6 +588,24 @@ func main() {
 	// Registreer routes voor stappen beheer
 	stepsHandler.RegisterRoutes(app)
 
+	// Registreer routes voor sponsortoezeggingen (publiek formulier en beheer)
+	pledgeHandler := handlers.NewPledgeHandler(pledgeService, rateLimiter, serviceFactory.AuthService, serviceFactory.PermissionService)
+	pledgeHandler.RegisterRoutes(app)
+
+	// Registreer de publieke aan- en afmeldroutes vóór het newsletter beheer (dat auth vereist)
+	newsletterSubscriptionHandler := handlers.NewNewsletterSubscriptionHandler(serviceFactory.Subscriptions, rateLimiter, serviceFactory.AuthService, serviceFactory.PermissionService)
+	newsletterSubscriptionHandler.RegisterRoutes(app)
+	newsletterTrackingHandler := handlers.NewNewsletterTrackingHandler(serviceFactory.NewsletterTracker, serviceFactory.AuthService, serviceFactory.PermissionService)
+	newsletterTrackingHandler.RegisterRoutes(app)
+
+	// Registreer routes voor nieuwsbrief segmenten (doelgroepen)
+	newsletterSegmentHandler := handlers.NewNewsletterSegmentHandler(repoFactory.NewsletterSegment, serviceFactory.NewsletterSender, serviceFactory.AuthService, serviceFactory.PermissionService)
+	newsletterSegmentHandler.RegisterRoutes(app)
+
+	// Registreer routes voor de bronnen van de automatische nieuwsbrief
+	newsletterSourceHandler := handlers.NewNewsletterSourceHandler(repoFactory.NewsletterSource, serviceFactory.NewsletterFetcher, serviceFactory.AuthService, serviceFactory.PermissionService)
+	newsletterSourceHandler.RegisterRoutes(app)
+
 	// Registreer routes voor newsletter beheer
 	newsletterHandler.RegisterRoutes(app)
 
@@ -606,6 +722,15 @@ func main() {
 			})
 		})
 
+		// Webhook, register van chats en koppelen van Telegram accounts voor acties onder notificaties
+		telegramBotHandler := handlers.NewTelegramBotHandler(
+			serviceFactory.TelegramBotService,
+			repoFactory.TelegramChat,
+			serviceFactory.AuthService,
+			serviceFactory.PermissionService,
+		)
+		telegramBotHandler.RegisterRoutes(app)
+
 		logger.Info("Telegram bot routes geregistreerd")
 	}
 
@@ -639,10 +764,31 @@ func main() {
 	// Registreer de admin mail routes
 	adminMailHandler.RegisterRoutes(app)
 
+	// Initialiseer email queue handler (outbox inspectie en requeue)
+	emailQueueHandler := handlers.NewEmailQueueHandler(repoFactory.EmailQueue, serviceFactory.AuthService, serviceFactory.PermissionService)
+	emailQueueHandler.RegisterRoutes(app)
+
+	// Initialiseer verzonden email handler
+	verzondEmailHandler := handlers.NewVerzondEmailHandler(repoFactory.VerzondEmail, serviceFactory.AuthService, serviceFactory.PermissionService)
+	verzondEmailHandler.RegisterRoutes(app)
+
+	// Initialiseer suppressielijst handler (gebouncede adressen)
+	emailSuppressionHandler := handlers.NewEmailSuppressionHandler(repoFactory.EmailSuppression, serviceFactory.AuthService, serviceFactory.PermissionService)
+	emailSuppressionHandler.RegisterRoutes(app)
+
+	// Initialiseer email template handler (database templates, versies en preview)
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(serviceFactory.EmailTemplate, serviceFactory.AuthService, serviceFactory.PermissionService)
+	emailTemplateHandler.RegisterRoutes(app)
+
 	// Initialiseer chat handler
 	chatHandler := handlers.NewChatHandler(serviceFactory.ChatService, serviceFactory.AuthService, serviceFactory.PermissionService, serviceFactory.ImageService, serviceFactory.Hub)
+	chatHandler.SetNotificationInbox(serviceFactory.NotificationInbox)
 	chatHandler.RegisterRoutes(app)
 
+	// Initialiseer persoonlijke inbox met live notificaties
+	notificationInboxHandler := handlers.NewNotificationInboxHandler(serviceFactory.NotificationInbox, serviceFactory.AuthService)
+	notificationInboxHandler.RegisterRoutes(app)
+
 	// Set WebSocket channel callback
 	chatHandler.SetChannelHubCallback()
 
@@ -669,6 +815,7 @@ func main() {
 
 	// Initialiseer user handler
 	userHandler := handlers.NewUserHandler(serviceFactory.AuthService, serviceFactory.PermissionService, repoFactory.UserRole)
+	userHandler.SetNewsletterSubscriptionService(serviceFactory.Subscriptions)
 	userHandler.RegisterRoutes(app)
 
 	// Initialiseer image handler
@@ -791,6 +938,11 @@ func main() {
 		serviceFactory.EmailBatcher.Shutdown()
 	}
 
+	// Stop de email queue nadat de batcher zijn laatste batches heeft aangeleverd
+	if serviceFactory.EmailQueue != nil {
+		serviceFactory.EmailQueue.Stop()
+	}
+
 	// Stop de email auto fetcher
 	if serviceFactory.EmailAutoFetcher != nil && serviceFactory.EmailAutoFetcher.IsRunning() {
 		logger.Info("Email auto fetcher stoppen...")
@@ -857,7 +1009,9 @@ func initializeMailFetcher(metrics *services.EmailMetrics) *services.MailFetcher
 			imapServer,
 			port,
 			"info",
+			parseMailFolders(os.Getenv("INFO_EMAIL_FOLDERS"))...,
 		)
+		mailFetcher.ConfigureAccountSync("info", mailAccountSyncConfig("INFO_EMAIL"))
 		logger.Info("Added info email account", "email", infoEmail)
 	} else {
 		logger.Warn("Info email credentials not set, skipping account setup")
@@ -870,7 +1024,9 @@ func initializeMailFetcher(metrics *services.EmailMetrics) *services.MailFetcher
 			imapServer,
 			port,
 			"inschrijving",
+			parseMailFolders(os.Getenv("INSCHRIJVING_EMAIL_FOLDERS"))...,
 		)
+		mailFetcher.ConfigureAccountSync("inschrijving", mailAccountSyncConfig("INSCHRIJVING_EMAIL"))
 		logger.Info("Added inschrijving email account", "email", inschrijvingEmail)
 	} else {
 		logger.Warn("Inschrijving email credentials not set, skipping account setup")
@@ -878,3 +1034,29 @@ func initializeMailFetcher(metrics *services.EmailMetrics) *services.MailFetcher
 
 	return mailFetcher
 }
+
+// parseMailFolders leest een komma-gescheiden lijst van IMAP folders (bijv. "INBOX,Spam").
+// Een lege waarde levert geen folders op, waarna alleen INBOX wordt opgehaald.
+func parseMailFolders(value string) []string {
+	var folders []string
+	for _, folder := range strings.Split(value, ",") {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// mailAccountSyncConfig leest de IMAP terugschrijf-instellingen voor een account. Verwerkte
+// mails gaan standaard naar "Verwerkt"; zonder prullenbak-folder worden verwijderde mails ge-expunged.
+func mailAccountSyncConfig(prefix string) services.MailAccountSyncConfig {
+	cfg := services.MailAccountSyncConfig{
+		WriteBack:       os.Getenv("ENABLE_IMAP_WRITEBACK") != "false",
+		ProcessedFolder: "Verwerkt",
+		TrashFolder:     strings.TrimSpace(os.Getenv(prefix + "_TRASH_FOLDER")),
+	}
+	if folder, ok := os.LookupEnv(prefix + "_PROCESSED_FOLDER"); ok {
+		cfg.ProcessedFolder = strings.TrimSpace(folder)
+	}
+	return cfg
+}
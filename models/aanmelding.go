@@ -31,11 +31,18 @@ type Aanmelding struct {
 	// Steps veld voor stappen tracking
 	Steps int `json:"steps" gorm:"default:0"`
 
+	// Team en zichtbaarheid op het openbare klassement
+	TeamID               *string `json:"team_id,omitempty" gorm:"type:uuid;index"`
+	LeaderboardZichtbaar bool    `json:"leaderboard_zichtbaar" gorm:"not null;default:false"`
+
 	// Link naar gebruikersaccount voor authenticatie
 	GebruikerID *string `json:"gebruiker_id,omitempty" gorm:"type:uuid;index"`
 
 	// Relatie met antwoorden
 	Antwoorden []AanmeldingAntwoord `json:"antwoorden,omitempty" gorm:"foreignKey:AanmeldingID"`
+
+	// Inkomende emails die aan deze aanmelding zijn gekoppeld
+	InkomendeEmails []*IncomingEmail `json:"inkomende_emails,omitempty" gorm:"foreignKey:AanmeldingID"`
 }
 
 // TableName specificeert de tabelnaam voor GORM
@@ -28,6 +28,9 @@ type ContactFormulier struct {
 
 	// Relatie met antwoorden
 	Antwoorden []ContactAntwoord `json:"antwoorden,omitempty" gorm:"foreignKey:ContactID"`
+
+	// Inkomende emails die aan dit contactformulier zijn gekoppeld
+	InkomendeEmails []*IncomingEmail `json:"inkomende_emails,omitempty" gorm:"foreignKey:ContactID"`
 }
 
 // TableName specificeert de tabelnaam voor GORM
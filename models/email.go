@@ -16,6 +16,8 @@ type AanmeldingEmailData struct {
 	ToAdmin    bool                 `json:"to_admin"`
 	Aanmelding *AanmeldingFormulier `json:"aanmelding"`
 	AdminEmail string               `json:"admin_email,omitempty"`
+	// AanmeldingID koppelt de verzonden email aan de opgeslagen aanmelding (optioneel)
+	AanmeldingID string `json:"aanmelding_id,omitempty"`
 }
 
 // Nieuwe error toevoegen (ergens in het models package)
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Email queue statussen
+const (
+	EmailQueueStatusPending    = "pending"
+	EmailQueueStatusProcessing = "processing"
+	EmailQueueStatusSent       = "sent"
+	EmailQueueStatusDead       = "dead"
+)
+
+// EmailQueueStaleError is de foutmelding van een item waarvan de worker is gestopt tijdens
+// de laatste toegestane poging
+const EmailQueueStaleError = "worker gestopt tijdens verzending, geen pogingen meer over"
+
+// Email queue kanalen, bepalen via welke SMTP configuratie een bericht verzonden wordt
+const (
+	EmailChannelDefault      = "default"
+	EmailChannelRegistration = "registration"
+	EmailChannelWFC          = "wfc"
+)
+
+// EmailQueueItem representeert een uitgaande email in de persistente outbox
+type EmailQueueItem struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Channel       string     `json:"channel" gorm:"not null;default:'default'"`
+	FromAddress   string     `json:"from_address"`
+	Recipient     string     `json:"recipient" gorm:"not null;index"`
+	Subject       string     `json:"subject" gorm:"not null"`
+	Body          string     `json:"body" gorm:"type:text;not null"`
+	EmailType     string     `json:"email_type" gorm:"index"`
+	TestMode      bool       `json:"test_mode" gorm:"default:false"`
+	Status        string     `json:"status" gorm:"not null;default:'pending';index"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts   int        `json:"max_attempts" gorm:"not null;default:5"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index"`
+	LockedAt      *time.Time `json:"locked_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	// VerzondEmailID verwijst naar de bijbehorende regel in verzonden_emails
+	VerzondEmailID *string `json:"verzonden_email_id,omitempty" gorm:"type:uuid"`
+	// NewsletterDeliveryID verwijst naar de aflevering van een nieuwsbrief aan deze ontvanger
+	NewsletterDeliveryID *string   `json:"newsletter_delivery_id,omitempty" gorm:"type:uuid"`
+	CreatedAt            time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt            time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Headers bevat extra headers zoals Message-ID die bij verzending worden meegestuurd
+	Headers EmailHeaders `json:"headers,omitempty" gorm:"type:text"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (EmailQueueItem) TableName() string {
+	return "email_queue"
+}
+
+// EmailHeaders bevat extra headers van een bericht en wordt als JSON opgeslagen
+type EmailHeaders map[string]string
+
+// Value implementeert driver.Valuer
+func (h EmailHeaders) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (h *EmailHeaders) Scan(value interface{}) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("kan %T niet omzetten naar EmailHeaders", value)
+	}
+
+	if len(data) == 0 {
+		*h = nil
+		return nil
+	}
+	return json.Unmarshal(data, h)
+}
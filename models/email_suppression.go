@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Bounce types van een delivery status notification
+const (
+	BounceTypeHard = "hard"
+	BounceTypeSoft = "soft"
+)
+
+// EmailSuppression houdt bij welke adressen bouncen. Een actieve suppressie betekent dat
+// er niet meer naar het adres wordt verzonden (na een harde bounce of herhaalde zachte bounces).
+// Los daarvan legt NieuwsbriefAfgemeld een blijvende afmelding voor de nieuwsbrief vast; dan
+// gaan transactionele emails gewoon door, maar nieuwsbrieven aan geen enkele doelgroep meer.
+type EmailSuppression struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Email           string    `json:"email" gorm:"not null;uniqueIndex"`
+	BounceType      string    `json:"bounce_type" gorm:"not null"`
+	StatusCode      string    `json:"status_code"`
+	DiagnosticCode  string    `json:"diagnostic_code" gorm:"type:text"`
+	BounceCount     int       `json:"bounce_count" gorm:"not null;default:0"`
+	Actief          bool      `json:"actief" gorm:"not null;default:false;index"`
+	IncomingEmailID *string   `json:"incoming_email_id,omitempty"`
+	LastBounceAt    time.Time `json:"last_bounce_at"`
+
+	NieuwsbriefAfgemeld bool       `json:"nieuwsbrief_afgemeld" gorm:"not null;default:false;index"`
+	AfgemeldAt          *time.Time `json:"afgemeld_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (EmailSuppression) TableName() string {
+	return "email_suppressions"
+}
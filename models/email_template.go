@@ -11,14 +11,33 @@ type EmailTemplate struct {
 	Onderwerp    string    `json:"onderwerp" gorm:"not null"`
 	Inhoud       string    `json:"inhoud" gorm:"type:text;not null"`
 	Beschrijving string    `json:"beschrijving" gorm:"type:text"`
-	IsActief     bool      `json:"is_actief" gorm:"default:true"`
+	IsActief     bool      `json:"is_actief" gorm:"not null"` // Geen GORM default, anders wordt false als true opgeslagen
+	Versie       int       `json:"versie" gorm:"not null;default:1"`
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	CreatedBy    string    `json:"created_by"`
-	UpdatedBy    string    `json:"updated_by"`
+	UpdatedBy    *string   `json:"updated_by,omitempty" gorm:"type:uuid"`
 }
 
 // TableName specificeert de tabelnaam voor GORM
 func (EmailTemplate) TableName() string {
 	return "email_templates"
 }
+
+// EmailTemplateVersie bevat een eerdere versie van een email template
+type EmailTemplateVersie struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TemplateID    string    `json:"template_id" gorm:"type:uuid;not null;index"`
+	Versie        int       `json:"versie" gorm:"not null"`
+	Onderwerp     string    `json:"onderwerp" gorm:"not null"`
+	Inhoud        string    `json:"inhoud" gorm:"type:text;not null"`
+	Beschrijving  string    `json:"beschrijving" gorm:"type:text"`
+	IsActief      bool      `json:"is_actief"`
+	GewijzigdDoor *string   `json:"gewijzigd_door,omitempty" gorm:"type:uuid"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (EmailTemplateVersie) TableName() string {
+	return "email_template_versies"
+}
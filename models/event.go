@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Strategieën om de fondsenpot van een editie over de routes te verdelen
+const (
+	AllocationStrategyEqual        = "equal"        // Gelijk over alle routes
+	AllocationStrategyParticipants = "participants" // Naar rato van het aantal deelnemers per route
+	AllocationStrategySteps        = "steps"        // Naar rato van het aantal gelopen stappen per route
+)
+
+// AllocationStrategies bevat alle geldige verdeelstrategieën
+var AllocationStrategies = []string{AllocationStrategyEqual, AllocationStrategyParticipants, AllocationStrategySteps}
+
+// Event is een editie van De Koninklijke Loop met een eigen fondsenpot, routes en verdeelstrategie.
+// Deelnemers horen bij de editie van het jaar waarin ze zich hebben aangemeld.
+type Event struct {
+	ID                 string      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Jaar               int         `json:"jaar" gorm:"not null;uniqueIndex"`
+	Naam               string      `json:"naam" gorm:"not null"`
+	TotalFunds         int         `json:"total_funds" gorm:"not null;default:0"` // Fondsenpot in euro's
+	AllocationStrategy string      `json:"allocation_strategy" gorm:"type:varchar(20);not null;default:'participants'"`
+	RouteFunds         []RouteFund `json:"route_funds,omitempty" gorm:"foreignKey:EventID"`
+	CreatedAt          time.Time   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time   `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Event) TableName() string {
+	return "events"
+}
+
+// RouteStats bevat het aantal deelnemers en stappen op een route binnen een editie
+type RouteStats struct {
+	Route        string `json:"route"`
+	Participants int    `json:"participants"`
+	Steps        int    `json:"steps"`
+}
+
+// RouteAllocation is het deel van de fondsenpot dat naar een route gaat
+type RouteAllocation struct {
+	Route        string `json:"route"`
+	Amount       int    `json:"amount"` // Ingesteld bedrag per deelnemer (RouteFund)
+	Participants int    `json:"participants"`
+	Steps        int    `json:"steps"`
+	Allocated    int    `json:"allocated"` // Toegewezen deel van de fondsenpot
+}
+
+// FundsDistribution is de berekende verdeling van de fondsenpot van een editie
+type FundsDistribution struct {
+	EventID            string            `json:"event_id"`
+	Jaar               int               `json:"jaar"`
+	Naam               string            `json:"naam"`
+	TotalFunds         int               `json:"total_funds"`
+	Strategy           string            `json:"strategy"`            // Gebruikte strategie
+	ConfiguredStrategy string            `json:"configured_strategy"` // Ingestelde strategie; wijkt af als er nog niets te verdelen viel
+	Routes             []RouteAllocation `json:"routes"`
+}
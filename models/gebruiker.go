@@ -18,6 +18,7 @@ type Gebruiker struct {
 	IsActief             bool       `json:"is_actief" gorm:"default:true"`
 	NewsletterSubscribed bool       `json:"newsletter_subscribed" gorm:"default:false;index"`
 	LaatsteLogin         *time.Time `json:"laatste_login"`
+	TelegramUserID       *int64     `json:"telegram_user_id,omitempty" gorm:"uniqueIndex"` // Gekoppeld Telegram account voor acties vanuit de bot
 	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt            time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 
@@ -12,12 +12,55 @@ type IncomingEmail struct {
 	To          string     `json:"to"`
 	Subject     string     `json:"subject"`
 	Body        string     `json:"body" gorm:"type:text"`
+	TextBody    string     `json:"text_body" gorm:"type:text"` // Platte tekst versie van het bericht
 	ContentType string     `json:"content_type"`
 	ReceivedAt  time.Time  `json:"received_at"`
-	UID         string     `json:"uid" gorm:"uniqueIndex"`
-	AccountType string     `json:"account_type" gorm:"index"` // "info" of "inschrijving"
+	UID         string     `json:"uid" gorm:"uniqueIndex:idx_incoming_emails_account_mailbox_uid,priority:4,where:uid <> ''"` // Leeg als het bericht niet meer op de server te vinden is
+	AccountType string     `json:"account_type" gorm:"index;uniqueIndex:idx_incoming_emails_account_mailbox_uid,priority:1"` // "info" of "inschrijving"
+	Mailbox     string     `json:"mailbox" gorm:"not null;default:'INBOX';uniqueIndex:idx_incoming_emails_account_mailbox_uid,priority:2"`
+	UIDValidity uint32     `json:"uid_validity" gorm:"type:bigint;not null;default:0;uniqueIndex:idx_incoming_emails_account_mailbox_uid,priority:3"`
 	IsProcessed bool       `json:"read" gorm:"index"`
 	ProcessedAt *time.Time `json:"processed_at"`
 	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Threading headers; ThreadID is de Message-ID van het eerste bericht in het gesprek
+	InReplyTo  string `json:"in_reply_to,omitempty"`
+	References string `json:"references,omitempty" gorm:"type:text"`
+	ThreadID   string `json:"thread_id" gorm:"index"`
+
+	// Gekoppelde aanmelding of contactformulier, bepaald op afzender of threading headers
+	AanmeldingID *string `json:"aanmelding_id,omitempty" gorm:"type:uuid;index"`
+	ContactID    *string `json:"contact_id,omitempty" gorm:"type:uuid;index"`
+
+	// IsBounce geeft aan dat de email een delivery status notification (bounce) is
+	IsBounce bool `json:"is_bounce" gorm:"default:false;index"`
+
+	// ImapSyncPending geeft aan dat een actie nog niet naar de mailserver kon worden teruggeschreven
+	ImapSyncPending bool `json:"imap_sync_pending" gorm:"default:false;index"`
+
+	// ImapDeletePending geeft aan dat de email is verwijderd maar nog niet van de mailserver kon
+	// worden verwijderd; de reconciliatie probeert het opnieuw en verwijdert daarna de regel
+	ImapDeletePending bool `json:"imap_delete_pending" gorm:"default:false;index"`
+
+	// Attachments worden bij het aanmaken van de email mee opgeslagen
+	Attachments []*IncomingEmailAttachment `json:"attachments,omitempty" gorm:"foreignKey:EmailID"`
+}
+
+// IncomingEmailAttachment bevat een bijlage van een inkomende e-mail
+type IncomingEmailAttachment struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	EmailID     string    `json:"email_id" gorm:"not null;index"`
+	Filename    string    `json:"filename" gorm:"not null"`
+	ContentType string    `json:"content_type"`
+	ContentID   string    `json:"content_id,omitempty"`
+	IsInline    bool      `json:"is_inline" gorm:"default:false"`
+	Size        int64     `json:"size"`
+	Data        []byte    `json:"-" gorm:"type:bytea"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (IncomingEmailAttachment) TableName() string {
+	return "incoming_email_attachments"
 }
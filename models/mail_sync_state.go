@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// MailSyncState bewaart per account en mailbox tot welke IMAP UID berichten zijn opgehaald.
+// Wijzigt de UIDVALIDITY van de mailbox, dan zijn de opgeslagen UIDs niet meer geldig
+// en wordt de mailbox opnieuw gesynchroniseerd.
+type MailSyncState struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	AccountType string     `json:"account_type" gorm:"not null;uniqueIndex:idx_mail_sync_states_account_mailbox"`
+	Username    string     `json:"username"`
+	Mailbox     string     `json:"mailbox" gorm:"not null;uniqueIndex:idx_mail_sync_states_account_mailbox"`
+	UIDValidity uint32     `json:"uid_validity" gorm:"type:bigint;not null;default:0"`
+	LastUID     uint32     `json:"last_uid" gorm:"type:bigint;not null;default:0"`
+	LastSyncAt  *time.Time `json:"last_sync_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (MailSyncState) TableName() string {
+	return "mail_sync_states"
+}
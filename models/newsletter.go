@@ -3,13 +3,40 @@ package models
 import "time"
 
 type NewsItem struct {
+	ID          string    `json:"id,omitempty"` // GUID of id uit de feed, of het type en ID van eigen content
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Link        string    `json:"link"`
 	PubDate     time.Time `json:"pub_date"`
 	Category    string    `json:"category"`
+	Source      string    `json:"source,omitempty"` // Naam van de bron
 }
 
+// Key geeft de sleutel terug waarmee een item herkend wordt in latere nieuwsbrieven
+func (i NewsItem) Key() string {
+	if i.ID != "" {
+		return i.ID
+	}
+	return i.Link
+}
+
+// NewsGroup is een groep nieuwsitems met dezelfde categorie
+type NewsGroup struct {
+	Category string     `json:"category"`
+	Items    []NewsItem `json:"items"`
+}
+
+// Statussen in de workflow van een nieuwsbrief
+const (
+	NewsletterStatusDraft     = "draft"
+	NewsletterStatusInReview  = "in_review"
+	NewsletterStatusApproved  = "approved"
+	NewsletterStatusScheduled = "scheduled"
+	NewsletterStatusSending   = "sending"
+	NewsletterStatusSent      = "sent"
+	NewsletterStatusFailed    = "failed"
+)
+
 type Newsletter struct {
 	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	Subject   string     `json:"subject"`
@@ -18,12 +45,26 @@ type Newsletter struct {
 	BatchID   string     `json:"batch_id"`
 	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Workflow: opstellen, laten goedkeuren door een tweede persoon en inplannen
+	Status      string     `json:"status" gorm:"not null;default:'draft';index"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty" gorm:"index"`
+	CreatedBy   *string    `json:"created_by,omitempty" gorm:"type:uuid"`
+	UpdatedBy   *string    `json:"updated_by,omitempty" gorm:"type:uuid"`
+	SubmittedBy *string    `json:"submitted_by,omitempty" gorm:"type:uuid"`
+	ApprovedBy  *string    `json:"approved_by,omitempty" gorm:"type:uuid"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+	FoutBericht string     `json:"fout_bericht,omitempty" gorm:"type:text"`
+
+	// Doelgroep; zonder segment gaat de nieuwsbrief naar alle subscribers
+	SegmentID *string `json:"segment_id,omitempty" gorm:"type:uuid"`
 }
 
 func (Newsletter) TableName() string { return "newsletters" }
 
 // ProcessedNews bevat gefilterde en samengevatte nieuwsitems voor rendering
 type ProcessedNews struct {
-	Items   []NewsItem `json:"items"`
-	Summary string     `json:"summary"`
+	Items   []NewsItem  `json:"items"`
+	Groups  []NewsGroup `json:"groups,omitempty"`
+	Summary string      `json:"summary"`
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Statussen van de aflevering van een nieuwsbrief aan één ontvanger
+const (
+	NewsletterDeliveryQueued  = "queued"
+	NewsletterDeliverySent    = "sent"
+	NewsletterDeliveryFailed  = "failed"
+	NewsletterDeliveryBounced = "bounced"
+)
+
+// NewsletterDelivery houdt per ontvanger bij of een nieuwsbrief is afgeleverd, geopend en aangeklikt
+type NewsletterDelivery struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	NewsletterID string     `json:"newsletter_id" gorm:"type:uuid;not null;index"`
+	Email        string     `json:"email" gorm:"not null;index"`
+	Status       string     `json:"status" gorm:"not null;default:'queued';index"`
+	FoutBericht  string     `json:"fout_bericht,omitempty" gorm:"type:text"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	BouncedAt    *time.Time `json:"bounced_at,omitempty"`
+	OpenedAt     *time.Time `json:"opened_at,omitempty"` // Eerste keer geopend
+	OpenCount    int        `json:"open_count" gorm:"not null;default:0"`
+	ClickedAt    *time.Time `json:"clicked_at,omitempty"` // Eerste klik
+	ClickCount   int        `json:"click_count" gorm:"not null;default:0"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterDelivery) TableName() string {
+	return "newsletter_deliveries"
+}
+
+// NewsletterStats vat de aflevering van een nieuwsbrief samen
+type NewsletterStats struct {
+	NewsletterID string  `json:"newsletter_id"`
+	Total        int64   `json:"total"`
+	Queued       int64   `json:"queued"`
+	Sent         int64   `json:"sent"`
+	Failed       int64   `json:"failed"`
+	Bounced      int64   `json:"bounced"`
+	UniqueOpens  int64   `json:"unique_opens"`
+	TotalOpens   int64   `json:"total_opens"`
+	UniqueClicks int64   `json:"unique_clicks"`
+	TotalClicks  int64   `json:"total_clicks"`
+	Reached      int64   `json:"reached"` // Verzonden en niet gebounced
+	OpenRate     float64 `json:"open_rate"`
+	ClickRate    float64 `json:"click_rate"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NewsletterSegment is een opgeslagen doelgroep voor de nieuwsbrief, bijvoorbeeld
+// "alle 15 KM lopers van 2025" of "begeleiders"
+type NewsletterSegment struct {
+	ID           string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Naam         string        `json:"naam" gorm:"not null;uniqueIndex"`
+	Beschrijving string        `json:"beschrijving" gorm:"type:text"`
+	Filter       SegmentFilter `json:"filter" gorm:"type:jsonb;not null"`
+	CreatedBy    *string       `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterSegment) TableName() string {
+	return "newsletter_segments"
+}
+
+// SegmentFilter beschrijft wie in een segment valt. De ontvangers zijn de vereniging van
+// de aanmeldingen, de gebruikers en de losse adressen die aan de filters voldoen.
+type SegmentFilter struct {
+	Aanmeldingen *AanmeldingSegmentFilter `json:"aanmeldingen,omitempty"`
+	Gebruikers   *GebruikerSegmentFilter  `json:"gebruikers,omitempty"`
+	// Emails is een expliciete lijst met adressen, bijvoorbeeld sponsors
+	Emails []string `json:"emails,omitempty"`
+}
+
+// AanmeldingSegmentFilter selecteert aanmeldingen. Lege velden filteren niet.
+type AanmeldingSegmentFilter struct {
+	Rollen    []string `json:"rollen,omitempty"`    // bijv. "Deelnemer", "Begeleider", "Vrijwilliger"
+	Afstanden []string `json:"afstanden,omitempty"` // bijv. "15 KM"
+	Statussen []string `json:"statussen,omitempty"`
+	Jaar      int      `json:"jaar,omitempty"` // Jaar van aanmelden
+	MinSteps  int      `json:"min_steps,omitempty"`
+}
+
+// GebruikerSegmentFilter selecteert actieve gebruikers. Lege velden filteren niet.
+type GebruikerSegmentFilter struct {
+	Rollen []string `json:"rollen,omitempty"` // Namen van RBAC rollen
+	// NewsletterSubscribed beperkt tot gebruikers met (of zonder) nieuwsbrief abonnement
+	NewsletterSubscribed *bool `json:"newsletter_subscribed,omitempty"`
+}
+
+// IsEmpty geeft aan of het filter geen enkele bron bevat
+func (f SegmentFilter) IsEmpty() bool {
+	return f.Aanmeldingen == nil && f.Gebruikers == nil && len(f.Emails) == 0
+}
+
+// Value implementeert driver.Valuer
+func (f SegmentFilter) Value() (driver.Value, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (f *SegmentFilter) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*f = SegmentFilter{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("kan %T niet omzetten naar SegmentFilter", value)
+	}
+
+	*f = SegmentFilter{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, f)
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Typen nieuwsbrief bronnen: externe feeds en eigen content van de website
+const (
+	NewsletterSourceRSS     = "rss"     // RSS 2.0 feed
+	NewsletterSourceAtom    = "atom"    // Atom feed
+	NewsletterSourceJSON    = "json"    // JSON Feed (jsonfeed.org)
+	NewsletterSourcePhotos  = "photos"  // Zichtbare foto's
+	NewsletterSourceAlbums  = "albums"  // Zichtbare albums
+	NewsletterSourceVideos  = "videos"  // Zichtbare video's
+	NewsletterSourceRadio   = "radio"   // Zichtbare radio-opnames
+	NewsletterSourceProgram = "program" // Zichtbare onderdelen van het programma
+)
+
+// NewsletterSource is een bron voor de automatische nieuwsbrief. Voor feeds is URL het adres
+// van de feed; voor eigen content is URL optioneel de pagina op de website waar de items
+// naartoe linken.
+type NewsletterSource struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Naam          string     `json:"naam" gorm:"not null"`
+	Type          string     `json:"type" gorm:"not null"`
+	URL           string     `json:"url"`
+	Categorie     string     `json:"categorie"`                     // Categorie voor items zonder eigen categorie
+	MaxItems      int        `json:"max_items" gorm:"default:10"`   // Maximaal aantal items per nieuwsbrief
+	MaxAgeDays    int        `json:"max_age_days" gorm:"default:7"` // Alleen items van de laatste dagen
+	IsActief      bool       `json:"is_actief" gorm:"not null;default:true"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterSource) TableName() string {
+	return "newsletter_sources"
+}
+
+// IsInternal geeft aan of de bron eigen content van de website is in plaats van een feed
+func (s *NewsletterSource) IsInternal() bool {
+	switch s.Type {
+	case NewsletterSourcePhotos, NewsletterSourceAlbums, NewsletterSourceVideos, NewsletterSourceRadio, NewsletterSourceProgram:
+		return true
+	}
+	return false
+}
+
+// IsValidNewsletterSourceType controleert of een bron type bekend is
+func IsValidNewsletterSourceType(sourceType string) bool {
+	switch sourceType {
+	case NewsletterSourceRSS, NewsletterSourceAtom, NewsletterSourceJSON:
+		return true
+	}
+	return (&NewsletterSource{Type: sourceType}).IsInternal()
+}
+
+// NewsletterSentItem legt vast dat een nieuwsitem in een automatische nieuwsbrief heeft
+// gestaan, zodat het niet nog een keer wordt verzonden
+type NewsletterSentItem struct {
+	ID      string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ItemKey string    `json:"item_key" gorm:"not null;uniqueIndex"`
+	Title   string    `json:"title"`
+	Link    string    `json:"link"`
+	Source  string    `json:"source"`
+	SentAt  time.Time `json:"sent_at" gorm:"not null"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterSentItem) TableName() string {
+	return "newsletter_sent_items"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Statussen van een nieuwsbrief abonnee zonder account
+const (
+	NewsletterSubscriberPending      = "pending"      // Aangemeld, wacht op bevestiging
+	NewsletterSubscriberConfirmed    = "confirmed"    // Aanmelding bevestigd via de link in de email
+	NewsletterSubscriberUnsubscribed = "unsubscribed" // Afgemeld
+)
+
+// NewsletterSubscriber is een abonnee van de nieuwsbrief die zich via de website heeft
+// aangemeld, los van een gebruikersaccount. De toestemming wordt met tijdstip en IP adres
+// vastgelegd (AVG); pas na bevestiging (double opt-in) ontvangt de abonnee de nieuwsbrief.
+type NewsletterSubscriber struct {
+	ID                 string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Email              string     `json:"email" gorm:"not null;uniqueIndex"`
+	Naam               string     `json:"naam,omitempty"`
+	Status             string     `json:"status" gorm:"not null;default:'pending';index"`
+	ConsentAt          time.Time  `json:"consent_at" gorm:"not null"`
+	ConsentIP          string     `json:"consent_ip,omitempty"`
+	ConfirmationSentAt *time.Time `json:"confirmation_sent_at,omitempty"`
+	ConfirmedAt        *time.Time `json:"confirmed_at,omitempty"`
+	ConfirmedIP        string     `json:"confirmed_ip,omitempty"`
+	UnsubscribedAt     *time.Time `json:"unsubscribed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterSubscriber) TableName() string {
+	return "newsletter_subscribers"
+}
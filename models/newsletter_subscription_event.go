@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Acties in de audit van nieuwsbrief aan- en afmeldingen
+const (
+	NewsletterActieAangemeld = "aangemeld"
+	NewsletterActieAfgemeld  = "afgemeld"
+)
+
+// Bronnen van een nieuwsbrief aan- of afmelding
+const (
+	NewsletterBronLink     = "link"      // Afmeldlink in de nieuwsbrief
+	NewsletterBronOneClick = "one_click" // RFC 8058 one-click vanuit de mailclient
+	NewsletterBronAdmin    = "admin"     // Gewijzigd door een beheerder
+	NewsletterBronWebsite  = "website"   // Aangemeld en bevestigd via het formulier op de website
+)
+
+// NewsletterSubscriptionEvent legt elke aan- of afmelding voor de nieuwsbrief vast
+type NewsletterSubscriptionEvent struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GebruikerID *string   `json:"gebruiker_id,omitempty" gorm:"type:uuid;index"`
+	Email       string    `json:"email" gorm:"not null;index"`
+	Actie       string    `json:"actie" gorm:"not null"`
+	Bron        string    `json:"bron" gorm:"not null"`
+	IPAdres     string    `json:"ip_adres,omitempty"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NewsletterSubscriptionEvent) TableName() string {
+	return "newsletter_subscription_events"
+}
@@ -24,6 +24,21 @@ const (
 	NotificationPriorityCritical NotificationPriority = "critical"
 )
 
+// Rank returns the order of the priority, from 1 (low) to 4 (critical); unknown priorities are 0
+func (p NotificationPriority) Rank() int {
+	switch p {
+	case NotificationPriorityLow:
+		return 1
+	case NotificationPriorityMedium:
+		return 2
+	case NotificationPriorityHigh:
+		return 3
+	case NotificationPriorityCritical:
+		return 4
+	}
+	return 0
+}
+
 // NotificationType represents the type of notification
 type NotificationType string
 
@@ -42,19 +57,33 @@ const (
 
 	// NotificationTypeHealth represents health check notifications
 	NotificationTypeHealth NotificationType = "health"
+
+	// NotificationTypeChat represents personal chat notifications, such as a reply to a message
+	NotificationTypeChat NotificationType = "chat"
 )
 
+// NotificationTypes lists all notification types, for example to show preferences per type
+var NotificationTypes = []NotificationType{
+	NotificationTypeContact,
+	NotificationTypeAanmelding,
+	NotificationTypeAuth,
+	NotificationTypeSystem,
+	NotificationTypeHealth,
+	NotificationTypeChat,
+}
+
 // Notification represents a notification to be sent via Telegram
 type Notification struct {
-	ID        string               `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Type      NotificationType     `json:"type" gorm:"type:varchar(50);not null"`
-	Priority  NotificationPriority `json:"priority" gorm:"type:varchar(20);not null"`
-	Title     string               `json:"title" gorm:"type:varchar(255);not null"`
-	Message   string               `json:"message" gorm:"type:text;not null"`
-	Sent      bool                 `json:"sent" gorm:"default:false"`
-	SentAt    *time.Time           `json:"sent_at" gorm:"type:timestamptz"`
-	CreatedAt time.Time            `json:"created_at" gorm:"type:timestamptz;not null;default:now()"`
-	UpdatedAt time.Time            `json:"updated_at" gorm:"type:timestamptz;not null;default:now()"`
+	ID          string               `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Type        NotificationType     `json:"type" gorm:"type:varchar(50);not null"`
+	Priority    NotificationPriority `json:"priority" gorm:"type:varchar(20);not null"`
+	Title       string               `json:"title" gorm:"type:varchar(255);not null"`
+	Message     string               `json:"message" gorm:"type:text;not null"`
+	ReferenceID *string              `json:"reference_id,omitempty" gorm:"type:varchar(255)"` // Contactformulier of aanmelding waar de notificatie over gaat
+	Sent        bool                 `json:"sent" gorm:"default:false"`
+	SentAt      *time.Time           `json:"sent_at" gorm:"type:timestamptz"`
+	CreatedAt   time.Time            `json:"created_at" gorm:"type:timestamptz;not null;default:now()"`
+	UpdatedAt   time.Time            `json:"updated_at" gorm:"type:timestamptz;not null;default:now()"`
 }
 
 // BeforeCreate sets the ID if it's not already set
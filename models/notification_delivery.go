@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// Kanalen waarover een notificatie kan worden afgeleverd
+const (
+	NotificationChannelTelegram = "telegram" // Telegram chat van de organisatie
+	NotificationChannelEmail    = "email"    // Email digest aan medewerkers
+	NotificationChannelWebhook  = "webhook"  // Uitgaande webhook (Slack, Discord, Mattermost)
+	NotificationChannelInApp    = "in_app"   // Notificatie in het dashboard, per gebruiker
+)
+
+// Statussen van de aflevering van een notificatie op één kanaal
+const (
+	NotificationDeliveryPending = "pending" // Nog niet (succesvol) afgeleverd, wordt opnieuw geprobeerd
+	NotificationDeliverySent    = "sent"    // Afgeleverd
+	NotificationDeliveryFailed  = "failed"  // Definitief mislukt na het maximale aantal pogingen
+)
+
+// NotificationDelivery is de aflevering van een notificatie op één kanaal. Elk kanaal heeft
+// een eigen status en eigen pogingen, zodat een storing bij het ene kanaal de andere niet raakt.
+type NotificationDelivery struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	NotificationID string     `json:"notification_id" gorm:"type:uuid;not null;uniqueIndex:idx_notification_deliveries_channel"`
+	Channel        string     `json:"channel" gorm:"not null;uniqueIndex:idx_notification_deliveries_channel"`
+	Status         string     `json:"status" gorm:"not null;default:'pending';index"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
+
+// IsFinal geeft aan of de aflevering klaar is, geslaagd of definitief mislukt
+func (d *NotificationDelivery) IsFinal() bool {
+	return d.Status == NotificationDeliverySent || d.Status == NotificationDeliveryFailed
+}
+
+// UserNotification is een notificatie in de persoonlijke inbox van één gebruiker. Hij komt
+// van een systeemnotificatie die in-app wordt afgeleverd, of is persoonlijk, zoals een
+// reactie op een chatbericht; dan is er geen NotificationID.
+type UserNotification struct {
+	ID             string           `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	NotificationID *string          `json:"notification_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_user_notifications_user"`
+	UserID         string           `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_user_notifications_user;index"`
+	Type           NotificationType `json:"type" gorm:"type:varchar(50);not null"`
+	Title          string           `json:"title" gorm:"type:varchar(255);not null"`
+	Message        string           `json:"message" gorm:"type:text"`
+	Link           string           `json:"link,omitempty" gorm:"type:text"`
+	ReadAt         *time.Time       `json:"read_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (UserNotification) TableName() string {
+	return "user_notifications"
+}
+
+// IsRead geeft aan of de gebruiker de notificatie heeft gelezen
+func (n *UserNotification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// NotificationPreference is de voorkeur van een gebruiker voor één type notificatie. Zonder
+// voorkeur komen notificaties in de inbox en worden ze live doorgestuurd.
+type NotificationPreference struct {
+	UserID    string           `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Type      NotificationType `json:"type" gorm:"primaryKey;type:varchar(50)"`
+	InApp     bool             `json:"in_app" gorm:"not null;default:true"`
+	Push      bool             `json:"push" gorm:"not null;default:true"`
+	UpdatedAt time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
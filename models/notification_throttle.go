@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxNotificationDigestTitles is het aantal titels dat in een digest wordt bewaard; de rest wordt alleen geteld
+const MaxNotificationDigestTitles = 10
+
+// NotificationThrottle is de throttling status van één sleutel, bijvoorbeeld alle notificaties van
+// een type. Binnen het venster wordt alleen de eerste notificatie verzonden; de rest wordt geteld
+// en na afloop van het venster als één digest verzonden.
+type NotificationThrottle struct {
+	Key         string               `json:"key" gorm:"primaryKey;type:varchar(255)"`
+	WindowEnd   time.Time            `json:"window_end" gorm:"not null"`
+	Type        NotificationType     `json:"type" gorm:"type:varchar(50)"`
+	Priority    NotificationPriority `json:"priority" gorm:"type:varchar(20)"`
+	Suppressed  int                  `json:"suppressed" gorm:"not null;default:0"`
+	Titles      NotificationTitles   `json:"titles" gorm:"type:jsonb"`
+	DigestDueAt *time.Time           `json:"digest_due_at,omitempty" gorm:"index"`
+	UpdatedAt   time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (NotificationThrottle) TableName() string {
+	return "notification_throttles"
+}
+
+// NotificationTitles zijn de titels van onderdrukte notificaties en worden als JSON opgeslagen
+type NotificationTitles []string
+
+// Value implementeert driver.Valuer
+func (t NotificationTitles) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (t *NotificationTitles) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("kan %T niet omzetten naar NotificationTitles", value)
+	}
+
+	if len(data) == 0 {
+		*t = nil
+		return nil
+	}
+	return json.Unmarshal(data, t)
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Soorten toezeggingen van een sponsor aan een deelnemer
+const (
+	PledgeTypeFixed       = "fixed"         // Vast bedrag
+	PledgeTypePerKm       = "per_km"        // Bedrag per kilometer van de route
+	PledgeTypePer10kSteps = "per_10k_steps" // Bedrag per 10.000 stappen
+)
+
+// PledgeTypes bevat alle geldige soorten toezeggingen
+var PledgeTypes = []string{PledgeTypeFixed, PledgeTypePerKm, PledgeTypePer10kSteps}
+
+// Betaalstatus van een toezegging
+const (
+	PledgeStatusPending   = "pending"
+	PledgeStatusPaid      = "paid"
+	PledgeStatusCancelled = "cancelled"
+)
+
+// PledgeStatuses bevat alle geldige betaalstatussen
+var PledgeStatuses = []string{PledgeStatusPending, PledgeStatusPaid, PledgeStatusCancelled}
+
+// Pledge is een toezegging van een sponsor aan één deelnemer (Aanmelding). Bij per_km en
+// per_10k_steps hangt het verwachte bedrag af van de route en de stappen van de deelnemer;
+// MaxAmount begrenst dat bedrag.
+type Pledge struct {
+	ID                  string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	AanmeldingID        string     `json:"aanmelding_id" gorm:"type:uuid;not null;index"`
+	SponsorNaam         string     `json:"sponsor_naam" gorm:"not null"`
+	SponsorEmail        string     `json:"sponsor_email" gorm:"not null;index"`
+	Type                string     `json:"type" gorm:"type:varchar(20);not null"`
+	Amount              float64    `json:"amount" gorm:"type:numeric(10,2);not null"`
+	MaxAmount           *float64   `json:"max_amount,omitempty" gorm:"type:numeric(10,2)"`
+	Bericht             *string    `json:"bericht,omitempty" gorm:"type:text"`
+	Anoniem             bool       `json:"anoniem" gorm:"not null;default:false"`
+	Status              string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	PaidAmount          *float64   `json:"paid_amount,omitempty" gorm:"type:numeric(10,2)"`
+	PaidAt              *time.Time `json:"paid_at,omitempty"`
+	PaymentReference    *string    `json:"payment_reference,omitempty"`
+	ConfirmationSentAt  *time.Time `json:"confirmation_sent_at,omitempty"`
+	FinishedEmailSentAt *time.Time `json:"finished_email_sent_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// ExpectedAmount wordt berekend uit de route en stappen van de deelnemer
+	ExpectedAmount float64 `json:"expected_amount" gorm:"-"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (Pledge) TableName() string {
+	return "pledges"
+}
+
+// PledgeFilter bakent de lijst met toezeggingen in het beheer af
+type PledgeFilter struct {
+	AanmeldingID string
+	Status       string
+	Limit        int
+	Offset       int
+}
+
+// PledgeSummary zijn de sponsortotalen van een deelnemer; geannuleerde toezeggingen tellen niet mee
+type PledgeSummary struct {
+	Count         int     `json:"count"`
+	PaidCount     int     `json:"paid_count"`
+	ExpectedTotal float64 `json:"expected_total"`
+	PaidTotal     float64 `json:"paid_total"`
+}
+
+// PledgeParticipant is wat het publieke sponsorformulier over een deelnemer laat zien
+type PledgeParticipant struct {
+	Naam  string `json:"naam"`
+	Route string `json:"route"`
+	Steps int    `json:"steps"`
+}
@@ -4,11 +4,12 @@ import (
 	"time"
 )
 
-// RouteFund vertegenwoordigt de fondsallocatie per route
+// RouteFund vertegenwoordigt de fondsallocatie per route binnen een editie (Event)
 type RouteFund struct {
 	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Route     string    `json:"route" gorm:"uniqueIndex;not null"` // Bijv. "6 KM", "10 KM", etc.
-	Amount    int       `json:"amount" gorm:"not null"`            // Bedrag in euro's
+	EventID   string    `json:"event_id" gorm:"type:uuid;not null;uniqueIndex:idx_route_funds_event_route"`
+	Route     string    `json:"route" gorm:"not null;uniqueIndex:idx_route_funds_event_route"` // Bijv. "6 KM", "10 KM", etc.
+	Amount    int       `json:"amount" gorm:"not null"`                                        // Bedrag in euro's
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -27,6 +28,7 @@ type RouteFundRequest struct {
 // RouteFundResponse voor API responses
 type RouteFundResponse struct {
 	ID        string    `json:"id"`
+	EventID   string    `json:"event_id"`
 	Route     string    `json:"route"`
 	Amount    int       `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Bronnen van een regel in het stappen grootboek
+const (
+	StepSourceApp        = "app"        // De deelnemer zelf, via de app of het dashboard
+	StepSourceAdmin      = "admin"      // Een medewerker namens de deelnemer
+	StepSourceCorrection = "correction" // Een correctie door een medewerker, altijd met reden
+	StepSourceMigration  = "migratie"   // Het totaal van voor het grootboek
+	StepSourceImport     = "import"     // Een geüpload bestand van een horloge of telefoon
+)
+
+// StepEntry is één regel in het stappen grootboek. Regels worden nooit gewijzigd of verwijderd;
+// een fout wordt hersteld met een correctie. Het totaal van een deelnemer is de som van de regels
+// en staat als afgeleide waarde in Aanmelding.Steps.
+type StepEntry struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	AanmeldingID   string    `json:"aanmelding_id" gorm:"type:uuid;not null;index"`
+	Delta          int       `json:"delta" gorm:"not null"`
+	Source         string    `json:"source" gorm:"type:varchar(20);not null"`
+	RecordedAt     time.Time `json:"recorded_at" gorm:"not null;index"`         // Moment waarop de stappen zijn gezet
+	EnteredBy      *string   `json:"entered_by,omitempty" gorm:"type:uuid"`     // Gebruiker die de regel heeft ingevoerd
+	Reason         *string   `json:"reason,omitempty" gorm:"type:text"`         // Verplicht bij correcties
+	IdempotencyKey *string   `json:"idempotency_key,omitempty" gorm:"size:255"` // Uniek per deelnemer; een herhaald verzoek telt maar één keer
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Herkomst van een geïmporteerde activiteit
+	Metadata *StepEntryMetadata `json:"metadata,omitempty" gorm:"type:jsonb"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (StepEntry) TableName() string {
+	return "step_entries"
+}
+
+// StepDayTotal is het aantal stappen van een deelnemer op één dag
+type StepDayTotal struct {
+	Date  string `json:"date"` // YYYY-MM-DD in de tijdzone van het evenement
+	Steps int    `json:"steps"`
+}
+
+// StepEntryMetadata beschrijft de activiteit waar een geïmporteerde regel vandaan komt
+type StepEntryMetadata struct {
+	Format         string     `json:"format"`                    // gpx, tcx, fit of csv
+	FileName       string     `json:"file_name,omitempty"`       // Naam van het geüploade bestand
+	ActivityHash   string     `json:"activity_hash,omitempty"`   // Vingerafdruk voor het herkennen van dubbele uploads
+	StartedAt      *time.Time `json:"started_at,omitempty"`      // Begin van de activiteit
+	DistanceMeters float64    `json:"distance_meters,omitempty"` // Afgelegde afstand
+	StepsEstimated bool       `json:"steps_estimated,omitempty"` // Stappen zijn geschat uit de afstand
+}
+
+// Value implementeert driver.Valuer
+func (m StepEntryMetadata) Value() (driver.Value, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (m *StepEntryMetadata) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("kan %T niet omzetten naar StepEntryMetadata", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, m)
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Soorten teams waarin deelnemers samen stappen verzamelen
+const (
+	StepTeamTypeOrganisatie    = "organisatie"
+	StepTeamTypeSchool         = "school"
+	StepTeamTypeZorginstelling = "zorginstelling"
+	StepTeamTypeOverig         = "overig"
+)
+
+// StepTeamTypes bevat alle geldige soorten teams
+var StepTeamTypes = []string{StepTeamTypeOrganisatie, StepTeamTypeSchool, StepTeamTypeZorginstelling, StepTeamTypeOverig}
+
+// StepTeam is een groep deelnemers, zoals een bedrijf, school of zorginstelling. Een deelnemer
+// hoort bij hoogstens één team (Aanmelding.TeamID).
+type StepTeam struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Naam         string    `json:"naam" gorm:"not null;uniqueIndex"`
+	Type         string    `json:"type" gorm:"type:varchar(30);not null;default:'organisatie'"`
+	Beschrijving *string   `json:"beschrijving,omitempty" gorm:"type:text"`
+	IsActief     bool      `json:"is_actief" gorm:"not null;default:true"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (StepTeam) TableName() string {
+	return "step_teams"
+}
+
+// StepMilestone is een mijlpaal (badge) die een deelnemer heeft behaald
+type StepMilestone struct {
+	ID           string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	AanmeldingID string     `json:"aanmelding_id" gorm:"type:uuid;not null;uniqueIndex:idx_step_milestones_aanmelding_milestone"`
+	Milestone    int        `json:"milestone" gorm:"not null;uniqueIndex:idx_step_milestones_aanmelding_milestone"`
+	ReachedAt    time.Time  `json:"reached_at" gorm:"not null"`
+	EmailSentAt  *time.Time `json:"email_sent_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (StepMilestone) TableName() string {
+	return "step_milestones"
+}
+
+// LeaderboardFilter bakent een klassement af
+type LeaderboardFilter struct {
+	RegisteredFrom time.Time  // Begin van het evenementjaar (aanmeldingsdatum)
+	RegisteredTo   time.Time  // Einde van het evenementjaar (exclusief)
+	From           *time.Time // Alleen stappen vanaf dit moment
+	To             *time.Time // Alleen stappen tot dit moment (exclusief)
+	Route          string     // Alleen deelnemers op deze afstand
+	TeamID         string     // Alleen leden van dit team
+	SortByAverage  bool       // Teams sorteren op gemiddelde per lid in plaats van totaal
+	Limit          int
+}
+
+// LeaderboardEntry is één deelnemer in een klassement
+type LeaderboardEntry struct {
+	Rank         int     `json:"rank"`
+	AanmeldingID string  `json:"aanmelding_id,omitempty"`
+	Naam         string  `json:"naam"`
+	Route        string  `json:"route"`
+	TeamID       *string `json:"team_id,omitempty"`
+	TeamNaam     *string `json:"team_naam,omitempty"`
+	Steps        int     `json:"steps"`
+	Zichtbaar    bool    `json:"-"` // De deelnemer wil met naam op het openbare klassement
+}
+
+// TeamLeaderboardEntry is één team in een klassement
+type TeamLeaderboardEntry struct {
+	Rank         int    `json:"rank"`
+	TeamID       string `json:"team_id"`
+	Naam         string `json:"naam"`
+	Type         string `json:"type"`
+	Members      int    `json:"members"` // Leden met stappen in de periode
+	Steps        int    `json:"steps"`
+	AverageSteps int    `json:"average_steps"`
+}
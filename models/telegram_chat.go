@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TelegramChatAll staat in een lijst voor alle notificatie types of alle commando's
+const TelegramChatAll = "*"
+
+// TelegramChat is een chat waarin de bot actief is, bijvoorbeeld het bestuur, de coördinatie
+// van vrijwilligers of de technische dienst. Elke chat krijgt alleen de notificatie types waarop
+// hij is geabonneerd en accepteert alleen de toegestane commando's.
+type TelegramChat struct {
+	ID              string           `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ChatID          int64            `json:"chat_id" gorm:"not null;uniqueIndex"`
+	Naam            string           `json:"naam" gorm:"not null"`
+	Subscriptions   TelegramChatList `json:"subscriptions" gorm:"type:jsonb;not null"`    // Notificatie types, of "*" voor alle types
+	AllowedCommands TelegramChatList `json:"allowed_commands" gorm:"type:jsonb;not null"` // Commando's zonder "/", of "*" voor alle commando's
+	IsActief        bool             `json:"is_actief" gorm:"not null;default:true"`
+	CreatedAt       time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specificeert de tabelnaam voor GORM
+func (TelegramChat) TableName() string {
+	return "telegram_chats"
+}
+
+// IsSubscribed geeft aan of de chat notificaties van dit type krijgt
+func (c *TelegramChat) IsSubscribed(notificationType NotificationType) bool {
+	return c.IsActief && c.Subscriptions.Contains(string(notificationType))
+}
+
+// AllowsCommand geeft aan of een commando, met of zonder "/", in deze chat gebruikt mag worden
+func (c *TelegramChat) AllowsCommand(command string) bool {
+	if len(command) > 0 && command[0] == '/' {
+		command = command[1:]
+	}
+	return c.IsActief && c.AllowedCommands.Contains(command)
+}
+
+// TelegramChatList is een lijst met notificatie types of commando's en wordt als JSON opgeslagen
+type TelegramChatList []string
+
+// Contains geeft aan of de waarde in de lijst staat; "*" bevat alles
+func (l TelegramChatList) Contains(value string) bool {
+	for _, item := range l {
+		if item == TelegramChatAll || item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implementeert driver.Valuer
+func (l TelegramChatList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implementeert sql.Scanner
+func (l *TelegramChatList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("kan %T niet omzetten naar TelegramChatList", value)
+	}
+
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// Statussen van een verzonden email
+const (
+	VerzondEmailStatusInWachtrij = "in_wachtrij"
+	VerzondEmailStatusVerzonden  = "verzonden"
+	VerzondEmailStatusMislukt    = "mislukt"
+)
+
 // VerzondEmail representeert een verzonden email
 type VerzondEmail struct {
 	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
@@ -14,11 +21,21 @@ type VerzondEmail struct {
 	Status      string    `json:"status" gorm:"default:'verzonden';index"`
 	FoutBericht string    `json:"fout_bericht" gorm:"type:text"`
 
+	// Type en template zodat per soort email gefilterd kan worden
+	EmailType    string `json:"email_type" gorm:"index"`
+	TemplateNaam string `json:"template_naam"`
+
 	// Optionele relaties
 	ContactID    *string `json:"contact_id" gorm:"index"`
 	AanmeldingID *string `json:"aanmelding_id" gorm:"index"`
 	TemplateID   *string `json:"template_id"`
 
+	// Threading van antwoorden en doorgestuurde inkomende emails
+	IncomingEmailID *string `json:"incoming_email_id,omitempty" gorm:"index"`
+	MessageID       string  `json:"message_id,omitempty"`
+	InReplyTo       string  `json:"in_reply_to,omitempty"`
+	ThreadID        string  `json:"thread_id,omitempty" gorm:"index"`
+
 	// Relaties
 	Contact    *ContactFormulier `json:"-" gorm:"foreignKey:ContactID"`
 	Aanmelding *Aanmelding       `json:"-" gorm:"foreignKey:AanmeldingID"`
@@ -68,7 +68,8 @@ func (r *PostgresAanmeldingRepository) Update(ctx context.Context, aanmelding *m
 	ctx, cancel := r.withTimeout(ctx)
 	defer cancel()
 
-	result := r.DB().WithContext(ctx).Save(aanmelding)
+	// Stappen zijn een afgeleide van het grootboek en worden alleen door StepEntryRepository gezet
+	result := r.DB().WithContext(ctx).Omit("steps").Save(aanmelding)
 	return r.handleError("Update", result.Error)
 }
 
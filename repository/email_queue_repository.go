@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresEmailQueueRepository implementeert EmailQueueRepository met PostgreSQL
+type PostgresEmailQueueRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresEmailQueueRepository maakt een nieuwe PostgreSQL email queue repository
+func NewPostgresEmailQueueRepository(base *PostgresRepository) *PostgresEmailQueueRepository {
+	return &PostgresEmailQueueRepository{
+		PostgresRepository: base,
+	}
+}
+
+// Enqueue plaatst een nieuwe email in de wachtrij
+func (r *PostgresEmailQueueRepository) Enqueue(ctx context.Context, item *models.EmailQueueItem) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if item.Status == "" {
+		item.Status = models.EmailQueueStatusPending
+	}
+	if item.NextAttemptAt.IsZero() {
+		item.NextAttemptAt = time.Now()
+	}
+
+	result := r.DB().WithContext(ctx).Create(item)
+	return r.handleError("Enqueue", result.Error)
+}
+
+// ClaimBatch claimt maximaal limit openstaande items. Door FOR UPDATE SKIP LOCKED
+// kunnen meerdere workers (ook over instanties heen) veilig parallel claimen.
+func (r *PostgresEmailQueueRepository) ClaimBatch(ctx context.Context, limit int) ([]*models.EmailQueueItem, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var items []*models.EmailQueueItem
+	err := r.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.EmailQueueStatusPending, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&items)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(items))
+		now := time.Now()
+		for _, item := range items {
+			ids = append(ids, item.ID)
+			item.Status = models.EmailQueueStatusProcessing
+			item.Attempts++
+			item.LockedAt = &now
+		}
+
+		return tx.Model(&models.EmailQueueItem{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":    models.EmailQueueStatusProcessing,
+				"attempts":  gorm.Expr("attempts + 1"),
+				"locked_at": now,
+			}).Error
+	})
+
+	if err := r.handleError("ClaimBatch", err); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// MarkSent markeert een item als verzonden
+func (r *PostgresEmailQueueRepository) MarkSent(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailQueueItem{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.EmailQueueStatusSent,
+			"sent_at":    now,
+			"locked_at":  nil,
+			"last_error": "",
+		})
+
+	return r.handleError("MarkSent", result.Error)
+}
+
+// MarkFailed registreert een mislukte poging
+func (r *PostgresEmailQueueRepository) MarkFailed(ctx context.Context, id string, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	status := models.EmailQueueStatusPending
+	if dead {
+		status = models.EmailQueueStatusDead
+	}
+
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailQueueItem{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"last_error":      errMsg,
+			"next_attempt_at": nextAttemptAt,
+			"locked_at":       nil,
+		})
+
+	return r.handleError("MarkFailed", result.Error)
+}
+
+// ReleaseStale zet items die langer dan olderThan in processing staan terug naar pending.
+// Items die hun pogingen al hebben opgebruikt gaan naar de dead-letter en worden teruggegeven,
+// zodat een job die steeds een worker laat crashen niet eindeloos opnieuw wordt geprobeerd.
+func (r *PostgresEmailQueueRepository) ReleaseStale(ctx context.Context, olderThan time.Duration) (int64, []*models.EmailQueueItem, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var released int64
+	var dead []*models.EmailQueueItem
+	err := r.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stale []*models.EmailQueueItem
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND locked_at < ?", models.EmailQueueStatusProcessing, time.Now().Add(-olderThan)).
+			Find(&stale)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var retryIDs, deadIDs []string
+		for _, item := range stale {
+			if item.Attempts >= item.MaxAttempts {
+				item.Status = models.EmailQueueStatusDead
+				item.LastError = models.EmailQueueStaleError
+				item.LockedAt = nil
+				deadIDs = append(deadIDs, item.ID)
+				dead = append(dead, item)
+				continue
+			}
+			retryIDs = append(retryIDs, item.ID)
+		}
+
+		if len(retryIDs) > 0 {
+			if err := tx.Model(&models.EmailQueueItem{}).
+				Where("id IN ?", retryIDs).
+				Updates(map[string]interface{}{
+					"status":    models.EmailQueueStatusPending,
+					"locked_at": nil,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(deadIDs) > 0 {
+			if err := tx.Model(&models.EmailQueueItem{}).
+				Where("id IN ?", deadIDs).
+				Updates(map[string]interface{}{
+					"status":     models.EmailQueueStatusDead,
+					"last_error": models.EmailQueueStaleError,
+					"locked_at":  nil,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		released = int64(len(retryIDs))
+		return nil
+	})
+
+	if err := r.handleError("ReleaseStale", err); err != nil {
+		return 0, nil, err
+	}
+
+	return released, dead, nil
+}
+
+// GetByID haalt een queue item op basis van ID
+func (r *PostgresEmailQueueRepository) GetByID(ctx context.Context, id string) (*models.EmailQueueItem, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var item models.EmailQueueItem
+	result := r.DB().WithContext(ctx).First(&item, "id = ?", id)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &item, nil
+}
+
+// List haalt queue items op, optioneel gefilterd op status
+func (r *PostgresEmailQueueRepository) List(ctx context.Context, status string, limit, offset int) ([]*models.EmailQueueItem, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var items []*models.EmailQueueItem
+	result := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&items)
+
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Requeue zet een item terug in de wachtrij met een nieuwe set pogingen. Items die in
+// verwerking zijn of al zijn verzonden blijven ongemoeid, anders gaat een email twee keer uit.
+func (r *PostgresEmailQueueRepository) Requeue(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailQueueItem{}).
+		Where("id = ? AND status NOT IN ?", id, []string{models.EmailQueueStatusProcessing, models.EmailQueueStatusSent}).
+		Updates(map[string]interface{}{
+			"status":          models.EmailQueueStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_at":       nil,
+		})
+
+	return r.handleError("Requeue", result.Error)
+}
+
+// CountByStatus telt het aantal items per status
+func (r *PostgresEmailQueueRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailQueueItem{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows)
+
+	if err := r.handleError("CountByStatus", result.Error); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"strings"
+)
+
+// PostgresEmailSuppressionRepository implementeert EmailSuppressionRepository met PostgreSQL
+type PostgresEmailSuppressionRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresEmailSuppressionRepository maakt een nieuwe PostgreSQL email suppressie repository
+func NewPostgresEmailSuppressionRepository(base *PostgresRepository) *PostgresEmailSuppressionRepository {
+	return &PostgresEmailSuppressionRepository{
+		PostgresRepository: base,
+	}
+}
+
+// GetByEmail haalt de suppressie van een adres op
+func (r *PostgresEmailSuppressionRepository) GetByEmail(ctx context.Context, email string) (*models.EmailSuppression, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var suppression models.EmailSuppression
+	result := r.DB().WithContext(ctx).
+		Where("email = ?", strings.ToLower(strings.TrimSpace(email))).
+		First(&suppression)
+	if err := r.handleError("GetByEmail", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &suppression, nil
+}
+
+// Save slaat een suppressie op of werkt deze bij
+func (r *PostgresEmailSuppressionRepository) Save(ctx context.Context, suppression *models.EmailSuppression) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	suppression.Email = strings.ToLower(strings.TrimSpace(suppression.Email))
+	result := r.DB().WithContext(ctx).Save(suppression)
+	return r.handleError("Save", result.Error)
+}
+
+// FindSuppressed geeft de adressen uit de lijst terug waarvoor een actieve suppressie bestaat
+func (r *PostgresEmailSuppressionRepository) FindSuppressed(ctx context.Context, emails []string) ([]string, error) {
+	suppressed := []string{}
+	if len(emails) == 0 {
+		return suppressed, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	normalized := make([]string, len(emails))
+	for i, email := range emails {
+		normalized[i] = strings.ToLower(strings.TrimSpace(email))
+	}
+
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailSuppression{}).
+		Where("actief = ? AND email IN ?", true, normalized).
+		Pluck("email", &suppressed)
+
+	if err := r.handleError("FindSuppressed", result.Error); err != nil {
+		return nil, err
+	}
+
+	return suppressed, nil
+}
+
+// FindUnsubscribed geeft de adressen uit de lijst terug die zich blijvend voor de nieuwsbrief hebben afgemeld
+func (r *PostgresEmailSuppressionRepository) FindUnsubscribed(ctx context.Context, emails []string) ([]string, error) {
+	unsubscribed := []string{}
+	if len(emails) == 0 {
+		return unsubscribed, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	normalized := make([]string, len(emails))
+	for i, email := range emails {
+		normalized[i] = strings.ToLower(strings.TrimSpace(email))
+	}
+
+	result := r.DB().WithContext(ctx).
+		Model(&models.EmailSuppression{}).
+		Where("nieuwsbrief_afgemeld = ? AND email IN ?", true, normalized).
+		Pluck("email", &unsubscribed)
+
+	if err := r.handleError("FindUnsubscribed", result.Error); err != nil {
+		return nil, err
+	}
+
+	return unsubscribed, nil
+}
+
+// List haalt een gepagineerde lijst van suppressies op, optioneel alleen de actieve
+func (r *PostgresEmailSuppressionRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.EmailSuppression, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.EmailSuppression{})
+	if activeOnly {
+		query = query.Where("actief = ?", true)
+	}
+
+	var total int64
+	if err := r.handleError("List", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var suppressions []*models.EmailSuppression
+	result := query.
+		Order("last_bounce_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&suppressions)
+
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, 0, err
+	}
+
+	return suppressions, total, nil
+}
+
+// Delete verwijdert de suppressie van een adres, zodat er weer naar verzonden wordt
+func (r *PostgresEmailSuppressionRepository) Delete(ctx context.Context, email string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Where("email = ?", strings.ToLower(strings.TrimSpace(email))).
+		Delete(&models.EmailSuppression{})
+	return r.handleError("Delete", result.Error)
+}
@@ -116,3 +116,50 @@ func (r *PostgresEmailTemplateRepository) FindActive(ctx context.Context) ([]*mo
 
 	return templates, nil
 }
+
+// CreateVersie slaat een eerdere versie van een email template op
+func (r *PostgresEmailTemplateRepository) CreateVersie(ctx context.Context, versie *models.EmailTemplateVersie) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Create(versie)
+	return r.handleError("CreateVersie", result.Error)
+}
+
+// ListVersies haalt de versiegeschiedenis van een email template op, nieuwste eerst
+func (r *PostgresEmailTemplateRepository) ListVersies(ctx context.Context, templateID string) ([]*models.EmailTemplateVersie, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var versies []*models.EmailTemplateVersie
+	result := r.DB().WithContext(ctx).
+		Where("template_id = ?", templateID).
+		Order("versie DESC").
+		Find(&versies)
+
+	if err := r.handleError("ListVersies", result.Error); err != nil {
+		return nil, err
+	}
+
+	return versies, nil
+}
+
+// GetVersie haalt een specifieke versie van een email template op
+func (r *PostgresEmailTemplateRepository) GetVersie(ctx context.Context, templateID string, versie int) (*models.EmailTemplateVersie, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var result models.EmailTemplateVersie
+	query := r.DB().WithContext(ctx).
+		Where("template_id = ? AND versie = ?", templateID, versie).
+		First(&result)
+	if err := r.handleError("GetVersie", query.Error); err != nil {
+		return nil, err
+	}
+
+	if query.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+)
+
+// PostgresEventRepository implementeert EventRepository met PostgreSQL
+type PostgresEventRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresEventRepository maakt een nieuwe PostgreSQL repository voor edities
+func NewPostgresEventRepository(base *PostgresRepository) *PostgresEventRepository {
+	return &PostgresEventRepository{PostgresRepository: base}
+}
+
+// Create slaat een nieuwe editie op, inclusief eventuele route bedragen
+func (r *PostgresEventRepository) Create(ctx context.Context, event *models.Event) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(event)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByYear haalt de editie van een jaar op; geeft nil terug als die niet bestaat
+func (r *PostgresEventRepository) GetByYear(ctx context.Context, year int) (*models.Event, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var event models.Event
+	result := r.DB().WithContext(ctx).Where("jaar = ?", year).Limit(1).Find(&event)
+	if err := r.handleError("GetByYear", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+// GetLatest haalt de meest recente editie op; geeft nil terug als er geen is
+func (r *PostgresEventRepository) GetLatest(ctx context.Context) (*models.Event, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var event models.Event
+	result := r.DB().WithContext(ctx).Order("jaar DESC").Limit(1).Find(&event)
+	if err := r.handleError("GetLatest", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+// List haalt alle edities op, nieuwste eerst
+func (r *PostgresEventRepository) List(ctx context.Context) ([]*models.Event, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var events []*models.Event
+	result := r.DB().WithContext(ctx).Order("jaar DESC").Find(&events)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Update werkt een editie bij, zonder de route bedragen
+func (r *PostgresEventRepository) Update(ctx context.Context, event *models.Event) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Omit("RouteFunds").Save(event)
+	return r.handleError("Update", result.Error)
+}
+
+// Delete verwijdert een editie; de route bedragen gaan mee
+func (r *PostgresEventRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Delete(&models.Event{}, "id = ?", id)
+	return r.handleError("Delete", result.Error)
+}
+
+// RouteStats telt per afstand de deelnemers en hun stappen voor aanmeldingen tussen from en to.
+// Aanmeldingen in test mode tellen niet mee.
+func (r *PostgresEventRepository) RouteStats(ctx context.Context, from, to time.Time) ([]models.RouteStats, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var stats []models.RouteStats
+	result := r.DB().WithContext(ctx).Raw(`
+		SELECT a.afstand AS route,
+			COUNT(*) AS participants,
+			COALESCE(SUM((SELECT SUM(se.delta) FROM step_entries se WHERE se.aanmelding_id = a.id)), 0) AS steps
+		FROM aanmeldingen a
+		WHERE a.test_mode = FALSE AND a.created_at >= ? AND a.created_at < ?
+		GROUP BY a.afstand`,
+		from, to).
+		Scan(&stats)
+	if err := r.handleError("RouteStats", result.Error); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -15,7 +15,21 @@ type Repository struct {
 	EmailTemplate          EmailTemplateRepository
 	Migratie               MigratieRepository
 	IncomingEmail          IncomingEmailRepository
+	IncomingAttachment     IncomingEmailAttachmentRepository
+	MailSyncState          MailSyncStateRepository
+	EmailSuppression       EmailSuppressionRepository
+	NewsletterEvent        NewsletterSubscriptionEventRepository
+	NewsletterSegment      NewsletterSegmentRepository
+	NewsletterDelivery     NewsletterDeliveryRepository
+	NewsletterSubscriber   NewsletterSubscriberRepository
+	NewsletterSource       NewsletterSourceRepository
+	NewsletterSentItem     NewsletterSentItemRepository
 	Notification           NotificationRepository
+	NotificationDelivery   NotificationDeliveryRepository
+	UserNotification       UserNotificationRepository
+	NotificationPreference NotificationPreferenceRepository
+	NotificationThrottle   NotificationThrottleRepository
+	TelegramChat           TelegramChatRepository
 	ChatChannel            ChatChannelRepository
 	ChatChannelParticipant ChatChannelParticipantRepository
 	ChatMessage            ChatMessageRepository
@@ -36,6 +50,13 @@ type Repository struct {
 	UnderConstruction      UnderConstructionRepository
 	TitleSection           TitleSectionRepository
 	RouteFund              RouteFundRepository
+	Event                  EventRepository
+	StepEntry              StepEntryRepository
+	StepTeam               StepTeamRepository
+	Leaderboard            LeaderboardRepository
+	StepMilestone          StepMilestoneRepository
+	Pledge                 PledgeRepository
+	EmailQueue             EmailQueueRepository
 
 	// RBAC repositories
 	RBACRole       RBACRoleRepository
@@ -59,7 +80,21 @@ func NewRepository(db *gorm.DB) *Repository {
 		EmailTemplate:          NewPostgresEmailTemplateRepository(baseRepo),
 		Migratie:               NewPostgresMigratieRepository(baseRepo),
 		IncomingEmail:          NewPostgresIncomingEmailRepository(db),
+		IncomingAttachment:     NewPostgresIncomingEmailAttachmentRepository(baseRepo),
+		MailSyncState:          NewPostgresMailSyncStateRepository(baseRepo),
+		EmailSuppression:       NewPostgresEmailSuppressionRepository(baseRepo),
+		NewsletterEvent:        NewPostgresNewsletterSubscriptionEventRepository(baseRepo),
+		NewsletterSegment:      NewPostgresNewsletterSegmentRepository(baseRepo),
+		NewsletterDelivery:     NewPostgresNewsletterDeliveryRepository(baseRepo),
+		NewsletterSubscriber:   NewPostgresNewsletterSubscriberRepository(baseRepo),
+		NewsletterSource:       NewPostgresNewsletterSourceRepository(baseRepo),
+		NewsletterSentItem:     NewPostgresNewsletterSentItemRepository(baseRepo),
 		Notification:           NewPostgresNotificationRepository(baseRepo),
+		NotificationDelivery:   NewPostgresNotificationDeliveryRepository(baseRepo),
+		UserNotification:       NewPostgresUserNotificationRepository(baseRepo),
+		NotificationPreference: NewPostgresNotificationPreferenceRepository(baseRepo),
+		NotificationThrottle:   NewPostgresNotificationThrottleRepository(baseRepo),
+		TelegramChat:           NewPostgresTelegramChatRepository(baseRepo),
 		ChatChannel:            NewPostgresChatChannelRepository(baseRepo),
 		ChatChannelParticipant: NewPostgresChatChannelParticipantRepository(baseRepo),
 		ChatMessage:            NewPostgresChatMessageRepository(baseRepo),
@@ -80,6 +115,13 @@ func NewRepository(db *gorm.DB) *Repository {
 		UnderConstruction:      NewPostgresUnderConstructionRepository(db),
 		TitleSection:           NewPostgresTitleSectionRepository(db),
 		RouteFund:              NewRouteFundRepository(db),
+		Event:                  NewPostgresEventRepository(baseRepo),
+		StepEntry:              NewPostgresStepEntryRepository(baseRepo),
+		StepTeam:               NewPostgresStepTeamRepository(baseRepo),
+		Leaderboard:            NewPostgresLeaderboardRepository(baseRepo),
+		StepMilestone:          NewPostgresStepMilestoneRepository(baseRepo),
+		Pledge:                 NewPostgresPledgeRepository(baseRepo),
+		EmailQueue:             NewPostgresEmailQueueRepository(baseRepo),
 
 		// RBAC repositories
 		RBACRole:       NewRBACRoleRepository(db),
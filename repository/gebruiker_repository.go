@@ -64,6 +64,24 @@ func (r *PostgresGebruikerRepository) GetByEmail(ctx context.Context, email stri
 	return &gebruiker, nil
 }
 
+// GetByTelegramUserID haalt de gebruiker op die aan een Telegram account is gekoppeld
+func (r *PostgresGebruikerRepository) GetByTelegramUserID(ctx context.Context, telegramUserID int64) (*models.Gebruiker, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var gebruiker models.Gebruiker
+	result := r.DB().WithContext(ctx).Where("telegram_user_id = ?", telegramUserID).First(&gebruiker)
+	if err := r.handleError("GetByTelegramUserID", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &gebruiker, nil
+}
+
 // List haalt een lijst van gebruikers op
 func (r *PostgresGebruikerRepository) List(ctx context.Context, limit, offset int) ([]*models.Gebruiker, error) {
 	ctx, cancel := r.withTimeout(ctx)
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+)
+
+// PostgresIncomingEmailAttachmentRepository implementeert IncomingEmailAttachmentRepository met PostgreSQL
+type PostgresIncomingEmailAttachmentRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresIncomingEmailAttachmentRepository maakt een nieuwe PostgreSQL bijlage repository
+func NewPostgresIncomingEmailAttachmentRepository(base *PostgresRepository) *PostgresIncomingEmailAttachmentRepository {
+	return &PostgresIncomingEmailAttachmentRepository{
+		PostgresRepository: base,
+	}
+}
+
+// ListByEmailID haalt de bijlagen van een e-mail op zonder de inhoud te laden
+func (r *PostgresIncomingEmailAttachmentRepository) ListByEmailID(ctx context.Context, emailID string) ([]*models.IncomingEmailAttachment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var attachments []*models.IncomingEmailAttachment
+	result := r.DB().WithContext(ctx).
+		Omit("data").
+		Where("email_id = ?", emailID).
+		Order("created_at ASC").
+		Find(&attachments)
+
+	if err := r.handleError("ListByEmailID", result.Error); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetByID haalt een bijlage inclusief inhoud op
+func (r *PostgresIncomingEmailAttachmentRepository) GetByID(ctx context.Context, emailID, id string) (*models.IncomingEmailAttachment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var attachment models.IncomingEmailAttachment
+	result := r.DB().WithContext(ctx).
+		Where("id = ? AND email_id = ?", id, emailID).
+		First(&attachment)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &attachment, nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PostgresIncomingEmailRepository implementeert IncomingEmailRepository met PostgreSQL
@@ -57,6 +59,7 @@ func (r *PostgresIncomingEmailRepository) List(ctx context.Context, limit, offse
 	var emails []*models.IncomingEmail
 
 	err := r.db.WithContext(ctx).
+		Where("imap_delete_pending = ?", false).
 		Order("received_at desc").
 		Limit(limit).
 		Offset(offset).
@@ -72,7 +75,8 @@ func (r *PostgresIncomingEmailRepository) List(ctx context.Context, limit, offse
 
 // Update werkt een bestaande inkomende e-mail bij
 func (r *PostgresIncomingEmailRepository) Update(ctx context.Context, email *models.IncomingEmail) error {
-	err := r.db.WithContext(ctx).Save(email).Error
+	// Bijlagen zijn onveranderlijk; voorkom dat ze bij elke update opnieuw worden weggeschreven
+	err := r.db.WithContext(ctx).Omit(clause.Associations).Save(email).Error
 	if err != nil {
 		logger.Error("Fout bij bijwerken inkomende e-mail", "error", err, "id", email.ID)
 		return err
@@ -113,7 +117,7 @@ func (r *PostgresIncomingEmailRepository) FindUnprocessed(ctx context.Context) (
 	var emails []*models.IncomingEmail
 
 	err := r.db.WithContext(ctx).
-		Where("is_processed = ?", false).
+		Where("is_processed = ? AND imap_delete_pending = ?", false, false).
 		Order("received_at asc").
 		Find(&emails).Error
 
@@ -130,7 +134,7 @@ func (r *PostgresIncomingEmailRepository) FindByAccountType(ctx context.Context,
 	var emails []*models.IncomingEmail
 
 	err := r.db.WithContext(ctx).
-		Where("account_type = ?", accountType).
+		Where("account_type = ? AND imap_delete_pending = ?", accountType, false).
 		Order("received_at desc").
 		Find(&emails).Error
 
@@ -155,7 +159,7 @@ func (r *PostgresIncomingEmailRepository) ListByAccountTypePaginated(ctx context
 	}
 
 	// Query om het totaal aantal te tellen
-	countQuery := tx.Model(&models.IncomingEmail{}).Where("account_type = ?", accountType)
+	countQuery := tx.Model(&models.IncomingEmail{}).Where("account_type = ? AND imap_delete_pending = ?", accountType, false)
 	if err := countQuery.Count(&totalCount).Error; err != nil {
 		tx.Rollback() // Rollback bij fout
 		logger.Error("Fout bij tellen inkomende e-mails op account type", "error", err, "account_type", accountType)
@@ -164,7 +168,7 @@ func (r *PostgresIncomingEmailRepository) ListByAccountTypePaginated(ctx context
 
 	// Query om de gepagineerde data op te halen
 	dataQuery := tx.Model(&models.IncomingEmail{}).
-		Where("account_type = ?", accountType).
+		Where("account_type = ? AND imap_delete_pending = ?", accountType, false).
 		Order("received_at desc").
 		Limit(limit).
 		Offset(offset)
@@ -185,3 +189,109 @@ func (r *PostgresIncomingEmailRepository) ListByAccountTypePaginated(ctx context
 
 	return emails, totalCount, nil
 }
+
+// FindByMailboxUID zoekt een inkomende e-mail op basis van account, mailbox, UIDVALIDITY en UID
+func (r *PostgresIncomingEmailRepository) FindByMailboxUID(ctx context.Context, accountType, mailbox string, uidValidity uint32, uid string) (*models.IncomingEmail, error) {
+	var email models.IncomingEmail
+
+	err := r.db.WithContext(ctx).
+		Where("account_type = ? AND mailbox = ? AND uid_validity = ? AND uid = ?", accountType, mailbox, uidValidity, uid).
+		First(&email).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		logger.Error("Fout bij zoeken inkomende e-mail op mailbox UID", "error", err, "account_type", accountType, "mailbox", mailbox, "uid", uid)
+		return nil, err
+	}
+
+	return &email, nil
+}
+
+// ListByMailbox haalt de e-mails uit één mailbox op die sinds het opgegeven moment zijn ontvangen
+func (r *PostgresIncomingEmailRepository) ListByMailbox(ctx context.Context, accountType, mailbox string, uidValidity uint32, since time.Time) ([]*models.IncomingEmail, error) {
+	var emails []*models.IncomingEmail
+
+	err := r.db.WithContext(ctx).
+		Where("account_type = ? AND mailbox = ? AND uid_validity = ? AND received_at >= ?", accountType, mailbox, uidValidity, since).
+		Order("received_at asc").
+		Find(&emails).Error
+
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mails per mailbox", "error", err, "account_type", accountType, "mailbox", mailbox)
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// FindSyncPending haalt e-mails op waarvan een actie of verwijdering nog naar de mailserver moet worden teruggeschreven
+func (r *PostgresIncomingEmailRepository) FindSyncPending(ctx context.Context) ([]*models.IncomingEmail, error) {
+	var emails []*models.IncomingEmail
+
+	err := r.db.WithContext(ctx).
+		Where("imap_sync_pending = ? OR imap_delete_pending = ?", true, true).
+		Order("updated_at asc").
+		Find(&emails).Error
+
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mails met openstaande synchronisatie", "error", err)
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// FindByThreadIDs haalt alle e-mails op die bij één van de opgegeven gesprekken horen
+func (r *PostgresIncomingEmailRepository) FindByThreadIDs(ctx context.Context, threadIDs []string) ([]*models.IncomingEmail, error) {
+	var emails []*models.IncomingEmail
+	if len(threadIDs) == 0 {
+		return emails, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("thread_id IN ? OR message_id IN ?", threadIDs, threadIDs).
+		Order("received_at asc").
+		Find(&emails).Error
+
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mails per gesprek", "error", err)
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// FindByAanmeldingID haalt de e-mails op die aan een aanmelding zijn gekoppeld
+func (r *PostgresIncomingEmailRepository) FindByAanmeldingID(ctx context.Context, aanmeldingID string) ([]*models.IncomingEmail, error) {
+	var emails []*models.IncomingEmail
+
+	err := r.db.WithContext(ctx).
+		Where("aanmelding_id = ?", aanmeldingID).
+		Order("received_at asc").
+		Find(&emails).Error
+
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mails per aanmelding", "error", err, "aanmelding_id", aanmeldingID)
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// FindByContactID haalt de e-mails op die aan een contactformulier zijn gekoppeld
+func (r *PostgresIncomingEmailRepository) FindByContactID(ctx context.Context, contactID string) ([]*models.IncomingEmail, error) {
+	var emails []*models.IncomingEmail
+
+	err := r.db.WithContext(ctx).
+		Where("contact_id = ?", contactID).
+		Order("received_at asc").
+		Find(&emails).Error
+
+	if err != nil {
+		logger.Error("Fout bij ophalen e-mails per contactformulier", "error", err, "contact_id", contactID)
+		return nil, err
+	}
+
+	return emails, nil
+}
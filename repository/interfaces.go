@@ -112,6 +112,15 @@ type EmailTemplateRepository interface {
 
 	// FindActive haalt alle actieve email templates op
 	FindActive(ctx context.Context) ([]*models.EmailTemplate, error)
+
+	// CreateVersie slaat een eerdere versie van een email template op
+	CreateVersie(ctx context.Context, versie *models.EmailTemplateVersie) error
+
+	// ListVersies haalt de versiegeschiedenis van een email template op
+	ListVersies(ctx context.Context, templateID string) ([]*models.EmailTemplateVersie, error)
+
+	// GetVersie haalt een specifieke versie van een email template op
+	GetVersie(ctx context.Context, templateID string, versie int) (*models.EmailTemplateVersie, error)
 }
 
 // VerzondEmailRepository definieert de interface voor verzonden email operaties
@@ -136,6 +145,18 @@ type VerzondEmailRepository interface {
 
 	// FindByOntvanger haalt verzonden emails op basis van ontvanger
 	FindByOntvanger(ctx context.Context, ontvanger string) ([]*models.VerzondEmail, error)
+
+	// ListFiltered haalt verzonden emails op met filters en geeft ook het totaal aantal terug
+	ListFiltered(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]*models.VerzondEmail, int64, error)
+
+	// UpdateStatus werkt de status en eventuele foutmelding van een verzonden email bij
+	UpdateStatus(ctx context.Context, id, status, foutBericht string) error
+
+	// FindByThreadID haalt verzonden emails op die bij een gesprek horen
+	FindByThreadID(ctx context.Context, threadID string) ([]*models.VerzondEmail, error)
+
+	// FindByMessageIDs haalt verzonden emails op basis van hun Message-ID op
+	FindByMessageIDs(ctx context.Context, messageIDs []string) ([]*models.VerzondEmail, error)
 }
 
 // GebruikerRepository definieert de interface voor gebruiker operaties
@@ -149,6 +170,9 @@ type GebruikerRepository interface {
 	// GetByEmail haalt een gebruiker op basis van email
 	GetByEmail(ctx context.Context, email string) (*models.Gebruiker, error)
 
+	// GetByTelegramUserID haalt de gebruiker op die aan een Telegram account is gekoppeld
+	GetByTelegramUserID(ctx context.Context, telegramUserID int64) (*models.Gebruiker, error)
+
 	// List haalt een lijst van gebruikers op
 	List(ctx context.Context, limit, offset int) ([]*models.Gebruiker, error)
 
@@ -200,6 +224,9 @@ type IncomingEmailRepository interface {
 	// FindByUID zoekt een inkomende e-mail op basis van UID
 	FindByUID(ctx context.Context, uid string) (*models.IncomingEmail, error)
 
+	// FindByMailboxUID zoekt een inkomende e-mail op basis van account, mailbox, UIDVALIDITY en UID
+	FindByMailboxUID(ctx context.Context, accountType, mailbox string, uidValidity uint32, uid string) (*models.IncomingEmail, error)
+
 	// FindUnprocessed haalt alle onverwerkte e-mails op
 	FindUnprocessed(ctx context.Context) ([]*models.IncomingEmail, error)
 
@@ -208,6 +235,168 @@ type IncomingEmailRepository interface {
 
 	// ListByAccountTypePaginated haalt een lijst van inkomende e-mails op basis van account type en paginatie
 	ListByAccountTypePaginated(ctx context.Context, accountType string, limit, offset int) ([]*models.IncomingEmail, int64, error)
+
+	// ListByMailbox haalt de e-mails uit één mailbox op die sinds het opgegeven moment zijn ontvangen
+	ListByMailbox(ctx context.Context, accountType, mailbox string, uidValidity uint32, since time.Time) ([]*models.IncomingEmail, error)
+
+	// FindSyncPending haalt e-mails op waarvan een actie of verwijdering nog naar de mailserver moet worden teruggeschreven
+	FindSyncPending(ctx context.Context) ([]*models.IncomingEmail, error)
+
+	// FindByThreadIDs haalt alle e-mails op die bij één van de opgegeven gesprekken horen
+	FindByThreadIDs(ctx context.Context, threadIDs []string) ([]*models.IncomingEmail, error)
+
+	// FindByAanmeldingID haalt de e-mails op die aan een aanmelding zijn gekoppeld
+	FindByAanmeldingID(ctx context.Context, aanmeldingID string) ([]*models.IncomingEmail, error)
+
+	// FindByContactID haalt de e-mails op die aan een contactformulier zijn gekoppeld
+	FindByContactID(ctx context.Context, contactID string) ([]*models.IncomingEmail, error)
+}
+
+// MailSyncStateRepository definieert de interface voor de IMAP synchronisatiestatus
+type MailSyncStateRepository interface {
+	// Get haalt de status van een mailbox van een account op
+	Get(ctx context.Context, accountType, mailbox string) (*models.MailSyncState, error)
+
+	// Save slaat de status op of werkt deze bij
+	Save(ctx context.Context, state *models.MailSyncState) error
+
+	// List haalt de status van alle mailboxen op
+	List(ctx context.Context) ([]*models.MailSyncState, error)
+}
+
+// EmailSuppressionRepository definieert de interface voor de suppressielijst van bouncende adressen
+type EmailSuppressionRepository interface {
+	// GetByEmail haalt de suppressie van een adres op
+	GetByEmail(ctx context.Context, email string) (*models.EmailSuppression, error)
+
+	// Save slaat een suppressie op of werkt deze bij
+	Save(ctx context.Context, suppression *models.EmailSuppression) error
+
+	// FindSuppressed geeft de adressen uit de lijst terug waarvoor een actieve suppressie bestaat
+	FindSuppressed(ctx context.Context, emails []string) ([]string, error)
+
+	// FindUnsubscribed geeft de adressen uit de lijst terug die zich blijvend voor de nieuwsbrief hebben afgemeld
+	FindUnsubscribed(ctx context.Context, emails []string) ([]string, error)
+
+	// List haalt een gepagineerde lijst van suppressies op, optioneel alleen de actieve
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.EmailSuppression, int64, error)
+
+	// Delete verwijdert de suppressie van een adres
+	Delete(ctx context.Context, email string) error
+}
+
+// NewsletterSubscriptionEventRepository definieert de interface voor de audit van nieuwsbrief aan- en afmeldingen
+type NewsletterSubscriptionEventRepository interface {
+	// Create legt een aan- of afmelding vast
+	Create(ctx context.Context, event *models.NewsletterSubscriptionEvent) error
+
+	// List haalt de aan- en afmeldingen op, nieuwste eerst, optioneel voor één adres
+	List(ctx context.Context, email string, limit, offset int) ([]*models.NewsletterSubscriptionEvent, int64, error)
+}
+
+// NewsletterSegmentRepository definieert de interface voor opgeslagen doelgroepen van de nieuwsbrief
+type NewsletterSegmentRepository interface {
+	// Create slaat een nieuw segment op
+	Create(ctx context.Context, segment *models.NewsletterSegment) error
+
+	// GetByID haalt een segment op basis van ID
+	GetByID(ctx context.Context, id string) (*models.NewsletterSegment, error)
+
+	// List haalt alle segmenten op
+	List(ctx context.Context) ([]*models.NewsletterSegment, error)
+
+	// Update werkt een bestaand segment bij
+	Update(ctx context.Context, segment *models.NewsletterSegment) error
+
+	// Delete verwijdert een segment
+	Delete(ctx context.Context, id string) error
+
+	// ResolveEmails geeft de unieke adressen terug die aan het filter voldoen
+	ResolveEmails(ctx context.Context, filter models.SegmentFilter) ([]string, error)
+}
+
+// NewsletterDeliveryRepository definieert de interface voor de aflevering van nieuwsbrieven per ontvanger
+type NewsletterDeliveryRepository interface {
+	// CreateQueued legt per ontvanger een aflevering met status queued vast
+	CreateQueued(ctx context.Context, newsletterID string, emails []string) ([]*models.NewsletterDelivery, error)
+
+	// MarkSent markeert een aflevering als verzonden
+	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+
+	// MarkFailed markeert een aflevering als mislukt
+	MarkFailed(ctx context.Context, id, foutBericht string) error
+
+	// MarkBounced markeert de meest recente verzonden nieuwsbrief aan een adres als gebounced
+	MarkBounced(ctx context.Context, email string, bouncedAt time.Time) (bool, error)
+
+	// RecordOpen telt een open van een aflevering
+	RecordOpen(ctx context.Context, id string, openedAt time.Time) (bool, error)
+
+	// RecordClick telt een klik van een aflevering
+	RecordClick(ctx context.Context, id string, clickedAt time.Time) (bool, error)
+
+	// GetStats telt de afleveringen, opens en kliks van een nieuwsbrief
+	GetStats(ctx context.Context, newsletterID string) (*models.NewsletterStats, error)
+
+	// ListByNewsletter haalt de afleveringen van een nieuwsbrief op, optioneel met één status
+	ListByNewsletter(ctx context.Context, newsletterID, status string, limit, offset int) ([]*models.NewsletterDelivery, int64, error)
+}
+
+// NewsletterSubscriberRepository definieert de interface voor nieuwsbrief abonnees zonder account
+type NewsletterSubscriberRepository interface {
+	// GetByEmail haalt een abonnee op basis van email adres
+	GetByEmail(ctx context.Context, email string) (*models.NewsletterSubscriber, error)
+
+	// Save slaat een nieuwe abonnee op of werkt een bestaande bij
+	Save(ctx context.Context, subscriber *models.NewsletterSubscriber) error
+
+	// ListConfirmedEmails haalt de adressen van alle bevestigde abonnees op
+	ListConfirmedEmails(ctx context.Context) ([]string, error)
+
+	// List haalt een gepagineerde lijst van abonnees op, optioneel met één status
+	List(ctx context.Context, status string, limit, offset int) ([]*models.NewsletterSubscriber, int64, error)
+}
+
+// NewsletterSourceRepository definieert de interface voor de bronnen van de automatische nieuwsbrief
+type NewsletterSourceRepository interface {
+	// Create slaat een nieuwe bron op
+	Create(ctx context.Context, source *models.NewsletterSource) error
+
+	// GetByID haalt een bron op basis van ID
+	GetByID(ctx context.Context, id string) (*models.NewsletterSource, error)
+
+	// List haalt alle bronnen op
+	List(ctx context.Context) ([]*models.NewsletterSource, error)
+
+	// ListActive haalt de actieve bronnen op
+	ListActive(ctx context.Context) ([]*models.NewsletterSource, error)
+
+	// Update werkt een bestaande bron bij
+	Update(ctx context.Context, source *models.NewsletterSource) error
+
+	// Delete verwijdert een bron
+	Delete(ctx context.Context, id string) error
+
+	// MarkFetched legt het tijdstip en de eventuele fout van de laatste keer ophalen vast
+	MarkFetched(ctx context.Context, id string, fetchedAt time.Time, lastError string) error
+}
+
+// NewsletterSentItemRepository definieert de interface voor nieuwsitems die al zijn verzonden
+type NewsletterSentItemRepository interface {
+	// FilterSent geeft de sleutels terug die al in een eerdere nieuwsbrief hebben gestaan
+	FilterSent(ctx context.Context, keys []string) (map[string]bool, error)
+
+	// MarkSent legt vast dat de items zijn verzonden
+	MarkSent(ctx context.Context, items []*models.NewsletterSentItem) error
+}
+
+// IncomingEmailAttachmentRepository definieert de interface voor bijlagen van inkomende e-mails
+type IncomingEmailAttachmentRepository interface {
+	// ListByEmailID haalt de bijlagen van een e-mail op zonder de inhoud
+	ListByEmailID(ctx context.Context, emailID string) ([]*models.IncomingEmailAttachment, error)
+
+	// GetByID haalt een bijlage van een e-mail inclusief inhoud op
+	GetByID(ctx context.Context, emailID, id string) (*models.IncomingEmailAttachment, error)
 }
 
 // NewsletterRepository definieert de interface voor nieuwsbrief operaties
@@ -232,6 +421,16 @@ type NewsletterRepository interface {
 
 	// MarkSent markeert een nieuwsbrief als verzonden
 	MarkSent(ctx context.Context, id string, sentAt time.Time) error
+
+	// TransitionStatus zet de status alleen om als de huidige status in from staat en geeft
+	// terug of dat gelukt is. Zo kan een nieuwsbrief maar door één proces verzonden worden.
+	TransitionStatus(ctx context.Context, id string, from []string, to string) (bool, error)
+
+	// MarkFailed zet de status op failed met de foutmelding
+	MarkFailed(ctx context.Context, id, foutBericht string) error
+
+	// ListDue haalt ingeplande nieuwsbrieven op waarvan het tijdstip is verstreken
+	ListDue(ctx context.Context, now time.Time) ([]*models.Newsletter, error)
 }
 
 // NotificationRepository definieert de interface voor notificaties
@@ -258,6 +457,207 @@ type NotificationRepository interface {
 	ListByPriority(ctx context.Context, priority models.NotificationPriority) ([]*models.Notification, error)
 }
 
+// NotificationDeliveryRepository definieert de interface voor de aflevering van notificaties per kanaal
+type NotificationDeliveryRepository interface {
+	// EnsureDeliveries maakt een openstaande aflevering aan voor elk kanaal dat er nog geen heeft
+	EnsureDeliveries(ctx context.Context, notificationID string, channels []string) error
+
+	// ListByNotification haalt de afleveringen van een notificatie op
+	ListByNotification(ctx context.Context, notificationID string) ([]*models.NotificationDelivery, error)
+
+	// ListDue haalt de openstaande afleveringen van een kanaal op die nu geprobeerd mogen worden
+	ListDue(ctx context.Context, channel string, now time.Time) ([]*models.NotificationDelivery, error)
+
+	// Update werkt een aflevering bij
+	Update(ctx context.Context, delivery *models.NotificationDelivery) error
+}
+
+// NotificationThrottleRepository definieert de interface voor persistente throttling van notificaties
+type NotificationThrottleRepository interface {
+	// Hit registreert een notificatie voor een sleutel en geeft terug of hij door mag; anders
+	// wordt hij geteld voor de digest aan het einde van het venster
+	Hit(ctx context.Context, key string, notification *models.Notification, windowEnd, now time.Time) (bool, error)
+
+	// ClaimDue claimt de digests waarvan het venster is verstreken en zet de tellers terug
+	ClaimDue(ctx context.Context, now time.Time) ([]*models.NotificationThrottle, error)
+
+	// DeleteExpired verwijdert verlopen vensters zonder openstaande digest
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// TelegramChatRepository definieert de interface voor het register van Telegram chats
+type TelegramChatRepository interface {
+	// Create registreert een nieuwe chat
+	Create(ctx context.Context, chat *models.TelegramChat) error
+
+	// GetByID haalt een chat op
+	GetByID(ctx context.Context, id string) (*models.TelegramChat, error)
+
+	// GetByChatID haalt een chat op met het Telegram chat ID
+	GetByChatID(ctx context.Context, chatID int64) (*models.TelegramChat, error)
+
+	// List haalt alle chats op
+	List(ctx context.Context) ([]*models.TelegramChat, error)
+
+	// Update werkt een chat bij
+	Update(ctx context.Context, chat *models.TelegramChat) error
+
+	// Delete verwijdert een chat uit het register
+	Delete(ctx context.Context, id string) error
+}
+
+// StepEntryRepository definieert de interface voor het stappen grootboek
+type StepEntryRepository interface {
+	// Record schrijft een regel en werkt het afgeleide totaal van de deelnemer atomair bij. Een
+	// aftrek wordt begrensd zodat het totaal niet onder nul komt. Bestaat er al een regel met
+	// dezelfde idempotency key, dan wordt entry daarmee gevuld en is created false.
+	Record(ctx context.Context, entry *models.StepEntry) (total int, created bool, err error)
+
+	// RecordDayTotal boekt voor een dagtotaal uit een export alleen het verschil met de import regels
+	// in hetzelfde formaat die al op entry.RecordedAt staan. Is er niets bij te boeken, dan is
+	// created false. Een herhaalde idempotency key telt net als bij Record maar één keer.
+	RecordDayTotal(ctx context.Context, entry *models.StepEntry, dayTotal int) (total int, created bool, err error)
+
+	// ListByAanmelding haalt de regels van een deelnemer op, nieuwste eerst
+	ListByAanmelding(ctx context.Context, aanmeldingID string, limit, offset int) ([]*models.StepEntry, error)
+
+	// DailyTotals telt de stappen van een deelnemer per dag in de gegeven tijdzone
+	DailyTotals(ctx context.Context, aanmeldingID string, from, to time.Time, timezone string) ([]models.StepDayTotal, error)
+
+	// SumByRegistrationPeriod telt de regels van deelnemers die zich tussen from en to hebben aangemeld
+	SumByRegistrationPeriod(ctx context.Context, from, to time.Time) (int, error)
+}
+
+// EventRepository definieert de interface voor edities met hun fondsenpot
+type EventRepository interface {
+	// Create slaat een nieuwe editie op
+	Create(ctx context.Context, event *models.Event) error
+
+	// GetByYear haalt de editie van een jaar op
+	GetByYear(ctx context.Context, year int) (*models.Event, error)
+
+	// GetLatest haalt de meest recente editie op
+	GetLatest(ctx context.Context) (*models.Event, error)
+
+	// List haalt alle edities op
+	List(ctx context.Context) ([]*models.Event, error)
+
+	// Update werkt een editie bij
+	Update(ctx context.Context, event *models.Event) error
+
+	// Delete verwijdert een editie
+	Delete(ctx context.Context, id string) error
+
+	// RouteStats telt per afstand de deelnemers en stappen van aanmeldingen in een periode
+	RouteStats(ctx context.Context, from, to time.Time) ([]models.RouteStats, error)
+}
+
+// StepTeamRepository definieert de interface voor teams in het stappen systeem
+type StepTeamRepository interface {
+	// Create slaat een nieuw team op
+	Create(ctx context.Context, team *models.StepTeam) error
+
+	// GetByID haalt een team op
+	GetByID(ctx context.Context, id string) (*models.StepTeam, error)
+
+	// List haalt alle teams op
+	List(ctx context.Context) ([]*models.StepTeam, error)
+
+	// Update werkt een team bij
+	Update(ctx context.Context, team *models.StepTeam) error
+
+	// Delete verwijdert een team
+	Delete(ctx context.Context, id string) error
+
+	// AddMembers zet deelnemers in een team
+	AddMembers(ctx context.Context, teamID string, aanmeldingIDs []string) error
+
+	// RemoveMember haalt een deelnemer uit een team
+	RemoveMember(ctx context.Context, teamID, aanmeldingID string) error
+
+	// ListMembers haalt de leden van een team op
+	ListMembers(ctx context.Context, teamID string) ([]*models.Aanmelding, error)
+}
+
+// LeaderboardRepository definieert de interface voor klassementen op basis van het stappen grootboek
+type LeaderboardRepository interface {
+	// Participants geeft de deelnemers met de meeste stappen
+	Participants(ctx context.Context, filter models.LeaderboardFilter) ([]models.LeaderboardEntry, error)
+
+	// Teams geeft de teams met de meeste stappen
+	Teams(ctx context.Context, filter models.LeaderboardFilter) ([]models.TeamLeaderboardEntry, error)
+
+	// SetVisibility legt vast of een deelnemer met naam op het openbare klassement wil
+	SetVisibility(ctx context.Context, aanmeldingID string, visible bool) error
+}
+
+// StepMilestoneRepository definieert de interface voor behaalde mijlpalen
+type StepMilestoneRepository interface {
+	// Award kent een mijlpaal toe; geeft false terug als de deelnemer hem al had
+	Award(ctx context.Context, milestone *models.StepMilestone) (bool, error)
+
+	// ListByAanmelding haalt de mijlpalen van een deelnemer op
+	ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.StepMilestone, error)
+
+	// MarkEmailSent legt vast dat de felicitatie is verstuurd
+	MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error
+}
+
+// PledgeRepository definieert de interface voor toezeggingen van sponsors aan deelnemers
+type PledgeRepository interface {
+	// Create slaat een nieuwe toezegging op
+	Create(ctx context.Context, pledge *models.Pledge) error
+
+	// GetByID haalt een toezegging op; nil als ze niet bestaat
+	GetByID(ctx context.Context, id string) (*models.Pledge, error)
+
+	// ListByAanmelding haalt de toezeggingen voor een deelnemer op
+	ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.Pledge, error)
+
+	// List haalt toezeggingen op met filter en paginering, met het totaal aantal
+	List(ctx context.Context, filter models.PledgeFilter) ([]*models.Pledge, int64, error)
+
+	// Update werkt een toezegging bij
+	Update(ctx context.Context, pledge *models.Pledge) error
+}
+
+// UserNotificationRepository definieert de interface voor de persoonlijke inbox van gebruikers
+type UserNotificationRepository interface {
+	// Create zet een persoonlijke notificatie in de inbox van een gebruiker
+	Create(ctx context.Context, notification *models.UserNotification) error
+
+	// CreateForPermission zet een systeemnotificatie in de inbox van alle actieve gebruikers met de
+	// gegeven permissie en geeft de aangemaakte notificaties terug
+	CreateForPermission(ctx context.Context, notificationID, resource, action string) ([]*models.UserNotification, error)
+
+	// ListByUser haalt de notificaties van een gebruiker op, nieuwste eerst
+	ListByUser(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*models.UserNotification, error)
+
+	// CountUnread telt de ongelezen notificaties van een gebruiker
+	CountUnread(ctx context.Context, userID string) (int64, error)
+
+	// MarkRead markeert de gegeven notificaties van een gebruiker als gelezen
+	MarkRead(ctx context.Context, userID string, ids []string) (int64, error)
+
+	// MarkAllRead markeert alle notificaties van een gebruiker als gelezen
+	MarkAllRead(ctx context.Context, userID string) (int64, error)
+
+	// Delete verwijdert een notificatie uit de inbox van een gebruiker
+	Delete(ctx context.Context, userID, id string) (bool, error)
+}
+
+// NotificationPreferenceRepository definieert de interface voor notificatie voorkeuren per gebruiker
+type NotificationPreferenceRepository interface {
+	// ListByUser haalt de opgeslagen voorkeuren van een gebruiker op
+	ListByUser(ctx context.Context, userID string) ([]*models.NotificationPreference, error)
+
+	// Get haalt de voorkeur van een gebruiker voor één type op
+	Get(ctx context.Context, userID string, notificationType models.NotificationType) (*models.NotificationPreference, error)
+
+	// Save slaat een voorkeur op of werkt hem bij
+	Save(ctx context.Context, preference *models.NotificationPreference) error
+}
+
 // ChatChannelRepository defines the interface for chat channel operations
 type ChatChannelRepository interface {
 	Create(ctx context.Context, channel *models.ChatChannel) error
@@ -600,3 +1000,35 @@ type AlbumPhotoRepository interface {
 	// DeleteByPhoto removes a photo from all albums
 	DeleteByPhoto(ctx context.Context, photoID string) error
 }
+
+// EmailQueueRepository definieert de interface voor de persistente email outbox
+type EmailQueueRepository interface {
+	// Enqueue plaatst een nieuwe email in de wachtrij
+	Enqueue(ctx context.Context, item *models.EmailQueueItem) error
+
+	// ClaimBatch claimt maximaal limit openstaande items met FOR UPDATE SKIP LOCKED
+	ClaimBatch(ctx context.Context, limit int) ([]*models.EmailQueueItem, error)
+
+	// MarkSent markeert een item als verzonden
+	MarkSent(ctx context.Context, id string) error
+
+	// MarkFailed registreert een mislukte poging en plant een nieuwe poging of zet het item op dead
+	MarkFailed(ctx context.Context, id string, errMsg string, nextAttemptAt time.Time, dead bool) error
+
+	// ReleaseStale zet items die te lang in processing staan terug naar pending, of naar
+	// dead als hun pogingen op zijn; die dead-letter items worden teruggegeven
+	ReleaseStale(ctx context.Context, olderThan time.Duration) (int64, []*models.EmailQueueItem, error)
+
+	// GetByID haalt een queue item op basis van ID
+	GetByID(ctx context.Context, id string) (*models.EmailQueueItem, error)
+
+	// List haalt queue items op, optioneel gefilterd op status
+	List(ctx context.Context, status string, limit, offset int) ([]*models.EmailQueueItem, error)
+
+	// Requeue zet een item terug in de wachtrij met een nieuwe set pogingen, behalve als het
+	// in verwerking of al verzonden is
+	Requeue(ctx context.Context, id string) error
+
+	// CountByStatus telt het aantal items per status
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+}
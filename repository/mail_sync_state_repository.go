@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// PostgresMailSyncStateRepository implementeert MailSyncStateRepository met PostgreSQL
+type PostgresMailSyncStateRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresMailSyncStateRepository maakt een nieuwe PostgreSQL mail sync state repository
+func NewPostgresMailSyncStateRepository(base *PostgresRepository) *PostgresMailSyncStateRepository {
+	return &PostgresMailSyncStateRepository{
+		PostgresRepository: base,
+	}
+}
+
+// Get haalt de status van een mailbox van een account op
+func (r *PostgresMailSyncStateRepository) Get(ctx context.Context, accountType, mailbox string) (*models.MailSyncState, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var state models.MailSyncState
+	result := r.DB().WithContext(ctx).
+		Where("account_type = ? AND mailbox = ?", accountType, mailbox).
+		First(&state)
+	if err := r.handleError("Get", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &state, nil
+}
+
+// Save slaat de status op of werkt de bestaande status van de mailbox bij
+func (r *PostgresMailSyncStateRepository) Save(ctx context.Context, state *models.MailSyncState) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_type"}, {Name: "mailbox"}},
+			DoUpdates: clause.AssignmentColumns([]string{"username", "uid_validity", "last_uid", "last_sync_at", "updated_at"}),
+		}).
+		Create(state)
+	return r.handleError("Save", result.Error)
+}
+
+// List haalt de status van alle mailboxen op
+func (r *PostgresMailSyncStateRepository) List(ctx context.Context) ([]*models.MailSyncState, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var states []*models.MailSyncState
+	result := r.DB().WithContext(ctx).
+		Order("account_type ASC, mailbox ASC").
+		Find(&states)
+
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresNewsletterDeliveryRepository implementeert NewsletterDeliveryRepository met PostgreSQL
+type PostgresNewsletterDeliveryRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterDeliveryRepository maakt een nieuwe PostgreSQL repository voor nieuwsbrief afleveringen
+func NewPostgresNewsletterDeliveryRepository(base *PostgresRepository) *PostgresNewsletterDeliveryRepository {
+	return &PostgresNewsletterDeliveryRepository{PostgresRepository: base}
+}
+
+// CreateQueued legt per ontvanger een aflevering met status queued vast. Bestaat de aflevering
+// al, bijvoorbeeld bij opnieuw verzenden na een fout, dan wordt die teruggezet naar queued.
+func (r *PostgresNewsletterDeliveryRepository) CreateQueued(ctx context.Context, newsletterID string, emails []string) ([]*models.NewsletterDelivery, error) {
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	// Grote verzendingen mogen langer duren dan de standaard timeout
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	deliveries := make([]*models.NewsletterDelivery, len(emails))
+	for i, email := range emails {
+		deliveries[i] = &models.NewsletterDelivery{
+			NewsletterID: newsletterID,
+			Email:        email,
+			Status:       models.NewsletterDeliveryQueued,
+		}
+	}
+
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "newsletter_id"}, {Name: "email"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"status":       models.NewsletterDeliveryQueued,
+				"fout_bericht": "",
+				"updated_at":   time.Now(),
+			}),
+		}).
+		CreateInBatches(deliveries, 500)
+	if err := r.handleError("CreateQueued", result.Error); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// MarkSent markeert een aflevering als verzonden
+func (r *PostgresNewsletterDeliveryRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.NewsletterDeliverySent,
+		"sent_at":      sentAt,
+		"fout_bericht": "",
+	})
+	return r.handleError("MarkSent", result.Error)
+}
+
+// MarkFailed markeert een aflevering als mislukt met de foutmelding
+func (r *PostgresNewsletterDeliveryRepository) MarkFailed(ctx context.Context, id, foutBericht string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.NewsletterDeliveryFailed,
+		"fout_bericht": foutBericht,
+	})
+	return r.handleError("MarkFailed", result.Error)
+}
+
+// MarkBounced markeert de meest recente verzonden nieuwsbrief aan een adres als gebounced
+// en geeft terug of er een aflevering gevonden is
+func (r *PostgresNewsletterDeliveryRepository) MarkBounced(ctx context.Context, email string, bouncedAt time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	latest := r.DB().Model(&models.NewsletterDelivery{}).
+		Select("id").
+		Where("email = ? AND status = ?", email, models.NewsletterDeliverySent).
+		Order("sent_at DESC").
+		Limit(1)
+
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).
+		Where("id = (?)", latest).
+		Updates(map[string]interface{}{
+			"status":     models.NewsletterDeliveryBounced,
+			"bounced_at": bouncedAt,
+		})
+	if err := r.handleError("MarkBounced", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordOpen telt een open van een aflevering en legt de eerste open vast
+func (r *PostgresNewsletterDeliveryRepository) RecordOpen(ctx context.Context, id string, openedAt time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"open_count": gorm.Expr("open_count + 1"),
+		"opened_at":  gorm.Expr("COALESCE(opened_at, ?)", openedAt),
+	})
+	if err := r.handleError("RecordOpen", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordClick telt een klik van een aflevering en legt de eerste klik vast. Een klik
+// betekent ook dat de nieuwsbrief geopend is, ook als de afbeeldingen geblokkeerd waren.
+func (r *PostgresNewsletterDeliveryRepository) RecordClick(ctx context.Context, id string, clickedAt time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"click_count": gorm.Expr("click_count + 1"),
+		"clicked_at":  gorm.Expr("COALESCE(clicked_at, ?)", clickedAt),
+		"opened_at":   gorm.Expr("COALESCE(opened_at, ?)", clickedAt),
+	})
+	if err := r.handleError("RecordClick", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetStats telt de afleveringen, opens en kliks van een nieuwsbrief
+func (r *PostgresNewsletterDeliveryRepository) GetStats(ctx context.Context, newsletterID string) (*models.NewsletterStats, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	stats := &models.NewsletterStats{NewsletterID: newsletterID}
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).
+		Select(`COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE status = ?) AS queued,
+			COUNT(*) FILTER (WHERE status IN ?) AS sent,
+			COUNT(*) FILTER (WHERE status = ?) AS failed,
+			COUNT(*) FILTER (WHERE status = ?) AS bounced,
+			COUNT(*) FILTER (WHERE opened_at IS NOT NULL) AS unique_opens,
+			COALESCE(SUM(open_count), 0) AS total_opens,
+			COUNT(*) FILTER (WHERE clicked_at IS NOT NULL) AS unique_clicks,
+			COALESCE(SUM(click_count), 0) AS total_clicks`,
+			models.NewsletterDeliveryQueued,
+			[]string{models.NewsletterDeliverySent, models.NewsletterDeliveryBounced},
+			models.NewsletterDeliveryFailed,
+			models.NewsletterDeliveryBounced).
+		Where("newsletter_id = ?", newsletterID).
+		Scan(stats)
+	if err := r.handleError("GetStats", result.Error); err != nil {
+		return nil, err
+	}
+
+	stats.Reached = stats.Sent - stats.Bounced
+	if stats.Reached > 0 {
+		stats.OpenRate = float64(stats.UniqueOpens) / float64(stats.Reached)
+		stats.ClickRate = float64(stats.UniqueClicks) / float64(stats.Reached)
+	}
+	return stats, nil
+}
+
+// ListByNewsletter haalt de afleveringen van een nieuwsbrief op, optioneel met één status
+func (r *PostgresNewsletterDeliveryRepository) ListByNewsletter(ctx context.Context, newsletterID, status string, limit, offset int) ([]*models.NewsletterDelivery, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.NewsletterDelivery{}).Where("newsletter_id = ?", newsletterID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := r.handleError("ListByNewsletter", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []*models.NewsletterDelivery
+	result := query.
+		Order("email ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries)
+	if err := r.handleError("ListByNewsletter", result.Error); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
@@ -82,6 +82,49 @@ func (r *PostgresNewsletterRepository) UpdateBatchID(ctx context.Context, id, ba
 func (r *PostgresNewsletterRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
 	ctx, cancel := r.withTimeout(ctx)
 	defer cancel()
-	result := r.DB().WithContext(ctx).Model(&models.Newsletter{}).Where("id = ?", id).Update("sent_at", sentAt)
+	result := r.DB().WithContext(ctx).Model(&models.Newsletter{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sent_at":      sentAt,
+		"status":       models.NewsletterStatusSent,
+		"fout_bericht": "",
+	})
 	return r.handleError("MarkSent", result.Error)
 }
+
+func (r *PostgresNewsletterRepository) TransitionStatus(ctx context.Context, id string, from []string, to string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.Newsletter{}).
+		Where("id = ? AND status IN ?", id, from).
+		Update("status", to)
+	if err := r.handleError("TransitionStatus", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *PostgresNewsletterRepository) MarkFailed(ctx context.Context, id, foutBericht string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.Newsletter{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.NewsletterStatusFailed,
+		"fout_bericht": foutBericht,
+	})
+	return r.handleError("MarkFailed", result.Error)
+}
+
+func (r *PostgresNewsletterRepository) ListDue(ctx context.Context, now time.Time) ([]*models.Newsletter, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var newsletters []*models.Newsletter
+	result := r.DB().WithContext(ctx).
+		Where("status = ? AND scheduled_at <= ?", models.NewsletterStatusScheduled, now).
+		Order("scheduled_at ASC").
+		Find(&newsletters)
+
+	if err := r.handleError("ListDue", result.Error); err != nil {
+		return nil, err
+	}
+
+	return newsletters, nil
+}
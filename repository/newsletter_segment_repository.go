@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"sort"
+	"strings"
+)
+
+// PostgresNewsletterSegmentRepository implementeert NewsletterSegmentRepository met PostgreSQL
+type PostgresNewsletterSegmentRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterSegmentRepository maakt een nieuwe PostgreSQL repository voor nieuwsbrief segmenten
+func NewPostgresNewsletterSegmentRepository(base *PostgresRepository) *PostgresNewsletterSegmentRepository {
+	return &PostgresNewsletterSegmentRepository{PostgresRepository: base}
+}
+
+// Create slaat een nieuw segment op
+func (r *PostgresNewsletterSegmentRepository) Create(ctx context.Context, segment *models.NewsletterSegment) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(segment)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByID haalt een segment op; geeft nil terug als het niet bestaat
+func (r *PostgresNewsletterSegmentRepository) GetByID(ctx context.Context, id string) (*models.NewsletterSegment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var segment models.NewsletterSegment
+	result := r.DB().WithContext(ctx).First(&segment, "id = ?", id)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &segment, nil
+}
+
+// List haalt alle segmenten op, gesorteerd op naam
+func (r *PostgresNewsletterSegmentRepository) List(ctx context.Context) ([]*models.NewsletterSegment, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var segments []*models.NewsletterSegment
+	result := r.DB().WithContext(ctx).Order("naam ASC").Find(&segments)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+// Update werkt een bestaand segment bij
+func (r *PostgresNewsletterSegmentRepository) Update(ctx context.Context, segment *models.NewsletterSegment) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Save(segment)
+	return r.handleError("Update", result.Error)
+}
+
+// Delete verwijdert een segment
+func (r *PostgresNewsletterSegmentRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Delete(&models.NewsletterSegment{}, "id = ?", id)
+	return r.handleError("Delete", result.Error)
+}
+
+// ResolveEmails geeft de adressen terug die aan het filter voldoen. Adressen worden
+// genormaliseerd naar kleine letters en komen maar één keer voor, ook als iemand
+// zowel een aanmelding als een gebruikersaccount heeft.
+func (r *PostgresNewsletterSegmentRepository) ResolveEmails(ctx context.Context, filter models.SegmentFilter) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var emails []string
+
+	if f := filter.Aanmeldingen; f != nil {
+		query := r.DB().WithContext(ctx).Model(&models.Aanmelding{}).Where("test_mode = ?", false)
+		if len(f.Rollen) > 0 {
+			query = query.Where("LOWER(rol) IN ?", lowerAll(f.Rollen))
+		}
+		if len(f.Afstanden) > 0 {
+			query = query.Where("LOWER(afstand) IN ?", lowerAll(f.Afstanden))
+		}
+		if len(f.Statussen) > 0 {
+			query = query.Where("status IN ?", f.Statussen)
+		}
+		if f.Jaar > 0 {
+			query = query.Where("EXTRACT(YEAR FROM created_at) = ?", f.Jaar)
+		}
+		if f.MinSteps > 0 {
+			query = query.Where("steps >= ?", f.MinSteps)
+		}
+
+		var aanmeldingEmails []string
+		if err := r.handleError("ResolveEmails", query.Pluck("email", &aanmeldingEmails).Error); err != nil {
+			return nil, err
+		}
+		emails = append(emails, aanmeldingEmails...)
+	}
+
+	if f := filter.Gebruikers; f != nil {
+		query := r.DB().WithContext(ctx).Model(&models.Gebruiker{}).Where("is_actief = ?", true)
+		if len(f.Rollen) > 0 {
+			query = query.Where(`id IN (
+				SELECT ur.user_id FROM user_roles ur
+				JOIN roles ro ON ro.id = ur.role_id
+				WHERE ur.is_active = true
+				AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+				AND LOWER(ro.name) IN ?)`, lowerAll(f.Rollen))
+		}
+		if f.NewsletterSubscribed != nil {
+			query = query.Where("newsletter_subscribed = ?", *f.NewsletterSubscribed)
+		}
+
+		var gebruikerEmails []string
+		if err := r.handleError("ResolveEmails", query.Pluck("email", &gebruikerEmails).Error); err != nil {
+			return nil, err
+		}
+		emails = append(emails, gebruikerEmails...)
+	}
+
+	emails = append(emails, filter.Emails...)
+	return uniqueEmails(emails), nil
+}
+
+// uniqueEmails normaliseert adressen en verwijdert lege waarden en dubbelingen
+func uniqueEmails(emails []string) []string {
+	seen := make(map[string]bool, len(emails))
+	unique := make([]string, 0, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" || !strings.Contains(email, "@") || seen[email] {
+			continue
+		}
+		seen[email] = true
+		unique = append(unique, email)
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// lowerAll zet alle waarden om naar kleine letters voor een hoofdletterongevoelige vergelijking
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return lowered
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// PostgresNewsletterSourceRepository implementeert NewsletterSourceRepository met PostgreSQL
+type PostgresNewsletterSourceRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterSourceRepository maakt een nieuwe PostgreSQL repository voor nieuwsbrief bronnen
+func NewPostgresNewsletterSourceRepository(base *PostgresRepository) *PostgresNewsletterSourceRepository {
+	return &PostgresNewsletterSourceRepository{PostgresRepository: base}
+}
+
+// Create slaat een nieuwe bron op
+func (r *PostgresNewsletterSourceRepository) Create(ctx context.Context, source *models.NewsletterSource) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(source)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByID haalt een bron op; geeft nil terug als hij niet bestaat
+func (r *PostgresNewsletterSourceRepository) GetByID(ctx context.Context, id string) (*models.NewsletterSource, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var source models.NewsletterSource
+	result := r.DB().WithContext(ctx).First(&source, "id = ?", id)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &source, nil
+}
+
+// List haalt alle bronnen op, gesorteerd op naam
+func (r *PostgresNewsletterSourceRepository) List(ctx context.Context) ([]*models.NewsletterSource, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var sources []*models.NewsletterSource
+	result := r.DB().WithContext(ctx).Order("naam ASC").Find(&sources)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// ListActive haalt de actieve bronnen op
+func (r *PostgresNewsletterSourceRepository) ListActive(ctx context.Context) ([]*models.NewsletterSource, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var sources []*models.NewsletterSource
+	result := r.DB().WithContext(ctx).Where("is_actief = ?", true).Order("naam ASC").Find(&sources)
+	if err := r.handleError("ListActive", result.Error); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// Update werkt een bestaande bron bij
+func (r *PostgresNewsletterSourceRepository) Update(ctx context.Context, source *models.NewsletterSource) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Save(source)
+	return r.handleError("Update", result.Error)
+}
+
+// Delete verwijdert een bron
+func (r *PostgresNewsletterSourceRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Delete(&models.NewsletterSource{}, "id = ?", id)
+	return r.handleError("Delete", result.Error)
+}
+
+// MarkFetched legt het tijdstip en de eventuele fout van de laatste keer ophalen vast
+func (r *PostgresNewsletterSourceRepository) MarkFetched(ctx context.Context, id string, fetchedAt time.Time, lastError string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterSource{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_fetched_at": fetchedAt,
+		"last_error":      lastError,
+	})
+	return r.handleError("MarkFetched", result.Error)
+}
+
+// PostgresNewsletterSentItemRepository implementeert NewsletterSentItemRepository met PostgreSQL
+type PostgresNewsletterSentItemRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterSentItemRepository maakt een nieuwe PostgreSQL repository voor verzonden nieuwsitems
+func NewPostgresNewsletterSentItemRepository(base *PostgresRepository) *PostgresNewsletterSentItemRepository {
+	return &PostgresNewsletterSentItemRepository{PostgresRepository: base}
+}
+
+// FilterSent geeft de sleutels terug die al in een eerdere nieuwsbrief hebben gestaan
+func (r *PostgresNewsletterSentItemRepository) FilterSent(ctx context.Context, keys []string) (map[string]bool, error) {
+	sent := make(map[string]bool)
+	if len(keys) == 0 {
+		return sent, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var found []string
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterSentItem{}).
+		Where("item_key IN ?", keys).
+		Pluck("item_key", &found)
+	if err := r.handleError("FilterSent", result.Error); err != nil {
+		return nil, err
+	}
+
+	for _, key := range found {
+		sent[key] = true
+	}
+	return sent, nil
+}
+
+// MarkSent legt vast dat de items zijn verzonden; items die al bekend zijn worden overgeslagen
+func (r *PostgresNewsletterSentItemRepository) MarkSent(ctx context.Context, items []*models.NewsletterSentItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	for _, item := range items {
+		item.ItemKey = strings.TrimSpace(item.ItemKey)
+	}
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "item_key"}}, DoNothing: true}).
+		Create(&items)
+	return r.handleError("MarkSent", result.Error)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"strings"
+)
+
+// PostgresNewsletterSubscriberRepository implementeert NewsletterSubscriberRepository met PostgreSQL
+type PostgresNewsletterSubscriberRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterSubscriberRepository maakt een nieuwe PostgreSQL repository voor nieuwsbrief abonnees
+func NewPostgresNewsletterSubscriberRepository(base *PostgresRepository) *PostgresNewsletterSubscriberRepository {
+	return &PostgresNewsletterSubscriberRepository{PostgresRepository: base}
+}
+
+// GetByEmail haalt een abonnee op; geeft nil terug als het adres onbekend is
+func (r *PostgresNewsletterSubscriberRepository) GetByEmail(ctx context.Context, email string) (*models.NewsletterSubscriber, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var subscriber models.NewsletterSubscriber
+	result := r.DB().WithContext(ctx).First(&subscriber, "email = ?", strings.ToLower(strings.TrimSpace(email)))
+	if err := r.handleError("GetByEmail", result.Error); err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &subscriber, nil
+}
+
+// Save slaat een nieuwe abonnee op of werkt een bestaande bij
+func (r *PostgresNewsletterSubscriberRepository) Save(ctx context.Context, subscriber *models.NewsletterSubscriber) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	subscriber.Email = strings.ToLower(strings.TrimSpace(subscriber.Email))
+	result := r.DB().WithContext(ctx).Save(subscriber)
+	return r.handleError("Save", result.Error)
+}
+
+// ListConfirmedEmails haalt de adressen van alle bevestigde abonnees op
+func (r *PostgresNewsletterSubscriberRepository) ListConfirmedEmails(ctx context.Context) ([]string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var emails []string
+	result := r.DB().WithContext(ctx).Model(&models.NewsletterSubscriber{}).
+		Where("status = ?", models.NewsletterSubscriberConfirmed).
+		Order("email ASC").
+		Pluck("email", &emails)
+	if err := r.handleError("ListConfirmedEmails", result.Error); err != nil {
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// List haalt een gepagineerde lijst van abonnees op, optioneel met één status
+func (r *PostgresNewsletterSubscriberRepository) List(ctx context.Context, status string, limit, offset int) ([]*models.NewsletterSubscriber, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.NewsletterSubscriber{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := r.handleError("List", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var subscribers []*models.NewsletterSubscriber
+	result := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&subscribers)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, 0, err
+	}
+
+	return subscribers, total, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"strings"
+)
+
+// PostgresNewsletterSubscriptionEventRepository implementeert NewsletterSubscriptionEventRepository met PostgreSQL
+type PostgresNewsletterSubscriptionEventRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNewsletterSubscriptionEventRepository maakt een nieuwe PostgreSQL repository voor de nieuwsbrief audit
+func NewPostgresNewsletterSubscriptionEventRepository(base *PostgresRepository) *PostgresNewsletterSubscriptionEventRepository {
+	return &PostgresNewsletterSubscriptionEventRepository{
+		PostgresRepository: base,
+	}
+}
+
+// Create legt een aan- of afmelding vast
+func (r *PostgresNewsletterSubscriptionEventRepository) Create(ctx context.Context, event *models.NewsletterSubscriptionEvent) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	event.Email = strings.ToLower(strings.TrimSpace(event.Email))
+	result := r.DB().WithContext(ctx).Create(event)
+	return r.handleError("Create", result.Error)
+}
+
+// List haalt de aan- en afmeldingen op, nieuwste eerst, optioneel voor één adres
+func (r *PostgresNewsletterSubscriptionEventRepository) List(ctx context.Context, email string, limit, offset int) ([]*models.NewsletterSubscriptionEvent, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.NewsletterSubscriptionEvent{})
+	if email != "" {
+		query = query.Where("email = ?", strings.ToLower(strings.TrimSpace(email)))
+	}
+
+	var total int64
+	if err := r.handleError("List", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.NewsletterSubscriptionEvent
+	result := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events)
+
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
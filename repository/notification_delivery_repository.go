@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// PostgresNotificationDeliveryRepository implementeert NotificationDeliveryRepository met PostgreSQL
+type PostgresNotificationDeliveryRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNotificationDeliveryRepository maakt een nieuwe PostgreSQL repository voor afleveringen per kanaal
+func NewPostgresNotificationDeliveryRepository(base *PostgresRepository) *PostgresNotificationDeliveryRepository {
+	return &PostgresNotificationDeliveryRepository{PostgresRepository: base}
+}
+
+// EnsureDeliveries maakt een openstaande aflevering aan voor elk kanaal dat er nog geen heeft
+func (r *PostgresNotificationDeliveryRepository) EnsureDeliveries(ctx context.Context, notificationID string, channels []string) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	deliveries := make([]*models.NotificationDelivery, len(channels))
+	for i, channel := range channels {
+		deliveries[i] = &models.NotificationDelivery{
+			NotificationID: notificationID,
+			Channel:        channel,
+			Status:         models.NotificationDeliveryPending,
+		}
+	}
+
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&deliveries)
+	return r.handleError("EnsureDeliveries", result.Error)
+}
+
+// ListByNotification haalt de afleveringen van een notificatie op
+func (r *PostgresNotificationDeliveryRepository) ListByNotification(ctx context.Context, notificationID string) ([]*models.NotificationDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var deliveries []*models.NotificationDelivery
+	result := r.DB().WithContext(ctx).
+		Where("notification_id = ?", notificationID).
+		Order("channel ASC").
+		Find(&deliveries)
+	if err := r.handleError("ListByNotification", result.Error); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ListDue haalt de openstaande afleveringen van een kanaal op die nu geprobeerd mogen worden
+func (r *PostgresNotificationDeliveryRepository) ListDue(ctx context.Context, channel string, now time.Time) ([]*models.NotificationDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var deliveries []*models.NotificationDelivery
+	result := r.DB().WithContext(ctx).
+		Where("channel = ? AND status = ?", channel, models.NotificationDeliveryPending).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Order("created_at ASC").
+		Find(&deliveries)
+	if err := r.handleError("ListDue", result.Error); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Update werkt een aflevering bij
+func (r *PostgresNotificationDeliveryRepository) Update(ctx context.Context, delivery *models.NotificationDelivery) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Save(delivery)
+	return r.handleError("Update", result.Error)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresNotificationThrottleRepository implementeert NotificationThrottleRepository met PostgreSQL
+type PostgresNotificationThrottleRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNotificationThrottleRepository maakt een nieuwe PostgreSQL repository voor notificatie throttling
+func NewPostgresNotificationThrottleRepository(base *PostgresRepository) *PostgresNotificationThrottleRepository {
+	return &PostgresNotificationThrottleRepository{PostgresRepository: base}
+}
+
+// Hit registreert een notificatie voor een sleutel. Is er geen venster of is het verlopen, dan
+// begint er een nieuw venster tot windowEnd en mag de notificatie door. Anders wordt hij geteld
+// voor de digest aan het einde van het venster.
+func (r *PostgresNotificationThrottleRepository) Hit(ctx context.Context, key string, notification *models.Notification, windowEnd, now time.Time) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	allowed := false
+	err := r.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO notification_throttles (key, window_end) VALUES (?, ?)
+			ON CONFLICT (key) DO UPDATE SET window_end = EXCLUDED.window_end, updated_at = NOW()
+			WHERE notification_throttles.window_end <= ?`,
+			key, windowEnd, now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			allowed = true
+			return nil
+		}
+
+		var throttle models.NotificationThrottle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&throttle, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		throttle.Suppressed++
+		throttle.Type = notification.Type
+		if notification.Priority.Rank() > throttle.Priority.Rank() {
+			throttle.Priority = notification.Priority
+		}
+		if len(throttle.Titles) < models.MaxNotificationDigestTitles {
+			throttle.Titles = append(throttle.Titles, notification.Title)
+		}
+		if throttle.DigestDueAt == nil {
+			due := throttle.WindowEnd
+			throttle.DigestDueAt = &due
+		}
+		return tx.Save(&throttle).Error
+	})
+
+	if err := r.handleError("Hit", err); err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// ClaimDue claimt de digests waarvan het venster is verstreken en zet de tellers terug. Door
+// FOR UPDATE SKIP LOCKED verstuurt maar één instantie een digest.
+func (r *PostgresNotificationThrottleRepository) ClaimDue(ctx context.Context, now time.Time) ([]*models.NotificationThrottle, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var due []*models.NotificationThrottle
+	err := r.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("digest_due_at <= ?", now).
+			Find(&due)
+		if result.Error != nil || len(due) == 0 {
+			return result.Error
+		}
+
+		keys := make([]string, len(due))
+		for i, throttle := range due {
+			keys[i] = throttle.Key
+		}
+		return tx.Model(&models.NotificationThrottle{}).
+			Where("key IN ?", keys).
+			Updates(map[string]interface{}{
+				"suppressed":    0,
+				"titles":        models.NotificationTitles{},
+				"priority":      "",
+				"digest_due_at": nil,
+			}).Error
+	})
+
+	if err := r.handleError("ClaimDue", err); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// DeleteExpired verwijdert verlopen vensters zonder openstaande digest
+func (r *PostgresNotificationThrottleRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Where("window_end < ? AND digest_due_at IS NULL", before).
+		Delete(&models.NotificationThrottle{})
+	return r.handleError("DeleteExpired", result.Error)
+}
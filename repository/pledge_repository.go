@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+)
+
+// PostgresPledgeRepository implementeert PledgeRepository met PostgreSQL
+type PostgresPledgeRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresPledgeRepository maakt een nieuwe PostgreSQL repository voor sponsortoezeggingen
+func NewPostgresPledgeRepository(base *PostgresRepository) *PostgresPledgeRepository {
+	return &PostgresPledgeRepository{PostgresRepository: base}
+}
+
+// Create slaat een nieuwe toezegging op
+func (r *PostgresPledgeRepository) Create(ctx context.Context, pledge *models.Pledge) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(pledge)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByID haalt een toezegging op; geeft nil terug als ze niet bestaat
+func (r *PostgresPledgeRepository) GetByID(ctx context.Context, id string) (*models.Pledge, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var pledge models.Pledge
+	result := r.DB().WithContext(ctx).Where("id = ?", id).Limit(1).Find(&pledge)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &pledge, nil
+}
+
+// ListByAanmelding haalt de toezeggingen voor een deelnemer op, oudste eerst
+func (r *PostgresPledgeRepository) ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.Pledge, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var pledges []*models.Pledge
+	result := r.DB().WithContext(ctx).Where("aanmelding_id = ?", aanmeldingID).Order("created_at ASC").Find(&pledges)
+	if err := r.handleError("ListByAanmelding", result.Error); err != nil {
+		return nil, err
+	}
+	return pledges, nil
+}
+
+// List haalt toezeggingen op met filter en paginering, nieuwste eerst, met het totaal aantal
+func (r *PostgresPledgeRepository) List(ctx context.Context, filter models.PledgeFilter) ([]*models.Pledge, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.Pledge{})
+	if filter.AanmeldingID != "" {
+		query = query.Where("aanmelding_id = ?", filter.AanmeldingID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := r.handleError("List", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var pledges []*models.Pledge
+	query = query.Order("created_at DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := r.handleError("List", query.Find(&pledges).Error); err != nil {
+		return nil, 0, err
+	}
+	return pledges, total, nil
+}
+
+// Update werkt een toezegging bij
+func (r *PostgresPledgeRepository) Update(ctx context.Context, pledge *models.Pledge) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Save(pledge)
+	return r.handleError("Update", result.Error)
+}
@@ -7,13 +7,13 @@ import (
 	"gorm.io/gorm"
 )
 
-// RouteFundRepository interface voor route fund operaties
+// RouteFundRepository interface voor route fund operaties binnen een editie
 type RouteFundRepository interface {
 	Create(ctx context.Context, routeFund *models.RouteFund) error
-	GetByRoute(ctx context.Context, route string) (*models.RouteFund, error)
-	GetAll(ctx context.Context) ([]*models.RouteFund, error)
+	GetByRoute(ctx context.Context, eventID, route string) (*models.RouteFund, error)
+	ListByEvent(ctx context.Context, eventID string) ([]*models.RouteFund, error)
 	Update(ctx context.Context, routeFund *models.RouteFund) error
-	Delete(ctx context.Context, route string) error
+	Delete(ctx context.Context, eventID, route string) error
 }
 
 // routeFundRepository implementeert RouteFundRepository
@@ -31,20 +31,20 @@ func (r *routeFundRepository) Create(ctx context.Context, routeFund *models.Rout
 	return r.db.WithContext(ctx).Create(routeFund).Error
 }
 
-// GetByRoute haalt een route fund op basis van route naam
-func (r *routeFundRepository) GetByRoute(ctx context.Context, route string) (*models.RouteFund, error) {
+// GetByRoute haalt een route fund van een editie op basis van route naam
+func (r *routeFundRepository) GetByRoute(ctx context.Context, eventID, route string) (*models.RouteFund, error) {
 	var routeFund models.RouteFund
-	err := r.db.WithContext(ctx).Where("route = ?", route).First(&routeFund).Error
+	err := r.db.WithContext(ctx).Where("event_id = ? AND route = ?", eventID, route).First(&routeFund).Error
 	if err != nil {
 		return nil, err
 	}
 	return &routeFund, nil
 }
 
-// GetAll haalt alle route funds op
-func (r *routeFundRepository) GetAll(ctx context.Context) ([]*models.RouteFund, error) {
+// ListByEvent haalt alle route funds van een editie op
+func (r *routeFundRepository) ListByEvent(ctx context.Context, eventID string) ([]*models.RouteFund, error) {
 	var routeFunds []*models.RouteFund
-	err := r.db.WithContext(ctx).Order("route ASC").Find(&routeFunds).Error
+	err := r.db.WithContext(ctx).Where("event_id = ?", eventID).Order("route ASC").Find(&routeFunds).Error
 	return routeFunds, err
 }
 
@@ -53,7 +53,7 @@ func (r *routeFundRepository) Update(ctx context.Context, routeFund *models.Rout
 	return r.db.WithContext(ctx).Save(routeFund).Error
 }
 
-// Delete verwijdert een route fund
-func (r *routeFundRepository) Delete(ctx context.Context, route string) error {
-	return r.db.WithContext(ctx).Where("route = ?", route).Delete(&models.RouteFund{}).Error
+// Delete verwijdert een route fund uit een editie
+func (r *routeFundRepository) Delete(ctx context.Context, eventID, route string) error {
+	return r.db.WithContext(ctx).Where("event_id = ? AND route = ?", eventID, route).Delete(&models.RouteFund{}).Error
 }
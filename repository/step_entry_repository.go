@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStepEntryRepository implementeert StepEntryRepository met PostgreSQL
+type PostgresStepEntryRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresStepEntryRepository maakt een nieuwe PostgreSQL repository voor het stappen grootboek
+func NewPostgresStepEntryRepository(base *PostgresRepository) *PostgresStepEntryRepository {
+	return &PostgresStepEntryRepository{PostgresRepository: base}
+}
+
+// Record schrijft een regel in het grootboek en zet het afgeleide totaal in aanmeldingen.steps.
+// De aanmelding wordt vergrendeld zodat gelijktijdige regels voor dezelfde deelnemer na elkaar
+// worden verwerkt en geen stappen verloren gaan.
+func (r *PostgresStepEntryRepository) Record(ctx context.Context, entry *models.StepEntry) (int, bool, error) {
+	return r.record(ctx, "Record", entry, nil)
+}
+
+// RecordDayTotal boekt het verschil tussen dayTotal en de import regels in hetzelfde formaat die al
+// op entry.RecordedAt staan. Een cumulatieve export die later opnieuw wordt geüpload telt zo
+// alleen de stappen die er sinds de vorige export bij zijn gekomen.
+func (r *PostgresStepEntryRepository) RecordDayTotal(ctx context.Context, entry *models.StepEntry, dayTotal int) (int, bool, error) {
+	return r.record(ctx, "RecordDayTotal", entry, &dayTotal)
+}
+
+// record schrijft een regel onder een lock op de aanmelding. Met dayTotal wordt de delta bepaald
+// uit wat voor die dag al is geïmporteerd.
+func (r *PostgresStepEntryRepository) record(ctx context.Context, op string, entry *models.StepEntry, dayTotal *int) (int, bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	total := 0
+	created := false
+	err := r.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var aanmelding models.Aanmelding
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Limit(1).
+			Find(&aanmelding, "id = ?", entry.AanmeldingID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("aanmelding %s niet gevonden", entry.AanmeldingID)
+		}
+
+		if err := tx.Model(&models.StepEntry{}).
+			Where("aanmelding_id = ?", entry.AanmeldingID).
+			Select("COALESCE(SUM(delta), 0)").
+			Scan(&total).Error; err != nil {
+			return err
+		}
+
+		if entry.IdempotencyKey != nil {
+			var existing models.StepEntry
+			result := tx.Limit(1).Find(&existing, "aanmelding_id = ? AND idempotency_key = ?", entry.AanmeldingID, *entry.IdempotencyKey)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				*entry = existing
+				return nil
+			}
+		}
+
+		if dayTotal != nil {
+			format := ""
+			if entry.Metadata != nil {
+				format = entry.Metadata.Format
+			}
+			var booked int
+			if err := tx.Model(&models.StepEntry{}).
+				Where("aanmelding_id = ? AND source = ? AND recorded_at = ? AND metadata->>'format' = ?",
+					entry.AanmeldingID, models.StepSourceImport, entry.RecordedAt, format).
+				Select("COALESCE(SUM(delta), 0)").
+				Scan(&booked).Error; err != nil {
+				return err
+			}
+			entry.Delta = *dayTotal - booked
+			if entry.Delta <= 0 {
+				entry.Delta = 0
+				return nil
+			}
+		}
+
+		// Het totaal komt nooit onder nul; een te grote aftrek wordt begrensd
+		if total+entry.Delta < 0 {
+			entry.Delta = -total
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		total += entry.Delta
+		created = true
+
+		return tx.Model(&models.Aanmelding{}).
+			Where("id = ?", entry.AanmeldingID).
+			Update("steps", total).Error
+	})
+
+	if err := r.handleError(op, err); err != nil {
+		return 0, false, err
+	}
+	return total, created, nil
+}
+
+// ListByAanmelding haalt de regels van een deelnemer op, nieuwste eerst
+func (r *PostgresStepEntryRepository) ListByAanmelding(ctx context.Context, aanmeldingID string, limit, offset int) ([]*models.StepEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var entries []*models.StepEntry
+	result := r.DB().WithContext(ctx).
+		Where("aanmelding_id = ?", aanmeldingID).
+		Order("recorded_at DESC, created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries)
+	if err := r.handleError("ListByAanmelding", result.Error); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DailyTotals telt de stappen van een deelnemer per dag in de gegeven tijdzone. Dagen zonder
+// regels worden niet teruggegeven.
+func (r *PostgresStepEntryRepository) DailyTotals(ctx context.Context, aanmeldingID string, from, to time.Time, timezone string) ([]models.StepDayTotal, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var totals []models.StepDayTotal
+	result := r.DB().WithContext(ctx).Raw(`
+		SELECT to_char(recorded_at AT TIME ZONE ?, 'YYYY-MM-DD') AS date, SUM(delta) AS steps
+		FROM step_entries
+		WHERE aanmelding_id = ? AND recorded_at >= ? AND recorded_at < ?
+		GROUP BY 1
+		ORDER BY 1`,
+		timezone, aanmeldingID, from, to).
+		Scan(&totals)
+	if err := r.handleError("DailyTotals", result.Error); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// SumByRegistrationPeriod telt alle regels van deelnemers die zich tussen from en to hebben
+// aangemeld, zodat correcties achteraf bij het juiste evenement blijven horen
+func (r *PostgresStepEntryRepository) SumByRegistrationPeriod(ctx context.Context, from, to time.Time) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var total int
+	result := r.DB().WithContext(ctx).Raw(`
+		SELECT COALESCE(SUM(se.delta), 0)
+		FROM step_entries se
+		JOIN aanmeldingen a ON a.id = se.aanmelding_id
+		WHERE a.created_at >= ? AND a.created_at < ?`,
+		from, to).
+		Scan(&total)
+	if err := r.handleError("SumByRegistrationPeriod", result.Error); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
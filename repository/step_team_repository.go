@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStepTeamRepository implementeert StepTeamRepository met PostgreSQL
+type PostgresStepTeamRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresStepTeamRepository maakt een nieuwe PostgreSQL repository voor stappen teams
+func NewPostgresStepTeamRepository(base *PostgresRepository) *PostgresStepTeamRepository {
+	return &PostgresStepTeamRepository{PostgresRepository: base}
+}
+
+// Create slaat een nieuw team op
+func (r *PostgresStepTeamRepository) Create(ctx context.Context, team *models.StepTeam) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(team)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByID haalt een team op; geeft nil terug als het niet bestaat
+func (r *PostgresStepTeamRepository) GetByID(ctx context.Context, id string) (*models.StepTeam, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var team models.StepTeam
+	result := r.DB().WithContext(ctx).First(&team, "id = ?", id)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &team, nil
+}
+
+// List haalt alle teams op, gesorteerd op naam
+func (r *PostgresStepTeamRepository) List(ctx context.Context) ([]*models.StepTeam, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var teams []*models.StepTeam
+	result := r.DB().WithContext(ctx).Order("naam ASC").Find(&teams)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// Update werkt een team bij
+func (r *PostgresStepTeamRepository) Update(ctx context.Context, team *models.StepTeam) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Save(team)
+	return r.handleError("Update", result.Error)
+}
+
+// Delete verwijdert een team; de leden blijven bestaan zonder team
+func (r *PostgresStepTeamRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Delete(&models.StepTeam{}, "id = ?", id)
+	return r.handleError("Delete", result.Error)
+}
+
+// AddMembers zet deelnemers in een team; een deelnemer die al in een ander team zat wordt verplaatst
+func (r *PostgresStepTeamRepository) AddMembers(ctx context.Context, teamID string, aanmeldingIDs []string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).
+		Model(&models.Aanmelding{}).
+		Where("id IN ?", aanmeldingIDs).
+		Update("team_id", teamID)
+	return r.handleError("AddMembers", result.Error)
+}
+
+// RemoveMember haalt een deelnemer uit een team
+func (r *PostgresStepTeamRepository) RemoveMember(ctx context.Context, teamID, aanmeldingID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).
+		Model(&models.Aanmelding{}).
+		Where("id = ? AND team_id = ?", aanmeldingID, teamID).
+		Update("team_id", nil)
+	return r.handleError("RemoveMember", result.Error)
+}
+
+// ListMembers haalt de leden van een team op, gesorteerd op naam
+func (r *PostgresStepTeamRepository) ListMembers(ctx context.Context, teamID string) ([]*models.Aanmelding, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var members []*models.Aanmelding
+	result := r.DB().WithContext(ctx).Where("team_id = ?", teamID).Order("naam ASC").Find(&members)
+	if err := r.handleError("ListMembers", result.Error); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// PostgresLeaderboardRepository implementeert LeaderboardRepository met PostgreSQL
+type PostgresLeaderboardRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresLeaderboardRepository maakt een nieuwe PostgreSQL repository voor klassementen
+func NewPostgresLeaderboardRepository(base *PostgresRepository) *PostgresLeaderboardRepository {
+	return &PostgresLeaderboardRepository{PostgresRepository: base}
+}
+
+// applyFilter beperkt een klassement tot het evenementjaar, de periode, de route en het team.
+// Aanmeldingen in test mode tellen nooit mee.
+func (r *PostgresLeaderboardRepository) applyFilter(query *gorm.DB, filter models.LeaderboardFilter) *gorm.DB {
+	query = query.Where("a.test_mode = ? AND a.created_at >= ? AND a.created_at < ?", false, filter.RegisteredFrom, filter.RegisteredTo)
+	if filter.From != nil {
+		query = query.Where("se.recorded_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("se.recorded_at < ?", *filter.To)
+	}
+	if filter.Route != "" {
+		query = query.Where("a.afstand = ?", filter.Route)
+	}
+	if filter.TeamID != "" {
+		query = query.Where("a.team_id = ?", filter.TeamID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return query
+}
+
+// Participants geeft de deelnemers met de meeste stappen, gesorteerd van hoog naar laag
+func (r *PostgresLeaderboardRepository) Participants(ctx context.Context, filter models.LeaderboardFilter) ([]models.LeaderboardEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).
+		Table("aanmeldingen a").
+		Select("a.id AS aanmelding_id, a.naam, a.afstand AS route, a.team_id, t.naam AS team_naam, a.leaderboard_zichtbaar AS zichtbaar, SUM(se.delta) AS steps").
+		Joins("JOIN step_entries se ON se.aanmelding_id = a.id").
+		Joins("LEFT JOIN step_teams t ON t.id = a.team_id").
+		Group("a.id, t.naam").
+		Having("SUM(se.delta) > 0").
+		Order("steps DESC, a.naam ASC")
+
+	var entries []models.LeaderboardEntry
+	result := r.applyFilter(query, filter).Scan(&entries)
+	if err := r.handleError("Participants", result.Error); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Teams geeft de actieve teams met de meeste stappen, op totaal of op gemiddelde per lid
+func (r *PostgresLeaderboardRepository) Teams(ctx context.Context, filter models.LeaderboardFilter) ([]models.TeamLeaderboardEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	order := "steps DESC, t.naam ASC"
+	if filter.SortByAverage {
+		order = "SUM(se.delta)::float / COUNT(DISTINCT a.id) DESC, t.naam ASC"
+	}
+
+	query := r.DB().WithContext(ctx).
+		Table("step_teams t").
+		Select("t.id AS team_id, t.naam, t.type, COUNT(DISTINCT a.id) AS members, SUM(se.delta) AS steps").
+		Joins("JOIN aanmeldingen a ON a.team_id = t.id").
+		Joins("JOIN step_entries se ON se.aanmelding_id = a.id").
+		Where("t.is_actief = ?", true).
+		Group("t.id").
+		Having("SUM(se.delta) > 0").
+		Order(order)
+
+	var entries []models.TeamLeaderboardEntry
+	result := r.applyFilter(query, filter).Scan(&entries)
+	if err := r.handleError("Teams", result.Error); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SetVisibility legt vast of een deelnemer met naam op het openbare klassement wil
+func (r *PostgresLeaderboardRepository) SetVisibility(ctx context.Context, aanmeldingID string, visible bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).
+		Model(&models.Aanmelding{}).
+		Where("id = ?", aanmeldingID).
+		Update("leaderboard_zichtbaar", visible)
+	return r.handleError("SetVisibility", result.Error)
+}
+
+// PostgresStepMilestoneRepository implementeert StepMilestoneRepository met PostgreSQL
+type PostgresStepMilestoneRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresStepMilestoneRepository maakt een nieuwe PostgreSQL repository voor mijlpalen
+func NewPostgresStepMilestoneRepository(base *PostgresRepository) *PostgresStepMilestoneRepository {
+	return &PostgresStepMilestoneRepository{PostgresRepository: base}
+}
+
+// Award kent een mijlpaal toe; geeft false terug als de deelnemer hem al had
+func (r *PostgresStepMilestoneRepository) Award(ctx context.Context, milestone *models.StepMilestone) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(milestone)
+	if err := r.handleError("Award", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListByAanmelding haalt de mijlpalen van een deelnemer op, laagste eerst
+func (r *PostgresStepMilestoneRepository) ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.StepMilestone, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var milestones []*models.StepMilestone
+	result := r.DB().WithContext(ctx).Where("aanmelding_id = ?", aanmeldingID).Order("milestone ASC").Find(&milestones)
+	if err := r.handleError("ListByAanmelding", result.Error); err != nil {
+		return nil, err
+	}
+	return milestones, nil
+}
+
+// MarkEmailSent legt vast dat de felicitatie voor een mijlpaal is verstuurd
+func (r *PostgresStepMilestoneRepository) MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).
+		Model(&models.StepMilestone{}).
+		Where("id = ?", id).
+		Update("email_sent_at", sentAt)
+	return r.handleError("MarkEmailSent", result.Error)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+)
+
+// PostgresTelegramChatRepository implementeert TelegramChatRepository met PostgreSQL
+type PostgresTelegramChatRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresTelegramChatRepository maakt een nieuwe PostgreSQL repository voor Telegram chats
+func NewPostgresTelegramChatRepository(base *PostgresRepository) *PostgresTelegramChatRepository {
+	return &PostgresTelegramChatRepository{PostgresRepository: base}
+}
+
+// Create registreert een nieuwe chat
+func (r *PostgresTelegramChatRepository) Create(ctx context.Context, chat *models.TelegramChat) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Create(chat)
+	return r.handleError("Create", result.Error)
+}
+
+// GetByID haalt een chat op; geeft nil terug als hij niet bestaat
+func (r *PostgresTelegramChatRepository) GetByID(ctx context.Context, id string) (*models.TelegramChat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var chat models.TelegramChat
+	result := r.DB().WithContext(ctx).First(&chat, "id = ?", id)
+	if err := r.handleError("GetByID", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &chat, nil
+}
+
+// GetByChatID haalt een chat op met het Telegram chat ID; geeft nil terug als hij niet bestaat
+func (r *PostgresTelegramChatRepository) GetByChatID(ctx context.Context, chatID int64) (*models.TelegramChat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var chat models.TelegramChat
+	result := r.DB().WithContext(ctx).First(&chat, "chat_id = ?", chatID)
+	if err := r.handleError("GetByChatID", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &chat, nil
+}
+
+// List haalt alle chats op, gesorteerd op naam
+func (r *PostgresTelegramChatRepository) List(ctx context.Context) ([]*models.TelegramChat, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var chats []*models.TelegramChat
+	result := r.DB().WithContext(ctx).Order("naam ASC").Find(&chats)
+	if err := r.handleError("List", result.Error); err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+// Update werkt een chat bij
+func (r *PostgresTelegramChatRepository) Update(ctx context.Context, chat *models.TelegramChat) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Save(chat)
+	return r.handleError("Update", result.Error)
+}
+
+// Delete verwijdert een chat uit het register
+func (r *PostgresTelegramChatRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := r.DB().WithContext(ctx).Delete(&models.TelegramChat{}, "id = ?", id)
+	return r.handleError("Delete", result.Error)
+}
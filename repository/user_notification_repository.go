@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// PostgresUserNotificationRepository implementeert UserNotificationRepository met PostgreSQL
+type PostgresUserNotificationRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresUserNotificationRepository maakt een nieuwe PostgreSQL repository voor in-app notificaties
+func NewPostgresUserNotificationRepository(base *PostgresRepository) *PostgresUserNotificationRepository {
+	return &PostgresUserNotificationRepository{PostgresRepository: base}
+}
+
+// Create zet een persoonlijke notificatie in de inbox van een gebruiker
+func (r *PostgresUserNotificationRepository) Create(ctx context.Context, notification *models.UserNotification) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Create(notification)
+	return r.handleError("Create", result.Error)
+}
+
+// CreateForPermission zet een systeemnotificatie in de inbox van alle actieve gebruikers met de
+// gegeven permissie via een actieve rol. Gebruikers die de notificatie al hebben of die dit type
+// in hun voorkeuren hebben uitgezet worden overgeslagen. Geeft de aangemaakte notificaties terug.
+func (r *PostgresUserNotificationRepository) CreateForPermission(ctx context.Context, notificationID, resource, action string) ([]*models.UserNotification, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var created []*models.UserNotification
+	result := r.DB().WithContext(ctx).Raw(`
+		INSERT INTO user_notifications (notification_id, user_id, type, title, message)
+		SELECT DISTINCT n.id, g.id, n.type, n.title, n.message
+		FROM notifications n
+		CROSS JOIN gebruikers g
+		JOIN user_roles ur ON ur.user_id = g.id
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE n.id = ?
+		  AND g.is_actief = true
+		  AND ur.is_active = true
+		  AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+		  AND p.resource = ? AND p.action = ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM notification_preferences np
+			WHERE np.user_id = g.id AND np.type = n.type AND np.in_app = false
+		  )
+		ON CONFLICT (notification_id, user_id) DO NOTHING
+		RETURNING *`,
+		notificationID, resource, action).Scan(&created)
+	if err := r.handleError("CreateForPermission", result.Error); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ListByUser haalt de notificaties van een gebruiker op, nieuwste eerst
+func (r *PostgresUserNotificationRepository) ListByUser(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*models.UserNotification, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []*models.UserNotification
+	result := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications)
+	if err := r.handleError("ListByUser", result.Error); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// CountUnread telt de ongelezen notificaties van een gebruiker
+func (r *PostgresUserNotificationRepository) CountUnread(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var count int64
+	result := r.DB().WithContext(ctx).Model(&models.UserNotification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count)
+	if err := r.handleError("CountUnread", result.Error); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead markeert de gegeven notificaties van een gebruiker als gelezen en geeft terug hoeveel
+// notificaties zijn bijgewerkt. Notificaties van andere gebruikers worden niet aangeraakt.
+func (r *PostgresUserNotificationRepository) MarkRead(ctx context.Context, userID string, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Model(&models.UserNotification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		Update("read_at", time.Now())
+	if err := r.handleError("MarkRead", result.Error); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// MarkAllRead markeert alle notificaties van een gebruiker als gelezen
+func (r *PostgresUserNotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Model(&models.UserNotification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if err := r.handleError("MarkAllRead", result.Error); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// Delete verwijdert een notificatie uit de inbox van een gebruiker en geeft terug of hij bestond
+func (r *PostgresUserNotificationRepository) Delete(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).Delete(&models.UserNotification{}, "id = ? AND user_id = ?", id, userID)
+	if err := r.handleError("Delete", result.Error); err != nil {
+		return false, err
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// PostgresNotificationPreferenceRepository implementeert NotificationPreferenceRepository met PostgreSQL
+type PostgresNotificationPreferenceRepository struct {
+	*PostgresRepository
+}
+
+// NewPostgresNotificationPreferenceRepository maakt een nieuwe PostgreSQL repository voor notificatie voorkeuren
+func NewPostgresNotificationPreferenceRepository(base *PostgresRepository) *PostgresNotificationPreferenceRepository {
+	return &PostgresNotificationPreferenceRepository{PostgresRepository: base}
+}
+
+// ListByUser haalt de opgeslagen voorkeuren van een gebruiker op
+func (r *PostgresNotificationPreferenceRepository) ListByUser(ctx context.Context, userID string) ([]*models.NotificationPreference, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var preferences []*models.NotificationPreference
+	result := r.DB().WithContext(ctx).Where("user_id = ?", userID).Order("type ASC").Find(&preferences)
+	if err := r.handleError("ListByUser", result.Error); err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// Get haalt de voorkeur van een gebruiker voor één type op; geeft nil terug als er geen is
+func (r *PostgresNotificationPreferenceRepository) Get(ctx context.Context, userID string, notificationType models.NotificationType) (*models.NotificationPreference, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var preference models.NotificationPreference
+	result := r.DB().WithContext(ctx).First(&preference, "user_id = ? AND type = ?", userID, notificationType)
+	if err := r.handleError("Get", result.Error); err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &preference, nil
+}
+
+// Save slaat een voorkeur op of werkt hem bij
+func (r *PostgresNotificationPreferenceRepository) Save(ctx context.Context, preference *models.NotificationPreference) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"in_app", "push", "updated_at"}),
+		}).
+		Create(preference)
+	return r.handleError("Save", result.Error)
+}
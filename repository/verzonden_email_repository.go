@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"dklautomationgo/models"
+	"time"
 )
 
 // PostgresVerzondEmailRepository implementeert VerzondEmailRepository met PostgreSQL
@@ -125,3 +126,124 @@ func (r *PostgresVerzondEmailRepository) FindByOntvanger(ctx context.Context, on
 
 	return emails, nil
 }
+
+// ListFiltered haalt verzonden emails op met filters en geeft ook het totaal aantal terug
+func (r *PostgresVerzondEmailRepository) ListFiltered(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]*models.VerzondEmail, int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := r.DB().WithContext(ctx).Model(&models.VerzondEmail{})
+
+	if ontvanger, ok := filters["ontvanger"].(string); ok && ontvanger != "" {
+		query = query.Where("ontvanger ILIKE ?", "%"+ontvanger+"%")
+	}
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if emailType, ok := filters["email_type"].(string); ok && emailType != "" {
+		query = query.Where("email_type = ?", emailType)
+	}
+
+	if templateNaam, ok := filters["template_naam"].(string); ok && templateNaam != "" {
+		query = query.Where("template_naam = ?", templateNaam)
+	}
+
+	if contactID, ok := filters["contact_id"].(string); ok && contactID != "" {
+		query = query.Where("contact_id = ?", contactID)
+	}
+
+	if aanmeldingID, ok := filters["aanmelding_id"].(string); ok && aanmeldingID != "" {
+		query = query.Where("aanmelding_id = ?", aanmeldingID)
+	}
+
+	if van, ok := filters["van"].(time.Time); ok && !van.IsZero() {
+		query = query.Where("verzonden_op >= ?", van)
+	}
+
+	if tot, ok := filters["tot"].(time.Time); ok && !tot.IsZero() {
+		query = query.Where("verzonden_op < ?", tot)
+	}
+
+	var total int64
+	if err := r.handleError("ListFiltered", query.Count(&total).Error); err != nil {
+		return nil, 0, err
+	}
+
+	var emails []*models.VerzondEmail
+	result := query.
+		Order("verzonden_op DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&emails)
+
+	if err := r.handleError("ListFiltered", result.Error); err != nil {
+		return nil, 0, err
+	}
+
+	return emails, total, nil
+}
+
+// UpdateStatus werkt de status en eventuele foutmelding van een verzonden email bij
+func (r *PostgresVerzondEmailRepository) UpdateStatus(ctx context.Context, id, status, foutBericht string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	updates := map[string]interface{}{
+		"status":       status,
+		"fout_bericht": foutBericht,
+	}
+
+	// Bij een geslaagde verzending via de queue is het verzendmoment later dan het aanmaakmoment
+	if status == models.VerzondEmailStatusVerzonden {
+		updates["verzonden_op"] = time.Now()
+	}
+
+	result := r.DB().WithContext(ctx).
+		Model(&models.VerzondEmail{}).
+		Where("id = ?", id).
+		Updates(updates)
+
+	return r.handleError("UpdateStatus", result.Error)
+}
+
+// FindByThreadID haalt verzonden emails op die bij een gesprek horen
+func (r *PostgresVerzondEmailRepository) FindByThreadID(ctx context.Context, threadID string) ([]*models.VerzondEmail, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var emails []*models.VerzondEmail
+	result := r.DB().WithContext(ctx).
+		Where("thread_id = ?", threadID).
+		Order("verzonden_op ASC").
+		Find(&emails)
+
+	if err := r.handleError("FindByThreadID", result.Error); err != nil {
+		return nil, err
+	}
+
+	return emails, nil
+}
+
+// FindByMessageIDs haalt verzonden emails op basis van hun Message-ID op
+func (r *PostgresVerzondEmailRepository) FindByMessageIDs(ctx context.Context, messageIDs []string) ([]*models.VerzondEmail, error) {
+	var emails []*models.VerzondEmail
+	if len(messageIDs) == 0 {
+		return emails, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result := r.DB().WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("verzonden_op DESC").
+		Find(&emails)
+
+	if err := r.handleError("FindByMessageIDs", result.Error); err != nil {
+		return nil, err
+	}
+
+	return emails, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"net/textproto"
+	"time"
+)
+
+// DefaultSoftBounceLimit is het aantal zachte bounces waarna een adres wordt onderdrukt
+const DefaultSoftBounceLimit = 3
+
+// BounceProcessor verwerkt delivery status notifications (RFC 3464) uit de inbox en houdt
+// de suppressielijst bij. Een harde bounce onderdrukt het adres direct, zachte bounces pas
+// na het ingestelde aantal. Alleen een DSN over een bericht dat wij hebben verzonden telt;
+// iedereen kan een mail met een delivery-status deel naar de inbox sturen.
+type BounceProcessor struct {
+	suppressionRepo   repository.EmailSuppressionRepository
+	sentEmailRepo     repository.VerzondEmailRepository
+	metrics           *EmailMetrics
+	prometheusMetrics *PrometheusMetrics
+	softBounceLimit   int
+	newsletterTracker *NewsletterTracker
+}
+
+// NewBounceProcessor maakt een nieuwe BounceProcessor. Met sentEmailRepo wordt gecontroleerd of
+// het teruggestuurde bericht van ons komt. Metrics mogen nil zijn.
+func NewBounceProcessor(suppressionRepo repository.EmailSuppressionRepository, sentEmailRepo repository.VerzondEmailRepository, metrics *EmailMetrics, prometheusMetrics *PrometheusMetrics, softBounceLimit int) *BounceProcessor {
+	if softBounceLimit <= 0 {
+		softBounceLimit = DefaultSoftBounceLimit
+	}
+	return &BounceProcessor{
+		suppressionRepo:   suppressionRepo,
+		sentEmailRepo:     sentEmailRepo,
+		metrics:           metrics,
+		prometheusMetrics: prometheusMetrics,
+		softBounceLimit:   softBounceLimit,
+	}
+}
+
+// SetNewsletterTracker stelt de tracker in, zodat een bounce ook bij de aflevering van de
+// laatst verzonden nieuwsbrief aan het adres wordt vastgelegd
+func (p *BounceProcessor) SetNewsletterTracker(tracker *NewsletterTracker) {
+	p.newsletterTracker = tracker
+}
+
+// DeliveryStatuses haalt de mislukte ontvangers uit de DSN delen van een inkomende email
+func DeliveryStatuses(email *models.IncomingEmail) []*DeliveryStatus {
+	var statuses []*DeliveryStatus
+	for _, att := range email.Attachments {
+		if !IsDeliveryStatusType(att.ContentType) {
+			continue
+		}
+		parsed, err := ParseDeliveryStatus(att.Data)
+		if err != nil {
+			logger.Warn("Kon delivery status niet lezen", "error", err, "message_id", email.MessageID)
+			continue
+		}
+		statuses = append(statuses, parsed...)
+	}
+	return statuses
+}
+
+// ReturnedMessageIDs haalt de Message-ID van het teruggestuurde bericht uit de message/rfc822 of
+// text/rfc822-headers delen van een DSN
+func ReturnedMessageIDs(email *models.IncomingEmail) []string {
+	var ids []string
+	for _, att := range email.Attachments {
+		if !IsReturnedMessageType(att.ContentType) {
+			continue
+		}
+		// Alleen de headers zijn nodig; een headers-deel zonder lege regel eindigt met EOF
+		header, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(att.Data))).ReadMIMEHeader()
+		ids = append(ids, ParseMessageIDs(header.Get("Message-ID"))...)
+	}
+	return ids
+}
+
+// Process registreert de bounces uit een opgeslagen DSN en geeft het aantal verwerkte ontvangers
+// terug. Een DSN waarvan het teruggestuurde bericht niet in verzonden_emails staat wordt genegeerd.
+func (p *BounceProcessor) Process(ctx context.Context, email *models.IncomingEmail) (int, error) {
+	statuses := DeliveryStatuses(email)
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	ids := ReturnedMessageIDs(email)
+	if len(ids) == 0 {
+		logger.Warn("DSN zonder teruggestuurd bericht genegeerd", "email_id", email.ID, "van", email.From)
+		return 0, nil
+	}
+	sent, err := p.sentEmailRepo.FindByMessageIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(sent) == 0 {
+		logger.Warn("DSN over een bericht dat niet door ons is verzonden genegeerd", "email_id", email.ID, "van", email.From, "message_ids", ids)
+		return 0, nil
+	}
+
+	for _, status := range statuses {
+		if err := p.RecordBounce(ctx, status, &email.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(statuses), nil
+}
+
+// RecordBounce werkt de suppressie van de ontvanger bij op basis van één delivery status
+func (p *BounceProcessor) RecordBounce(ctx context.Context, status *DeliveryStatus, incomingEmailID *string) error {
+	suppression, err := p.suppressionRepo.GetByEmail(ctx, status.Recipient)
+	if err != nil {
+		return err
+	}
+	if suppression == nil {
+		suppression = &models.EmailSuppression{Email: status.Recipient}
+	}
+
+	bounceType := status.BounceType()
+	suppression.BounceType = bounceType
+	suppression.StatusCode = status.Status
+	suppression.DiagnosticCode = status.DiagnosticCode
+	suppression.BounceCount++
+	suppression.IncomingEmailID = incomingEmailID
+	suppression.LastBounceAt = time.Now()
+	if bounceType == models.BounceTypeHard || suppression.BounceCount >= p.softBounceLimit {
+		suppression.Actief = true
+	}
+
+	if err := p.suppressionRepo.Save(ctx, suppression); err != nil {
+		return err
+	}
+
+	if p.metrics != nil {
+		p.metrics.RecordBounce(bounceType)
+	}
+	if p.prometheusMetrics != nil {
+		p.prometheusMetrics.RecordEmailBounced(bounceType)
+	}
+	if p.newsletterTracker != nil {
+		p.newsletterTracker.RecordBounce(ctx, status.Recipient)
+	}
+
+	logger.Info("Bounce geregistreerd",
+		"ontvanger", status.Recipient,
+		"type", bounceType,
+		"status", status.Status,
+		"bounce_count", suppression.BounceCount,
+		"onderdrukt", suppression.Actief)
+	return nil
+}
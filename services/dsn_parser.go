@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"dklautomationgo/models"
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+// DeliveryStatus is de status van één ontvanger uit een RFC 3464 delivery status notification
+type DeliveryStatus struct {
+	Recipient      string
+	Action         string
+	Status         string // Enhanced status code, bijv. "5.1.1"
+	DiagnosticCode string
+}
+
+// BounceType geeft een harde bounce terug voor permanente fouten (5.x.x) en anders een zachte
+func (d *DeliveryStatus) BounceType() string {
+	if strings.HasPrefix(d.Status, "5") {
+		return models.BounceTypeHard
+	}
+	return models.BounceTypeSoft
+}
+
+// IsDeliveryStatusType geeft aan of een content type een machine-leesbaar DSN deel is
+func IsDeliveryStatusType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "message/delivery-status" || contentType == "message/global-delivery-status"
+}
+
+// IsReturnedMessageType geeft aan of een content type het teruggestuurde bericht van een DSN is,
+// volledig of alleen de headers
+func IsReturnedMessageType(contentType string) bool {
+	switch strings.ToLower(contentType) {
+	case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+		return true
+	}
+	return false
+}
+
+// ParseDeliveryStatus leest het message/delivery-status deel van een DSN. Het deel bestaat uit
+// een blok met per-message velden gevolgd door een blok per ontvanger. Alleen ontvangers
+// waarvan de aflevering definitief is mislukt (Action: failed) worden teruggegeven.
+func ParseDeliveryStatus(data []byte) ([]*DeliveryStatus, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+
+	var statuses []*DeliveryStatus
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			if status := deliveryStatusFromFields(fields); status != nil {
+				statuses = append(statuses, status)
+			}
+		}
+		if err == io.EOF {
+			return statuses, nil
+		}
+		if err != nil {
+			// Een kapotte DSN levert wel de ontvangers op die al gelezen zijn
+			if len(statuses) > 0 {
+				return statuses, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// deliveryStatusFromFields zet een per-recipient blok om naar een DeliveryStatus
+func deliveryStatusFromFields(fields textproto.MIMEHeader) *DeliveryStatus {
+	recipient := dsnAddress(fields.Get("Final-Recipient"))
+	if recipient == "" {
+		recipient = dsnAddress(fields.Get("Original-Recipient"))
+	}
+	if recipient == "" {
+		return nil
+	}
+
+	action := strings.ToLower(strings.TrimSpace(fields.Get("Action")))
+	if action != "failed" {
+		return nil
+	}
+
+	status := strings.TrimSpace(fields.Get("Status"))
+	if i := strings.IndexAny(status, " ("); i >= 0 {
+		status = status[:i]
+	}
+
+	return &DeliveryStatus{
+		Recipient:      recipient,
+		Action:         action,
+		Status:         status,
+		DiagnosticCode: strings.TrimSpace(fields.Get("Diagnostic-Code")),
+	}
+}
+
+// dsnAddress haalt het adres uit een veld als "rfc822; jan@example.com"
+func dsnAddress(value string) string {
+	if i := strings.Index(value, ";"); i >= 0 {
+		value = value[i+1:]
+	}
+	value = strings.Trim(strings.TrimSpace(value), "<>")
+	if !strings.Contains(value, "@") {
+		return ""
+	}
+	return strings.ToLower(value)
+}
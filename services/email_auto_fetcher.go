@@ -113,22 +113,45 @@ func (f *EmailAutoFetcher) fetchLoop() {
 	}
 }
 
-// fetchOnce haalt eenmalig emails op en slaat ze op
+// fetchOnce haalt eenmalig emails op, slaat ze op en synchroniseert daarna de status met de mailserver
 func (f *EmailAutoFetcher) fetchOnce() {
 	f.mutex.Lock()
 	f.lastRunTime = time.Now()
 	f.mutex.Unlock()
 
-	logger.Info("Automatisch emails ophalen...")
-
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	// Haal emails op van alle accounts
+	f.fetchAndStore(ctx)
+	f.reconcile(ctx)
+}
+
+// reconcile neemt wijzigingen die op de mailserver zijn gedaan (bijv. gelezen in een
+// mailclient) over en schrijft eerder mislukte acties opnieuw terug
+func (f *EmailAutoFetcher) reconcile(ctx context.Context) {
+	result, err := f.mailFetcher.Reconcile(ctx, f.emailRepository)
+	if err != nil {
+		logger.Error("Fout bij reconciliatie met mailserver", "error", err)
+		return
+	}
+
+	logger.Info("Reconciliatie met mailserver voltooid",
+		"checked", result.Checked,
+		"marked_processed", result.MarkedProcessed,
+		"marked_unprocessed", result.MarkedUnprocessed,
+		"retried", result.Retried,
+		"missing", result.Missing)
+}
+
+// fetchAndStore haalt nieuwe emails op van alle accounts en slaat ze op
+func (f *EmailAutoFetcher) fetchAndStore(ctx context.Context) {
+	logger.Info("Automatisch emails ophalen...")
+
+	// Haal emails op van alle accounts. Bij een fout in één account of folder komen
+	// de emails van de rest toch mee en worden die gewoon opgeslagen.
 	emails, err := f.mailFetcher.FetchMails()
 	if err != nil {
 		logger.Error("Fout bij automatisch ophalen van emails", "error", err)
-		return
 	}
 
 	if len(emails) == 0 {
@@ -138,24 +161,8 @@ func (f *EmailAutoFetcher) fetchOnce() {
 
 	logger.Info("Nieuwe emails gevonden", "count", len(emails))
 
-	// Sla nieuwe emails op in de database
-	savedCount := 0
-	for _, email := range emails {
-		// Controleer eerst of de email al bestaat om dubbele emails te voorkomen
-		existing, err := f.emailRepository.FindByUID(ctx, email.UID)
-		if err == nil && existing != nil {
-			logger.Debug("Email overgeslagen (bestaat al)", "uid", email.UID)
-			continue
-		}
-
-		// Sla de nieuwe email op
-		if err := f.emailRepository.Create(ctx, email); err != nil {
-			logger.Error("Fout bij opslaan van nieuwe email", "error", err, "uid", email.UID)
-			continue
-		}
-
-		savedCount++
-	}
+	// Sla nieuwe emails op in de database; de synchronisatiestatus schuift pas na het opslaan op
+	savedCount := f.mailFetcher.StoreFetchedEmails(ctx, f.emailRepository, emails)
 
 	logger.Info("Nieuwe emails opgeslagen", "count", savedCount)
 }
@@ -16,8 +16,23 @@ type EmailBatch struct {
 	FromAddress  string // Optional custom from address
 	BatchID      string
 	CreatedAt    time.Time
+
+	// Per ontvanger afwijkende template data en headers, bijv. een persoonlijke afmeldlink
+	RecipientData    map[string]map[string]interface{}
+	RecipientHeaders map[string]map[string]string
+
+	// Per ontvanger de aflevering van een nieuwsbrief die de outbox na verzending bijwerkt
+	RecipientDeliveryIDs map[string]string
+
+	// resultHandler is de handler die gold toen de batch werd aangemaakt
+	resultHandler BatchResultHandler
 }
 
+// BatchResultHandler wordt na het verzenden per ontvanger aangeroepen, met de fout als het mislukte.
+// queued geeft aan dat de email alleen in de outbox is geplaatst; het definitieve resultaat
+// volgt dan pas als een queue worker het item heeft verwerkt.
+type BatchResultHandler func(recipient string, queued bool, err error)
+
 // EmailBatcher verzamelt emails in batches en verwerkt ze periodiek
 type EmailBatcher struct {
 	batchMap          map[string]*EmailBatch
@@ -28,16 +43,18 @@ type EmailBatcher struct {
 	ticker            *time.Ticker
 	doneChan          chan bool
 	prometheusMetrics *PrometheusMetrics
+	resultHandlers    map[string]BatchResultHandler
 }
 
 // NewEmailBatcher creëert een nieuwe email batcher
 func NewEmailBatcher(emailSvc *EmailService, batchSize int, batchWindow time.Duration) *EmailBatcher {
 	batcher := &EmailBatcher{
-		batchMap:    make(map[string]*EmailBatch),
-		batchSize:   batchSize,
-		batchWindow: batchWindow,
-		emailSvc:    emailSvc,
-		doneChan:    make(chan bool),
+		batchMap:       make(map[string]*EmailBatch),
+		batchSize:      batchSize,
+		batchWindow:    batchWindow,
+		emailSvc:       emailSvc,
+		doneChan:       make(chan bool),
+		resultHandlers: make(map[string]BatchResultHandler),
 	}
 
 	// Start periodieke verwerking
@@ -47,12 +64,47 @@ func NewEmailBatcher(emailSvc *EmailService, batchSize int, batchWindow time.Dur
 	return batcher
 }
 
+// SetResultHandler stelt voor een batch key een handler in die na het verzenden per ontvanger
+// het resultaat krijgt, bijvoorbeeld om de aflevering van een nieuwsbrief bij te houden.
+// Een batch onthoudt de handler bij het aanmaken, zodat de aanroeper de handler met een nil
+// handler kan verwijderen zodra alle ontvangers zijn toegevoegd.
+func (b *EmailBatcher) SetResultHandler(batchKey string, handler BatchResultHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if handler == nil {
+		delete(b.resultHandlers, batchKey)
+		return
+	}
+	b.resultHandlers[batchKey] = handler
+}
+
 // AddToBatch voegt een email toe aan een batch
 func (b *EmailBatcher) AddToBatch(batchKey, recipient, subject string,
 	templateName string, templateData map[string]interface{}, fromAddress ...string) {
 
-	b.mutex.Lock()
-	defer b.mutex.Unlock()
+	b.addToBatch(batchKey, recipient, subject, templateName, templateData, nil, "", false, fromAddress...)
+}
+
+// AddPersonalizedToBatch voegt een email toe aan een batch met eigen template data en headers
+// voor deze ontvanger. Onderwerp, template en afzender komen van de batch.
+func (b *EmailBatcher) AddPersonalizedToBatch(batchKey, recipient, subject string,
+	templateName string, templateData map[string]interface{}, headers map[string]string, fromAddress ...string) {
+
+	b.addToBatch(batchKey, recipient, subject, templateName, templateData, headers, "", true, fromAddress...)
+}
+
+// AddTrackedToBatch doet hetzelfde als AddPersonalizedToBatch en koppelt de email aan de
+// aflevering van een nieuwsbrief, zodat de outbox die na de definitieve verzending bijwerkt
+func (b *EmailBatcher) AddTrackedToBatch(batchKey, recipient, subject string,
+	templateName string, templateData map[string]interface{}, headers map[string]string, deliveryID string, fromAddress ...string) {
+
+	b.addToBatch(batchKey, recipient, subject, templateName, templateData, headers, deliveryID, true, fromAddress...)
+}
+
+// addToBatch voegt een ontvanger toe aan een batch, met of zonder eigen data, headers en aflevering
+func (b *EmailBatcher) addToBatch(batchKey, recipient, subject string,
+	templateName string, templateData map[string]interface{}, headers map[string]string, deliveryID string, personalized bool, fromAddress ...string) {
 
 	// Extract from address if provided
 	var fromAddr string
@@ -60,6 +112,23 @@ func (b *EmailBatcher) AddToBatch(batchKey, recipient, subject string,
 		fromAddr = fromAddress[0]
 	}
 
+	// Met een actieve outbox gaat de email meteen de database in. Een batch in het geheugen
+	// zou bij een herstart verloren gaan; het tempo bewaken de queue workers al.
+	if b.emailSvc != nil && b.emailSvc.queueRunning() {
+		b.mutex.Lock()
+		resultHandler := b.resultHandlers[batchKey]
+		b.mutex.Unlock()
+
+		if !personalized {
+			headers = nil
+		}
+		b.sendEmail(batchKey, recipient, subject, templateName, templateData, headers, deliveryID, fromAddr, resultHandler)
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
 	// Maak een nieuwe batch als deze niet bestaat
 	batch, exists := b.batchMap[batchKey]
 	if !exists {
@@ -72,6 +141,8 @@ func (b *EmailBatcher) AddToBatch(batchKey, recipient, subject string,
 			FromAddress:  fromAddr,
 			BatchID:      "batch-" + time.Now().Format("20060102-150405"),
 			CreatedAt:    time.Now(),
+
+			resultHandler: b.resultHandlers[batchKey],
 		}
 		b.batchMap[batchKey] = batch
 	} else {
@@ -83,6 +154,20 @@ func (b *EmailBatcher) AddToBatch(batchKey, recipient, subject string,
 
 	// Voeg ontvanger toe
 	batch.Recipients = append(batch.Recipients, recipient)
+	if personalized {
+		if batch.RecipientData == nil {
+			batch.RecipientData = make(map[string]map[string]interface{})
+			batch.RecipientHeaders = make(map[string]map[string]string)
+		}
+		batch.RecipientData[recipient] = templateData
+		batch.RecipientHeaders[recipient] = headers
+	}
+	if deliveryID != "" {
+		if batch.RecipientDeliveryIDs == nil {
+			batch.RecipientDeliveryIDs = make(map[string]string)
+		}
+		batch.RecipientDeliveryIDs[recipient] = deliveryID
+	}
 
 	// Verwerk meteen als we de batch size bereiken
 	if len(batch.Recipients) >= b.batchSize {
@@ -136,26 +221,41 @@ func (b *EmailBatcher) processBatch(batchKey string, batch *EmailBatch) {
 
 	// Verwerk elke email sequentieel
 	for _, recipient := range batch.Recipients {
-		var err error
-		if batch.FromAddress != "" {
-			err = b.emailSvc.SendTemplateEmail(recipient, batch.Subject, batch.TemplateName, batch.TemplateData, batch.FromAddress)
-		} else {
-			err = b.emailSvc.SendTemplateEmail(recipient, batch.Subject, batch.TemplateName, batch.TemplateData)
-		}
-
-		if err != nil {
-			logger.Error("Fout bij verzenden batch email",
-				"error", err,
-				"recipient", recipient,
-				"batch_id", batch.BatchID,
-			)
-			continue // Ga door met de volgende email
+		data := batch.TemplateData
+		if recipientData, ok := batch.RecipientData[recipient]; ok {
+			data = recipientData
 		}
+		// Bij een fout gaan we door met de volgende email
+		b.sendEmail(batch.BatchID, recipient, batch.Subject, batch.TemplateName, data, batch.RecipientHeaders[recipient],
+			batch.RecipientDeliveryIDs[recipient], batch.FromAddress, batch.resultHandler)
 	}
 
 	b.updateBatchCount()
 }
 
+// sendEmail verstuurt één email uit een batch en geeft het resultaat door aan de handler
+func (b *EmailBatcher) sendEmail(batchID, recipient, subject, templateName string,
+	data map[string]interface{}, headers map[string]string, deliveryID, fromAddress string, resultHandler BatchResultHandler) {
+
+	// Het onderwerp van een batch is dat van de nieuwsbrief, niet dat van het template
+	meta := EmailMetadata{Headers: headers, KeepSubject: true}
+	if deliveryID != "" {
+		meta.NewsletterDeliveryID = &deliveryID
+	}
+	queued, err := b.emailSvc.sendTemplateEmail(recipient, subject, templateName, data, meta, fromAddress)
+
+	if err != nil {
+		logger.Error("Fout bij verzenden batch email",
+			"error", err,
+			"recipient", recipient,
+			"batch_id", batchID,
+		)
+	}
+	if resultHandler != nil {
+		resultHandler(recipient, queued, err)
+	}
+}
+
 // FlushBatch forces immediate sending of a specific batch
 func (b *EmailBatcher) FlushBatch(batchKey string) {
 	b.mutex.Lock()
@@ -13,6 +13,7 @@ type EmailMetrics struct {
 	successEmails int64
 	failedEmails  int64
 	emailsByType  map[string]int64
+	bounces       map[string]int64
 	mutex         sync.RWMutex
 	startTime     time.Time
 	resetInterval time.Duration
@@ -24,6 +25,7 @@ func NewEmailMetrics(resetInterval time.Duration) *EmailMetrics {
 	now := time.Now()
 	return &EmailMetrics{
 		emailsByType:  make(map[string]int64),
+		bounces:       make(map[string]int64),
 		startTime:     now,
 		resetInterval: resetInterval,
 		lastResetTime: now,
@@ -50,6 +52,28 @@ func (m *EmailMetrics) RecordEmailFailed(emailType string) {
 	m.checkAndResetPeriodic()
 }
 
+// RecordBounce registreert een bounce van het opgegeven type ("hard" of "soft")
+func (m *EmailMetrics) RecordBounce(bounceType string) {
+	m.mutex.Lock()
+	m.bounces[bounceType]++
+	m.mutex.Unlock()
+
+	m.checkAndResetPeriodic()
+}
+
+// GetBounceCounts geeft een kopie van het aantal bounces per type
+func (m *EmailMetrics) GetBounceCounts() map[string]int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := make(map[string]int64, len(m.bounces))
+	for k, v := range m.bounces {
+		result[k] = v
+	}
+
+	return result
+}
+
 // GetTotalEmails geeft het totaal aantal verzonden emails
 func (m *EmailMetrics) GetTotalEmails() int64 {
 	return atomic.LoadInt64(&m.totalEmails)
@@ -156,4 +180,5 @@ func (m *EmailMetrics) resetCountersLocked() {
 	atomic.StoreInt64(&m.successEmails, 0)
 	atomic.StoreInt64(&m.failedEmails, 0)
 	m.emailsByType = make(map[string]int64)
+	m.bounces = make(map[string]int64)
 }
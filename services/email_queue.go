@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EmailQueue verwerkt de persistente email outbox met een pool van workers.
+// Elke worker claimt zelfstandig jobs; de database zorgt via SKIP LOCKED dat
+// een job nooit door twee workers tegelijk wordt opgepakt.
+type EmailQueue struct {
+	repo              repository.EmailQueueRepository
+	sentEmailRepo     repository.VerzondEmailRepository
+	deliveryRepo      repository.NewsletterDeliveryRepository
+	smtpClient        SMTPClient
+	prometheusMetrics PrometheusMetricsInterface
+	workers           int
+	batchSize         int
+	pollInterval      time.Duration
+	maxAttempts       int
+	baseBackoff       time.Duration
+	maxBackoff        time.Duration
+	staleAfter        time.Duration
+	ctx               context.Context
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+	running           bool
+	mu                sync.Mutex
+}
+
+// EmailQueueConfig bevat de instellingen van de email queue
+type EmailQueueConfig struct {
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// NewEmailQueue maakt een nieuwe EmailQueue
+func NewEmailQueue(repo repository.EmailQueueRepository, smtpClient SMTPClient, prometheusMetrics PrometheusMetricsInterface, cfg EmailQueueConfig) *EmailQueue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 6 * time.Hour
+	}
+
+	return &EmailQueue{
+		repo:              repo,
+		smtpClient:        smtpClient,
+		prometheusMetrics: prometheusMetrics,
+		workers:           cfg.Workers,
+		batchSize:         cfg.BatchSize,
+		pollInterval:      cfg.PollInterval,
+		maxAttempts:       cfg.MaxAttempts,
+		baseBackoff:       cfg.BaseBackoff,
+		maxBackoff:        cfg.MaxBackoff,
+		staleAfter:        10 * time.Minute,
+	}
+}
+
+// EmailQueueBackoff berekent de wachttijd voor de volgende poging (exponentieel, begrensd op max)
+func EmailQueueBackoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// SetSentEmailRepository stelt de repository in waarmee de status in verzonden_emails wordt bijgewerkt
+func (q *EmailQueue) SetSentEmailRepository(repo repository.VerzondEmailRepository) {
+	q.sentEmailRepo = repo
+}
+
+// SetNewsletterDeliveryRepository stelt de repository in waarmee de aflevering van een nieuwsbrief
+// wordt bijgewerkt zodra een item definitief is verzonden of in de dead-letter belandt
+func (q *EmailQueue) SetNewsletterDeliveryRepository(repo repository.NewsletterDeliveryRepository) {
+	q.deliveryRepo = repo
+}
+
+// Enqueue plaatst een bericht in de outbox. verzondEmailID en newsletterDeliveryID koppelen
+// het item optioneel aan een regel in verzonden_emails en aan de aflevering van een nieuwsbrief,
+// waarvan de status na de definitieve verzending wordt bijgewerkt.
+func (q *EmailQueue) Enqueue(ctx context.Context, channel, fromAddress string, msg *EmailMessage, emailType string, verzondEmailID, newsletterDeliveryID *string) error {
+	if msg.To == "" {
+		return fmt.Errorf("invalid recipient")
+	}
+	if channel == "" {
+		channel = models.EmailChannelDefault
+	}
+
+	item := &models.EmailQueueItem{
+		Channel:        channel,
+		FromAddress:    fromAddress,
+		Recipient:      msg.To,
+		Subject:        msg.Subject,
+		Body:           msg.Body,
+		EmailType:      emailType,
+		TestMode:       msg.TestMode,
+		Status:         models.EmailQueueStatusPending,
+		MaxAttempts:    q.maxAttempts,
+		NextAttemptAt:  time.Now(),
+		VerzondEmailID: verzondEmailID,
+		Headers:        models.EmailHeaders(msg.Headers),
+
+		NewsletterDeliveryID: newsletterDeliveryID,
+	}
+
+	if err := q.repo.Enqueue(ctx, item); err != nil {
+		logger.Error("Kon email niet in de wachtrij plaatsen", "recipient", msg.To, "type", emailType, "error", err)
+		return fmt.Errorf("kon email niet in de wachtrij plaatsen: %w", err)
+	}
+
+	logger.Debug("Email in wachtrij geplaatst", "id", item.ID, "recipient", msg.To, "type", emailType, "channel", channel)
+	return nil
+}
+
+// Start start de workers van de queue
+func (q *EmailQueue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running {
+		return
+	}
+
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	q.running = true
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.workerLoop(i)
+	}
+
+	q.wg.Add(1)
+	go q.janitorLoop()
+
+	logger.Info("Email queue gestart", "workers", q.workers, "poll_interval", q.pollInterval.String())
+}
+
+// Stop stopt de workers en wacht tot lopende verzendingen klaar zijn
+func (q *EmailQueue) Stop() {
+	q.mu.Lock()
+	if !q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = false
+	q.cancel()
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	logger.Info("Email queue gestopt")
+}
+
+// IsRunning geeft aan of de workers actief zijn
+func (q *EmailQueue) IsRunning() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running
+}
+
+// workerLoop claimt en verwerkt periodiek een batch jobs
+func (q *EmailQueue) workerLoop(workerID int) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Blijf verwerken zolang er volle batches zijn
+		for q.ProcessBatch(q.ctx) >= q.batchSize {
+			if q.ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// janitorLoop geeft jobs vrij van workers die zijn gecrasht tijdens de verwerking
+func (q *EmailQueue) janitorLoop() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+			released, dead, err := q.repo.ReleaseStale(q.ctx, q.staleAfter)
+			if err != nil {
+				logger.Error("Kon vastgelopen email jobs niet vrijgeven", "error", err)
+				continue
+			}
+			if released > 0 {
+				logger.Warn("Vastgelopen email jobs vrijgegeven", "count", released)
+			}
+			for _, item := range dead {
+				q.deadLetter(item, item.LastError)
+			}
+		}
+	}
+}
+
+// ProcessBatch claimt één batch en verwerkt deze; geeft het aantal geclaimde jobs terug
+func (q *EmailQueue) ProcessBatch(ctx context.Context) int {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	items, err := q.repo.ClaimBatch(ctx, q.batchSize)
+	if err != nil {
+		logger.Error("Kon email jobs niet claimen", "error", err)
+		return 0
+	}
+
+	for _, item := range items {
+		q.processItem(ctx, item)
+	}
+
+	return len(items)
+}
+
+// processItem verzendt één job en werkt de status bij
+func (q *EmailQueue) processItem(ctx context.Context, item *models.EmailQueueItem) {
+	start := time.Now()
+	msg := &EmailMessage{
+		To:       item.Recipient,
+		Subject:  item.Subject,
+		Body:     item.Body,
+		TestMode: item.TestMode,
+		Headers:  item.Headers,
+	}
+
+	sendErr := deliverMessage(q.smtpClient, item.Channel, item.FromAddress, msg)
+	if q.prometheusMetrics != nil {
+		q.prometheusMetrics.ObserveEmailLatency("email_queue", time.Since(start).Seconds())
+	}
+
+	// Gebruik een losse context zodat de status ook tijdens shutdown wordt opgeslagen
+	updateCtx := context.Background()
+
+	if sendErr == nil {
+		if err := q.repo.MarkSent(updateCtx, item.ID); err != nil {
+			logger.Error("Kon email job niet als verzonden markeren", "id", item.ID, "error", err)
+		}
+		q.updateSentEmail(item, models.VerzondEmailStatusVerzonden, "")
+		q.updateNewsletterDelivery(item, "")
+		if q.prometheusMetrics != nil {
+			q.prometheusMetrics.RecordEmailSent("email_queue", item.EmailType)
+		}
+		logger.Debug("Email job verzonden", "id", item.ID, "recipient", item.Recipient, "attempt", item.Attempts)
+		return
+	}
+
+	dead := item.Attempts >= item.MaxAttempts
+	nextAttempt := time.Now().Add(EmailQueueBackoff(item.Attempts, q.baseBackoff, q.maxBackoff))
+
+	if err := q.repo.MarkFailed(updateCtx, item.ID, sendErr.Error(), nextAttempt, dead); err != nil {
+		logger.Error("Kon mislukte email job niet bijwerken", "id", item.ID, "error", err)
+	}
+
+	if dead {
+		q.deadLetter(item, sendErr.Error())
+		return
+	}
+
+	q.updateSentEmail(item, models.VerzondEmailStatusInWachtrij, sendErr.Error())
+	if q.prometheusMetrics != nil {
+		q.prometheusMetrics.RecordEmailFailed("email_queue", "retry")
+	}
+	logger.Warn("Email job mislukt, nieuwe poging gepland",
+		"id", item.ID,
+		"recipient", item.Recipient,
+		"attempt", item.Attempts,
+		"next_attempt_at", nextAttempt,
+		"error", sendErr)
+}
+
+// deadLetter verwerkt een job die definitief is mislukt
+func (q *EmailQueue) deadLetter(item *models.EmailQueueItem, foutBericht string) {
+	q.updateSentEmail(item, models.VerzondEmailStatusMislukt, foutBericht)
+	q.updateNewsletterDelivery(item, foutBericht)
+	if q.prometheusMetrics != nil {
+		q.prometheusMetrics.RecordEmailFailed("email_queue", "dead_letter")
+	}
+	logger.Error("Email job definitief mislukt, verplaatst naar dead-letter",
+		"id", item.ID,
+		"recipient", item.Recipient,
+		"attempts", item.Attempts,
+		"error", foutBericht)
+}
+
+// updateSentEmail werkt de gekoppelde regel in verzonden_emails bij
+func (q *EmailQueue) updateSentEmail(item *models.EmailQueueItem, status, foutBericht string) {
+	if q.sentEmailRepo == nil || item.VerzondEmailID == nil {
+		return
+	}
+
+	if err := q.sentEmailRepo.UpdateStatus(context.Background(), *item.VerzondEmailID, status, foutBericht); err != nil {
+		logger.Error("Kon status van verzonden email niet bijwerken", "error", err, "id", *item.VerzondEmailID)
+	}
+}
+
+// updateNewsletterDelivery werkt de gekoppelde aflevering van een nieuwsbrief bij: verzonden
+// zonder foutBericht, anders mislukt
+func (q *EmailQueue) updateNewsletterDelivery(item *models.EmailQueueItem, foutBericht string) {
+	if q.deliveryRepo == nil || item.NewsletterDeliveryID == nil {
+		return
+	}
+
+	status := models.NewsletterDeliverySent
+	var err error
+	if foutBericht == "" {
+		err = q.deliveryRepo.MarkSent(context.Background(), *item.NewsletterDeliveryID, time.Now())
+	} else {
+		status = models.NewsletterDeliveryFailed
+		err = q.deliveryRepo.MarkFailed(context.Background(), *item.NewsletterDeliveryID, foutBericht)
+	}
+	if err != nil {
+		logger.Error("Kon aflevering van nieuwsbrief niet bijwerken", "error", err, "delivery_id", *item.NewsletterDeliveryID, "status", status)
+	}
+
+	if metrics, ok := q.prometheusMetrics.(interface{ RecordNewsletterDelivery(string) }); ok {
+		metrics.RecordNewsletterDelivery(status)
+	}
+}
+
+// Stats geeft het aantal jobs per status terug
+func (q *EmailQueue) Stats(ctx context.Context) (map[string]int64, error) {
+	return q.repo.CountByStatus(ctx)
+}
+
+// deliverMessage verzendt een bericht via de SMTP configuratie die bij het kanaal hoort
+func deliverMessage(client SMTPClient, channel, fromAddress string, msg *EmailMessage) error {
+	switch channel {
+	case models.EmailChannelRegistration:
+		return client.SendRegistration(msg)
+	case models.EmailChannelWFC:
+		return client.SendWFC(msg)
+	default:
+		if fromAddress != "" {
+			return client.SendWithFrom(fromAddress, msg)
+		}
+		return client.Send(msg)
+	}
+}
@@ -2,19 +2,26 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	texttemplate "text/template"
 	"time"
 )
 
 var RetryDelayFactor = 100 // milliseconden
 
+// ErrRecipientSuppressed wordt teruggegeven als de ontvanger op de suppressielijst staat
+var ErrRecipientSuppressed = errors.New("adres staat op de suppressielijst")
+
 // EmailService is verantwoordelijk voor het versturen van emails
 type EmailService struct {
 	smtpClient        SMTPClient
@@ -23,15 +30,53 @@ type EmailService struct {
 	metrics           *EmailMetrics
 	prometheusMetrics PrometheusMetricsInterface
 	excludedEmails    []string
+	queue             *EmailQueue
+	sentEmailRepo     repository.VerzondEmailRepository
+	templateRepo      repository.EmailTemplateRepository
+	suppressionRepo   repository.EmailSuppressionRepository
+	dbTemplates       map[string]*dbTemplateEntry
+	templateCacheTTL  time.Duration
 	mu                sync.RWMutex
 }
 
+// dbTemplateEntry is een gecachte opzoeking van een template in de database.
+// Een entry zonder template betekent dat er geen actief database template is.
+type dbTemplateEntry struct {
+	tmpl      *template.Template
+	onderwerp *texttemplate.Template
+	loadedAt  time.Time
+}
+
+// EmailMetadata bevat de gegevens die bij het vastleggen van een verzonden email worden bewaard
+type EmailMetadata struct {
+	Type         string  // Email type voor metrics en de verzonden_emails administratie
+	Template     string  // Naam van het gebruikte template, leeg bij vrije tekst
+	ContactID    *string // Optionele koppeling met een contactformulier
+	AanmeldingID *string // Optionele koppeling met een aanmelding
+
+	// IncomingEmailID en ThreadID koppelen een antwoord of doorgestuurde email aan het gesprek
+	IncomingEmailID *string
+	ThreadID        string
+
+	// NewsletterDeliveryID koppelt een nieuwsbrief aan de aflevering die de outbox na de
+	// definitieve verzending of in de dead-letter bijwerkt
+	NewsletterDeliveryID *string
+
+	// Headers zijn extra headers voor het bericht, bijv. List-Unsubscribe bij nieuwsbrieven
+	Headers map[string]string
+
+	// KeepSubject houdt het opgegeven onderwerp aan, ook als het database template een eigen
+	// onderwerp heeft; voor onderwerpen van de gebruiker, zoals bij nieuwsbrieven
+	KeepSubject bool
+}
+
 // EmailMessage representeert een te verzenden email
 type EmailMessage struct {
 	To       string
 	Subject  string
 	Body     string
 	TestMode bool
+	Headers  map[string]string // Extra headers, bijv. In-Reply-To en References
 }
 
 // NewEmailService maakt een nieuwe EmailService met de opgegeven SMTP client
@@ -43,29 +88,7 @@ func NewEmailService(smtpClient SMTPClient, metrics *EmailMetrics, rateLimiter R
 
 // NewEmailServiceWithTemplatesDir maakt een nieuwe EmailService met de opgegeven SMTP client en templates directory
 func NewEmailServiceWithTemplatesDir(smtpClient SMTPClient, metrics *EmailMetrics, rateLimiter RateLimiterInterface, prometheusMetrics PrometheusMetricsInterface, templatesDir string) *EmailService {
-	// Definieer template functies
-	templateFuncs := template.FuncMap{
-		"multiply": func(a, b interface{}) float64 {
-			// Convert interface values to float64
-			var floatA, floatB float64
-			switch v := a.(type) {
-			case int:
-				floatA = float64(v)
-			case float64:
-				floatA = v
-			}
-			switch v := b.(type) {
-			case int:
-				floatB = float64(v)
-			case float64:
-				floatB = v
-			}
-			return floatA * floatB
-		},
-		"currentYear": func() int {
-			return time.Now().Year()
-		},
-	}
+	templateFuncs := emailTemplateFuncs()
 
 	// Laad alle templates bij initialisatie
 	templates := make(map[string]*template.Template)
@@ -77,6 +100,11 @@ func NewEmailServiceWithTemplatesDir(smtpClient SMTPClient, metrics *EmailMetric
 		"wfc_order_confirmation",
 		"wfc_order_admin",
 		"newsletter",
+		"newsletter_confirm",
+		"notification_digest",
+		"steps_milestone",
+		"pledge_confirmation",
+		"pledge_finished",
 	}
 
 	for _, name := range templateFiles {
@@ -115,6 +143,33 @@ func NewEmailServiceWithTemplatesDir(smtpClient SMTPClient, metrics *EmailMetric
 	}
 }
 
+// emailTemplateFuncs geeft de functies terug die in alle email templates beschikbaar zijn,
+// zowel voor templates uit bestanden als voor templates uit de database
+func emailTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"multiply": func(a, b interface{}) float64 {
+			// Convert interface values to float64
+			var floatA, floatB float64
+			switch v := a.(type) {
+			case int:
+				floatA = float64(v)
+			case float64:
+				floatA = v
+			}
+			switch v := b.(type) {
+			case int:
+				floatB = float64(v)
+			case float64:
+				floatB = v
+			}
+			return floatA * floatB
+		},
+		"currentYear": func() int {
+			return time.Now().Year()
+		},
+	}
+}
+
 // isExcludedEmail controleert of een email adres is uitgesloten van test emails
 func (s *EmailService) isExcludedEmail(email string) bool {
 	email = strings.TrimSpace(strings.ToLower(email))
@@ -143,14 +198,14 @@ func (s *EmailService) SendContactEmail(data *models.ContactEmailData) error {
 			"naam", data.Contact.Naam,
 			"email", data.Contact.Email,
 			"test_mode", data.Contact.TestMode)
-		return s.sendEmailWithTemplate("contact_admin_email", data.AdminEmail, "Nieuw contactformulier", data)
+		return s.sendEmailWithTemplate("contact_admin_email", data.AdminEmail, "Nieuw contactformulier", data, contactMetadata("contact_admin_email", data.Contact))
 	}
 
 	logger.Debug("Contact bevestigingsemail wordt voorbereid",
 		"naam", data.Contact.Naam,
 		"email", data.Contact.Email,
 		"test_mode", data.Contact.TestMode)
-	return s.sendEmailWithTemplate("contact_email", data.Contact.Email, "Bedankt voor je bericht", data)
+	return s.sendEmailWithTemplate("contact_email", data.Contact.Email, "Bedankt voor je bericht", data, contactMetadata("contact_email", data.Contact))
 }
 
 func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) error {
@@ -189,14 +244,11 @@ func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) err
 	if err := template.Execute(&body, data); err != nil {
 		return fmt.Errorf("failed to execute template: %v", err)
 	}
+	subject = s.templateSubject(templateName, data, subject)
 
-	// Controleer rate limits voordat we een poging wagen
-	if !s.rateLimiter.AllowEmail("email_generic", "") {
-		if s.metrics != nil {
-			s.metrics.RecordEmailFailed("aanmelding_email")
-		}
-		s.prometheusMetrics.RecordEmailFailed("aanmelding_email", "rate_limited")
-		return fmt.Errorf("rate limit exceeded")
+	meta := EmailMetadata{Type: "aanmelding_email", Template: templateName}
+	if data.AanmeldingID != "" {
+		meta.AanmeldingID = &data.AanmeldingID
 	}
 
 	// Bereid bericht voor
@@ -207,12 +259,23 @@ func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) err
 		TestMode: data.Aanmelding.TestMode,
 	}
 
+	// Controleer rate limits voordat we een poging wagen
+	if !s.rateLimiter.AllowEmail("email_generic", "") {
+		if s.metrics != nil {
+			s.metrics.RecordEmailFailed("aanmelding_email")
+		}
+		s.prometheusMetrics.RecordEmailFailed("aanmelding_email", "rate_limited")
+		err := fmt.Errorf("rate limit exceeded")
+		s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return err
+	}
+
 	// Verzend met de juiste client op basis van type
 	var err error
 	if data.ToAdmin {
-		err = s.smtpClient.Send(msg) // Gebruik standaard SMTP voor admin emails
+		err = s.dispatch(models.EmailChannelDefault, "", msg, meta) // Gebruik standaard SMTP voor admin emails
 	} else {
-		err = s.smtpClient.SendRegistration(msg) // Gebruik registratie SMTP voor gebruiker emails
+		err = s.dispatch(models.EmailChannelRegistration, "", msg, meta) // Gebruik registratie SMTP voor gebruiker emails
 	}
 
 	elapsedTime := time.Since(start)
@@ -236,18 +299,26 @@ func (s *EmailService) SendAanmeldingEmail(data *models.AanmeldingEmailData) err
 	return nil
 }
 
-func (s *EmailService) sendEmail(to, subject, body string) error {
-	if !s.rateLimiter.AllowEmail("email_generic", "") {
-		return fmt.Errorf("rate limit exceeded")
-	}
-
+func (s *EmailService) sendEmail(to, subject, body string, meta EmailMetadata) error {
 	msg := &EmailMessage{
 		To:      to,
 		Subject: subject,
 		Body:    body,
 	}
+	if len(meta.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(meta.Headers))
+		for k, v := range meta.Headers {
+			msg.Headers[k] = v
+		}
+	}
 
-	err := s.smtpClient.Send(msg)
+	if !s.rateLimiter.AllowEmail("email_generic", "") {
+		err := fmt.Errorf("rate limit exceeded")
+		s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return err
+	}
+
+	err := s.dispatch(models.EmailChannelDefault, "", msg, meta)
 	if err != nil {
 		if s.metrics != nil {
 			s.metrics.RecordEmailFailed("email_generic")
@@ -262,8 +333,13 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 	return nil
 }
 
-// GetTemplate geeft een template terug op basis van de naam
+// GetTemplate geeft een template terug op basis van de naam. Een actief template in de
+// database heeft voorrang; anders wordt het template uit de templates directory gebruikt.
 func (s *EmailService) GetTemplate(name string) *template.Template {
+	if tmpl := s.getDatabaseTemplate(name); tmpl != nil {
+		return tmpl
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -274,6 +350,127 @@ func (s *EmailService) GetTemplate(name string) *template.Template {
 	return tmpl
 }
 
+// SetTemplateRepository koppelt de database templates aan de service. Opzoekingen worden
+// maximaal cacheTTL bewaard, zodat wijzigingen op andere instanties vanzelf worden opgepakt.
+func (s *EmailService) SetTemplateRepository(repo repository.EmailTemplateRepository, cacheTTL time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templateRepo = repo
+	s.templateCacheTTL = cacheTTL
+	s.dbTemplates = make(map[string]*dbTemplateEntry)
+}
+
+// ReloadTemplate verwijdert een template uit de cache zodat de volgende verzending
+// de actuele versie uit de database gebruikt. Een lege naam leegt de hele cache.
+func (s *EmailService) ReloadTemplate(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == "" {
+		s.dbTemplates = make(map[string]*dbTemplateEntry)
+		return
+	}
+	delete(s.dbTemplates, name)
+}
+
+// HasFileTemplate geeft aan of er een template met deze naam uit de templates directory is geladen
+func (s *EmailService) HasFileTemplate(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.templates[name]
+	return exists
+}
+
+// ParseTemplate parset template inhoud met dezelfde functies als de template bestanden
+func ParseTemplate(name, inhoud string) (*template.Template, error) {
+	return template.New(name).Funcs(emailTemplateFuncs()).Parse(inhoud)
+}
+
+// getDatabaseTemplate zoekt een actief template op in de database, met caching
+func (s *EmailService) getDatabaseTemplate(name string) *template.Template {
+	if entry := s.getDatabaseEntry(name); entry != nil {
+		return entry.tmpl
+	}
+	return nil
+}
+
+// getDatabaseEntry zoekt een template met onderwerp op in de database, met caching. Geeft nil
+// terug als er geen database templates zijn of de database niet bereikbaar is.
+func (s *EmailService) getDatabaseEntry(name string) *dbTemplateEntry {
+	s.mu.RLock()
+	repo := s.templateRepo
+	entry, cached := s.dbTemplates[name]
+	ttl := s.templateCacheTTL
+	s.mu.RUnlock()
+
+	if repo == nil {
+		return nil
+	}
+	if cached && time.Since(entry.loadedAt) < ttl {
+		return entry
+	}
+
+	record, err := repo.GetByNaam(context.Background(), name)
+	if err != nil {
+		// Bij een databasefout vallen we terug op de laatst bekende versie of het bestand
+		logger.Error("Kon email template niet uit database laden", "template", name, "error", err)
+		if cached {
+			return entry
+		}
+		return nil
+	}
+
+	entry = &dbTemplateEntry{loadedAt: time.Now()}
+	if record != nil && record.IsActief {
+		entry.tmpl, err = ParseTemplate(name, record.Inhoud)
+		if err != nil {
+			logger.Error("Database template kon niet worden geparsed, bestand wordt gebruikt", "template", name, "versie", record.Versie, "error", err)
+			entry.tmpl = nil
+		}
+		if entry.tmpl != nil && strings.TrimSpace(record.Onderwerp) != "" {
+			entry.onderwerp, err = ParseSubject(name, record.Onderwerp)
+			if err != nil {
+				logger.Error("Onderwerp van database template kon niet worden geparsed, standaard onderwerp wordt gebruikt", "template", name, "versie", record.Versie, "error", err)
+				entry.onderwerp = nil
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if s.dbTemplates != nil {
+		s.dbTemplates[name] = entry
+	}
+	s.mu.Unlock()
+
+	return entry
+}
+
+// ParseSubject parset het onderwerp van een template; het onderwerp is platte tekst en kan
+// dezelfde velden en functies gebruiken als de inhoud
+func ParseSubject(name, onderwerp string) (*texttemplate.Template, error) {
+	return texttemplate.New(name + "_onderwerp").Funcs(texttemplate.FuncMap(emailTemplateFuncs())).Parse(onderwerp)
+}
+
+// templateSubject geeft het onderwerp van het actieve database template terug, gerenderd met
+// de data. Zonder actief database template met onderwerp, of als het renderen mislukt, blijft
+// het standaard onderwerp van de verzending staan.
+func (s *EmailService) templateSubject(name string, data interface{}, subject string) string {
+	entry := s.getDatabaseEntry(name)
+	if entry == nil || entry.onderwerp == nil {
+		return subject
+	}
+
+	var buf bytes.Buffer
+	if err := entry.onderwerp.Execute(&buf, data); err != nil {
+		logger.Error("Onderwerp van database template kon niet worden gerenderd, standaard onderwerp wordt gebruikt", "template", name, "error", err)
+		return subject
+	}
+	if rendered := strings.Join(strings.Fields(buf.String()), " "); rendered != "" {
+		return rendered
+	}
+	return subject
+}
+
 // ValidateTemplate valideert of een template correct kan worden uitgevoerd met de gegeven data
 func ValidateTemplate(tmpl *template.Template, data interface{}) error {
 	if tmpl == nil {
@@ -291,14 +488,37 @@ func ValidateTemplate(tmpl *template.Template, data interface{}) error {
 
 // SendEmail stuurt een email met optioneel 'From' adres
 func (s *EmailService) SendEmail(to, subject, body string, fromAddress ...string) error {
+	return s.SendEmailWithMetadata(to, subject, body, EmailMetadata{Type: "email_generic"}, fromAddress...)
+}
+
+// SendEmailWithMetadata stuurt een email met optioneel 'From' adres en legt de verzending
+// vast met de opgegeven koppelingen (contactformulier, aanmelding, template)
+func (s *EmailService) SendEmailWithMetadata(to, subject, body string, meta EmailMetadata, fromAddress ...string) error {
+	_, err := s.sendEmailWithMetadata(to, subject, body, meta, fromAddress...)
+	return err
+}
+
+// sendEmailWithMetadata doet hetzelfde als SendEmailWithMetadata en geeft ook terug of de email
+// alleen in de outbox is geplaatst en dus nog niet via SMTP is verzonden
+func (s *EmailService) sendEmailWithMetadata(to, subject, body string, meta EmailMetadata, fromAddress ...string) (bool, error) {
 	start := time.Now()
 	defer func() {
-		duration := time.Since(start)
-		s.prometheusMetrics.ObserveEmailLatency("email_generic", duration.Seconds())
+		if s.prometheusMetrics != nil {
+			duration := time.Since(start)
+			s.prometheusMetrics.ObserveEmailLatency("email_generic", duration.Seconds())
+		}
 	}()
 
+	msg := &EmailMessage{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	}
+
 	if !s.rateLimiter.AllowEmail("email_generic", "") {
-		return fmt.Errorf("rate limit exceeded")
+		err := fmt.Errorf("rate limit exceeded")
+		s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return false, err
 	}
 
 	// Bepaal het uiteindelijke 'From' adres
@@ -307,39 +527,27 @@ func (s *EmailService) SendEmail(to, subject, body string, fromAddress ...string
 		finalFromAddress = fromAddress[0] // Gebruik de override indien meegegeven en niet leeg
 	}
 
-	msg := &EmailMessage{
-		To:      to,
-		Subject: subject,
-		Body:    body,
-	}
-
-	var err error
-	// Roep de juiste methode van de SMTP client aan
-	if finalFromAddress == "" {
-		// Geen specifiek 'From' adres opgegeven, gebruik de standaard Send
-		// die waarschijnlijk de default 'From' van de client configuratie pakt.
-		err = s.smtpClient.Send(msg)
-	} else {
-		// Specifiek 'From' adres opgegeven.
-		// We gaan ervan uit dat de SMTPClient interface een methode SendWithFrom heeft.
-		// Deze moet nog geimplementeerd worden in de interface en concrete client!
-		// Interface aanpassing nodig: SendWithFrom(from string, msg *EmailMessage) error
-		err = s.smtpClient.SendWithFrom(finalFromAddress, msg)
-	}
+	// Zonder 'From' adres gebruikt de client de standaard afzender (SMTP_FROM),
+	// anders wordt SendWithFrom gebruikt met het opgegeven adres.
+	_, queued, err := s.dispatchRecorded(models.EmailChannelDefault, finalFromAddress, msg, meta)
 
 	if err != nil {
 		if s.metrics != nil {
 			s.metrics.RecordEmailFailed("email_generic")
 		}
-		s.prometheusMetrics.RecordEmailFailed("email", "smtp_error")
-		return err
+		if s.prometheusMetrics != nil {
+			s.prometheusMetrics.RecordEmailFailed("email", "smtp_error")
+		}
+		return false, err
 	}
 
 	if s.metrics != nil {
 		s.metrics.RecordEmailSent("email_generic")
 	}
-	s.prometheusMetrics.RecordEmailSent("email", "success")
-	return nil
+	if s.prometheusMetrics != nil {
+		s.prometheusMetrics.RecordEmailSent("email", "success")
+	}
+	return queued, nil
 }
 
 func NewTestEmailService(smtpClient SMTPClient) (*EmailService, error) {
@@ -371,8 +579,8 @@ func NewTestEmailService(smtpClient SMTPClient) (*EmailService, error) {
 	}, nil
 }
 
-func (s *EmailService) sendEmailWithTemplate(templateName, to, subject string, data interface{}) error {
-	template := s.templates[templateName]
+func (s *EmailService) sendEmailWithTemplate(templateName, to, subject string, data interface{}, meta EmailMetadata) error {
+	template := s.GetTemplate(templateName)
 	if template == nil {
 		logger.Error("Template not found", "template", templateName)
 		return fmt.Errorf("template not found: %s", templateName)
@@ -385,34 +593,235 @@ func (s *EmailService) sendEmailWithTemplate(templateName, to, subject string, d
 	}
 
 	logger.Debug("Successfully generated email body for template", "template", templateName)
-	return s.sendEmail(to, subject, body.String())
+	return s.sendEmail(to, s.templateSubject(templateName, data, subject), body.String(), meta)
 }
 
 // SendTemplateEmail verzendt een email met template en optioneel 'From' adres
 func (s *EmailService) SendTemplateEmail(recipient, subject, templateName string, templateData map[string]interface{}, fromAddress ...string) error {
-	template := s.templates[templateName]
+	return s.SendTemplateEmailWithHeaders(recipient, subject, templateName, templateData, nil, fromAddress...)
+}
+
+// SendTemplateEmailWithHeaders verzendt een email met template en extra headers, zoals
+// de List-Unsubscribe headers van een nieuwsbrief
+func (s *EmailService) SendTemplateEmailWithHeaders(recipient, subject, templateName string, templateData map[string]interface{}, headers map[string]string, fromAddress ...string) error {
+	_, err := s.sendTemplateEmail(recipient, subject, templateName, templateData, EmailMetadata{Headers: headers}, fromAddress...)
+	return err
+}
+
+// SendTemplateEmailWithMetadata verzendt een email met template en de koppelingen uit meta. Met
+// meta.KeepSubject wint het opgegeven onderwerp van het onderwerp van het database template.
+func (s *EmailService) SendTemplateEmailWithMetadata(recipient, subject, templateName string, templateData map[string]interface{}, meta EmailMetadata, fromAddress ...string) error {
+	_, err := s.sendTemplateEmail(recipient, subject, templateName, templateData, meta, fromAddress...)
+	return err
+}
+
+// sendTemplateEmail verzendt een email met template en de koppelingen uit meta. Type en template
+// komen van templateName. Geeft ook terug of de email alleen in de outbox is geplaatst.
+func (s *EmailService) sendTemplateEmail(recipient, subject, templateName string, templateData map[string]interface{}, meta EmailMetadata, fromAddress ...string) (bool, error) {
+	template := s.GetTemplate(templateName)
 	if template == nil {
 		logger.Error("Template not found", "template", templateName)
-		return fmt.Errorf("template not found: %s", templateName)
+		return false, fmt.Errorf("template not found: %s", templateName)
 	}
 
 	var body bytes.Buffer
 	if err := template.Execute(&body, templateData); err != nil {
 		logger.Error("Template rendering fout", "error", err, "template", templateName)
-		return err
+		return false, err
+	}
+
+	if !meta.KeepSubject {
+		subject = s.templateSubject(templateName, templateData, subject)
 	}
 
 	// Email verzenden via SendEmail (die nu het optionele 'from' adres accepteert en doorgeeft)
-	err := s.SendEmail(recipient, subject, body.String(), fromAddress...)
+	meta.Type = templateName
+	meta.Template = templateName
+	queued, err := s.sendEmailWithMetadata(recipient, subject, body.String(), meta, fromAddress...)
 
 	if err != nil {
 		s.metrics.RecordEmailFailed(templateName)
-		return err // SendEmail logt al de prometheus metrics
+		return false, err // SendEmail logt al de prometheus metrics
 	}
 
 	s.metrics.RecordEmailSent(templateName)
 	// Prometheus metrics worden al gelogd door de aangeroepen SendEmail
-	return nil
+	return queued, nil
+}
+
+// SetQueue koppelt een persistente outbox aan de service. Zolang de queue actief is
+// worden alle uitgaande emails via de outbox verzonden in plaats van direct via SMTP.
+func (s *EmailService) SetQueue(queue *EmailQueue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = queue
+}
+
+// SetSentEmailRepository stelt de repository in waarin elke verzending wordt vastgelegd
+func (s *EmailService) SetSentEmailRepository(repo repository.VerzondEmailRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sentEmailRepo = repo
+}
+
+// SetSuppressionRepository stelt de suppressielijst in. Adressen met een actieve suppressie
+// (na een harde bounce of herhaalde zachte bounces) krijgen geen email meer.
+func (s *EmailService) SetSuppressionRepository(repo repository.EmailSuppressionRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suppressionRepo = repo
+}
+
+// isSuppressed controleert of een ontvanger op de suppressielijst staat. Als de lijst niet
+// beschikbaar is wordt de email gewoon verzonden.
+func (s *EmailService) isSuppressed(to string) bool {
+	s.mu.RLock()
+	repo := s.suppressionRepo
+	s.mu.RUnlock()
+
+	if repo == nil {
+		return false
+	}
+
+	suppressed, err := repo.FindSuppressed(context.Background(), []string{to})
+	if err != nil {
+		logger.Error("Kon suppressielijst niet controleren", "error", err, "ontvanger", to)
+		return false
+	}
+	return len(suppressed) > 0
+}
+
+// queueRunning geeft aan of uitgaande emails via een actieve outbox gaan
+func (s *EmailService) queueRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queue != nil && s.queue.IsRunning()
+}
+
+// SendMessage verstuurt een kant-en-klaar bericht via het kanaal, langs de suppressielijst en
+// de outbox, en geeft de regel in verzonden_emails terug. Bedoeld voor berichten zonder
+// template, zoals antwoorden vanuit het dashboard; er geldt geen rate limit.
+func (s *EmailService) SendMessage(channel string, msg *EmailMessage, meta EmailMetadata) (*models.VerzondEmail, error) {
+	record, _, err := s.dispatchRecorded(channel, "", msg, meta)
+	return record, err
+}
+
+// dispatch plaatst een bericht in de outbox of verzendt het direct als er geen actieve queue is.
+// In beide gevallen wordt de verzending vastgelegd in verzonden_emails.
+func (s *EmailService) dispatch(channel, fromAddress string, msg *EmailMessage, meta EmailMetadata) error {
+	_, _, err := s.dispatchRecorded(channel, fromAddress, msg, meta)
+	return err
+}
+
+// dispatchRecorded doet hetzelfde als dispatch en geeft ook de vastgelegde verzending terug,
+// en of het bericht in de outbox is geplaatst in plaats van direct verzonden
+func (s *EmailService) dispatchRecorded(channel, fromAddress string, msg *EmailMessage, meta EmailMetadata) (*models.VerzondEmail, bool, error) {
+	// Een eigen Message-ID maakt het mogelijk antwoorden op deze email later te herkennen
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	if msg.Headers["Message-ID"] == "" {
+		msg.Headers["Message-ID"] = newMessageID(fromAddress)
+	}
+
+	if s.isSuppressed(msg.To) {
+		logger.Warn("Email niet verzonden, adres staat op de suppressielijst", "ontvanger", msg.To, "type", meta.Type)
+		record := s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, ErrRecipientSuppressed)
+		return record, false, ErrRecipientSuppressed
+	}
+
+	s.mu.RLock()
+	queue := s.queue
+	s.mu.RUnlock()
+
+	if queue != nil && queue.IsRunning() {
+		record := s.recordSentEmail(msg, meta, models.VerzondEmailStatusInWachtrij, nil)
+		var recordID *string
+		if record != nil {
+			recordID = &record.ID
+		}
+
+		if err := queue.Enqueue(context.Background(), channel, fromAddress, msg, meta.Type, recordID, meta.NewsletterDeliveryID); err != nil {
+			if record != nil {
+				s.updateSentEmailStatus(record.ID, models.VerzondEmailStatusMislukt, err.Error())
+				record.Status = models.VerzondEmailStatusMislukt
+				record.FoutBericht = err.Error()
+			}
+			return record, false, err
+		}
+		return record, true, nil
+	}
+
+	err := deliverMessage(s.smtpClient, channel, fromAddress, msg)
+	if err != nil {
+		record := s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return record, false, err
+	}
+
+	return s.recordSentEmail(msg, meta, models.VerzondEmailStatusVerzonden, nil), false, nil
+}
+
+// recordSentEmail legt een verzending vast in verzonden_emails. Fouten bij het opslaan
+// worden gelogd maar nooit teruggegeven, de administratie mag een verzending niet blokkeren.
+func (s *EmailService) recordSentEmail(msg *EmailMessage, meta EmailMetadata, status string, sendErr error) *models.VerzondEmail {
+	s.mu.RLock()
+	repo := s.sentEmailRepo
+	s.mu.RUnlock()
+
+	if repo == nil {
+		return nil
+	}
+
+	record := &models.VerzondEmail{
+		Ontvanger:    msg.To,
+		Onderwerp:    msg.Subject,
+		Inhoud:       msg.Body,
+		Status:       status,
+		EmailType:    meta.Type,
+		TemplateNaam: meta.Template,
+		ContactID:    meta.ContactID,
+		AanmeldingID: meta.AanmeldingID,
+		MessageID:    msg.Headers["Message-ID"],
+
+		IncomingEmailID: meta.IncomingEmailID,
+		InReplyTo:       msg.Headers["In-Reply-To"],
+		ThreadID:        meta.ThreadID,
+	}
+	if sendErr != nil {
+		record.FoutBericht = sendErr.Error()
+	}
+
+	if err := repo.Create(context.Background(), record); err != nil {
+		logger.Error("Kon verzonden email niet vastleggen", "error", err, "ontvanger", msg.To, "type", meta.Type)
+		return nil
+	}
+
+	return record
+}
+
+// updateSentEmailStatus werkt de status van een eerder vastgelegde verzending bij
+func (s *EmailService) updateSentEmailStatus(id, status, foutBericht string) {
+	s.mu.RLock()
+	repo := s.sentEmailRepo
+	s.mu.RUnlock()
+
+	if repo == nil {
+		return
+	}
+
+	if err := repo.UpdateStatus(context.Background(), id, status, foutBericht); err != nil {
+		logger.Error("Kon status van verzonden email niet bijwerken", "error", err, "id", id)
+	}
+}
+
+// contactMetadata bouwt de metadata voor een contactformulier email
+func contactMetadata(templateName string, contact *models.ContactFormulier) EmailMetadata {
+	meta := EmailMetadata{Type: "contact_email", Template: templateName}
+	if contact != nil && contact.ID != "" {
+		id := contact.ID
+		meta.ContactID = &id
+	}
+	return meta
 }
 
 // SetMetrics stelt een nieuwe metrics tracker in (voor testen)
@@ -435,6 +844,13 @@ func (s *EmailService) SendWFCEmail(to, subject, body string) error {
 		}
 	}()
 
+	msg := &EmailMessage{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	}
+	meta := EmailMetadata{Type: "wfc_email"}
+
 	if !s.rateLimiter.AllowEmail("email_generic", "") {
 		if s.metrics != nil {
 			s.metrics.RecordEmailFailed("wfc_email")
@@ -442,16 +858,12 @@ func (s *EmailService) SendWFCEmail(to, subject, body string) error {
 		if s.prometheusMetrics != nil {
 			s.prometheusMetrics.RecordEmailFailed("wfc_email", "rate_limited")
 		}
-		return fmt.Errorf("rate limit exceeded")
-	}
-
-	msg := &EmailMessage{
-		To:      to,
-		Subject: subject,
-		Body:    body,
+		err := fmt.Errorf("rate limit exceeded")
+		s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return err
 	}
 
-	err := s.smtpClient.SendWFC(msg)
+	err := s.dispatch(models.EmailChannelWFC, "", msg, meta)
 	if err != nil {
 		if s.metrics != nil {
 			s.metrics.RecordEmailFailed("wfc_email")
@@ -503,6 +915,15 @@ func (s *EmailService) SendWFCOrderEmail(data *models.WFCOrderEmailData) error {
 		return fmt.Errorf("failed to execute template: %v", err)
 	}
 
+	// Prepare message
+	msg := &EmailMessage{
+		To:       recipient,
+		Subject:  s.templateSubject(templateName, data, subject),
+		Body:     body.String(),
+		TestMode: false,
+	}
+	meta := EmailMetadata{Type: "wfc_email", Template: templateName}
+
 	// Check rate limits
 	if !s.rateLimiter.AllowEmail("wfc_email", "") {
 		if s.metrics != nil {
@@ -511,19 +932,13 @@ func (s *EmailService) SendWFCOrderEmail(data *models.WFCOrderEmailData) error {
 		if s.prometheusMetrics != nil {
 			s.prometheusMetrics.RecordEmailFailed("wfc_email", "rate_limited")
 		}
-		return fmt.Errorf("rate limit exceeded")
-	}
-
-	// Prepare message
-	msg := &EmailMessage{
-		To:       recipient,
-		Subject:  subject,
-		Body:     body.String(),
-		TestMode: false,
+		err := fmt.Errorf("rate limit exceeded")
+		s.recordSentEmail(msg, meta, models.VerzondEmailStatusMislukt, err)
+		return err
 	}
 
 	// Send via WFC SMTP
-	err := s.smtpClient.SendWFC(msg)
+	err := s.dispatch(models.EmailChannelWFC, "", msg, meta)
 
 	elapsedTime := time.Since(start)
 
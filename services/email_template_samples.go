@@ -0,0 +1,109 @@
+package services
+
+import (
+	"dklautomationgo/models"
+	"strings"
+	"time"
+)
+
+// TemplatePreviewData geeft voorbeelddata terug die past bij het template met deze naam.
+// De data wordt gebruikt voor previews en om een template te valideren voordat het
+// geactiveerd wordt; ze heeft dezelfde vorm als de data die bij een echte verzending
+// aan het template wordt meegegeven.
+func TemplatePreviewData(naam string) interface{} {
+	switch {
+	case strings.HasPrefix(naam, "aanmelding"):
+		return &models.AanmeldingEmailData{
+			ToAdmin:      naam == "aanmelding_admin_email",
+			AdminEmail:   "info@dekoninklijkeloop.nl",
+			AanmeldingID: "00000000-0000-0000-0000-000000000000",
+			Aanmelding: &models.AanmeldingFormulier{
+				Naam:           "Jan de Vries",
+				Email:          "jan@example.com",
+				Telefoon:       "0612345678",
+				Rol:            "Deelnemer",
+				Afstand:        "10 KM",
+				Ondersteuning:  "Nee",
+				Bijzonderheden: "Geen bijzonderheden",
+				Terms:          true,
+			},
+		}
+	case strings.HasPrefix(naam, "contact"):
+		return &models.ContactEmailData{
+			ToAdmin:    naam == "contact_admin_email",
+			AdminEmail: "info@dekoninklijkeloop.nl",
+			Contact: &models.ContactFormulier{
+				ID:             "00000000-0000-0000-0000-000000000000",
+				CreatedAt:      time.Now(),
+				Naam:           "Jan de Vries",
+				Email:          "jan@example.com",
+				Bericht:        "Dit is een voorbeeldbericht via het contactformulier.",
+				PrivacyAkkoord: true,
+				Status:         "nieuw",
+			},
+		}
+	case strings.HasPrefix(naam, "wfc_order"):
+		return &models.WFCOrderEmailData{
+			ToAdmin:    naam == "wfc_order_admin",
+			AdminEmail: "info@whiskyforcharity.com",
+			SiteURL:    "https://www.whiskyforcharity.com",
+			Order: &models.WFCOrder{
+				ID:              "WFC-0001",
+				CustomerName:    "Jan de Vries",
+				CustomerEmail:   "jan@example.com",
+				CustomerAddress: "Voorbeeldstraat 1",
+				CustomerCity:    "Apeldoorn",
+				CustomerPostal:  "1234 AB",
+				CustomerCountry: "Nederland",
+				TotalAmount:     89.90,
+				Status:          "pending",
+				CreatedAt:       time.Now(),
+				Items: []models.WFCOrderItem{
+					{ID: "1", ProductName: "Voorbeeld whisky", Quantity: 2, Price: 44.95},
+				},
+			},
+		}
+	case naam == "newsletter":
+		return map[string]interface{}{
+			"Summary": "Het laatste nieuws van De Koninklijke Loop.",
+			"Items": []models.NewsItem{
+				{Title: "Voorbeeld nieuwsbericht", Description: "Een korte omschrijving van het bericht.", Link: "https://www.dekoninklijkeloop.nl", PubDate: time.Now()},
+			},
+		}
+	case naam == "steps_milestone":
+		return map[string]interface{}{
+			"Naam":      "Jan de Vries",
+			"Route":     "10 KM",
+			"Milestone": FormatSteps(StepMilestones[0]),
+			"Steps":     FormatSteps(StepMilestones[0] + 1250),
+		}
+	case naam == "pledge_confirmation":
+		return map[string]interface{}{
+			"Naam":      "Marieke Jansen",
+			"Deelnemer": "Jan V.",
+			"Route":     "10 KM",
+			"Pledge":    FormatEuro(2.5) + " per kilometer",
+			"Expected":  FormatEuro(25),
+			"MaxAmount": FormatEuro(50),
+			"Fixed":     false,
+		}
+	case naam == "pledge_finished":
+		return map[string]interface{}{
+			"Naam":      "Marieke Jansen",
+			"Deelnemer": "Jan V.",
+			"Route":     "10 KM",
+			"Steps":     FormatSteps(14250),
+			"Pledge":    FormatEuro(2.5) + " per kilometer",
+			"Amount":    FormatEuro(25),
+			"Paid":      false,
+		}
+	default:
+		// Vrije templates (SendTemplateEmail) krijgen een generieke map
+		return map[string]interface{}{
+			"Naam":    "Jan de Vries",
+			"Email":   "jan@example.com",
+			"Bericht": "Dit is een voorbeeldbericht.",
+			"Datum":   time.Now().Format("02-01-2006"),
+		}
+	}
+}
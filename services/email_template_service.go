@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrTemplateNotFound wordt teruggegeven als een email template niet bestaat
+	ErrTemplateNotFound = errors.New("email template niet gevonden")
+
+	// ErrTemplateExists wordt teruggegeven als er al een template met dezelfde naam bestaat
+	ErrTemplateExists = errors.New("er bestaat al een email template met deze naam")
+
+	// ErrTemplateInvalid wordt teruggegeven als een template niet geparsed of gerenderd kan worden
+	ErrTemplateInvalid = errors.New("ongeldig email template")
+)
+
+// EmailTemplateUpdate bevat de velden die bij het wijzigen van een template aangepast kunnen worden
+type EmailTemplateUpdate struct {
+	Onderwerp    *string `json:"onderwerp"`
+	Inhoud       *string `json:"inhoud"`
+	Beschrijving *string `json:"beschrijving"`
+}
+
+// EmailTemplatePreview is het resultaat van het renderen van een template met voorbeelddata
+type EmailTemplatePreview struct {
+	Naam      string `json:"naam"`
+	Onderwerp string `json:"onderwerp"`
+	HTML      string `json:"html"`
+}
+
+// EmailTemplateService beheert de email templates in de database. Elke wijziging wordt
+// als nieuwe versie opgeslagen en direct in de EmailService herladen.
+type EmailTemplateService struct {
+	repo         repository.EmailTemplateRepository
+	emailService *EmailService
+}
+
+// NewEmailTemplateService maakt een nieuwe EmailTemplateService
+func NewEmailTemplateService(repo repository.EmailTemplateRepository, emailService *EmailService) *EmailTemplateService {
+	return &EmailTemplateService{
+		repo:         repo,
+		emailService: emailService,
+	}
+}
+
+// ValidateTemplateInhoud parset de inhoud en rendert deze met de voorbeelddata die bij de
+// template naam hoort. Een template wordt alleen geactiveerd als deze controle slaagt.
+func ValidateTemplateInhoud(naam, inhoud string) error {
+	tmpl, err := ParseTemplate(naam, inhoud)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	if err := ValidateTemplate(tmpl, TemplatePreviewData(naam)); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	return nil
+}
+
+// ValidateTemplateOnderwerp parset het onderwerp en rendert het met de voorbeelddata die bij de
+// template naam hoort. Het onderwerp van een actief template vervangt dat van de verzending.
+func ValidateTemplateOnderwerp(naam, onderwerp string) error {
+	_, err := renderOnderwerp(naam, onderwerp)
+	return err
+}
+
+// renderOnderwerp rendert het onderwerp met de voorbeelddata die bij de template naam hoort
+func renderOnderwerp(naam, onderwerp string) (string, error) {
+	tmpl, err := ParseSubject(naam, onderwerp)
+	if err != nil {
+		return "", fmt.Errorf("%w: onderwerp: %v", ErrTemplateInvalid, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Option("missingkey=error").Execute(&buf, TemplatePreviewData(naam)); err != nil {
+		return "", fmt.Errorf("%w: onderwerp: %v", ErrTemplateInvalid, err)
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// List haalt een lijst van templates op
+func (s *EmailTemplateService) List(ctx context.Context, limit, offset int) ([]*models.EmailTemplate, error) {
+	return s.repo.List(ctx, limit, offset)
+}
+
+// Get haalt een template op basis van ID op
+func (s *EmailTemplateService) Get(ctx context.Context, id string) (*models.EmailTemplate, error) {
+	tmpl, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return tmpl, nil
+}
+
+// Create slaat een nieuw template op als versie 1
+func (s *EmailTemplateService) Create(ctx context.Context, tmpl *models.EmailTemplate, userID string) error {
+	tmpl.Naam = strings.TrimSpace(tmpl.Naam)
+	if tmpl.Naam == "" || tmpl.Onderwerp == "" || tmpl.Inhoud == "" {
+		return fmt.Errorf("%w: naam, onderwerp en inhoud zijn verplicht", ErrTemplateInvalid)
+	}
+
+	existing, err := s.repo.GetByNaam(ctx, tmpl.Naam)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrTemplateExists
+	}
+
+	if err := s.checkInhoud(tmpl.Naam, tmpl.Onderwerp, tmpl.Inhoud, tmpl.IsActief); err != nil {
+		return err
+	}
+
+	tmpl.ID = ""
+	tmpl.Versie = 1
+	tmpl.CreatedBy = userID
+	tmpl.UpdatedBy = &userID
+
+	if err := s.repo.Create(ctx, tmpl); err != nil {
+		return err
+	}
+
+	s.emailService.ReloadTemplate(tmpl.Naam)
+	logger.Info("Email template aangemaakt", "naam", tmpl.Naam, "actief", tmpl.IsActief, "user_id", userID)
+	return nil
+}
+
+// Update wijzigt een template. De vorige inhoud wordt als versie bewaard.
+func (s *EmailTemplateService) Update(ctx context.Context, id string, update EmailTemplateUpdate, userID string) (*models.EmailTemplate, error) {
+	tmpl, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	vorige := *tmpl
+
+	if update.Onderwerp != nil {
+		tmpl.Onderwerp = *update.Onderwerp
+	}
+	if update.Inhoud != nil {
+		tmpl.Inhoud = *update.Inhoud
+	}
+	if update.Beschrijving != nil {
+		tmpl.Beschrijving = *update.Beschrijving
+	}
+	if tmpl.Onderwerp == "" || tmpl.Inhoud == "" {
+		return nil, fmt.Errorf("%w: onderwerp en inhoud zijn verplicht", ErrTemplateInvalid)
+	}
+
+	if err := s.checkInhoud(tmpl.Naam, tmpl.Onderwerp, tmpl.Inhoud, tmpl.IsActief); err != nil {
+		return nil, err
+	}
+
+	if err := s.saveVersie(ctx, &vorige); err != nil {
+		return nil, err
+	}
+
+	tmpl.Versie++
+	tmpl.UpdatedBy = &userID
+
+	if err := s.repo.Update(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	s.emailService.ReloadTemplate(tmpl.Naam)
+	logger.Info("Email template bijgewerkt", "naam", tmpl.Naam, "versie", tmpl.Versie, "user_id", userID)
+	return tmpl, nil
+}
+
+// SetActief activeert of deactiveert een template. Activeren lukt alleen als het
+// template met voorbeelddata gerenderd kan worden.
+func (s *EmailTemplateService) SetActief(ctx context.Context, id string, actief bool, userID string) (*models.EmailTemplate, error) {
+	tmpl, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if actief {
+		if err := ValidateTemplateInhoud(tmpl.Naam, tmpl.Inhoud); err != nil {
+			return nil, err
+		}
+		if err := ValidateTemplateOnderwerp(tmpl.Naam, tmpl.Onderwerp); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpl.IsActief = actief
+	tmpl.UpdatedBy = &userID
+
+	if err := s.repo.Update(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	s.emailService.ReloadTemplate(tmpl.Naam)
+	logger.Info("Email template status gewijzigd", "naam", tmpl.Naam, "actief", actief, "user_id", userID)
+	return tmpl, nil
+}
+
+// Delete verwijdert een template; daarna wordt het bestand in templates/ weer gebruikt
+func (s *EmailTemplateService) Delete(ctx context.Context, id string) error {
+	tmpl, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.emailService.ReloadTemplate(tmpl.Naam)
+	logger.Info("Email template verwijderd", "naam", tmpl.Naam)
+	return nil
+}
+
+// ListVersies haalt de eerdere versies van een template op
+func (s *EmailTemplateService) ListVersies(ctx context.Context, id string) ([]*models.EmailTemplateVersie, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListVersies(ctx, id)
+}
+
+// RestoreVersie zet de inhoud van een eerdere versie terug als nieuwe versie
+func (s *EmailTemplateService) RestoreVersie(ctx context.Context, id string, versie int, userID string) (*models.EmailTemplate, error) {
+	oud, err := s.repo.GetVersie(ctx, id, versie)
+	if err != nil {
+		return nil, err
+	}
+	if oud == nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	return s.Update(ctx, id, EmailTemplateUpdate{
+		Onderwerp:    &oud.Onderwerp,
+		Inhoud:       &oud.Inhoud,
+		Beschrijving: &oud.Beschrijving,
+	}, userID)
+}
+
+// Preview rendert de opgegeven inhoud met voorbeelddata voor de template naam
+func (s *EmailTemplateService) Preview(naam, onderwerp, inhoud string) (*EmailTemplatePreview, error) {
+	tmpl, err := ParseTemplate(naam, inhoud)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, TemplatePreviewData(naam)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+
+	if onderwerp != "" {
+		if onderwerp, err = renderOnderwerp(naam, onderwerp); err != nil {
+			return nil, err
+		}
+	}
+
+	return &EmailTemplatePreview{
+		Naam:      naam,
+		Onderwerp: onderwerp,
+		HTML:      body.String(),
+	}, nil
+}
+
+// checkInhoud controleert of onderwerp en inhoud parsebaar zijn en, voor actieve templates, renderen
+func (s *EmailTemplateService) checkInhoud(naam, onderwerp, inhoud string, actief bool) error {
+	if actief {
+		if err := ValidateTemplateInhoud(naam, inhoud); err != nil {
+			return err
+		}
+		return ValidateTemplateOnderwerp(naam, onderwerp)
+	}
+	if _, err := ParseTemplate(naam, inhoud); err != nil {
+		return fmt.Errorf("%w: %v", ErrTemplateInvalid, err)
+	}
+	if _, err := ParseSubject(naam, onderwerp); err != nil {
+		return fmt.Errorf("%w: onderwerp: %v", ErrTemplateInvalid, err)
+	}
+	return nil
+}
+
+// saveVersie bewaart de huidige staat van een template in de versiegeschiedenis
+func (s *EmailTemplateService) saveVersie(ctx context.Context, tmpl *models.EmailTemplate) error {
+	versie := &models.EmailTemplateVersie{
+		TemplateID:    tmpl.ID,
+		Versie:        tmpl.Versie,
+		Onderwerp:     tmpl.Onderwerp,
+		Inhoud:        tmpl.Inhoud,
+		Beschrijving:  tmpl.Beschrijving,
+		IsActief:      tmpl.IsActief,
+		GewijzigdDoor: tmpl.UpdatedBy,
+	}
+	if err := s.repo.CreateVersie(ctx, versie); err != nil {
+		return fmt.Errorf("kon template versie niet opslaan: %w", err)
+	}
+	return nil
+}
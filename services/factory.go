@@ -2,12 +2,15 @@
 package services
 
 import (
+	"context"
 	"dklautomationgo/config"
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
 	"dklautomationgo/repository"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -20,14 +23,22 @@ type ServiceFactory struct {
 	RateLimiter         RateLimiterInterface
 	EmailMetrics        *EmailMetrics
 	EmailBatcher        *EmailBatcher
+	EmailQueue          *EmailQueue
+	BounceProcessor     *BounceProcessor
+	EmailTemplate       *EmailTemplateService
 	AuthService         AuthService
 	EmailAutoFetcher    EmailAutoFetcherInterface
 	NotificationService NotificationService
+	NotificationInbox   *NotificationInbox
 	TelegramBotService  *TelegramBotService
 	ChatService         ChatService
 	Hub                 *Hub
 	NewsletterService   *NewsletterService
+	NewsletterFetcher   *NewsletterFetcher
 	NewsletterSender    *NewsletterSender
+	NewsletterWorkflow  *NewsletterWorkflow
+	Subscriptions       *NewsletterSubscriptionService
+	NewsletterTracker   *NewsletterTracker
 	PermissionService   PermissionService
 	ImageService        *ImageService
 	RedisClient         *redis.Client
@@ -72,6 +83,25 @@ func NewServiceFactory(repoFactory *repository.Repository) *ServiceFactory {
 
 	// Initialiseer email service
 	emailService := NewEmailService(smtpClient, emailMetrics, rateLimiter, prometheusMetrics)
+	emailService.SetSentEmailRepository(repoFactory.VerzondEmail)
+	emailService.SetSuppressionRepository(repoFactory.EmailSuppression)
+
+	// Bounces uit de inbox vullen de suppressielijst
+	softBounceLimit, _ := strconv.Atoi(getEnvWithDefault("SOFT_BOUNCE_LIMIT", strconv.Itoa(DefaultSoftBounceLimit)))
+	bounceProcessor := NewBounceProcessor(repoFactory.EmailSuppression, repoFactory.VerzondEmail, emailMetrics, prometheusMetrics, softBounceLimit)
+
+	// Database templates hebben voorrang op de bestanden in templates/
+	templateCacheTTL, _ := strconv.Atoi(getEnvWithDefault("EMAIL_TEMPLATE_CACHE_TTL", "60"))
+	emailService.SetTemplateRepository(repoFactory.EmailTemplate, time.Duration(templateCacheTTL)*time.Second)
+	emailTemplateService := NewEmailTemplateService(repoFactory.EmailTemplate, emailService)
+
+	// Initialiseer persistente email queue
+	emailQueue := createEmailQueue(repoFactory.EmailQueue, smtpClient, prometheusMetrics)
+	if emailQueue != nil {
+		emailQueue.SetSentEmailRepository(repoFactory.VerzondEmail)
+		emailQueue.SetNewsletterDeliveryRepository(repoFactory.NewsletterDelivery)
+		emailService.SetQueue(emailQueue)
+	}
 
 	// Initialiseer email batcher
 	emailBatcher := createEmailBatcher(emailService)
@@ -89,10 +119,12 @@ func NewServiceFactory(repoFactory *repository.Repository) *ServiceFactory {
 	)
 
 	// Initialiseer notification service
-	notificationService := createNotificationService(repoFactory.Notification)
+	// Persoonlijke inbox; systeemnotificaties komen er via het in-app kanaal in
+	notificationInbox := NewNotificationInbox(repoFactory.UserNotification, repoFactory.NotificationPreference)
+	notificationService := createNotificationService(repoFactory, emailService, notificationInbox, redisClient)
 
 	// Initialiseer telegram bot service
-	telegramBotService := createTelegramBotService(repoFactory.Contact, repoFactory.Aanmelding)
+	telegramBotService := createTelegramBotService(repoFactory)
 
 	chatService := NewChatService(repoFactory.ChatChannel, repoFactory.ChatChannelParticipant, repoFactory.ChatMessage, repoFactory.ChatMessageReaction, repoFactory.ChatUserPresence)
 
@@ -104,13 +136,61 @@ func NewServiceFactory(repoFactory *repository.Repository) *ServiceFactory {
 
 	// Newsletter components
 	fetcher := NewNewsletterFetcher()
+	fetcher.SetSourceRepository(repoFactory.NewsletterSource, repoFactory)
 	processor := NewNewsletterProcessor()
+	processor.SetSentItemRepository(repoFactory.NewsletterSentItem)
 	formatter := NewNewsletterFormatter(emailService)
 	sender := NewNewsletterSender(emailService, emailBatcher, repoFactory.Gebruiker, repoFactory.Newsletter, notificationService)
-	var newsletterSvc *NewsletterService
-	if getEnvWithDefault("ENABLE_NEWSLETTER", "false") == "true" {
-		newsletterSvc = NewNewsletterService(fetcher, processor, formatter, sender)
+	sender.SetSuppressionRepository(repoFactory.EmailSuppression)
+
+	// Ondertekende afmeldlinks en List-Unsubscribe headers in elke nieuwsbrief
+	unsubscribeSecret := getEnvWithDefault("NEWSLETTER_UNSUBSCRIBE_SECRET", os.Getenv("JWT_SECRET"))
+	if unsubscribeSecret == "" {
+		logger.Warn("NEWSLETTER_UNSUBSCRIBE_SECRET en JWT_SECRET ontbreken, afmeldlinks gebruiken een standaard geheim")
+		unsubscribeSecret = "default_unsubscribe_secret_change_in_production"
+	}
+	tokenSigner := NewTokenSigner(unsubscribeSecret)
+	publicAPIURL := getEnvWithDefault("PUBLIC_API_URL", "https://dklemailservice.onrender.com")
+	subscriptionService := NewNewsletterSubscriptionService(
+		tokenSigner,
+		publicAPIURL,
+		repoFactory.Gebruiker,
+		repoFactory.NewsletterEvent,
+	)
+	subscriptionService.EnableSignup(repoFactory.NewsletterSubscriber, emailService)
+	subscriptionService.SetSuppressionRepository(repoFactory.EmailSuppression)
+	sender.SetSubscriptionService(subscriptionService)
+
+	// Knoppen onder Telegram notificaties; koppelcodes worden met hetzelfde geheim ondertekend
+	if telegramBotService != nil {
+		telegramBotService.EnableActions(repoFactory.Gebruiker, permissionService, tokenSigner)
+		telegramBotService.SetQuickReplies(repoFactory.EmailTemplate, repoFactory.ContactAntwoord, repoFactory.AanmeldingAntwoord, emailService)
+		startTelegramBotUpdates(telegramBotService, publicAPIURL)
+	}
+	sender.SetSubscriberRepository(repoFactory.NewsletterSubscriber)
+	sender.SetSegmentRepository(repoFactory.NewsletterSegment)
+
+	// Aflevering per ontvanger; opens en kliks alleen als ze expliciet zijn ingeschakeld
+	newsletterTracker := NewNewsletterTracker(
+		repoFactory.NewsletterDelivery,
+		tokenSigner,
+		publicAPIURL,
+		getEnvWithDefault("NEWSLETTER_TRACK_OPENS", "false") == "true",
+		getEnvWithDefault("NEWSLETTER_TRACK_CLICKS", "false") == "true",
+		prometheusMetrics,
+	)
+	sender.SetTracker(newsletterTracker)
+	bounceProcessor.SetNewsletterTracker(newsletterTracker)
+
+	// De scheduler voor ingeplande nieuwsbrieven draait altijd; de RSS pipeline alleen met ENABLE_NEWSLETTER
+	newsletterSvc := NewNewsletterService(fetcher, processor, formatter, sender)
+	schedulerInterval, err := time.ParseDuration(getEnvWithDefault("NEWSLETTER_SCHEDULER_INTERVAL", "1m"))
+	if err != nil {
+		logger.Warn("Ongeldige NEWSLETTER_SCHEDULER_INTERVAL, standaard 1m wordt gebruikt", "error", err)
+		schedulerInterval = time.Minute
 	}
+	newsletterSvc.ConfigureScheduler(repoFactory.Newsletter, schedulerInterval, getEnvWithDefault("ENABLE_NEWSLETTER", "false") == "true")
+	newsletterWorkflow := NewNewsletterWorkflow(repoFactory.Newsletter, sender)
 
 	// Initialize ImageService if Cloudinary is configured
 	var imageService *ImageService
@@ -130,14 +210,22 @@ func NewServiceFactory(repoFactory *repository.Repository) *ServiceFactory {
 		RateLimiter:         rateLimiter,
 		EmailMetrics:        emailMetrics,
 		EmailBatcher:        emailBatcher,
+		EmailQueue:          emailQueue,
+		BounceProcessor:     bounceProcessor,
+		EmailTemplate:       emailTemplateService,
 		AuthService:         authService,
 		EmailAutoFetcher:    nil, // Dit wordt later in main.go ingesteld
 		NotificationService: notificationService,
+		NotificationInbox:   notificationInbox,
 		TelegramBotService:  telegramBotService,
 		ChatService:         chatService,
 		Hub:                 hub,
 		NewsletterService:   newsletterSvc,
+		NewsletterFetcher:   fetcher,
 		NewsletterSender:    sender,
+		NewsletterWorkflow:  newsletterWorkflow,
+		Subscriptions:       subscriptionService,
+		NewsletterTracker:   newsletterTracker,
 		PermissionService:   permissionService,
 		ImageService:        imageService,
 		RedisClient:         redisClient,
@@ -162,10 +250,22 @@ func createRateLimiter(prometheusMetrics *PrometheusMetrics, redisClient *redis.
 	loginLimitPeriod, _ := strconv.Atoi(getEnvWithDefault("LOGIN_LIMIT_PERIOD", "300"))
 	loginLimitPerIP := getEnvWithDefault("LOGIN_LIMIT_PER_IP", "true") == "true"
 
+	// Publieke aanmelding voor de nieuwsbrief
+	subscribeLimitCount, _ := strconv.Atoi(getEnvWithDefault("NEWSLETTER_SUBSCRIBE_LIMIT_COUNT", "5"))
+	subscribeLimitPeriod, _ := strconv.Atoi(getEnvWithDefault("NEWSLETTER_SUBSCRIBE_LIMIT_PERIOD", "3600"))
+	subscribeLimitPerIP := getEnvWithDefault("NEWSLETTER_SUBSCRIBE_LIMIT_PER_IP", "true") == "true"
+
+	// Publiek sponsorformulier per deelnemer
+	pledgeLimitCount, _ := strconv.Atoi(getEnvWithDefault("PLEDGE_LIMIT_COUNT", "10"))
+	pledgeLimitPeriod, _ := strconv.Atoi(getEnvWithDefault("PLEDGE_LIMIT_PERIOD", "3600"))
+	pledgeLimitPerIP := getEnvWithDefault("PLEDGE_LIMIT_PER_IP", "true") == "true"
+
 	// Voeg limieten toe
 	rateLimiter.AddLimit("contact", contactLimitCount, time.Duration(contactLimitPeriod)*time.Second, contactLimitPerIP)
 	rateLimiter.AddLimit("aanmelding", aanmeldingLimitCount, time.Duration(aanmeldingLimitPeriod)*time.Second, aanmeldingLimitPerIP)
 	rateLimiter.AddLimit("login", loginLimitCount, time.Duration(loginLimitPeriod)*time.Second, loginLimitPerIP)
+	rateLimiter.AddLimit("newsletter_subscribe", subscribeLimitCount, time.Duration(subscribeLimitPeriod)*time.Second, subscribeLimitPerIP)
+	rateLimiter.AddLimit("pledge", pledgeLimitCount, time.Duration(pledgeLimitPeriod)*time.Second, pledgeLimitPerIP)
 
 	return rateLimiter
 }
@@ -219,40 +319,42 @@ func createEmailBatcher(emailService *EmailService) *EmailBatcher {
 	return NewEmailBatcher(emailService, batchSize, time.Duration(batchWindow)*time.Second)
 }
 
-// createNotificationService maakt een nieuwe notification service
-func createNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
-	// Check of notificaties zijn ingeschakeld
-	enabled := getEnvWithDefault("ENABLE_NOTIFICATIONS", "false") == "true"
-	if !enabled {
-		logger.Info("Notificaties zijn uitgeschakeld")
+// createEmailQueue maakt en start de persistente email queue
+func createEmailQueue(repo repository.EmailQueueRepository, smtpClient SMTPClient, prometheusMetrics *PrometheusMetrics) *EmailQueue {
+	if getEnvWithDefault("ENABLE_EMAIL_QUEUE", "true") != "true" || repo == nil {
+		logger.Info("Email queue is uitgeschakeld, emails worden direct verzonden")
 		return nil
 	}
 
-	// Haal Telegram configuratie op
-	botToken := getEnvWithDefault("TELEGRAM_BOT_TOKEN", "")
-	chatID := getEnvWithDefault("TELEGRAM_CHAT_ID", "")
+	workers, _ := strconv.Atoi(getEnvWithDefault("EMAIL_QUEUE_WORKERS", "2"))
+	batchSize, _ := strconv.Atoi(getEnvWithDefault("EMAIL_QUEUE_BATCH_SIZE", "10"))
+	pollInterval, _ := strconv.Atoi(getEnvWithDefault("EMAIL_QUEUE_POLL_INTERVAL", "5"))
+	maxAttempts, _ := strconv.Atoi(getEnvWithDefault("EMAIL_QUEUE_MAX_ATTEMPTS", "5"))
+	baseBackoff, _ := strconv.Atoi(getEnvWithDefault("EMAIL_QUEUE_BACKOFF", "30"))
+
+	queue := NewEmailQueue(repo, smtpClient, prometheusMetrics, EmailQueueConfig{
+		Workers:      workers,
+		BatchSize:    batchSize,
+		PollInterval: time.Duration(pollInterval) * time.Second,
+		MaxAttempts:  maxAttempts,
+		BaseBackoff:  time.Duration(baseBackoff) * time.Second,
+	})
+	queue.Start()
+
+	return queue
+}
 
-	// Als Telegram niet geconfigureerd is, return nil
-	if botToken == "" || chatID == "" {
-		logger.Warn("Telegram configuratie ontbreekt, notificaties worden niet verzonden",
-			"bot_token_provided", botToken != "",
-			"chat_id_provided", chatID != "")
+// createNotificationService maakt een nieuwe notification service. Naast Telegram kunnen
+// notificaties via een email digest, uitgaande webhooks en in-app worden afgeleverd; de
+// kanalen die geconfigureerd zijn krijgen elk hun eigen aflevering.
+func createNotificationService(repoFactory *repository.Repository, emailSender EmailSender, inbox *NotificationInbox, redisClient *redis.Client) NotificationService {
+	// Check of notificaties zijn ingeschakeld
+	enabled := getEnvWithDefault("ENABLE_NOTIFICATIONS", "false") == "true"
+	if !enabled {
+		logger.Info("Notificaties zijn uitgeschakeld")
 		return nil
 	}
 
-	// Maak een nieuwe Telegram client
-	telegramClient := NewTelegramClient(botToken, chatID)
-
-	// Parseer throttle duration
-	throttleDurationStr := getEnvWithDefault("NOTIFICATION_THROTTLE", "15m")
-	throttleDuration, err := time.ParseDuration(throttleDurationStr)
-	if err != nil {
-		logger.Warn("Ongeldige throttle duur, gebruik standaard 15 minuten",
-			"duration", throttleDurationStr,
-			"error", err)
-		throttleDuration = 15 * time.Minute
-	}
-
 	// Parseer minimale prioriteit
 	minPriorityStr := getEnvWithDefault("NOTIFICATION_MIN_PRIORITY", "medium")
 	var minPriority models.NotificationPriority
@@ -269,26 +371,163 @@ func createNotificationService(notificationRepo repository.NotificationRepositor
 		minPriority = models.NotificationPriorityMedium
 	}
 
+	channels := createNotificationChannels(repoFactory, emailSender, inbox)
+	if len(channels) == 0 {
+		logger.Warn("Geen notificatie kanalen geconfigureerd, notificaties worden niet verzonden")
+		return nil
+	}
+
+	// Parseer throttle duration
+	throttleDurationStr := getEnvWithDefault("NOTIFICATION_THROTTLE", "15m")
+	throttleDuration, err := time.ParseDuration(throttleDurationStr)
+	if err != nil {
+		logger.Warn("Ongeldige throttle duur, gebruik standaard 15 minuten",
+			"duration", throttleDurationStr,
+			"error", err)
+		throttleDuration = 15 * time.Minute
+	}
+	throttler := createNotificationThrottler(repoFactory, redisClient, throttleDuration)
+
+	// Routeringsregels per kanaal
+	routes := DefaultNotificationRoutes(minPriority)
+	if spec := getEnvWithDefault("NOTIFICATION_ROUTES", ""); spec != "" {
+		parsed, err := ParseNotificationRoutes(spec)
+		if err != nil {
+			logger.Warn("Ongeldige NOTIFICATION_ROUTES, gebruik standaard regels", "error", err)
+		} else {
+			routes = parsed
+		}
+	}
+
+	router := NewNotificationRouter(repoFactory.Notification, repoFactory.NotificationDelivery, channels, routes)
+	maxAttempts, _ := strconv.Atoi(getEnvWithDefault("NOTIFICATION_MAX_ATTEMPTS", strconv.Itoa(DefaultNotificationMaxAttempts)))
+	retryDelay, _ := time.ParseDuration(getEnvWithDefault("NOTIFICATION_RETRY_DELAY", DefaultNotificationRetryDelay.String()))
+	router.SetRetryPolicy(maxAttempts, retryDelay, DefaultNotificationMaxRetryDelay)
+
+	// De Telegram client blijft de standaard client van de service; de router gebruikt hem via het Telegram kanaal
+	var client NotificationClient
+	if telegram, ok := findNotificationChannel(channels, models.NotificationChannelTelegram).(*TelegramChannel); ok {
+		client = telegram.client
+	}
+
 	// Maak een nieuwe notification service
 	notificationService := NewNotificationService(
-		notificationRepo,
-		telegramClient,
-		throttleDuration,
+		repoFactory.Notification,
+		client,
+		throttler,
 		minPriority,
 	)
+	notificationService.SetRouter(router)
 
 	// Start de notification service
 	go notificationService.Start()
 
 	logger.Info("Notificatie service geïnitialiseerd",
+		"channels", router.Channels(),
 		"throttle", throttleDuration.String(),
 		"min_priority", minPriority)
 
 	return notificationService
 }
 
+// createNotificationThrottler maakt de throttler voor notificaties. De status staat in Redis als
+// dat beschikbaar is en anders in de database, zodat alle instanties dezelfde vensters delen.
+func createNotificationThrottler(repoFactory *repository.Repository, redisClient *redis.Client, defaultWindow time.Duration) *NotificationThrottler {
+	var store repository.NotificationThrottleRepository = repoFactory.NotificationThrottle
+	storeName := "database"
+	if redisClient != nil {
+		store = NewRedisNotificationThrottleStore(redisClient)
+		storeName = "redis"
+	}
+
+	var rules []NotificationThrottleRule
+	if spec := getEnvWithDefault("NOTIFICATION_THROTTLE_RULES", ""); spec != "" {
+		parsed, err := ParseNotificationThrottleRules(spec)
+		if err != nil {
+			logger.Warn("Ongeldige NOTIFICATION_THROTTLE_RULES, gebruik standaard throttle voor alle types", "error", err)
+		} else {
+			rules = parsed
+		}
+	}
+	throttler := NewNotificationThrottler(store, defaultWindow, rules)
+
+	if spec := getEnvWithDefault("NOTIFICATION_QUIET_HOURS", ""); spec != "" {
+		location, err := time.LoadLocation(getEnvWithDefault("NOTIFICATION_TIMEZONE", DefaultNotificationTimezone))
+		if err != nil {
+			logger.Warn("Ongeldige NOTIFICATION_TIMEZONE, gebruik UTC", "error", err)
+			location = time.UTC
+		}
+		quietHours, err := ParseQuietHours(spec, location)
+		if err != nil {
+			logger.Warn("Ongeldige NOTIFICATION_QUIET_HOURS, stille uren uitgeschakeld", "error", err)
+		} else {
+			throttler.SetQuietHours(quietHours)
+		}
+	}
+
+	logger.Info("Notificatie throttling geïnitialiseerd",
+		"store", storeName,
+		"rules", len(rules),
+		"quiet_hours", throttler.QuietHours() != nil)
+	return throttler
+}
+
+// createNotificationChannels maakt de notificatie kanalen die in de omgeving zijn geconfigureerd
+func createNotificationChannels(repoFactory *repository.Repository, emailSender EmailSender, inbox *NotificationInbox) []NotificationChannel {
+	var channels []NotificationChannel
+
+	// Telegram
+	botToken := getEnvWithDefault("TELEGRAM_BOT_TOKEN", "")
+	chatID := getEnvWithDefault("TELEGRAM_CHAT_ID", "")
+	if botToken != "" && chatID != "" {
+		telegram := NewTelegramChannel(NewTelegramClient(botToken, chatID))
+		telegram.SetChatRepository(repoFactory.TelegramChat)
+		channels = append(channels, telegram)
+	} else {
+		logger.Warn("Telegram configuratie ontbreekt, notificaties worden niet via Telegram verzonden",
+			"bot_token_provided", botToken != "",
+			"chat_id_provided", chatID != "")
+	}
+
+	// Email digest aan medewerkers
+	if recipients := splitAndTrim(getEnvWithDefault("NOTIFICATION_EMAIL_TO", "")); len(recipients) > 0 && emailSender != nil {
+		interval, err := time.ParseDuration(getEnvWithDefault("NOTIFICATION_EMAIL_DIGEST_INTERVAL", "1h"))
+		if err != nil || interval <= 0 {
+			logger.Warn("Ongeldige NOTIFICATION_EMAIL_DIGEST_INTERVAL, gebruik standaard 1 uur", "error", err)
+			interval = time.Hour
+		}
+		channels = append(channels, NewEmailDigestChannel(emailSender, recipients, interval))
+	}
+
+	// Uitgaande webhooks; de eerste heet webhook, de volgende webhook_2, webhook_3, ...
+	for i, webhookURL := range splitAndTrim(getEnvWithDefault("NOTIFICATION_WEBHOOK_URLS", "")) {
+		name := models.NotificationChannelWebhook
+		if i > 0 {
+			name = fmt.Sprintf("%s_%d", models.NotificationChannelWebhook, i+1)
+		}
+		channels = append(channels, NewWebhookChannel(name, webhookURL))
+	}
+
+	// In-app notificaties in de inbox van medewerkers
+	if getEnvWithDefault("NOTIFICATION_IN_APP", "true") == "true" && repoFactory.UserNotification != nil {
+		channels = append(channels, NewInAppChannel(inbox))
+	}
+
+	return channels
+}
+
+// findNotificationChannel zoekt een kanaal op naam
+func findNotificationChannel(channels []NotificationChannel, name string) NotificationChannel {
+	for _, channel := range channels {
+		if channel.Name() == name {
+			return channel
+		}
+	}
+	return nil
+}
+
 // createTelegramBotService maakt een nieuwe Telegram bot service
-func createTelegramBotService(contactRepo repository.ContactRepository, aanmeldingRepo repository.AanmeldingRepository) *TelegramBotService {
+func createTelegramBotService(repoFactory *repository.Repository) *TelegramBotService {
 	// Check of bot enabled is in omgevingsvariabelen
 	enabled := getEnvWithDefault("ENABLE_TELEGRAM_BOT", "false") == "true"
 	if !enabled {
@@ -297,18 +536,46 @@ func createTelegramBotService(contactRepo repository.ContactRepository, aanmeldi
 	}
 
 	// Maak een nieuwe Telegram bot service
-	telegramBotService := NewTelegramBotService(contactRepo, aanmeldingRepo)
+	telegramBotService := NewTelegramBotService(repoFactory.Contact, repoFactory.Aanmelding)
+	if telegramBotService == nil {
+		return nil
+	}
 
-	// Als de service succesvol is aangemaakt, start polling
-	if telegramBotService != nil {
-		logger.Info("Telegram bot service geïnitialiseerd")
-		// Polling temporarily disabled to avoid conflicts on Render
-		// telegramBotService.StartPolling()
+	// Register van chats; de standaard chat krijgt alles zodat er niets verandert tot er chats bij komen
+	telegramBotService.SetChatRepository(repoFactory.TelegramChat)
+	if err := telegramBotService.EnsureDefaultChat(context.Background()); err != nil {
+		logger.Warn("Kon standaard telegram chat niet registreren", "error", err)
 	}
 
+	logger.Info("Telegram bot service geïnitialiseerd")
 	return telegramBotService
 }
 
+// startTelegramBotUpdates laat de bot updates ontvangen volgens TELEGRAM_UPDATE_MODE. Standaard
+// ontvangt de bot niets; op Render gaat de voorkeur naar een webhook, omdat polling vanaf meerdere
+// instanties conflicteert.
+func startTelegramBotUpdates(telegramBotService *TelegramBotService, publicAPIURL string) {
+	mode := getEnvWithDefault("TELEGRAM_UPDATE_MODE", TelegramUpdateModeOff)
+	switch mode {
+	case TelegramUpdateModeWebhook:
+		webhookURL := getEnvWithDefault("TELEGRAM_WEBHOOK_URL", strings.TrimRight(publicAPIURL, "/")+TelegramWebhookPath)
+		if err := telegramBotService.ConfigureWebhook(webhookURL, os.Getenv("TELEGRAM_WEBHOOK_SECRET")); err != nil {
+			logger.Error("Kon telegram webhook niet instellen", "url", webhookURL, "error", err)
+			return
+		}
+		logger.Info("Telegram bot ontvangt updates via webhook", "url", webhookURL)
+	case TelegramUpdateModePolling:
+		if err := telegramBotService.DeleteWebhook(); err != nil {
+			logger.Warn("Kon telegram webhook niet verwijderen", "error", err)
+		}
+		telegramBotService.StartPolling()
+	case TelegramUpdateModeOff:
+		logger.Info("Telegram bot ontvangt geen updates", "mode", mode)
+	default:
+		logger.Warn("Ongeldige TELEGRAM_UPDATE_MODE, de bot ontvangt geen updates", "mode", mode)
+	}
+}
+
 // getEnvWithDefault haalt een omgevingsvariabele op met een standaardwaarde
 func getEnvWithDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -317,3 +584,14 @@ func getEnvWithDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+// splitAndTrim splitst een kommagescheiden lijst uit de omgeving en laat lege waarden weg
+func splitAndTrim(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -87,6 +87,8 @@ type EmailSender interface {
 	SendEmail(to, subject, body string, fromAddress ...string) error
 	// SendTemplateEmail stuurt een e-mail met behulp van een template, met optioneel 'From' adres.
 	SendTemplateEmail(recipient, subject, templateName string, templateData map[string]interface{}, fromAddress ...string) error
+	// SendTemplateEmailWithMetadata stuurt een template e-mail met koppelingen; met KeepSubject wint het opgegeven onderwerp.
+	SendTemplateEmailWithMetadata(recipient, subject, templateName string, templateData map[string]interface{}, meta EmailMetadata, fromAddress ...string) error
 
 	// Methoden specifiek gebruikt door EmailHandler (Contact/Aanmelding).
 	// Behoud originele signature als ze altijd de geconfigureerde afzender moeten gebruiken.
@@ -142,6 +144,11 @@ type NotificationService interface {
 	CreateNotification(ctx context.Context, notificationType models.NotificationType,
 		priority models.NotificationPriority, title, message string) (*models.Notification, error)
 
+	// CreateReferencedNotification maakt een notificatie aan over een contactformulier of aanmelding.
+	// In Telegram krijgt zo'n notificatie knoppen om het record direct af te handelen.
+	CreateReferencedNotification(ctx context.Context, notificationType models.NotificationType,
+		priority models.NotificationPriority, title, message, referenceID string) (*models.Notification, error)
+
 	// GetNotification haalt een notificatie op basis van ID
 	GetNotification(ctx context.Context, id string) (*models.Notification, error)
 
@@ -1,13 +1,14 @@
 package services
 
 import (
+	"context"
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
-	"encoding/base64"
+	"dklautomationgo/repository"
 	"fmt"
 	"io"
-	"mime/quotedprintable"
 	"net/mail"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,15 +25,20 @@ type MailAccount struct {
 	Password string
 	Host     string
 	Port     int
-	Type     string // "info" of "inschrijving"
+	Type     string   // "info" of "inschrijving"
+	Folders  []string // Mailboxen die worden opgehaald, standaard alleen INBOX
+	Sync     MailAccountSyncConfig
 }
 
 // MailFetcher is verantwoordelijk voor het ophalen van e-mails uit inboxen
 type MailFetcher struct {
-	accounts  []*MailAccount
-	metrics   *EmailMetrics
-	lastFetch time.Time
-	mu        sync.RWMutex
+	accounts      []*MailAccount
+	metrics       *EmailMetrics
+	lastFetch     time.Time
+	syncStateRepo repository.MailSyncStateRepository
+	linker        *MailLinker
+	bounces       *BounceProcessor
+	mu            sync.RWMutex
 }
 
 // NewMailFetcher maakt een nieuwe MailFetcher
@@ -44,21 +50,75 @@ func NewMailFetcher(metrics *EmailMetrics) *MailFetcher {
 	}
 }
 
-// AddAccount voegt een mail account toe aan de fetcher
-func (f *MailFetcher) AddAccount(username, password, host string, port int, accountType string) {
+// AddAccount voegt een mail account toe aan de fetcher. Zonder folders wordt alleen INBOX opgehaald.
+func (f *MailFetcher) AddAccount(username, password, host string, port int, accountType string, folders ...string) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	if len(folders) == 0 {
+		folders = []string{"INBOX"}
+	}
+
 	account := &MailAccount{
 		Username: username,
 		Password: password,
 		Host:     host,
 		Port:     port,
 		Type:     accountType,
+		Folders:  folders,
 	}
 
 	f.accounts = append(f.accounts, account)
-	logger.Info("Mail account toegevoegd", "username", username, "host", host, "type", accountType)
+	logger.Info("Mail account toegevoegd", "username", username, "host", host, "type", accountType, "folders", folders)
+}
+
+// SetSyncStateRepository stelt de repository in waarin per mailbox de laatst opgehaalde UID
+// wordt bewaard. Zonder repository valt de fetcher terug op zoeken op datum.
+func (f *MailFetcher) SetSyncStateRepository(repo repository.MailSyncStateRepository) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.syncStateRepo = repo
+}
+
+// SetMailLinker stelt de linker in waarmee nieuwe emails aan aanmeldingen en contactformulieren worden gekoppeld
+func (f *MailFetcher) SetMailLinker(linker *MailLinker) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.linker = linker
+}
+
+// SetBounceProcessor stelt de processor in die bounces (DSN's) op de suppressielijst zet
+func (f *MailFetcher) SetBounceProcessor(processor *BounceProcessor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bounces = processor
+}
+
+// ProcessStoredEmail verwerkt een zojuist opgeslagen email: bounces gaan naar de suppressielijst,
+// andere emails worden aan een aanmelding of contactformulier gekoppeld.
+// Fouten worden gelogd; de verwerking mag het opslaan van mail nooit blokkeren.
+func (f *MailFetcher) ProcessStoredEmail(ctx context.Context, email *models.IncomingEmail) {
+	f.mu.RLock()
+	linker := f.linker
+	bounces := f.bounces
+	f.mu.RUnlock()
+
+	if email.IsBounce {
+		if bounces == nil {
+			return
+		}
+		if _, err := bounces.Process(ctx, email); err != nil {
+			logger.Error("Kon bounce niet verwerken", "error", err, "email_id", email.ID)
+		}
+		return
+	}
+
+	if linker == nil {
+		return
+	}
+	if _, err := linker.Link(ctx, email); err != nil {
+		logger.Error("Kon inkomende email niet koppelen", "error", err, "email_id", email.ID)
+	}
 }
 
 // FetchMails haalt e-mails op van alle geconfigureerde accounts
@@ -83,13 +143,13 @@ func (f *MailFetcher) FetchMails() ([]*models.IncomingEmail, error) {
 		go func(acc *MailAccount) {
 			defer wg.Done()
 
+			// Ook bij een fout komen de al opgehaalde emails mee, bijv. als alleen één folder ontbreekt
 			mails, err := f.fetchFromAccount(acc, f.lastFetch)
 			if err != nil {
 				logger.Error("Fout bij ophalen e-mails", "error", err, "account", acc.Username)
 				mu.Lock()
 				errors = append(errors, fmt.Errorf("account %s: %w", acc.Username, err))
 				mu.Unlock()
-				return
 			}
 
 			if len(mails) > 0 {
@@ -120,145 +180,329 @@ func (f *MailFetcher) FetchMails() ([]*models.IncomingEmail, error) {
 	return allMails, nil
 }
 
-// fetchFromAccount haalt e-mails op van één specifiek account
+// fetchFromAccount haalt e-mails op uit alle geconfigureerde mailboxen van één account
 func (f *MailFetcher) fetchFromAccount(account *MailAccount, since time.Time) ([]*models.IncomingEmail, error) {
-	// Begin fetchFromAccount
-	logger.Info("Start fetchFromAccount", "account", account.Username, "since_parameter", since)
-	// Verbind met de IMAP server
-	imapAddr := fmt.Sprintf("%s:%d", account.Host, account.Port)
-	c, err := client.DialTLS(imapAddr, nil)
+	logger.Info("Start fetchFromAccount", "account", account.Username, "since_parameter", since, "folders", account.Folders)
+	c, err := connectIMAP(account)
 	if err != nil {
-		return nil, fmt.Errorf("kan niet verbinden met IMAP server: %w", err)
+		return nil, err
 	}
 	defer c.Logout()
 
-	// Login
-	if err := c.Login(account.Username, account.Password); err != nil {
-		return nil, fmt.Errorf("login mislukt: %w", err)
+	folders := account.Folders
+	if len(folders) == 0 {
+		folders = []string{"INBOX"}
 	}
 
-	// Selecteer INBOX
-	mbox, err := c.Select("INBOX", false)
-	if err != nil {
-		return nil, fmt.Errorf("kan inbox niet selecteren: %w", err)
+	var emails []*models.IncomingEmail
+	var folderErrors []string
+	for _, folder := range folders {
+		mails, err := f.fetchFromMailbox(c, account, folder, since)
+		emails = append(emails, mails...)
+		if err != nil {
+			// Een ontbrekende of onleesbare folder mag de andere folders niet blokkeren
+			logger.Error("Fout bij ophalen mailbox", "error", err, "account", account.Username, "mailbox", folder)
+			folderErrors = append(folderErrors, fmt.Sprintf("%s: %v", folder, err))
+		}
 	}
 
-	// Geen berichten in inbox
-	if mbox.Messages == 0 {
-		return []*models.IncomingEmail{}, nil
+	if len(folderErrors) > 0 {
+		return emails, fmt.Errorf("mailboxen mislukt: %s", strings.Join(folderErrors, "; "))
 	}
 
-	// Zoek berichten van na de laatste keer ophalen
-	criteria := imap.NewSearchCriteria()
-	// criteria.Since = since // Weer inschakelen van de SINCE criteria
-	// Introduceer een kleine buffer om timing issues te voorkomen
-	criteria.Since = since.Add(-1 * time.Minute)
-	logger.Debug("Using SINCE criteria with buffer", "effective_since", criteria.Since)
+	return emails, nil
+}
 
-	// Voer zoekopdracht uit
-	uids, err := c.Search(criteria)
+// fetchFromMailbox haalt nieuwe berichten op uit één mailbox. Met een opgeslagen
+// synchronisatiestatus en gelijke UIDVALIDITY worden alleen UIDs na de laatst
+// opgehaalde UID opgevraagd; anders wordt gezocht op datum en de status opnieuw opgebouwd.
+// De status schuift hier alleen op als er niets op te slaan valt; anders doet StoreFetchedEmails
+// dat per opgeslagen email.
+func (f *MailFetcher) fetchFromMailbox(c *client.Client, account *MailAccount, folder string, since time.Time) ([]*models.IncomingEmail, error) {
+	// Read-only selecteren zodat flags (\Seen) niet wijzigen
+	mbox, err := c.Select(folder, true)
 	if err != nil {
-		return nil, fmt.Errorf("zoeken mislukt: %w", err)
+		return nil, fmt.Errorf("kan mailbox %s niet selecteren: %w", folder, err)
 	}
 
-	logger.Info("IMAP Search resultaat", "account", account.Username, "aantal_uids", len(uids))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if len(uids) == 0 {
-		return []*models.IncomingEmail{}, nil
+	var state *models.MailSyncState
+	if f.syncStateRepo != nil {
+		state, err = f.syncStateRepo.Get(ctx, account.Type, folder)
+		if err != nil {
+			logger.Warn("Kon synchronisatiestatus niet laden, terugvallen op datum", "error", err, "account", account.Type, "mailbox", folder)
+			state = nil
+		}
 	}
 
-	// Maak een sequence set voor berichten
-	seqset := new(imap.SeqSet)
-	seqset.AddNum(uids...)
-
-	// Items om op te halen
-	var section imap.BodySectionName
-	items := []imap.FetchItem{section.FetchItem(), imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid}
+	incremental := state != nil && state.UIDValidity == mbox.UidValidity && state.LastUID > 0
+	if state != nil && state.UIDValidity != mbox.UidValidity {
+		logger.Warn("UIDVALIDITY van mailbox gewijzigd, mailbox wordt opnieuw gesynchroniseerd",
+			"account", account.Type,
+			"mailbox", folder,
+			"oud", state.UIDValidity,
+			"nieuw", mbox.UidValidity)
+	}
 
-	// Haal berichten op
-	messages := make(chan *imap.Message, 10)
-	done := make(chan error, 1)
+	var uids []uint32
+	switch {
+	case mbox.Messages == 0:
+		// Lege mailbox, alleen de status bijwerken
+	case incremental && mbox.UidNext > 0 && mbox.UidNext <= state.LastUID+1:
+		// Geen nieuwe berichten sinds de vorige synchronisatie
+	case incremental:
+		criteria := imap.NewSearchCriteria()
+		criteria.Uid = new(imap.SeqSet)
+		criteria.Uid.AddRange(state.LastUID+1, 0) // LastUID+1:*
+		found, err := c.UidSearch(criteria)
+		if err != nil {
+			return nil, fmt.Errorf("zoeken mislukt: %w", err)
+		}
+		// "n:*" levert altijd het laatste bericht op, ook als dat al is opgehaald
+		for _, uid := range found {
+			if uid > state.LastUID {
+				uids = append(uids, uid)
+			}
+		}
+	default:
+		criteria := imap.NewSearchCriteria()
+		// Introduceer een kleine buffer om timing issues te voorkomen
+		criteria.Since = since.Add(-1 * time.Minute)
+		found, err := c.UidSearch(criteria)
+		if err != nil {
+			return nil, fmt.Errorf("zoeken mislukt: %w", err)
+		}
+		uids = found
+	}
 
-	go func() {
-		done <- c.Fetch(seqset, items, messages)
-	}()
+	logger.Info("IMAP Search resultaat", "account", account.Username, "mailbox", folder, "incremental", incremental, "aantal_uids", len(uids))
 
 	var emails []*models.IncomingEmail
-	for msg := range messages {
-		email, err := processMessage(msg, section, account.Type)
+	lastUID := uint32(0)
+	if incremental {
+		lastUID = state.LastUID
+	}
+
+	if len(uids) > 0 {
+		seqset := new(imap.SeqSet)
+		seqset.AddNum(uids...)
+
+		// Items om op te halen
+		var section imap.BodySectionName
+		section.Peek = true
+		items := []imap.FetchItem{section.FetchItem(), imap.FetchEnvelope, imap.FetchFlags, imap.FetchUid}
+
+		messages := make(chan *imap.Message, 10)
+		done := make(chan error, 1)
+
+		go func() {
+			done <- c.UidFetch(seqset, items, messages)
+		}()
+
+		for msg := range messages {
+			if msg.Uid > lastUID {
+				lastUID = msg.Uid
+			}
+			email, err := processMessage(msg, section, account.Type)
+			if err != nil {
+				logger.Warn("Fout bij verwerken bericht", "error", err, "uid", msg.Uid, "mailbox", folder)
+				continue
+			}
+			email.Mailbox = folder
+			email.UIDValidity = mbox.UidValidity
+			emails = append(emails, email)
+		}
+
+		if err := <-done; err != nil {
+			return emails, fmt.Errorf("ophalen berichten mislukt: %w", err)
+		}
+	}
+
+	// Zonder nieuwe berichten begint een volgende synchronisatie na de huidige UIDNEXT
+	if lastUID == 0 && mbox.UidNext > 1 {
+		lastUID = mbox.UidNext - 1
+	}
+
+	if f.syncStateRepo != nil && len(emails) == 0 {
+		now := time.Now()
+		newState := &models.MailSyncState{
+			AccountType: account.Type,
+			Username:    account.Username,
+			Mailbox:     folder,
+			UIDValidity: mbox.UidValidity,
+			LastUID:     lastUID,
+			LastSyncAt:  &now,
+		}
+		if err := f.syncStateRepo.Save(ctx, newState); err != nil {
+			logger.Error("Kon synchronisatiestatus niet opslaan", "error", err, "account", account.Type, "mailbox", folder)
+		}
+	}
+
+	return emails, nil
+}
+
+// StoreFetchedEmails slaat opgehaalde emails op, verwerkt ze en schuift daarna per mailbox de
+// synchronisatiestatus op. Na een mislukte opslag blijft de status van die mailbox staan, zodat
+// de email en alles erna bij de volgende synchronisatie opnieuw wordt opgehaald.
+// Geeft het aantal nieuw opgeslagen emails terug.
+func (f *MailFetcher) StoreFetchedEmails(ctx context.Context, repo repository.IncomingEmailRepository, emails []*models.IncomingEmail) int {
+	// Per mailbox op UID volgorde, zodat de status nooit voorbij een niet opgeslagen email schuift
+	sorted := make([]*models.IncomingEmail, len(emails))
+	copy(sorted, emails)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.AccountType != b.AccountType {
+			return a.AccountType < b.AccountType
+		}
+		if a.Mailbox != b.Mailbox {
+			return a.Mailbox < b.Mailbox
+		}
+		return parseUID(a.UID) < parseUID(b.UID)
+	})
+
+	saved := 0
+	blocked := make(map[string]bool)
+	for _, email := range sorted {
+		mailboxKey := email.AccountType + "/" + email.Mailbox
+		if blocked[mailboxKey] {
+			continue
+		}
+
+		existing, err := repo.FindByMailboxUID(ctx, email.AccountType, email.Mailbox, email.UIDValidity, email.UID)
 		if err != nil {
-			logger.Warn("Fout bij verwerken bericht", "error", err, "uid", msg.Uid)
+			logger.Error("Fout bij controleren op bestaande email", "error", err, "uid", email.UID, "mailbox", email.Mailbox)
+			blocked[mailboxKey] = true
 			continue
 		}
-		emails = append(emails, email)
+
+		if existing != nil {
+			logger.Debug("Email overgeslagen (bestaat al)", "uid", email.UID)
+		} else {
+			if err := repo.Create(ctx, email); err != nil {
+				logger.Error("Fout bij opslaan van nieuwe email", "error", err, "uid", email.UID, "mailbox", email.Mailbox)
+				blocked[mailboxKey] = true
+				continue
+			}
+			f.ProcessStoredEmail(ctx, email)
+			saved++
+		}
+
+		f.advanceSyncState(ctx, email)
+	}
+
+	return saved
+}
+
+// advanceSyncState legt vast dat een mailbox tot en met de UID van deze email is opgeslagen
+func (f *MailFetcher) advanceSyncState(ctx context.Context, email *models.IncomingEmail) {
+	f.mu.RLock()
+	repo := f.syncStateRepo
+	var username string
+	for _, account := range f.accounts {
+		if account.Type == email.AccountType {
+			username = account.Username
+			break
+		}
 	}
+	f.mu.RUnlock()
 
-	if err := <-done; err != nil {
-		return nil, fmt.Errorf("ophalen berichten mislukt: %w", err)
+	uid := parseUID(email.UID)
+	if repo == nil || email.Mailbox == "" || uid == 0 {
+		return
 	}
 
-	return emails, nil
+	now := time.Now()
+	state := &models.MailSyncState{
+		AccountType: email.AccountType,
+		Username:    username,
+		Mailbox:     email.Mailbox,
+		UIDValidity: email.UIDValidity,
+		LastUID:     uid,
+		LastSyncAt:  &now,
+	}
+	if err := repo.Save(ctx, state); err != nil {
+		logger.Error("Kon synchronisatiestatus niet opslaan", "error", err, "account", email.AccountType, "mailbox", email.Mailbox)
+	}
+}
+
+// parseUID zet een opgeslagen IMAP UID om naar een getal; ongeldige waarden worden 0
+func parseUID(uid string) uint32 {
+	n, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(n)
+}
+
+// GetSyncStates geeft de opgeslagen synchronisatiestatus van alle mailboxen terug
+func (f *MailFetcher) GetSyncStates(ctx context.Context) ([]*models.MailSyncState, error) {
+	f.mu.RLock()
+	repo := f.syncStateRepo
+	f.mu.RUnlock()
+
+	if repo == nil {
+		return []*models.MailSyncState{}, nil
+	}
+	return repo.List(ctx)
 }
 
 // processMessage verwerkt een imap bericht naar een IncomingEmail model
 func processMessage(msg *imap.Message, section imap.BodySectionName, accountType string) (*models.IncomingEmail, error) {
-	var contentType string
-
 	// Haal body op
 	bodyReader := msg.GetBody(&section)
 	if bodyReader == nil {
 		return nil, fmt.Errorf("geen body gevonden")
 	}
 
+	email, err := parseIncomingEmail(bodyReader, accountType)
+	if err != nil {
+		return nil, err
+	}
+	email.UID = strconv.FormatUint(uint64(msg.Uid), 10)
+
+	return email, nil
+}
+
+// parseIncomingEmail leest een volledig RFC 5322 bericht en zet het om naar een IncomingEmail.
+// Multipart berichten worden volledig doorlopen; de HTML-versie heeft de voorkeur als body,
+// de tekstversie wordt apart bewaard en bijlagen worden aan de email gekoppeld.
+func parseIncomingEmail(r io.Reader, accountType string) (*models.IncomingEmail, error) {
 	// Parse het bericht
-	m, err := mail.ReadMessage(bodyReader)
+	m, err := mail.ReadMessage(r)
 	if err != nil {
 		return nil, fmt.Errorf("kan bericht niet lezen: %w", err)
 	}
 
 	// Lees de headers
-	contentType = m.Header.Get("Content-Type")
-	contentEncoding := m.Header.Get("Content-Transfer-Encoding")
-	from := m.Header.Get("From")
-	subject := m.Header.Get("Subject")
+	from := DecodeAddressHeader(m.Header.Get("From"))
+	subject := DecodeMIMEHeader(m.Header.Get("Subject"))
 	date := m.Header.Get("Date")
 	messageId := m.Header.Get("Message-ID")
+	inReplyTo := strings.TrimSpace(m.Header.Get("In-Reply-To"))
+	references := strings.Join(strings.Fields(m.Header.Get("References")), " ")
 
-	// Maak de juiste reader aan op basis van de encoding
-	var finalBodyReader io.Reader = m.Body
-	switch strings.ToLower(contentEncoding) {
-	case "quoted-printable":
-		finalBodyReader = quotedprintable.NewReader(m.Body)
-		logger.Debug("Using quoted-printable decoder", "message_id", messageId, "uid", msg.Uid)
-	case "base64":
-		finalBodyReader = base64.NewDecoder(base64.StdEncoding, m.Body)
-		logger.Debug("Using base64 decoder", "message_id", messageId, "uid", msg.Uid)
-	case "7bit", "8bit", "binary", "":
-		// Geen decoding nodig, finalBodyReader blijft m.Body
-		logger.Debug("No transfer decoding needed", "encoding", contentEncoding, "message_id", messageId, "uid", msg.Uid)
-	default:
-		logger.Warn("Onbekende Content-Transfer-Encoding", "encoding", contentEncoding, "message_id", messageId, "uid", msg.Uid)
-		// Probeer toch direct te lezen
-	}
-
-	// Lees de (mogelijk gedecodeerde) body
-	bodyBytes, err := io.ReadAll(finalBodyReader)
+	parsed, err := ParseMIMEMessage(m.Header, m.Body)
 	if err != nil {
-		return nil, fmt.Errorf("kan body niet lezen na decoding: %w", err)
+		return nil, fmt.Errorf("kan MIME structuur niet verwerken: %w", err)
 	}
-	decodedBody := string(bodyBytes) // Dit is nu de gedecodeerde body
-
-	// DEBUG: Log de gedecodeerde body
-	logger.Debug("Decoded email body content", "message_id", messageId, "uid", msg.Uid, "decoded_body_preview", getFirstNChars(decodedBody, 200)) // Log first 200 chars
 
-	// TODO: Overweeg charset conversie hier indien nodig, gebaseerd op contentType
-
-	// Sanitize body if content type is text/html
-	finalBody := decodedBody // Gebruik de gedecodeerde body als basis
-	if strings.Contains(strings.ToLower(contentType), "text/html") {
-		p := bluemonday.UGCPolicy()         // User Generated Content policy
-		finalBody = p.Sanitize(decodedBody) // Sanitize de gedecodeerde body
-		logger.Debug("HTML body gesanitized", "message_id", messageId, "uid", msg.Uid)
+	logger.Debug("MIME bericht verwerkt",
+		"message_id", messageId,
+		"has_html", parsed.HTML != "",
+		"has_text", parsed.Text != "",
+		"attachments", len(parsed.Attachments))
+
+	// Kies de body: gesanitizede HTML als die er is, anders de platte tekst
+	var finalBody, contentType string
+	if parsed.HTML != "" {
+		p := bluemonday.UGCPolicy() // User Generated Content policy
+		finalBody = p.Sanitize(parsed.HTML)
+		contentType = "text/html; charset=utf-8"
+	} else {
+		finalBody = parsed.Text
+		contentType = "text/plain; charset=utf-8"
 	}
 
 	// Parse de datum
@@ -274,19 +518,42 @@ func processMessage(msg *imap.Message, section imap.BodySectionName, accountType
 		receivedAt = time.Now()
 	}
 
-	// Maak een IncomingEmail model met de CORRECTE veldnamen
+	attachments := make([]*models.IncomingEmailAttachment, 0, len(parsed.Attachments))
+	for _, att := range parsed.Attachments {
+		attachments = append(attachments, &models.IncomingEmailAttachment{
+			Filename:    att.Filename,
+			ContentType: att.ContentType,
+			ContentID:   att.ContentID,
+			IsInline:    att.Inline,
+			Size:        int64(len(att.Data)),
+			Data:        att.Data,
+		})
+	}
+
 	email := &models.IncomingEmail{
 		MessageID:   messageId,
 		From:        from,
 		To:          accountType + "@dekoninklijkeloop.nl", // Houd de originele logica voor To aan
 		Subject:     subject,
-		Body:        finalBody, // Sla de gedecodeerde en mogelijk gesanitized body op
+		Body:        finalBody,
+		TextBody:    parsed.Text,
 		ContentType: contentType,
 		ReceivedAt:  receivedAt,
 		AccountType: accountType,
-		UID:         strconv.FormatUint(uint64(msg.Uid), 10),
 		IsProcessed: false,
 		ProcessedAt: nil, // Gebruik nil, niet false
+		Attachments: attachments,
+		InReplyTo:   inReplyTo,
+		References:  references,
+		ThreadID:    MailThreadID(messageId, inReplyTo, references),
+	}
+
+	// Een DSN bevat een machine-leesbaar message/delivery-status deel
+	for _, att := range attachments {
+		if IsDeliveryStatusType(att.ContentType) {
+			email.IsBounce = true
+			break
+		}
 	}
 
 	return email, nil
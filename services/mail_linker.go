@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"net/mail"
+	"strings"
+)
+
+// MailLinkStatusInBehandeling is de status die een gekoppelde aanmelding of contactformulier
+// krijgt wanneer er een nieuwe reactie binnenkomt
+const MailLinkStatusInBehandeling = "in_behandeling"
+
+// MailLinker koppelt inkomende emails aan bestaande aanmeldingen en contactformulieren.
+// Een antwoord op een email die wij verstuurden wordt via In-Reply-To/References herkend;
+// anders wordt op het adres van de afzender gezocht.
+type MailLinker struct {
+	incomingRepo   repository.IncomingEmailRepository
+	sentEmailRepo  repository.VerzondEmailRepository
+	aanmeldingRepo repository.AanmeldingRepository
+	contactRepo    repository.ContactRepository
+	reopen         bool
+}
+
+// NewMailLinker maakt een nieuwe MailLinker. Met reopen wordt de status van het gekoppelde
+// record teruggezet naar "in_behandeling" als er een nieuwe reactie binnenkomt.
+func NewMailLinker(
+	incomingRepo repository.IncomingEmailRepository,
+	sentEmailRepo repository.VerzondEmailRepository,
+	aanmeldingRepo repository.AanmeldingRepository,
+	contactRepo repository.ContactRepository,
+	reopen bool,
+) *MailLinker {
+	return &MailLinker{
+		incomingRepo:   incomingRepo,
+		sentEmailRepo:  sentEmailRepo,
+		aanmeldingRepo: aanmeldingRepo,
+		contactRepo:    contactRepo,
+		reopen:         reopen,
+	}
+}
+
+// Link zoekt de aanmelding en/of het contactformulier bij een opgeslagen inkomende email,
+// slaat de koppeling op en geeft terug of er iets gekoppeld is
+func (l *MailLinker) Link(ctx context.Context, email *models.IncomingEmail) (bool, error) {
+	if email.AanmeldingID != nil || email.ContactID != nil {
+		return true, nil
+	}
+
+	if err := l.linkByThread(ctx, email); err != nil {
+		return false, err
+	}
+	if email.AanmeldingID == nil && email.ContactID == nil {
+		if err := l.linkBySender(ctx, email); err != nil {
+			return false, err
+		}
+	}
+	if email.AanmeldingID == nil && email.ContactID == nil {
+		return false, nil
+	}
+
+	if err := l.incomingRepo.Update(ctx, email); err != nil {
+		return false, err
+	}
+
+	logger.Info("Inkomende email gekoppeld",
+		"email_id", email.ID,
+		"aanmelding_id", stringValue(email.AanmeldingID),
+		"contact_id", stringValue(email.ContactID))
+
+	if l.reopen {
+		l.reopenLinked(ctx, email)
+	}
+	return true, nil
+}
+
+// linkByThread koppelt op basis van de verzonden email waarop dit bericht een antwoord is
+func (l *MailLinker) linkByThread(ctx context.Context, email *models.IncomingEmail) error {
+	ids := ParseMessageIDs(email.InReplyTo + " " + email.References)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	sent, err := l.sentEmailRepo.FindByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	// Het meest recente bericht in References is het meest specifiek
+	byMessageID := make(map[string]*models.VerzondEmail, len(sent))
+	for _, item := range sent {
+		byMessageID[item.MessageID] = item
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		item, ok := byMessageID[ids[i]]
+		if !ok {
+			continue
+		}
+
+		if item.AanmeldingID != nil || item.ContactID != nil {
+			email.AanmeldingID = item.AanmeldingID
+			email.ContactID = item.ContactID
+			return nil
+		}
+
+		// Een antwoord vanuit het dashboard erft de koppeling van de beantwoorde email
+		if item.IncomingEmailID != nil {
+			original, err := l.incomingRepo.GetByID(ctx, *item.IncomingEmailID)
+			if err != nil {
+				return err
+			}
+			if original != nil && (original.AanmeldingID != nil || original.ContactID != nil) {
+				email.AanmeldingID = original.AanmeldingID
+				email.ContactID = original.ContactID
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// linkBySender koppelt aan de meest recente aanmelding en/of contactformulier van de afzender
+func (l *MailLinker) linkBySender(ctx context.Context, email *models.IncomingEmail) error {
+	address := senderAddress(email.From)
+	if address == "" {
+		return nil
+	}
+
+	aanmeldingen, err := l.aanmeldingRepo.FindByEmail(ctx, address)
+	if err != nil {
+		return err
+	}
+	if len(aanmeldingen) > 0 {
+		email.AanmeldingID = &aanmeldingen[0].ID
+	}
+
+	contacts, err := l.contactRepo.FindByEmail(ctx, address)
+	if err != nil {
+		return err
+	}
+	if len(contacts) > 0 {
+		email.ContactID = &contacts[0].ID
+	}
+	return nil
+}
+
+// reopenLinked zet de status van gekoppelde records terug naar "in_behandeling".
+// Nieuwe records (status "nieuw") blijven ongemoeid.
+func (l *MailLinker) reopenLinked(ctx context.Context, email *models.IncomingEmail) {
+	if email.AanmeldingID != nil {
+		aanmelding, err := l.aanmeldingRepo.GetByID(ctx, *email.AanmeldingID)
+		if err != nil {
+			logger.Error("Kon gekoppelde aanmelding niet ophalen", "error", err, "aanmelding_id", *email.AanmeldingID)
+		} else if aanmelding != nil && shouldReopen(aanmelding.Status) {
+			aanmelding.Status = MailLinkStatusInBehandeling
+			if err := l.aanmeldingRepo.Update(ctx, aanmelding); err != nil {
+				logger.Error("Kon status van aanmelding niet bijwerken", "error", err, "aanmelding_id", aanmelding.ID)
+			}
+		}
+	}
+
+	if email.ContactID != nil {
+		contact, err := l.contactRepo.GetByID(ctx, *email.ContactID)
+		if err != nil {
+			logger.Error("Kon gekoppeld contactformulier niet ophalen", "error", err, "contact_id", *email.ContactID)
+		} else if contact != nil && shouldReopen(contact.Status) {
+			contact.Status = MailLinkStatusInBehandeling
+			if err := l.contactRepo.Update(ctx, contact); err != nil {
+				logger.Error("Kon status van contactformulier niet bijwerken", "error", err, "contact_id", contact.ID)
+			}
+		}
+	}
+}
+
+// shouldReopen geeft aan of een record met deze status heropend moet worden
+func shouldReopen(status string) bool {
+	return status != "" && status != "nieuw" && status != MailLinkStatusInBehandeling
+}
+
+// senderAddress haalt het kale emailadres uit een From header
+func senderAddress(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return addr.Address
+	}
+	if strings.Contains(from, "@") {
+		return strings.TrimSpace(from)
+	}
+	return ""
+}
+
+// stringValue geeft de waarde van een optionele string terug, of een lege string
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
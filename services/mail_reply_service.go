@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// ErrMailNotFound wordt teruggegeven als de inkomende email niet bestaat
+	ErrMailNotFound = errors.New("email niet gevonden")
+	// ErrInvalidRecipient wordt teruggegeven bij een ongeldig ontvanger adres
+	ErrInvalidRecipient = errors.New("ongeldig ontvanger adres")
+	// ErrEmptyReply wordt teruggegeven als er geen tekst is opgegeven
+	ErrEmptyReply = errors.New("bericht mag niet leeg zijn")
+)
+
+// Email types van berichten die vanuit het dashboard worden verstuurd
+const (
+	MailReplyEmailType   = "mail_antwoord"
+	MailForwardEmailType = "mail_doorgestuurd"
+)
+
+// MailThreadItem is één bericht in een gesprek, inkomend of uitgaand
+type MailThreadItem struct {
+	ID        string    `json:"id"`
+	Direction string    `json:"direction"` // "inkomend" of "uitgaand"
+	MessageID string    `json:"message_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status,omitempty"`
+	Date      time.Time `json:"date"`
+}
+
+// MailThread bevat alle berichten van een gesprek in chronologische volgorde
+type MailThread struct {
+	ThreadID string            `json:"thread_id"`
+	Items    []*MailThreadItem `json:"items"`
+}
+
+// MailReplyService verstuurt antwoorden op en doorgestuurde versies van inkomende emails
+type MailReplyService struct {
+	emailService  *EmailService
+	incomingRepo  repository.IncomingEmailRepository
+	sentEmailRepo repository.VerzondEmailRepository
+}
+
+// NewMailReplyService maakt een nieuwe MailReplyService. Verzenden loopt via de email service,
+// zodat antwoorden net als andere emails de suppressielijst en de outbox volgen.
+func NewMailReplyService(emailService *EmailService, incomingRepo repository.IncomingEmailRepository, sentEmailRepo repository.VerzondEmailRepository) *MailReplyService {
+	return &MailReplyService{
+		emailService:  emailService,
+		incomingRepo:  incomingRepo,
+		sentEmailRepo: sentEmailRepo,
+	}
+}
+
+// Reply beantwoordt een inkomende email via het SMTP account waarop de email binnenkwam.
+// In-Reply-To en References worden gezet zodat mailclients het antwoord in het gesprek tonen.
+func (s *MailReplyService) Reply(ctx context.Context, emailID, body string) (*models.VerzondEmail, error) {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrEmptyReply
+	}
+
+	to, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecipient, email.From)
+	}
+
+	headers := map[string]string{"Message-ID": newMessageID(email.To)}
+	if messageID := strings.TrimSpace(email.MessageID); messageID != "" {
+		headers["In-Reply-To"] = messageID
+		headers["References"] = strings.TrimSpace(email.References + " " + messageID)
+	}
+
+	msg := &EmailMessage{
+		To:      to.Address,
+		Subject: prefixSubject("Re: ", email.Subject),
+		Body:    replyBodyHTML(body) + quoteOriginal(email),
+		Headers: headers,
+	}
+
+	return s.send(ctx, email, msg, MailReplyEmailType)
+}
+
+// Forward stuurt een inkomende email door naar een ander adres, met optionele begeleidende tekst
+func (s *MailReplyService) Forward(ctx context.Context, emailID, to, body string) (*models.VerzondEmail, error) {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecipient, to)
+	}
+
+	var intro string
+	if strings.TrimSpace(body) != "" {
+		intro = replyBodyHTML(body)
+	}
+
+	msg := &EmailMessage{
+		To:      recipient.Address,
+		Subject: prefixSubject("Fwd: ", email.Subject),
+		Body:    intro + forwardOriginal(email),
+		Headers: map[string]string{"Message-ID": newMessageID(email.To)},
+	}
+
+	return s.send(ctx, email, msg, MailForwardEmailType)
+}
+
+// Thread haalt het volledige gesprek op waar een inkomende email bij hoort
+func (s *MailReplyService) Thread(ctx context.Context, emailID string) (*MailThread, error) {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadID := email.ThreadID
+	if threadID == "" {
+		threadID = MailThreadID(email.MessageID, email.InReplyTo, email.References)
+	}
+	thread := &MailThread{ThreadID: threadID}
+	if threadID == "" {
+		thread.Items = []*MailThreadItem{incomingThreadItem(email)}
+		return thread, nil
+	}
+
+	sent, err := s.sentEmailRepo.FindByThreadID(ctx, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("kan verzonden berichten niet ophalen: %w", err)
+	}
+
+	// Antwoorden op onze eigen berichten zonder References verwijzen alleen naar dat bericht
+	threadIDs := []string{threadID}
+	for _, item := range sent {
+		if item.MessageID != "" {
+			threadIDs = append(threadIDs, item.MessageID)
+		}
+	}
+
+	incoming, err := s.incomingRepo.FindByThreadIDs(ctx, threadIDs)
+	if err != nil {
+		return nil, fmt.Errorf("kan inkomende berichten niet ophalen: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, item := range incoming {
+		if !seen[item.ID] {
+			seen[item.ID] = true
+			thread.Items = append(thread.Items, incomingThreadItem(item))
+		}
+	}
+	if !seen[email.ID] {
+		thread.Items = append(thread.Items, incomingThreadItem(email))
+	}
+	for _, item := range sent {
+		thread.Items = append(thread.Items, &MailThreadItem{
+			ID:        item.ID,
+			Direction: "uitgaand",
+			MessageID: item.MessageID,
+			To:        item.Ontvanger,
+			Subject:   item.Onderwerp,
+			Body:      item.Inhoud,
+			Status:    item.Status,
+			Date:      item.VerzondOp,
+		})
+	}
+
+	sort.SliceStable(thread.Items, func(i, j int) bool {
+		return thread.Items[i].Date.Before(thread.Items[j].Date)
+	})
+	return thread, nil
+}
+
+// getEmail haalt de inkomende email op en vertaalt een ontbrekende email naar ErrMailNotFound
+func (s *MailReplyService) getEmail(ctx context.Context, emailID string) (*models.IncomingEmail, error) {
+	email, err := s.incomingRepo.GetByID(ctx, emailID)
+	if err != nil {
+		return nil, fmt.Errorf("kan email niet ophalen: %w", err)
+	}
+	if email == nil {
+		return nil, ErrMailNotFound
+	}
+	return email, nil
+}
+
+// send verstuurt het bericht via het kanaal van het account waarop de originele email binnenkwam.
+// De email service legt de verzending vast in verzonden_emails, gekoppeld aan het gesprek.
+func (s *MailReplyService) send(ctx context.Context, email *models.IncomingEmail, msg *EmailMessage, emailType string) (*models.VerzondEmail, error) {
+	channel := models.EmailChannelDefault
+	if email.AccountType == "inschrijving" {
+		channel = models.EmailChannelRegistration
+	}
+
+	threadID := email.ThreadID
+	if threadID == "" {
+		threadID = MailThreadID(email.MessageID, email.InReplyTo, email.References)
+	}
+
+	record, sendErr := s.emailService.SendMessage(channel, msg, EmailMetadata{
+		Type:            emailType,
+		IncomingEmailID: &email.ID,
+		ThreadID:        threadID,
+	})
+	if sendErr != nil {
+		logger.Error("Versturen van antwoord mislukt", "error", sendErr, "email_id", email.ID, "type", emailType)
+		return record, fmt.Errorf("versturen mislukt: %w", sendErr)
+	}
+
+	logger.Info("Antwoord op inkomende email verstuurd", "email_id", email.ID, "type", emailType, "ontvanger", msg.To)
+	return record, nil
+}
+
+// incomingThreadItem zet een inkomende email om naar een gespreksitem
+func incomingThreadItem(email *models.IncomingEmail) *MailThreadItem {
+	return &MailThreadItem{
+		ID:        email.ID,
+		Direction: "inkomend",
+		MessageID: email.MessageID,
+		From:      email.From,
+		To:        email.To,
+		Subject:   email.Subject,
+		Body:      email.Body,
+		Date:      email.ReceivedAt,
+	}
+}
+
+// newMessageID genereert een Message-ID op het domein van het account dat de email ontving
+func newMessageID(accountAddress string) string {
+	domain := "dekoninklijkeloop.nl"
+	if at := strings.LastIndex(accountAddress, "@"); at >= 0 && at < len(accountAddress)-1 {
+		domain = strings.Trim(accountAddress[at+1:], "> ")
+	}
+	return fmt.Sprintf("<%s@%s>", uuid.NewString(), domain)
+}
+
+// prefixSubject zet "Re: " of "Fwd: " voor het onderwerp, tenzij het er al staat
+func prefixSubject(prefix, subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), strings.ToLower(prefix)) {
+		return subject
+	}
+	return prefix + subject
+}
+
+// replyBodyHTML zet de tekst van de medewerker om naar veilige HTML. Platte tekst
+// behoudt de regeleindes; HTML wordt gesanitized.
+func replyBodyHTML(body string) string {
+	if strings.Contains(body, "<") && strings.Contains(body, ">") {
+		return bluemonday.UGCPolicy().Sanitize(body)
+	}
+	return "<p>" + textToHTML(body) + "</p>"
+}
+
+// originalHTML geeft de body van de originele email als HTML terug
+func originalHTML(email *models.IncomingEmail) string {
+	if strings.HasPrefix(email.ContentType, "text/html") {
+		return email.Body
+	}
+	return textToHTML(email.Body)
+}
+
+// quoteOriginal citeert de originele email onder een antwoord
+func quoteOriginal(email *models.IncomingEmail) string {
+	return fmt.Sprintf(
+		`<br><div>Op %s schreef %s:</div><blockquote style="margin:0 0 0 .8ex;border-left:1px solid #ccc;padding-left:1ex">%s</blockquote>`,
+		email.ReceivedAt.Format("02-01-2006 15:04"),
+		html.EscapeString(email.From),
+		originalHTML(email))
+}
+
+// forwardOriginal voegt de originele email met zijn kopregels toe aan een doorgestuurd bericht
+func forwardOriginal(email *models.IncomingEmail) string {
+	return fmt.Sprintf(
+		`<br><div>---------- Doorgestuurd bericht ----------<br>Van: %s<br>Datum: %s<br>Onderwerp: %s<br>Aan: %s</div><br>%s`,
+		html.EscapeString(email.From),
+		email.ReceivedAt.Format("02-01-2006 15:04"),
+		html.EscapeString(email.Subject),
+		html.EscapeString(email.To),
+		originalHTML(email))
+}
+
+// textToHTML escapet platte tekst en zet regeleindes om naar <br>
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n")
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
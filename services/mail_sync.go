@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+)
+
+// MailAccountSyncConfig bepaalt hoe acties uit het dashboard naar de mailserver worden teruggeschreven
+type MailAccountSyncConfig struct {
+	WriteBack       bool   // Acties terugschrijven naar de mailserver
+	ProcessedFolder string // Verwerkte mails hierheen verplaatsen; leeg betekent alleen \Seen zetten
+	TrashFolder     string // Verwijderde mails hierheen verplaatsen; leeg betekent alleen dit bericht expungen
+}
+
+// MailReconcileResult bevat de uitkomst van een reconciliatie met de mailserver
+type MailReconcileResult struct {
+	Checked           int `json:"checked"`
+	MarkedProcessed   int `json:"marked_processed"`
+	MarkedUnprocessed int `json:"marked_unprocessed"`
+	Retried           int `json:"retried"`
+	Missing           int `json:"missing"`
+}
+
+// reconcileWindow bepaalt hoe ver terug de flags van berichten worden vergeleken
+const reconcileWindow = 30 * 24 * time.Hour
+
+// ConfigureAccountSync stelt de terugschrijf-instellingen van een account in
+func (f *MailFetcher) ConfigureAccountSync(accountType string, cfg MailAccountSyncConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, account := range f.accounts {
+		if account.Type == accountType {
+			account.Sync = cfg
+			logger.Info("IMAP synchronisatie geconfigureerd",
+				"type", accountType,
+				"write_back", cfg.WriteBack,
+				"processed_folder", cfg.ProcessedFolder,
+				"trash_folder", cfg.TrashFolder)
+		}
+	}
+}
+
+// findAccount zoekt het account dat bij een account type hoort
+func (f *MailFetcher) findAccount(accountType string) *MailAccount {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, account := range f.accounts {
+		if account.Type == accountType {
+			return account
+		}
+	}
+	return nil
+}
+
+// MarkProcessedOnServer zet \Seen op het bericht en verplaatst het naar de verwerkt-folder
+// van het account. Mailbox, UID en UIDVALIDITY van de email worden bijgewerkt naar de
+// nieuwe locatie; de aanroeper is verantwoordelijk voor het opslaan van de email. Wordt het
+// verplaatste bericht niet teruggevonden, dan wordt de UID leeg en slaan latere acties de
+// mailserver over.
+func (f *MailFetcher) MarkProcessedOnServer(email *models.IncomingEmail) error {
+	account := f.findAccount(email.AccountType)
+	if account == nil || !account.Sync.WriteBack || !onServer(email) {
+		return nil
+	}
+
+	c, seqset, err := selectMessage(account, email)
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.UidStore(seqset, item, []interface{}{imap.SeenFlag}, nil); err != nil {
+		return fmt.Errorf("kan \\Seen niet zetten: %w", err)
+	}
+
+	target := account.Sync.ProcessedFolder
+	if target == "" || target == email.Mailbox {
+		return nil
+	}
+
+	if err := moveMessage(c, seqset, target); err != nil {
+		return err
+	}
+
+	// Zoek de nieuwe UID op in de doelmap, zodat latere acties het bericht terugvinden
+	previousMailbox := email.Mailbox
+	email.Mailbox = target
+	uidValidity, uid, err := locateMessage(c, target, email.MessageID)
+	if err != nil || uid == 0 {
+		logger.Warn("Verplaatst bericht niet teruggevonden in doelmap", "error", err, "message_id", email.MessageID, "mailbox", target)
+		email.UIDValidity = uidValidity
+		email.UID = ""
+		return nil
+	}
+
+	email.UIDValidity = uidValidity
+	email.UID = strconv.FormatUint(uint64(uid), 10)
+	logger.Info("Bericht verplaatst op mailserver", "id", email.ID, "van", previousMailbox, "naar", target)
+	return nil
+}
+
+// DeleteOnServer verwijdert het bericht van de mailserver, of verplaatst het naar de
+// prullenbak als het account een TrashFolder heeft. Een bericht zonder UID is niet meer op de
+// server te vinden en wordt alleen lokaal verwijderd.
+func (f *MailFetcher) DeleteOnServer(email *models.IncomingEmail) error {
+	account := f.findAccount(email.AccountType)
+	if account == nil || !account.Sync.WriteBack || !onServer(email) {
+		return nil
+	}
+
+	c, seqset, err := selectMessage(account, email)
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	if trash := account.Sync.TrashFolder; trash != "" && trash != email.Mailbox {
+		return moveMessage(c, seqset, trash)
+	}
+
+	if err := deleteMessage(c, seqset); err != nil {
+		return err
+	}
+
+	logger.Info("Bericht verwijderd van mailserver", "id", email.ID, "mailbox", email.Mailbox, "uid", email.UID)
+	return nil
+}
+
+// Reconcile brengt de lokale administratie en de mailserver in lijn. Eerst worden acties
+// die eerder niet konden worden teruggeschreven opnieuw geprobeerd; daarna worden de
+// \Seen flags van recente berichten van de server overgenomen.
+func (f *MailFetcher) Reconcile(ctx context.Context, repo repository.IncomingEmailRepository) (*MailReconcileResult, error) {
+	result := &MailReconcileResult{}
+
+	pending, err := repo.FindSyncPending(ctx)
+	if err != nil {
+		return result, fmt.Errorf("kan openstaande synchronisaties niet ophalen: %w", err)
+	}
+	for _, email := range pending {
+		if email.ImapDeletePending {
+			if err := f.DeleteOnServer(email); err != nil {
+				logger.Warn("Verwijderen van mailserver opnieuw mislukt", "error", err, "id", email.ID)
+				continue
+			}
+			if err := repo.Delete(ctx, email.ID); err != nil {
+				logger.Error("Kon email na verwijderen van mailserver niet verwijderen", "error", err, "id", email.ID)
+				continue
+			}
+			result.Retried++
+			continue
+		}
+
+		if err := f.MarkProcessedOnServer(email); err != nil {
+			logger.Warn("Terugschrijven naar mailserver opnieuw mislukt", "error", err, "id", email.ID)
+			continue
+		}
+		email.ImapSyncPending = false
+		if err := repo.Update(ctx, email); err != nil {
+			logger.Error("Kon email na synchronisatie niet bijwerken", "error", err, "id", email.ID)
+			continue
+		}
+		result.Retried++
+	}
+
+	f.mu.RLock()
+	accounts := make([]*MailAccount, len(f.accounts))
+	copy(accounts, f.accounts)
+	f.mu.RUnlock()
+
+	for _, account := range accounts {
+		if err := f.reconcileAccount(ctx, repo, account, result); err != nil {
+			logger.Error("Reconciliatie van account mislukt", "error", err, "account", account.Type)
+		}
+	}
+
+	return result, nil
+}
+
+// reconcileAccount vergelijkt de flags van alle opgehaalde mailboxen van één account.
+// Zonder write-back is de mailserver niet de bron van de verwerkt-status en blijft die lokaal.
+func (f *MailFetcher) reconcileAccount(ctx context.Context, repo repository.IncomingEmailRepository, account *MailAccount, result *MailReconcileResult) error {
+	if !account.Sync.WriteBack {
+		return nil
+	}
+
+	c, err := connectIMAP(account)
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
+
+	folders := account.Folders
+	if len(folders) == 0 {
+		folders = []string{"INBOX"}
+	}
+
+	for _, folder := range folders {
+		mbox, err := c.Select(folder, true)
+		if err != nil {
+			logger.Warn("Kan mailbox niet selecteren voor reconciliatie", "error", err, "mailbox", folder)
+			continue
+		}
+
+		emails, err := repo.ListByMailbox(ctx, account.Type, folder, mbox.UidValidity, time.Now().Add(-reconcileWindow))
+		if err != nil {
+			return err
+		}
+
+		byUID := make(map[uint32]*models.IncomingEmail, len(emails))
+		seqset := new(imap.SeqSet)
+		for _, email := range emails {
+			uid, err := strconv.ParseUint(email.UID, 10, 32)
+			if err != nil {
+				continue
+			}
+			byUID[uint32(uid)] = email
+			seqset.AddNum(uint32(uid))
+		}
+		if len(byUID) == 0 {
+			continue
+		}
+
+		messages := make(chan *imap.Message, 10)
+		done := make(chan error, 1)
+		go func() {
+			done <- c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, imap.FetchFlags}, messages)
+		}()
+
+		seenOnServer := make(map[uint32]bool, len(byUID))
+		for msg := range messages {
+			seen := false
+			for _, flag := range msg.Flags {
+				if flag == imap.SeenFlag {
+					seen = true
+				}
+			}
+			seenOnServer[msg.Uid] = seen
+		}
+		if err := <-done; err != nil {
+			return fmt.Errorf("ophalen flags mislukt: %w", err)
+		}
+
+		for uid, email := range byUID {
+			result.Checked++
+			seen, exists := seenOnServer[uid]
+			if !exists {
+				result.Missing++
+				continue
+			}
+			if email.ImapSyncPending || email.ImapDeletePending || seen == email.IsProcessed {
+				continue
+			}
+
+			email.IsProcessed = seen
+			if seen {
+				now := time.Now()
+				email.ProcessedAt = &now
+				result.MarkedProcessed++
+			} else {
+				email.ProcessedAt = nil
+				result.MarkedUnprocessed++
+			}
+			if err := repo.Update(ctx, email); err != nil {
+				logger.Error("Kon email status niet overnemen van mailserver", "error", err, "id", email.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// onServer geeft aan of de email nog een UID op de mailserver heeft. Na een verplaatsing die niet
+// kon worden teruggevonden is de UID leeg.
+func onServer(email *models.IncomingEmail) bool {
+	if email.UID == "" {
+		logger.Debug("Email heeft geen UID op de mailserver, serveractie overgeslagen", "id", email.ID)
+		return false
+	}
+	return true
+}
+
+// connectIMAP maakt een ingelogde verbinding met de IMAP server van een account
+func connectIMAP(account *MailAccount) (*client.Client, error) {
+	imapAddr := fmt.Sprintf("%s:%d", account.Host, account.Port)
+	c, err := client.DialTLS(imapAddr, nil)
+	if err != nil {
+		return nil, fmt.Errorf("kan niet verbinden met IMAP server: %w", err)
+	}
+
+	if err := c.Login(account.Username, account.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("login mislukt: %w", err)
+	}
+
+	return c, nil
+}
+
+// selectMessage verbindt, selecteert de mailbox van de email (schrijfbaar) en controleert
+// of de UIDVALIDITY nog overeenkomt, zodat nooit een verkeerd bericht wordt aangepast
+func selectMessage(account *MailAccount, email *models.IncomingEmail) (*client.Client, *imap.SeqSet, error) {
+	uid, err := strconv.ParseUint(email.UID, 10, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ongeldige UID %q: %w", email.UID, err)
+	}
+
+	mailbox := email.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+
+	c, err := connectIMAP(account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mbox, err := c.Select(mailbox, false)
+	if err != nil {
+		c.Logout()
+		return nil, nil, fmt.Errorf("kan mailbox %s niet selecteren: %w", mailbox, err)
+	}
+
+	if email.UIDValidity != 0 && mbox.UidValidity != email.UIDValidity {
+		c.Logout()
+		return nil, nil, fmt.Errorf("UIDVALIDITY van %s gewijzigd (%d != %d)", mailbox, mbox.UidValidity, email.UIDValidity)
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uint32(uid))
+	return c, seqset, nil
+}
+
+// moveMessage verplaatst berichten naar een map en maakt de map aan als die nog niet bestaat.
+// Zonder MOVE op de server wordt gekopieerd en daarna alleen het origineel verwijderd.
+func moveMessage(c *client.Client, seqset *imap.SeqSet, target string) error {
+	move := func() error {
+		if ok, err := c.Support("MOVE"); err != nil || ok {
+			return c.UidMove(seqset, target)
+		}
+		if err := c.UidCopy(seqset, target); err != nil {
+			return err
+		}
+		return deleteMessage(c, seqset)
+	}
+
+	if err := move(); err != nil {
+		// De doelmap bestaat mogelijk nog niet; aanmaken en opnieuw proberen
+		if createErr := c.Create(target); createErr != nil {
+			return fmt.Errorf("verplaatsen naar %s mislukt: %w", target, err)
+		}
+		if err := move(); err != nil {
+			return fmt.Errorf("verplaatsen naar %s mislukt: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// deleteMessage zet \Deleted op de berichten en expunget alleen die UIDs met UID EXPUNGE.
+// Een gewone EXPUNGE zou ook andere berichten met \Deleted in de mailbox definitief verwijderen;
+// zonder UIDPLUS blijft het bericht daarom gemarkeerd staan tot een mailclient expunget.
+func deleteMessage(c *client.Client, seqset *imap.SeqSet) error {
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := c.UidStore(seqset, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+		return fmt.Errorf("kan \\Deleted niet zetten: %w", err)
+	}
+
+	ok, err := c.Support("UIDPLUS")
+	if err != nil {
+		return fmt.Errorf("kan capabilities niet opvragen: %w", err)
+	}
+	if !ok {
+		logger.Warn("Mailserver ondersteunt geen UIDPLUS, bericht alleen als verwijderd gemarkeerd", "uids", seqset.String())
+		return nil
+	}
+
+	status, err := c.Execute(&commands.Uid{Cmd: &uidExpunge{SeqSet: seqset}}, nil)
+	if err == nil {
+		err = status.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("UID EXPUNGE mislukt: %w", err)
+	}
+	return nil
+}
+
+// uidExpunge is het EXPUNGE deel van UID EXPUNGE uit de UIDPLUS extensie (RFC 4315)
+type uidExpunge struct {
+	SeqSet *imap.SeqSet
+}
+
+// Command implementeert imap.Commander
+func (cmd *uidExpunge) Command() *imap.Command {
+	return &imap.Command{
+		Name:      "EXPUNGE",
+		Arguments: []interface{}{cmd.SeqSet},
+	}
+}
+
+// locateMessage zoekt een bericht op Message-ID in een map en geeft UIDVALIDITY en UID terug
+func locateMessage(c *client.Client, mailbox, messageID string) (uint32, uint32, error) {
+	mbox, err := c.Select(mailbox, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if messageID == "" {
+		return mbox.UidValidity, 0, nil
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Header.Add("Message-ID", messageID)
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return mbox.UidValidity, 0, err
+	}
+
+	var newest uint32
+	for _, uid := range uids {
+		if uid > newest {
+			newest = uid
+		}
+	}
+	return mbox.UidValidity, newest, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+)
+
+// ParseMessageIDs haalt alle Message-ID's (<...>) uit een In-Reply-To of References header
+func ParseMessageIDs(value string) []string {
+	var ids []string
+	for {
+		start := strings.Index(value, "<")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(value[start:], ">")
+		if end < 0 {
+			break
+		}
+		if id := strings.TrimSpace(value[start : start+end+1]); len(id) > 2 {
+			ids = append(ids, id)
+		}
+		value = value[start+end+1:]
+	}
+	return ids
+}
+
+// MailThreadID bepaalt het gesprek waar een bericht bij hoort. Volgens RFC 5322 staat het
+// oudste bericht vooraan in References; zonder References wordt In-Reply-To gebruikt en
+// anders begint het bericht zelf een nieuw gesprek.
+func MailThreadID(messageID, inReplyTo, references string) string {
+	if ids := ParseMessageIDs(references); len(ids) > 0 {
+		return ids[0]
+	}
+	if ids := ParseMessageIDs(inReplyTo); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.TrimSpace(messageID)
+}
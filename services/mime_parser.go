@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"dklautomationgo/logger"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxMIMEDepth begrenst het nesten van multipart delen
+	maxMIMEDepth = 10
+
+	// maxAttachmentSize is de maximale grootte van een bijlage die wordt opgeslagen
+	maxAttachmentSize = 25 * 1024 * 1024
+)
+
+// ParsedMail bevat de gedecodeerde inhoud van een MIME bericht
+type ParsedMail struct {
+	Text        string
+	HTML        string
+	Attachments []*ParsedAttachment
+}
+
+// ParsedAttachment is een bijlage of inline deel uit een MIME bericht
+type ParsedAttachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+// mimeWordDecoder decodeert RFC 2047 encoded-words met ondersteuning voor niet-UTF-8 charsets
+var mimeWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// DecodeMIMEHeader decodeert een header die RFC 2047 encoded-words kan bevatten
+// (bijv. "=?ISO-8859-1?Q?Caf=E9?="). Bij een fout wordt de originele waarde teruggegeven.
+func DecodeMIMEHeader(value string) string {
+	if value == "" {
+		return value
+	}
+	decoded, err := mimeWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// DecodeAddressHeader decodeert een adres header zoals From naar "Naam <adres>".
+// Lukt het parsen niet, dan wordt alleen de RFC 2047 decodering toegepast.
+func DecodeAddressHeader(value string) string {
+	if value == "" {
+		return value
+	}
+	parser := &mail.AddressParser{WordDecoder: mimeWordDecoder}
+	addr, err := parser.Parse(value)
+	if err != nil {
+		return DecodeMIMEHeader(value)
+	}
+	if addr.Name == "" {
+		return addr.Address
+	}
+	return fmt.Sprintf("%s <%s>", addr.Name, addr.Address)
+}
+
+// ParseMIMEMessage loopt recursief door alle delen van een bericht en verzamelt de
+// tekst- en HTML-versie van de body en de bijlagen. Transfer encodings en charsets
+// worden gedecodeerd, zodat Text en HTML altijd UTF-8 zijn.
+func ParseMIMEMessage(header mail.Header, body io.Reader) (*ParsedMail, error) {
+	result := &ParsedMail{}
+	if err := walkMIMEPart(textproto.MIMEHeader(header), body, result, 0); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkMIMEPart verwerkt één MIME deel en daalt af in multipart delen
+func walkMIMEPart(header textproto.MIMEHeader, body io.Reader, result *ParsedMail, depth int) error {
+	if depth > maxMIMEDepth {
+		return fmt.Errorf("MIME structuur te diep genest")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// Zonder (geldige) Content-Type geldt volgens RFC 2045 text/plain
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return fmt.Errorf("multipart deel zonder boundary")
+		}
+
+		reader := multipart.NewReader(body, boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				// Een afgebroken multipart levert wel de delen op die al gelezen zijn
+				if result.Text != "" || result.HTML != "" || len(result.Attachments) > 0 {
+					return nil
+				}
+				return fmt.Errorf("kan multipart deel niet lezen: %w", err)
+			}
+
+			if err := walkMIMEPart(part.Header, part, result, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("kan deel niet decoderen: %w", err)
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := DecodeMIMEHeader(dispParams["filename"])
+	if filename == "" {
+		filename = DecodeMIMEHeader(params["name"])
+	}
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if isText && disposition != "attachment" && filename == "" {
+		text := decodeCharset(data, params["charset"])
+		if mediaType == "text/html" {
+			// Bij meerdere HTML delen (bijv. multipart/mixed) worden ze samengevoegd
+			result.HTML += text
+		} else {
+			if result.Text != "" {
+				result.Text += "\n"
+			}
+			result.Text += text
+		}
+		return nil
+	}
+
+	if len(data) > maxAttachmentSize {
+		logger.Warn("Bijlage overgeslagen, te groot", "filename", filename, "size", len(data))
+		return nil
+	}
+
+	contentID := strings.Trim(header.Get("Content-Id"), "<> ")
+	if filename == "" {
+		filename = defaultAttachmentName(mediaType, len(result.Attachments)+1)
+	}
+
+	result.Attachments = append(result.Attachments, &ParsedAttachment{
+		Filename:    filepath.Base(filename),
+		ContentType: mediaType,
+		ContentID:   contentID,
+		Inline:      disposition == "inline" || (disposition == "" && contentID != ""),
+		Data:        data,
+	})
+	return nil
+}
+
+// decodeTransferEncoding geeft een reader terug die de Content-Transfer-Encoding decodeert
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	case "base64":
+		// Sommige mailers zetten witruimte in base64 regels; die wordt genegeerd
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	default:
+		return r
+	}
+}
+
+// decodeCharset zet tekst in de opgegeven charset om naar UTF-8
+func decodeCharset(data []byte, label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || label == "utf-8" || label == "utf8" || label == "us-ascii" {
+		return string(data)
+	}
+
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+	converted, err := io.ReadAll(reader)
+	if err != nil {
+		return string(data)
+	}
+	return string(converted)
+}
+
+// defaultAttachmentName bedenkt een bestandsnaam voor bijlagen zonder naam
+func defaultAttachmentName(mediaType string, index int) string {
+	if mediaType == "message/rfc822" {
+		return fmt.Sprintf("bericht-%d.eml", index)
+	}
+	ext := ""
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("bijlage-%d%s", index, ext)
+}
+
+// base64Cleaner filtert witruimte uit een base64 stroom
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	j := 0
+	for i := 0; i < n; i++ {
+		switch p[i] {
+		case '\r', '\n', ' ', '\t':
+			continue
+		}
+		p[j] = p[i]
+		j++
+	}
+	if j == 0 && n > 0 && err == nil {
+		return c.Read(p)
+	}
+	return j, err
+}
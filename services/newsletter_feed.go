@@ -0,0 +1,429 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// FeedSource levert nieuwsitems voor de automatische nieuwsbrief
+type FeedSource interface {
+	// Name geeft de naam van de bron terug, voor logging en het veld Source van de items
+	Name() string
+	// Fetch haalt de items van de bron op
+	Fetch(ctx context.Context) ([]models.NewsItem, error)
+}
+
+// maxFeedSize begrenst hoeveel bytes van een feed worden gelezen
+const maxFeedSize = 5 << 20
+
+// maxDescriptionLength is de maximale lengte van een beschrijving in de nieuwsbrief
+const maxDescriptionLength = 300
+
+// feedDateLayouts zijn de datumnotaties die in feeds voorkomen
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// minimal RSS parsing structures
+type rss struct {
+	Channel rssChannel `xml:"channel"`
+}
+type rssChannel struct {
+	Items []rssItem `xml:"item"`
+}
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Category    string `xml:"category"`
+}
+
+// minimal Atom parsing structures
+type atomFeed struct {
+	Entries []atomEntry `xml:"entry"`
+}
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// minimal JSON Feed (https://jsonfeed.org) parsing structures
+type jsonFeed struct {
+	Items []jsonFeedItem `json:"items"`
+}
+type jsonFeedItem struct {
+	ID            interface{} `json:"id"`
+	URL           string      `json:"url"`
+	Title         string      `json:"title"`
+	Summary       string      `json:"summary"`
+	ContentText   string      `json:"content_text"`
+	ContentHTML   string      `json:"content_html"`
+	DatePublished string      `json:"date_published"`
+	DateModified  string      `json:"date_modified"`
+	Tags          []string    `json:"tags"`
+}
+
+// ParseFeed zet een RSS, Atom of JSON feed om naar nieuwsitems. Met een leeg formaat wordt
+// het formaat uit de inhoud afgeleid.
+func ParseFeed(format string, data []byte) ([]models.NewsItem, error) {
+	if format == "" {
+		format = detectFeedFormat(data)
+	}
+
+	switch format {
+	case models.NewsletterSourceRSS:
+		return parseRSS(data)
+	case models.NewsletterSourceAtom:
+		return parseAtom(data)
+	case models.NewsletterSourceJSON:
+		return parseJSONFeed(data)
+	default:
+		return nil, fmt.Errorf("onbekend feed formaat: %s", format)
+	}
+}
+
+// detectFeedFormat leidt het formaat van een feed af uit de inhoud
+func detectFeedFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return models.NewsletterSourceJSON
+	}
+	if bytes.Contains(trimmed, []byte("<feed")) {
+		return models.NewsletterSourceAtom
+	}
+	return models.NewsletterSourceRSS
+}
+
+func parseRSS(data []byte) ([]models.NewsItem, error) {
+	var doc rss
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("ongeldige RSS feed: %w", err)
+	}
+
+	items := make([]models.NewsItem, 0, len(doc.Channel.Items))
+	for _, it := range doc.Channel.Items {
+		items = append(items, models.NewsItem{
+			ID:          strings.TrimSpace(it.GUID),
+			Title:       cleanFeedText(it.Title),
+			Description: cleanFeedDescription(it.Description),
+			Link:        strings.TrimSpace(it.Link),
+			PubDate:     parseFeedDate(it.PubDate),
+			Category:    strings.TrimSpace(it.Category),
+		})
+	}
+	return items, nil
+}
+
+func parseAtom(data []byte) ([]models.NewsItem, error) {
+	var doc atomFeed
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("ongeldige Atom feed: %w", err)
+	}
+
+	items := make([]models.NewsItem, 0, len(doc.Entries))
+	for _, entry := range doc.Entries {
+		item := models.NewsItem{
+			ID:          strings.TrimSpace(entry.ID),
+			Title:       cleanFeedText(entry.Title),
+			Description: cleanFeedDescription(entry.Summary),
+			PubDate:     parseFeedDate(entry.Published),
+		}
+		if item.Description == "" {
+			item.Description = cleanFeedDescription(entry.Content)
+		}
+		if item.PubDate.IsZero() {
+			item.PubDate = parseFeedDate(entry.Updated)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				item.Link = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		if len(entry.Categories) > 0 {
+			item.Category = entry.Categories[0].Label
+			if item.Category == "" {
+				item.Category = entry.Categories[0].Term
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func parseJSONFeed(data []byte) ([]models.NewsItem, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("ongeldige JSON feed: %w", err)
+	}
+
+	items := make([]models.NewsItem, 0, len(doc.Items))
+	for _, it := range doc.Items {
+		item := models.NewsItem{
+			Title:       cleanFeedText(it.Title),
+			Description: cleanFeedDescription(it.Summary),
+			Link:        strings.TrimSpace(it.URL),
+			PubDate:     parseFeedDate(it.DatePublished),
+		}
+		if it.ID != nil {
+			item.ID = fmt.Sprint(it.ID)
+		}
+		if item.Description == "" {
+			item.Description = cleanFeedDescription(it.ContentText)
+		}
+		if item.Description == "" {
+			item.Description = cleanFeedDescription(it.ContentHTML)
+		}
+		if item.PubDate.IsZero() {
+			item.PubDate = parseFeedDate(it.DateModified)
+		}
+		if len(it.Tags) > 0 {
+			item.Category = strings.TrimSpace(it.Tags[0])
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseFeedDate probeert de bekende datumnotaties; een onbekende datum geeft de nulwaarde
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// cleanFeedText verwijdert HTML en overbodige witruimte uit tekst uit een feed
+func cleanFeedText(value string) string {
+	text := html.UnescapeString(bluemonday.StrictPolicy().Sanitize(value))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// cleanFeedDescription maakt een platte, ingekorte beschrijving van de HTML uit een feed
+func cleanFeedDescription(value string) string {
+	text := cleanFeedText(value)
+	runes := []rune(text)
+	if len(runes) <= maxDescriptionLength {
+		return text
+	}
+	cut := string(runes[:maxDescriptionLength])
+	if i := strings.LastIndex(cut, " "); i > maxDescriptionLength/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+// HTTPFeedSource haalt een RSS, Atom of JSON feed op via HTTP
+type HTTPFeedSource struct {
+	name   string
+	url    string
+	format string
+	client *http.Client
+}
+
+// NewHTTPFeedSource maakt een nieuwe HTTPFeedSource. Met een leeg formaat wordt het formaat
+// uit de inhoud afgeleid.
+func NewHTTPFeedSource(name, url, format string) *HTTPFeedSource {
+	return &HTTPFeedSource{
+		name:   name,
+		url:    url,
+		format: format,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name geeft de naam van de bron terug
+func (s *HTTPFeedSource) Name() string {
+	return s.name
+}
+
+// Fetch haalt de feed op en zet hem om naar nieuwsitems
+func (s *HTTPFeedSource) Fetch(ctx context.Context) ([]models.NewsItem, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("feed gaf status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+	return ParseFeed(s.format, data)
+}
+
+// InternalFeedSource zet eigen content van de website die zichtbaar is gemaakt om naar
+// nieuwsitems: foto's, albums, video's, radio-opnames of het programma
+type InternalFeedSource struct {
+	name     string
+	kind     string
+	linkBase string
+	repos    *repository.Repository
+}
+
+// NewInternalFeedSource maakt een nieuwe InternalFeedSource. linkBase is de pagina op de
+// website waar de items naartoe linken; zonder linkBase linkt een item naar zijn eigen media.
+func NewInternalFeedSource(name, kind, linkBase string, repos *repository.Repository) *InternalFeedSource {
+	return &InternalFeedSource{
+		name:     name,
+		kind:     kind,
+		linkBase: strings.TrimRight(linkBase, "/"),
+		repos:    repos,
+	}
+}
+
+// Name geeft de naam van de bron terug
+func (s *InternalFeedSource) Name() string {
+	return s.name
+}
+
+// Fetch haalt de zichtbare content op. De datum van een item is het moment van de laatste
+// wijziging, zodat content die net zichtbaar is gemaakt als nieuw geldt.
+func (s *InternalFeedSource) Fetch(ctx context.Context) ([]models.NewsItem, error) {
+	var items []models.NewsItem
+
+	switch s.kind {
+	case models.NewsletterSourcePhotos:
+		photos, err := s.repos.Photo.ListVisible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, photo := range photos {
+			title := photo.Title
+			if title == "" {
+				title = photo.AltText
+			}
+			items = append(items, models.NewsItem{
+				ID:          "photo:" + photo.ID,
+				Title:       title,
+				Description: photo.Description,
+				Link:        s.link(photo.ID, photo.URL),
+				PubDate:     photo.UpdatedAt,
+			})
+		}
+	case models.NewsletterSourceAlbums:
+		albums, err := s.repos.Album.ListVisibleWithCovers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, album := range albums {
+			fallback := ""
+			if album.CoverPhoto != nil {
+				fallback = album.CoverPhoto.URL
+			}
+			items = append(items, models.NewsItem{
+				ID:          "album:" + album.ID,
+				Title:       album.Title,
+				Description: album.Description,
+				Link:        s.link(album.ID, fallback),
+				PubDate:     album.UpdatedAt,
+			})
+		}
+	case models.NewsletterSourceVideos:
+		videos, err := s.repos.Video.ListVisible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, video := range videos {
+			items = append(items, models.NewsItem{
+				ID:          "video:" + video.ID,
+				Title:       video.Title,
+				Description: video.Description,
+				Link:        s.link(video.ID, video.URL),
+				PubDate:     video.UpdatedAt,
+			})
+		}
+	case models.NewsletterSourceRadio:
+		recordings, err := s.repos.RadioRecording.ListVisible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, recording := range recordings {
+			items = append(items, models.NewsItem{
+				ID:          "radio:" + recording.ID,
+				Title:       recording.Title,
+				Description: recording.Description,
+				Link:        s.link(recording.ID, recording.AudioURL),
+				PubDate:     recording.UpdatedAt,
+			})
+		}
+	case models.NewsletterSourceProgram:
+		schedules, err := s.repos.ProgramSchedule.ListVisible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, schedule := range schedules {
+			items = append(items, models.NewsItem{
+				ID:          "program:" + schedule.ID,
+				Title:       strings.TrimSpace(schedule.Time + " " + schedule.EventDescription),
+				Description: schedule.EventDescription,
+				Link:        s.linkBase,
+				PubDate:     schedule.UpdatedAt,
+				Category:    schedule.Category,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("onbekend type eigen content: %s", s.kind)
+	}
+
+	return items, nil
+}
+
+// link geeft de link naar een item op de website, of de link naar de media zelf als er geen
+// pagina is ingesteld
+func (s *InternalFeedSource) link(id, fallback string) string {
+	if s.linkBase == "" {
+		return fallback
+	}
+	return s.linkBase + "#" + id
+}
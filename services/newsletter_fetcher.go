@@ -2,10 +2,10 @@ package services
 
 import (
 	"context"
+	"dklautomationgo/logger"
 	"dklautomationgo/models"
-	"encoding/xml"
-	"io"
-	"net/http"
+	"dklautomationgo/repository"
+	"fmt"
 	"os"
 	"sort"
 	"strings"
@@ -14,6 +14,10 @@ import (
 
 type NewsletterFetcher struct {
 	sources []string
+
+	// Bronnen uit de database; eigen content wordt uit de content repositories gehaald
+	sourceRepo repository.NewsletterSourceRepository
+	repos      *repository.Repository
 }
 
 func NewNewsletterFetcher() *NewsletterFetcher {
@@ -31,61 +35,100 @@ func NewNewsletterFetcher() *NewsletterFetcher {
 	return &NewsletterFetcher{sources: sources}
 }
 
-// minimal RSS parsing structures
-type rss struct {
-	Channel rssChannel `xml:"channel"`
-}
-type rssChannel struct {
-	Items []rssItem `xml:"item"`
-}
-type rssItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-	Category    string `xml:"category"`
+// SetSourceRepository stelt de bronnen uit de database in, naast de feeds uit NEWSLETTER_SOURCES.
+// De bronnen worden bij elke run opnieuw gelezen, zodat wijzigingen direct gelden.
+func (f *NewsletterFetcher) SetSourceRepository(sourceRepo repository.NewsletterSourceRepository, repos *repository.Repository) {
+	f.sourceRepo = sourceRepo
+	f.repos = repos
 }
 
-func (f *NewsletterFetcher) Fetch(ctx context.Context) ([]models.NewsItem, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	var items []models.NewsItem
+// configuredSources geeft de bronnen uit NEWSLETTER_SOURCES en de actieve bronnen uit de database
+func (f *NewsletterFetcher) configuredSources(ctx context.Context) []*models.NewsletterSource {
+	var sources []*models.NewsletterSource
 	for _, src := range f.sources {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+		sources = append(sources, &models.NewsletterSource{Naam: src, URL: src})
+	}
+
+	if f.sourceRepo != nil {
+		active, err := f.sourceRepo.ListActive(ctx)
 		if err != nil {
+			logger.Error("Kon nieuwsbrief bronnen niet ophalen", "error", err)
+		} else {
+			sources = append(sources, active...)
+		}
+	}
+	return sources
+}
+
+// NewFeedSource maakt de FeedSource voor een geconfigureerde bron
+func (f *NewsletterFetcher) NewFeedSource(cfg *models.NewsletterSource) (FeedSource, error) {
+	if cfg.IsInternal() {
+		if f.repos == nil {
+			return nil, fmt.Errorf("eigen content is niet geconfigureerd")
+		}
+		return NewInternalFeedSource(cfg.Naam, cfg.Type, cfg.URL, f.repos), nil
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("bron %s heeft geen URL", cfg.Naam)
+	}
+	return NewHTTPFeedSource(cfg.Naam, cfg.URL, cfg.Type), nil
+}
+
+// FetchSource haalt de items van één bron op en past de instellingen van de bron toe:
+// standaard categorie, maximale leeftijd en maximaal aantal items
+func (f *NewsletterFetcher) FetchSource(ctx context.Context, cfg *models.NewsletterSource) ([]models.NewsItem, error) {
+	source, err := f.NewFeedSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var since time.Time
+	if cfg.MaxAgeDays > 0 {
+		since = time.Now().AddDate(0, 0, -cfg.MaxAgeDays)
+	}
+
+	filtered := make([]models.NewsItem, 0, len(items))
+	for _, item := range items {
+		if !since.IsZero() && !item.PubDate.IsZero() && item.PubDate.Before(since) {
 			continue
 		}
-		resp, err := client.Do(req)
+		item.Source = source.Name()
+		if item.Category == "" {
+			item.Category = cfg.Categorie
+		}
+		filtered = append(filtered, item)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].PubDate.After(filtered[j].PubDate) })
+	if cfg.MaxItems > 0 && len(filtered) > cfg.MaxItems {
+		filtered = filtered[:cfg.MaxItems]
+	}
+	return filtered, nil
+}
+
+// Fetch haalt de items van alle bronnen op, nieuwste eerst. Een bron die niet werkt wordt
+// overgeslagen; de fout wordt bij een bron uit de database vastgelegd.
+func (f *NewsletterFetcher) Fetch(ctx context.Context) ([]models.NewsItem, error) {
+	var items []models.NewsItem
+	for _, cfg := range f.configuredSources(ctx) {
+		sourceItems, err := f.FetchSource(ctx, cfg)
 		if err != nil {
-			continue
+			logger.Warn("Nieuwsbrief bron kon niet worden opgehaald", "source", cfg.Naam, "error", err)
 		}
-		func() {
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				io.Copy(io.Discard, resp.Body)
-				return
+		if cfg.ID != "" && f.sourceRepo != nil {
+			lastError := ""
+			if err != nil {
+				lastError = err.Error()
 			}
-			var doc rss
-			dec := xml.NewDecoder(resp.Body)
-			if err := dec.Decode(&doc); err != nil {
-				return
+			if markErr := f.sourceRepo.MarkFetched(ctx, cfg.ID, time.Now(), lastError); markErr != nil {
+				logger.Error("Kon status van nieuwsbrief bron niet vastleggen", "error", markErr, "source", cfg.Naam)
 			}
-			for _, it := range doc.Channel.Items {
-				if strings.TrimSpace(it.Title) == "" || strings.TrimSpace(it.Link) == "" {
-					continue
-				}
-				var ts time.Time
-				if t, err := time.Parse(time.RFC1123Z, it.PubDate); err == nil {
-					ts = t
-				}
-				items = append(items, models.NewsItem{
-					Title:       it.Title,
-					Description: it.Description,
-					Link:        it.Link,
-					PubDate:     ts,
-					Category:    it.Category,
-				})
-			}
-		}()
+		}
+		items = append(items, sourceItems...)
 	}
 	sort.SliceStable(items, func(i, j int) bool { return items[i].PubDate.After(items[j].PubDate) })
 	return items, nil
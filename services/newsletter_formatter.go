@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"dklautomationgo/models"
 	"fmt"
+	"strings"
 )
 
+// uncategorizedNews is de kop voor items zonder categorie
+const uncategorizedNews = "Overig"
+
 type NewsletterFormatter struct {
 	emailSvc *EmailService
 }
@@ -16,8 +20,10 @@ func NewNewsletterFormatter(es *EmailService) *NewsletterFormatter {
 
 func (f *NewsletterFormatter) Format(processed *models.ProcessedNews, subject string) (string, error) {
 	tmplName := "newsletter"
+	processed.Groups = GroupNewsByCategory(processed.Items)
 	data := map[string]interface{}{
 		"Items":   processed.Items,
+		"Groups":  processed.Groups,
 		"Summary": processed.Summary,
 	}
 	var body bytes.Buffer
@@ -30,3 +36,36 @@ func (f *NewsletterFormatter) Format(processed *models.ProcessedNews, subject st
 	}
 	return body.String(), nil
 }
+
+// GroupNewsByCategory groepeert items per categorie. De groepen staan in de volgorde waarin
+// de categorie voor het eerst voorkomt; items zonder categorie komen als laatste onder Overig.
+// Heeft geen enkel item een categorie, dan zijn er geen groepen en toont de template een lijst.
+func GroupNewsByCategory(items []models.NewsItem) []models.NewsGroup {
+	var groups []models.NewsGroup
+	index := make(map[string]int)
+	var uncategorized []models.NewsItem
+
+	for _, item := range items {
+		category := strings.TrimSpace(item.Category)
+		if category == "" {
+			uncategorized = append(uncategorized, item)
+			continue
+		}
+		key := strings.ToLower(category)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, models.NewsGroup{Category: category})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+
+	if len(groups) == 0 {
+		return nil
+	}
+	if len(uncategorized) > 0 {
+		groups = append(groups, models.NewsGroup{Category: uncategorizedNews, Items: uncategorized})
+	}
+	return groups
+}
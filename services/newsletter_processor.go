@@ -1,27 +1,93 @@
 package services
 
 import (
+	"context"
 	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"strings"
+	"time"
 )
 
-type NewsletterProcessor struct{}
+// maxNewsletterItems is het maximale aantal items in een automatische nieuwsbrief
+const maxNewsletterItems = 20
+
+type NewsletterProcessor struct {
+	sentItems repository.NewsletterSentItemRepository
+}
 
 func NewNewsletterProcessor() *NewsletterProcessor { return &NewsletterProcessor{} }
 
-// Process converteert en filtert ruwe feed items naar ons model
-func (p *NewsletterProcessor) Process(items []models.NewsItem) models.ProcessedNews {
+// SetSentItemRepository stelt de verzonden items in; items die al in een eerdere nieuwsbrief
+// hebben gestaan worden overgeslagen
+func (p *NewsletterProcessor) SetSentItemRepository(repo repository.NewsletterSentItemRepository) {
+	p.sentItems = repo
+}
+
+// Process converteert en filtert ruwe feed items naar ons model. Dubbele items en items die
+// al eerder zijn verzonden worden overgeslagen.
+func (p *NewsletterProcessor) Process(ctx context.Context, items []models.NewsItem) (models.ProcessedNews, error) {
 	processed := models.ProcessedNews{Items: make([]models.NewsItem, 0), Summary: ""}
+
+	seen := make(map[string]bool, len(items))
+	candidates := make([]models.NewsItem, 0, len(items))
+	keys := make([]string, 0, len(items))
 	for _, it := range items {
 		if it.Title == "" || it.Link == "" {
 			continue
 		}
+		key := newsItemKey(it)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, it)
+		keys = append(keys, key)
+	}
+
+	sent := map[string]bool{}
+	if p.sentItems != nil {
+		var err error
+		if sent, err = p.sentItems.FilterSent(ctx, keys); err != nil {
+			return processed, err
+		}
+	}
+
+	for _, it := range candidates {
+		if sent[newsItemKey(it)] {
+			continue
+		}
 		processed.Items = append(processed.Items, it)
-		if len(processed.Items) >= 20 {
+		if len(processed.Items) >= maxNewsletterItems {
 			break
 		}
 	}
 	if len(processed.Items) > 0 {
 		processed.Summary = "Laatste updates en artikelen"
 	}
-	return processed
+	return processed, nil
+}
+
+// MarkSent legt vast dat de items zijn verzonden, zodat ze niet in een volgende nieuwsbrief komen
+func (p *NewsletterProcessor) MarkSent(ctx context.Context, items []models.NewsItem) error {
+	if p.sentItems == nil || len(items) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	sentItems := make([]*models.NewsletterSentItem, len(items))
+	for i, it := range items {
+		sentItems[i] = &models.NewsletterSentItem{
+			ItemKey: newsItemKey(it),
+			Title:   it.Title,
+			Link:    it.Link,
+			Source:  it.Source,
+			SentAt:  now,
+		}
+	}
+	return p.sentItems.MarkSent(ctx, sentItems)
+}
+
+// newsItemKey geeft de genormaliseerde sleutel van een item
+func newsItemKey(item models.NewsItem) string {
+	return strings.TrimSpace(item.Key())
 }
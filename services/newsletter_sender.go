@@ -6,6 +6,8 @@ import (
 	"dklautomationgo/models"
 	"dklautomationgo/repository"
 	"fmt"
+	"html/template"
+	"strings"
 	"time"
 )
 
@@ -15,15 +17,252 @@ type NewsletterSender struct {
 	gebruikerRepo repository.GebruikerRepository
 	nlRepo        repository.NewsletterRepository
 	notifSvc      NotificationService
+	suppressions  repository.EmailSuppressionRepository
+	subscriptions *NewsletterSubscriptionService
+	segments      repository.NewsletterSegmentRepository
+	tracker       *NewsletterTracker
+	subscribers   repository.NewsletterSubscriberRepository
 }
 
+// SegmentPreview is het resultaat van een dry-run van een segment
+type SegmentPreview struct {
+	Total        int      `json:"total"`        // Unieke adressen die aan het filter voldoen
+	Suppressed   int      `json:"suppressed"`   // Waarvan onderdrukt na bounces
+	Unsubscribed int      `json:"unsubscribed"` // Waarvan afgemeld voor de nieuwsbrief
+	Recipients   int      `json:"recipients"`   // Adressen die de nieuwsbrief daadwerkelijk ontvangen
+	Sample       []string `json:"sample"`       // De eerste ontvangers, ter controle
+}
+
+// segmentPreviewSampleSize is het aantal voorbeeldadressen in een dry-run
+const segmentPreviewSampleSize = 10
+
 func NewNewsletterSender(es *EmailService, eb *EmailBatcher, gr repository.GebruikerRepository,
 	nr repository.NewsletterRepository, ns NotificationService) *NewsletterSender {
 	return &NewsletterSender{emailSvc: es, batcher: eb, gebruikerRepo: gr, nlRepo: nr, notifSvc: ns}
 }
 
+// SetSuppressionRepository stelt de suppressielijst in; onderdrukte adressen krijgen geen nieuwsbrief
+func (s *NewsletterSender) SetSuppressionRepository(repo repository.EmailSuppressionRepository) {
+	s.suppressions = repo
+}
+
+// SetSubscriptionService stelt de service in die per ontvanger een afmeldlink en
+// List-Unsubscribe headers aan de nieuwsbrief toevoegt
+func (s *NewsletterSender) SetSubscriptionService(svc *NewsletterSubscriptionService) {
+	s.subscriptions = svc
+}
+
+// SetSegmentRepository stelt de opgeslagen segmenten in, zodat een nieuwsbrief aan een doelgroep
+// verzonden kan worden in plaats van aan alle subscribers
+func (s *NewsletterSender) SetSegmentRepository(repo repository.NewsletterSegmentRepository) {
+	s.segments = repo
+}
+
+// SetSubscriberRepository stelt de abonnees zonder account in; bevestigde abonnees ontvangen
+// de nieuwsbrief naast de gebruikers die zich hebben aangemeld
+func (s *NewsletterSender) SetSubscriberRepository(repo repository.NewsletterSubscriberRepository) {
+	s.subscribers = repo
+}
+
+// SetTracker stelt de tracker in die per ontvanger de aflevering, opens en kliks bijhoudt
+func (s *NewsletterSender) SetTracker(tracker *NewsletterTracker) {
+	s.tracker = tracker
+}
+
+// queueAll zet de nieuwsbrief voor alle ontvangers in de batch. Met een tracker wordt per
+// ontvanger een aflevering vastgelegd en werkt de batcher die bij na het verzenden. De batches
+// onthouden de handler, dus die wordt verwijderd zodra alle ontvangers zijn toegevoegd.
+func (s *NewsletterSender) queueAll(ctx context.Context, newsletterID, batchKey, subject string, emails []string, data map[string]interface{}) error {
+	var deliveries map[string]*models.NewsletterDelivery
+	if s.tracker != nil {
+		var err error
+		deliveries, err = s.tracker.Prepare(ctx, newsletterID, emails)
+		if err != nil {
+			return err
+		}
+		s.batcher.SetResultHandler(batchKey, s.tracker.ResultHandler(deliveries))
+		defer s.batcher.SetResultHandler(batchKey, nil)
+	}
+
+	// Queue in batcher (use default SMTP_FROM address)
+	for _, email := range emails {
+		s.queue(batchKey, email, subject, data, deliveries[email])
+	}
+
+	// Force immediate sending for small newsletter batches
+	if len(emails) < s.batcher.batchSize {
+		logger.Info("Small newsletter batch detected, forcing immediate send", "recipient_count", len(emails), "batch_size", s.batcher.batchSize)
+		// Force immediate flush of this specific batch
+		s.batcher.FlushBatch(batchKey)
+	}
+	return nil
+}
+
+// queue zet de nieuwsbrief voor één ontvanger in de batch, met een persoonlijke afmeldlink
+// als de subscription service is ingesteld en tracking links als er een aflevering is
+func (s *NewsletterSender) queue(batchKey, email, subject string, data map[string]interface{}, delivery *models.NewsletterDelivery) {
+	if s.subscriptions == nil && delivery == nil {
+		s.batcher.AddToBatch(batchKey, email, subject, "newsletter", data)
+		return
+	}
+
+	recipientData := make(map[string]interface{}, len(data)+2)
+	for k, v := range data {
+		recipientData[k] = v
+	}
+
+	var headers map[string]string
+	if s.subscriptions != nil {
+		recipientData["UnsubscribeURL"] = s.subscriptions.UnsubscribeURL(email)
+		headers = s.subscriptions.Headers(email)
+	}
+	if delivery != nil {
+		s.tracker.Personalize(delivery, recipientData)
+	}
+
+	if delivery != nil {
+		s.batcher.AddTrackedToBatch(batchKey, email, subject, "newsletter", recipientData, headers, delivery.ID)
+		return
+	}
+	s.batcher.AddPersonalizedToBatch(batchKey, email, subject, "newsletter", recipientData, headers)
+}
+
+// filterSuppressed verwijdert adressen die op de suppressielijst staan of zich voor de nieuwsbrief
+// hebben afgemeld, en telt beide apart. Een afgemeld adres krijgt nooit een nieuwsbrief, ongeacht
+// de doelgroep; kan de afmelding niet worden gecontroleerd, dan wordt er niet verzonden.
+func (s *NewsletterSender) filterSuppressed(ctx context.Context, emails []string) (filtered []string, suppressed, unsubscribed int, err error) {
+	if s.suppressions == nil || len(emails) == 0 {
+		return emails, 0, 0, nil
+	}
+
+	optedOut, err := s.suppressions.FindUnsubscribed(ctx, emails)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("kon afmeldingen voor de nieuwsbrief niet controleren: %w", err)
+	}
+	bounced, err := s.suppressions.FindSuppressed(ctx, emails)
+	if err != nil {
+		logger.Error("Kon suppressielijst niet controleren", "error", err)
+		bounced = nil
+	}
+	if len(optedOut) == 0 && len(bounced) == 0 {
+		return emails, 0, 0, nil
+	}
+
+	skip := make(map[string]string, len(optedOut)+len(bounced))
+	for _, email := range bounced {
+		skip[email] = "bounce"
+	}
+	for _, email := range optedOut {
+		skip[email] = "afgemeld"
+	}
+	filtered = make([]string, 0, len(emails))
+	for _, email := range emails {
+		switch skip[strings.ToLower(strings.TrimSpace(email))] {
+		case "afgemeld":
+			unsubscribed++
+		case "bounce":
+			suppressed++
+		default:
+			filtered = append(filtered, email)
+		}
+	}
+
+	logger.Info("Onderdrukte en afgemelde adressen overgeslagen voor nieuwsbrief", "suppressed", suppressed, "unsubscribed", unsubscribed)
+	return filtered, suppressed, unsubscribed, nil
+}
+
+// recipients bepaalt de ontvangers van een nieuwsbrief: de adressen uit het segment, of alle
+// subscribers (gebruikers en bevestigde abonnees zonder account) als er geen segment is. Elk
+// adres komt maar één keer voor; onderdrukte en afgemelde adressen worden overgeslagen, ook als
+// het segment op aanmeldingen of een losse lijst adressen is gebaseerd.
+func (s *NewsletterSender) recipients(ctx context.Context, segmentID string) ([]string, error) {
+	if segmentID == "" {
+		subs, err := s.gebruikerRepo.GetNewsletterSubscribers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		emails := make([]string, len(subs))
+		for i, sub := range subs {
+			emails[i] = sub.Email
+		}
+		if s.subscribers != nil {
+			confirmed, err := s.subscribers.ListConfirmedEmails(ctx)
+			if err != nil {
+				return nil, err
+			}
+			emails = append(emails, confirmed...)
+		}
+		filtered, _, _, err := s.filterSuppressed(ctx, normalizeEmails(emails))
+		return filtered, err
+	}
+
+	if s.segments == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNewsletterSegmentNotFound, segmentID)
+	}
+	segment, err := s.segments.GetByID(ctx, segmentID)
+	if err != nil {
+		return nil, err
+	}
+	if segment == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNewsletterSegmentNotFound, segmentID)
+	}
+
+	emails, err := s.segments.ResolveEmails(ctx, segment.Filter)
+	if err != nil {
+		return nil, err
+	}
+	filtered, _, _, err := s.filterSuppressed(ctx, normalizeEmails(emails))
+	return filtered, err
+}
+
+// PreviewSegment telt zonder te verzenden hoeveel adressen een segment filter oplevert
+func (s *NewsletterSender) PreviewSegment(ctx context.Context, filter models.SegmentFilter) (*SegmentPreview, error) {
+	if s.segments == nil {
+		return nil, fmt.Errorf("segmenten zijn niet geconfigureerd")
+	}
+
+	emails, err := s.segments.ResolveEmails(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	emails = normalizeEmails(emails)
+	recipients, suppressed, unsubscribed, err := s.filterSuppressed(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := recipients
+	if len(sample) > segmentPreviewSampleSize {
+		sample = sample[:segmentPreviewSampleSize]
+	}
+
+	return &SegmentPreview{
+		Total:        len(emails),
+		Suppressed:   suppressed,
+		Unsubscribed: unsubscribed,
+		Recipients:   len(recipients),
+		Sample:       sample,
+	}, nil
+}
+
+// normalizeEmails zet adressen om naar kleine letters en verwijdert lege adressen en dubbelingen,
+// zodat iemand met zowel een aanmelding als een account de nieuwsbrief maar één keer krijgt
+func normalizeEmails(emails []string) []string {
+	seen := make(map[string]bool, len(emails))
+	unique := make([]string, 0, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" || seen[email] {
+			continue
+		}
+		seen[email] = true
+		unique = append(unique, email)
+	}
+	return unique
+}
+
 func (s *NewsletterSender) Send(ctx context.Context, content, subject string) error {
-	subs, err := s.gebruikerRepo.GetNewsletterSubscribers(ctx)
+	subs, err := s.recipients(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -33,25 +272,20 @@ func (s *NewsletterSender) Send(ctx context.Context, content, subject string) er
 	}
 
 	// Save newsletter record
-	nl := &models.Newsletter{Subject: subject, Content: content}
+	nl := &models.Newsletter{Subject: subject, Content: content, Status: models.NewsletterStatusSending}
 	if err := s.nlRepo.Create(ctx, nl); err != nil {
 		return err
 	}
 
 	batchKey := "newsletter_daily"
 	data := map[string]interface{}{"Summary": "", "Items": []models.NewsItem{}}
-	data["Content"] = content
+	data["Content"] = template.HTML(content)
 
-	// Queue in batcher (use default SMTP_FROM address)
-	for _, sub := range subs {
-		s.batcher.AddToBatch(batchKey, sub.Email, subject, "newsletter", data)
-	}
-
-	// Force immediate sending for small newsletter batches
-	if len(subs) < s.batcher.batchSize {
-		logger.Info("SendManual: Small batch detected, forcing immediate send", "subscriber_count", len(subs), "batch_size", s.batcher.batchSize)
-		// Force immediate flush of this specific batch
-		s.batcher.FlushBatch(batchKey)
+	if err := s.queueAll(ctx, nl.ID, batchKey, subject, subs, data); err != nil {
+		if markErr := s.nlRepo.MarkFailed(ctx, nl.ID, err.Error()); markErr != nil {
+			logger.Error("Fout bij markeren nieuwsbrief als mislukt", "error", markErr, "newsletter_id", nl.ID)
+		}
+		return err
 	}
 
 	// Mark newsletter as sent
@@ -65,7 +299,10 @@ func (s *NewsletterSender) Send(ctx context.Context, content, subject string) er
 	return nil
 }
 
-func (s *NewsletterSender) SendManual(ctx context.Context, newsletterID string) error {
+// SendManual verzendt een goedgekeurde of ingeplande nieuwsbrief naar het segment van de
+// nieuwsbrief of, als dat ontbreekt, naar alle subscribers. De doelgroep is onderdeel van de
+// goedkeuring: een opgegeven segmentID moet gelijk zijn aan dat van de nieuwsbrief.
+func (s *NewsletterSender) SendManual(ctx context.Context, newsletterID, segmentID string) error {
 	logger.Info("SendManual: Getting newsletter", "newsletter_id", newsletterID)
 
 	// Get the newsletter
@@ -76,29 +313,57 @@ func (s *NewsletterSender) SendManual(ctx context.Context, newsletterID string)
 	}
 	if nl == nil {
 		logger.Warn("SendManual: Newsletter not found", "newsletter_id", newsletterID)
-		return fmt.Errorf("newsletter not found: %s", newsletterID)
+		return fmt.Errorf("%w: %s", ErrNewsletterNotFound, newsletterID)
 	}
 
 	logger.Info("SendManual: Newsletter found", "newsletter_id", newsletterID, "subject", nl.Subject)
 
-	// Check if already sent
-	if nl.SentAt != nil {
-		return fmt.Errorf("newsletter already sent")
+	if segmentID != "" && (nl.SegmentID == nil || *nl.SegmentID != segmentID) {
+		return fmt.Errorf("%w: %s", ErrNewsletterSegmentMismatch, segmentID)
+	}
+
+	// Alleen een goedgekeurde of ingeplande nieuwsbrief mag verzonden worden. De status
+	// wordt atomisch op sending gezet, zodat een nieuwsbrief nooit twee keer uitgaat.
+	claimed, err := s.nlRepo.TransitionStatus(ctx, newsletterID,
+		[]string{models.NewsletterStatusApproved, models.NewsletterStatusScheduled, models.NewsletterStatusFailed},
+		models.NewsletterStatusSending)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("%w (status: %s)", ErrNewsletterInvalidStatus, nl.Status)
+	}
+
+	if nl.SegmentID != nil {
+		segmentID = *nl.SegmentID
+	}
+
+	if err := s.sendManual(ctx, nl, segmentID); err != nil {
+		if markErr := s.nlRepo.MarkFailed(ctx, newsletterID, err.Error()); markErr != nil {
+			logger.Error("Fout bij markeren nieuwsbrief als mislukt", "error", markErr, "newsletter_id", newsletterID)
+		}
+		return err
 	}
+	return nil
+}
 
-	// Get subscribers
-	logger.Info("SendManual: Getting newsletter subscribers")
-	subs, err := s.gebruikerRepo.GetNewsletterSubscribers(ctx)
+// sendManual zet een geclaimde nieuwsbrief voor alle ontvangers in de batcher
+func (s *NewsletterSender) sendManual(ctx context.Context, nl *models.Newsletter, segmentID string) error {
+	newsletterID := nl.ID
+
+	// Get recipients
+	logger.Info("SendManual: Getting newsletter recipients", "segment_id", segmentID)
+	subs, err := s.recipients(ctx, segmentID)
 	if err != nil {
-		logger.Error("SendManual: Error getting subscribers", "error", err)
+		logger.Error("SendManual: Error getting recipients", "error", err, "segment_id", segmentID)
 		return err
 	}
 	if len(subs) == 0 {
-		logger.Info("SendManual: Geen subscribers voor nieuwsbrief")
-		return nil
+		logger.Info("SendManual: Geen ontvangers voor nieuwsbrief", "segment_id", segmentID)
+		return fmt.Errorf("geen ontvangers voor nieuwsbrief")
 	}
 
-	logger.Info("SendManual: Found subscribers", "count", len(subs))
+	logger.Info("SendManual: Found recipients", "count", len(subs), "segment_id", segmentID)
 
 	// Update batch ID
 	batchKey := "newsletter_manual_" + newsletterID
@@ -108,21 +373,12 @@ func (s *NewsletterSender) SendManual(ctx context.Context, newsletterID string)
 
 	// Queue in batcher
 	data := map[string]interface{}{
-		"Content": nl.Content,
+		"Content": template.HTML(nl.Content),
 	}
 
-	// Queue in batcher (use default SMTP_FROM address)
 	logger.Info("SendManual: Queueing emails in batcher", "batch_key", batchKey)
-
-	for _, sub := range subs {
-		s.batcher.AddToBatch(batchKey, sub.Email, nl.Subject, "newsletter", data)
-	}
-
-	// Force immediate sending for small newsletter batches
-	if len(subs) < s.batcher.batchSize {
-		logger.Info("Send: Small batch detected, forcing immediate send", "subscriber_count", len(subs), "batch_size", s.batcher.batchSize)
-		// Force immediate flush of this specific batch
-		s.batcher.FlushBatch(batchKey)
+	if err := s.queueAll(ctx, newsletterID, batchKey, nl.Subject, subs, data); err != nil {
+		return err
 	}
 
 	// Mark newsletter as sent
@@ -132,6 +388,22 @@ func (s *NewsletterSender) SendManual(ctx context.Context, newsletterID string)
 		return err
 	}
 
-	logger.Info("Manual nieuwsbrief verzonden", "newsletter_id", newsletterID, "recipients", len(subs), "batch_id", batchKey, "sent_at", sentAt)
+	logger.Info("Manual nieuwsbrief verzonden", "newsletter_id", newsletterID, "recipients", len(subs), "segment_id", segmentID, "batch_id", batchKey, "sent_at", sentAt)
 	return nil
 }
+
+// SendTest verstuurt een voorbeeld van de nieuwsbrief naar één adres, bijvoorbeeld de
+// medewerker die de nieuwsbrief opstelt. Er wordt niets aan de nieuwsbrief gewijzigd.
+func (s *NewsletterSender) SendTest(nl *models.Newsletter, email string) error {
+	data := map[string]interface{}{
+		"Summary": "",
+		"Items":   []models.NewsItem{},
+		"Content": template.HTML(nl.Content),
+	}
+	if s.subscriptions != nil {
+		data["UnsubscribeURL"] = s.subscriptions.UnsubscribeURL(email)
+	}
+
+	logger.Info("Testversie van nieuwsbrief verzonden", "newsletter_id", nl.ID, "ontvanger", email)
+	return s.emailSvc.SendTemplateEmailWithMetadata(email, "[TEST] "+nl.Subject, "newsletter", data, EmailMetadata{KeepSubject: true})
+}
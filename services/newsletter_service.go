@@ -4,6 +4,7 @@ import (
 	"context"
 	"dklautomationgo/logger"
 	"dklautomationgo/models"
+	"dklautomationgo/repository"
 	"time"
 )
 
@@ -15,6 +16,11 @@ type NewsletterService struct {
 	ticker    *time.Ticker
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// De scheduler verzendt ingeplande nieuwsbrieven; de pipeline (RSS) is optioneel
+	nlRepo            repository.NewsletterRepository
+	pipelineEnabled   bool
+	schedulerInterval time.Duration
 }
 
 func NewNewsletterService(fetcher *NewsletterFetcher, processor *NewsletterProcessor,
@@ -22,13 +28,25 @@ func NewNewsletterService(fetcher *NewsletterFetcher, processor *NewsletterProce
 	ticker := time.NewTicker(24 * time.Hour)
 	ctx, cancel := context.WithCancel(context.Background())
 	return &NewsletterService{
-		fetcher:   fetcher,
-		processor: processor,
-		formatter: formatter,
-		sender:    sender,
-		ticker:    ticker,
-		ctx:       ctx,
-		cancel:    cancel,
+		fetcher:           fetcher,
+		processor:         processor,
+		formatter:         formatter,
+		sender:            sender,
+		ticker:            ticker,
+		ctx:               ctx,
+		cancel:            cancel,
+		pipelineEnabled:   true,
+		schedulerInterval: time.Minute,
+	}
+}
+
+// ConfigureScheduler stelt de scheduler voor ingeplande nieuwsbrieven in. Met pipelineEnabled
+// false draait alleen de scheduler en niet de dagelijkse RSS nieuwsbrief.
+func (ns *NewsletterService) ConfigureScheduler(nlRepo repository.NewsletterRepository, interval time.Duration, pipelineEnabled bool) {
+	ns.nlRepo = nlRepo
+	ns.pipelineEnabled = pipelineEnabled
+	if interval > 0 {
+		ns.schedulerInterval = interval
 	}
 }
 
@@ -36,6 +54,14 @@ func (ns *NewsletterService) Start() {
 	if ns == nil {
 		return
 	}
+	if ns.nlRepo != nil {
+		go ns.runScheduler()
+	}
+	if !ns.pipelineEnabled {
+		ns.ticker.Stop()
+		logger.Info("Newsletter scheduler gestart", "interval", ns.schedulerInterval)
+		return
+	}
 	go func() {
 		for {
 			select {
@@ -45,9 +71,7 @@ func (ns *NewsletterService) Start() {
 			case <-ns.ticker.C:
 				if err := ns.RunPipeline(); err != nil {
 					logger.Error("Nieuwsbrief pipeline error", "error", err)
-					if ns.sender != nil && ns.sender.notifSvc != nil {
-						ns.sender.notifSvc.CreateNotification(ns.ctx, models.NotificationTypeSystem, models.NotificationPriorityMedium, "Nieuwsbrief Fout", err.Error())
-					}
+					ns.notifyError("Nieuwsbrief Fout", err.Error())
 				}
 			}
 		}
@@ -55,17 +79,76 @@ func (ns *NewsletterService) Start() {
 	logger.Info("Newsletter service gestart")
 }
 
+// runScheduler controleert periodiek of er ingeplande nieuwsbrieven verzonden moeten worden.
+// De planning staat in de database, dus na een herstart worden gemiste nieuwsbrieven alsnog verzonden.
+func (ns *NewsletterService) runScheduler() {
+	ticker := time.NewTicker(ns.schedulerInterval)
+	defer ticker.Stop()
+
+	ns.SendDue(ns.ctx)
+	for {
+		select {
+		case <-ns.ctx.Done():
+			return
+		case <-ticker.C:
+			ns.SendDue(ns.ctx)
+		}
+	}
+}
+
+// SendDue verzendt alle ingeplande nieuwsbrieven waarvan het tijdstip is verstreken en geeft
+// het aantal verzonden nieuwsbrieven terug
+func (ns *NewsletterService) SendDue(ctx context.Context) int {
+	due, err := ns.nlRepo.ListDue(ctx, time.Now())
+	if err != nil {
+		logger.Error("Kon ingeplande nieuwsbrieven niet ophalen", "error", err)
+		return 0
+	}
+
+	sent := 0
+	for _, nl := range due {
+		logger.Info("Ingeplande nieuwsbrief wordt verzonden", "newsletter_id", nl.ID, "scheduled_at", nl.ScheduledAt)
+		if err := ns.sender.SendManual(ctx, nl.ID, ""); err != nil {
+			logger.Error("Verzenden van ingeplande nieuwsbrief mislukt", "error", err, "newsletter_id", nl.ID)
+			ns.notifyError("Ingeplande nieuwsbrief mislukt", nl.Subject+": "+err.Error())
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// notifyError meldt een fout via de notificatie service, als die beschikbaar is
+func (ns *NewsletterService) notifyError(title, message string) {
+	if ns.sender != nil && ns.sender.notifSvc != nil {
+		ns.sender.notifSvc.CreateNotification(ns.ctx, models.NotificationTypeSystem, models.NotificationPriorityMedium, title, message)
+	}
+}
+
 func (ns *NewsletterService) RunPipeline() error {
 	raw, err := ns.fetcher.Fetch(ns.ctx)
 	if err != nil {
 		return err
 	}
-	processed := ns.processor.Process(raw)
+	processed, err := ns.processor.Process(ns.ctx, raw)
+	if err != nil {
+		return err
+	}
+	if len(processed.Items) == 0 {
+		logger.Info("Geen nieuwe items voor de nieuwsbrief, er wordt niets verzonden")
+		return nil
+	}
 	content, err := ns.formatter.Format(&processed, "DKL Wekelijkse Nieuwsbrief")
 	if err != nil {
 		return err
 	}
-	return ns.sender.Send(ns.ctx, content, "DKL Wekelijkse Nieuwsbrief")
+	if err := ns.sender.Send(ns.ctx, content, "DKL Wekelijkse Nieuwsbrief"); err != nil {
+		return err
+	}
+	if err := ns.processor.MarkSent(ns.ctx, processed.Items); err != nil {
+		logger.Error("Kon verzonden nieuwsitems niet vastleggen", "error", err)
+	}
+	return nil
 }
 
 func (ns *NewsletterService) Stop() {
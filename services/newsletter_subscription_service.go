@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Doelen waarvoor aan- en afmeldtokens worden ondertekend
+const (
+	unsubscribeTokenPurpose = "newsletter_unsubscribe"
+	confirmTokenPurpose     = "newsletter_confirm"
+)
+
+const (
+	// newsletterConfirmValidity is hoe lang een bevestigingslink geldig is
+	newsletterConfirmValidity = 7 * 24 * time.Hour
+	// newsletterConfirmResendAfter voorkomt dat herhaald aanmelden de ontvanger overspoelt
+	newsletterConfirmResendAfter = 10 * time.Minute
+)
+
+var (
+	// ErrNewsletterConsentRequired wordt teruggegeven als er geen toestemming is gegeven
+	ErrNewsletterConsentRequired = errors.New("toestemming voor de nieuwsbrief is verplicht")
+	// ErrNewsletterInvalidEmail wordt teruggegeven bij een ongeldig email adres
+	ErrNewsletterInvalidEmail = errors.New("ongeldig email adres")
+	// ErrNewsletterSignupDisabled wordt teruggegeven als aanmelden via de website niet is ingesteld
+	ErrNewsletterSignupDisabled = errors.New("aanmelden voor de nieuwsbrief is niet beschikbaar")
+)
+
+// NewsletterSubscriptionService beheert aan- en afmeldingen voor de nieuwsbrief. Elke
+// ontvanger krijgt een ondertekende afmeldlink; elke wijziging wordt vastgelegd in de audit.
+type NewsletterSubscriptionService struct {
+	signer        *TokenSigner
+	baseURL       string
+	gebruikerRepo repository.GebruikerRepository
+	eventRepo     repository.NewsletterSubscriptionEventRepository
+	subscribers   repository.NewsletterSubscriberRepository
+	emailSender   EmailSender
+	suppressions  repository.EmailSuppressionRepository
+}
+
+// NewNewsletterSubscriptionService maakt een nieuwe NewsletterSubscriptionService.
+// baseURL is het publieke adres van de API, bijv. https://dklemailservice.onrender.com
+func NewNewsletterSubscriptionService(
+	signer *TokenSigner,
+	baseURL string,
+	gebruikerRepo repository.GebruikerRepository,
+	eventRepo repository.NewsletterSubscriptionEventRepository,
+) *NewsletterSubscriptionService {
+	return &NewsletterSubscriptionService{
+		signer:        signer,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		gebruikerRepo: gebruikerRepo,
+		eventRepo:     eventRepo,
+	}
+}
+
+// EnableSignup maakt aanmelden via de website mogelijk voor mensen zonder account. Nieuwe
+// abonnees krijgen een bevestigingsmail en ontvangen de nieuwsbrief pas na bevestiging.
+func (s *NewsletterSubscriptionService) EnableSignup(subscribers repository.NewsletterSubscriberRepository, emailSender EmailSender) {
+	s.subscribers = subscribers
+	s.emailSender = emailSender
+}
+
+// SetSuppressionRepository stelt de suppressielijst in waarop afmeldingen blijvend worden
+// vastgelegd, zodat de nieuwsbrief ze voor elke doelgroep respecteert
+func (s *NewsletterSubscriptionService) SetSuppressionRepository(repo repository.EmailSuppressionRepository) {
+	s.suppressions = repo
+}
+
+// Subscribe meldt een adres aan voor de nieuwsbrief (double opt-in). De toestemming wordt
+// met tijdstip en IP adres vastgelegd en er gaat een bevestigingsmail uit. Voor een adres
+// dat al bevestigd is gebeurt niets, zodat de endpoint niet verraadt wie er al op staat.
+func (s *NewsletterSubscriptionService) Subscribe(ctx context.Context, email, naam string, consent bool, ip string) error {
+	if s.subscribers == nil || s.emailSender == nil {
+		return ErrNewsletterSignupDisabled
+	}
+	if !consent {
+		return ErrNewsletterConsentRequired
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" || !strings.Contains(address.Address, ".") {
+		return ErrNewsletterInvalidEmail
+	}
+	email = strings.ToLower(address.Address)
+
+	subscriber, err := s.subscribers.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if subscriber == nil {
+		subscriber = &models.NewsletterSubscriber{Email: email}
+	} else if subscriber.Status == models.NewsletterSubscriberConfirmed {
+		return nil
+	} else if subscriber.Status == models.NewsletterSubscriberPending &&
+		subscriber.ConfirmationSentAt != nil && now.Sub(*subscriber.ConfirmationSentAt) < newsletterConfirmResendAfter {
+		return nil
+	}
+
+	subscriber.Naam = strings.TrimSpace(naam)
+	subscriber.Status = models.NewsletterSubscriberPending
+	subscriber.ConsentAt = now
+	subscriber.ConsentIP = ip
+	subscriber.ConfirmationSentAt = &now
+	subscriber.UnsubscribedAt = nil
+	if err := s.subscribers.Save(ctx, subscriber); err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"Naam":       subscriber.Naam,
+		"ConfirmURL": s.ConfirmURL(email, now),
+	}
+	if err := s.emailSender.SendTemplateEmail(email, "Bevestig je aanmelding voor de nieuwsbrief", "newsletter_confirm", data); err != nil {
+		return err
+	}
+
+	logger.Info("Bevestigingsmail voor nieuwsbrief verzonden", "email", email)
+	return nil
+}
+
+// ConfirmToken geeft het ondertekende bevestigingstoken voor een aanmelding terug
+func (s *NewsletterSubscriptionService) ConfirmToken(email string, issuedAt time.Time) string {
+	value := strings.ToLower(strings.TrimSpace(email)) + "|" + strconv.FormatInt(issuedAt.Unix(), 10)
+	return s.signer.Sign(confirmTokenPurpose, value)
+}
+
+// ConfirmURL geeft de publieke bevestigingslink voor een aanmelding terug
+func (s *NewsletterSubscriptionService) ConfirmURL(email string, issuedAt time.Time) string {
+	return s.baseURL + "/api/newsletter/confirm?token=" + url.QueryEscape(s.ConfirmToken(email, issuedAt))
+}
+
+// Confirm bevestigt de aanmelding uit een bevestigingstoken. Bevestigen is idempotent; een
+// verlopen link of een adres dat zich inmiddels heeft afgemeld geeft ErrInvalidSignedToken.
+func (s *NewsletterSubscriptionService) Confirm(ctx context.Context, token, ip string) (string, error) {
+	if s.subscribers == nil {
+		return "", ErrNewsletterSignupDisabled
+	}
+
+	value, err := s.signer.Verify(confirmTokenPurpose, token)
+	if err != nil {
+		return "", err
+	}
+	email, issued, ok := strings.Cut(value, "|")
+	if !ok {
+		return "", ErrInvalidSignedToken
+	}
+	issuedUnix, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil || time.Since(time.Unix(issuedUnix, 0)) > newsletterConfirmValidity {
+		return "", ErrInvalidSignedToken
+	}
+
+	subscriber, err := s.subscribers.GetByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if subscriber == nil || subscriber.Status == models.NewsletterSubscriberUnsubscribed {
+		return "", ErrInvalidSignedToken
+	}
+	if subscriber.Status == models.NewsletterSubscriberConfirmed {
+		return email, nil
+	}
+
+	now := time.Now()
+	subscriber.Status = models.NewsletterSubscriberConfirmed
+	subscriber.ConfirmedAt = &now
+	subscriber.ConfirmedIP = ip
+	if err := s.subscribers.Save(ctx, subscriber); err != nil {
+		return "", err
+	}
+	if err := s.setOptOut(ctx, email, false); err != nil {
+		return "", err
+	}
+
+	s.recordEvent(ctx, email, nil, models.NewsletterActieAangemeld, models.NewsletterBronWebsite, ip)
+	logger.Info("Aanmelding voor nieuwsbrief bevestigd", "email", email)
+	return email, nil
+}
+
+// ListSubscribers haalt de abonnees zonder account op, optioneel met één status
+func (s *NewsletterSubscriptionService) ListSubscribers(ctx context.Context, status string, limit, offset int) ([]*models.NewsletterSubscriber, int64, error) {
+	if s.subscribers == nil {
+		return nil, 0, ErrNewsletterSignupDisabled
+	}
+	return s.subscribers.List(ctx, status, limit, offset)
+}
+
+// UnsubscribeToken geeft het ondertekende afmeldtoken voor een adres terug
+func (s *NewsletterSubscriptionService) UnsubscribeToken(email string) string {
+	return s.signer.Sign(unsubscribeTokenPurpose, strings.ToLower(strings.TrimSpace(email)))
+}
+
+// UnsubscribeURL geeft de publieke afmeldlink voor een adres terug
+func (s *NewsletterSubscriptionService) UnsubscribeURL(email string) string {
+	return s.baseURL + "/api/newsletter/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken(email))
+}
+
+// Headers geeft de RFC 8058 List-Unsubscribe headers voor een nieuwsbrief aan een adres terug
+func (s *NewsletterSubscriptionService) Headers(email string) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + s.UnsubscribeURL(email) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// Unsubscribe meldt het adres uit een afmeldtoken af voor de nieuwsbrief. De afmelding wordt
+// blijvend op de suppressielijst gezet, zodat ook segmenten op aanmeldingen of losse adressen
+// het adres overslaan. Afmelden is idempotent: een adres dat al is afgemeld geeft geen fout.
+func (s *NewsletterSubscriptionService) Unsubscribe(ctx context.Context, token, bron, ip string) (string, error) {
+	email, err := s.signer.Verify(unsubscribeTokenPurpose, token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.setOptOut(ctx, email, true); err != nil {
+		return "", err
+	}
+
+	if err := s.unsubscribeSubscriber(ctx, email, bron, ip); err != nil {
+		return "", err
+	}
+
+	gebruiker, err := s.gebruikerRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if gebruiker == nil || !gebruiker.NewsletterSubscribed {
+		return email, nil
+	}
+
+	gebruiker.NewsletterSubscribed = false
+	if err := s.gebruikerRepo.Update(ctx, gebruiker); err != nil {
+		return "", err
+	}
+
+	s.RecordChange(ctx, gebruiker, false, bron, ip)
+	logger.Info("Afgemeld voor nieuwsbrief", "email", email, "bron", bron)
+	return email, nil
+}
+
+// unsubscribeSubscriber meldt een abonnee zonder account af, als het adres in de lijst staat
+func (s *NewsletterSubscriptionService) unsubscribeSubscriber(ctx context.Context, email, bron, ip string) error {
+	if s.subscribers == nil {
+		return nil
+	}
+
+	subscriber, err := s.subscribers.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if subscriber == nil || subscriber.Status == models.NewsletterSubscriberUnsubscribed {
+		return nil
+	}
+
+	wasConfirmed := subscriber.Status == models.NewsletterSubscriberConfirmed
+	now := time.Now()
+	subscriber.Status = models.NewsletterSubscriberUnsubscribed
+	subscriber.UnsubscribedAt = &now
+	if err := s.subscribers.Save(ctx, subscriber); err != nil {
+		return err
+	}
+
+	if wasConfirmed {
+		s.recordEvent(ctx, email, nil, models.NewsletterActieAfgemeld, bron, ip)
+	}
+	logger.Info("Abonnee afgemeld voor nieuwsbrief", "email", email, "bron", bron)
+	return nil
+}
+
+// RecordChange legt een aan- of afmelding van een gebruiker vast in de audit en op de
+// suppressielijst. Fouten worden gelogd; de audit mag de wijziging zelf niet blokkeren.
+func (s *NewsletterSubscriptionService) RecordChange(ctx context.Context, gebruiker *models.Gebruiker, subscribed bool, bron, ip string) {
+	actie := models.NewsletterActieAfgemeld
+	if subscribed {
+		actie = models.NewsletterActieAangemeld
+	}
+
+	if err := s.setOptOut(ctx, gebruiker.Email, !subscribed); err != nil {
+		logger.Error("Kon afmelding voor nieuwsbrief niet bijwerken", "error", err, "email", gebruiker.Email)
+	}
+
+	var gebruikerID *string
+	if gebruiker.ID != "" {
+		id := gebruiker.ID
+		gebruikerID = &id
+	}
+	s.recordEvent(ctx, gebruiker.Email, gebruikerID, actie, bron, ip)
+}
+
+// setOptOut zet of verwijdert de blijvende afmelding van een adres op de suppressielijst.
+// Een eventuele bounce op hetzelfde adres blijft ongemoeid.
+func (s *NewsletterSubscriptionService) setOptOut(ctx context.Context, email string, optOut bool) error {
+	if s.suppressions == nil {
+		return nil
+	}
+
+	suppression, err := s.suppressions.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if suppression == nil {
+		if !optOut {
+			return nil
+		}
+		suppression = &models.EmailSuppression{Email: email}
+	}
+	if suppression.NieuwsbriefAfgemeld == optOut {
+		return nil
+	}
+
+	suppression.NieuwsbriefAfgemeld = optOut
+	suppression.AfgemeldAt = nil
+	if optOut {
+		now := time.Now()
+		suppression.AfgemeldAt = &now
+	}
+	return s.suppressions.Save(ctx, suppression)
+}
+
+// recordEvent legt een aan- of afmelding vast in de audit
+func (s *NewsletterSubscriptionService) recordEvent(ctx context.Context, email string, gebruikerID *string, actie, bron, ip string) {
+	event := &models.NewsletterSubscriptionEvent{
+		GebruikerID: gebruikerID,
+		Email:       email,
+		Actie:       actie,
+		Bron:        bron,
+		IPAdres:     ip,
+	}
+
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		logger.Error("Kon nieuwsbrief audit niet vastleggen", "error", err, "email", email, "actie", actie)
+	}
+}
+
+// ListEvents haalt de audit van aan- en afmeldingen op, optioneel voor één adres
+func (s *NewsletterSubscriptionService) ListEvents(ctx context.Context, email string, limit, offset int) ([]*models.NewsletterSubscriptionEvent, int64, error) {
+	return s.eventRepo.List(ctx, email, limit, offset)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Doelen waarvoor tracking tokens worden ondertekend
+const (
+	newsletterOpenTokenPurpose  = "newsletter_open"
+	newsletterClickTokenPurpose = "newsletter_click"
+)
+
+// newsletterLinkPattern vindt http(s) links in de HTML van een nieuwsbrief
+var newsletterLinkPattern = regexp.MustCompile(`(?i)(<a\s[^>]*?href\s*=\s*)(["'])(https?://[^"']+)(["'])`)
+
+// NewsletterTracker houdt per ontvanger bij of een nieuwsbrief is afgeleverd en, als dat is
+// ingeschakeld, of hij is geopend (tracking pixel) en welke links zijn aangeklikt (ondertekende
+// redirect links). Zo is te zien hoeveel mensen een nieuwsbrief werkelijk heeft bereikt.
+type NewsletterTracker struct {
+	deliveryRepo      repository.NewsletterDeliveryRepository
+	signer            *TokenSigner
+	baseURL           string
+	trackOpens        bool
+	trackClicks       bool
+	prometheusMetrics *PrometheusMetrics
+}
+
+// NewNewsletterTracker maakt een nieuwe NewsletterTracker. baseURL is het publieke adres van de
+// API; prometheusMetrics mag nil zijn.
+func NewNewsletterTracker(
+	deliveryRepo repository.NewsletterDeliveryRepository,
+	signer *TokenSigner,
+	baseURL string,
+	trackOpens, trackClicks bool,
+	prometheusMetrics *PrometheusMetrics,
+) *NewsletterTracker {
+	return &NewsletterTracker{
+		deliveryRepo:      deliveryRepo,
+		signer:            signer,
+		baseURL:           strings.TrimRight(baseURL, "/"),
+		trackOpens:        trackOpens,
+		trackClicks:       trackClicks,
+		prometheusMetrics: prometheusMetrics,
+	}
+}
+
+// Prepare legt voor elke ontvanger een aflevering met status queued vast en geeft de
+// afleveringen per adres terug
+func (t *NewsletterTracker) Prepare(ctx context.Context, newsletterID string, emails []string) (map[string]*models.NewsletterDelivery, error) {
+	deliveries, err := t.deliveryRepo.CreateQueued(ctx, newsletterID, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := make(map[string]*models.NewsletterDelivery, len(deliveries))
+	for _, delivery := range deliveries {
+		byEmail[delivery.Email] = delivery
+	}
+	return byEmail, nil
+}
+
+// Personalize voegt de tracking pixel en ondertekende klik links toe aan de template data van
+// één ontvanger. data moet een eigen kopie voor deze ontvanger zijn.
+func (t *NewsletterTracker) Personalize(delivery *models.NewsletterDelivery, data map[string]interface{}) {
+	if delivery == nil || delivery.ID == "" {
+		return
+	}
+
+	if t.trackOpens {
+		data["TrackingPixelURL"] = t.baseURL + "/api/newsletter/track/open?t=" +
+			url.QueryEscape(t.signer.Sign(newsletterOpenTokenPurpose, delivery.ID))
+	}
+
+	if !t.trackClicks {
+		return
+	}
+	switch content := data["Content"].(type) {
+	case template.HTML:
+		data["Content"] = template.HTML(t.rewriteLinks(delivery.ID, string(content)))
+	case string:
+		data["Content"] = template.HTML(t.rewriteLinks(delivery.ID, content))
+	}
+	if items, ok := data["Items"].([]models.NewsItem); ok && len(items) > 0 {
+		tracked := make([]models.NewsItem, len(items))
+		for i, item := range items {
+			tracked[i] = item
+			if strings.HasPrefix(item.Link, "http://") || strings.HasPrefix(item.Link, "https://") {
+				tracked[i].Link = t.ClickURL(delivery.ID, item.Link)
+			}
+		}
+		data["Items"] = tracked
+	}
+}
+
+// ClickURL geeft de ondertekende redirect link voor een link in de nieuwsbrief terug
+func (t *NewsletterTracker) ClickURL(deliveryID, target string) string {
+	return t.baseURL + "/api/newsletter/track/click?t=" +
+		url.QueryEscape(t.signer.Sign(newsletterClickTokenPurpose, deliveryID+"|"+target))
+}
+
+// rewriteLinks vervangt de http(s) links in de HTML door ondertekende redirect links. De
+// afmeldlink wordt niet vervangen, zodat afmelden altijd direct werkt.
+func (t *NewsletterTracker) rewriteLinks(deliveryID, content string) string {
+	return newsletterLinkPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := newsletterLinkPattern.FindStringSubmatch(match)
+		target := html.UnescapeString(parts[3])
+		if strings.HasPrefix(target, t.baseURL+"/api/newsletter/unsubscribe") {
+			return match
+		}
+		return parts[1] + parts[2] + html.EscapeString(t.ClickURL(deliveryID, target)) + parts[4]
+	})
+}
+
+// ResultHandler geeft een BatchResultHandler terug die de afleveringen bijwerkt zodra de
+// batcher een ontvanger heeft verwerkt. Een email die alleen in de outbox is geplaatst blijft
+// queued; de outbox werkt de aflevering bij na de definitieve verzending of de dead-letter.
+func (t *NewsletterTracker) ResultHandler(deliveries map[string]*models.NewsletterDelivery) BatchResultHandler {
+	return func(recipient string, queued bool, sendErr error) {
+		delivery, ok := deliveries[recipient]
+		if !ok || (queued && sendErr == nil) {
+			return
+		}
+
+		ctx := context.Background()
+		status := models.NewsletterDeliverySent
+		var err error
+		if sendErr != nil {
+			status = models.NewsletterDeliveryFailed
+			err = t.deliveryRepo.MarkFailed(ctx, delivery.ID, sendErr.Error())
+		} else {
+			err = t.deliveryRepo.MarkSent(ctx, delivery.ID, time.Now())
+		}
+		if err != nil {
+			logger.Error("Kon aflevering van nieuwsbrief niet bijwerken", "error", err, "delivery_id", delivery.ID, "status", status)
+		}
+
+		if t.prometheusMetrics != nil {
+			t.prometheusMetrics.RecordNewsletterDelivery(status)
+		}
+	}
+}
+
+// RecordBounce markeert de meest recente nieuwsbrief aan een adres als gebounced
+func (t *NewsletterTracker) RecordBounce(ctx context.Context, email string) {
+	found, err := t.deliveryRepo.MarkBounced(ctx, strings.ToLower(strings.TrimSpace(email)), time.Now())
+	if err != nil {
+		logger.Error("Kon bounce van nieuwsbrief niet vastleggen", "error", err, "email", email)
+		return
+	}
+	if found && t.prometheusMetrics != nil {
+		t.prometheusMetrics.RecordNewsletterDelivery(models.NewsletterDeliveryBounced)
+	}
+}
+
+// Open registreert het openen van een nieuwsbrief via de tracking pixel
+func (t *NewsletterTracker) Open(ctx context.Context, token string) error {
+	deliveryID, err := t.signer.Verify(newsletterOpenTokenPurpose, token)
+	if err != nil {
+		return err
+	}
+
+	found, err := t.deliveryRepo.RecordOpen(ctx, deliveryID, time.Now())
+	if err != nil {
+		return err
+	}
+	if found && t.prometheusMetrics != nil {
+		t.prometheusMetrics.RecordNewsletterOpen()
+	}
+	return nil
+}
+
+// Click registreert een klik en geeft de oorspronkelijke link terug. Omdat de link in het
+// ondertekende token zit, kan de redirect niet misbruikt worden om naar andere sites te sturen.
+func (t *NewsletterTracker) Click(ctx context.Context, token string) (string, error) {
+	value, err := t.signer.Verify(newsletterClickTokenPurpose, token)
+	if err != nil {
+		return "", err
+	}
+	deliveryID, target, ok := strings.Cut(value, "|")
+	if !ok || target == "" {
+		return "", ErrInvalidSignedToken
+	}
+
+	found, err := t.deliveryRepo.RecordClick(ctx, deliveryID, time.Now())
+	if err != nil {
+		// De ontvanger moet altijd bij de link uitkomen, ook als het tellen mislukt
+		logger.Error("Kon klik op nieuwsbrief niet vastleggen", "error", err, "delivery_id", deliveryID)
+		return target, nil
+	}
+	if found && t.prometheusMetrics != nil {
+		t.prometheusMetrics.RecordNewsletterClick()
+	}
+	return target, nil
+}
+
+// Stats geeft de statistieken van een nieuwsbrief terug
+func (t *NewsletterTracker) Stats(ctx context.Context, newsletterID string) (*models.NewsletterStats, error) {
+	return t.deliveryRepo.GetStats(ctx, newsletterID)
+}
+
+// ListDeliveries haalt de afleveringen van een nieuwsbrief op, optioneel met één status
+func (t *NewsletterTracker) ListDeliveries(ctx context.Context, newsletterID, status string, limit, offset int) ([]*models.NewsletterDelivery, int64, error) {
+	return t.deliveryRepo.ListByNewsletter(ctx, newsletterID, status, limit, offset)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNewsletterNotFound wordt teruggegeven als de nieuwsbrief niet bestaat
+	ErrNewsletterNotFound = errors.New("nieuwsbrief niet gevonden")
+	// ErrNewsletterInvalidStatus wordt teruggegeven als een actie niet past bij de huidige status
+	ErrNewsletterInvalidStatus = errors.New("actie niet toegestaan in de huidige status van de nieuwsbrief")
+	// ErrNewsletterSelfApproval wordt teruggegeven als de opsteller, de laatste bewerker of de indiener
+	// de nieuwsbrief zelf goedkeurt
+	ErrNewsletterSelfApproval = errors.New("een nieuwsbrief moet door een tweede persoon worden goedgekeurd")
+	// ErrNewsletterScheduleInPast wordt teruggegeven bij een geplande tijd in het verleden
+	ErrNewsletterScheduleInPast = errors.New("geplande tijd moet in de toekomst liggen")
+	// ErrNewsletterSegmentNotFound wordt teruggegeven als het segment van de nieuwsbrief niet bestaat
+	ErrNewsletterSegmentNotFound = errors.New("segment niet gevonden")
+	// ErrNewsletterSegmentMismatch wordt teruggegeven als bij verzenden een ander segment wordt opgegeven
+	// dan het goedgekeurde segment van de nieuwsbrief
+	ErrNewsletterSegmentMismatch = errors.New("segment wijkt af van het goedgekeurde segment van de nieuwsbrief")
+)
+
+// NewsletterWorkflow bewaakt de levenscyclus van een nieuwsbrief:
+// draft → in_review → approved → scheduled → sending → sent (of failed).
+// Wijzigen van de inhoud na het indienen zet de nieuwsbrief terug naar draft.
+type NewsletterWorkflow struct {
+	nlRepo repository.NewsletterRepository
+	sender *NewsletterSender
+}
+
+// NewNewsletterWorkflow maakt een nieuwe NewsletterWorkflow
+func NewNewsletterWorkflow(nlRepo repository.NewsletterRepository, sender *NewsletterSender) *NewsletterWorkflow {
+	return &NewsletterWorkflow{nlRepo: nlRepo, sender: sender}
+}
+
+// Edit werkt onderwerp, inhoud en doelgroep bij. Een lege segmentID verwijdert het segment,
+// nil laat het ongewijzigd. Een ingediende, goedgekeurde of ingeplande nieuwsbrief gaat terug
+// naar draft, zodat de nieuwe inhoud en doelgroep opnieuw worden goedgekeurd. editorID wordt
+// als laatste bewerker vastgelegd en mag de nieuwsbrief daarna niet goedkeuren.
+func (w *NewsletterWorkflow) Edit(ctx context.Context, id, subject, content string, segmentID *string, editorID string) (*models.Newsletter, error) {
+	nl, err := w.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if nl.Status == models.NewsletterStatusSending || nl.Status == models.NewsletterStatusSent {
+		return nil, w.statusError(nl)
+	}
+
+	if subject != "" {
+		nl.Subject = subject
+	}
+	if content != "" {
+		nl.Content = content
+	}
+	if segmentID != nil {
+		if *segmentID == "" {
+			nl.SegmentID = nil
+		} else {
+			nl.SegmentID = segmentID
+		}
+	}
+	if editorID != "" {
+		nl.UpdatedBy = &editorID
+	}
+	if nl.Status != models.NewsletterStatusDraft {
+		resetToDraft(nl)
+	}
+
+	return nl, w.nlRepo.Update(ctx, nl)
+}
+
+// Submit dient een draft in ter goedkeuring; de indiener mag hem daarna niet goedkeuren
+func (w *NewsletterWorkflow) Submit(ctx context.Context, id, submitterID string) (*models.Newsletter, error) {
+	return w.transition(ctx, id, []string{models.NewsletterStatusDraft, models.NewsletterStatusFailed}, func(nl *models.Newsletter) error {
+		nl.Status = models.NewsletterStatusInReview
+		if submitterID != "" {
+			nl.SubmittedBy = &submitterID
+		}
+		return nil
+	})
+}
+
+// Approve keurt een ingediende nieuwsbrief goed. De goedkeurder moet een ander zijn dan de
+// opsteller, de laatste bewerker en de indiener.
+func (w *NewsletterWorkflow) Approve(ctx context.Context, id, approverID string) (*models.Newsletter, error) {
+	return w.transition(ctx, id, []string{models.NewsletterStatusInReview}, func(nl *models.Newsletter) error {
+		for _, author := range []*string{nl.CreatedBy, nl.UpdatedBy, nl.SubmittedBy} {
+			if author != nil && *author == approverID {
+				return ErrNewsletterSelfApproval
+			}
+		}
+		now := time.Now()
+		nl.Status = models.NewsletterStatusApproved
+		nl.ApprovedBy = &approverID
+		nl.ApprovedAt = &now
+		return nil
+	})
+}
+
+// Reject stuurt een ingediende, goedgekeurde of ingeplande nieuwsbrief terug naar draft
+func (w *NewsletterWorkflow) Reject(ctx context.Context, id string) (*models.Newsletter, error) {
+	return w.transition(ctx, id, []string{models.NewsletterStatusInReview, models.NewsletterStatusApproved, models.NewsletterStatusScheduled}, func(nl *models.Newsletter) error {
+		resetToDraft(nl)
+		return nil
+	})
+}
+
+// Schedule plant een goedgekeurde nieuwsbrief in; de scheduler verzendt hem op het opgegeven tijdstip
+func (w *NewsletterWorkflow) Schedule(ctx context.Context, id string, at time.Time) (*models.Newsletter, error) {
+	if !at.After(time.Now()) {
+		return nil, ErrNewsletterScheduleInPast
+	}
+	return w.transition(ctx, id, []string{models.NewsletterStatusApproved, models.NewsletterStatusScheduled}, func(nl *models.Newsletter) error {
+		nl.Status = models.NewsletterStatusScheduled
+		nl.ScheduledAt = &at
+		return nil
+	})
+}
+
+// Unschedule haalt een ingeplande nieuwsbrief uit de planning; hij blijft goedgekeurd
+func (w *NewsletterWorkflow) Unschedule(ctx context.Context, id string) (*models.Newsletter, error) {
+	return w.transition(ctx, id, []string{models.NewsletterStatusScheduled}, func(nl *models.Newsletter) error {
+		nl.Status = models.NewsletterStatusApproved
+		nl.ScheduledAt = nil
+		return nil
+	})
+}
+
+// TestSend verstuurt een voorbeeld van de nieuwsbrief naar één adres, ongeacht de status
+func (w *NewsletterWorkflow) TestSend(ctx context.Context, id, email string) error {
+	nl, err := w.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return w.sender.SendTest(nl, email)
+}
+
+// transition laadt de nieuwsbrief, controleert de huidige status en slaat de wijziging op
+func (w *NewsletterWorkflow) transition(ctx context.Context, id string, from []string, apply func(nl *models.Newsletter) error) (*models.Newsletter, error) {
+	nl, err := w.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(from, nl.Status) {
+		return nil, w.statusError(nl)
+	}
+
+	previous := nl.Status
+	if err := apply(nl); err != nil {
+		return nil, err
+	}
+	if err := w.nlRepo.Update(ctx, nl); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Status van nieuwsbrief gewijzigd", "newsletter_id", nl.ID, "van", previous, "naar", nl.Status)
+	return nl, nil
+}
+
+// get haalt de nieuwsbrief op en vertaalt een ontbrekende nieuwsbrief naar ErrNewsletterNotFound
+func (w *NewsletterWorkflow) get(ctx context.Context, id string) (*models.Newsletter, error) {
+	nl, err := w.nlRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if nl == nil {
+		return nil, ErrNewsletterNotFound
+	}
+	return nl, nil
+}
+
+// statusError geeft ErrNewsletterInvalidStatus terug met de huidige status erbij
+func (w *NewsletterWorkflow) statusError(nl *models.Newsletter) error {
+	return fmt.Errorf("%w (status: %s)", ErrNewsletterInvalidStatus, nl.Status)
+}
+
+// resetToDraft zet een nieuwsbrief terug naar draft en wist goedkeuring en planning
+func resetToDraft(nl *models.Newsletter) {
+	nl.Status = models.NewsletterStatusDraft
+	nl.ApprovedBy = nil
+	nl.ApprovedAt = nil
+	nl.ScheduledAt = nil
+}
+
+// containsString geeft aan of value in values voorkomt
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NotificationChannel levert een notificatie af op één kanaal
+type NotificationChannel interface {
+	// Name geeft de naam van het kanaal terug, zoals die in de afleveringen en routeringsregels staat
+	Name() string
+	// Deliver levert één notificatie af
+	Deliver(ctx context.Context, notification *models.Notification) error
+}
+
+// BatchNotificationChannel is een kanaal dat notificaties verzameld aflevert, zoals een digest.
+// Alleen kritieke notificaties worden direct met Deliver afgeleverd; de rest wacht op de volgende batch.
+type BatchNotificationChannel interface {
+	NotificationChannel
+	// DeliverBatch levert de verzamelde notificaties in één keer af
+	DeliverBatch(ctx context.Context, notifications []*models.Notification) error
+	// BatchInterval geeft aan hoe vaak een batch wordt afgeleverd
+	BatchInterval() time.Duration
+}
+
+// TelegramChannel levert notificaties af in de Telegram chats van de organisatie. Met een register
+// van chats krijgt elke chat alleen de types waarop hij is geabonneerd; zonder register, of
+// zolang het register leeg is, gaat alles naar de standaard chat van de client.
+type TelegramChannel struct {
+	client   NotificationClient
+	chatRepo repository.TelegramChatRepository
+}
+
+// NewTelegramChannel maakt een nieuw Telegram kanaal
+func NewTelegramChannel(client NotificationClient) *TelegramChannel {
+	return &TelegramChannel{client: client}
+}
+
+// SetChatRepository stelt het register van chats in
+func (c *TelegramChannel) SetChatRepository(chatRepo repository.TelegramChatRepository) {
+	c.chatRepo = chatRepo
+}
+
+// Name geeft de naam van het kanaal terug
+func (c *TelegramChannel) Name() string {
+	return models.NotificationChannelTelegram
+}
+
+// Deliver verstuurt de notificatie met een emoji op basis van de prioriteit. Notificaties over
+// een contactformulier of aanmelding krijgen knoppen om het record direct af te handelen.
+func (c *TelegramChannel) Deliver(ctx context.Context, notification *models.Notification) error {
+	title := formatTitleWithEmoji(notification.Priority, notification.Title)
+	keyboard := TelegramActionKeyboard(notification)
+
+	if chatClient, ok := c.client.(ChatNotificationClient); ok && c.chatRepo != nil {
+		chats, err := c.chatRepo.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list telegram chats: %w", err)
+		}
+		if len(chats) > 0 {
+			return c.deliverToChats(chatClient, chats, notification, title, keyboard)
+		}
+	}
+
+	if keyboard != nil {
+		if client, ok := c.client.(KeyboardNotificationClient); ok {
+			return client.SendMessageWithKeyboard(title, notification.Message, keyboard)
+		}
+	}
+	return c.client.SendMessage(title, notification.Message)
+}
+
+// deliverToChats verstuurt de notificatie naar elke chat die op het type is geabonneerd. Een
+// nieuwe poging zou de chats die hem al hebben opnieuw bereiken, daarom mislukt de aflevering
+// alleen als geen enkele chat hem heeft ontvangen.
+func (c *TelegramChannel) deliverToChats(client ChatNotificationClient, chats []*models.TelegramChat, notification *models.Notification, title string, keyboard TelegramInlineKeyboard) error {
+	var delivered int
+	var lastErr error
+	for _, chat := range chats {
+		if !chat.IsSubscribed(notification.Type) {
+			continue
+		}
+		if err := client.SendMessageToChat(strconv.FormatInt(chat.ChatID, 10), title, notification.Message, keyboard); err != nil {
+			logger.Warn("Kon notificatie niet naar telegram chat versturen",
+				"chat", chat.Naam,
+				"notification_id", notification.ID,
+				"error", err)
+			lastErr = err
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// maxWebhookTextLength is de maximale lengte van de tekst in een webhook; Discord weigert langere berichten
+const maxWebhookTextLength = 2000
+
+// webhookPayload is de body van een uitgaande webhook. Slack en Mattermost lezen text,
+// Discord leest content; andere ontvangers kunnen de notificatie zelf gebruiken.
+type webhookPayload struct {
+	Text         string               `json:"text"`
+	Content      string               `json:"content"`
+	Notification *models.Notification `json:"notification"`
+}
+
+// WebhookChannel levert notificaties af met een HTTP POST naar een uitgaande webhook
+type WebhookChannel struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel maakt een nieuw webhook kanaal
+func NewWebhookChannel(name, url string) *WebhookChannel {
+	return &WebhookChannel{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name geeft de naam van het kanaal terug
+func (c *WebhookChannel) Name() string {
+	return c.name
+}
+
+// Deliver post de notificatie als JSON naar de webhook
+func (c *WebhookChannel) Deliver(ctx context.Context, notification *models.Notification) error {
+	text := fmt.Sprintf("%s\n\n%s", formatTitleWithEmoji(notification.Priority, notification.Title), notification.Message)
+	if runes := []rune(text); len(runes) > maxWebhookTextLength {
+		text = string(runes[:maxWebhookTextLength-1]) + "…"
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Text:         text,
+		Content:      text,
+		Notification: notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook niet bereikbaar: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook gaf status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailDigestChannel stuurt medewerkers periodiek één email met de verzamelde notificaties
+type EmailDigestChannel struct {
+	emailSender EmailSender
+	recipients  []string
+	interval    time.Duration
+}
+
+// NewEmailDigestChannel maakt een nieuw email digest kanaal
+func NewEmailDigestChannel(emailSender EmailSender, recipients []string, interval time.Duration) *EmailDigestChannel {
+	return &EmailDigestChannel{
+		emailSender: emailSender,
+		recipients:  recipients,
+		interval:    interval,
+	}
+}
+
+// Name geeft de naam van het kanaal terug
+func (c *EmailDigestChannel) Name() string {
+	return models.NotificationChannelEmail
+}
+
+// BatchInterval geeft aan hoe vaak de digest wordt verstuurd
+func (c *EmailDigestChannel) BatchInterval() time.Duration {
+	return c.interval
+}
+
+// Deliver verstuurt een kritieke notificatie direct, als digest met één notificatie
+func (c *EmailDigestChannel) Deliver(ctx context.Context, notification *models.Notification) error {
+	return c.DeliverBatch(ctx, []*models.Notification{notification})
+}
+
+// DeliverBatch verstuurt de digest naar alle ontvangers. Lukt het bij één ontvanger niet,
+// dan geldt de batch als mislukt en wordt hij later opnieuw geprobeerd.
+func (c *EmailDigestChannel) DeliverBatch(ctx context.Context, notifications []*models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("DKL notificaties: %d nieuw", len(notifications))
+	if len(notifications) == 1 {
+		subject = "DKL notificatie: " + notifications[0].Title
+	}
+	data := map[string]interface{}{
+		"Notifications": notifications,
+		"Count":         len(notifications),
+	}
+
+	for _, recipient := range c.recipients {
+		if err := c.emailSender.SendTemplateEmail(recipient, subject, "notification_digest", data); err != nil {
+			return fmt.Errorf("digest naar %s mislukt: %w", recipient, err)
+		}
+	}
+	return nil
+}
+
+// InAppChannel zet notificaties in de inbox van de gebruikers die notificaties mogen lezen
+type InAppChannel struct {
+	inbox *NotificationInbox
+}
+
+// NewInAppChannel maakt een nieuw in-app kanaal
+func NewInAppChannel(inbox *NotificationInbox) *InAppChannel {
+	return &InAppChannel{inbox: inbox}
+}
+
+// Name geeft de naam van het kanaal terug
+func (c *InAppChannel) Name() string {
+	return models.NotificationChannelInApp
+}
+
+// Deliver maakt de notificatie aan voor elke gebruiker met de permissie notification:read
+func (c *InAppChannel) Deliver(ctx context.Context, notification *models.Notification) error {
+	_, err := c.inbox.DeliverToPermission(ctx, notification.ID, "notification", "read")
+	return err
+}
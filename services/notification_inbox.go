@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownNotificationType wordt teruggegeven voor een voorkeur voor een onbekend type notificatie
+var ErrUnknownNotificationType = errors.New("onbekend type notificatie")
+
+// Soorten live gebeurtenissen in de inbox
+const (
+	InboxEventNotification = "notification" // Nieuwe notificatie
+	InboxEventRead         = "read"         // Notificaties gelezen, bijvoorbeeld in een ander tabblad
+)
+
+// inboxSubscriberBuffer is het aantal gebeurtenissen dat voor een trage verbinding wordt bewaard
+const inboxSubscriberBuffer = 16
+
+// InboxEvent is een live gebeurtenis in de inbox van een gebruiker
+type InboxEvent struct {
+	Type         string                   `json:"type"`
+	Notification *models.UserNotification `json:"notification,omitempty"`
+	IDs          []string                 `json:"ids,omitempty"` // Gelezen notificaties; leeg betekent alle
+}
+
+// NotificationInbox beheert de persoonlijke inbox van gebruikers: notificaties met gelezen
+// status, voorkeuren per type en live doorsturen naar open verbindingen
+type NotificationInbox struct {
+	notifications repository.UserNotificationRepository
+	preferences   repository.NotificationPreferenceRepository
+
+	subscribers map[string]map[chan InboxEvent]struct{}
+	mutex       sync.RWMutex
+}
+
+// NewNotificationInbox maakt een nieuwe inbox service
+func NewNotificationInbox(
+	notifications repository.UserNotificationRepository,
+	preferences repository.NotificationPreferenceRepository,
+) *NotificationInbox {
+	return &NotificationInbox{
+		notifications: notifications,
+		preferences:   preferences,
+		subscribers:   make(map[string]map[chan InboxEvent]struct{}),
+	}
+}
+
+// Notify zet een persoonlijke notificatie in de inbox van een gebruiker en stuurt hem live door.
+// Heeft de gebruiker dit type uitgezet, dan wordt er niets aangemaakt en is het resultaat nil.
+func (s *NotificationInbox) Notify(ctx context.Context, userID string, notificationType models.NotificationType, title, message, link string) (*models.UserNotification, error) {
+	preference, err := s.preferences.Get(ctx, userID, notificationType)
+	if err != nil {
+		return nil, err
+	}
+	if preference != nil && !preference.InApp {
+		return nil, nil
+	}
+
+	notification := &models.UserNotification{
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+		Link:    link,
+	}
+	if err := s.notifications.Create(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	if preference == nil || preference.Push {
+		s.publish(userID, InboxEvent{Type: InboxEventNotification, Notification: notification})
+	}
+	return notification, nil
+}
+
+// DeliverToPermission zet een systeemnotificatie in de inbox van alle gebruikers met de gegeven
+// permissie en stuurt hem live door. Geeft terug bij hoeveel gebruikers hij is aangekomen.
+func (s *NotificationInbox) DeliverToPermission(ctx context.Context, notificationID, resource, action string) (int, error) {
+	created, err := s.notifications.CreateForPermission(ctx, notificationID, resource, action)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, notification := range created {
+		if !s.hasSubscribers(notification.UserID) {
+			continue
+		}
+		preference, err := s.preferences.Get(ctx, notification.UserID, notification.Type)
+		if err != nil {
+			logger.Warn("Kon notificatie voorkeur niet ophalen", "user_id", notification.UserID, "error", err)
+			continue
+		}
+		if preference == nil || preference.Push {
+			s.publish(notification.UserID, InboxEvent{Type: InboxEventNotification, Notification: notification})
+		}
+	}
+	return len(created), nil
+}
+
+// List haalt de notificaties van een gebruiker op, nieuwste eerst
+func (s *NotificationInbox) List(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*models.UserNotification, error) {
+	return s.notifications.ListByUser(ctx, userID, unreadOnly, limit, offset)
+}
+
+// UnreadCount telt de ongelezen notificaties van een gebruiker
+func (s *NotificationInbox) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	return s.notifications.CountUnread(ctx, userID)
+}
+
+// MarkRead markeert notificaties als gelezen; andere open verbindingen van de gebruiker krijgen dat live te zien
+func (s *NotificationInbox) MarkRead(ctx context.Context, userID string, ids []string) (int64, error) {
+	updated, err := s.notifications.MarkRead(ctx, userID, ids)
+	if err != nil {
+		return 0, err
+	}
+	if updated > 0 {
+		s.publish(userID, InboxEvent{Type: InboxEventRead, IDs: ids})
+	}
+	return updated, nil
+}
+
+// MarkAllRead markeert alle notificaties van een gebruiker als gelezen
+func (s *NotificationInbox) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	updated, err := s.notifications.MarkAllRead(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if updated > 0 {
+		s.publish(userID, InboxEvent{Type: InboxEventRead})
+	}
+	return updated, nil
+}
+
+// Delete verwijdert een notificatie uit de inbox van een gebruiker
+func (s *NotificationInbox) Delete(ctx context.Context, userID, id string) (bool, error) {
+	return s.notifications.Delete(ctx, userID, id)
+}
+
+// Preferences geeft de voorkeuren van een gebruiker voor alle types. Types zonder opgeslagen
+// voorkeur staan aan.
+func (s *NotificationInbox) Preferences(ctx context.Context, userID string) ([]*models.NotificationPreference, error) {
+	stored, err := s.preferences.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[models.NotificationType]*models.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		byType[preference.Type] = preference
+	}
+
+	preferences := make([]*models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preference, ok := byType[notificationType]
+		if !ok {
+			preference = &models.NotificationPreference{UserID: userID, Type: notificationType, InApp: true, Push: true}
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, nil
+}
+
+// SetPreference slaat de voorkeur van een gebruiker voor één type op
+func (s *NotificationInbox) SetPreference(ctx context.Context, preference *models.NotificationPreference) error {
+	preference.Type = models.NotificationType(strings.ToLower(strings.TrimSpace(string(preference.Type))))
+	if !isKnownNotificationType(preference.Type) {
+		return ErrUnknownNotificationType
+	}
+	return s.preferences.Save(ctx, preference)
+}
+
+// Subscribe opent een live verbinding voor een gebruiker. De gebeurtenissen komen op het kanaal
+// binnen tot de teruggegeven functie wordt aangeroepen. Een verbinding die niet bijhoudt mist
+// gebeurtenissen in plaats van de andere verbindingen op te houden.
+func (s *NotificationInbox) Subscribe(userID string) (<-chan InboxEvent, func()) {
+	events := make(chan InboxEvent, inboxSubscriberBuffer)
+
+	s.mutex.Lock()
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[chan InboxEvent]struct{})
+	}
+	s.subscribers[userID][events] = struct{}{}
+	s.mutex.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			delete(s.subscribers[userID], events)
+			if len(s.subscribers[userID]) == 0 {
+				delete(s.subscribers, userID)
+			}
+			s.mutex.Unlock()
+			close(events)
+		})
+	}
+	return events, unsubscribe
+}
+
+// hasSubscribers controleert of een gebruiker een open live verbinding heeft
+func (s *NotificationInbox) hasSubscribers(userID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.subscribers[userID]) > 0
+}
+
+// publish stuurt een gebeurtenis naar alle open verbindingen van een gebruiker
+func (s *NotificationInbox) publish(userID string, event InboxEvent) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for events := range s.subscribers[userID] {
+		select {
+		case events <- event:
+		default:
+			logger.Debug("Live inbox verbinding loopt achter, gebeurtenis overgeslagen", "user_id", userID)
+		}
+	}
+}
+
+// isKnownNotificationType controleert of een type notificatie bestaat
+func isKnownNotificationType(notificationType models.NotificationType) bool {
+	for _, known := range models.NotificationTypes {
+		if known == notificationType {
+			return true
+		}
+	}
+	return false
+}
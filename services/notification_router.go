@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Standaard instellingen voor het opnieuw proberen van een mislukte aflevering
+const (
+	DefaultNotificationMaxAttempts   = 5
+	DefaultNotificationRetryDelay    = time.Minute
+	DefaultNotificationMaxRetryDelay = time.Hour
+)
+
+// NotificationRoute bepaalt welke notificaties een kanaal krijgt. Een regel voor "webhook"
+// geldt ook voor de kanalen webhook_2, webhook_3 enzovoort.
+type NotificationRoute struct {
+	Channel     string
+	Types       []models.NotificationType // Leeg betekent alle types
+	MinPriority models.NotificationPriority
+}
+
+// Matches controleert of de regel geldt voor het kanaal en de notificatie
+func (r NotificationRoute) Matches(channel string, notification *models.Notification) bool {
+	if channel != r.Channel && !strings.HasPrefix(channel, r.Channel+"_") {
+		return false
+	}
+	if !isPriorityHighEnough(notification.Priority, r.MinPriority) {
+		return false
+	}
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, t := range r.Types {
+		if t == notification.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultNotificationRoutes geeft de standaard regels: Telegram vanaf de minimale prioriteit,
+// de email digest vanaf medium, webhooks vanaf high en in-app alles
+func DefaultNotificationRoutes(telegramMinPriority models.NotificationPriority) []NotificationRoute {
+	return []NotificationRoute{
+		{Channel: models.NotificationChannelTelegram, MinPriority: telegramMinPriority},
+		{Channel: models.NotificationChannelEmail, MinPriority: models.NotificationPriorityMedium},
+		{Channel: models.NotificationChannelWebhook, MinPriority: models.NotificationPriorityHigh},
+		{Channel: models.NotificationChannelInApp, MinPriority: models.NotificationPriorityLow},
+	}
+}
+
+// ParseNotificationRoutes leest regels in de vorm "kanaal=types:prioriteit", gescheiden door
+// puntkomma's. Types zijn gescheiden door komma's, * betekent alle types, bijvoorbeeld
+// "telegram=*:high;email=contact,aanmelding:low".
+func ParseNotificationRoutes(spec string) ([]NotificationRoute, error) {
+	var routes []NotificationRoute
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		channel, rest, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("ongeldige notificatie regel %q: kanaal ontbreekt", part)
+		}
+		types, priority, ok := strings.Cut(rest, ":")
+		if !ok {
+			priority = string(models.NotificationPriorityLow)
+		}
+
+		route := NotificationRoute{
+			Channel:     strings.TrimSpace(channel),
+			MinPriority: models.NotificationPriority(strings.ToLower(strings.TrimSpace(priority))),
+		}
+		if route.Channel == "" {
+			return nil, fmt.Errorf("ongeldige notificatie regel %q: kanaal ontbreekt", part)
+		}
+		if !isValidNotificationPriority(route.MinPriority) {
+			return nil, fmt.Errorf("ongeldige prioriteit in notificatie regel %q", part)
+		}
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if t != "" && t != "*" {
+				route.Types = append(route.Types, models.NotificationType(t))
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// NotificationRouter verdeelt notificaties over de kanalen volgens de regels. Elk kanaal
+// krijgt een eigen aflevering met status en pogingen, zodat een mislukt kanaal apart
+// opnieuw wordt geprobeerd zonder de andere kanalen opnieuw te versturen.
+type NotificationRouter struct {
+	notificationRepo repository.NotificationRepository
+	deliveryRepo     repository.NotificationDeliveryRepository
+	channels         map[string]NotificationChannel
+	channelOrder     []string
+	routes           []NotificationRoute
+	maxAttempts      int
+	retryDelay       time.Duration
+	maxRetryDelay    time.Duration
+
+	started   time.Time
+	lastBatch map[string]time.Time
+	mutex     sync.Mutex
+}
+
+// NewNotificationRouter maakt een nieuwe router voor de gegeven kanalen en regels
+func NewNotificationRouter(
+	notificationRepo repository.NotificationRepository,
+	deliveryRepo repository.NotificationDeliveryRepository,
+	channels []NotificationChannel,
+	routes []NotificationRoute,
+) *NotificationRouter {
+	r := &NotificationRouter{
+		notificationRepo: notificationRepo,
+		deliveryRepo:     deliveryRepo,
+		channels:         make(map[string]NotificationChannel, len(channels)),
+		routes:           routes,
+		maxAttempts:      DefaultNotificationMaxAttempts,
+		retryDelay:       DefaultNotificationRetryDelay,
+		maxRetryDelay:    DefaultNotificationMaxRetryDelay,
+		started:          time.Now(),
+		lastBatch:        make(map[string]time.Time),
+	}
+	for _, channel := range channels {
+		r.channels[channel.Name()] = channel
+		r.channelOrder = append(r.channelOrder, channel.Name())
+	}
+	return r
+}
+
+// SetRetryPolicy stelt het maximale aantal pogingen per kanaal en de wachttijd tussen pogingen in.
+// De wachttijd verdubbelt na elke mislukte poging, tot maxDelay.
+func (r *NotificationRouter) SetRetryPolicy(maxAttempts int, delay, maxDelay time.Duration) {
+	if maxAttempts > 0 {
+		r.maxAttempts = maxAttempts
+	}
+	if delay > 0 {
+		r.retryDelay = delay
+	}
+	if maxDelay > 0 {
+		r.maxRetryDelay = maxDelay
+	}
+}
+
+// Channels geeft de namen van de geconfigureerde kanalen
+func (r *NotificationRouter) Channels() []string {
+	return r.channelOrder
+}
+
+// ChannelsFor geeft de kanalen waarop een notificatie volgens de regels wordt afgeleverd
+func (r *NotificationRouter) ChannelsFor(notification *models.Notification) []string {
+	var channels []string
+	for _, name := range r.channelOrder {
+		for _, route := range r.routes {
+			if route.Matches(name, notification) {
+				channels = append(channels, name)
+				break
+			}
+		}
+	}
+	return channels
+}
+
+// Deliveries haalt de afleveringen van een notificatie op
+func (r *NotificationRouter) Deliveries(ctx context.Context, notificationID string) ([]*models.NotificationDelivery, error) {
+	return r.deliveryRepo.ListByNotification(ctx, notificationID)
+}
+
+// Deliver levert een notificatie af op alle kanalen waarvoor nog een aflevering openstaat en
+// waarvan de volgende poging is aangebroken. Batch kanalen krijgen alleen kritieke notificaties
+// direct. Zijn alle afleveringen afgerond, dan wordt de notificatie als verzonden gemarkeerd.
+func (r *NotificationRouter) Deliver(ctx context.Context, notification *models.Notification) error {
+	if err := r.deliveryRepo.EnsureDeliveries(ctx, notification.ID, r.ChannelsFor(notification)); err != nil {
+		return fmt.Errorf("failed to create deliveries: %w", err)
+	}
+
+	deliveries, err := r.deliveryRepo.ListByNotification(ctx, notification.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list deliveries: %w", err)
+	}
+
+	now := time.Now()
+	var failed []string
+	for _, delivery := range deliveries {
+		if delivery.IsFinal() || (delivery.NextAttemptAt != nil && delivery.NextAttemptAt.After(now)) {
+			continue
+		}
+
+		channel, ok := r.channels[delivery.Channel]
+		if !ok {
+			// Het kanaal is uit de configuratie gehaald; opnieuw proberen heeft geen zin
+			delivery.Attempts = r.maxAttempts
+			r.record(ctx, delivery, fmt.Errorf("kanaal %s is niet geconfigureerd", delivery.Channel))
+			continue
+		}
+		if _, isBatch := channel.(BatchNotificationChannel); isBatch && notification.Priority != models.NotificationPriorityCritical {
+			continue
+		}
+
+		if err := channel.Deliver(ctx, notification); err != nil {
+			failed = append(failed, delivery.Channel)
+			logger.Warn("Aflevering van notificatie mislukt",
+				"id", notification.ID,
+				"channel", delivery.Channel,
+				"attempt", delivery.Attempts+1,
+				"error", err)
+			r.record(ctx, delivery, err)
+			continue
+		}
+		r.record(ctx, delivery, nil)
+	}
+
+	if err := r.completeIfFinal(ctx, notification, deliveries); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("aflevering mislukt voor kanalen: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// FlushBatches levert de verzamelde notificaties af op de batch kanalen waarvan het interval
+// is verstreken
+func (r *NotificationRouter) FlushBatches(ctx context.Context) error {
+	now := time.Now()
+	for _, name := range r.channelOrder {
+		channel, ok := r.channels[name].(BatchNotificationChannel)
+		if !ok || !r.batchDue(name, channel.BatchInterval(), now) {
+			continue
+		}
+
+		if err := r.flushBatch(ctx, channel, now); err != nil {
+			logger.Error("Fout bij afleveren van notificatie batch", "channel", name, "error", err)
+		}
+	}
+	return nil
+}
+
+// batchDue controleert of het interval van een batch kanaal is verstreken en legt dan de
+// nieuwe batch vast
+func (r *NotificationRouter) batchDue(name string, interval time.Duration, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	last, ok := r.lastBatch[name]
+	if !ok {
+		last = r.started
+	}
+	if now.Sub(last) < interval {
+		return false
+	}
+	r.lastBatch[name] = now
+	return true
+}
+
+// flushBatch levert alle openstaande afleveringen van één batch kanaal in één keer af
+func (r *NotificationRouter) flushBatch(ctx context.Context, channel BatchNotificationChannel, now time.Time) error {
+	deliveries, err := r.deliveryRepo.ListDue(ctx, channel.Name(), now)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	notifications := make([]*models.Notification, 0, len(deliveries))
+	batch := make([]*models.NotificationDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		notification, err := r.notificationRepo.GetByID(ctx, delivery.NotificationID)
+		if err != nil {
+			return err
+		}
+		if notification == nil {
+			continue
+		}
+		notifications = append(notifications, notification)
+		batch = append(batch, delivery)
+	}
+
+	deliverErr := channel.DeliverBatch(ctx, notifications)
+	for _, delivery := range batch {
+		r.record(ctx, delivery, deliverErr)
+	}
+
+	for _, notification := range notifications {
+		if err := r.completeIfFinal(ctx, notification, nil); err != nil {
+			logger.Error("Fout bij het updaten van notificatie status", "id", notification.ID, "error", err)
+		}
+	}
+
+	if deliverErr != nil {
+		return deliverErr
+	}
+	logger.Info("Notificatie batch afgeleverd", "channel", channel.Name(), "count", len(notifications))
+	return nil
+}
+
+// record legt de uitkomst van een poging vast. Na een mislukte poging wordt de volgende poging
+// met exponentieel oplopende wachttijd ingepland, tot het maximale aantal pogingen is bereikt.
+func (r *NotificationRouter) record(ctx context.Context, delivery *models.NotificationDelivery, deliverErr error) {
+	now := time.Now()
+	delivery.Attempts++
+	if deliverErr == nil {
+		delivery.Status = models.NotificationDeliverySent
+		delivery.SentAt = &now
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+	} else {
+		delivery.LastError = deliverErr.Error()
+		if delivery.Attempts >= r.maxAttempts {
+			delivery.Status = models.NotificationDeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(r.retryBackoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	if err := r.deliveryRepo.Update(ctx, delivery); err != nil {
+		logger.Error("Fout bij het updaten van aflevering",
+			"notification_id", delivery.NotificationID,
+			"channel", delivery.Channel,
+			"error", err)
+	}
+}
+
+// retryBackoff geeft de wachttijd na het gegeven aantal mislukte pogingen
+func (r *NotificationRouter) retryBackoff(attempts int) time.Duration {
+	delay := r.retryDelay
+	for i := 1; i < attempts && delay < r.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.maxRetryDelay {
+		delay = r.maxRetryDelay
+	}
+	return delay
+}
+
+// completeIfFinal markeert de notificatie als verzonden als alle afleveringen zijn afgerond.
+// Zonder afleveringen worden ze opnieuw opgehaald.
+func (r *NotificationRouter) completeIfFinal(ctx context.Context, notification *models.Notification, deliveries []*models.NotificationDelivery) error {
+	if notification.Sent {
+		return nil
+	}
+	if deliveries == nil {
+		var err error
+		if deliveries, err = r.deliveryRepo.ListByNotification(ctx, notification.ID); err != nil {
+			return fmt.Errorf("failed to list deliveries: %w", err)
+		}
+	}
+
+	var sentAt *time.Time
+	for _, delivery := range deliveries {
+		if !delivery.IsFinal() {
+			return nil
+		}
+		if delivery.SentAt != nil && (sentAt == nil || delivery.SentAt.After(*sentAt)) {
+			sentAt = delivery.SentAt
+		}
+	}
+
+	if sentAt == nil {
+		now := time.Now()
+		sentAt = &now
+	}
+	notification.Sent = true
+	notification.SentAt = sentAt
+	if err := r.notificationRepo.Update(ctx, notification); err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+	return nil
+}
+
+// isValidNotificationPriority controleert of een prioriteit bestaat
+func isValidNotificationPriority(priority models.NotificationPriority) bool {
+	switch priority {
+	case models.NotificationPriorityLow, models.NotificationPriorityMedium,
+		models.NotificationPriorityHigh, models.NotificationPriorityCritical:
+		return true
+	}
+	return false
+}
@@ -13,25 +13,26 @@ import (
 	"time"
 )
 
-// NotificationThrottleKey is een struct voor het bijhouden van throttling
-type NotificationThrottleKey struct {
-	Type    models.NotificationType
-	Title   string
-	Message string
-}
-
-// NotificationThrottleValue is een struct voor het bijhouden van throttling
-type NotificationThrottleValue struct {
-	LastSent time.Time
-	Count    int
-}
-
 // NotificationClient is een interface voor het verzenden van notificaties
 type NotificationClient interface {
 	// SendMessage verstuurt een bericht
 	SendMessage(title, message string) error
 }
 
+// KeyboardNotificationClient is een NotificationClient die knoppen onder een bericht kan zetten
+type KeyboardNotificationClient interface {
+	NotificationClient
+	// SendMessageWithKeyboard verstuurt een bericht met knoppen
+	SendMessageWithKeyboard(title, message string, keyboard TelegramInlineKeyboard) error
+}
+
+// ChatNotificationClient is een NotificationClient die naar een andere chat dan de standaard chat kan versturen
+type ChatNotificationClient interface {
+	KeyboardNotificationClient
+	// SendMessageToChat verstuurt een bericht naar een specifieke chat
+	SendMessageToChat(chatID, title, message string, keyboard TelegramInlineKeyboard) error
+}
+
 // TelegramClient implementeert NotificationClient met Telegram
 type TelegramClient struct {
 	BotToken string
@@ -50,9 +51,19 @@ func NewTelegramClient(botToken, chatID string) *TelegramClient {
 
 // SendMessage stuurt een bericht naar Telegram
 func (t *TelegramClient) SendMessage(title, message string) error {
-	if t.BotToken == "" || t.ChatID == "" {
+	return t.SendMessageWithKeyboard(title, message, nil)
+}
+
+// SendMessageWithKeyboard stuurt een bericht naar Telegram met knoppen onder het bericht
+func (t *TelegramClient) SendMessageWithKeyboard(title, message string, keyboard TelegramInlineKeyboard) error {
+	return t.SendMessageToChat(t.ChatID, title, message, keyboard)
+}
+
+// SendMessageToChat stuurt een bericht naar een specifieke Telegram chat
+func (t *TelegramClient) SendMessageToChat(chatID, title, message string, keyboard TelegramInlineKeyboard) error {
+	if t.BotToken == "" || chatID == "" {
 		return fmt.Errorf("telegram not configured (bot token: %v, chat id: %v)",
-			t.BotToken != "", t.ChatID != "")
+			t.BotToken != "", chatID != "")
 	}
 
 	fullMessage := fmt.Sprintf("%s\n\n%s", title, message)
@@ -62,9 +73,16 @@ func (t *TelegramClient) SendMessage(title, message string) error {
 
 	// Parameters voor het bericht
 	params := url.Values{}
-	params.Add("chat_id", t.ChatID)
+	params.Add("chat_id", chatID)
 	params.Add("text", fullMessage)
 	params.Add("parse_mode", "HTML")
+	if len(keyboard) > 0 {
+		markup, err := keyboard.Markup()
+		if err != nil {
+			return err
+		}
+		params.Add("reply_markup", markup)
+	}
 
 	// HTTP POST request
 	resp, err := t.client.PostForm(apiURL, params)
@@ -85,8 +103,8 @@ func (t *TelegramClient) SendMessage(title, message string) error {
 type NotificationServiceImpl struct {
 	notificationRepo repository.NotificationRepository
 	client           NotificationClient
-	throttleMap      map[NotificationThrottleKey]*NotificationThrottleValue
-	throttleDuration time.Duration
+	router           *NotificationRouter
+	throttler        *NotificationThrottler
 	minPriority      models.NotificationPriority
 	ticker           *time.Ticker
 	running          bool
@@ -94,30 +112,47 @@ type NotificationServiceImpl struct {
 	startupDone      bool
 }
 
-// NewNotificationService maakt een nieuwe notificatie service
+// NewNotificationService maakt een nieuwe notificatie service. Zonder throttler worden
+// notificaties niet afgeremd.
 func NewNotificationService(
 	notificationRepo repository.NotificationRepository,
 	client NotificationClient,
-	throttleDuration time.Duration,
+	throttler *NotificationThrottler,
 	minPriority models.NotificationPriority,
 ) *NotificationServiceImpl {
 	return &NotificationServiceImpl{
 		notificationRepo: notificationRepo,
 		client:           client,
-		throttleMap:      make(map[NotificationThrottleKey]*NotificationThrottleValue),
-		throttleDuration: throttleDuration,
+		throttler:        throttler,
 		minPriority:      minPriority,
 		mutex:            sync.Mutex{},
 		startupDone:      false,
 	}
 }
 
+// SetRouter verdeelt notificaties voortaan over meerdere kanalen. De regels van de router
+// bepalen dan welke notificaties waar heen gaan, in plaats van de minimale prioriteit.
+func (s *NotificationServiceImpl) SetRouter(router *NotificationRouter) {
+	s.router = router
+}
+
 // CreateNotification maakt een nieuwe notificatie aan
 func (s *NotificationServiceImpl) CreateNotification(
 	ctx context.Context,
 	notificationType models.NotificationType,
 	priority models.NotificationPriority,
 	title, message string,
+) (*models.Notification, error) {
+	return s.CreateReferencedNotification(ctx, notificationType, priority, title, message, "")
+}
+
+// CreateReferencedNotification maakt een nieuwe notificatie aan over een contactformulier of
+// aanmelding; zonder referenceID is het een gewone notificatie
+func (s *NotificationServiceImpl) CreateReferencedNotification(
+	ctx context.Context,
+	notificationType models.NotificationType,
+	priority models.NotificationPriority,
+	title, message, referenceID string,
 ) (*models.Notification, error) {
 	notification := &models.Notification{
 		Type:     notificationType,
@@ -126,14 +161,17 @@ func (s *NotificationServiceImpl) CreateNotification(
 		Message:  message,
 		Sent:     false,
 	}
+	if referenceID != "" {
+		notification.ReferenceID = &referenceID
+	}
 
 	if err := s.notificationRepo.Create(ctx, notification); err != nil {
 		return nil, fmt.Errorf("failed to create notification: %w", err)
 	}
 
 	// Als de prioriteit hoger is dan of gelijk aan de minimaal ingestelde prioriteit,
-	// probeer de notificatie meteen te verzenden
-	if isPriorityHighEnough(priority, s.minPriority) {
+	// probeer de notificatie meteen te verzenden. Met een router bepalen de regels per kanaal dat.
+	if s.router != nil || isPriorityHighEnough(priority, s.minPriority) {
 		go func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
@@ -153,8 +191,20 @@ func (s *NotificationServiceImpl) SendNotification(ctx context.Context, notifica
 		return nil
 	}
 
+	// Tijdens de stille uren blijft de notificatie onverzonden staan tot erna
+	if s.throttler != nil && s.throttler.Held(notification) {
+		logger.Debug("Notificatie aangehouden tot na de stille uren",
+			"id", notification.ID,
+			"type", notification.Type)
+		return nil
+	}
+
+	if s.router != nil {
+		return s.routeNotification(ctx, notification)
+	}
+
 	// Controleer of de prioriteit hoog genoeg is
-	if !isPriorityHighEnough(notification.Priority, s.minPriority) {
+	if !s.priorityAllowed(notification, s.minPriority) {
 		logger.Info("Notificatie overgeslagen vanwege lage prioriteit",
 			"id", notification.ID,
 			"type", notification.Type,
@@ -164,14 +214,16 @@ func (s *NotificationServiceImpl) SendNotification(ctx context.Context, notifica
 	}
 
 	// Controleer throttling
-	if !s.shouldSendNotification(notification) {
-		logger.Info("Notificatie overgeslagen vanwege throttling",
-			"id", notification.ID,
-			"type", notification.Type,
-			"title", notification.Title)
-		return nil
+	if send, err := s.shouldSendNotification(ctx, notification); err != nil || !send {
+		return err
 	}
 
+	return s.deliverDirect(ctx, notification)
+}
+
+// deliverDirect verstuurt een notificatie zonder router via de standaard client
+func (s *NotificationServiceImpl) deliverDirect(ctx context.Context, notification *models.Notification) error {
+
 	// Voeg emoji toe op basis van prioriteit
 	formattedTitle := formatTitleWithEmoji(notification.Priority, notification.Title)
 
@@ -203,6 +255,40 @@ func (s *NotificationServiceImpl) SendNotification(ctx context.Context, notifica
 	return nil
 }
 
+// routeNotification levert een notificatie af via de router. Throttling geldt alleen voor de
+// eerste aflevering; nieuwe pogingen voor een mislukt kanaal worden niet tegengehouden.
+func (s *NotificationServiceImpl) routeNotification(ctx context.Context, notification *models.Notification) error {
+	deliveries, err := s.router.Deliveries(ctx, notification.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(deliveries) == 0 {
+		if !s.priorityAllowed(notification, models.NotificationPriorityLow) {
+			logger.Info("Notificatie overgeslagen vanwege lage prioriteit",
+				"id", notification.ID,
+				"type", notification.Type,
+				"priority", notification.Priority)
+			return nil
+		}
+		if send, err := s.shouldSendNotification(ctx, notification); err != nil || !send {
+			return err
+		}
+	}
+
+	if err := s.router.Deliver(ctx, notification); err != nil {
+		return err
+	}
+
+	if notification.Sent {
+		logger.Info("Notificatie succesvol verzonden",
+			"id", notification.ID,
+			"type", notification.Type,
+			"priority", notification.Priority)
+	}
+	return nil
+}
+
 // GetNotification haalt een notificatie op basis van ID
 func (s *NotificationServiceImpl) GetNotification(ctx context.Context, id string) (*models.Notification, error) {
 	return s.notificationRepo.GetByID(ctx, id)
@@ -215,6 +301,18 @@ func (s *NotificationServiceImpl) ListUnsentNotifications(ctx context.Context) (
 
 // ProcessUnsentNotifications verwerkt alle niet verzonden notificaties
 func (s *NotificationServiceImpl) ProcessUnsentNotifications(ctx context.Context) error {
+	// Digests van afgeremde notificaties waarvan het venster is verstreken
+	if err := s.sendThrottleDigests(ctx); err != nil {
+		logger.Error("Fout bij verzenden van notificatie digests", "error", err)
+	}
+
+	// Verzamelde notificaties voor digests gaan eerst, zodat ze in één batch meegaan
+	if s.router != nil {
+		if err := s.router.FlushBatches(ctx); err != nil {
+			logger.Error("Fout bij afleveren van notificatie batches", "error", err)
+		}
+	}
+
 	notifications, err := s.notificationRepo.ListUnsent(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list unsent notifications: %w", err)
@@ -285,7 +383,7 @@ func (s *NotificationServiceImpl) Start() {
 	}()
 
 	logger.Info("Notificatie service gestart",
-		"throttle", s.throttleDuration.String(),
+		"throttling", s.throttler != nil,
 		"min_priority", s.minPriority)
 }
 
@@ -331,54 +429,84 @@ func (s *NotificationServiceImpl) IsRunning() bool {
 	return s.running
 }
 
-// shouldSendNotification controleert of een notificatie verzonden mag worden op basis van throttling
-func (s *NotificationServiceImpl) shouldSendNotification(notification *models.Notification) bool {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// priorityAllowed controleert de prioriteit volgens de throttle regel van het type, met fallback
+// als de regel geen minimale prioriteit heeft
+func (s *NotificationServiceImpl) priorityAllowed(notification *models.Notification, fallback models.NotificationPriority) bool {
+	if s.throttler == nil {
+		return isPriorityHighEnough(notification.Priority, fallback)
+	}
+	return s.throttler.PriorityAllowed(notification, fallback)
+}
 
-	// Kritieke notificaties altijd verzenden
-	if notification.Priority == models.NotificationPriorityCritical {
-		return true
+// shouldSendNotification controleert of een notificatie verzonden mag worden op basis van throttling.
+// Een afgeremde notificatie wordt als afgehandeld gemarkeerd; hij komt mee in de digest van het venster.
+func (s *NotificationServiceImpl) shouldSendNotification(ctx context.Context, notification *models.Notification) (bool, error) {
+	if s.throttler == nil {
+		return true, nil
 	}
 
-	key := NotificationThrottleKey{
-		Type:    notification.Type,
-		Title:   notification.Title,
-		Message: notification.Message,
+	allowed, err := s.throttler.Allow(ctx, notification)
+	if err != nil {
+		// Liever een notificatie te veel dan een gemiste notificatie
+		logger.Warn("Throttling niet beschikbaar, notificatie wordt verzonden",
+			"id", notification.ID,
+			"error", err)
+		return true, nil
+	}
+	if allowed {
+		return true, nil
 	}
 
-	now := time.Now()
-	value, exists := s.throttleMap[key]
+	notification.Sent = true
+	if err := s.notificationRepo.Update(ctx, notification); err != nil {
+		return false, fmt.Errorf("failed to update throttled notification: %w", err)
+	}
 
-	if !exists {
-		// Nieuwe notificatie, nog niet eerder gezien
-		s.throttleMap[key] = &NotificationThrottleValue{
-			LastSent: now,
-			Count:    1,
-		}
-		return true
+	logger.Info("Notificatie afgeremd, wordt meegenomen in digest",
+		"id", notification.ID,
+		"type", notification.Type,
+		"title", notification.Title)
+	return false, nil
+}
+
+// sendThrottleDigests verstuurt de digests van afgeremde notificaties. Een digest wordt als
+// gewone notificatie opgeslagen, zodat een mislukte aflevering later opnieuw wordt geprobeerd.
+func (s *NotificationServiceImpl) sendThrottleDigests(ctx context.Context) error {
+	if s.throttler == nil {
+		return nil
 	}
 
-	// Als de laatste notificatie langer geleden is dan de throttle duur,
-	// stuur dan opnieuw en reset de teller
-	if now.Sub(value.LastSent) > s.throttleDuration {
-		value.LastSent = now
-		value.Count = 1
-		return true
+	digests, err := s.throttler.DueDigests(ctx)
+	if err != nil {
+		return err
 	}
 
-	// Verhoog de teller
-	value.Count++
+	for _, digest := range digests {
+		if err := s.notificationRepo.Create(ctx, digest); err != nil {
+			logger.Error("Fout bij opslaan van notificatie digest",
+				"type", digest.Type,
+				"error", err)
+			continue
+		}
 
-	// Voor sommige notificaties willen we misschien toch een update sturen,
-	// bijvoorbeeld als er erg veel zijn in korte tijd
-	if value.Count >= 10 && notification.Priority == models.NotificationPriorityHigh {
-		value.LastSent = now
-		value.Count = 0
-		return true
-	}
+		if s.router != nil {
+			err = s.router.Deliver(ctx, digest)
+		} else {
+			err = s.deliverDirect(ctx, digest)
+		}
+		if err != nil {
+			logger.Error("Fout bij verzenden van notificatie digest",
+				"id", digest.ID,
+				"error", err)
+			continue
+		}
 
-	return false
+		logger.Info("Notificatie digest verzonden",
+			"id", digest.ID,
+			"type", digest.Type,
+			"title", digest.Title)
+	}
+	return nil
 }
 
 // isPriorityHighEnough controleert of een prioriteit hoog genoeg is
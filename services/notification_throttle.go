@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Tijdzones meenemen in de binary; het Alpine image heeft geen tzdata
+	_ "time/tzdata"
+)
+
+// DefaultNotificationTimezone is de tijdzone waarin stille uren worden uitgelegd
+const DefaultNotificationTimezone = "Europe/Amsterdam"
+
+// NotificationThrottleRule bepaalt hoe notificaties van een type worden afgeremd. Binnen het
+// venster gaat alleen de eerste notificatie direct uit; de rest komt aan het einde van het
+// venster mee in één digest. Notificaties onder de minimale prioriteit worden niet verzonden.
+type NotificationThrottleRule struct {
+	Type        models.NotificationType // Leeg betekent de standaard voor alle types
+	Window      time.Duration           // 0 betekent niet afremmen
+	MinPriority models.NotificationPriority
+}
+
+// ParseNotificationThrottleRules leest regels in de vorm "type=venster:prioriteit", gescheiden
+// door puntkomma's. * is de standaard voor alle types en de prioriteit is optioneel, bijvoorbeeld
+// "aanmelding=1h;contact=15m:medium;health=0;*=15m".
+func ParseNotificationThrottleRules(spec string) ([]NotificationThrottleRule, error) {
+	var rules []NotificationThrottleRule
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		notificationType, rest, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(notificationType) == "" {
+			return nil, fmt.Errorf("ongeldige throttle regel %q: type ontbreekt", part)
+		}
+		window, priority, _ := strings.Cut(rest, ":")
+
+		rule := NotificationThrottleRule{
+			MinPriority: models.NotificationPriority(strings.ToLower(strings.TrimSpace(priority))),
+		}
+		if t := strings.TrimSpace(notificationType); t != "*" {
+			rule.Type = models.NotificationType(t)
+		}
+		if window = strings.TrimSpace(window); window != "" && window != "0" {
+			duration, err := time.ParseDuration(window)
+			if err != nil || duration < 0 {
+				return nil, fmt.Errorf("ongeldig venster in throttle regel %q", part)
+			}
+			rule.Window = duration
+		}
+		if rule.MinPriority != "" && !isValidNotificationPriority(rule.MinPriority) {
+			return nil, fmt.Errorf("ongeldige prioriteit in throttle regel %q", part)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// QuietHours is een dagelijkse periode waarin alleen kritieke notificaties uitgaan. De
+// periode mag over middernacht lopen, bijvoorbeeld van 22:00 tot 07:00.
+type QuietHours struct {
+	Start    time.Duration // Tijd na middernacht
+	End      time.Duration
+	Location *time.Location
+}
+
+// ParseQuietHours leest stille uren in de vorm "22:00-07:00"
+func ParseQuietHours(spec string, location *time.Location) (*QuietHours, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, fmt.Errorf("ongeldige stille uren %q: verwacht begin-eind", spec)
+	}
+
+	startOffset, err := parseClockTime(start)
+	if err != nil {
+		return nil, fmt.Errorf("ongeldige stille uren %q: %w", spec, err)
+	}
+	endOffset, err := parseClockTime(end)
+	if err != nil {
+		return nil, fmt.Errorf("ongeldige stille uren %q: %w", spec, err)
+	}
+	if startOffset == endOffset {
+		return nil, fmt.Errorf("ongeldige stille uren %q: begin en eind zijn gelijk", spec)
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &QuietHours{Start: startOffset, End: endOffset, Location: location}, nil
+}
+
+// Contains controleert of een tijdstip binnen de stille uren valt
+func (q *QuietHours) Contains(t time.Time) bool {
+	local := t.In(q.Location)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if q.Start < q.End {
+		return offset >= q.Start && offset < q.End
+	}
+	return offset >= q.Start || offset < q.End
+}
+
+// String geeft de stille uren terug in dezelfde vorm als ParseQuietHours verwacht
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%s-%s", formatClockTime(q.Start), formatClockTime(q.End))
+}
+
+// parseClockTime leest een tijd in de vorm "HH:MM" als duur na middernacht
+func parseClockTime(value string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		minutes = "0"
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("ongeldig uur in %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("ongeldige minuten in %q", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// formatClockTime geeft een duur na middernacht terug als "HH:MM"
+func formatClockTime(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// NotificationThrottler remt notificaties af per type. De status staat in een gedeelde store,
+// Redis of de database, zodat hij een herstart overleeft en alle instanties dezelfde vensters
+// zien. Onderdrukte notificaties gaan niet verloren maar komen samen in een digest.
+type NotificationThrottler struct {
+	store         repository.NotificationThrottleRepository
+	rules         map[models.NotificationType]NotificationThrottleRule
+	defaultRule   NotificationThrottleRule
+	quietHours    *QuietHours
+	now           func() time.Time
+	lastCleanup   time.Time
+	cleanupPeriod time.Duration
+}
+
+// NewNotificationThrottler maakt een throttler met een standaard venster voor alle types. Regels
+// met een type gaan voor de standaard; een regel zonder type vervangt de standaard.
+func NewNotificationThrottler(
+	store repository.NotificationThrottleRepository,
+	defaultWindow time.Duration,
+	rules []NotificationThrottleRule,
+) *NotificationThrottler {
+	t := &NotificationThrottler{
+		store:         store,
+		rules:         make(map[models.NotificationType]NotificationThrottleRule),
+		defaultRule:   NotificationThrottleRule{Window: defaultWindow},
+		now:           time.Now,
+		cleanupPeriod: time.Hour,
+	}
+	for _, rule := range rules {
+		if rule.Type == "" {
+			t.defaultRule = rule
+			continue
+		}
+		t.rules[rule.Type] = rule
+	}
+	return t
+}
+
+// SetQuietHours stelt de stille uren in; nil schakelt ze uit
+func (t *NotificationThrottler) SetQuietHours(quietHours *QuietHours) {
+	t.quietHours = quietHours
+}
+
+// SetClock vervangt de klok, voor tests
+func (t *NotificationThrottler) SetClock(now func() time.Time) {
+	t.now = now
+}
+
+// QuietHours geeft de ingestelde stille uren, of nil
+func (t *NotificationThrottler) QuietHours() *QuietHours {
+	return t.quietHours
+}
+
+// Rule geeft de regel die voor een type geldt
+func (t *NotificationThrottler) Rule(notificationType models.NotificationType) NotificationThrottleRule {
+	if rule, ok := t.rules[notificationType]; ok {
+		return rule
+	}
+	rule := t.defaultRule
+	rule.Type = notificationType
+	return rule
+}
+
+// PriorityAllowed controleert of de prioriteit hoog genoeg is volgens de regel van het type.
+// Zonder minimale prioriteit in de regel geldt fallback.
+func (t *NotificationThrottler) PriorityAllowed(notification *models.Notification, fallback models.NotificationPriority) bool {
+	minPriority := t.Rule(notification.Type).MinPriority
+	if minPriority == "" {
+		minPriority = fallback
+	}
+	return isPriorityHighEnough(notification.Priority, minPriority)
+}
+
+// Held controleert of een notificatie vanwege de stille uren moet wachten. Kritieke
+// notificaties gaan altijd uit.
+func (t *NotificationThrottler) Held(notification *models.Notification) bool {
+	if notification.Priority == models.NotificationPriorityCritical || t.quietHours == nil {
+		return false
+	}
+	return t.quietHours.Contains(t.now())
+}
+
+// Allow controleert of een notificatie direct mag uitgaan. Is het venster van het type nog
+// open, dan wordt de notificatie geteld voor de digest en geeft Allow false terug.
+func (t *NotificationThrottler) Allow(ctx context.Context, notification *models.Notification) (bool, error) {
+	if notification.Priority == models.NotificationPriorityCritical {
+		return true, nil
+	}
+	rule := t.Rule(notification.Type)
+	if rule.Window <= 0 {
+		return true, nil
+	}
+
+	now := t.now()
+	return t.store.Hit(ctx, notificationThrottleKey(notification.Type), notification, now.Add(rule.Window), now)
+}
+
+// DueDigests geeft een digest notificatie voor elk venster dat is verstreken en waarin
+// notificaties zijn onderdrukt. Tijdens de stille uren blijven de digests staan tot erna.
+func (t *NotificationThrottler) DueDigests(ctx context.Context) ([]*models.Notification, error) {
+	now := t.now()
+	if t.quietHours != nil && t.quietHours.Contains(now) {
+		return nil, nil
+	}
+
+	throttles, err := t.store.ClaimDue(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification digests: %w", err)
+	}
+
+	if now.Sub(t.lastCleanup) >= t.cleanupPeriod {
+		t.lastCleanup = now
+		if err := t.store.DeleteExpired(ctx, now); err != nil {
+			return nil, fmt.Errorf("failed to delete expired throttles: %w", err)
+		}
+	}
+
+	digests := make([]*models.Notification, 0, len(throttles))
+	for _, throttle := range throttles {
+		if throttle.Suppressed == 0 {
+			continue
+		}
+		digests = append(digests, t.buildDigest(throttle))
+	}
+	return digests, nil
+}
+
+// buildDigest maakt één notificatie van de onderdrukte notificaties van een venster,
+// bijvoorbeeld "12 nieuwe aanmeldingen in het afgelopen uur"
+func (t *NotificationThrottler) buildDigest(throttle *models.NotificationThrottle) *models.Notification {
+	priority := throttle.Priority
+	if !isValidNotificationPriority(priority) {
+		priority = models.NotificationPriorityLow
+	}
+
+	title := fmt.Sprintf("%d nieuwe %s in %s",
+		throttle.Suppressed,
+		notificationTypePlural(throttle.Type),
+		describeNotificationWindow(t.Rule(throttle.Type).Window))
+
+	var message strings.Builder
+	for _, itemTitle := range throttle.Titles {
+		message.WriteString("• ")
+		message.WriteString(itemTitle)
+		message.WriteString("\n")
+	}
+	if more := throttle.Suppressed - len(throttle.Titles); more > 0 {
+		fmt.Fprintf(&message, "... en nog %d\n", more)
+	}
+
+	return &models.Notification{
+		Type:     throttle.Type,
+		Priority: priority,
+		Title:    title,
+		Message:  strings.TrimSuffix(message.String(), "\n"),
+	}
+}
+
+// notificationThrottleKey geeft de sleutel waaronder het venster van een type wordt bijgehouden
+func notificationThrottleKey(notificationType models.NotificationType) string {
+	return "type:" + string(notificationType)
+}
+
+// notificationTypePlural geeft een leesbare meervoudsvorm van een notificatie type
+func notificationTypePlural(notificationType models.NotificationType) string {
+	switch notificationType {
+	case models.NotificationTypeAanmelding:
+		return "aanmeldingen"
+	case models.NotificationTypeContact:
+		return "contactformulieren"
+	case models.NotificationTypeAuth:
+		return "authenticatie meldingen"
+	case models.NotificationTypeSystem:
+		return "systeem meldingen"
+	case models.NotificationTypeHealth:
+		return "health meldingen"
+	case models.NotificationTypeChat:
+		return "chatberichten"
+	}
+	return fmt.Sprintf("%s notificaties", notificationType)
+}
+
+// describeNotificationWindow beschrijft een venster, bijvoorbeeld "het afgelopen uur"
+func describeNotificationWindow(window time.Duration) string {
+	switch {
+	case window == time.Hour:
+		return "het afgelopen uur"
+	case window == time.Minute:
+		return "de afgelopen minuut"
+	case window > time.Hour && window%time.Hour == 0:
+		return fmt.Sprintf("de afgelopen %d uur", int(window.Hours()))
+	case window >= time.Minute && window%time.Minute == 0:
+		return fmt.Sprintf("de afgelopen %d minuten", int(window.Minutes()))
+	}
+	return fmt.Sprintf("de afgelopen %s", window)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisNotificationThrottlePrefix is het voorvoegsel van alle Redis sleutels voor notificatie throttling
+const redisNotificationThrottlePrefix = "notification_throttle:"
+
+// redisThrottleHitScript opent een venster als er geen is, en telt anders de notificatie voor de
+// digest. Alles gebeurt in één script zodat instanties elkaar niet in de weg zitten.
+//
+// KEYS: venster, digest hash, titels, lijst van openstaande digests
+// ARGV: venster in ms, einde venster in ms, type, prioriteit, rang, titel, max titels, sleutel
+var redisThrottleHitScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[2], 'NX', 'PX', ARGV[1]) then
+	return 1
+end
+local windowEnd = redis.call('GET', KEYS[1]) or ARGV[2]
+redis.call('HINCRBY', KEYS[2], 'suppressed', 1)
+redis.call('HSET', KEYS[2], 'type', ARGV[3])
+local rank = tonumber(redis.call('HGET', KEYS[2], 'rank') or '0')
+if tonumber(ARGV[5]) > rank then
+	redis.call('HSET', KEYS[2], 'priority', ARGV[4], 'rank', ARGV[5])
+end
+if redis.call('LLEN', KEYS[3]) < tonumber(ARGV[7]) then
+	redis.call('RPUSH', KEYS[3], ARGV[6])
+end
+redis.call('ZADD', KEYS[4], 'NX', windowEnd, ARGV[8])
+return 0
+`)
+
+// redisThrottleClaimScript haalt de digests op waarvan het venster is verstreken en verwijdert
+// ze, zodat maar één instantie een digest verstuurt
+//
+// KEYS: lijst van openstaande digests
+// ARGV: nu in ms, voorvoegsel
+var redisThrottleClaimScript = redis.NewScript(`
+local keys = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+local result = {}
+for _, key in ipairs(keys) do
+	local digestKey = ARGV[2] .. 'digest:' .. key
+	local titlesKey = ARGV[2] .. 'titles:' .. key
+	local due = redis.call('ZSCORE', KEYS[1], key)
+	local digest = redis.call('HMGET', digestKey, 'type', 'priority', 'suppressed')
+	local titles = redis.call('LRANGE', titlesKey, 0, -1)
+	redis.call('ZREM', KEYS[1], key)
+	redis.call('DEL', digestKey, titlesKey)
+	table.insert(result, {key, due, digest[1] or '', digest[2] or '', digest[3] or '0', titles})
+end
+return result
+`)
+
+// RedisNotificationThrottleStore houdt de throttling status van notificaties bij in Redis.
+// Vensters verlopen vanzelf; openstaande digests staan in een sorted set op einde venster.
+type RedisNotificationThrottleStore struct {
+	client *redis.Client
+}
+
+// NewRedisNotificationThrottleStore maakt een nieuwe Redis store voor notificatie throttling
+func NewRedisNotificationThrottleStore(client *redis.Client) *RedisNotificationThrottleStore {
+	return &RedisNotificationThrottleStore{client: client}
+}
+
+// Hit registreert een notificatie voor een sleutel en geeft terug of hij door mag
+func (s *RedisNotificationThrottleStore) Hit(ctx context.Context, key string, notification *models.Notification, windowEnd, now time.Time) (bool, error) {
+	window := windowEnd.Sub(now).Milliseconds()
+	if window <= 0 {
+		return true, nil
+	}
+
+	result, err := redisThrottleHitScript.Run(ctx, s.client,
+		[]string{
+			redisNotificationThrottlePrefix + "window:" + key,
+			redisNotificationThrottlePrefix + "digest:" + key,
+			redisNotificationThrottlePrefix + "titles:" + key,
+			redisNotificationThrottlePrefix + "due",
+		},
+		window,
+		windowEnd.UnixMilli(),
+		string(notification.Type),
+		string(notification.Priority),
+		notification.Priority.Rank(),
+		notification.Title,
+		models.MaxNotificationDigestTitles,
+		key,
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to register notification in redis: %w", err)
+	}
+	return result == 1, nil
+}
+
+// ClaimDue claimt de digests waarvan het venster is verstreken
+func (s *RedisNotificationThrottleStore) ClaimDue(ctx context.Context, now time.Time) ([]*models.NotificationThrottle, error) {
+	result, err := redisThrottleClaimScript.Run(ctx, s.client,
+		[]string{redisNotificationThrottlePrefix + "due"},
+		now.UnixMilli(),
+		redisNotificationThrottlePrefix,
+	).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notification digests from redis: %w", err)
+	}
+
+	throttles := make([]*models.NotificationThrottle, 0, len(result))
+	for _, item := range result {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) != 6 {
+			continue
+		}
+
+		throttle := &models.NotificationThrottle{
+			Key:      fmt.Sprint(fields[0]),
+			Type:     models.NotificationType(fmt.Sprint(fields[2])),
+			Priority: models.NotificationPriority(fmt.Sprint(fields[3])),
+		}
+		throttle.Suppressed, _ = strconv.Atoi(fmt.Sprint(fields[4]))
+		if due, err := strconv.ParseInt(fmt.Sprint(fields[1]), 10, 64); err == nil {
+			throttle.WindowEnd = time.UnixMilli(due)
+		}
+		if titles, ok := fields[5].([]interface{}); ok {
+			for _, title := range titles {
+				throttle.Titles = append(throttle.Titles, fmt.Sprint(title))
+			}
+		}
+		throttles = append(throttles, throttle)
+	}
+	return throttles, nil
+}
+
+// DeleteExpired hoeft niets te doen; vensters verlopen in Redis vanzelf
+func (s *RedisNotificationThrottleStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	return nil
+}
@@ -0,0 +1,451 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// AnonymousSponsorName staat op het dashboard van de deelnemer voor anonieme sponsors
+	AnonymousSponsorName = "Anonieme sponsor"
+	// maxPledgeAmount is het hoogste bedrag dat per toezegging kan worden opgegeven
+	maxPledgeAmount = 10000
+	// maxPledgeMessageLength is het langste bericht dat een sponsor kan meesturen
+	maxPledgeMessageLength = 500
+)
+
+var (
+	// ErrPledgeNotFound wordt teruggegeven als de toezegging niet bestaat
+	ErrPledgeNotFound = errors.New("toezegging niet gevonden")
+	// ErrInvalidPledge wordt teruggegeven bij een ongeldige toezegging of betaalstatus
+	ErrInvalidPledge = errors.New("ongeldige toezegging")
+)
+
+// routeKilometersPattern vindt de afstand in een route zoals "10 KM" of "2,5 km"
+var routeKilometersPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?i:km)`)
+
+// PledgeInput is wat een sponsor via het publieke formulier invult
+type PledgeInput struct {
+	SponsorNaam  string   `json:"sponsor_naam"`
+	SponsorEmail string   `json:"sponsor_email"`
+	Type         string   `json:"type"`
+	Amount       float64  `json:"amount"`
+	MaxAmount    *float64 `json:"max_amount"`
+	Bericht      string   `json:"bericht"`
+	Anoniem      bool     `json:"anoniem"`
+}
+
+// PledgeService beheert toezeggingen van sponsors aan deelnemers: het verwachte bedrag op basis
+// van route en stappen, de betaalstatus en de bevestigings- en eindmails aan sponsors
+type PledgeService struct {
+	pledgeRepo     repository.PledgeRepository
+	aanmeldingRepo repository.AanmeldingRepository
+	emailSender    EmailSender
+}
+
+// NewPledgeService maakt een nieuwe pledge service; zonder emailSender worden geen mails verstuurd
+func NewPledgeService(pledgeRepo repository.PledgeRepository, aanmeldingRepo repository.AanmeldingRepository, emailSender EmailSender) *PledgeService {
+	return &PledgeService{
+		pledgeRepo:     pledgeRepo,
+		aanmeldingRepo: aanmeldingRepo,
+		emailSender:    emailSender,
+	}
+}
+
+// RouteKilometers leest het aantal kilometers uit een route; 0 als de route geen afstand bevat
+func RouteKilometers(route string) float64 {
+	match := routeKilometersPattern.FindStringSubmatch(route)
+	if match == nil {
+		return 0
+	}
+	km, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return km
+}
+
+// CalculatePledgeAmount berekent wat een toezegging bij de huidige route en stappen van de
+// deelnemer oplevert, begrensd door het maximum en afgerond op centen
+func CalculatePledgeAmount(pledge *models.Pledge, participant *models.Aanmelding) float64 {
+	var amount float64
+	switch pledge.Type {
+	case models.PledgeTypeFixed:
+		amount = pledge.Amount
+	case models.PledgeTypePerKm:
+		amount = pledge.Amount * RouteKilometers(participant.Afstand)
+	case models.PledgeTypePer10kSteps:
+		amount = pledge.Amount * float64(participant.Steps) / 10000
+	}
+	if pledge.MaxAmount != nil && amount > *pledge.MaxAmount {
+		amount = *pledge.MaxAmount
+	}
+	return roundCents(amount)
+}
+
+// roundCents rondt een bedrag af op hele centen
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// FormatEuro schrijft een bedrag zoals € 12,50
+func FormatEuro(amount float64) string {
+	cents := int(math.Round(amount * 100))
+	return fmt.Sprintf("€ %s,%02d", FormatSteps(cents/100), cents%100)
+}
+
+// describePledge beschrijft de toezegging voor in de mails, zoals "€ 2,50 per kilometer"
+func describePledge(pledge *models.Pledge) string {
+	switch pledge.Type {
+	case models.PledgeTypePerKm:
+		return FormatEuro(pledge.Amount) + " per kilometer"
+	case models.PledgeTypePer10kSteps:
+		return FormatEuro(pledge.Amount) + " per 10.000 stappen"
+	}
+	return FormatEuro(pledge.Amount)
+}
+
+// getParticipant haalt de deelnemer bij een toezegging op
+func (s *PledgeService) getParticipant(ctx context.Context, participantID string) (*models.Aanmelding, error) {
+	participant, err := s.aanmeldingRepo.GetByID(ctx, participantID)
+	if err != nil {
+		return nil, fmt.Errorf("kon deelnemer niet ophalen: %w", err)
+	}
+	if participant == nil {
+		return nil, ErrParticipantNotFound
+	}
+	return participant, nil
+}
+
+// GetPublicParticipant geeft wat het sponsorformulier over een deelnemer mag tonen: voornaam en
+// initiaal, route en stappen
+func (s *PledgeService) GetPublicParticipant(participantID string) (*models.PledgeParticipant, error) {
+	participant, err := s.getParticipant(context.Background(), participantID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PledgeParticipant{
+		Naam:  publicParticipantName(participant.Naam, true),
+		Route: participant.Afstand,
+		Steps: participant.Steps,
+	}, nil
+}
+
+// validatePledge controleert en normaliseert de invoer van het sponsorformulier
+func validatePledge(input *PledgeInput) (*models.Pledge, error) {
+	naam := strings.TrimSpace(input.SponsorNaam)
+	if naam == "" {
+		return nil, fmt.Errorf("%w: naam is verplicht", ErrInvalidPledge)
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(input.SponsorEmail))
+	if err != nil || address.Name != "" || !strings.Contains(address.Address, ".") {
+		return nil, fmt.Errorf("%w: ongeldig emailadres", ErrInvalidPledge)
+	}
+
+	pledgeType := input.Type
+	if pledgeType == "" {
+		pledgeType = models.PledgeTypeFixed
+	}
+	valid := false
+	for _, t := range models.PledgeTypes {
+		if pledgeType == t {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: onbekende soort %q", ErrInvalidPledge, input.Type)
+	}
+
+	amount := roundCents(input.Amount)
+	if amount <= 0 || amount > maxPledgeAmount {
+		return nil, fmt.Errorf("%w: bedrag moet tussen 0 en %d euro liggen", ErrInvalidPledge, maxPledgeAmount)
+	}
+
+	pledge := &models.Pledge{
+		SponsorNaam:  naam,
+		SponsorEmail: strings.ToLower(address.Address),
+		Type:         pledgeType,
+		Amount:       amount,
+		Anoniem:      input.Anoniem,
+		Status:       models.PledgeStatusPending,
+	}
+	// Een maximum heeft alleen zin als het bedrag afhangt van de prestatie
+	if input.MaxAmount != nil && pledgeType != models.PledgeTypeFixed {
+		maxAmount := roundCents(*input.MaxAmount)
+		if maxAmount <= 0 || maxAmount > maxPledgeAmount {
+			return nil, fmt.Errorf("%w: maximum moet tussen 0 en %d euro liggen", ErrInvalidPledge, maxPledgeAmount)
+		}
+		pledge.MaxAmount = &maxAmount
+	}
+	if bericht := strings.TrimSpace(input.Bericht); bericht != "" {
+		if utf8.RuneCountInString(bericht) > maxPledgeMessageLength {
+			return nil, fmt.Errorf("%w: bericht is langer dan %d tekens", ErrInvalidPledge, maxPledgeMessageLength)
+		}
+		pledge.Bericht = &bericht
+	}
+	return pledge, nil
+}
+
+// CreatePledge legt een toezegging voor een deelnemer vast en stuurt de sponsor een bevestiging.
+// Een mislukte bevestiging wordt gelogd; de toezegging staat dan al.
+func (s *PledgeService) CreatePledge(participantID string, input *PledgeInput) (*models.Pledge, error) {
+	ctx := context.Background()
+	participant, err := s.getParticipant(ctx, participantID)
+	if err != nil {
+		return nil, err
+	}
+	pledge, err := validatePledge(input)
+	if err != nil {
+		return nil, err
+	}
+	pledge.AanmeldingID = participant.ID
+
+	if err := s.pledgeRepo.Create(ctx, pledge); err != nil {
+		return nil, fmt.Errorf("kon toezegging niet opslaan: %w", err)
+	}
+	pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+	logger.Info("Sponsortoezegging ontvangen", "pledge_id", pledge.ID, "aanmelding_id", participant.ID, "type", pledge.Type)
+
+	s.sendConfirmation(ctx, pledge, participant)
+	return pledge, nil
+}
+
+// sendConfirmation bevestigt een toezegging aan de sponsor
+func (s *PledgeService) sendConfirmation(ctx context.Context, pledge *models.Pledge, participant *models.Aanmelding) {
+	if s.emailSender == nil || participant.TestMode {
+		return
+	}
+	deelnemer := publicParticipantName(participant.Naam, true)
+	data := map[string]interface{}{
+		"Naam":      pledge.SponsorNaam,
+		"Deelnemer": deelnemer,
+		"Route":     participant.Afstand,
+		"Pledge":    describePledge(pledge),
+		"Expected":  FormatEuro(pledge.ExpectedAmount),
+		"Fixed":     pledge.Type == models.PledgeTypeFixed,
+	}
+	if pledge.MaxAmount != nil {
+		data["MaxAmount"] = FormatEuro(*pledge.MaxAmount)
+	}
+	subject := fmt.Sprintf("Bedankt voor je sponsoring van %s", deelnemer)
+	if err := s.emailSender.SendTemplateEmail(pledge.SponsorEmail, subject, "pledge_confirmation", data); err != nil {
+		logger.Error("Kon bevestiging van toezegging niet versturen", "pledge_id", pledge.ID, "error", err)
+		return
+	}
+	now := time.Now()
+	pledge.ConfirmationSentAt = &now
+	if err := s.pledgeRepo.Update(ctx, pledge); err != nil {
+		logger.Error("Kon verzending bevestiging niet vastleggen", "pledge_id", pledge.ID, "error", err)
+	}
+}
+
+// ListByParticipant haalt de toezeggingen van een deelnemer op met de totalen. Voor de deelnemer
+// zelf (forParticipant) verdwijnen de emailadressen en de namen van anonieme sponsors.
+func (s *PledgeService) ListByParticipant(participantID string, forParticipant bool) ([]*models.Pledge, *models.PledgeSummary, error) {
+	ctx := context.Background()
+	participant, err := s.getParticipant(ctx, participantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pledges, err := s.pledgeRepo.ListByAanmelding(ctx, participant.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("kon toezeggingen niet ophalen: %w", err)
+	}
+
+	for _, pledge := range pledges {
+		pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+		if forParticipant {
+			pledge.SponsorEmail = ""
+			pledge.PaymentReference = nil
+			if pledge.Anoniem {
+				pledge.SponsorNaam = AnonymousSponsorName
+			}
+		}
+	}
+	if pledges == nil {
+		pledges = []*models.Pledge{}
+	}
+	return pledges, summarizePledges(pledges), nil
+}
+
+// Summary geeft de sponsortotalen van een deelnemer voor het dashboard
+func (s *PledgeService) Summary(participant *models.Aanmelding) (*models.PledgeSummary, error) {
+	pledges, err := s.pledgeRepo.ListByAanmelding(context.Background(), participant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("kon toezeggingen niet ophalen: %w", err)
+	}
+	for _, pledge := range pledges {
+		pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+	}
+	return summarizePledges(pledges), nil
+}
+
+// summarizePledges telt de toezeggingen op; een betaalde toezegging telt met het betaalde bedrag
+func summarizePledges(pledges []*models.Pledge) *models.PledgeSummary {
+	summary := &models.PledgeSummary{}
+	for _, pledge := range pledges {
+		if pledge.Status == models.PledgeStatusCancelled {
+			continue
+		}
+		amount := pledge.ExpectedAmount
+		summary.Count++
+		if pledge.Status == models.PledgeStatusPaid {
+			if pledge.PaidAmount != nil {
+				amount = *pledge.PaidAmount
+			}
+			summary.PaidCount++
+			summary.PaidTotal += amount
+		}
+		summary.ExpectedTotal += amount
+	}
+	summary.ExpectedTotal = roundCents(summary.ExpectedTotal)
+	summary.PaidTotal = roundCents(summary.PaidTotal)
+	return summary
+}
+
+// List haalt toezeggingen op voor het beheer, met het verwachte bedrag per toezegging
+func (s *PledgeService) List(filter models.PledgeFilter) ([]*models.Pledge, int64, error) {
+	ctx := context.Background()
+	if filter.Status != "" && !validPledgeStatus(filter.Status) {
+		return nil, 0, fmt.Errorf("%w: onbekende status %q", ErrInvalidPledge, filter.Status)
+	}
+	pledges, total, err := s.pledgeRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("kon toezeggingen niet ophalen: %w", err)
+	}
+
+	participants := map[string]*models.Aanmelding{}
+	for _, pledge := range pledges {
+		participant, ok := participants[pledge.AanmeldingID]
+		if !ok {
+			participant, err = s.aanmeldingRepo.GetByID(ctx, pledge.AanmeldingID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("kon deelnemer niet ophalen: %w", err)
+			}
+			participants[pledge.AanmeldingID] = participant
+		}
+		if participant != nil {
+			pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+		}
+	}
+	if pledges == nil {
+		pledges = []*models.Pledge{}
+	}
+	return pledges, total, nil
+}
+
+// validPledgeStatus controleert of een betaalstatus bestaat
+func validPledgeStatus(status string) bool {
+	for _, s := range models.PledgeStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateStatus werkt de betaalstatus van een toezegging bij. Bij paid wordt het betaalde bedrag
+// vastgelegd, standaard het verwachte bedrag op dat moment.
+func (s *PledgeService) UpdateStatus(id, status string, paidAmount *float64, reference string) (*models.Pledge, error) {
+	ctx := context.Background()
+	if !validPledgeStatus(status) {
+		return nil, fmt.Errorf("%w: onbekende status %q", ErrInvalidPledge, status)
+	}
+	pledge, err := s.pledgeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("kon toezegging niet ophalen: %w", err)
+	}
+	if pledge == nil {
+		return nil, ErrPledgeNotFound
+	}
+	participant, err := s.getParticipant(ctx, pledge.AanmeldingID)
+	if err != nil {
+		return nil, err
+	}
+	pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+
+	pledge.Status = status
+	if reference = strings.TrimSpace(reference); reference != "" {
+		pledge.PaymentReference = &reference
+	}
+	if status == models.PledgeStatusPaid {
+		amount := pledge.ExpectedAmount
+		if paidAmount != nil {
+			amount = roundCents(*paidAmount)
+		}
+		if amount <= 0 {
+			return nil, fmt.Errorf("%w: betaald bedrag moet groter dan 0 zijn", ErrInvalidPledge)
+		}
+		now := time.Now()
+		pledge.PaidAmount = &amount
+		pledge.PaidAt = &now
+	} else {
+		pledge.PaidAmount = nil
+		pledge.PaidAt = nil
+	}
+
+	if err := s.pledgeRepo.Update(ctx, pledge); err != nil {
+		return nil, fmt.Errorf("kon toezegging niet bijwerken: %w", err)
+	}
+	return pledge, nil
+}
+
+// NotifyFinished laat de sponsors van een deelnemer weten dat die de route heeft gelopen, met het
+// bedrag dat hun toezegging heeft opgeleverd. Elke sponsor krijgt deze mail één keer; geannuleerde
+// toezeggingen worden overgeslagen. Geeft het aantal verstuurde mails terug.
+func (s *PledgeService) NotifyFinished(participantID string) (int, error) {
+	ctx := context.Background()
+	participant, err := s.getParticipant(ctx, participantID)
+	if err != nil {
+		return 0, err
+	}
+	if s.emailSender == nil || participant.TestMode {
+		return 0, nil
+	}
+	pledges, err := s.pledgeRepo.ListByAanmelding(ctx, participant.ID)
+	if err != nil {
+		return 0, fmt.Errorf("kon toezeggingen niet ophalen: %w", err)
+	}
+
+	deelnemer := publicParticipantName(participant.Naam, true)
+	subject := fmt.Sprintf("%s heeft de finish gehaald!", deelnemer)
+	sent := 0
+	for _, pledge := range pledges {
+		if pledge.Status == models.PledgeStatusCancelled || pledge.FinishedEmailSentAt != nil {
+			continue
+		}
+		pledge.ExpectedAmount = CalculatePledgeAmount(pledge, participant)
+		data := map[string]interface{}{
+			"Naam":      pledge.SponsorNaam,
+			"Deelnemer": deelnemer,
+			"Route":     participant.Afstand,
+			"Steps":     FormatSteps(participant.Steps),
+			"Pledge":    describePledge(pledge),
+			"Amount":    FormatEuro(pledge.ExpectedAmount),
+			"Paid":      pledge.Status == models.PledgeStatusPaid,
+		}
+		if err := s.emailSender.SendTemplateEmail(pledge.SponsorEmail, subject, "pledge_finished", data); err != nil {
+			logger.Error("Kon eindmail aan sponsor niet versturen", "pledge_id", pledge.ID, "error", err)
+			continue
+		}
+		now := time.Now()
+		pledge.FinishedEmailSentAt = &now
+		if err := s.pledgeRepo.Update(ctx, pledge); err != nil {
+			logger.Error("Kon verzending eindmail niet vastleggen", "pledge_id", pledge.ID, "error", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
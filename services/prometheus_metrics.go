@@ -21,6 +21,10 @@ type PrometheusMetrics struct {
 	emailLatency         *prometheus.HistogramVec
 	rateLimitExceeded    *prometheus.CounterVec
 	activeEmailBatches   prometheus.Gauge
+	emailsBounced        *prometheus.CounterVec
+	newsletterDeliveries *prometheus.CounterVec
+	newsletterOpens      prometheus.Counter
+	newsletterClicks     prometheus.Counter
 	mu                   sync.Mutex
 	emailTypeCardinality map[string]bool // Helpt bij het beperken van cardinality
 }
@@ -75,12 +79,38 @@ func newPrometheusMetrics() *PrometheusMetrics {
 		Help: "Het huidige aantal actieve email batches",
 	})
 
+	// Bounces uit delivery status notifications
+	emailsBounced := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_emails_bounced_total",
+		Help: "Het aantal bounces per type (hard of soft)",
+	}, []string{"bounce_type"})
+
+	// Aflevering, opens en kliks van nieuwsbrieven
+	newsletterDeliveries := promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_newsletter_deliveries_total",
+		Help: "Het aantal nieuwsbrief afleveringen per status (sent, failed of bounced)",
+	}, []string{"status"})
+
+	newsletterOpens := promauto.NewCounter(prometheus.CounterOpts{
+		Name: "email_service_newsletter_opens_total",
+		Help: "Het aantal geopende nieuwsbrieven (tracking pixel)",
+	})
+
+	newsletterClicks := promauto.NewCounter(prometheus.CounterOpts{
+		Name: "email_service_newsletter_clicks_total",
+		Help: "Het aantal kliks op links in nieuwsbrieven",
+	})
+
 	return &PrometheusMetrics{
 		emailsSent:           emailsSent,
 		emailsFailed:         emailsFailed,
 		emailLatency:         emailLatency,
 		rateLimitExceeded:    rateLimitExceeded,
 		activeEmailBatches:   activeEmailBatches,
+		emailsBounced:        emailsBounced,
+		newsletterDeliveries: newsletterDeliveries,
+		newsletterOpens:      newsletterOpens,
+		newsletterClicks:     newsletterClicks,
 		emailTypeCardinality: make(map[string]bool),
 	}
 }
@@ -109,6 +139,26 @@ func (pm *PrometheusMetrics) RecordRateLimitExceeded(emailType, limitType string
 	pm.rateLimitExceeded.WithLabelValues(emailType, limitType).Inc()
 }
 
+// RecordEmailBounced registreert een bounce in Prometheus
+func (pm *PrometheusMetrics) RecordEmailBounced(bounceType string) {
+	pm.emailsBounced.WithLabelValues(bounceType).Inc()
+}
+
+// RecordNewsletterDelivery registreert de aflevering van een nieuwsbrief aan één ontvanger
+func (pm *PrometheusMetrics) RecordNewsletterDelivery(status string) {
+	pm.newsletterDeliveries.WithLabelValues(status).Inc()
+}
+
+// RecordNewsletterOpen registreert het openen van een nieuwsbrief
+func (pm *PrometheusMetrics) RecordNewsletterOpen() {
+	pm.newsletterOpens.Inc()
+}
+
+// RecordNewsletterClick registreert een klik op een link in een nieuwsbrief
+func (pm *PrometheusMetrics) RecordNewsletterClick() {
+	pm.newsletterClicks.Inc()
+}
+
 // UpdateActiveBatches werkt het aantal actieve batches bij
 func (pm *PrometheusMetrics) UpdateActiveBatches(count int) {
 	pm.activeEmailBatches.Set(float64(count))
@@ -162,12 +212,38 @@ func NewPrometheusMetricsWithRegistry(reg *prometheus.Registry) *PrometheusMetri
 		Help: "Het huidige aantal actieve email batches",
 	})
 
+	// Bounces uit delivery status notifications
+	emailsBounced := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_emails_bounced_total",
+		Help: "Het aantal bounces per type (hard of soft)",
+	}, []string{"bounce_type"})
+
+	// Aflevering, opens en kliks van nieuwsbrieven
+	newsletterDeliveries := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_service_newsletter_deliveries_total",
+		Help: "Het aantal nieuwsbrief afleveringen per status (sent, failed of bounced)",
+	}, []string{"status"})
+
+	newsletterOpens := factory.NewCounter(prometheus.CounterOpts{
+		Name: "email_service_newsletter_opens_total",
+		Help: "Het aantal geopende nieuwsbrieven (tracking pixel)",
+	})
+
+	newsletterClicks := factory.NewCounter(prometheus.CounterOpts{
+		Name: "email_service_newsletter_clicks_total",
+		Help: "Het aantal kliks op links in nieuwsbrieven",
+	})
+
 	return &PrometheusMetrics{
 		emailsSent:           emailsSent,
 		emailsFailed:         emailsFailed,
 		emailLatency:         emailLatency,
 		rateLimitExceeded:    rateLimitExceeded,
 		activeEmailBatches:   activeEmailBatches,
+		emailsBounced:        emailsBounced,
+		newsletterDeliveries: newsletterDeliveries,
+		newsletterOpens:      newsletterOpens,
+		newsletterClicks:     newsletterClicks,
 		emailTypeCardinality: make(map[string]bool),
 	}
 }
@@ -162,6 +162,7 @@ func (c *RealSMTPClient) sendWithDialer(msg *EmailMessage, conf *SMTPConfig, dia
 	m.SetHeader("From", conf.From)
 	m.SetHeader("To", msg.To)
 	m.SetHeader("Subject", msg.Subject)
+	setExtraHeaders(m, msg)
 	m.SetBody("text/html", msg.Body)
 
 	// Gebruik connection pooling voor betere performance
@@ -248,6 +249,7 @@ func (c *RealSMTPClient) SendWithFrom(from string, msg *EmailMessage) error {
 	m.SetHeader("From", from) // Gebruik het meegegeven 'from' adres
 	m.SetHeader("To", msg.To)
 	m.SetHeader("Subject", msg.Subject)
+	setExtraHeaders(m, msg)
 	m.SetBody("text/html", msg.Body)
 
 	// Gebruik connection pooling voor betere performance
@@ -263,3 +265,12 @@ func (c *RealSMTPClient) SendWithFrom(from string, msg *EmailMessage) error {
 
 	return nil
 }
+
+// setExtraHeaders zet de optionele extra headers van een bericht
+func setExtraHeaders(m *gomail.Message, msg *EmailMessage) {
+	for name, value := range msg.Headers {
+		if value != "" {
+			m.SetHeader(name, value)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEventNotFound wordt teruggegeven als er geen editie voor het jaar is
+	ErrEventNotFound = errors.New("editie niet gevonden")
+	// ErrInvalidEvent wordt teruggegeven bij een ongeldige editie of route bedrag
+	ErrInvalidEvent = errors.New("ongeldige editie")
+	// ErrRouteFundNotFound wordt teruggegeven als de route niet bij de editie hoort
+	ErrRouteFundNotFound = errors.New("route niet gevonden in deze editie")
+)
+
+// eventYear geeft het jaar van de editie waar een aanmelding bij hoort
+func (s *StepsService) eventYear(participant *models.Aanmelding) int {
+	return participant.CreatedAt.In(s.location).Year()
+}
+
+// eventPeriod geeft de aanmeldingsperiode van de editie van een jaar: het kalenderjaar in de
+// tijdzone van het evenement, to exclusief
+func (s *StepsService) eventPeriod(year int) (from, to time.Time) {
+	from = time.Date(year, time.January, 1, 0, 0, 0, 0, s.location)
+	return from, from.AddDate(1, 0, 0)
+}
+
+// GetEvent haalt de editie van een jaar op; met jaar 0 de meest recente editie
+func (s *StepsService) GetEvent(year int) (*models.Event, error) {
+	var (
+		event *models.Event
+		err   error
+	)
+	if year == 0 {
+		event, err = s.eventRepo.GetLatest(context.Background())
+	} else {
+		event, err = s.eventRepo.GetByYear(context.Background(), year)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kon editie niet ophalen: %w", err)
+	}
+	if event == nil {
+		return nil, ErrEventNotFound
+	}
+	return event, nil
+}
+
+// ListEvents haalt alle edities op, nieuwste eerst
+func (s *StepsService) ListEvents() ([]*models.Event, error) {
+	events, err := s.eventRepo.List(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("kon edities niet ophalen: %w", err)
+	}
+	return events, nil
+}
+
+// validateEvent controleert en normaliseert een editie. Zonder naam krijgt de editie de naam
+// van het evenement met het jaar; zonder strategie wordt naar rato van deelnemers verdeeld.
+func validateEvent(event *models.Event) error {
+	if event.Jaar < 2000 || event.Jaar > 2100 {
+		return fmt.Errorf("%w: jaar %d", ErrInvalidEvent, event.Jaar)
+	}
+	event.Naam = strings.TrimSpace(event.Naam)
+	if event.Naam == "" {
+		event.Naam = fmt.Sprintf("De Koninklijke Loop %d", event.Jaar)
+	}
+	if event.TotalFunds < 0 {
+		return fmt.Errorf("%w: de fondsenpot mag niet negatief zijn", ErrInvalidEvent)
+	}
+	if event.AllocationStrategy == "" {
+		event.AllocationStrategy = models.AllocationStrategyParticipants
+	}
+	for _, strategy := range models.AllocationStrategies {
+		if event.AllocationStrategy == strategy {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: onbekende verdeelstrategie %q", ErrInvalidEvent, event.AllocationStrategy)
+}
+
+// validateRouteFund controleert een route bedrag
+func validateRouteFund(route string, amount int) error {
+	if strings.TrimSpace(route) == "" {
+		return fmt.Errorf("%w: route is verplicht", ErrInvalidEvent)
+	}
+	if amount < 0 {
+		return fmt.Errorf("%w: bedrag moet groter of gelijk zijn aan 0", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// CreateEvent maakt een nieuwe editie aan, met de meegegeven routes en bedragen
+func (s *StepsService) CreateEvent(event *models.Event) error {
+	if err := validateEvent(event); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i := range event.RouteFunds {
+		routeFund := &event.RouteFunds[i]
+		routeFund.ID = ""
+		routeFund.Route = strings.TrimSpace(routeFund.Route)
+		if err := validateRouteFund(routeFund.Route, routeFund.Amount); err != nil {
+			return err
+		}
+		if seen[routeFund.Route] {
+			return fmt.Errorf("%w: route %q staat er dubbel in", ErrInvalidEvent, routeFund.Route)
+		}
+		seen[routeFund.Route] = true
+	}
+
+	existing, err := s.eventRepo.GetByYear(context.Background(), event.Jaar)
+	if err != nil {
+		return fmt.Errorf("kon editie niet ophalen: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("%w: er is al een editie voor %d", ErrInvalidEvent, event.Jaar)
+	}
+
+	event.ID = ""
+	if err := s.eventRepo.Create(context.Background(), event); err != nil {
+		return fmt.Errorf("kon editie niet aanmaken: %w", err)
+	}
+	logger.Info("Editie aangemaakt", "jaar", event.Jaar, "total_funds", event.TotalFunds, "strategy", event.AllocationStrategy)
+	return nil
+}
+
+// UpdateEvent werkt de naam, fondsenpot en verdeelstrategie van een editie bij
+func (s *StepsService) UpdateEvent(year int, update *models.Event) (*models.Event, error) {
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return nil, err
+	}
+	event.Naam = update.Naam
+	event.TotalFunds = update.TotalFunds
+	event.AllocationStrategy = update.AllocationStrategy
+	if err := validateEvent(event); err != nil {
+		return nil, err
+	}
+	if err := s.eventRepo.Update(context.Background(), event); err != nil {
+		return nil, fmt.Errorf("kon editie niet bijwerken: %w", err)
+	}
+	return event, nil
+}
+
+// DeleteEvent verwijdert een editie met haar route bedragen
+func (s *StepsService) DeleteEvent(year int) error {
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return err
+	}
+	if err := s.eventRepo.Delete(context.Background(), event.ID); err != nil {
+		return fmt.Errorf("kon editie niet verwijderen: %w", err)
+	}
+	return nil
+}
+
+// CalculateAllocatedFunds geeft het ingestelde bedrag voor de route van een deelnemer in de editie
+// van de aanmelding. Zonder editie of route bedrag is dat 0.
+func (s *StepsService) CalculateAllocatedFunds(participant *models.Aanmelding) int {
+	event, err := s.GetEvent(s.eventYear(participant))
+	if err != nil {
+		if !errors.Is(err, ErrEventNotFound) {
+			logger.Error("Kon editie voor deelnemer niet ophalen", "aanmelding_id", participant.ID, "error", err)
+		}
+		return 0
+	}
+	routeFund, err := s.routeFundRepo.GetByRoute(context.Background(), event.ID, participant.Afstand)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("Kon route fund niet ophalen", "jaar", event.Jaar, "route", participant.Afstand, "error", err)
+		}
+		return 0
+	}
+	return routeFund.Amount
+}
+
+// GetFundsDistribution berekent hoe de fondsenpot van een editie over de routes wordt verdeeld
+// volgens de ingestelde strategie. Is er nog niets om naar te verdelen, bijvoorbeeld geen
+// deelnemers of stappen, dan wordt gelijk verdeeld en staat dat in Strategy. De bedragen worden
+// afgerond zodat ze samen precies de pot zijn.
+func (s *StepsService) GetFundsDistribution(year int) (*models.FundsDistribution, error) {
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return nil, err
+	}
+	routeFunds, err := s.routeFundRepo.ListByEvent(context.Background(), event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("kon route funds niet ophalen: %w", err)
+	}
+
+	from, to := s.eventPeriod(event.Jaar)
+	stats, err := s.eventRepo.RouteStats(context.Background(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("kon route statistieken niet ophalen: %w", err)
+	}
+	statsByRoute := make(map[string]models.RouteStats, len(stats))
+	for _, stat := range stats {
+		statsByRoute[stat.Route] = stat
+	}
+
+	distribution := &models.FundsDistribution{
+		EventID:            event.ID,
+		Jaar:               event.Jaar,
+		Naam:               event.Naam,
+		TotalFunds:         event.TotalFunds,
+		Strategy:           event.AllocationStrategy,
+		ConfiguredStrategy: event.AllocationStrategy,
+		Routes:             make([]models.RouteAllocation, 0, len(routeFunds)),
+	}
+	weights := make([]int, len(routeFunds))
+	totalWeight := 0
+	for i, routeFund := range routeFunds {
+		stat := statsByRoute[routeFund.Route]
+		distribution.Routes = append(distribution.Routes, models.RouteAllocation{
+			Route:        routeFund.Route,
+			Amount:       routeFund.Amount,
+			Participants: stat.Participants,
+			Steps:        stat.Steps,
+		})
+		switch event.AllocationStrategy {
+		case models.AllocationStrategyParticipants:
+			weights[i] = stat.Participants
+		case models.AllocationStrategySteps:
+			weights[i] = stat.Steps
+		default:
+			weights[i] = 1
+		}
+		totalWeight += weights[i]
+	}
+	if totalWeight == 0 {
+		distribution.Strategy = models.AllocationStrategyEqual
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	for i, amount := range distributeFunds(event.TotalFunds, weights) {
+		distribution.Routes[i].Allocated = amount
+	}
+	return distribution, nil
+}
+
+// distributeFunds verdeelt total naar rato van weights met de methode van de grootste overschotten,
+// zodat de delen hele euro's zijn en samen precies total
+func distributeFunds(total int, weights []int) []int {
+	shares := make([]int, len(weights))
+	sum := 0
+	for _, weight := range weights {
+		sum += weight
+	}
+	if sum == 0 {
+		return shares
+	}
+
+	remainders := make([]int, len(weights))
+	rest := total
+	for i, weight := range weights {
+		product := int64(total) * int64(weight)
+		shares[i] = int(product / int64(sum))
+		remainders[i] = int(product % int64(sum))
+		rest -= shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; i < rest; i++ {
+		shares[order[i%len(order)]]++
+	}
+	return shares
+}
+
+// GetRouteFunds haalt alle route fondsallocaties van een editie op
+func (s *StepsService) GetRouteFunds(year int) ([]*models.RouteFund, error) {
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return nil, err
+	}
+	routeFunds, err := s.routeFundRepo.ListByEvent(context.Background(), event.ID)
+	if err != nil {
+		return nil, fmt.Errorf("kon route funds niet ophalen: %w", err)
+	}
+	return routeFunds, nil
+}
+
+// UpdateRouteFund werkt een route fondsallocatie van een editie bij
+func (s *StepsService) UpdateRouteFund(year int, route string, amount int) (*models.RouteFund, error) {
+	if err := validateRouteFund(route, amount); err != nil {
+		return nil, err
+	}
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return nil, err
+	}
+
+	// Controleer of route bestaat
+	existing, err := s.routeFundRepo.GetByRoute(context.Background(), event.ID, route)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRouteFundNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kon route fund niet ophalen: %w", err)
+	}
+
+	existing.Amount = amount
+	if err := s.routeFundRepo.Update(context.Background(), existing); err != nil {
+		return nil, fmt.Errorf("kon route fund niet bijwerken: %w", err)
+	}
+
+	return existing, nil
+}
+
+// CreateRouteFund voegt een route met fondsallocatie toe aan een editie
+func (s *StepsService) CreateRouteFund(year int, route string, amount int) (*models.RouteFund, error) {
+	if err := validateRouteFund(route, amount); err != nil {
+		return nil, err
+	}
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return nil, err
+	}
+
+	routeFund := &models.RouteFund{
+		EventID: event.ID,
+		Route:   strings.TrimSpace(route),
+		Amount:  amount,
+	}
+
+	if err := s.routeFundRepo.Create(context.Background(), routeFund); err != nil {
+		return nil, fmt.Errorf("kon route fund niet aanmaken: %w", err)
+	}
+
+	return routeFund, nil
+}
+
+// DeleteRouteFund verwijdert een route fondsallocatie uit een editie
+func (s *StepsService) DeleteRouteFund(year int, route string) error {
+	event, err := s.GetEvent(year)
+	if err != nil {
+		return err
+	}
+	if err := s.routeFundRepo.Delete(context.Background(), event.ID, route); err != nil {
+		return fmt.Errorf("kon route fund niet verwijderen: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"fmt"
+	"time"
+)
+
+const (
+	// MaxStepsImportSize is de grootste upload die de stappen import accepteert
+	MaxStepsImportSize = 4 << 20
+	// MaxImportedStepsPerActivity is het hoogste aantal stappen dat één activiteit of dag mag opleveren
+	MaxImportedStepsPerActivity = 150000
+	// stepsImportKeyPrefix onderscheidt de idempotency keys van imports van die van de app
+	stepsImportKeyPrefix = "import:"
+)
+
+// Status van een activiteit na de import
+const (
+	ImportStatusImported  = "imported"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusRejected  = "rejected"
+)
+
+// StepsImportResult is het resultaat van het importeren van een activiteitenbestand
+type StepsImportResult struct {
+	Format        string             `json:"format"`
+	Imported      int                `json:"imported"`
+	Duplicates    int                `json:"duplicates"`
+	Rejected      int                `json:"rejected"`
+	ImportedSteps int                `json:"imported_steps"`
+	TotalSteps    int                `json:"total_steps"`
+	Activities    []ImportedActivity `json:"activities"`
+}
+
+// ImportActivities leest een GPX, TCX, FIT of CSV bestand en boekt elke activiteit als regel in het
+// stappen grootboek. De hash van een activiteit is de idempotency key, zodat een bestand dat
+// opnieuw wordt geüpload niet dubbel telt. Een CSV bevat dagtotalen en exports overlappen vaak;
+// per dag wordt alleen het verschil met de eerder geïmporteerde stappen van die dag geboekt. Bij
+// een fout halverwege blijven de geboekte activiteiten staan; het bestand kan veilig opnieuw
+// worden geüpload.
+func (s *StepsService) ImportActivities(participantID, fileName string, data []byte, enteredBy string) (*StepsImportResult, error) {
+	participant, err := s.GetParticipant(participantID)
+	if err != nil {
+		return nil, err
+	}
+
+	format, activities, err := ParseActivityFile(fileName, data, s.location)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StepsImportResult{
+		Format:     format,
+		TotalSteps: participant.Steps,
+		Activities: activities,
+	}
+	now := time.Now()
+	for i := range result.Activities {
+		activity := &result.Activities[i]
+		activity.Hash = activity.fingerprint(format)
+
+		switch {
+		case activity.Steps <= 0:
+			activity.Reason = "geen stappen of afstand gevonden"
+		case activity.Steps > MaxImportedStepsPerActivity:
+			activity.Reason = fmt.Sprintf("meer dan %d stappen", MaxImportedStepsPerActivity)
+		case activity.StartedAt.After(now.Add(maxStepsClockSkew)):
+			activity.Reason = "ligt in de toekomst"
+		}
+		if activity.Reason != "" {
+			activity.Status = ImportStatusRejected
+			result.Rejected++
+			continue
+		}
+
+		startedAt := activity.StartedAt
+		entry, err := s.newEntry(participant.ID, activity.Steps, StepEntryOptions{
+			Source:         models.StepSourceImport,
+			EnteredBy:      enteredBy,
+			IdempotencyKey: stepsImportKeyPrefix + activity.Hash,
+			RecordedAt:     &startedAt,
+			Metadata: &models.StepEntryMetadata{
+				Format:         format,
+				FileName:       fileName,
+				ActivityHash:   activity.Hash,
+				StartedAt:      &startedAt,
+				DistanceMeters: activity.DistanceMeters,
+				StepsEstimated: activity.StepsEstimated,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var total int
+		var created bool
+		if format == ActivityFormatCSV {
+			total, created, err = s.recordDayTotal(participant, entry, activity.Steps)
+		} else {
+			total, created, err = s.record(participant, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kon activiteit niet boeken: %w", err)
+		}
+		result.TotalSteps = total
+		if created {
+			activity.Status = ImportStatusImported
+			result.Imported++
+			result.ImportedSteps += entry.Delta
+		} else {
+			activity.Status = ImportStatusDuplicate
+			result.Duplicates++
+		}
+	}
+
+	logger.Info("Activiteitenbestand geïmporteerd",
+		"aanmelding_id", participant.ID,
+		"format", format,
+		"imported", result.Imported,
+		"duplicates", result.Duplicates,
+		"rejected", result.Rejected,
+		"entered_by", enteredBy)
+
+	return result, nil
+}
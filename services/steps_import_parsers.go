@@ -0,0 +1,535 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formaten van activiteitenbestanden die de stappen import begrijpt
+const (
+	ActivityFormatGPX = "gpx"
+	ActivityFormatTCX = "tcx"
+	ActivityFormatFIT = "fit"
+	ActivityFormatCSV = "csv"
+)
+
+// StepLengthMeters is de gemiddelde staplengte van een wandelaar. Bevat een bestand alleen een
+// afstand, dan worden de stappen daarmee geschat.
+const StepLengthMeters = 0.75
+
+// ErrUnsupportedActivityFile wordt teruggegeven als een bestand niet gelezen kan worden
+var ErrUnsupportedActivityFile = errors.New("onbekend of ongeldig activiteitenbestand")
+
+// ImportedActivity is één activiteit (of bij een CSV één dag) uit een geüpload bestand
+type ImportedActivity struct {
+	StartedAt      time.Time `json:"started_at"`
+	DistanceMeters float64   `json:"distance_meters"`
+	Steps          int       `json:"steps"`
+	StepsEstimated bool      `json:"steps_estimated"`
+	Hash           string    `json:"hash"`
+	Status         string    `json:"status"` // imported, duplicate of rejected
+	Reason         string    `json:"reason,omitempty"`
+}
+
+// fingerprint berekent de hash waarmee een dubbele upload van dezelfde activiteit wordt herkend.
+// Een CSV dag wordt herkend aan dag en dagtotaal; de afstand kan tussen exports verschillen.
+func (a *ImportedActivity) fingerprint(format string) string {
+	key := fmt.Sprintf("%s|%s|%d|%.0f", format, a.StartedAt.UTC().Format(time.RFC3339), a.Steps, a.DistanceMeters)
+	if format == ActivityFormatCSV {
+		key = fmt.Sprintf("%s|%s|%d", format, a.StartedAt.UTC().Format(time.RFC3339), a.Steps)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// estimateSteps vult de stappen aan uit de afstand als het bestand er zelf geen bevat
+func (a *ImportedActivity) estimateSteps() {
+	if a.Steps == 0 && a.DistanceMeters > 0 {
+		a.Steps = int(math.Round(a.DistanceMeters / StepLengthMeters))
+		a.StepsEstimated = true
+	}
+}
+
+// ParseActivityFile herkent het formaat van een bestand aan de inhoud of de extensie en leest de
+// activiteiten eruit. Dagen in een CSV worden afgebakend in location.
+func ParseActivityFile(fileName string, data []byte, location *time.Location) (string, []ImportedActivity, error) {
+	format := detectActivityFormat(fileName, data)
+
+	var activities []ImportedActivity
+	var err error
+	switch format {
+	case ActivityFormatFIT:
+		activities, err = parseFIT(data)
+	case ActivityFormatGPX:
+		activities, err = parseGPX(data)
+	case ActivityFormatTCX:
+		activities, err = parseTCX(data)
+	default:
+		activities, err = parseStepsCSV(data, location)
+	}
+	if err != nil {
+		return format, nil, fmt.Errorf("%w: %v", ErrUnsupportedActivityFile, err)
+	}
+	if len(activities) == 0 {
+		return format, nil, fmt.Errorf("%w: geen activiteiten gevonden", ErrUnsupportedActivityFile)
+	}
+
+	for i := range activities {
+		activities[i].estimateSteps()
+	}
+	return format, activities, nil
+}
+
+// detectActivityFormat bepaalt het formaat; de inhoud gaat voor de extensie
+func detectActivityFormat(fileName string, data []byte) string {
+	if len(data) >= 12 && string(data[8:12]) == ".FIT" {
+		return ActivityFormatFIT
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if trimmed := bytes.TrimSpace(head); bytes.HasPrefix(trimmed, []byte("<")) {
+		if bytes.Contains(head, []byte("TrainingCenterDatabase")) {
+			return ActivityFormatTCX
+		}
+		return ActivityFormatGPX
+	}
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gpx":
+		return ActivityFormatGPX
+	case ".tcx":
+		return ActivityFormatTCX
+	case ".fit":
+		return ActivityFormatFIT
+	}
+	return ActivityFormatCSV
+}
+
+// gpxFile bevat de delen van een GPX bestand die nodig zijn voor afstand en tijd
+type gpxFile struct {
+	Time   string `xml:"metadata>time"`
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat  float64 `xml:"lat,attr"`
+				Lon  float64 `xml:"lon,attr"`
+				Time string  `xml:"time"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// parseGPX maakt van elke track een activiteit. GPX bevat geen stappen; die worden geschat uit
+// de afstand over de trackpunten.
+func parseGPX(data []byte) ([]ImportedActivity, error) {
+	var file gpxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("ongeldige GPX: %v", err)
+	}
+
+	var activities []ImportedActivity
+	for _, track := range file.Tracks {
+		var activity ImportedActivity
+		for _, segment := range track.Segments {
+			for i, point := range segment.Points {
+				if activity.StartedAt.IsZero() && point.Time != "" {
+					if t, err := time.Parse(time.RFC3339, point.Time); err == nil {
+						activity.StartedAt = t
+					}
+				}
+				if i > 0 {
+					prev := segment.Points[i-1]
+					activity.DistanceMeters += haversineMeters(prev.Lat, prev.Lon, point.Lat, point.Lon)
+				}
+			}
+		}
+		if activity.StartedAt.IsZero() && file.Time != "" {
+			if t, err := time.Parse(time.RFC3339, file.Time); err == nil {
+				activity.StartedAt = t
+			}
+		}
+		if activity.StartedAt.IsZero() {
+			return nil, errors.New("GPX track zonder tijd")
+		}
+		activity.DistanceMeters = math.Round(activity.DistanceMeters)
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+// haversineMeters berekent de afstand tussen twee coördinaten over het aardoppervlak
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// tcxFile bevat de delen van een TCX bestand die nodig zijn voor afstand, tijd en stappen
+type tcxFile struct {
+	Activities []struct {
+		ID   string `xml:"Id"`
+		Laps []struct {
+			StartTime      string  `xml:"StartTime,attr"`
+			DistanceMeters float64 `xml:"DistanceMeters"`
+			Steps          int     `xml:"Extensions>LX>Steps"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// parseTCX maakt van elke activiteit een activiteit; afstand en stappen (uit de LX extensie van
+// Garmin) worden over de ronden opgeteld
+func parseTCX(data []byte) ([]ImportedActivity, error) {
+	var file tcxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("ongeldige TCX: %v", err)
+	}
+
+	var activities []ImportedActivity
+	for _, item := range file.Activities {
+		var activity ImportedActivity
+		start := item.ID
+		if len(item.Laps) > 0 && item.Laps[0].StartTime != "" {
+			start = item.Laps[0].StartTime
+		}
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, fmt.Errorf("TCX activiteit zonder geldige starttijd: %q", start)
+		}
+		activity.StartedAt = t
+
+		for _, lap := range item.Laps {
+			activity.DistanceMeters += lap.DistanceMeters
+			activity.Steps += lap.Steps
+		}
+		activity.DistanceMeters = math.Round(activity.DistanceMeters)
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+// FIT berichten en velden die de import gebruikt
+const (
+	fitMessageSession = 18
+	fitMessageRecord  = 20
+
+	fitFieldTimestamp       = 253
+	fitFieldSessionStart    = 2
+	fitFieldSessionSport    = 5
+	fitFieldSessionDistance = 9
+	fitFieldSessionCycles   = 10
+	fitFieldRecordDistance  = 5
+
+	fitSportRunning = 1
+	fitSportWalking = 11
+	fitSportHiking  = 17
+)
+
+// fitEpoch is het nulpunt van tijden in FIT bestanden
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// fitDefinition beschrijft de velden van een lokaal berichttype
+type fitDefinition struct {
+	global    uint16
+	bigEndian bool
+	fields    [][2]byte // veldnummer en grootte
+	devSize   int
+}
+
+// parseFIT leest een FIT bestand van een horloge. Elke sessie wordt een activiteit; bij lopen en
+// wandelen telt een sessie schreden (total_cycles) en is een schrede twee stappen. Zonder sessies
+// wordt de activiteit opgebouwd uit de losse meetpunten.
+func parseFIT(data []byte) ([]ImportedActivity, error) {
+	if len(data) < 12 {
+		return nil, errors.New("FIT bestand is te kort")
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || len(data) < headerSize || string(data[8:12]) != ".FIT" {
+		return nil, errors.New("ongeldige FIT header")
+	}
+	end := headerSize + int(binary.LittleEndian.Uint32(data[4:8]))
+	if end > len(data) {
+		return nil, errors.New("FIT bestand is afgekapt")
+	}
+
+	definitions := make(map[byte]*fitDefinition)
+	var sessions []ImportedActivity
+	var fromRecords ImportedActivity
+
+	pos := headerSize
+	for pos < end {
+		header := data[pos]
+		pos++
+
+		var local byte
+		switch {
+		case header&0x80 != 0:
+			// Compressed timestamp header: altijd een databericht
+			local = (header >> 5) & 0x03
+		case header&0x40 != 0:
+			local = header & 0x0F
+			definition, next, err := readFITDefinition(data, pos, end, header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[local] = definition
+			pos = next
+			continue
+		default:
+			local = header & 0x0F
+		}
+
+		definition, ok := definitions[local]
+		if !ok {
+			return nil, fmt.Errorf("FIT databericht zonder definitie (%d)", local)
+		}
+		values := make(map[byte]uint64, len(definition.fields))
+		for _, field := range definition.fields {
+			size := int(field[1])
+			if pos+size > end {
+				return nil, errors.New("FIT bericht is afgekapt")
+			}
+			if value, ok := fitUint(data[pos:pos+size], definition.bigEndian); ok {
+				values[field[0]] = value
+			}
+			pos += size
+		}
+		pos += definition.devSize
+
+		switch definition.global {
+		case fitMessageSession:
+			var session ImportedActivity
+			if start, ok := values[fitFieldSessionStart]; ok {
+				session.StartedAt = fitEpoch.Add(time.Duration(start) * time.Second)
+			} else if timestamp, ok := values[fitFieldTimestamp]; ok {
+				session.StartedAt = fitEpoch.Add(time.Duration(timestamp) * time.Second)
+			}
+			session.DistanceMeters = math.Round(float64(values[fitFieldSessionDistance]) / 100)
+			switch values[fitFieldSessionSport] {
+			case fitSportRunning, fitSportWalking, fitSportHiking:
+				session.Steps = int(values[fitFieldSessionCycles]) * 2
+			}
+			sessions = append(sessions, session)
+		case fitMessageRecord:
+			if timestamp, ok := values[fitFieldTimestamp]; ok && fromRecords.StartedAt.IsZero() {
+				fromRecords.StartedAt = fitEpoch.Add(time.Duration(timestamp) * time.Second)
+			}
+			if distance, ok := values[fitFieldRecordDistance]; ok {
+				fromRecords.DistanceMeters = math.Round(float64(distance) / 100)
+			}
+		}
+	}
+
+	if len(sessions) > 0 {
+		for _, session := range sessions {
+			if session.StartedAt.IsZero() {
+				return nil, errors.New("FIT sessie zonder starttijd")
+			}
+		}
+		return sessions, nil
+	}
+	if fromRecords.StartedAt.IsZero() {
+		return nil, nil
+	}
+	return []ImportedActivity{fromRecords}, nil
+}
+
+// readFITDefinition leest een definitiebericht vanaf pos en geeft de positie erna terug
+func readFITDefinition(data []byte, pos, end int, developerData bool) (*fitDefinition, int, error) {
+	if pos+5 > end {
+		return nil, 0, errors.New("FIT definitie is afgekapt")
+	}
+	definition := &fitDefinition{bigEndian: data[pos+1] == 1}
+	if definition.bigEndian {
+		definition.global = binary.BigEndian.Uint16(data[pos+2 : pos+4])
+	} else {
+		definition.global = binary.LittleEndian.Uint16(data[pos+2 : pos+4])
+	}
+	count := int(data[pos+4])
+	pos += 5
+
+	if pos+3*count > end {
+		return nil, 0, errors.New("FIT definitie is afgekapt")
+	}
+	for i := 0; i < count; i++ {
+		definition.fields = append(definition.fields, [2]byte{data[pos], data[pos+1]})
+		pos += 3
+	}
+
+	if developerData {
+		if pos >= end {
+			return nil, 0, errors.New("FIT definitie is afgekapt")
+		}
+		count = int(data[pos])
+		pos++
+		if pos+3*count > end {
+			return nil, 0, errors.New("FIT definitie is afgekapt")
+		}
+		for i := 0; i < count; i++ {
+			definition.devSize += int(data[pos+1])
+			pos += 3
+		}
+	}
+	return definition, pos, nil
+}
+
+// fitUint leest een veld van 1, 2 of 4 bytes; ongeldige waarden (alle bits gezet) en andere
+// groottes worden overgeslagen
+func fitUint(raw []byte, bigEndian bool) (uint64, bool) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	switch len(raw) {
+	case 1:
+		return uint64(raw[0]), raw[0] != 0xFF
+	case 2:
+		value := order.Uint16(raw)
+		return uint64(value), value != 0xFFFF
+	case 4:
+		value := order.Uint32(raw)
+		return uint64(value), value != 0xFFFFFFFF
+	}
+	return 0, false
+}
+
+// csvDateLayouts zijn de datumnotaties die in exports van Google Fit en gezondheidsapps voorkomen
+var csvDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04",
+	time.RFC3339,
+	"02-01-2006",
+	"02/01/2006",
+	"2006/01/02",
+}
+
+// parseStepsCSV leest een export met een datumkolom en een kolom voor stappen en/of afstand. De
+// kolommen worden herkend aan hun naam (Date, Datum, Start; Step count, Stappen; Distance (m),
+// Afstand (km)). Meerdere regels op één dag worden opgeteld tot één activiteit per dag.
+func parseStepsCSV(data []byte, location *time.Location) ([]ImportedActivity, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	firstLine := text
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		firstLine = text[:i]
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("ongeldige CSV: %v", err)
+	}
+	if len(rows) < 2 {
+		return nil, nil
+	}
+
+	dateColumn, stepsColumn, distanceColumn := -1, -1, -1
+	distanceFactor := 1.0
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case dateColumn < 0 && (strings.HasPrefix(name, "date") || strings.HasPrefix(name, "datum") || strings.HasPrefix(name, "start")):
+			dateColumn = i
+		case stepsColumn < 0 && (strings.Contains(name, "step") || strings.Contains(name, "stappen")):
+			stepsColumn = i
+		case distanceColumn < 0 && (strings.Contains(name, "distance") || strings.Contains(name, "afstand")):
+			distanceColumn = i
+			switch {
+			case strings.Contains(name, "km"):
+				distanceFactor = 1000
+			case strings.Contains(name, "(mi)") || strings.Contains(name, "mile"):
+				distanceFactor = 1609.344
+			}
+		}
+	}
+	if dateColumn < 0 || (stepsColumn < 0 && distanceColumn < 0) {
+		return nil, errors.New("CSV mist een kolom voor datum en stappen of afstand")
+	}
+
+	perDay := make(map[string]*ImportedActivity)
+	for _, row := range rows[1:] {
+		if dateColumn >= len(row) || strings.TrimSpace(row[dateColumn]) == "" {
+			continue
+		}
+		day, err := parseCSVDay(row[dateColumn], location)
+		if err != nil {
+			return nil, err
+		}
+
+		key := day.Format("2006-01-02")
+		activity, ok := perDay[key]
+		if !ok {
+			activity = &ImportedActivity{StartedAt: day}
+			perDay[key] = activity
+		}
+		if stepsColumn >= 0 && stepsColumn < len(row) {
+			activity.Steps += parseCSVSteps(row[stepsColumn])
+		}
+		if distanceColumn >= 0 && distanceColumn < len(row) {
+			distance, _ := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(row[distanceColumn]), ",", "."), 64)
+			activity.DistanceMeters += distance * distanceFactor
+		}
+	}
+
+	activities := make([]ImportedActivity, 0, len(perDay))
+	for _, activity := range perDay {
+		activity.DistanceMeters = math.Round(activity.DistanceMeters)
+		activities = append(activities, *activity)
+	}
+	sort.Slice(activities, func(i, j int) bool { return activities[i].StartedAt.Before(activities[j].StartedAt) })
+	return activities, nil
+}
+
+// parseCSVDay leest een datum en geeft het begin van die dag in location
+func parseCSVDay(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			t = t.In(location)
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("onbekende datum in CSV: %q", value)
+}
+
+// parseCSVSteps leest een aantal stappen. Een decimaal deel ("8123.0") telt niet mee; drie cijfers
+// na de laatste punt of komma zijn een scheidingsteken voor duizendtallen ("8.123").
+func parseCSVSteps(value string) int {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndexAny(value, ".,"); i >= 0 && len(value)-i-1 != 3 {
+		value = value[:i]
+	}
+
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	steps, _ := strconv.Atoi(digits.String())
+	return steps
+}
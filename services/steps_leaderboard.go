@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLeaderboardLimit is het standaard aantal plaatsen in een klassement
+	DefaultLeaderboardLimit = 10
+	// MaxLeaderboardLimit is het grootste aantal plaatsen dat in één keer kan worden opgevraagd
+	MaxLeaderboardLimit = 100
+	// AnonymousParticipantName staat op het openbare klassement voor deelnemers zonder opt-in
+	AnonymousParticipantName = "Anonieme deelnemer"
+	// maxTeamNameLength is de langste toegestane teamnaam
+	maxTeamNameLength = 100
+)
+
+// StepMilestones zijn de totalen waarbij een deelnemer een badge en een felicitatie krijgt
+var StepMilestones = []int{100000, 250000, 500000, 1000000}
+
+var (
+	// ErrTeamNotFound wordt teruggegeven als het team niet bestaat
+	ErrTeamNotFound = errors.New("team niet gevonden")
+	// ErrInvalidTeam wordt teruggegeven bij een ongeldige naam of soort team
+	ErrInvalidTeam = errors.New("ongeldig team")
+	// ErrLeaderboardsDisabled wordt teruggegeven als de klassementen niet zijn geconfigureerd
+	ErrLeaderboardsDisabled = errors.New("klassementen zijn niet geconfigureerd")
+)
+
+// EnableLeaderboards zet teams en klassementen aan
+func (s *StepsService) EnableLeaderboards(teamRepo repository.StepTeamRepository, leaderboardRepo repository.LeaderboardRepository) {
+	s.teamRepo = teamRepo
+	s.leaderboardRepo = leaderboardRepo
+}
+
+// EnableMilestones zet mijlpalen aan; de felicitatie wordt via emailSender verstuurd
+func (s *StepsService) EnableMilestones(milestoneRepo repository.StepMilestoneRepository, emailSender EmailSender) {
+	s.milestoneRepo = milestoneRepo
+	s.emailSender = emailSender
+}
+
+// record boekt een regel in het grootboek en kent daarna de mijlpalen toe die met deze regel
+// zijn gepasseerd. Fouten bij mijlpalen worden gelogd; de stappen zijn dan al geboekt.
+func (s *StepsService) record(participant *models.Aanmelding, entry *models.StepEntry) (int, bool, error) {
+	total, created, err := s.stepEntryRepo.Record(context.Background(), entry)
+	return s.passMilestones(participant, entry, total, created, err)
+}
+
+// recordDayTotal boekt het verschil tussen dayTotal en wat al voor die dag is geïmporteerd, en
+// kent daarna de gepasseerde mijlpalen toe zoals record
+func (s *StepsService) recordDayTotal(participant *models.Aanmelding, entry *models.StepEntry, dayTotal int) (int, bool, error) {
+	total, created, err := s.stepEntryRepo.RecordDayTotal(context.Background(), entry, dayTotal)
+	return s.passMilestones(participant, entry, total, created, err)
+}
+
+// passMilestones kent de mijlpalen toe die met een nieuw geboekte regel zijn gepasseerd
+func (s *StepsService) passMilestones(participant *models.Aanmelding, entry *models.StepEntry, total int, created bool, err error) (int, bool, error) {
+	if err != nil || !created || entry.Delta <= 0 || s.milestoneRepo == nil {
+		return total, created, err
+	}
+
+	previous := total - entry.Delta
+	for _, milestone := range StepMilestones {
+		if previous < milestone && total >= milestone {
+			s.awardMilestone(participant, milestone, total)
+		}
+	}
+	return total, created, nil
+}
+
+// awardMilestone legt een behaalde mijlpaal vast en stuurt de deelnemer een felicitatie
+func (s *StepsService) awardMilestone(participant *models.Aanmelding, milestone, total int) {
+	ctx := context.Background()
+	award := &models.StepMilestone{
+		AanmeldingID: participant.ID,
+		Milestone:    milestone,
+		ReachedAt:    time.Now(),
+	}
+	awarded, err := s.milestoneRepo.Award(ctx, award)
+	if err != nil {
+		logger.Error("Kon mijlpaal niet vastleggen", "aanmelding_id", participant.ID, "milestone", milestone, "error", err)
+		return
+	}
+	if !awarded {
+		return
+	}
+	logger.Info("Mijlpaal behaald", "aanmelding_id", participant.ID, "milestone", milestone, "total", total)
+
+	if s.emailSender == nil || participant.TestMode || participant.Email == "" {
+		return
+	}
+	subject := fmt.Sprintf("Gefeliciteerd: %s stappen!", FormatSteps(milestone))
+	data := map[string]interface{}{
+		"Naam":      participant.Naam,
+		"Route":     participant.Afstand,
+		"Milestone": FormatSteps(milestone),
+		"Steps":     FormatSteps(total),
+	}
+	if err := s.emailSender.SendTemplateEmail(participant.Email, subject, "steps_milestone", data); err != nil {
+		logger.Error("Kon felicitatie voor mijlpaal niet versturen", "aanmelding_id", participant.ID, "milestone", milestone, "error", err)
+		return
+	}
+	if err := s.milestoneRepo.MarkEmailSent(ctx, award.ID, time.Now()); err != nil {
+		logger.Error("Kon verzending felicitatie niet vastleggen", "aanmelding_id", participant.ID, "milestone", milestone, "error", err)
+	}
+}
+
+// GetMilestones haalt de behaalde mijlpalen van een deelnemer op
+func (s *StepsService) GetMilestones(participantID string) ([]*models.StepMilestone, error) {
+	if s.milestoneRepo == nil {
+		return []*models.StepMilestone{}, nil
+	}
+	milestones, err := s.milestoneRepo.ListByAanmelding(context.Background(), participantID)
+	if err != nil {
+		return nil, fmt.Errorf("kon mijlpalen niet ophalen: %w", err)
+	}
+	return milestones, nil
+}
+
+// FormatSteps schrijft een aantal stappen met punten als scheidingsteken, zoals 250.000
+func FormatSteps(steps int) string {
+	digits := strconv.Itoa(steps)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// LeaderboardFilter bakent een klassement af op het evenementjaar van de aanmelding, zodat
+// deelnemers van vorige edities niet meetellen
+func (s *StepsService) LeaderboardFilter(year int) models.LeaderboardFilter {
+	from, to := s.eventPeriod(year)
+	return models.LeaderboardFilter{
+		RegisteredFrom: from,
+		RegisteredTo:   to,
+		Limit:          DefaultLeaderboardLimit,
+	}
+}
+
+// Leaderboard geeft het klassement van deelnemers. Gelijke totalen delen een plaats. Voor het
+// openbare klassement (public) verdwijnen de ID's en staat alleen de naam van deelnemers die
+// daarvoor hebben gekozen erop, ingekort tot voornaam en initiaal.
+func (s *StepsService) Leaderboard(filter models.LeaderboardFilter, public bool) ([]models.LeaderboardEntry, error) {
+	if s.leaderboardRepo == nil {
+		return nil, ErrLeaderboardsDisabled
+	}
+	entries, err := s.leaderboardRepo.Participants(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("kon klassement niet ophalen: %w", err)
+	}
+
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].Steps == entries[i-1].Steps {
+			entries[i].Rank = entries[i-1].Rank
+		}
+		if public {
+			entries[i].AanmeldingID = ""
+			entries[i].TeamID = nil
+			entries[i].Naam = publicParticipantName(entries[i].Naam, entries[i].Zichtbaar)
+		}
+	}
+	if entries == nil {
+		entries = []models.LeaderboardEntry{}
+	}
+	return entries, nil
+}
+
+// publicParticipantName kort een naam in tot voornaam en initiaal van de achternaam
+func publicParticipantName(naam string, zichtbaar bool) string {
+	parts := strings.Fields(naam)
+	if !zichtbaar || len(parts) == 0 {
+		return AnonymousParticipantName
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	initial, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+	return parts[0] + " " + strings.ToUpper(string(initial)) + "."
+}
+
+// TeamLeaderboard geeft het klassement van actieve teams, op totaal of op gemiddelde per lid
+func (s *StepsService) TeamLeaderboard(filter models.LeaderboardFilter) ([]models.TeamLeaderboardEntry, error) {
+	if s.leaderboardRepo == nil {
+		return nil, ErrLeaderboardsDisabled
+	}
+	entries, err := s.leaderboardRepo.Teams(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("kon teamklassement niet ophalen: %w", err)
+	}
+
+	score := func(entry models.TeamLeaderboardEntry) int {
+		if filter.SortByAverage {
+			return entry.AverageSteps
+		}
+		return entry.Steps
+	}
+	for i := range entries {
+		if entries[i].Members > 0 {
+			entries[i].AverageSteps = entries[i].Steps / entries[i].Members
+		}
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && score(entries[i]) == score(entries[i-1]) {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	if entries == nil {
+		entries = []models.TeamLeaderboardEntry{}
+	}
+	return entries, nil
+}
+
+// SetLeaderboardVisibility legt vast of een deelnemer met naam op het openbare klassement wil
+func (s *StepsService) SetLeaderboardVisibility(participantID string, visible bool) error {
+	if s.leaderboardRepo == nil {
+		return ErrLeaderboardsDisabled
+	}
+	if err := s.leaderboardRepo.SetVisibility(context.Background(), participantID, visible); err != nil {
+		return fmt.Errorf("kon zichtbaarheid niet bijwerken: %w", err)
+	}
+	logger.Info("Zichtbaarheid op klassement bijgewerkt", "aanmelding_id", participantID, "zichtbaar", visible)
+	return nil
+}
+
+// validateTeam controleert en normaliseert de naam en soort van een team
+func validateTeam(team *models.StepTeam) error {
+	team.Naam = strings.TrimSpace(team.Naam)
+	if team.Naam == "" {
+		return fmt.Errorf("%w: naam is verplicht", ErrInvalidTeam)
+	}
+	if utf8.RuneCountInString(team.Naam) > maxTeamNameLength {
+		return fmt.Errorf("%w: naam is langer dan %d tekens", ErrInvalidTeam, maxTeamNameLength)
+	}
+	if team.Type == "" {
+		team.Type = models.StepTeamTypeOrganisatie
+	}
+	for _, teamType := range models.StepTeamTypes {
+		if team.Type == teamType {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: onbekende soort %q", ErrInvalidTeam, team.Type)
+}
+
+// ListTeams haalt alle teams op
+func (s *StepsService) ListTeams() ([]*models.StepTeam, error) {
+	if s.teamRepo == nil {
+		return nil, ErrLeaderboardsDisabled
+	}
+	teams, err := s.teamRepo.List(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("kon teams niet ophalen: %w", err)
+	}
+	return teams, nil
+}
+
+// GetTeam haalt een team op
+func (s *StepsService) GetTeam(id string) (*models.StepTeam, error) {
+	if s.teamRepo == nil {
+		return nil, ErrLeaderboardsDisabled
+	}
+	team, err := s.teamRepo.GetByID(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("kon team niet ophalen: %w", err)
+	}
+	if team == nil {
+		return nil, ErrTeamNotFound
+	}
+	return team, nil
+}
+
+// CreateTeam maakt een nieuw team aan
+func (s *StepsService) CreateTeam(team *models.StepTeam) error {
+	if s.teamRepo == nil {
+		return ErrLeaderboardsDisabled
+	}
+	if err := validateTeam(team); err != nil {
+		return err
+	}
+	team.IsActief = true
+	if err := s.teamRepo.Create(context.Background(), team); err != nil {
+		return fmt.Errorf("kon team niet aanmaken: %w", err)
+	}
+	return nil
+}
+
+// UpdateTeam werkt de naam, soort, beschrijving en status van een team bij
+func (s *StepsService) UpdateTeam(id string, update *models.StepTeam) (*models.StepTeam, error) {
+	team, err := s.GetTeam(id)
+	if err != nil {
+		return nil, err
+	}
+	team.Naam = update.Naam
+	team.Type = update.Type
+	team.Beschrijving = update.Beschrijving
+	team.IsActief = update.IsActief
+	if err := validateTeam(team); err != nil {
+		return nil, err
+	}
+	if err := s.teamRepo.Update(context.Background(), team); err != nil {
+		return nil, fmt.Errorf("kon team niet bijwerken: %w", err)
+	}
+	return team, nil
+}
+
+// DeleteTeam verwijdert een team; de leden blijven deelnemer zonder team
+func (s *StepsService) DeleteTeam(id string) error {
+	if _, err := s.GetTeam(id); err != nil {
+		return err
+	}
+	if err := s.teamRepo.Delete(context.Background(), id); err != nil {
+		return fmt.Errorf("kon team niet verwijderen: %w", err)
+	}
+	return nil
+}
+
+// ListTeamMembers haalt de leden van een team op
+func (s *StepsService) ListTeamMembers(teamID string) ([]*models.Aanmelding, error) {
+	if _, err := s.GetTeam(teamID); err != nil {
+		return nil, err
+	}
+	members, err := s.teamRepo.ListMembers(context.Background(), teamID)
+	if err != nil {
+		return nil, fmt.Errorf("kon teamleden niet ophalen: %w", err)
+	}
+	return members, nil
+}
+
+// AddTeamMembers zet deelnemers in een team. Alle deelnemers moeten bestaan; een deelnemer die al
+// in een ander team zat wordt verplaatst.
+func (s *StepsService) AddTeamMembers(teamID string, participantIDs []string) error {
+	if _, err := s.GetTeam(teamID); err != nil {
+		return err
+	}
+	if len(participantIDs) == 0 {
+		return fmt.Errorf("%w: geen deelnemers opgegeven", ErrInvalidTeam)
+	}
+	for _, id := range participantIDs {
+		if _, err := s.GetParticipant(id); err != nil {
+			return err
+		}
+	}
+	if err := s.teamRepo.AddMembers(context.Background(), teamID, participantIDs); err != nil {
+		return fmt.Errorf("kon teamleden niet toevoegen: %w", err)
+	}
+	return nil
+}
+
+// RemoveTeamMember haalt een deelnemer uit een team
+func (s *StepsService) RemoveTeamMember(teamID, participantID string) error {
+	if _, err := s.GetTeam(teamID); err != nil {
+		return err
+	}
+	if err := s.teamRepo.RemoveMember(context.Background(), teamID, participantID); err != nil {
+		return fmt.Errorf("kon teamlid niet verwijderen: %w", err)
+	}
+	return nil
+}
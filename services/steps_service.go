@@ -1,241 +1,281 @@
 package services
 
 import (
+	"context"
+	"dklautomationgo/logger"
 	"dklautomationgo/models"
 	"dklautomationgo/repository"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// StepsEventTimezone is de tijdzone waarin dagen en evenementjaren worden afgebakend
+const StepsEventTimezone = "Europe/Amsterdam"
+
+const (
+	// DefaultStepsHistoryDays is de standaard periode van de geschiedenis per dag
+	DefaultStepsHistoryDays = 30
+	// MaxStepsHistoryDays is de langste periode die in één keer kan worden opgevraagd
+	MaxStepsHistoryDays = 366
+	// maxStepsClockSkew is hoeveel een recorded_at in de toekomst mag liggen
+	maxStepsClockSkew = 5 * time.Minute
+)
+
+var (
+	// ErrParticipantNotFound wordt teruggegeven als de deelnemer niet bestaat
+	ErrParticipantNotFound = errors.New("deelnemer niet gevonden")
+	// ErrCorrectionReasonRequired wordt teruggegeven als een correctie geen reden heeft
+	ErrCorrectionReasonRequired = errors.New("een correctie vereist een reden")
+	// ErrInvalidStepsEntry wordt teruggegeven bij een ongeldige regel of periode
+	ErrInvalidStepsEntry = errors.New("ongeldige stappen invoer")
+)
+
 // StepsService bevat business logic voor stappen tracking
 type StepsService struct {
 	db             *gorm.DB
 	aanmeldingRepo repository.AanmeldingRepository
 	routeFundRepo  repository.RouteFundRepository
+	stepEntryRepo  repository.StepEntryRepository
+	eventRepo      repository.EventRepository
+	location       *time.Location
+
+	// Optioneel: teams, klassementen en mijlpalen (zie EnableLeaderboards en EnableMilestones)
+	teamRepo        repository.StepTeamRepository
+	leaderboardRepo repository.LeaderboardRepository
+	milestoneRepo   repository.StepMilestoneRepository
+	emailSender     EmailSender
 }
 
 // NewStepsService maakt een nieuwe steps service
-func NewStepsService(db *gorm.DB, aanmeldingRepo repository.AanmeldingRepository, routeFundRepo repository.RouteFundRepository) *StepsService {
+func NewStepsService(db *gorm.DB, aanmeldingRepo repository.AanmeldingRepository, routeFundRepo repository.RouteFundRepository, stepEntryRepo repository.StepEntryRepository, eventRepo repository.EventRepository) *StepsService {
+	location, err := time.LoadLocation(StepsEventTimezone)
+	if err != nil {
+		location = time.UTC
+	}
 	return &StepsService{
 		db:             db,
 		aanmeldingRepo: aanmeldingRepo,
 		routeFundRepo:  routeFundRepo,
+		stepEntryRepo:  stepEntryRepo,
+		eventRepo:      eventRepo,
+		location:       location,
 	}
 }
 
-// UpdateSteps werkt stappen bij voor een deelnemer (delta toevoegen)
-func (s *StepsService) UpdateSteps(participantID string, deltaSteps int) (*models.Aanmelding, error) {
-	// Haal deelnemer op
-	participant, err := s.aanmeldingRepo.GetByID(nil, participantID)
+// StepEntryOptions bevat de herkomst van een regel in het stappen grootboek
+type StepEntryOptions struct {
+	Source         string     // Standaard app
+	EnteredBy      string     // Gebruiker die de regel invoert
+	Reason         string     // Verplicht bij correcties
+	IdempotencyKey string     // Een herhaald verzoek met dezelfde key telt maar één keer
+	RecordedAt     *time.Time // Standaard nu
+	Metadata       *models.StepEntryMetadata
+}
+
+// GetParticipant haalt een deelnemer op
+func (s *StepsService) GetParticipant(participantID string) (*models.Aanmelding, error) {
+	participant, err := s.aanmeldingRepo.GetByID(context.Background(), participantID)
 	if err != nil {
-		return nil, fmt.Errorf("deelnemer niet gevonden: %w", err)
+		return nil, fmt.Errorf("kon deelnemer niet ophalen: %w", err)
 	}
 	if participant == nil {
-		return nil, fmt.Errorf("deelnemer niet gevonden")
+		return nil, ErrParticipantNotFound
 	}
+	return participant, nil
+}
 
-	// Update stappen (voorkom negatieve stappen)
-	newSteps := participant.Steps + deltaSteps
-	if newSteps < 0 {
-		newSteps = 0
+// GetParticipantByUserID haalt de deelnemer op die bij een gebruikersaccount hoort
+func (s *StepsService) GetParticipantByUserID(userID string) (*models.Aanmelding, error) {
+	var participant models.Aanmelding
+	result := s.db.Where("gebruiker_id = ?", userID).Limit(1).Find(&participant)
+	if result.Error != nil {
+		return nil, fmt.Errorf("kon deelnemer niet ophalen: %w", result.Error)
 	}
-	participant.Steps = newSteps
-
-	// Sla wijzigingen op
-	if err := s.aanmeldingRepo.Update(nil, participant); err != nil {
-		return nil, fmt.Errorf("kon stappen niet bijwerken: %w", err)
+	if result.RowsAffected == 0 {
+		return nil, ErrParticipantNotFound
 	}
-
-	return participant, nil
+	return &participant, nil
 }
 
-// UpdateStepsByUserID werkt stappen bij voor een deelnemer via gebruiker ID
-func (s *StepsService) UpdateStepsByUserID(userID string, deltaSteps int) (*models.Aanmelding, error) {
-	// Haal deelnemer op via gebruiker_id
-	var participant models.Aanmelding
-	err := s.db.Where("gebruiker_id = ?", userID).First(&participant).Error
+// UpdateSteps voegt stappen toe aan een deelnemer (delta) als nieuwe regel in het grootboek. Het
+// totaal komt nooit onder nul. Een herhaald verzoek met dezelfde idempotency key geeft het huidige
+// totaal terug zonder opnieuw te tellen.
+func (s *StepsService) UpdateSteps(participantID string, deltaSteps int, opts StepEntryOptions) (*models.Aanmelding, error) {
+	participant, err := s.GetParticipant(participantID)
 	if err != nil {
-		return nil, fmt.Errorf("deelnemer niet gevonden: %w", err)
+		return nil, err
 	}
 
-	// Update stappen (voorkom negatieve stappen)
-	newSteps := participant.Steps + deltaSteps
-	if newSteps < 0 {
-		newSteps = 0
+	if opts.Source == "" {
+		opts.Source = models.StepSourceApp
 	}
-	participant.Steps = newSteps
-
-	// Sla wijzigingen op
-	if err := s.aanmeldingRepo.Update(nil, &participant); err != nil {
-		return nil, fmt.Errorf("kon stappen niet bijwerken: %w", err)
+	entry, err := s.newEntry(participant.ID, deltaSteps, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return &participant, nil
-}
-
-// GetParticipantDashboard haalt dashboard data op voor een deelnemer
-func (s *StepsService) GetParticipantDashboard(participantID string) (*models.Aanmelding, int, error) {
-	// Haal deelnemer op
-	participant, err := s.aanmeldingRepo.GetByID(nil, participantID)
+	total, created, err := s.record(participant, entry)
 	if err != nil {
-		return nil, 0, fmt.Errorf("deelnemer niet gevonden: %w", err)
+		return nil, fmt.Errorf("kon stappen niet bijwerken: %w", err)
 	}
-	if participant == nil {
-		return nil, 0, fmt.Errorf("deelnemer niet gevonden")
+	if created {
+		logger.Info("Stappen geregistreerd",
+			"aanmelding_id", participant.ID,
+			"delta", entry.Delta,
+			"total", total,
+			"source", entry.Source,
+			"entered_by", opts.EnteredBy)
 	}
 
-	// Bereken allocated funds gebaseerd op afstand
-	allocatedFunds := s.CalculateAllocatedFunds(participant.Afstand)
-
-	return participant, allocatedFunds, nil
+	participant.Steps = total
+	return participant, nil
 }
 
-// GetParticipantDashboardByUserID haalt dashboard data op voor een deelnemer via gebruiker ID
-func (s *StepsService) GetParticipantDashboardByUserID(userID string) (*models.Aanmelding, int, error) {
-	// Haal deelnemer op via gebruiker_id
-	var participant models.Aanmelding
-	err := s.db.Where("gebruiker_id = ?", userID).First(&participant).Error
-	if err != nil {
-		return nil, 0, fmt.Errorf("deelnemer niet gevonden: %w", err)
+// RecordCorrection corrigeert het totaal van een deelnemer met een regel die een reden vermeldt
+func (s *StepsService) RecordCorrection(participantID string, deltaSteps int, reason, enteredBy string, recordedAt *time.Time) (*models.Aanmelding, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, ErrCorrectionReasonRequired
 	}
-
-	// Bereken allocated funds gebaseerd op afstand
-	allocatedFunds := s.CalculateAllocatedFunds(participant.Afstand)
-
-	return &participant, allocatedFunds, nil
+	if deltaSteps == 0 {
+		return nil, fmt.Errorf("%w: een correctie van 0 stappen", ErrInvalidStepsEntry)
+	}
+	return s.UpdateSteps(participantID, deltaSteps, StepEntryOptions{
+		Source:     models.StepSourceCorrection,
+		EnteredBy:  enteredBy,
+		Reason:     strings.TrimSpace(reason),
+		RecordedAt: recordedAt,
+	})
 }
 
-// CalculateAllocatedFunds berekent toegewezen fondsen gebaseerd op afstand
-func (s *StepsService) CalculateAllocatedFunds(route string) int {
-	// Haal fondsallocatie op uit database
-	routeFund, err := s.routeFundRepo.GetByRoute(nil, route)
-	if err != nil {
-		// Fallback naar standaard waarden als route niet gevonden wordt
-		switch route {
-		case "6 KM":
-			return 50
-		case "10 KM":
-			return 75
-		case "15 KM":
-			return 100
-		case "20 KM":
-			return 125
-		default:
-			return 50 // Standaard bedrag
+// newEntry valideert de opties en bouwt de regel voor het grootboek
+func (s *StepsService) newEntry(participantID string, deltaSteps int, opts StepEntryOptions) (*models.StepEntry, error) {
+	now := time.Now()
+	recordedAt := now
+	if opts.RecordedAt != nil {
+		if opts.RecordedAt.After(now.Add(maxStepsClockSkew)) {
+			return nil, fmt.Errorf("%w: recorded_at ligt in de toekomst", ErrInvalidStepsEntry)
 		}
+		recordedAt = *opts.RecordedAt
 	}
-	return routeFund.Amount
-}
 
-// GetTotalSteps haalt totaal aantal stappen op voor een jaar
-func (s *StepsService) GetTotalSteps(year int) (int, error) {
-	var total int
-	err := s.db.Model(&models.Aanmelding{}).
-		Where("EXTRACT(YEAR FROM created_at) = ?", year).
-		Select("SUM(steps)").Scan(&total).Error
-	if err != nil {
-		return 0, fmt.Errorf("kon totaal stappen niet ophalen: %w", err)
+	entry := &models.StepEntry{
+		AanmeldingID: participantID,
+		Delta:        deltaSteps,
+		Source:       opts.Source,
+		RecordedAt:   recordedAt,
+		Metadata:     opts.Metadata,
 	}
-	return total, nil
+	if opts.EnteredBy != "" {
+		entry.EnteredBy = &opts.EnteredBy
+	}
+	if opts.Reason != "" {
+		entry.Reason = &opts.Reason
+	}
+	if key := strings.TrimSpace(opts.IdempotencyKey); key != "" {
+		if len(key) > 255 {
+			return nil, fmt.Errorf("%w: idempotency key is te lang", ErrInvalidStepsEntry)
+		}
+		entry.IdempotencyKey = &key
+	}
+	return entry, nil
 }
 
-// GetFundsDistribution haalt fondsverdeling op over routes
-func (s *StepsService) GetFundsDistribution() (map[string]int, error) {
-	// Totaal bedrag (kan uit config komen)
-	totalFunds := 10000
-
-	// Routes
-	routes := []string{"6 KM", "10 KM", "15 KM", "20 KM"}
-	distribution := make(map[string]int)
-
-	// Verdeel gelijk over routes (kan proportioneel gemaakt worden)
-	fundsPerRoute := totalFunds / len(routes)
-
-	for _, route := range routes {
-		distribution[route] = fundsPerRoute
+// GetHistory geeft de stappen van een deelnemer per dag tussen from en to (beide inclusief, in de
+// tijdzone van het evenement). Dagen zonder stappen staan er met 0 in, zodat een grafiek geen
+// gaten heeft. Zonder periode worden de laatste DefaultStepsHistoryDays dagen teruggegeven.
+func (s *StepsService) GetHistory(participantID string, from, to *time.Time) ([]models.StepDayTotal, error) {
+	if _, err := s.GetParticipant(participantID); err != nil {
+		return nil, err
 	}
 
-	return distribution, nil
-}
+	end := s.startOfDay(time.Now())
+	if to != nil {
+		end = s.startOfDay(*to)
+	}
+	start := end.AddDate(0, 0, -(DefaultStepsHistoryDays - 1))
+	if from != nil {
+		start = s.startOfDay(*from)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("%w: from ligt na to", ErrInvalidStepsEntry)
+	}
+	if end.Sub(start) >= MaxStepsHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: periode is langer dan %d dagen", ErrInvalidStepsEntry, MaxStepsHistoryDays)
+	}
 
-// GetFundsDistributionProportional haalt proportionele fondsverdeling op gebaseerd op aantal deelnemers
-func (s *StepsService) GetFundsDistributionProportional() (map[string]int, int, error) {
-	// Haal alle route funds op
-	routeFunds, err := s.routeFundRepo.GetAll(nil)
+	totals, err := s.stepEntryRepo.DailyTotals(context.Background(), participantID, start, end.AddDate(0, 0, 1), s.location.String())
 	if err != nil {
-		return nil, 0, fmt.Errorf("kon route funds niet ophalen: %w", err)
+		return nil, fmt.Errorf("kon stappen geschiedenis niet ophalen: %w", err)
 	}
-
-	// Bereken totaal bedrag
-	totalFunds := 0
-	for _, rf := range routeFunds {
-		totalFunds += rf.Amount
+	perDay := make(map[string]int, len(totals))
+	for _, total := range totals {
+		perDay[total.Date] = total.Steps
 	}
 
-	distribution := make(map[string]int)
-
-	// Tel aantal deelnemers per route
-	totalParticipants := 0
-	for _, rf := range routeFunds {
-		var count int64
-		s.db.Model(&models.Aanmelding{}).Where("afstand = ?", rf.Route).Count(&count)
-		totalParticipants += int(count)
-		distribution[rf.Route] = int(count)
+	var history []models.StepDayTotal
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		history = append(history, models.StepDayTotal{Date: date, Steps: perDay[date]})
 	}
+	return history, nil
+}
 
-	// Verdeel proportioneel gebaseerd op aantal deelnemers
-	if totalParticipants > 0 {
-		for route, count := range distribution {
-			// Zoek het fondsbedrag voor deze route
-			for _, rf := range routeFunds {
-				if rf.Route == route {
-					distribution[route] = (rf.Amount * count) / totalParticipants
-					break
-				}
-			}
-		}
+// ListEntries haalt de regels van een deelnemer op, nieuwste eerst
+func (s *StepsService) ListEntries(participantID string, limit, offset int) ([]*models.StepEntry, error) {
+	if _, err := s.GetParticipant(participantID); err != nil {
+		return nil, err
 	}
+	entries, err := s.stepEntryRepo.ListByAanmelding(context.Background(), participantID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("kon stappen regels niet ophalen: %w", err)
+	}
+	return entries, nil
+}
 
-	return distribution, totalFunds, nil
+// ParseDay leest een dag (YYYY-MM-DD) in de tijdzone van het evenement
+func (s *StepsService) ParseDay(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, s.location)
 }
 
-// GetRouteFunds haalt alle route fondsallocaties op
-func (s *StepsService) GetRouteFunds() ([]*models.RouteFund, error) {
-	return s.routeFundRepo.GetAll(nil)
+// startOfDay geeft middernacht van de dag van t in de tijdzone van het evenement
+func (s *StepsService) startOfDay(t time.Time) time.Time {
+	t = t.In(s.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
 }
 
-// UpdateRouteFund werkt een route fondsallocatie bij
-func (s *StepsService) UpdateRouteFund(route string, amount int) (*models.RouteFund, error) {
-	// Controleer of route bestaat
-	existing, err := s.routeFundRepo.GetByRoute(nil, route)
+// GetParticipantDashboard haalt dashboard data op voor een deelnemer
+func (s *StepsService) GetParticipantDashboard(participantID string) (*models.Aanmelding, int, error) {
+	participant, err := s.GetParticipant(participantID)
 	if err != nil {
-		return nil, fmt.Errorf("route niet gevonden: %w", err)
+		return nil, 0, err
 	}
 
-	existing.Amount = amount
-	if err := s.routeFundRepo.Update(nil, existing); err != nil {
-		return nil, fmt.Errorf("kon route fund niet bijwerken: %w", err)
-	}
-
-	return existing, nil
+	// Bereken allocated funds gebaseerd op editie en afstand
+	return participant, s.CalculateAllocatedFunds(participant), nil
 }
 
-// CreateRouteFund maakt een nieuwe route fondsallocatie aan
-func (s *StepsService) CreateRouteFund(route string, amount int) (*models.RouteFund, error) {
-	routeFund := &models.RouteFund{
-		Route:  route,
-		Amount: amount,
-	}
-
-	if err := s.routeFundRepo.Create(nil, routeFund); err != nil {
-		return nil, fmt.Errorf("kon route fund niet aanmaken: %w", err)
+// GetParticipantDashboardByUserID haalt dashboard data op voor een deelnemer via gebruiker ID
+func (s *StepsService) GetParticipantDashboardByUserID(userID string) (*models.Aanmelding, int, error) {
+	participant, err := s.GetParticipantByUserID(userID)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return routeFund, nil
+	// Bereken allocated funds gebaseerd op editie en afstand
+	return participant, s.CalculateAllocatedFunds(participant), nil
 }
 
-// DeleteRouteFund verwijdert een route fondsallocatie
-func (s *StepsService) DeleteRouteFund(route string) error {
-	return s.routeFundRepo.Delete(nil, route)
+// GetTotalSteps haalt totaal aantal stappen op voor een evenementjaar uit het grootboek. Alle regels
+// van deelnemers die zich in dat jaar hebben aangemeld tellen mee, ook correcties van later.
+func (s *StepsService) GetTotalSteps(year int) (int, error) {
+	from, to := s.eventPeriod(year)
+	total, err := s.stepEntryRepo.SumByRegistrationPeriod(context.Background(), from, to)
+	if err != nil {
+		return 0, fmt.Errorf("kon totaal stappen niet ophalen: %w", err)
+	}
+	return total, nil
 }
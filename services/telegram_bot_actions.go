@@ -0,0 +1,607 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramQuickReplyPrefix is het voorvoegsel van de email templates die als snel antwoord in de
+// Telegram bot verschijnen, bijvoorbeeld "snelantwoord_ontvangen"
+const TelegramQuickReplyPrefix = "snelantwoord_"
+
+// TelegramLinkCodeTTL is hoe lang een koppelcode voor een Telegram account geldig is
+const TelegramLinkCodeTTL = 15 * time.Minute
+
+// telegramLinkPurpose is het doel waarvoor koppelcodes worden ondertekend
+const telegramLinkPurpose = "telegram_link"
+
+// Acties onder een notificatie in Telegram. De callback data heeft de vorm
+// "actie:soort:id" of "actie:soort:id:argument" en mag maximaal 64 bytes zijn.
+const (
+	TelegramActionHandled = "done"  // Markeer als afgehandeld
+	TelegramActionAssign  = "mine"  // Wijs toe aan mij
+	TelegramActionReply   = "reply" // Toon de snelle antwoorden
+	TelegramActionAnswer  = "ans"   // Verstuur een snel antwoord
+	TelegramActionBack    = "back"  // Terug naar de acties
+)
+
+// Soorten records waarop een actie kan worden uitgevoerd
+const (
+	telegramKindContact    = "c"
+	telegramKindAanmelding = "a"
+)
+
+// telegramTemplateIDLength is het aantal tekens van een template ID in de callback data
+const telegramTemplateIDLength = 8
+
+// ErrTelegramNotLinked wordt teruggegeven als een Telegram account niet aan een gebruiker is gekoppeld
+var ErrTelegramNotLinked = errors.New("telegram account is niet gekoppeld")
+
+// TelegramInlineButton is een knop onder een bericht
+type TelegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// TelegramInlineKeyboard zijn de rijen met knoppen onder een bericht
+type TelegramInlineKeyboard [][]TelegramInlineButton
+
+// Markup geeft het toetsenbord als reply_markup voor de Telegram API
+func (k TelegramInlineKeyboard) Markup() (string, error) {
+	rows := k
+	if rows == nil {
+		rows = TelegramInlineKeyboard{}
+	}
+	data, err := json.Marshal(map[string]interface{}{"inline_keyboard": rows})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal keyboard: %w", err)
+	}
+	return string(data), nil
+}
+
+// TelegramCallbackQuery is een klik op een knop onder een bericht
+type TelegramCallbackQuery struct {
+	ID   string `json:"id"`
+	From struct {
+		ID        int64  `json:"id"`
+		FirstName string `json:"first_name"`
+		Username  string `json:"username"`
+	} `json:"from"`
+	Message *struct {
+		MessageID int `json:"message_id"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message,omitempty"`
+	Data string `json:"data"`
+}
+
+// telegramAction is een ontlede callback
+type telegramAction struct {
+	Name     string
+	Kind     string
+	ID       string
+	Argument string
+}
+
+// data geeft de callback data voor de actie
+func (a telegramAction) data() string {
+	parts := []string{a.Name, a.Kind, a.ID}
+	if a.Argument != "" {
+		parts = append(parts, a.Argument)
+	}
+	return strings.Join(parts, ":")
+}
+
+// resource geeft de RBAC resource die bij de soort record hoort
+func (a telegramAction) resource() string {
+	if a.Kind == telegramKindAanmelding {
+		return "aanmelding"
+	}
+	return "contact"
+}
+
+// parseTelegramAction ontleedt de callback data van een knop
+func parseTelegramAction(data string) (telegramAction, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) < 3 || len(parts) > 4 || parts[2] == "" {
+		return telegramAction{}, fmt.Errorf("ongeldige actie %q", data)
+	}
+	action := telegramAction{Name: parts[0], Kind: parts[1], ID: parts[2]}
+	if len(parts) == 4 {
+		action.Argument = parts[3]
+	}
+
+	switch action.Kind {
+	case telegramKindContact, telegramKindAanmelding:
+	default:
+		return telegramAction{}, fmt.Errorf("onbekende soort in actie %q", data)
+	}
+	switch action.Name {
+	case TelegramActionHandled, TelegramActionAssign, TelegramActionReply, TelegramActionBack:
+	case TelegramActionAnswer:
+		if action.Argument == "" {
+			return telegramAction{}, fmt.Errorf("snel antwoord ontbreekt in actie %q", data)
+		}
+	default:
+		return telegramAction{}, fmt.Errorf("onbekende actie %q", data)
+	}
+	return action, nil
+}
+
+// TelegramActionKeyboard geeft de knoppen voor een notificatie over een contactformulier of
+// aanmelding, of nil als de notificatie nergens naar verwijst
+func TelegramActionKeyboard(notification *models.Notification) TelegramInlineKeyboard {
+	if notification.ReferenceID == nil || *notification.ReferenceID == "" {
+		return nil
+	}
+
+	var kind string
+	switch notification.Type {
+	case models.NotificationTypeContact:
+		kind = telegramKindContact
+	case models.NotificationTypeAanmelding:
+		kind = telegramKindAanmelding
+	default:
+		return nil
+	}
+	return telegramActionKeyboard(kind, *notification.ReferenceID)
+}
+
+// telegramActionKeyboard geeft de standaard acties voor een record
+func telegramActionKeyboard(kind, id string) TelegramInlineKeyboard {
+	button := func(text, name string) TelegramInlineButton {
+		return TelegramInlineButton{Text: text, CallbackData: telegramAction{Name: name, Kind: kind, ID: id}.data()}
+	}
+	return TelegramInlineKeyboard{
+		{button("✅ Afgehandeld", TelegramActionHandled), button("🙋 Ik pak het op", TelegramActionAssign)},
+		{button("✉️ Snel antwoord", TelegramActionReply)},
+	}
+}
+
+// TelegramReplySender verstuurt een antwoord per email, zoals de EmailService
+type TelegramReplySender interface {
+	SendEmailWithMetadata(to, subject, body string, meta EmailMetadata, fromAddress ...string) error
+}
+
+// EnableActions maakt de knoppen onder notificaties bruikbaar. Elke actie gaat via de
+// permissies van de gebruiker die aan het Telegram account is gekoppeld.
+func (s *TelegramBotService) EnableActions(
+	gebruikerRepo repository.GebruikerRepository,
+	permissionService PermissionService,
+	signer *TokenSigner,
+) {
+	s.gebruikerRepo = gebruikerRepo
+	s.permissionService = permissionService
+	s.signer = signer
+}
+
+// SetQuickReplies stelt de snelle antwoorden in. De antwoorden zijn de actieve email templates
+// waarvan de naam met TelegramQuickReplyPrefix begint.
+func (s *TelegramBotService) SetQuickReplies(
+	templateRepo repository.EmailTemplateRepository,
+	contactAntwoordRepo repository.ContactAntwoordRepository,
+	aanmeldingAntwoordRepo repository.AanmeldingAntwoordRepository,
+	sender TelegramReplySender,
+) {
+	s.templateRepo = templateRepo
+	s.contactAntwoordRepo = contactAntwoordRepo
+	s.aanmeldingAntwoordRepo = aanmeldingAntwoordRepo
+	s.replySender = sender
+}
+
+// CreateLinkCode maakt een koppelcode waarmee een gebruiker zijn Telegram account koppelt door
+// "/koppel <code>" naar de bot te sturen
+func (s *TelegramBotService) CreateLinkCode(userID string) (string, time.Time, error) {
+	if s.signer == nil {
+		return "", time.Time{}, fmt.Errorf("acties in de telegram bot zijn niet ingeschakeld")
+	}
+	expiresAt := time.Now().Add(TelegramLinkCodeTTL)
+	code := s.signer.Sign(telegramLinkPurpose, userID+"|"+strconv.FormatInt(expiresAt.Unix(), 10))
+	return code, expiresAt, nil
+}
+
+// LinkAccount koppelt een Telegram account aan de gebruiker uit de koppelcode. Een Telegram
+// account hoort bij één gebruiker; een eerdere koppeling wordt verbroken.
+func (s *TelegramBotService) LinkAccount(ctx context.Context, code string, telegramUserID int64) (*models.Gebruiker, error) {
+	if s.signer == nil || s.gebruikerRepo == nil {
+		return nil, fmt.Errorf("acties in de telegram bot zijn niet ingeschakeld")
+	}
+
+	value, err := s.signer.Verify(telegramLinkPurpose, strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+	userID, expiry, ok := strings.Cut(value, "|")
+	expiresAt, parseErr := strconv.ParseInt(expiry, 10, 64)
+	if !ok || parseErr != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidSignedToken
+	}
+
+	gebruiker, err := s.gebruikerRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if gebruiker == nil || !gebruiker.IsActief {
+		return nil, ErrInvalidSignedToken
+	}
+
+	previous, err := s.gebruikerRepo.GetByTelegramUserID(ctx, telegramUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked user: %w", err)
+	}
+	if previous != nil && previous.ID != gebruiker.ID {
+		previous.TelegramUserID = nil
+		if err := s.gebruikerRepo.Update(ctx, previous); err != nil {
+			return nil, fmt.Errorf("failed to unlink previous user: %w", err)
+		}
+	}
+
+	gebruiker.TelegramUserID = &telegramUserID
+	if err := s.gebruikerRepo.Update(ctx, gebruiker); err != nil {
+		return nil, fmt.Errorf("failed to link telegram account: %w", err)
+	}
+	return gebruiker, nil
+}
+
+// UnlinkAccount verbreekt de koppeling van een gebruiker met zijn Telegram account
+func (s *TelegramBotService) UnlinkAccount(ctx context.Context, userID string) error {
+	if s.gebruikerRepo == nil {
+		return fmt.Errorf("acties in de telegram bot zijn niet ingeschakeld")
+	}
+	gebruiker, err := s.gebruikerRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if gebruiker == nil || gebruiker.TelegramUserID == nil {
+		return nil
+	}
+	gebruiker.TelegramUserID = nil
+	return s.gebruikerRepo.Update(ctx, gebruiker)
+}
+
+// handleLinkCommand behandelt het /koppel commando
+func (s *TelegramBotService) handleLinkCommand(update *TelegramUpdate) (string, error) {
+	_, code, _ := strings.Cut(strings.TrimSpace(update.Message.Text), " ")
+	if strings.TrimSpace(code) == "" {
+		return "Stuur <b>/koppel &lt;code&gt;</b> met de koppelcode uit het dashboard.", nil
+	}
+
+	gebruiker, err := s.LinkAccount(context.Background(), code, int64(update.Message.From.ID))
+	if errors.Is(err, ErrInvalidSignedToken) {
+		return "❌ De koppelcode is ongeldig of verlopen. Vraag een nieuwe code aan in het dashboard.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🔗 Telegram account gekoppeld aan %s.", html.EscapeString(gebruiker.Naam)), nil
+}
+
+// HandleCallbackQuery voert de actie achter een knop uit namens de gekoppelde gebruiker en
+// geeft de tekst terug die de gebruiker als bevestiging ziet
+func (s *TelegramBotService) HandleCallbackQuery(ctx context.Context, query *TelegramCallbackQuery) (string, error) {
+	action, err := parseTelegramAction(query.Data)
+	if err != nil {
+		return "Onbekende actie", err
+	}
+
+	gebruiker, err := s.linkedGebruiker(ctx, query.From.ID)
+	if errors.Is(err, ErrTelegramNotLinked) {
+		return "Koppel eerst je account met /koppel", nil
+	}
+	if err != nil {
+		return "Er is een fout opgetreden", err
+	}
+	if !s.permissionService.HasPermission(ctx, gebruiker.ID, action.resource(), "write") {
+		logger.Warn("Telegram actie geweigerd",
+			"user_id", gebruiker.ID,
+			"action", action.Name,
+			"resource", action.resource())
+		return "Je hebt geen rechten voor deze actie", nil
+	}
+
+	var text string
+	switch action.Name {
+	case TelegramActionHandled:
+		text, err = s.updateRecordStatus(ctx, action, gebruiker, "gesloten", false)
+	case TelegramActionAssign:
+		text, err = s.updateRecordStatus(ctx, action, gebruiker, "in_behandeling", true)
+	case TelegramActionReply:
+		return s.showQuickReplies(ctx, query, action)
+	case TelegramActionBack:
+		return "", s.editReplyMarkup(query, telegramActionKeyboard(action.Kind, action.ID))
+	case TelegramActionAnswer:
+		text, err = s.sendQuickReply(ctx, action, gebruiker)
+		if err == nil {
+			_ = s.editReplyMarkup(query, telegramActionKeyboard(action.Kind, action.ID))
+		}
+	}
+	if err != nil {
+		return "Er is een fout opgetreden", err
+	}
+
+	logger.Info("Telegram actie uitgevoerd",
+		"user_id", gebruiker.ID,
+		"action", action.Name,
+		"resource", action.resource(),
+		"id", action.ID)
+
+	// De hele chat ziet wie wat heeft gedaan. Berichten gaan met parse_mode HTML; namen komen van
+	// gebruikers en formulieren en worden daarom ge-escaped. De callback tekst is platte tekst.
+	confirmation := fmt.Sprintf("%s (door %s)", html.EscapeString(text), html.EscapeString(gebruiker.Naam))
+	if query.Message != nil {
+		s.sendToChat(query.Message.Chat.ID, confirmation)
+	} else if err := s.SendMessage(confirmation); err != nil {
+		logger.Warn("Kon bevestiging van telegram actie niet versturen", "error", err)
+	}
+	return text, nil
+}
+
+// linkedGebruiker haalt de actieve gebruiker op die aan een Telegram account is gekoppeld
+func (s *TelegramBotService) linkedGebruiker(ctx context.Context, telegramUserID int64) (*models.Gebruiker, error) {
+	if s.gebruikerRepo == nil || s.permissionService == nil {
+		return nil, ErrTelegramNotLinked
+	}
+	gebruiker, err := s.gebruikerRepo.GetByTelegramUserID(ctx, telegramUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked user: %w", err)
+	}
+	if gebruiker == nil || !gebruiker.IsActief {
+		return nil, ErrTelegramNotLinked
+	}
+	return gebruiker, nil
+}
+
+// updateRecordStatus zet de status van een contactformulier of aanmelding. BehandeldDoor wordt
+// gezet als het record nog niet in behandeling was, of altijd als assign waar is.
+func (s *TelegramBotService) updateRecordStatus(ctx context.Context, action telegramAction, gebruiker *models.Gebruiker, status string, assign bool) (string, error) {
+	now := time.Now()
+	switch action.Kind {
+	case telegramKindContact:
+		contact, err := s.contactRepo.GetByID(ctx, action.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get contact form: %w", err)
+		}
+		if contact == nil {
+			return "Contactformulier niet gevonden", nil
+		}
+		contact.Status = status
+		if assign || contact.BehandeldDoor == nil || *contact.BehandeldDoor == "" {
+			contact.BehandeldDoor = &gebruiker.Email
+			contact.BehandeldOp = &now
+		}
+		if err := s.contactRepo.Update(ctx, contact); err != nil {
+			return "", fmt.Errorf("failed to update contact form: %w", err)
+		}
+		return fmt.Sprintf("Contactformulier van %s: %s", contact.Naam, telegramStatusLabel(status)), nil
+
+	default:
+		aanmelding, err := s.aanmeldingRepo.GetByID(ctx, action.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get registration: %w", err)
+		}
+		if aanmelding == nil {
+			return "Aanmelding niet gevonden", nil
+		}
+		aanmelding.Status = status
+		if assign || aanmelding.BehandeldDoor == nil || *aanmelding.BehandeldDoor == "" {
+			aanmelding.BehandeldDoor = &gebruiker.Email
+			aanmelding.BehandeldOp = &now
+		}
+		if err := s.aanmeldingRepo.Update(ctx, aanmelding); err != nil {
+			return "", fmt.Errorf("failed to update registration: %w", err)
+		}
+		return fmt.Sprintf("Aanmelding van %s: %s", aanmelding.Naam, telegramStatusLabel(status)), nil
+	}
+}
+
+// quickReplies haalt de actieve snelle antwoorden op, gesorteerd op naam
+func (s *TelegramBotService) quickReplies(ctx context.Context) ([]*models.EmailTemplate, error) {
+	if s.templateRepo == nil {
+		return nil, nil
+	}
+	templates, err := s.templateRepo.FindActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quick replies: %w", err)
+	}
+
+	var replies []*models.EmailTemplate
+	for _, tmpl := range templates {
+		if strings.HasPrefix(tmpl.Naam, TelegramQuickReplyPrefix) && len(tmpl.ID) >= telegramTemplateIDLength {
+			replies = append(replies, tmpl)
+		}
+	}
+	sort.Slice(replies, func(i, j int) bool { return replies[i].Naam < replies[j].Naam })
+	return replies, nil
+}
+
+// showQuickReplies vervangt de knoppen onder het bericht door de snelle antwoorden
+func (s *TelegramBotService) showQuickReplies(ctx context.Context, query *TelegramCallbackQuery, action telegramAction) (string, error) {
+	replies, err := s.quickReplies(ctx)
+	if err != nil {
+		return "Er is een fout opgetreden", err
+	}
+	if len(replies) == 0 || s.replySender == nil {
+		return "Er zijn geen snelle antwoorden ingesteld", nil
+	}
+
+	var keyboard TelegramInlineKeyboard
+	for _, reply := range replies {
+		label := reply.Beschrijving
+		if label == "" {
+			label = strings.ReplaceAll(strings.TrimPrefix(reply.Naam, TelegramQuickReplyPrefix), "_", " ")
+		}
+		answer := telegramAction{Name: TelegramActionAnswer, Kind: action.Kind, ID: action.ID, Argument: reply.ID[:telegramTemplateIDLength]}
+		keyboard = append(keyboard, []TelegramInlineButton{{Text: label, CallbackData: answer.data()}})
+	}
+	back := telegramAction{Name: TelegramActionBack, Kind: action.Kind, ID: action.ID}
+	keyboard = append(keyboard, []TelegramInlineButton{{Text: "« Terug", CallbackData: back.data()}})
+
+	if err := s.editReplyMarkup(query, keyboard); err != nil {
+		return "Er is een fout opgetreden", err
+	}
+	return "Kies een antwoord", nil
+}
+
+// sendQuickReply verstuurt een snel antwoord per email, legt het vast als antwoord bij het
+// record en zet de status op beantwoord
+func (s *TelegramBotService) sendQuickReply(ctx context.Context, action telegramAction, gebruiker *models.Gebruiker) (string, error) {
+	if s.replySender == nil {
+		return "Er zijn geen snelle antwoorden ingesteld", nil
+	}
+	replies, err := s.quickReplies(ctx)
+	if err != nil {
+		return "", err
+	}
+	var reply *models.EmailTemplate
+	for _, candidate := range replies {
+		if strings.HasPrefix(candidate.ID, action.Argument) {
+			reply = candidate
+			break
+		}
+	}
+	if reply == nil {
+		return "Dit snelle antwoord bestaat niet meer", nil
+	}
+
+	now := time.Now()
+	switch action.Kind {
+	case telegramKindContact:
+		contact, err := s.contactRepo.GetByID(ctx, action.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get contact form: %w", err)
+		}
+		if contact == nil {
+			return "Contactformulier niet gevonden", nil
+		}
+
+		tekst := renderQuickReply(reply, contact.Naam, contact.Email)
+		antwoord := &models.ContactAntwoord{ContactID: contact.ID, Tekst: tekst, VerzondDoor: gebruiker.Email}
+		if err := s.contactAntwoordRepo.Create(ctx, antwoord); err != nil {
+			return "", fmt.Errorf("failed to save answer: %w", err)
+		}
+
+		contact.Status = "beantwoord"
+		contact.Beantwoord = true
+		contact.AntwoordTekst = tekst
+		contact.AntwoordDatum = &now
+		contact.AntwoordDoor = gebruiker.Email
+		if contact.BehandeldDoor == nil || *contact.BehandeldDoor == "" {
+			contact.BehandeldDoor = &gebruiker.Email
+			contact.BehandeldOp = &now
+		}
+		if err := s.contactRepo.Update(ctx, contact); err != nil {
+			return "", fmt.Errorf("failed to update contact form: %w", err)
+		}
+
+		contactID := contact.ID
+		if err := s.replySender.SendEmailWithMetadata(contact.Email, reply.Onderwerp, tekst, EmailMetadata{Type: "contact_antwoord", Template: reply.Naam, ContactID: &contactID}); err != nil {
+			return "", fmt.Errorf("failed to send answer: %w", err)
+		}
+		antwoord.EmailVerzonden = true
+		if err := s.contactAntwoordRepo.Update(ctx, antwoord); err != nil {
+			logger.Error("Fout bij bijwerken antwoord e-mail status", "error", err, "antwoord_id", antwoord.ID)
+		}
+		return fmt.Sprintf("Contactformulier van %s beantwoord met %q", contact.Naam, reply.Onderwerp), nil
+
+	default:
+		aanmelding, err := s.aanmeldingRepo.GetByID(ctx, action.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get registration: %w", err)
+		}
+		if aanmelding == nil {
+			return "Aanmelding niet gevonden", nil
+		}
+
+		tekst := renderQuickReply(reply, aanmelding.Naam, aanmelding.Email)
+		antwoord := &models.AanmeldingAntwoord{AanmeldingID: aanmelding.ID, Tekst: tekst, VerzondDoor: gebruiker.Email}
+		if err := s.aanmeldingAntwoordRepo.Create(ctx, antwoord); err != nil {
+			return "", fmt.Errorf("failed to save answer: %w", err)
+		}
+
+		aanmelding.Status = "beantwoord"
+		if aanmelding.BehandeldDoor == nil || *aanmelding.BehandeldDoor == "" {
+			aanmelding.BehandeldDoor = &gebruiker.Email
+			aanmelding.BehandeldOp = &now
+		}
+		if err := s.aanmeldingRepo.Update(ctx, aanmelding); err != nil {
+			return "", fmt.Errorf("failed to update registration: %w", err)
+		}
+
+		aanmeldingID := aanmelding.ID
+		if err := s.replySender.SendEmailWithMetadata(aanmelding.Email, reply.Onderwerp, tekst, EmailMetadata{Type: "aanmelding_antwoord", Template: reply.Naam, AanmeldingID: &aanmeldingID}); err != nil {
+			return "", fmt.Errorf("failed to send answer: %w", err)
+		}
+		antwoord.EmailVerzonden = true
+		if err := s.aanmeldingAntwoordRepo.Update(ctx, antwoord); err != nil {
+			logger.Error("Fout bij bijwerken antwoord e-mail status", "error", err, "antwoord_id", antwoord.ID)
+		}
+		return fmt.Sprintf("Aanmelding van %s beantwoord met %q", aanmelding.Naam, reply.Onderwerp), nil
+	}
+}
+
+// renderQuickReply vult de naam en het email adres in een snel antwoord in. Lukt dat niet,
+// dan wordt de tekst ongewijzigd gebruikt.
+func renderQuickReply(reply *models.EmailTemplate, naam, email string) string {
+	tmpl, err := ParseTemplate(reply.Naam, reply.Inhoud)
+	if err != nil {
+		return reply.Inhoud
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}{"Naam": naam, "Email": email}); err != nil {
+		return reply.Inhoud
+	}
+	return buf.String()
+}
+
+// telegramStatusLabel geeft een leesbare omschrijving van een status
+func telegramStatusLabel(status string) string {
+	switch status {
+	case "gesloten":
+		return "afgehandeld ✅"
+	case "in_behandeling":
+		return "in behandeling 🙋"
+	case "beantwoord":
+		return "beantwoord ✉️"
+	}
+	return status
+}
+
+// answerCallbackQuery bevestigt een klik op een knop met een korte melding
+func (s *TelegramBotService) answerCallbackQuery(queryID, text string) error {
+	params := url.Values{}
+	params.Add("callback_query_id", queryID)
+	if text != "" {
+		params.Add("text", text)
+	}
+	return s.postForm("answerCallbackQuery", params)
+}
+
+// editReplyMarkup vervangt de knoppen onder het bericht waarop is geklikt
+func (s *TelegramBotService) editReplyMarkup(query *TelegramCallbackQuery, keyboard TelegramInlineKeyboard) error {
+	if query.Message == nil {
+		return nil
+	}
+	markup, err := keyboard.Markup()
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Add("chat_id", strconv.FormatInt(query.Message.Chat.ID, 10))
+	params.Add("message_id", strconv.Itoa(query.Message.MessageID))
+	params.Add("reply_markup", markup)
+	return s.postForm("editMessageReplyMarkup", params)
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"dklautomationgo/logger"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TelegramWebhookPath is het pad waarop Telegram updates aflevert in webhook modus
+const TelegramWebhookPath = "/api/v1/telegrambot/webhook"
+
+// TelegramWebhookSecretHeader is de header waarin Telegram het geheim van de webhook meestuurt
+const TelegramWebhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Manieren waarop de bot updates ontvangt
+const (
+	TelegramUpdateModeOff     = "off"     // Alleen berichten versturen
+	TelegramUpdateModePolling = "polling" // getUpdates elke paar seconden
+	TelegramUpdateModeWebhook = "webhook" // Telegram stuurt updates naar TelegramWebhookPath
+)
+
+// ErrInvalidWebhookSecret wordt teruggegeven als een webhook update niet het juiste geheim heeft
+var ErrInvalidWebhookSecret = errors.New("ongeldig webhook geheim")
+
+// telegramPublicCommands mogen in elke chat worden gebruikt, ook als die niet in het register staat,
+// zodat een nieuwe chat zijn ID kan opvragen en iedereen zijn account kan koppelen
+var telegramPublicCommands = map[string]bool{
+	"/start":  true,
+	"/help":   true,
+	"/koppel": true,
+}
+
+// SetChatRepository stelt het register van chats in. Zonder register reageert de bot alleen in
+// de standaard chat uit TELEGRAM_CHAT_ID.
+func (s *TelegramBotService) SetChatRepository(chatRepo repository.TelegramChatRepository) {
+	s.chatRepo = chatRepo
+}
+
+// EnsureDefaultChat zet de standaard chat in het register met alle notificatie types en commando's,
+// zodat een bestaande installatie hetzelfde blijft werken als het register wordt ingeschakeld
+func (s *TelegramBotService) EnsureDefaultChat(ctx context.Context) error {
+	if s.chatRepo == nil {
+		return nil
+	}
+	chatID, err := strconv.ParseInt(s.chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("ongeldige TELEGRAM_CHAT_ID %q: %w", s.chatID, err)
+	}
+
+	existing, err := s.chatRepo.GetByChatID(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to get default chat: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	return s.chatRepo.Create(ctx, &models.TelegramChat{
+		ChatID:          chatID,
+		Naam:            "Standaard",
+		Subscriptions:   models.TelegramChatList{models.TelegramChatAll},
+		AllowedCommands: models.TelegramChatList{models.TelegramChatAll},
+		IsActief:        true,
+	})
+}
+
+// chat haalt een chat uit het register. De standaard chat mag altijd alles, ook zonder register.
+func (s *TelegramBotService) chat(ctx context.Context, chatID int64) (*models.TelegramChat, error) {
+	if s.chatRepo != nil {
+		chat, err := s.chatRepo.GetByChatID(ctx, chatID)
+		if err != nil || chat != nil {
+			return chat, err
+		}
+	}
+	if strconv.FormatInt(chatID, 10) == s.chatID {
+		return &models.TelegramChat{
+			ChatID:          chatID,
+			Naam:            "Standaard",
+			Subscriptions:   models.TelegramChatList{models.TelegramChatAll},
+			AllowedCommands: models.TelegramChatList{models.TelegramChatAll},
+			IsActief:        true,
+		}, nil
+	}
+	return nil, nil
+}
+
+// handleSubscribeCommand behandelt het /subscribe commando
+func (s *TelegramBotService) handleSubscribeCommand(update *TelegramUpdate) (string, error) {
+	return s.changeSubscription(update, true)
+}
+
+// handleUnsubscribeCommand behandelt het /unsubscribe commando
+func (s *TelegramBotService) handleUnsubscribeCommand(update *TelegramUpdate) (string, error) {
+	return s.changeSubscription(update, false)
+}
+
+// changeSubscription zet een abonnement van de chat op een notificatie type aan of uit. Zonder
+// type toont het de huidige abonnementen. Wijzigen vraagt een gekoppeld account met notification:write.
+func (s *TelegramBotService) changeSubscription(update *TelegramUpdate, subscribe bool) (string, error) {
+	ctx := context.Background()
+	if s.chatRepo == nil {
+		return "Het register van chats is niet ingeschakeld.", nil
+	}
+	chat, err := s.chatRepo.GetByChatID(ctx, update.Message.Chat.ID)
+	if err != nil {
+		return "", err
+	}
+	if chat == nil {
+		return "Deze chat staat niet in het register.", nil
+	}
+
+	_, argument, _ := strings.Cut(strings.TrimSpace(update.Message.Text), " ")
+	notificationType := strings.ToLower(strings.TrimSpace(argument))
+	if notificationType == "" {
+		return formatChatSubscriptions(chat), nil
+	}
+	if notificationType != models.TelegramChatAll && !isKnownNotificationType(models.NotificationType(notificationType)) {
+		return fmt.Sprintf("Onbekend type %q.\n\n%s", notificationType, formatChatSubscriptions(chat)), nil
+	}
+
+	gebruiker, err := s.linkedGebruiker(ctx, int64(update.Message.From.ID))
+	if errors.Is(err, ErrTelegramNotLinked) {
+		return "Koppel eerst je account met /koppel", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !s.permissionService.HasPermission(ctx, gebruiker.ID, "notification", "write") {
+		return "Je hebt geen rechten om abonnementen te wijzigen", nil
+	}
+
+	if subscribe {
+		chat.Subscriptions = addChatSubscription(chat.Subscriptions, notificationType)
+	} else {
+		chat.Subscriptions = removeChatSubscription(chat.Subscriptions, notificationType)
+	}
+	if err := s.chatRepo.Update(ctx, chat); err != nil {
+		return "", fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	logger.Info("Telegram abonnement gewijzigd",
+		"chat", chat.Naam,
+		"type", notificationType,
+		"subscribe", subscribe,
+		"user_id", gebruiker.ID)
+	return formatChatSubscriptions(chat), nil
+}
+
+// addChatSubscription voegt een type toe; "*" vervangt alle losse types
+func addChatSubscription(subscriptions models.TelegramChatList, notificationType string) models.TelegramChatList {
+	if notificationType == models.TelegramChatAll {
+		return models.TelegramChatList{models.TelegramChatAll}
+	}
+	if subscriptions.Contains(notificationType) {
+		return subscriptions
+	}
+	return append(subscriptions, notificationType)
+}
+
+// removeChatSubscription haalt een type weg. Bij een abonnement op alles blijven de andere types over.
+func removeChatSubscription(subscriptions models.TelegramChatList, notificationType string) models.TelegramChatList {
+	if notificationType == models.TelegramChatAll {
+		return models.TelegramChatList{}
+	}
+
+	source := []string(subscriptions)
+	for _, item := range subscriptions {
+		if item == models.TelegramChatAll {
+			source = nil
+			for _, known := range models.NotificationTypes {
+				source = append(source, string(known))
+			}
+			break
+		}
+	}
+
+	result := models.TelegramChatList{}
+	for _, item := range source {
+		if item != notificationType {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// formatChatSubscriptions toont de abonnementen van een chat en de beschikbare types
+func formatChatSubscriptions(chat *models.TelegramChat) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔔 <b>Abonnementen van %s:</b>\n", chat.Naam))
+	for _, notificationType := range models.NotificationTypes {
+		mark := "⚪"
+		if chat.Subscriptions.Contains(string(notificationType)) {
+			mark = "🟢"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", mark, notificationType))
+	}
+	sb.WriteString("\nGebruik /subscribe &lt;type&gt; of /unsubscribe &lt;type&gt;, of * voor alle types.")
+	return sb.String()
+}
+
+// ConfigureWebhook laat Telegram updates naar de webhook sturen. Telegram stuurt het geheim mee
+// in TelegramWebhookSecretHeader; updates zonder het juiste geheim worden geweigerd.
+func (s *TelegramBotService) ConfigureWebhook(webhookURL, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("webhook geheim ontbreekt")
+	}
+	allowedUpdates, err := json.Marshal([]string{"message", "callback_query"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed updates: %w", err)
+	}
+
+	params := url.Values{}
+	params.Add("url", webhookURL)
+	params.Add("secret_token", secret)
+	params.Add("allowed_updates", string(allowedUpdates))
+	if err := s.postForm("setWebhook", params); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.webhookSecret = secret
+	s.mutex.Unlock()
+
+	if err := s.setMyCommands(); err != nil {
+		logger.Warn("Kon telegram commando's niet registreren", "error", err)
+	}
+	return nil
+}
+
+// DeleteWebhook schakelt de webhook uit; Telegram weigert getUpdates zolang er een webhook is
+func (s *TelegramBotService) DeleteWebhook() error {
+	return s.postForm("deleteWebhook", url.Values{})
+}
+
+// HandleWebhook verwerkt een update die Telegram naar de webhook stuurt. Een update die al
+// verwerkt is, bijvoorbeeld omdat Telegram hem opnieuw aflevert, wordt overgeslagen.
+func (s *TelegramBotService) HandleWebhook(secret string, body []byte) error {
+	s.mutex.Lock()
+	expected := s.webhookSecret
+	s.mutex.Unlock()
+	if expected == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		return ErrInvalidWebhookSecret
+	}
+
+	var update TelegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		return fmt.Errorf("failed to parse update: %w", err)
+	}
+
+	s.mutex.Lock()
+	if update.UpdateID < s.updateOffset {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.updateOffset = update.UpdateID + 1
+	s.mutex.Unlock()
+
+	s.processUpdate(&update)
+	return nil
+}
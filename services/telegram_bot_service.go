@@ -21,6 +21,7 @@ import (
 type TelegramBotService struct {
 	botToken        string
 	chatID          string
+	apiBaseURL      string
 	client          *http.Client
 	contactRepo     repository.ContactRepository
 	aanmeldingRepo  repository.AanmeldingRepository
@@ -29,8 +30,22 @@ type TelegramBotService struct {
 	polling         bool
 	pollingDone     chan struct{}
 	mutex           sync.Mutex
+	webhookSecret   string
+	chatRepo        repository.TelegramChatRepository
+
+	// Acties onder notificaties, zie EnableActions en SetQuickReplies
+	gebruikerRepo          repository.GebruikerRepository
+	permissionService      PermissionService
+	signer                 *TokenSigner
+	templateRepo           repository.EmailTemplateRepository
+	contactAntwoordRepo    repository.ContactAntwoordRepository
+	aanmeldingAntwoordRepo repository.AanmeldingAntwoordRepository
+	replySender            TelegramReplySender
 }
 
+// defaultTelegramAPIBaseURL is het adres van de Telegram Bot API
+const defaultTelegramAPIBaseURL = "https://api.telegram.org"
+
 // CommandHandlerFunc is een functie die een Telegram commando afhandelt
 type CommandHandlerFunc func(update *TelegramUpdate) (string, error)
 
@@ -55,6 +70,7 @@ type TelegramUpdate struct {
 		Date int    `json:"date"`
 		Text string `json:"text"`
 	} `json:"message"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query,omitempty"`
 }
 
 // TelegramResponse bevat de respons van de Telegram API
@@ -86,6 +102,7 @@ func NewTelegramBotService(
 	service := &TelegramBotService{
 		botToken:        botToken,
 		chatID:          chatID,
+		apiBaseURL:      defaultTelegramAPIBaseURL,
 		client:          &http.Client{Timeout: 10 * time.Second},
 		contactRepo:     contactRepo,
 		aanmeldingRepo:  aanmeldingRepo,
@@ -109,9 +126,36 @@ func (s *TelegramBotService) registerCommandHandlers() {
 		"/aanmelding":    s.handleAanmeldingCommand,
 		"/aanmeldingnew": s.handleNewAanmeldingCommand,
 		"/status":        s.handleStatusCommand,
+		"/koppel":        s.handleLinkCommand,
+		"/subscribe":     s.handleSubscribeCommand,
+		"/unsubscribe":   s.handleUnsubscribeCommand,
 	}
 }
 
+// SetAPIBaseURL stelt het adres van de Telegram Bot API in, bijvoorbeeld voor tests
+func (s *TelegramBotService) SetAPIBaseURL(baseURL string) {
+	s.apiBaseURL = strings.TrimRight(baseURL, "/")
+}
+
+// apiURL geeft het adres van een methode van de Telegram Bot API
+func (s *TelegramBotService) apiURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", s.apiBaseURL, s.botToken, method)
+}
+
+// postForm roept een methode van de Telegram Bot API aan
+func (s *TelegramBotService) postForm(method string, params url.Values) error {
+	resp, err := s.client.PostForm(s.apiURL(method), params)
+	if err != nil {
+		return fmt.Errorf("failed to call telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram API error, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // StartPolling start het pollen voor nieuwe berichten
 func (s *TelegramBotService) StartPolling() {
 	s.mutex.Lock()
@@ -166,7 +210,7 @@ func (s *TelegramBotService) StopPolling() {
 
 // getUpdates haalt updates op van de Telegram API
 func (s *TelegramBotService) getUpdates() ([]TelegramUpdate, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates", s.botToken)
+	apiURL := s.apiURL("getUpdates")
 
 	// Parameters voor het request
 	params := url.Values{}
@@ -212,10 +256,43 @@ func (s *TelegramBotService) getUpdates() ([]TelegramUpdate, error) {
 
 // processUpdate verwerkt een Telegram update
 func (s *TelegramBotService) processUpdate(update *TelegramUpdate) {
+	// Klik op een knop onder een notificatie
+	if update.CallbackQuery != nil {
+		text, err := s.HandleCallbackQuery(context.Background(), update.CallbackQuery)
+		if err != nil {
+			logger.Error("Error handling callback query",
+				"data", update.CallbackQuery.Data,
+				"error", err)
+		}
+		if err := s.answerCallbackQuery(update.CallbackQuery.ID, text); err != nil {
+			logger.Error("Error answering callback query", "error", err)
+		}
+		return
+	}
+
 	// Controleer of het bericht een commando is
 	if len(update.Message.Text) > 0 && update.Message.Text[0] == '/' {
-		// Haal het commando uit het bericht
+		// Haal het commando uit het bericht; in groepen staat de naam van de bot erachter (/help@dkl_bot)
 		command := strings.Split(update.Message.Text, " ")[0]
+		command, _, _ = strings.Cut(command, "@")
+		chatID := update.Message.Chat.ID
+
+		// Alleen chats uit het register mogen commando's gebruiken, en alleen de toegestane
+		if !telegramPublicCommands[command] {
+			chat, err := s.chat(context.Background(), chatID)
+			if err != nil {
+				logger.Error("Error getting telegram chat", "chat_id", chatID, "error", err)
+				return
+			}
+			if chat == nil {
+				logger.Warn("Telegram commando uit onbekende chat genegeerd", "command", command, "chat_id", chatID)
+				return
+			}
+			if !chat.AllowsCommand(command) {
+				s.sendToChat(chatID, fmt.Sprintf("⛔ %s is niet beschikbaar in deze chat.", command))
+				return
+			}
+		}
 
 		// Behandel het commando
 		if handler, ok := s.commandHandlers[command]; ok {
@@ -224,21 +301,21 @@ func (s *TelegramBotService) processUpdate(update *TelegramUpdate) {
 				logger.Error("Error handling command",
 					"command", command,
 					"error", err)
-				s.SendMessage("❌ Er is een fout opgetreden bij het uitvoeren van het commando. Probeer het later opnieuw.")
+				s.sendToChat(chatID, "❌ Er is een fout opgetreden bij het uitvoeren van het commando. Probeer het later opnieuw.")
 				return
 			}
 
-			// Stuur het antwoord
-			s.SendMessage(response)
+			// Stuur het antwoord naar de chat waar het commando vandaan kwam
+			s.sendToChat(chatID, response)
 		} else {
-			s.SendMessage(fmt.Sprintf("Onbekend commando: %s\nType /help voor een lijst met beschikbare commando's.", command))
+			s.sendToChat(chatID, fmt.Sprintf("Onbekend commando: %s\nType /help voor een lijst met beschikbare commando's.", command))
 		}
 	}
 }
 
 // setMyCommands registreert de bot commando's bij Telegram
 func (s *TelegramBotService) setMyCommands() error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/setMyCommands", s.botToken)
+	apiURL := s.apiURL("setMyCommands")
 
 	commands := []map[string]string{
 		{"command": "start", "description": "Start de bot"},
@@ -248,6 +325,9 @@ func (s *TelegramBotService) setMyCommands() error {
 		{"command": "aanmelding", "description": "Toon recente aanmeldingen"},
 		{"command": "aanmeldingnew", "description": "Toon onverwerkte aanmeldingen"},
 		{"command": "status", "description": "Toon status van de service"},
+		{"command": "koppel", "description": "Koppel je Telegram account aan je DKL account"},
+		{"command": "subscribe", "description": "Abonneer deze chat op een notificatie type"},
+		{"command": "unsubscribe", "description": "Zeg het abonnement van deze chat op een type op"},
 	}
 
 	commandsJSON, err := json.Marshal(commands)
@@ -274,13 +354,25 @@ func (s *TelegramBotService) setMyCommands() error {
 	return nil
 }
 
-// SendMessage stuurt een bericht naar de Telegram chat
+// SendMessage stuurt een bericht naar de standaard Telegram chat
 func (s *TelegramBotService) SendMessage(message string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", s.botToken)
+	return s.sendMessage(s.chatID, message)
+}
+
+// sendToChat stuurt een bericht naar een specifieke chat en logt als dat mislukt
+func (s *TelegramBotService) sendToChat(chatID int64, message string) {
+	if err := s.sendMessage(strconv.FormatInt(chatID, 10), message); err != nil {
+		logger.Error("Error sending telegram message", "chat_id", chatID, "error", err)
+	}
+}
+
+// sendMessage stuurt een bericht naar een Telegram chat
+func (s *TelegramBotService) sendMessage(chatID, message string) error {
+	apiURL := s.apiURL("sendMessage")
 
 	// Parameters voor het bericht
 	params := url.Values{}
-	params.Add("chat_id", s.chatID)
+	params.Add("chat_id", chatID)
 	params.Add("text", message)
 	params.Add("parse_mode", "HTML")
 
@@ -301,9 +393,19 @@ func (s *TelegramBotService) SendMessage(message string) error {
 
 // handleStartCommand behandelt het /start commando
 func (s *TelegramBotService) handleStartCommand(update *TelegramUpdate) (string, error) {
-	return "👋 Welkom bij de DKL Email Service Bot!\n\n" +
+	welcome := "👋 Welkom bij de DKL Email Service Bot!\n\n" +
 		"Deze bot stelt je in staat om contactformulieren en aanmeldingen direct vanuit Telegram te bekijken.\n\n" +
-		"Type /help voor een lijst met beschikbare commando's.", nil
+		"Type /help voor een lijst met beschikbare commando's."
+
+	// Een nieuwe chat heeft zijn ID nodig om in het register te komen
+	chat, err := s.chat(context.Background(), update.Message.Chat.ID)
+	if err != nil {
+		return "", err
+	}
+	if chat == nil {
+		welcome += fmt.Sprintf("\n\nDeze chat staat nog niet in het register. Chat ID: <code>%d</code>", update.Message.Chat.ID)
+	}
+	return welcome, nil
 }
 
 // handleHelpCommand behandelt het /help commando
@@ -313,7 +415,10 @@ func (s *TelegramBotService) handleHelpCommand(update *TelegramUpdate) (string,
 		"<b>/contactnew</b> - Toon nieuwe contactformulieren\n" +
 		"<b>/aanmelding</b> - Toon recente aanmeldingen\n" +
 		"<b>/aanmeldingnew</b> - Toon onverwerkte aanmeldingen\n" +
-		"<b>/status</b> - Toon status van de service", nil
+		"<b>/status</b> - Toon status van de service\n" +
+		"<b>/koppel</b> &lt;code&gt; - Koppel je Telegram account aan je DKL account\n" +
+		"<b>/subscribe</b> &lt;type&gt; - Abonneer deze chat op een notificatie type\n" +
+		"<b>/unsubscribe</b> &lt;type&gt; - Zeg het abonnement van deze chat op een type op", nil
 }
 
 // handleContactCommand behandelt het /contact commando
@@ -554,6 +659,9 @@ func (s *TelegramBotService) GetCommands() []TelegramCommand {
 		{Command: "aanmelding", Description: "Toon recente aanmeldingen"},
 		{Command: "aanmeldingnew", Description: "Toon onverwerkte aanmeldingen"},
 		{Command: "status", Description: "Toon status van de service"},
+		{Command: "koppel", Description: "Koppel je Telegram account aan je DKL account"},
+		{Command: "subscribe", Description: "Abonneer deze chat op een notificatie type"},
+		{Command: "unsubscribe", Description: "Zeg het abonnement van deze chat op een type op"},
 	}
 	return commands
 }
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSignedToken wordt teruggegeven als een ondertekend token niet klopt
+var ErrInvalidSignedToken = errors.New("ongeldig token")
+
+// TokenSigner maakt en controleert HMAC-ondertekende tokens voor publieke links, zoals
+// afmeldlinks in nieuwsbrieven. Het doel wordt mee ondertekend zodat een token voor het
+// ene doel niet voor een ander doel bruikbaar is.
+type TokenSigner struct {
+	secret []byte
+}
+
+// NewTokenSigner maakt een nieuwe TokenSigner met het opgegeven geheim
+func NewTokenSigner(secret string) *TokenSigner {
+	return &TokenSigner{secret: []byte(secret)}
+}
+
+// Sign ondertekent een waarde voor het opgegeven doel
+func (s *TokenSigner) Sign(purpose, value string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return payload + "." + s.signature(purpose, payload)
+}
+
+// Verify controleert een token en geeft de ondertekende waarde terug
+func (s *TokenSigner) Verify(purpose, token string) (string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || payload == "" {
+		return "", ErrInvalidSignedToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(purpose, payload))) {
+		return "", ErrInvalidSignedToken
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+	return string(value), nil
+}
+
+// signature berekent de HMAC-SHA256 over doel en payload
+func (s *TokenSigner) signature(purpose, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
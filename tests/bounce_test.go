@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeEmailSuppressionRepository houdt de suppressielijst in het geheugen bij
+type fakeEmailSuppressionRepository struct {
+	suppressions map[string]*models.EmailSuppression
+}
+
+func newFakeEmailSuppressionRepository() *fakeEmailSuppressionRepository {
+	return &fakeEmailSuppressionRepository{suppressions: make(map[string]*models.EmailSuppression)}
+}
+
+func (r *fakeEmailSuppressionRepository) GetByEmail(ctx context.Context, email string) (*models.EmailSuppression, error) {
+	return r.suppressions[strings.ToLower(email)], nil
+}
+
+func (r *fakeEmailSuppressionRepository) Save(ctx context.Context, suppression *models.EmailSuppression) error {
+	suppression.Email = strings.ToLower(suppression.Email)
+	r.suppressions[suppression.Email] = suppression
+	return nil
+}
+
+func (r *fakeEmailSuppressionRepository) FindSuppressed(ctx context.Context, emails []string) ([]string, error) {
+	var result []string
+	for _, email := range emails {
+		if s, ok := r.suppressions[strings.ToLower(email)]; ok && s.Actief {
+			result = append(result, s.Email)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmailSuppressionRepository) FindUnsubscribed(ctx context.Context, emails []string) ([]string, error) {
+	var result []string
+	for _, email := range emails {
+		if s, ok := r.suppressions[strings.ToLower(email)]; ok && s.NieuwsbriefAfgemeld {
+			result = append(result, s.Email)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmailSuppressionRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.EmailSuppression, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *fakeEmailSuppressionRepository) Delete(ctx context.Context, email string) error {
+	delete(r.suppressions, strings.ToLower(email))
+	return nil
+}
+
+const sampleDSN = "From: Mail Delivery System <MAILER-DAEMON@mail.example.net>\r\n" +
+	"To: info@dekoninklijkeloop.nl\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"dsn\"\r\n" +
+	"\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: text/plain; charset=us-ascii\r\n" +
+	"\r\n" +
+	"Your message could not be delivered.\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mail.example.net\r\n" +
+	"Arrival-Date: Mon, 3 Mar 2025 10:00:00 +0100\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; Onbekend@Example.com\r\n" +
+	"Original-Recipient: rfc822;onbekend@example.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 User unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; vol@example.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 4.2.2 (mailbox full)\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; later@example.com\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"\r\n" +
+	"--dsn\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"From: info@dekoninklijkeloop.nl\r\n" +
+	"Subject: Nieuwsbrief\r\n" +
+	"Message-ID: <verzonden-1@dekoninklijkeloop.nl>\r\n" +
+	"--dsn--\r\n"
+
+func TestBounceProcessing(t *testing.T) {
+	m, err := mail.ReadMessage(strings.NewReader(sampleDSN))
+	assert.NoError(t, err)
+	parsed, err := services.ParseMIMEMessage(m.Header, m.Body)
+	assert.NoError(t, err)
+
+	email := &models.IncomingEmail{ID: "dsn-1"}
+	for _, att := range parsed.Attachments {
+		email.Attachments = append(email.Attachments, &models.IncomingEmailAttachment{ContentType: att.ContentType, Data: att.Data})
+	}
+
+	t.Run("DSN levert alleen definitief mislukte ontvangers op", func(t *testing.T) {
+		statuses := services.DeliveryStatuses(email)
+		if assert.Len(t, statuses, 2) {
+			assert.Equal(t, "onbekend@example.com", statuses[0].Recipient)
+			assert.Equal(t, "5.1.1", statuses[0].Status)
+			assert.Equal(t, models.BounceTypeHard, statuses[0].BounceType())
+			assert.Equal(t, "smtp; 550 5.1.1 User unknown", statuses[0].DiagnosticCode)
+			assert.Equal(t, "vol@example.com", statuses[1].Recipient)
+			assert.Equal(t, models.BounceTypeSoft, statuses[1].BounceType())
+		}
+	})
+
+	sent := &fakeVerzondEmailRepository{emails: []*models.VerzondEmail{{ID: "verzonden-1", Ontvanger: "onbekend@example.com", MessageID: "<verzonden-1@dekoninklijkeloop.nl>"}}}
+
+	t.Run("Message-ID van het teruggestuurde bericht", func(t *testing.T) {
+		assert.Equal(t, []string{"<verzonden-1@dekoninklijkeloop.nl>"}, services.ReturnedMessageIDs(email))
+	})
+
+	t.Run("Harde bounce onderdrukt direct, zachte pas na de limiet", func(t *testing.T) {
+		repo := newFakeEmailSuppressionRepository()
+		metrics := services.NewEmailMetrics(0)
+		processor := services.NewBounceProcessor(repo, sent, metrics, nil, 2)
+
+		count, err := processor.Process(context.Background(), email)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.True(t, repo.suppressions["onbekend@example.com"].Actief)
+		assert.False(t, repo.suppressions["vol@example.com"].Actief)
+
+		_, err = processor.Process(context.Background(), email)
+		assert.NoError(t, err)
+		assert.True(t, repo.suppressions["vol@example.com"].Actief)
+		assert.Equal(t, 2, repo.suppressions["vol@example.com"].BounceCount)
+		assert.Equal(t, map[string]int64{"hard": 2, "soft": 2}, metrics.GetBounceCounts())
+	})
+
+	t.Run("DSN over een bericht dat niet van ons is wordt genegeerd", func(t *testing.T) {
+		repo := newFakeEmailSuppressionRepository()
+		processor := services.NewBounceProcessor(repo, &fakeVerzondEmailRepository{}, nil, nil, 2)
+
+		count, err := processor.Process(context.Background(), email)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+		assert.Empty(t, repo.suppressions)
+
+		// Zonder teruggestuurd bericht valt er niets te controleren
+		processor = services.NewBounceProcessor(repo, sent, nil, nil, 2)
+		withoutOriginal := &models.IncomingEmail{ID: "dsn-2"}
+		for _, att := range email.Attachments {
+			if services.IsDeliveryStatusType(att.ContentType) {
+				withoutOriginal.Attachments = append(withoutOriginal.Attachments, att)
+			}
+		}
+		count, err = processor.Process(context.Background(), withoutOriginal)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+		assert.Empty(t, repo.suppressions)
+	})
+
+	t.Run("Naar een onderdrukt adres wordt niet verzonden", func(t *testing.T) {
+		repo := newFakeEmailSuppressionRepository()
+		assert.NoError(t, repo.Save(context.Background(), &models.EmailSuppression{Email: "onbekend@example.com", Actief: true}))
+		sentRepo := &fakeVerzondEmailRepository{}
+
+		smtp := &mockSMTP{}
+		smtp.On("Send", mock.Anything).Return(nil)
+		emailService, err := services.NewTestEmailService(smtp)
+		assert.NoError(t, err)
+		emailService.SetSentEmailRepository(sentRepo)
+		emailService.SetSuppressionRepository(repo)
+
+		err = emailService.SendEmail("Onbekend@example.com", "Onderwerp", "<p>Hallo</p>")
+		assert.ErrorIs(t, err, services.ErrRecipientSuppressed)
+		smtp.AssertNotCalled(t, "Send", mock.Anything)
+		if assert.Len(t, sentRepo.emails, 1) {
+			assert.Equal(t, models.VerzondEmailStatusMislukt, sentRepo.emails[0].Status)
+		}
+	})
+}
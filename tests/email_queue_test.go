@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeEmailQueueRepository is een in-memory EmailQueueRepository voor tests
+type fakeEmailQueueRepository struct {
+	mu    sync.Mutex
+	items map[string]*models.EmailQueueItem
+	seq   int
+}
+
+func newFakeEmailQueueRepository() *fakeEmailQueueRepository {
+	return &fakeEmailQueueRepository{items: make(map[string]*models.EmailQueueItem)}
+}
+
+func (r *fakeEmailQueueRepository) Enqueue(ctx context.Context, item *models.EmailQueueItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	item.ID = fmt.Sprintf("job-%d", r.seq)
+	r.items[item.ID] = item
+	return nil
+}
+
+func (r *fakeEmailQueueRepository) ClaimBatch(ctx context.Context, limit int) ([]*models.EmailQueueItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []*models.EmailQueueItem
+	for _, item := range r.items {
+		if len(claimed) >= limit {
+			break
+		}
+		if item.Status == models.EmailQueueStatusPending && !item.NextAttemptAt.After(time.Now()) {
+			item.Status = models.EmailQueueStatusProcessing
+			item.Attempts++
+			claimedItem := *item
+			claimed = append(claimed, &claimedItem)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *fakeEmailQueueRepository) MarkSent(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[id].Status = models.EmailQueueStatusSent
+	return nil
+}
+
+func (r *fakeEmailQueueRepository) MarkFailed(ctx context.Context, id string, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item := r.items[id]
+	item.LastError = errMsg
+	item.NextAttemptAt = nextAttemptAt
+	item.Status = models.EmailQueueStatusPending
+	if dead {
+		item.Status = models.EmailQueueStatusDead
+	}
+	return nil
+}
+
+func (r *fakeEmailQueueRepository) ReleaseStale(ctx context.Context, olderThan time.Duration) (int64, []*models.EmailQueueItem, error) {
+	return 0, nil, nil
+}
+
+func (r *fakeEmailQueueRepository) GetByID(ctx context.Context, id string) (*models.EmailQueueItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items[id], nil
+}
+
+func (r *fakeEmailQueueRepository) List(ctx context.Context, status string, limit, offset int) ([]*models.EmailQueueItem, error) {
+	return nil, nil
+}
+
+func (r *fakeEmailQueueRepository) Requeue(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item := r.items[id]
+	if item.Status == models.EmailQueueStatusProcessing || item.Status == models.EmailQueueStatusSent {
+		return nil
+	}
+	item.Status = models.EmailQueueStatusPending
+	item.Attempts = 0
+	item.NextAttemptAt = time.Now()
+	return nil
+}
+
+func (r *fakeEmailQueueRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int64)
+	for _, item := range r.items {
+		counts[item.Status]++
+	}
+	return counts, nil
+}
+
+func (r *fakeEmailQueueRepository) only(t *testing.T) *models.EmailQueueItem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	assert.Len(t, r.items, 1)
+	for _, item := range r.items {
+		return item
+	}
+	return nil
+}
+
+func TestEmailQueueBackoff(t *testing.T) {
+	base := 30 * time.Second
+	max := 10 * time.Minute
+
+	assert.Equal(t, 30*time.Second, services.EmailQueueBackoff(1, base, max))
+	assert.Equal(t, 60*time.Second, services.EmailQueueBackoff(2, base, max))
+	assert.Equal(t, 4*time.Minute, services.EmailQueueBackoff(4, base, max))
+	assert.Equal(t, max, services.EmailQueueBackoff(6, base, max))
+	assert.Equal(t, max, services.EmailQueueBackoff(50, base, max))
+}
+
+func TestEmailQueue_ProcessBatch(t *testing.T) {
+	cfg := services.EmailQueueConfig{MaxAttempts: 2, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	t.Run("Succesvolle verzending via registratie kanaal", func(t *testing.T) {
+		repo := newFakeEmailQueueRepository()
+		smtp := &mockSMTP{}
+		smtp.On("SendRegistration", mock.Anything).Return(nil)
+		queue := services.NewEmailQueue(repo, smtp, nil, cfg)
+
+		err := queue.Enqueue(context.Background(), models.EmailChannelRegistration, "",
+			&services.EmailMessage{To: "deelnemer@example.com", Subject: "Welkom", Body: "<p>Hoi</p>"}, "aanmelding_email", nil, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1, queue.ProcessBatch(context.Background()))
+		assert.True(t, smtp.SendRegCalled)
+		assert.Equal(t, models.EmailQueueStatusSent, repo.only(t).Status)
+	})
+
+	t.Run("Mislukte verzending gaat na max pogingen naar dead-letter", func(t *testing.T) {
+		repo := newFakeEmailQueueRepository()
+		smtp := &mockSMTP{}
+		smtp.On("Send", mock.Anything).Return(errors.New("smtp down"))
+		queue := services.NewEmailQueue(repo, smtp, nil, cfg)
+
+		err := queue.Enqueue(context.Background(), models.EmailChannelDefault, "",
+			&services.EmailMessage{To: "info@example.com", Subject: "Test", Body: "Body"}, "email_generic", nil, nil)
+		assert.NoError(t, err)
+
+		queue.ProcessBatch(context.Background())
+		item := repo.only(t)
+		assert.Equal(t, models.EmailQueueStatusPending, item.Status)
+		assert.Equal(t, "smtp down", item.LastError)
+
+		time.Sleep(5 * time.Millisecond)
+		queue.ProcessBatch(context.Background())
+		assert.Equal(t, models.EmailQueueStatusDead, repo.only(t).Status)
+	})
+
+	t.Run("Ongeldige ontvanger wordt geweigerd", func(t *testing.T) {
+		queue := services.NewEmailQueue(newFakeEmailQueueRepository(), &mockSMTP{}, nil, cfg)
+		err := queue.Enqueue(context.Background(), models.EmailChannelDefault, "", &services.EmailMessage{}, "email_generic", nil, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestEmailBatcherUsesRunningQueue(t *testing.T) {
+	repo := newFakeEmailQueueRepository()
+	smtp := &mockSMTP{}
+	smtp.On("Send", mock.Anything).Return(nil)
+	emailService, err := services.NewTestEmailService(smtp)
+	assert.NoError(t, err)
+
+	queue := services.NewEmailQueue(repo, smtp, nil, services.EmailQueueConfig{PollInterval: time.Hour})
+	emailService.SetQueue(queue)
+	queue.Start()
+	defer queue.Stop()
+
+	batcher := services.NewEmailBatcher(emailService, 50, time.Hour)
+	defer batcher.Shutdown()
+
+	var results []string
+	batcher.SetResultHandler("contact", func(recipient string, queued bool, err error) {
+		assert.NoError(t, err)
+		assert.True(t, queued)
+		results = append(results, recipient)
+	})
+	batcher.AddTrackedToBatch("contact", "lezer@example.com", "Hallo", "contact_email", map[string]interface{}{}, nil, "delivery-1")
+
+	// Het bericht staat meteen in de outbox en wacht niet op het batchvenster in het geheugen
+	item := repo.only(t)
+	assert.Equal(t, "lezer@example.com", item.Recipient)
+	if assert.NotNil(t, item.NewsletterDeliveryID) {
+		assert.Equal(t, "delivery-1", *item.NewsletterDeliveryID)
+	}
+	assert.Equal(t, []string{"lezer@example.com"}, results)
+}
+
+func TestEmailQueueUpdatesNewsletterDelivery(t *testing.T) {
+	ctx := context.Background()
+	deliveries := newFakeNewsletterDeliveryRepository()
+	created, err := deliveries.CreateQueued(ctx, "nl-1", []string{"lezer@example.com", "weg@example.com"})
+	assert.NoError(t, err)
+	tracker := services.NewNewsletterTracker(deliveries, services.NewTokenSigner("test-secret"), "https://api.example.com", false, false, nil)
+	handler := tracker.ResultHandler(map[string]*models.NewsletterDelivery{"lezer@example.com": created[0], "weg@example.com": created[1]})
+
+	repo := newFakeEmailQueueRepository()
+	smtp := &mockSMTP{}
+	smtp.On("Send", mock.MatchedBy(func(msg *services.EmailMessage) bool { return msg.To == "lezer@example.com" })).Return(nil)
+	smtp.On("Send", mock.Anything).Return(errors.New("mailbox bestaat niet"))
+	queue := services.NewEmailQueue(repo, smtp, nil, services.EmailQueueConfig{MaxAttempts: 1})
+	queue.SetNewsletterDeliveryRepository(deliveries)
+
+	for _, delivery := range created {
+		assert.NoError(t, queue.Enqueue(ctx, models.EmailChannelDefault, "",
+			&services.EmailMessage{To: delivery.Email, Subject: "Nieuwsbrief", Body: "<p>Hallo</p>"}, "newsletter", nil, &delivery.ID))
+		// In de outbox geplaatst is nog niet verzonden
+		handler(delivery.Email, true, nil)
+		assert.Equal(t, models.NewsletterDeliveryQueued, deliveries.get(delivery.ID).Status)
+	}
+
+	// Pas het resultaat van de queue worker bepaalt de aflevering
+	assert.Equal(t, 2, queue.ProcessBatch(ctx))
+	sent := deliveries.get(created[0].ID)
+	assert.Equal(t, models.NewsletterDeliverySent, sent.Status)
+	assert.NotNil(t, sent.SentAt)
+	failed := deliveries.get(created[1].ID)
+	assert.Equal(t, models.NewsletterDeliveryFailed, failed.Status)
+	assert.Equal(t, "mailbox bestaat niet", failed.FoutBericht)
+}
+
+func TestEmailBatcherResultHandlerBelongsToBatch(t *testing.T) {
+	sent := make(chan string, 2)
+	smtp := &mockSMTP{}
+	smtp.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent <- args.Get(0).(*services.EmailMessage).To
+	}).Return(nil)
+	emailService, err := services.NewTestEmailService(smtp)
+	assert.NoError(t, err)
+
+	batcher := services.NewEmailBatcher(emailService, 50, time.Hour)
+	defer batcher.Shutdown()
+
+	results := make(chan string, 2)
+	batcher.SetResultHandler("newsletter_manual_1", func(recipient string, queued bool, err error) {
+		results <- recipient
+	})
+	batcher.AddToBatch("newsletter_manual_1", "lezer@example.com", "Hallo", "contact_email", map[string]interface{}{})
+
+	// De handler is na het toevoegen al verwijderd, maar de batch onthoudt hem
+	batcher.SetResultHandler("newsletter_manual_1", nil)
+	batcher.FlushBatch("newsletter_manual_1")
+	select {
+	case recipient := <-results:
+		assert.Equal(t, "lezer@example.com", recipient)
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler van de batch is niet aangeroepen")
+	}
+
+	// Een nieuwe batch met dezelfde key heeft geen handler meer
+	batcher.AddToBatch("newsletter_manual_1", "later@example.com", "Hallo", "contact_email", map[string]interface{}{})
+	batcher.FlushBatch("newsletter_manual_1")
+	assert.Equal(t, "lezer@example.com", <-sent)
+	assert.Equal(t, "later@example.com", <-sent)
+	assert.Never(t, func() bool { return len(results) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+}
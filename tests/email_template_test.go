@@ -0,0 +1,267 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/repository"
+	"dklautomationgo/services"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeEmailTemplateRepository is een in-memory EmailTemplateRepository voor tests
+type fakeEmailTemplateRepository struct {
+	mu        sync.Mutex
+	templates map[string]*models.EmailTemplate
+	versies   []*models.EmailTemplateVersie
+	lookups   int
+}
+
+func newFakeEmailTemplateRepository() *fakeEmailTemplateRepository {
+	return &fakeEmailTemplateRepository{templates: make(map[string]*models.EmailTemplate)}
+}
+
+func (r *fakeEmailTemplateRepository) Create(ctx context.Context, tmpl *models.EmailTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tmpl.ID = "tmpl-" + tmpl.Naam
+	copied := *tmpl
+	r.templates[tmpl.ID] = &copied
+	return nil
+}
+
+func (r *fakeEmailTemplateRepository) GetByID(ctx context.Context, id string) (*models.EmailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tmpl, ok := r.templates[id]; ok {
+		copied := *tmpl
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeEmailTemplateRepository) GetByNaam(ctx context.Context, naam string) (*models.EmailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	for _, tmpl := range r.templates {
+		if tmpl.Naam == naam {
+			copied := *tmpl
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeEmailTemplateRepository) List(ctx context.Context, limit, offset int) ([]*models.EmailTemplate, error) {
+	return nil, nil
+}
+
+func (r *fakeEmailTemplateRepository) Update(ctx context.Context, tmpl *models.EmailTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *tmpl
+	r.templates[tmpl.ID] = &copied
+	return nil
+}
+
+func (r *fakeEmailTemplateRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.templates, id)
+	return nil
+}
+
+func (r *fakeEmailTemplateRepository) FindActive(ctx context.Context) ([]*models.EmailTemplate, error) {
+	return nil, nil
+}
+
+func (r *fakeEmailTemplateRepository) CreateVersie(ctx context.Context, versie *models.EmailTemplateVersie) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versies = append(r.versies, versie)
+	return nil
+}
+
+func (r *fakeEmailTemplateRepository) ListVersies(ctx context.Context, templateID string) ([]*models.EmailTemplateVersie, error) {
+	return r.versies, nil
+}
+
+func (r *fakeEmailTemplateRepository) GetVersie(ctx context.Context, templateID string, versie int) (*models.EmailTemplateVersie, error) {
+	for _, v := range r.versies {
+		if v.TemplateID == templateID && v.Versie == versie {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+func renderTemplate(t *testing.T, emailService *services.EmailService, naam string) string {
+	tmpl := emailService.GetTemplate(naam)
+	if !assert.NotNil(t, tmpl) {
+		return ""
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&buf, services.TemplatePreviewData(naam)))
+	return buf.String()
+}
+
+func TestEmailTemplateService(t *testing.T) {
+	ctx := context.Background()
+
+	emailService, err := services.NewTestEmailService(&mockSMTP{})
+	assert.NoError(t, err)
+	repo := newFakeEmailTemplateRepository()
+	emailService.SetTemplateRepository(repo, time.Hour)
+	templateService := services.NewEmailTemplateService(repo, emailService)
+
+	// Zonder database template wordt het bestand gebruikt
+	assert.Equal(t, "<p>Test template</p>", renderTemplate(t, emailService, "contact_email"))
+
+	t.Run("Actief template met onbekend veld wordt geweigerd", func(t *testing.T) {
+		err := templateService.Create(ctx, &models.EmailTemplate{
+			Naam:      "contact_email",
+			Onderwerp: "Bedankt",
+			Inhoud:    "<p>{{.Contact.Onbekend}}</p>",
+			IsActief:  true,
+		}, "user-1")
+		assert.True(t, errors.Is(err, services.ErrTemplateInvalid))
+	})
+
+	t.Run("Database template heeft voorrang en wordt direct herladen", func(t *testing.T) {
+		tmpl := &models.EmailTemplate{
+			Naam:      "contact_email",
+			Onderwerp: "Bedankt",
+			Inhoud:    "<p>Hoi {{.Contact.Naam}}</p>",
+			IsActief:  true,
+		}
+		assert.NoError(t, templateService.Create(ctx, tmpl, "user-1"))
+		assert.Equal(t, "<p>Hoi Jan de Vries</p>", renderTemplate(t, emailService, "contact_email"))
+
+		inhoud := "<p>Beste {{.Contact.Naam}}</p>"
+		updated, err := templateService.Update(ctx, tmpl.ID, services.EmailTemplateUpdate{Inhoud: &inhoud}, "user-2")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, updated.Versie)
+		assert.Equal(t, "user-2", *updated.UpdatedBy)
+		assert.Equal(t, "<p>Beste Jan de Vries</p>", renderTemplate(t, emailService, "contact_email"))
+
+		if assert.Len(t, repo.versies, 1) {
+			assert.Equal(t, 1, repo.versies[0].Versie)
+			assert.Equal(t, "<p>Hoi {{.Contact.Naam}}</p>", repo.versies[0].Inhoud)
+		}
+
+		_, err = templateService.SetActief(ctx, tmpl.ID, false, "user-2")
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>Test template</p>", renderTemplate(t, emailService, "contact_email"))
+	})
+
+	t.Run("Opzoekingen worden gecached", func(t *testing.T) {
+		emailService.GetTemplate("aanmelding_email")
+		lookups := repo.lookups
+		emailService.GetTemplate("aanmelding_email")
+		assert.Equal(t, lookups, repo.lookups)
+	})
+}
+
+func TestEmailTemplateInactiveStaysInactive(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open("file:email_templates?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	// Dezelfde default als in PostgreSQL: een kolom die niet wordt meegestuurd wordt actief
+	require.NoError(t, db.Exec(`
+		CREATE TABLE IF NOT EXISTS email_templates (
+			id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+			naam TEXT NOT NULL UNIQUE,
+			onderwerp TEXT NOT NULL,
+			inhoud TEXT NOT NULL,
+			beschrijving TEXT,
+			is_actief BOOLEAN NOT NULL DEFAULT TRUE,
+			versie INTEGER NOT NULL DEFAULT 1,
+			created_at DATETIME,
+			updated_at DATETIME,
+			created_by TEXT,
+			updated_by TEXT
+		)
+	`).Error)
+
+	repo := repository.NewPostgresEmailTemplateRepository(repository.NewPostgresRepository(db))
+	emailService, err := services.NewTestEmailService(&mockSMTP{})
+	require.NoError(t, err)
+	emailService.SetTemplateRepository(repo, time.Hour)
+	templateService := services.NewEmailTemplateService(repo, emailService)
+
+	// Een concept met een onbekend veld mag inactief worden opgeslagen
+	tmpl := &models.EmailTemplate{
+		Naam:      "contact_email",
+		Onderwerp: "Bedankt",
+		Inhoud:    "<p>{{.Contact.Onbekend}}</p>",
+		IsActief:  false,
+	}
+	require.NoError(t, templateService.Create(ctx, tmpl, "user-1"))
+
+	stored, err := repo.GetByNaam(ctx, "contact_email")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.False(t, stored.IsActief)
+
+	// Het bestand blijft in gebruik zolang het concept niet is geactiveerd
+	assert.Equal(t, "<p>Test template</p>", renderTemplate(t, emailService, "contact_email"))
+}
+
+func TestEmailTemplateOnderwerp(t *testing.T) {
+	ctx := context.Background()
+
+	smtp := &mockSMTP{}
+	smtp.On("Send", mock.Anything).Return(nil)
+	emailService, err := services.NewTestEmailService(smtp)
+	require.NoError(t, err)
+	repo := newFakeEmailTemplateRepository()
+	emailService.SetTemplateRepository(repo, time.Hour)
+	templateService := services.NewEmailTemplateService(repo, emailService)
+
+	t.Run("Onderwerp met onbekend veld wordt geweigerd", func(t *testing.T) {
+		err := templateService.Create(ctx, &models.EmailTemplate{
+			Naam:      "contact_email",
+			Onderwerp: "Bedankt {{.Contact.Onbekend}}",
+			Inhoud:    "<p>Hoi</p>",
+			IsActief:  true,
+		}, "user-1")
+		assert.True(t, errors.Is(err, services.ErrTemplateInvalid))
+	})
+
+	require.NoError(t, templateService.Create(ctx, &models.EmailTemplate{
+		Naam:      "contact_email",
+		Onderwerp: "Bedankt {{.Contact.Naam}}",
+		Inhoud:    "<p>Hoi {{.Contact.Naam}}</p>",
+		IsActief:  true,
+	}, "user-1"))
+
+	data := map[string]interface{}{"Contact": map[string]interface{}{"Naam": "Piet"}}
+
+	t.Run("Onderwerp van het actieve template wordt verzonden", func(t *testing.T) {
+		require.NoError(t, emailService.SendTemplateEmail("piet@example.com", "Standaard onderwerp", "contact_email", data))
+		assert.Equal(t, "Bedankt Piet", smtp.LastSubject)
+		assert.Equal(t, "<p>Hoi Piet</p>", smtp.LastBody)
+	})
+
+	t.Run("KeepSubject houdt het opgegeven onderwerp", func(t *testing.T) {
+		require.NoError(t, emailService.SendTemplateEmailWithMetadata("piet@example.com", "Eigen onderwerp", "contact_email", data, services.EmailMetadata{KeepSubject: true}))
+		assert.Equal(t, "Eigen onderwerp", smtp.LastSubject)
+	})
+
+	t.Run("Preview rendert het onderwerp", func(t *testing.T) {
+		preview, err := templateService.Preview("contact_email", "Bedankt {{.Contact.Naam}}", "<p>Hoi</p>")
+		require.NoError(t, err)
+		assert.Equal(t, "Bedankt Jan de Vries", preview.Onderwerp)
+	})
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeMailSyncStateRepository houdt de synchronisatiestatus per mailbox in het geheugen bij
+type fakeMailSyncStateRepository struct {
+	states map[string]*models.MailSyncState
+}
+
+func (r *fakeMailSyncStateRepository) Get(ctx context.Context, accountType, mailbox string) (*models.MailSyncState, error) {
+	return r.states[accountType+"/"+mailbox], nil
+}
+
+func (r *fakeMailSyncStateRepository) Save(ctx context.Context, state *models.MailSyncState) error {
+	stored := *state
+	r.states[state.AccountType+"/"+state.Mailbox] = &stored
+	return nil
+}
+
+func (r *fakeMailSyncStateRepository) List(ctx context.Context) ([]*models.MailSyncState, error) {
+	return nil, nil
+}
+
+func TestMailFetcherStoreFetchedEmailsAdvancesCursorAfterStore(t *testing.T) {
+	fetcher := services.NewMailFetcher(services.NewEmailMetrics(time.Hour))
+	fetcher.AddAccount("info@example.com", "geheim", "imap.example.com", 993, "info", "INBOX", "Spam")
+	syncStates := &fakeMailSyncStateRepository{states: make(map[string]*models.MailSyncState)}
+	fetcher.SetSyncStateRepository(syncStates)
+
+	inbox := func(uid string) *models.IncomingEmail {
+		return &models.IncomingEmail{AccountType: "info", Mailbox: "INBOX", UIDValidity: 7, UID: uid}
+	}
+	spam := &models.IncomingEmail{AccountType: "info", Mailbox: "Spam", UIDValidity: 3, UID: "40"}
+
+	repo := new(MockIncomingEmailRepository)
+	repo.On("FindByMailboxUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(email *models.IncomingEmail) bool { return email.UID == "12" })).Return(errors.New("database weg"))
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	// Ongesorteerd aangeleverd; 12 kan niet worden opgeslagen
+	saved := fetcher.StoreFetchedEmails(context.Background(), repo, []*models.IncomingEmail{inbox("13"), inbox("11"), spam, inbox("12")})
+	assert.Equal(t, 2, saved)
+
+	// De INBOX cursor blijft op 11 staan zodat 12 en 13 opnieuw worden opgehaald
+	assert.Equal(t, uint32(11), syncStates.states["info/INBOX"].LastUID)
+	assert.Equal(t, uint32(7), syncStates.states["info/INBOX"].UIDValidity)
+	assert.Equal(t, "info@example.com", syncStates.states["info/INBOX"].Username)
+	assert.Equal(t, uint32(40), syncStates.states["info/Spam"].LastUID)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.MatchedBy(func(email *models.IncomingEmail) bool { return email.UID == "13" }))
+}
+
+func TestMailFetcherDeletesUnlocatedMessageLocally(t *testing.T) {
+	// Er luistert geen mailserver; elke serveractie zou mislukken
+	fetcher := services.NewMailFetcher(services.NewEmailMetrics(time.Hour))
+	fetcher.AddAccount("info@example.com", "geheim", "127.0.0.1", 1, "info")
+	fetcher.ConfigureAccountSync("info", services.MailAccountSyncConfig{WriteBack: true, ProcessedFolder: "Verwerkt"})
+
+	// Een bericht zonder Message-ID kan na het verplaatsen niet worden teruggevonden en heeft geen UID
+	unlocated := &models.IncomingEmail{ID: "mail-1", AccountType: "info", Mailbox: "Verwerkt", UIDValidity: 9, ImapDeletePending: true}
+	assert.NoError(t, fetcher.DeleteOnServer(unlocated))
+	assert.NoError(t, fetcher.MarkProcessedOnServer(unlocated))
+
+	repo := new(MockIncomingEmailRepository)
+	repo.On("FindSyncPending", mock.Anything).Return([]*models.IncomingEmail{unlocated}, nil)
+	repo.On("Delete", mock.Anything, "mail-1").Return(nil)
+
+	result, err := fetcher.Reconcile(context.Background(), repo)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Retried)
+	repo.AssertCalled(t, "Delete", mock.Anything, "mail-1")
+}
@@ -95,6 +95,14 @@ func (m *MockIncomingEmailRepository) FindByUID(ctx context.Context, uid string)
 	return args.Get(0).(*models.IncomingEmail), args.Error(1)
 }
 
+func (m *MockIncomingEmailRepository) FindByMailboxUID(ctx context.Context, accountType, mailbox string, uidValidity uint32, uid string) (*models.IncomingEmail, error) {
+	args := m.Called(ctx, accountType, mailbox, uidValidity, uid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IncomingEmail), args.Error(1)
+}
+
 func (m *MockIncomingEmailRepository) FindUnprocessed(ctx context.Context) ([]*models.IncomingEmail, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
@@ -105,6 +113,31 @@ func (m *MockIncomingEmailRepository) FindByAccountType(ctx context.Context, acc
 	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
 }
 
+func (m *MockIncomingEmailRepository) ListByMailbox(ctx context.Context, accountType, mailbox string, uidValidity uint32, since time.Time) ([]*models.IncomingEmail, error) {
+	args := m.Called(ctx, accountType, mailbox, uidValidity, since)
+	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
+}
+
+func (m *MockIncomingEmailRepository) FindSyncPending(ctx context.Context) ([]*models.IncomingEmail, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
+}
+
+func (m *MockIncomingEmailRepository) FindByThreadIDs(ctx context.Context, threadIDs []string) ([]*models.IncomingEmail, error) {
+	args := m.Called(ctx, threadIDs)
+	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
+}
+
+func (m *MockIncomingEmailRepository) FindByAanmeldingID(ctx context.Context, aanmeldingID string) ([]*models.IncomingEmail, error) {
+	args := m.Called(ctx, aanmeldingID)
+	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
+}
+
+func (m *MockIncomingEmailRepository) FindByContactID(ctx context.Context, contactID string) ([]*models.IncomingEmail, error) {
+	args := m.Called(ctx, contactID)
+	return args.Get(0).([]*models.IncomingEmail), args.Error(1)
+}
+
 // Implementeer ListByAccountTypePaginated voor de mock
 func (m *MockIncomingEmailRepository) ListByAccountTypePaginated(ctx context.Context, accountType string, limit, offset int) ([]*models.IncomingEmail, int64, error) {
 	if m.ListByAccountTypePaginatedFunc != nil {
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMailLinker(t *testing.T) {
+	newLinker := func(sent *fakeVerzondEmailRepository) (*services.MailLinker, *mocks.MockAanmeldingRepository, *mocks.MockContactRepository) {
+		db := mocks.NewMockDB()
+		aanmeldingRepo := mocks.NewMockAanmeldingRepository(db)
+		contactRepo := mocks.NewMockContactRepository(db)
+
+		incomingRepo := new(MockIncomingEmailRepository)
+		incomingRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+		return services.NewMailLinker(incomingRepo, sent, aanmeldingRepo, contactRepo, true), aanmeldingRepo, contactRepo
+	}
+
+	t.Run("Antwoord op bevestigingsmail wordt via References gekoppeld en heropend", func(t *testing.T) {
+		aanmeldingID := "aanmelding-1"
+		sent := &fakeVerzondEmailRepository{emails: []*models.VerzondEmail{
+			{ID: "sent-1", MessageID: "<bevestiging@dekoninklijkeloop.nl>", AanmeldingID: &aanmeldingID},
+		}}
+		linker, aanmeldingRepo, _ := newLinker(sent)
+		assert.NoError(t, aanmeldingRepo.Create(context.Background(), &models.Aanmelding{ID: aanmeldingID, Email: "ander@example.com", Status: "beantwoord"}))
+
+		email := &models.IncomingEmail{
+			ID:         "email-1",
+			From:       "Deelnemer <deelnemer@example.com>",
+			InReplyTo:  "<bevestiging@dekoninklijkeloop.nl>",
+			References: "<bevestiging@dekoninklijkeloop.nl>",
+		}
+		linked, err := linker.Link(context.Background(), email)
+		assert.NoError(t, err)
+		assert.True(t, linked)
+		if assert.NotNil(t, email.AanmeldingID) {
+			assert.Equal(t, aanmeldingID, *email.AanmeldingID)
+		}
+
+		aanmelding, _ := aanmeldingRepo.GetByID(context.Background(), aanmeldingID)
+		assert.Equal(t, services.MailLinkStatusInBehandeling, aanmelding.Status)
+	})
+
+	t.Run("Zonder threading wordt op afzender gekoppeld", func(t *testing.T) {
+		linker, _, contactRepo := newLinker(&fakeVerzondEmailRepository{})
+		assert.NoError(t, contactRepo.Create(context.Background(), &models.ContactFormulier{ID: "contact-1", Email: "vraag@example.com", Status: "nieuw"}))
+
+		email := &models.IncomingEmail{ID: "email-2", From: "Vrager <vraag@example.com>"}
+		linked, err := linker.Link(context.Background(), email)
+		assert.NoError(t, err)
+		assert.True(t, linked)
+		assert.Nil(t, email.AanmeldingID)
+		if assert.NotNil(t, email.ContactID) {
+			assert.Equal(t, "contact-1", *email.ContactID)
+		}
+
+		// Een nieuw contactformulier blijft op "nieuw" staan
+		contact, _ := contactRepo.GetByID(context.Background(), "contact-1")
+		assert.Equal(t, "nieuw", contact.Status)
+	})
+
+	t.Run("Onbekende afzender blijft ongekoppeld", func(t *testing.T) {
+		linker, _, _ := newLinker(&fakeVerzondEmailRepository{})
+
+		linked, err := linker.Link(context.Background(), &models.IncomingEmail{ID: "email-3", From: "onbekend@example.com"})
+		assert.NoError(t, err)
+		assert.False(t, linked)
+	})
+}
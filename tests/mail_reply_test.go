@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMailThreadID(t *testing.T) {
+	assert.Equal(t, "<root@example.com>", services.MailThreadID("<c@example.com>", "<b@example.com>", "<root@example.com>\r\n <b@example.com>"))
+	assert.Equal(t, "<b@example.com>", services.MailThreadID("<c@example.com>", "<b@example.com>", ""))
+	assert.Equal(t, "<c@example.com>", services.MailThreadID("<c@example.com>", "", ""))
+}
+
+func TestMailReplyService(t *testing.T) {
+	original := &models.IncomingEmail{
+		ID:          "email-1",
+		MessageID:   "<vraag@example.com>",
+		References:  "<eerder@example.com>",
+		ThreadID:    "<eerder@example.com>",
+		From:        "Jan Jansen <jan@example.com>",
+		To:          "inschrijving@dekoninklijkeloop.nl",
+		Subject:     "Vraag over de route",
+		Body:        "Hoe lang is de route?",
+		ContentType: "text/plain; charset=utf-8",
+		AccountType: "inschrijving",
+		ReceivedAt:  time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("Antwoord via registratie account met threading headers", func(t *testing.T) {
+		repo := new(MockIncomingEmailRepository)
+		repo.On("GetByID", mock.Anything, "email-1").Return(original, nil)
+		sentRepo := &fakeVerzondEmailRepository{}
+
+		var sent *services.EmailMessage
+		smtp := &mockSMTP{}
+		smtp.On("SendRegistration", mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(0).(*services.EmailMessage)
+		}).Return(nil)
+
+		emailService, err := services.NewTestEmailService(smtp)
+		assert.NoError(t, err)
+		emailService.SetSentEmailRepository(sentRepo)
+
+		service := services.NewMailReplyService(emailService, repo, sentRepo)
+		record, err := service.Reply(context.Background(), "email-1", "De route is 15 km.\nGroet")
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, sent) {
+			assert.Equal(t, "jan@example.com", sent.To)
+			assert.Equal(t, "Re: Vraag over de route", sent.Subject)
+			assert.Equal(t, "<vraag@example.com>", sent.Headers["In-Reply-To"])
+			assert.Equal(t, "<eerder@example.com> <vraag@example.com>", sent.Headers["References"])
+			assert.Contains(t, sent.Headers["Message-ID"], "@dekoninklijkeloop.nl>")
+			assert.Contains(t, sent.Body, "De route is 15 km.<br>Groet")
+			assert.Contains(t, sent.Body, "<blockquote")
+			assert.Contains(t, sent.Body, "Hoe lang is de route?")
+		}
+
+		if assert.Len(t, sentRepo.emails, 1) {
+			assert.Equal(t, record, sentRepo.emails[0])
+			assert.Equal(t, services.MailReplyEmailType, record.EmailType)
+			assert.Equal(t, "<eerder@example.com>", record.ThreadID)
+			assert.Equal(t, models.VerzondEmailStatusVerzonden, record.Status)
+		}
+	})
+
+	t.Run("Doorsturen naar een onderdrukt adres gaat niet uit", func(t *testing.T) {
+		repo := new(MockIncomingEmailRepository)
+		repo.On("GetByID", mock.Anything, "email-1").Return(original, nil)
+		sentRepo := &fakeVerzondEmailRepository{}
+		suppressions := newFakeEmailSuppressionRepository()
+		assert.NoError(t, suppressions.Save(context.Background(), &models.EmailSuppression{Email: "bounce@example.com", Actief: true}))
+
+		smtp := &mockSMTP{}
+		emailService, err := services.NewTestEmailService(smtp)
+		assert.NoError(t, err)
+		emailService.SetSentEmailRepository(sentRepo)
+		emailService.SetSuppressionRepository(suppressions)
+
+		service := services.NewMailReplyService(emailService, repo, sentRepo)
+		record, err := service.Forward(context.Background(), "email-1", "bounce@example.com", "Zie hieronder")
+		assert.ErrorIs(t, err, services.ErrRecipientSuppressed)
+		assert.False(t, smtp.SendCalled)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, models.VerzondEmailStatusMislukt, record.Status)
+			assert.Equal(t, services.MailForwardEmailType, record.EmailType)
+			assert.Equal(t, "email-1", *record.IncomingEmailID)
+		}
+	})
+
+	t.Run("Doorsturen vereist een geldig adres", func(t *testing.T) {
+		repo := new(MockIncomingEmailRepository)
+		repo.On("GetByID", mock.Anything, "email-1").Return(original, nil)
+
+		service := services.NewMailReplyService(nil, repo, &fakeVerzondEmailRepository{})
+		_, err := service.Forward(context.Background(), "email-1", "geen-adres", "")
+		assert.ErrorIs(t, err, services.ErrInvalidRecipient)
+	})
+
+	t.Run("Onbekende email", func(t *testing.T) {
+		repo := new(MockIncomingEmailRepository)
+		repo.On("GetByID", mock.Anything, "onbekend").Return(nil, nil)
+
+		service := services.NewMailReplyService(nil, repo, &fakeVerzondEmailRepository{})
+		_, err := service.Reply(context.Background(), "onbekend", "Hallo")
+		assert.ErrorIs(t, err, services.ErrMailNotFound)
+	})
+}
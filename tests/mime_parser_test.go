@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"dklautomationgo/services"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multipartTestMail = "From: =?ISO-8859-1?Q?Andr=E9_Janssen?= <andre@example.com>\r\n" +
+	"Subject: =?UTF-8?B?Q2Fmw6kgbmEgZGUgbG9vcA==?=\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=ISO-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Tot zaterdag in het caf=E9!\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"\r\n" +
+	"<p>Tot zaterdag in het café!</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"route.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"route.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+func TestParseMIMEMessage(t *testing.T) {
+	msg, err := mail.ReadMessage(strings.NewReader(multipartTestMail))
+	assert.NoError(t, err)
+
+	parsed, err := services.ParseMIMEMessage(msg.Header, msg.Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Tot zaterdag in het café!", strings.TrimSpace(parsed.Text))
+	assert.Equal(t, "<p>Tot zaterdag in het café!</p>", strings.TrimSpace(parsed.HTML))
+
+	if assert.Len(t, parsed.Attachments, 1) {
+		att := parsed.Attachments[0]
+		assert.Equal(t, "route.pdf", att.Filename)
+		assert.Equal(t, "application/pdf", att.ContentType)
+		assert.False(t, att.Inline)
+		assert.Equal(t, "%PDF-1.4\n", string(att.Data))
+	}
+
+	assert.Equal(t, "Café na de loop", services.DecodeMIMEHeader(msg.Header.Get("Subject")))
+	assert.Equal(t, "André Janssen <andre@example.com>", services.DecodeAddressHeader(msg.Header.Get("From")))
+}
+
+func TestParseMIMEMessage_SinglePart(t *testing.T) {
+	raw := "Subject: Test\r\nContent-Type: text/plain; charset=windows-1252\r\nContent-Transfer-Encoding: 8bit\r\n\r\nPrijs: \x80 10\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	assert.NoError(t, err)
+
+	parsed, err := services.ParseMIMEMessage(msg.Header, msg.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "Prijs: € 10", strings.TrimSpace(parsed.Text))
+	assert.Empty(t, parsed.HTML)
+	assert.Empty(t, parsed.Attachments)
+}
@@ -445,6 +445,20 @@ func (m *MockNotificationService) CreateNotification(
 	return args.Get(0).(*models.Notification), args.Error(1)
 }
 
+// CreateReferencedNotification mocks creating a notification about a contact form or registration
+func (m *MockNotificationService) CreateReferencedNotification(
+	ctx context.Context,
+	notificationType models.NotificationType,
+	priority models.NotificationPriority,
+	title, message, referenceID string,
+) (*models.Notification, error) {
+	args := m.Called(ctx, notificationType, priority, title, message, referenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Notification), args.Error(1)
+}
+
 // GetNotification mocks retrieving a notification by ID
 func (m *MockNotificationService) GetNotification(ctx context.Context, id string) (*models.Notification, error) {
 	args := m.Called(ctx, id)
@@ -494,6 +508,11 @@ func (m *MockEmailSender) SendTemplateEmail(recipient, subject, templateName str
 	return args.Error(0)
 }
 
+func (m *MockEmailSender) SendTemplateEmailWithMetadata(recipient, subject, templateName string, templateData map[string]interface{}, meta services.EmailMetadata, fromAddress ...string) error {
+	args := m.Called(recipient, subject, templateName, templateData, meta, fromAddress)
+	return args.Error(0)
+}
+
 func (m *MockEmailSender) SendContactEmail(data *models.ContactEmailData) error {
 	args := m.Called(data)
 	return args.Error(0)
@@ -567,6 +567,20 @@ func (r *MockGebruikerRepository) GetByEmail(ctx context.Context, email string)
 	return nil, nil
 }
 
+// GetByTelegramUserID haalt de gebruiker op die aan een Telegram account is gekoppeld
+func (r *MockGebruikerRepository) GetByTelegramUserID(ctx context.Context, telegramUserID int64) (*models.Gebruiker, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, gebruiker := range r.db.gebruikers {
+		if gebruiker.TelegramUserID != nil && *gebruiker.TelegramUserID == telegramUserID {
+			return gebruiker, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // List haalt een lijst van gebruikers op
 func (r *MockGebruikerRepository) List(ctx context.Context, limit, offset int) ([]*models.Gebruiker, error) {
 	r.db.mu.RLock()
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNewsletterSentItemRepository houdt verzonden nieuwsitems in het geheugen bij
+type fakeNewsletterSentItemRepository struct {
+	sent map[string]bool
+}
+
+func (r *fakeNewsletterSentItemRepository) FilterSent(ctx context.Context, keys []string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	for _, key := range keys {
+		if r.sent[key] {
+			found[key] = true
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeNewsletterSentItemRepository) MarkSent(ctx context.Context, items []*models.NewsletterSentItem) error {
+	for _, item := range items {
+		r.sent[item.ItemKey] = true
+	}
+	return nil
+}
+
+func TestParseFeed(t *testing.T) {
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel>
+		<item><guid>rss-1</guid><title>Route bekend</title><link>https://example.com/route</link>
+		<description>&lt;p&gt;De &lt;b&gt;route&lt;/b&gt; is bekend&lt;/p&gt;</description>
+		<pubDate>Mon, 02 Jun 2025 10:00:00 +0200</pubDate><category>Nieuws</category></item></channel></rss>`
+	items, err := services.ParseFeed("", []byte(rss))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "rss-1", items[0].ID)
+		assert.Equal(t, "De route is bekend", items[0].Description)
+		assert.Equal(t, "Nieuws", items[0].Category)
+		assert.Equal(t, 2025, items[0].PubDate.Year())
+	}
+
+	atom := `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom">
+		<entry><id>urn:atom-1</id><title>Vrijwilligers gezocht</title>
+		<link rel="alternate" href="https://example.com/vrijwilligers"/><updated>2025-06-01T12:00:00Z</updated>
+		<summary>Help mee</summary><category term="oproep" label="Oproep"/></entry></feed>`
+	items, err = services.ParseFeed("", []byte(atom))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "urn:atom-1", items[0].ID)
+		assert.Equal(t, "https://example.com/vrijwilligers", items[0].Link)
+		assert.Equal(t, "Oproep", items[0].Category)
+		assert.False(t, items[0].PubDate.IsZero())
+	}
+
+	jsonFeed := `{"version":"https://jsonfeed.org/version/1.1","items":[
+		{"id":42,"url":"https://example.com/uitslag","title":"Uitslag","content_text":"Alle tijden","date_published":"2025-06-03T09:00:00Z","tags":["Uitslagen"]}]}`
+	items, err = services.ParseFeed(models.NewsletterSourceJSON, []byte(jsonFeed))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "42", items[0].ID)
+		assert.Equal(t, "Alle tijden", items[0].Description)
+		assert.Equal(t, "Uitslagen", items[0].Category)
+	}
+
+	_, err = services.ParseFeed(models.NewsletterSourceAtom, []byte("geen feed"))
+	assert.Error(t, err)
+}
+
+func TestNewsletterFetcherAppliesSourceConfig(t *testing.T) {
+	now := time.Now().UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		w.Write([]byte(`{"items":[
+			{"id":"1","url":"https://example.com/1","title":"Nieuw","date_published":"` + now.Format(time.RFC3339) + `"},
+			{"id":"2","url":"https://example.com/2","title":"Ook nieuw","date_published":"` + now.Add(-time.Hour).Format(time.RFC3339) + `"},
+			{"id":"3","url":"https://example.com/3","title":"Oud","date_published":"` + now.AddDate(0, 0, -30).Format(time.RFC3339) + `"}]}`))
+	}))
+	defer server.Close()
+
+	fetcher := services.NewNewsletterFetcher()
+	items, err := fetcher.FetchSource(context.Background(), &models.NewsletterSource{
+		Naam:       "Website",
+		Type:       models.NewsletterSourceJSON,
+		URL:        server.URL,
+		Categorie:  "Website",
+		MaxItems:   5,
+		MaxAgeDays: 7,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "1", items[0].ID)
+		assert.Equal(t, "Website", items[0].Source)
+		assert.Equal(t, "Website", items[0].Category)
+	}
+}
+
+func TestNewsletterProcessorSkipsSentItems(t *testing.T) {
+	ctx := context.Background()
+	sentItems := &fakeNewsletterSentItemRepository{sent: map[string]bool{"oud": true}}
+	processor := services.NewNewsletterProcessor()
+	processor.SetSentItemRepository(sentItems)
+
+	items := []models.NewsItem{
+		{ID: "oud", Title: "Al verzonden", Link: "https://example.com/oud"},
+		{ID: "nieuw", Title: "Nieuw", Link: "https://example.com/nieuw", Category: "Nieuws"},
+		{ID: "nieuw", Title: "Nieuw (dubbel)", Link: "https://example.com/nieuw", Category: "Nieuws"},
+		{Title: "Foto's", Link: "https://example.com/fotos", Category: "Media"},
+		{Title: "Zonder categorie", Link: "https://example.com/overig"},
+		{Title: "Zonder link"},
+	}
+
+	processed, err := processor.Process(ctx, items)
+	assert.NoError(t, err)
+	assert.Len(t, processed.Items, 3)
+
+	groups := services.GroupNewsByCategory(processed.Items)
+	if assert.Len(t, groups, 3) {
+		assert.Equal(t, "Nieuws", groups[0].Category)
+		assert.Equal(t, "Media", groups[1].Category)
+		assert.Equal(t, "Overig", groups[2].Category)
+	}
+
+	// Na verzenden komen dezelfde items niet nog een keer in de nieuwsbrief
+	assert.NoError(t, processor.MarkSent(ctx, processed.Items))
+	processed, err = processor.Process(ctx, items)
+	assert.NoError(t, err)
+	assert.Empty(t, processed.Items)
+}
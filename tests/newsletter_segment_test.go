@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNewsletterSegmentRepository geeft voor elk segment een vaste lijst met adressen terug
+type fakeNewsletterSegmentRepository struct {
+	segments map[string]*models.NewsletterSegment
+	emails   []string
+}
+
+func (r *fakeNewsletterSegmentRepository) Create(ctx context.Context, segment *models.NewsletterSegment) error {
+	r.segments[segment.ID] = segment
+	return nil
+}
+
+func (r *fakeNewsletterSegmentRepository) GetByID(ctx context.Context, id string) (*models.NewsletterSegment, error) {
+	return r.segments[id], nil
+}
+
+func (r *fakeNewsletterSegmentRepository) List(ctx context.Context) ([]*models.NewsletterSegment, error) {
+	return nil, nil
+}
+
+func (r *fakeNewsletterSegmentRepository) Update(ctx context.Context, segment *models.NewsletterSegment) error {
+	r.segments[segment.ID] = segment
+	return nil
+}
+
+func (r *fakeNewsletterSegmentRepository) Delete(ctx context.Context, id string) error {
+	delete(r.segments, id)
+	return nil
+}
+
+func (r *fakeNewsletterSegmentRepository) ResolveEmails(ctx context.Context, filter models.SegmentFilter) ([]string, error) {
+	return append(r.emails, filter.Emails...), nil
+}
+
+func TestNewsletterSegments(t *testing.T) {
+	ctx := context.Background()
+	segments := &fakeNewsletterSegmentRepository{
+		segments: map[string]*models.NewsletterSegment{
+			"segment-15km": {ID: "segment-15km", Naam: "15 KM lopers 2025", Filter: models.SegmentFilter{
+				Aanmeldingen: &models.AanmeldingSegmentFilter{Afstanden: []string{"15 KM"}, Jaar: 2025},
+			}},
+		},
+		// Dezelfde loper met een aanmelding en een account, in verschillende schrijfwijzen
+		emails: []string{"Loper@Example.com", "loper@example.com ", "gebounced@example.com", "tweede@example.com", "Afgemeld@Example.com"},
+	}
+	suppressions := newFakeEmailSuppressionRepository()
+	assert.NoError(t, suppressions.Save(ctx, &models.EmailSuppression{Email: "gebounced@example.com", Actief: true}))
+	assert.NoError(t, suppressions.Save(ctx, &models.EmailSuppression{Email: "afgemeld@example.com", NieuwsbriefAfgemeld: true}))
+
+	segment15km, onbekend := "segment-15km", "bestaat-niet"
+	repo := newFakeNewsletterRepository(
+		&models.Newsletter{ID: "nl-segment", Subject: "Voor de 15 KM", Status: models.NewsletterStatusApproved, SegmentID: &segment15km},
+		&models.Newsletter{ID: "nl-onbekend", Subject: "Onbekend segment", Status: models.NewsletterStatusApproved, SegmentID: &onbekend},
+		&models.Newsletter{ID: "nl-alle", Subject: "Voor iedereen", Status: models.NewsletterStatusApproved},
+	)
+	sender := newTestNewsletterSender(t, repo)
+	sender.SetSegmentRepository(segments)
+	sender.SetSuppressionRepository(suppressions)
+	deliveries := newFakeNewsletterDeliveryRepository()
+	sender.SetTracker(services.NewNewsletterTracker(deliveries, services.NewTokenSigner("test-secret"), "https://api.example.com", false, false, nil))
+
+	t.Run("Dry-run telt unieke adressen en slaat onderdrukte en afgemelde adressen over", func(t *testing.T) {
+		preview, err := sender.PreviewSegment(ctx, models.SegmentFilter{Emails: []string{"TWEEDE@example.com", "sponsor@example.com", "afgemeld@example.com"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, preview.Total)
+		assert.Equal(t, 1, preview.Suppressed)
+		assert.Equal(t, 1, preview.Unsubscribed)
+		assert.Equal(t, 3, preview.Recipients)
+		assert.ElementsMatch(t, []string{"loper@example.com", "tweede@example.com", "sponsor@example.com"}, preview.Sample)
+	})
+
+	t.Run("Verzenden aan een segment", func(t *testing.T) {
+		assert.NoError(t, sender.SendManual(ctx, "nl-segment", "segment-15km"))
+
+		nl, _ := repo.GetByID(ctx, "nl-segment")
+		assert.Equal(t, models.NewsletterStatusSent, nl.Status)
+
+		// Een segment op aanmeldingen slaat afgemelde en gebouncede adressen over
+		var recipients []string
+		deliveries.mu.Lock()
+		for _, delivery := range deliveries.deliveries {
+			recipients = append(recipients, delivery.Email)
+		}
+		deliveries.mu.Unlock()
+		assert.ElementsMatch(t, []string{"loper@example.com", "tweede@example.com"}, recipients)
+	})
+
+	t.Run("Onbekend segment", func(t *testing.T) {
+		assert.ErrorIs(t, sender.SendManual(ctx, "nl-onbekend", ""), services.ErrNewsletterSegmentNotFound)
+	})
+
+	t.Run("Ander segment dan goedgekeurd wordt geweigerd", func(t *testing.T) {
+		assert.ErrorIs(t, sender.SendManual(ctx, "nl-alle", "segment-15km"), services.ErrNewsletterSegmentMismatch)
+
+		nl, _ := repo.GetByID(ctx, "nl-alle")
+		assert.Equal(t, models.NewsletterStatusApproved, nl.Status)
+	})
+}
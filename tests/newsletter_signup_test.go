@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeNewsletterSubscriberRepository houdt abonnees zonder account in het geheugen bij
+type fakeNewsletterSubscriberRepository struct {
+	subscribers map[string]*models.NewsletterSubscriber
+}
+
+func newFakeNewsletterSubscriberRepository() *fakeNewsletterSubscriberRepository {
+	return &fakeNewsletterSubscriberRepository{subscribers: make(map[string]*models.NewsletterSubscriber)}
+}
+
+func (r *fakeNewsletterSubscriberRepository) GetByEmail(ctx context.Context, email string) (*models.NewsletterSubscriber, error) {
+	subscriber, ok := r.subscribers[email]
+	if !ok {
+		return nil, nil
+	}
+	copied := *subscriber
+	return &copied, nil
+}
+
+func (r *fakeNewsletterSubscriberRepository) Save(ctx context.Context, subscriber *models.NewsletterSubscriber) error {
+	copied := *subscriber
+	r.subscribers[subscriber.Email] = &copied
+	return nil
+}
+
+func (r *fakeNewsletterSubscriberRepository) ListConfirmedEmails(ctx context.Context) ([]string, error) {
+	var emails []string
+	for email, subscriber := range r.subscribers {
+		if subscriber.Status == models.NewsletterSubscriberConfirmed {
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+func (r *fakeNewsletterSubscriberRepository) List(ctx context.Context, status string, limit, offset int) ([]*models.NewsletterSubscriber, int64, error) {
+	var subscribers []*models.NewsletterSubscriber
+	for _, subscriber := range r.subscribers {
+		if status == "" || subscriber.Status == status {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	return subscribers, int64(len(subscribers)), nil
+}
+
+func TestNewsletterSignup(t *testing.T) {
+	ctx := context.Background()
+	gebruikerRepo := mocks.NewMockGebruikerRepository(mocks.NewMockDB())
+	events := &fakeNewsletterEventRepository{}
+	subscribers := newFakeNewsletterSubscriberRepository()
+
+	var confirmURL string
+	emailSender := new(MockEmailSender)
+	emailSender.On("SendTemplateEmail", "nieuw@example.com", mock.Anything, "newsletter_confirm", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			confirmURL = args.Get(3).(map[string]interface{})["ConfirmURL"].(string)
+		}).
+		Return(nil).Once()
+
+	svc := services.NewNewsletterSubscriptionService(services.NewTokenSigner("geheim"), "https://api.example.com", gebruikerRepo, events)
+	svc.EnableSignup(subscribers, emailSender)
+
+	// Zonder toestemming of met een ongeldig adres wordt niemand aangemeld
+	assert.ErrorIs(t, svc.Subscribe(ctx, "nieuw@example.com", "Nieuw", false, "10.0.0.1"), services.ErrNewsletterConsentRequired)
+	assert.ErrorIs(t, svc.Subscribe(ctx, "geen-adres", "", true, "10.0.0.1"), services.ErrNewsletterInvalidEmail)
+	assert.Empty(t, subscribers.subscribers)
+
+	// Aanmelden legt de toestemming vast en stuurt een bevestigingsmail
+	assert.NoError(t, svc.Subscribe(ctx, " Nieuw@Example.com ", "Nieuw", true, "10.0.0.1"))
+	subscriber := subscribers.subscribers["nieuw@example.com"]
+	if assert.NotNil(t, subscriber) {
+		assert.Equal(t, models.NewsletterSubscriberPending, subscriber.Status)
+		assert.Equal(t, "10.0.0.1", subscriber.ConsentIP)
+		assert.WithinDuration(t, time.Now(), subscriber.ConsentAt, time.Minute)
+	}
+
+	// Direct nogmaals aanmelden stuurt geen tweede bevestigingsmail
+	assert.NoError(t, svc.Subscribe(ctx, "nieuw@example.com", "Nieuw", true, "10.0.0.1"))
+	emailSender.AssertNumberOfCalls(t, "SendTemplateEmail", 1)
+
+	// Een onbevestigde abonnee ontvangt nog geen nieuwsbrief
+	confirmed, _ := subscribers.ListConfirmedEmails(ctx)
+	assert.Empty(t, confirmed)
+
+	link, err := url.Parse(confirmURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/newsletter/confirm", link.Path)
+
+	_, err = svc.Confirm(ctx, svc.UnsubscribeToken("nieuw@example.com"), "10.0.0.2")
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	email, err := svc.Confirm(ctx, link.Query().Get("token"), "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "nieuw@example.com", email)
+	subscriber = subscribers.subscribers["nieuw@example.com"]
+	assert.Equal(t, models.NewsletterSubscriberConfirmed, subscriber.Status)
+	assert.Equal(t, "10.0.0.2", subscriber.ConfirmedIP)
+	if assert.Len(t, events.events, 1) {
+		assert.Equal(t, models.NewsletterActieAangemeld, events.events[0].Actie)
+		assert.Equal(t, models.NewsletterBronWebsite, events.events[0].Bron)
+	}
+
+	// Een verlopen bevestigingslink wordt geweigerd
+	_, err = svc.Confirm(ctx, svc.ConfirmToken("nieuw@example.com", time.Now().Add(-8*24*time.Hour)), "")
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	// De afmeldlink uit de nieuwsbrief werkt ook voor abonnees zonder account
+	_, err = svc.Unsubscribe(ctx, svc.UnsubscribeToken("nieuw@example.com"), models.NewsletterBronLink, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewsletterSubscriberUnsubscribed, subscribers.subscribers["nieuw@example.com"].Status)
+	assert.Len(t, events.events, 2)
+}
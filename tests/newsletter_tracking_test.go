@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNewsletterDeliveryRepository houdt afleveringen in het geheugen bij
+type fakeNewsletterDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries map[string]*models.NewsletterDelivery
+	createErr  error
+}
+
+func newFakeNewsletterDeliveryRepository() *fakeNewsletterDeliveryRepository {
+	return &fakeNewsletterDeliveryRepository{deliveries: make(map[string]*models.NewsletterDelivery)}
+}
+
+func (r *fakeNewsletterDeliveryRepository) CreateQueued(ctx context.Context, newsletterID string, emails []string) ([]*models.NewsletterDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.createErr != nil {
+		return nil, r.createErr
+	}
+	var result []*models.NewsletterDelivery
+	for _, email := range emails {
+		delivery := &models.NewsletterDelivery{
+			ID:           fmt.Sprintf("delivery-%d", len(r.deliveries)+1),
+			NewsletterID: newsletterID,
+			Email:        email,
+			Status:       models.NewsletterDeliveryQueued,
+		}
+		r.deliveries[delivery.ID] = delivery
+		result = append(result, delivery)
+	}
+	return result, nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) update(id string, fn func(d *models.NewsletterDelivery)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if ok {
+		fn(d)
+	}
+	return ok
+}
+
+func (r *fakeNewsletterDeliveryRepository) get(id string) models.NewsletterDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.deliveries[id]
+}
+
+func (r *fakeNewsletterDeliveryRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	r.update(id, func(d *models.NewsletterDelivery) { d.Status = models.NewsletterDeliverySent; d.SentAt = &sentAt })
+	return nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) MarkFailed(ctx context.Context, id, foutBericht string) error {
+	r.update(id, func(d *models.NewsletterDelivery) {
+		d.Status = models.NewsletterDeliveryFailed
+		d.FoutBericht = foutBericht
+	})
+	return nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) MarkBounced(ctx context.Context, email string, bouncedAt time.Time) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) RecordOpen(ctx context.Context, id string, openedAt time.Time) (bool, error) {
+	return r.update(id, func(d *models.NewsletterDelivery) { d.OpenCount++ }), nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) RecordClick(ctx context.Context, id string, clickedAt time.Time) (bool, error) {
+	return r.update(id, func(d *models.NewsletterDelivery) { d.ClickCount++ }), nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) GetStats(ctx context.Context, newsletterID string) (*models.NewsletterStats, error) {
+	return &models.NewsletterStats{NewsletterID: newsletterID}, nil
+}
+
+func (r *fakeNewsletterDeliveryRepository) ListByNewsletter(ctx context.Context, newsletterID, status string, limit, offset int) ([]*models.NewsletterDelivery, int64, error) {
+	return nil, 0, nil
+}
+
+// trackingToken haalt het t-token uit een tracking URL
+func trackingToken(t *testing.T, rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	assert.NoError(t, err)
+	return parsed.Query().Get("t")
+}
+
+func TestNewsletterTracker(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeNewsletterDeliveryRepository()
+	tracker := services.NewNewsletterTracker(repo, services.NewTokenSigner("test-secret"), "https://api.example.com", true, true, nil)
+
+	deliveries, err := tracker.Prepare(ctx, "nl-1", []string{"lezer@example.com"})
+	assert.NoError(t, err)
+	delivery := deliveries["lezer@example.com"]
+
+	data := map[string]interface{}{
+		"Content": template.HTML(`<p><a href="https://www.dekoninklijkeloop.nl/route?a=1&amp;b=2">Route</a> ` +
+			`<a href="https://api.example.com/api/newsletter/unsubscribe?token=x">Afmelden</a> <a href="mailto:info@example.com">Mail</a></p>`),
+	}
+	tracker.Personalize(delivery, data)
+
+	t.Run("Open via de tracking pixel", func(t *testing.T) {
+		pixelURL, ok := data["TrackingPixelURL"].(string)
+		assert.True(t, ok)
+		assert.NoError(t, tracker.Open(ctx, trackingToken(t, pixelURL)))
+		assert.Equal(t, 1, repo.get(delivery.ID).OpenCount)
+	})
+
+	t.Run("Links worden vervangen door ondertekende redirects", func(t *testing.T) {
+		content := string(data["Content"].(template.HTML))
+		assert.Contains(t, content, `href="https://api.example.com/api/newsletter/track/click?t=`)
+		assert.Contains(t, content, `href="https://api.example.com/api/newsletter/unsubscribe?token=x"`)
+		assert.Contains(t, content, `href="mailto:info@example.com"`)
+
+		start := strings.Index(content, "https://api.example.com/api/newsletter/track/click")
+		clickURL := content[start : start+strings.Index(content[start:], `"`)]
+		target, err := tracker.Click(ctx, trackingToken(t, clickURL))
+		assert.NoError(t, err)
+		assert.Equal(t, "https://www.dekoninklijkeloop.nl/route?a=1&b=2", target)
+		assert.Equal(t, 1, repo.get(delivery.ID).ClickCount)
+	})
+
+	t.Run("Een open token is geen geldig klik token", func(t *testing.T) {
+		_, err := tracker.Click(ctx, trackingToken(t, data["TrackingPixelURL"].(string)))
+		assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+	})
+}
+
+func TestNewsletterDeliveryTracking(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeNewsletterRepository(&models.Newsletter{ID: "nl-tracked", Subject: "Bereik", Content: "<p>Hallo</p>", Status: models.NewsletterStatusApproved})
+	deliveries := newFakeNewsletterDeliveryRepository()
+
+	sender := newTestNewsletterSender(t, repo)
+	sender.SetTracker(services.NewNewsletterTracker(deliveries, services.NewTokenSigner("test-secret"), "https://api.example.com", false, false, nil))
+
+	assert.NoError(t, sender.SendManual(ctx, "nl-tracked", ""))
+
+	// De test email service heeft geen newsletter template, dus de aflevering mislukt en
+	// dat moet per ontvanger zichtbaar zijn in plaats van alleen in de log
+	assert.Eventually(t, func() bool {
+		return deliveries.get("delivery-1").Status == models.NewsletterDeliveryFailed
+	}, 2*time.Second, 10*time.Millisecond)
+	delivery := deliveries.get("delivery-1")
+	assert.Equal(t, "lezer@example.com", delivery.Email)
+	assert.NotEmpty(t, delivery.FoutBericht)
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNewsletterEventRepository legt de nieuwsbrief audit in het geheugen vast
+type fakeNewsletterEventRepository struct {
+	events []*models.NewsletterSubscriptionEvent
+}
+
+func (r *fakeNewsletterEventRepository) Create(ctx context.Context, event *models.NewsletterSubscriptionEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeNewsletterEventRepository) List(ctx context.Context, email string, limit, offset int) ([]*models.NewsletterSubscriptionEvent, int64, error) {
+	return r.events, int64(len(r.events)), nil
+}
+
+func TestTokenSigner(t *testing.T) {
+	signer := services.NewTokenSigner("geheim")
+	token := signer.Sign("newsletter_unsubscribe", "jan@example.com")
+
+	value, err := signer.Verify("newsletter_unsubscribe", token)
+	assert.NoError(t, err)
+	assert.Equal(t, "jan@example.com", value)
+
+	_, err = signer.Verify("ander_doel", token)
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+	_, err = services.NewTokenSigner("ander-geheim").Verify("newsletter_unsubscribe", token)
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	// Een ander adres met de handtekening van jan wordt geweigerd
+	other := signer.Sign("newsletter_unsubscribe", "marie@example.com")
+	forged := strings.Split(other, ".")[0] + "." + strings.Split(token, ".")[1]
+	_, err = signer.Verify("newsletter_unsubscribe", forged)
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+}
+
+func TestNewsletterUnsubscribe(t *testing.T) {
+	db := mocks.NewMockDB()
+	gebruikerRepo := mocks.NewMockGebruikerRepository(db)
+	assert.NoError(t, gebruikerRepo.Create(context.Background(), &models.Gebruiker{ID: "user-1", Email: "jan@example.com", NewsletterSubscribed: true}))
+	events := &fakeNewsletterEventRepository{}
+	suppressions := newFakeEmailSuppressionRepository()
+
+	svc := services.NewNewsletterSubscriptionService(services.NewTokenSigner("geheim"), "https://api.example.com/", gebruikerRepo, events)
+	svc.SetSuppressionRepository(suppressions)
+
+	headers := svc.Headers("jan@example.com")
+	assert.Equal(t, "List-Unsubscribe=One-Click", headers["List-Unsubscribe-Post"])
+	assert.Equal(t, "<"+svc.UnsubscribeURL("jan@example.com")+">", headers["List-Unsubscribe"])
+
+	link, err := url.Parse(svc.UnsubscribeURL("jan@example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/newsletter/unsubscribe", link.Path)
+
+	email, err := svc.Unsubscribe(context.Background(), link.Query().Get("token"), models.NewsletterBronOneClick, "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "jan@example.com", email)
+
+	gebruiker, _ := gebruikerRepo.GetByID(context.Background(), "user-1")
+	assert.False(t, gebruiker.NewsletterSubscribed)
+	if assert.Len(t, events.events, 1) {
+		assert.Equal(t, models.NewsletterActieAfgemeld, events.events[0].Actie)
+		assert.Equal(t, models.NewsletterBronOneClick, events.events[0].Bron)
+	}
+
+	// De afmelding staat blijvend op de suppressielijst, maar blokkeert geen transactionele mail
+	unsubscribed, _ := suppressions.FindUnsubscribed(context.Background(), []string{"jan@example.com"})
+	assert.Equal(t, []string{"jan@example.com"}, unsubscribed)
+	suppressed, _ := suppressions.FindSuppressed(context.Background(), []string{"jan@example.com"})
+	assert.Empty(t, suppressed)
+
+	// Nogmaals afmelden wijzigt niets en legt niets extra vast
+	_, err = svc.Unsubscribe(context.Background(), link.Query().Get("token"), models.NewsletterBronLink, "")
+	assert.NoError(t, err)
+	assert.Len(t, events.events, 1)
+
+	_, err = svc.Unsubscribe(context.Background(), "ongeldig", models.NewsletterBronLink, "")
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	// Opnieuw aanmelden door een beheerder heft de afmelding op
+	svc.RecordChange(context.Background(), gebruiker, true, models.NewsletterBronAdmin, "")
+	unsubscribed, _ = suppressions.FindUnsubscribed(context.Background(), []string{"jan@example.com"})
+	assert.Empty(t, unsubscribed)
+}
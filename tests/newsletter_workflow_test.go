@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeNewsletterRepository houdt nieuwsbrieven in het geheugen bij
+type fakeNewsletterRepository struct {
+	mu          sync.Mutex
+	newsletters map[string]*models.Newsletter
+}
+
+func newFakeNewsletterRepository(newsletters ...*models.Newsletter) *fakeNewsletterRepository {
+	repo := &fakeNewsletterRepository{newsletters: make(map[string]*models.Newsletter)}
+	for _, nl := range newsletters {
+		repo.newsletters[nl.ID] = nl
+	}
+	return repo
+}
+
+func (r *fakeNewsletterRepository) Create(ctx context.Context, nl *models.Newsletter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if nl.ID == "" {
+		nl.ID = "nl-auto"
+	}
+	r.newsletters[nl.ID] = nl
+	return nil
+}
+
+func (r *fakeNewsletterRepository) GetByID(ctx context.Context, id string) (*models.Newsletter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if nl, ok := r.newsletters[id]; ok {
+		copy := *nl
+		return &copy, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeNewsletterRepository) List(ctx context.Context, limit, offset int) ([]*models.Newsletter, error) {
+	return nil, nil
+}
+
+func (r *fakeNewsletterRepository) Update(ctx context.Context, nl *models.Newsletter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.newsletters[nl.ID] = nl
+	return nil
+}
+
+func (r *fakeNewsletterRepository) Delete(ctx context.Context, id string) error {
+	return nil
+}
+
+func (r *fakeNewsletterRepository) UpdateBatchID(ctx context.Context, id, batchID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.newsletters[id].BatchID = batchID
+	return nil
+}
+
+func (r *fakeNewsletterRepository) MarkSent(ctx context.Context, id string, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.newsletters[id].SentAt = &sentAt
+	r.newsletters[id].Status = models.NewsletterStatusSent
+	return nil
+}
+
+func (r *fakeNewsletterRepository) TransitionStatus(ctx context.Context, id string, from []string, to string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nl, ok := r.newsletters[id]
+	if !ok {
+		return false, nil
+	}
+	for _, status := range from {
+		if nl.Status == status {
+			nl.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeNewsletterRepository) MarkFailed(ctx context.Context, id, foutBericht string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.newsletters[id].Status = models.NewsletterStatusFailed
+	r.newsletters[id].FoutBericht = foutBericht
+	return nil
+}
+
+func (r *fakeNewsletterRepository) ListDue(ctx context.Context, now time.Time) ([]*models.Newsletter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*models.Newsletter
+	for _, nl := range r.newsletters {
+		if nl.Status == models.NewsletterStatusScheduled && nl.ScheduledAt != nil && !nl.ScheduledAt.After(now) {
+			due = append(due, nl)
+		}
+	}
+	return due, nil
+}
+
+func newTestNewsletterSender(t *testing.T, repo *fakeNewsletterRepository) *services.NewsletterSender {
+	smtp := &mockSMTP{}
+	smtp.On("Send", mock.Anything).Return(nil)
+	emailService, err := services.NewTestEmailService(smtp)
+	assert.NoError(t, err)
+
+	gebruikerRepo := mocks.NewMockGebruikerRepository(mocks.NewMockDB())
+	assert.NoError(t, gebruikerRepo.Create(context.Background(), &models.Gebruiker{ID: "sub-1", Email: "lezer@example.com", IsActief: true, NewsletterSubscribed: true}))
+
+	batcher := services.NewEmailBatcher(emailService, 50, time.Hour)
+	t.Cleanup(batcher.Shutdown)
+	return services.NewNewsletterSender(emailService, batcher, gebruikerRepo, repo, nil)
+}
+
+func TestNewsletterWorkflow(t *testing.T) {
+	author, reviewer := "user-author", "user-reviewer"
+	repo := newFakeNewsletterRepository(&models.Newsletter{ID: "nl-1", Subject: "Zaterdag", Content: "<p>Hallo</p>", Status: models.NewsletterStatusDraft, CreatedBy: &author})
+	sender := newTestNewsletterSender(t, repo)
+	workflow := services.NewNewsletterWorkflow(repo, sender)
+	ctx := context.Background()
+
+	// Een draft kan niet verzonden worden
+	assert.ErrorIs(t, sender.SendManual(ctx, "nl-1", ""), services.ErrNewsletterInvalidStatus)
+
+	_, err := workflow.Submit(ctx, "nl-1", author)
+	assert.NoError(t, err)
+
+	_, err = workflow.Approve(ctx, "nl-1", author)
+	assert.ErrorIs(t, err, services.ErrNewsletterSelfApproval)
+
+	nl, err := workflow.Approve(ctx, "nl-1", reviewer)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewsletterStatusApproved, nl.Status)
+	assert.Equal(t, &reviewer, nl.ApprovedBy)
+
+	_, err = workflow.Schedule(ctx, "nl-1", time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, services.ErrNewsletterScheduleInPast)
+
+	nl, err = workflow.Schedule(ctx, "nl-1", time.Now().Add(48*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewsletterStatusScheduled, nl.Status)
+
+	// Wijzigen na goedkeuring vereist een nieuwe goedkeuring
+	nl, err = workflow.Edit(ctx, "nl-1", "Zaterdag ochtend", "", nil, reviewer)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewsletterStatusDraft, nl.Status)
+	assert.Nil(t, nl.ApprovedBy)
+	assert.Nil(t, nl.ScheduledAt)
+	assert.Equal(t, &reviewer, nl.UpdatedBy)
+}
+
+func TestNewsletterWorkflowEditorCannotApprove(t *testing.T) {
+	author, editor, reviewer := "user-author", "user-editor", "user-reviewer"
+	repo := newFakeNewsletterRepository(&models.Newsletter{ID: "nl-1", Subject: "Zaterdag", Content: "<p>Hallo</p>", Status: models.NewsletterStatusDraft, CreatedBy: &author})
+	workflow := services.NewNewsletterWorkflow(repo, newTestNewsletterSender(t, repo))
+	ctx := context.Background()
+
+	// De bewerker past de nieuwsbrief van een ander aan en keurt hem daarna zelf goed
+	_, err := workflow.Edit(ctx, "nl-1", "", "<p>Andere inhoud</p>", nil, editor)
+	assert.NoError(t, err)
+	_, err = workflow.Submit(ctx, "nl-1", author)
+	assert.NoError(t, err)
+	_, err = workflow.Approve(ctx, "nl-1", editor)
+	assert.ErrorIs(t, err, services.ErrNewsletterSelfApproval)
+
+	// Ook de indiener kan zijn ingediende nieuwsbrief niet goedkeuren
+	_, err = workflow.Reject(ctx, "nl-1")
+	assert.NoError(t, err)
+	_, err = workflow.Submit(ctx, "nl-1", reviewer)
+	assert.NoError(t, err)
+	_, err = workflow.Approve(ctx, "nl-1", reviewer)
+	assert.ErrorIs(t, err, services.ErrNewsletterSelfApproval)
+
+	_, err = workflow.Reject(ctx, "nl-1")
+	assert.NoError(t, err)
+	_, err = workflow.Submit(ctx, "nl-1", editor)
+	assert.NoError(t, err)
+	nl, err := workflow.Approve(ctx, "nl-1", reviewer)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewsletterStatusApproved, nl.Status)
+}
+
+func TestNewsletterSchedulerSendsDueNewsletters(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	repo := newFakeNewsletterRepository(
+		&models.Newsletter{ID: "nl-due", Subject: "Nu", Status: models.NewsletterStatusScheduled, ScheduledAt: &past},
+		&models.Newsletter{ID: "nl-later", Subject: "Straks", Status: models.NewsletterStatusScheduled, ScheduledAt: &future},
+	)
+
+	service := services.NewNewsletterService(nil, nil, nil, newTestNewsletterSender(t, repo))
+	service.ConfigureScheduler(repo, time.Minute, false)
+	defer service.Stop()
+
+	assert.Equal(t, 1, service.SendDue(context.Background()))
+
+	due, _ := repo.GetByID(context.Background(), "nl-due")
+	assert.Equal(t, models.NewsletterStatusSent, due.Status)
+	assert.NotNil(t, due.SentAt)
+	later, _ := repo.GetByID(context.Background(), "nl-later")
+	assert.Equal(t, models.NewsletterStatusScheduled, later.Status)
+
+	// Een al verzonden nieuwsbrief wordt niet opnieuw opgepakt
+	assert.Equal(t, 0, service.SendDue(context.Background()))
+}
+
+func TestNewsletterSendMarksFailedWhenQueueingFails(t *testing.T) {
+	repo := newFakeNewsletterRepository()
+	sender := newTestNewsletterSender(t, repo)
+	deliveries := newFakeNewsletterDeliveryRepository()
+	deliveries.createErr = errors.New("database niet bereikbaar")
+	sender.SetTracker(services.NewNewsletterTracker(deliveries, services.NewTokenSigner("test-secret"), "https://api.example.com", false, false, nil))
+
+	assert.Error(t, sender.Send(context.Background(), "<p>Hallo</p>", "Dagelijks nieuws"))
+
+	// De nieuwsbrief blijft niet op sending staan
+	nl, _ := repo.GetByID(context.Background(), "nl-auto")
+	if assert.NotNil(t, nl) {
+		assert.Equal(t, models.NewsletterStatusFailed, nl.Status)
+		assert.Equal(t, "database niet bereikbaar", nl.FoutBericht)
+	}
+}
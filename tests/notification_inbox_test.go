@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserNotificationRepository houdt de inbox van gebruikers in het geheugen bij
+type fakeUserNotificationRepository struct {
+	notifications []*models.UserNotification
+}
+
+func (r *fakeUserNotificationRepository) Create(ctx context.Context, n *models.UserNotification) error {
+	n.ID = fmt.Sprintf("un-%d", len(r.notifications)+1)
+	n.CreatedAt = time.Now()
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func (r *fakeUserNotificationRepository) CreateForPermission(ctx context.Context, notificationID, resource, action string) ([]*models.UserNotification, error) {
+	return nil, nil
+}
+
+func (r *fakeUserNotificationRepository) ListByUser(ctx context.Context, userID string, unreadOnly bool, limit, offset int) ([]*models.UserNotification, error) {
+	var found []*models.UserNotification
+	for _, n := range r.notifications {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			found = append(found, n)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeUserNotificationRepository) CountUnread(ctx context.Context, userID string) (int64, error) {
+	unread, _ := r.ListByUser(ctx, userID, true, 100, 0)
+	return int64(len(unread)), nil
+}
+
+func (r *fakeUserNotificationRepository) MarkRead(ctx context.Context, userID string, ids []string) (int64, error) {
+	var updated int64
+	now := time.Now()
+	for _, n := range r.notifications {
+		for _, id := range ids {
+			if n.ID == id && n.UserID == userID && n.ReadAt == nil {
+				n.ReadAt = &now
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
+
+func (r *fakeUserNotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	var ids []string
+	for _, n := range r.notifications {
+		ids = append(ids, n.ID)
+	}
+	return r.MarkRead(ctx, userID, ids)
+}
+
+func (r *fakeUserNotificationRepository) Delete(ctx context.Context, userID, id string) (bool, error) {
+	for i, n := range r.notifications {
+		if n.ID == id && n.UserID == userID {
+			r.notifications = append(r.notifications[:i], r.notifications[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeNotificationPreferenceRepository houdt voorkeuren in het geheugen bij
+type fakeNotificationPreferenceRepository struct {
+	preferences map[string]*models.NotificationPreference
+}
+
+func (r *fakeNotificationPreferenceRepository) ListByUser(ctx context.Context, userID string) ([]*models.NotificationPreference, error) {
+	var found []*models.NotificationPreference
+	for _, p := range r.preferences {
+		if p.UserID == userID {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeNotificationPreferenceRepository) Get(ctx context.Context, userID string, t models.NotificationType) (*models.NotificationPreference, error) {
+	return r.preferences[userID+"/"+string(t)], nil
+}
+
+func (r *fakeNotificationPreferenceRepository) Save(ctx context.Context, p *models.NotificationPreference) error {
+	r.preferences[p.UserID+"/"+string(p.Type)] = p
+	return nil
+}
+
+func TestNotificationInbox(t *testing.T) {
+	ctx := context.Background()
+	notifications := &fakeUserNotificationRepository{}
+	preferences := &fakeNotificationPreferenceRepository{preferences: make(map[string]*models.NotificationPreference)}
+	inbox := services.NewNotificationInbox(notifications, preferences)
+
+	events, unsubscribe := inbox.Subscribe("user-1")
+	defer unsubscribe()
+
+	// Een nieuwe notificatie komt in de inbox en live binnen
+	created, err := inbox.Notify(ctx, "user-1", models.NotificationTypeChat, "Jan reageerde in #vrijwilligers", "Ik help mee", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, created)
+	select {
+	case event := <-events:
+		assert.Equal(t, services.InboxEventNotification, event.Type)
+		assert.Equal(t, created.ID, event.Notification.ID)
+	default:
+		t.Fatal("verwachtte een live notificatie")
+	}
+
+	// Zonder push komt hij wel in de inbox maar niet live; zonder in-app helemaal niet
+	assert.NoError(t, inbox.SetPreference(ctx, &models.NotificationPreference{UserID: "user-1", Type: "CHAT", InApp: true, Push: false}))
+	_, err = inbox.Notify(ctx, "user-1", models.NotificationTypeChat, "Tweede reactie", "", "")
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+
+	assert.NoError(t, inbox.SetPreference(ctx, &models.NotificationPreference{UserID: "user-1", Type: models.NotificationTypeChat, InApp: false}))
+	skipped, err := inbox.Notify(ctx, "user-1", models.NotificationTypeChat, "Derde reactie", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, skipped)
+
+	unread, _ := inbox.UnreadCount(ctx, "user-1")
+	assert.Equal(t, int64(2), unread)
+
+	// Alleen eigen notificaties kunnen gelezen worden; andere verbindingen krijgen een read gebeurtenis
+	updated, err := inbox.MarkRead(ctx, "user-2", []string{created.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updated)
+	updated, err = inbox.MarkRead(ctx, "user-1", []string{created.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+	event := <-events
+	assert.Equal(t, services.InboxEventRead, event.Type)
+	assert.Equal(t, []string{created.ID}, event.IDs)
+
+	// Voorkeuren bevatten alle types, met standaard aan voor types zonder voorkeur
+	prefs, err := inbox.Preferences(ctx, "user-1")
+	assert.NoError(t, err)
+	assert.Len(t, prefs, len(models.NotificationTypes))
+	for _, p := range prefs {
+		if p.Type == models.NotificationTypeChat {
+			assert.False(t, p.InApp)
+		} else {
+			assert.True(t, p.InApp)
+			assert.True(t, p.Push)
+		}
+	}
+
+	assert.ErrorIs(t, inbox.SetPreference(ctx, &models.NotificationPreference{UserID: "user-1", Type: "onbekend"}), services.ErrUnknownNotificationType)
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotificationRepository houdt notificaties in het geheugen bij
+type fakeNotificationRepository struct {
+	notifications map[string]*models.Notification
+}
+
+func newFakeNotificationRepository(notifications ...*models.Notification) *fakeNotificationRepository {
+	r := &fakeNotificationRepository{notifications: make(map[string]*models.Notification)}
+	for _, n := range notifications {
+		r.notifications[n.ID] = n
+	}
+	return r
+}
+
+func (r *fakeNotificationRepository) Create(ctx context.Context, n *models.Notification) error {
+	r.notifications[n.ID] = n
+	return nil
+}
+
+func (r *fakeNotificationRepository) GetByID(ctx context.Context, id string) (*models.Notification, error) {
+	return r.notifications[id], nil
+}
+
+func (r *fakeNotificationRepository) Update(ctx context.Context, n *models.Notification) error {
+	r.notifications[n.ID] = n
+	return nil
+}
+
+func (r *fakeNotificationRepository) Delete(ctx context.Context, id string) error {
+	delete(r.notifications, id)
+	return nil
+}
+
+func (r *fakeNotificationRepository) ListUnsent(ctx context.Context) ([]*models.Notification, error) {
+	var unsent []*models.Notification
+	for _, n := range r.notifications {
+		if !n.Sent {
+			unsent = append(unsent, n)
+		}
+	}
+	return unsent, nil
+}
+
+func (r *fakeNotificationRepository) ListByType(ctx context.Context, t models.NotificationType) ([]*models.Notification, error) {
+	return nil, nil
+}
+
+func (r *fakeNotificationRepository) ListByPriority(ctx context.Context, p models.NotificationPriority) ([]*models.Notification, error) {
+	return nil, nil
+}
+
+// fakeNotificationDeliveryRepository houdt afleveringen per kanaal in het geheugen bij
+type fakeNotificationDeliveryRepository struct {
+	deliveries []*models.NotificationDelivery
+}
+
+func (r *fakeNotificationDeliveryRepository) EnsureDeliveries(ctx context.Context, notificationID string, channels []string) error {
+	for _, channel := range channels {
+		if r.find(notificationID, channel) == nil {
+			r.deliveries = append(r.deliveries, &models.NotificationDelivery{
+				NotificationID: notificationID,
+				Channel:        channel,
+				Status:         models.NotificationDeliveryPending,
+			})
+		}
+	}
+	return nil
+}
+
+func (r *fakeNotificationDeliveryRepository) ListByNotification(ctx context.Context, notificationID string) ([]*models.NotificationDelivery, error) {
+	var found []*models.NotificationDelivery
+	for _, d := range r.deliveries {
+		if d.NotificationID == notificationID {
+			found = append(found, d)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeNotificationDeliveryRepository) ListDue(ctx context.Context, channel string, now time.Time) ([]*models.NotificationDelivery, error) {
+	var due []*models.NotificationDelivery
+	for _, d := range r.deliveries {
+		if d.Channel == channel && d.Status == models.NotificationDeliveryPending &&
+			(d.NextAttemptAt == nil || !d.NextAttemptAt.After(now)) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeNotificationDeliveryRepository) Update(ctx context.Context, delivery *models.NotificationDelivery) error {
+	return nil
+}
+
+func (r *fakeNotificationDeliveryRepository) find(notificationID, channel string) *models.NotificationDelivery {
+	for _, d := range r.deliveries {
+		if d.NotificationID == notificationID && d.Channel == channel {
+			return d
+		}
+	}
+	return nil
+}
+
+// stubNotificationChannel telt afleveringen en faalt de eerste failures keer
+type stubNotificationChannel struct {
+	name      string
+	failures  int
+	delivered []string
+}
+
+func (c *stubNotificationChannel) Name() string { return c.name }
+
+func (c *stubNotificationChannel) Deliver(ctx context.Context, n *models.Notification) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("kanaal niet bereikbaar")
+	}
+	c.delivered = append(c.delivered, n.ID)
+	return nil
+}
+
+// stubBatchNotificationChannel verzamelt notificaties zoals de email digest
+type stubBatchNotificationChannel struct {
+	stubNotificationChannel
+	batches [][]string
+}
+
+func (c *stubBatchNotificationChannel) DeliverBatch(ctx context.Context, notifications []*models.Notification) error {
+	var ids []string
+	for _, n := range notifications {
+		ids = append(ids, n.ID)
+	}
+	c.batches = append(c.batches, ids)
+	return nil
+}
+
+func (c *stubBatchNotificationChannel) BatchInterval() time.Duration { return 0 }
+
+func TestParseNotificationRoutes(t *testing.T) {
+	routes, err := services.ParseNotificationRoutes("telegram=*:high; email=contact,aanmelding:low")
+	assert.NoError(t, err)
+	if assert.Len(t, routes, 2) {
+		assert.Equal(t, "telegram", routes[0].Channel)
+		assert.Empty(t, routes[0].Types)
+		assert.Equal(t, models.NotificationPriorityHigh, routes[0].MinPriority)
+		assert.Equal(t, []models.NotificationType{models.NotificationTypeContact, models.NotificationTypeAanmelding}, routes[1].Types)
+	}
+
+	contact := &models.Notification{Type: models.NotificationTypeContact, Priority: models.NotificationPriorityLow}
+	health := &models.Notification{Type: models.NotificationTypeHealth, Priority: models.NotificationPriorityCritical}
+	assert.True(t, routes[1].Matches("email", contact))
+	assert.False(t, routes[1].Matches("email", health))
+	assert.False(t, routes[0].Matches("telegram", contact))
+	assert.True(t, routes[0].Matches("telegram", health))
+
+	webhook := services.NotificationRoute{Channel: "webhook", MinPriority: models.NotificationPriorityLow}
+	assert.True(t, webhook.Matches("webhook_2", contact))
+	assert.False(t, webhook.Matches("webhooks", contact))
+
+	_, err = services.ParseNotificationRoutes("telegram=*:dringend")
+	assert.Error(t, err)
+}
+
+func TestNotificationRouterDeliversPerChannel(t *testing.T) {
+	ctx := context.Background()
+	notification := &models.Notification{
+		ID:       "n-1",
+		Type:     models.NotificationTypeContact,
+		Priority: models.NotificationPriorityHigh,
+		Title:    "Nieuw contactformulier",
+		Message:  "Er is een nieuw bericht",
+	}
+	notificationRepo := newFakeNotificationRepository(notification)
+	deliveryRepo := &fakeNotificationDeliveryRepository{}
+
+	telegram := &stubNotificationChannel{name: "telegram"}
+	webhook := &stubNotificationChannel{name: "webhook_2", failures: 1}
+	email := &stubBatchNotificationChannel{stubNotificationChannel: stubNotificationChannel{name: "email"}}
+	inApp := &stubNotificationChannel{name: "in_app"}
+
+	router := services.NewNotificationRouter(notificationRepo, deliveryRepo,
+		[]services.NotificationChannel{telegram, webhook, email, inApp},
+		[]services.NotificationRoute{
+			{Channel: "telegram", MinPriority: models.NotificationPriorityHigh},
+			{Channel: "webhook", MinPriority: models.NotificationPriorityHigh},
+			{Channel: "email", MinPriority: models.NotificationPriorityMedium},
+			{Channel: "in_app", Types: []models.NotificationType{models.NotificationTypeHealth}, MinPriority: models.NotificationPriorityLow},
+		})
+	router.SetRetryPolicy(3, time.Nanosecond, time.Nanosecond)
+
+	// In-app geldt alleen voor health; de webhook faalt de eerste keer
+	assert.ElementsMatch(t, []string{"telegram", "webhook_2", "email"}, router.ChannelsFor(notification))
+	err := router.Deliver(ctx, notification)
+	assert.Error(t, err)
+	assert.Equal(t, models.NotificationDeliverySent, deliveryRepo.find("n-1", "telegram").Status)
+	failed := deliveryRepo.find("n-1", "webhook_2")
+	assert.Equal(t, models.NotificationDeliveryPending, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.NotEmpty(t, failed.LastError)
+	assert.Equal(t, models.NotificationDeliveryPending, deliveryRepo.find("n-1", "email").Status)
+	assert.Nil(t, deliveryRepo.find("n-1", "in_app"))
+	assert.False(t, notification.Sent)
+
+	// Alleen de webhook wordt opnieuw geprobeerd; Telegram niet nog een keer
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, router.Deliver(ctx, notification))
+	assert.Len(t, telegram.delivered, 1)
+	assert.Equal(t, models.NotificationDeliverySent, deliveryRepo.find("n-1", "webhook_2").Status)
+	assert.False(t, notification.Sent, "de digest staat nog open")
+
+	// De digest levert alle openstaande notificaties in één batch af
+	assert.NoError(t, router.FlushBatches(ctx))
+	assert.Equal(t, [][]string{{"n-1"}}, email.batches)
+	assert.Equal(t, models.NotificationDeliverySent, deliveryRepo.find("n-1", "email").Status)
+	assert.True(t, notification.Sent)
+	assert.NotNil(t, notification.SentAt)
+}
+
+func TestNotificationRouterGivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	notification := &models.Notification{ID: "n-2", Type: models.NotificationTypeSystem, Priority: models.NotificationPriorityCritical}
+	deliveryRepo := &fakeNotificationDeliveryRepository{}
+	webhook := &stubNotificationChannel{name: "webhook", failures: 10}
+	email := &stubBatchNotificationChannel{stubNotificationChannel: stubNotificationChannel{name: "email"}}
+
+	router := services.NewNotificationRouter(newFakeNotificationRepository(notification), deliveryRepo,
+		[]services.NotificationChannel{webhook, email},
+		services.DefaultNotificationRoutes(models.NotificationPriorityMedium))
+	router.SetRetryPolicy(2, time.Nanosecond, time.Nanosecond)
+
+	// Kritieke notificaties gaan direct over het batch kanaal
+	assert.Error(t, router.Deliver(ctx, notification))
+	assert.Equal(t, []string{"n-2"}, email.delivered)
+
+	time.Sleep(time.Millisecond)
+	assert.Error(t, router.Deliver(ctx, notification))
+	assert.Equal(t, models.NotificationDeliveryFailed, deliveryRepo.find("n-2", "webhook").Status)
+	assert.Equal(t, 2, deliveryRepo.find("n-2", "webhook").Attempts)
+	assert.True(t, notification.Sent, "alle kanalen zijn afgerond")
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotificationThrottleStore houdt de throttling status in het geheugen bij, zoals de database doet
+type fakeNotificationThrottleStore struct {
+	throttles map[string]*models.NotificationThrottle
+}
+
+func newFakeNotificationThrottleStore() *fakeNotificationThrottleStore {
+	return &fakeNotificationThrottleStore{throttles: make(map[string]*models.NotificationThrottle)}
+}
+
+func (s *fakeNotificationThrottleStore) Hit(ctx context.Context, key string, n *models.Notification, windowEnd, now time.Time) (bool, error) {
+	throttle, ok := s.throttles[key]
+	if !ok || !throttle.WindowEnd.After(now) {
+		if !ok {
+			throttle = &models.NotificationThrottle{Key: key}
+			s.throttles[key] = throttle
+		}
+		throttle.WindowEnd = windowEnd
+		return true, nil
+	}
+
+	throttle.Suppressed++
+	throttle.Type = n.Type
+	if n.Priority.Rank() > throttle.Priority.Rank() {
+		throttle.Priority = n.Priority
+	}
+	if len(throttle.Titles) < models.MaxNotificationDigestTitles {
+		throttle.Titles = append(throttle.Titles, n.Title)
+	}
+	if throttle.DigestDueAt == nil {
+		due := throttle.WindowEnd
+		throttle.DigestDueAt = &due
+	}
+	return false, nil
+}
+
+func (s *fakeNotificationThrottleStore) ClaimDue(ctx context.Context, now time.Time) ([]*models.NotificationThrottle, error) {
+	var due []*models.NotificationThrottle
+	for _, throttle := range s.throttles {
+		if throttle.DigestDueAt != nil && !throttle.DigestDueAt.After(now) {
+			claimed := *throttle
+			due = append(due, &claimed)
+			throttle.Suppressed = 0
+			throttle.Titles = nil
+			throttle.Priority = ""
+			throttle.DigestDueAt = nil
+		}
+	}
+	return due, nil
+}
+
+func (s *fakeNotificationThrottleStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	for key, throttle := range s.throttles {
+		if throttle.WindowEnd.Before(before) && throttle.DigestDueAt == nil {
+			delete(s.throttles, key)
+		}
+	}
+	return nil
+}
+
+// recordingNotificationClient onthoudt de verzonden titels
+type recordingNotificationClient struct {
+	titles []string
+}
+
+func (c *recordingNotificationClient) SendMessage(title, message string) error {
+	c.titles = append(c.titles, title)
+	return nil
+}
+
+func TestParseNotificationThrottleRules(t *testing.T) {
+	rules, err := services.ParseNotificationThrottleRules("aanmelding=1h; contact=15m:medium; health=0; *=5m")
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 4) {
+		assert.Equal(t, models.NotificationTypeAanmelding, rules[0].Type)
+		assert.Equal(t, time.Hour, rules[0].Window)
+		assert.Empty(t, rules[0].MinPriority)
+		assert.Equal(t, models.NotificationPriorityMedium, rules[1].MinPriority)
+		assert.Zero(t, rules[2].Window)
+		assert.Empty(t, rules[3].Type)
+	}
+
+	throttler := services.NewNotificationThrottler(newFakeNotificationThrottleStore(), 15*time.Minute, rules)
+	assert.Equal(t, time.Hour, throttler.Rule(models.NotificationTypeAanmelding).Window)
+	assert.Equal(t, 5*time.Minute, throttler.Rule(models.NotificationTypeChat).Window)
+
+	contact := &models.Notification{Type: models.NotificationTypeContact, Priority: models.NotificationPriorityLow}
+	chat := &models.Notification{Type: models.NotificationTypeChat, Priority: models.NotificationPriorityLow}
+	assert.False(t, throttler.PriorityAllowed(contact, models.NotificationPriorityLow))
+	assert.True(t, throttler.PriorityAllowed(chat, models.NotificationPriorityLow))
+	assert.False(t, throttler.PriorityAllowed(chat, models.NotificationPriorityHigh))
+
+	_, err = services.ParseNotificationThrottleRules("contact=soms")
+	assert.Error(t, err)
+	_, err = services.ParseNotificationThrottleRules("contact=1h:dringend")
+	assert.Error(t, err)
+}
+
+func TestQuietHours(t *testing.T) {
+	quiet, err := services.ParseQuietHours("22:00-07:00", time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "22:00-07:00", quiet.String())
+	assert.True(t, quiet.Contains(time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC)))
+	assert.True(t, quiet.Contains(time.Date(2026, 10, 16, 6, 59, 0, 0, time.UTC)))
+	assert.False(t, quiet.Contains(time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)))
+	assert.False(t, quiet.Contains(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)))
+
+	lunch, err := services.ParseQuietHours("12:00-13:30", time.UTC)
+	assert.NoError(t, err)
+	assert.True(t, lunch.Contains(time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)))
+	assert.False(t, lunch.Contains(time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC)))
+
+	_, err = services.ParseQuietHours("22:00", time.UTC)
+	assert.Error(t, err)
+	_, err = services.ParseQuietHours("25:00-07:00", time.UTC)
+	assert.Error(t, err)
+}
+
+func TestNotificationThrottlingSendsDigest(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	notificationRepo := newFakeNotificationRepository()
+	client := &recordingNotificationClient{}
+
+	throttler := services.NewNotificationThrottler(newFakeNotificationThrottleStore(), 15*time.Minute,
+		[]services.NotificationThrottleRule{{Type: models.NotificationTypeAanmelding, Window: time.Hour}})
+	throttler.SetClock(func() time.Time { return now })
+	service := services.NewNotificationService(notificationRepo, client, throttler, models.NotificationPriorityLow)
+
+	// De eerste aanmelding gaat direct uit; de volgende worden geteld voor de digest
+	for i, title := range []string{"Aanmelding Anna", "Aanmelding Bram", "Aanmelding Cor"} {
+		n := &models.Notification{ID: title, Type: models.NotificationTypeAanmelding, Priority: models.NotificationPriorityMedium, Title: title}
+		notificationRepo.notifications[n.ID] = n
+		assert.NoError(t, service.SendNotification(ctx, n))
+		assert.True(t, n.Sent, "notificatie %d is afgehandeld", i)
+	}
+	assert.Len(t, client.titles, 1)
+
+	// Kritieke notificaties worden nooit afgeremd
+	critical := &models.Notification{ID: "kritiek", Type: models.NotificationTypeAanmelding, Priority: models.NotificationPriorityCritical, Title: "Database onbereikbaar"}
+	assert.NoError(t, service.SendNotification(ctx, critical))
+	assert.Len(t, client.titles, 2)
+
+	// Zolang het venster open is, is er nog geen digest
+	assert.NoError(t, service.ProcessUnsentNotifications(ctx))
+	assert.Len(t, client.titles, 2)
+
+	now = now.Add(time.Hour)
+	assert.NoError(t, service.ProcessUnsentNotifications(ctx))
+	if assert.Len(t, client.titles, 3) {
+		assert.Contains(t, client.titles[2], "2 nieuwe aanmeldingen in het afgelopen uur")
+	}
+	digest := notificationRepo.notifications[""]
+	if assert.NotNil(t, digest) {
+		assert.Equal(t, "• Aanmelding Bram\n• Aanmelding Cor", digest.Message)
+		assert.Equal(t, models.NotificationPriorityMedium, digest.Priority)
+		assert.True(t, digest.Sent)
+	}
+
+	// Een digest wordt maar één keer verzonden
+	assert.NoError(t, service.ProcessUnsentNotifications(ctx))
+	assert.Len(t, client.titles, 3)
+}
+
+func TestNotificationQuietHoursHoldNotifications(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	notificationRepo := newFakeNotificationRepository()
+	client := &recordingNotificationClient{}
+
+	throttler := services.NewNotificationThrottler(newFakeNotificationThrottleStore(), 15*time.Minute, nil)
+	throttler.SetClock(func() time.Time { return now })
+	quiet, err := services.ParseQuietHours("22:00-07:00", time.UTC)
+	assert.NoError(t, err)
+	throttler.SetQuietHours(quiet)
+	service := services.NewNotificationService(notificationRepo, client, throttler, models.NotificationPriorityLow)
+
+	contact := &models.Notification{ID: "contact", Type: models.NotificationTypeContact, Priority: models.NotificationPriorityHigh, Title: "Nieuw contactformulier"}
+	critical := &models.Notification{ID: "kritiek", Type: models.NotificationTypeHealth, Priority: models.NotificationPriorityCritical, Title: "Service down"}
+	notificationRepo.notifications[contact.ID] = contact
+	notificationRepo.notifications[critical.ID] = critical
+
+	// 's Nachts gaan alleen kritieke notificaties uit
+	assert.NoError(t, service.ProcessUnsentNotifications(ctx))
+	assert.Len(t, client.titles, 1)
+	assert.False(t, contact.Sent)
+	assert.True(t, critical.Sent)
+
+	// Na de stille uren gaat de aangehouden notificatie alsnog uit
+	now = time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)
+	assert.NoError(t, service.ProcessUnsentNotifications(ctx))
+	assert.Len(t, client.titles, 2)
+	assert.True(t, contact.Sent)
+}
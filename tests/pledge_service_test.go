@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakePledgeRepository houdt toezeggingen in het geheugen en geeft kopieën terug, zoals de database
+type fakePledgeRepository struct {
+	pledges []*models.Pledge
+}
+
+func (r *fakePledgeRepository) Create(ctx context.Context, pledge *models.Pledge) error {
+	pledge.ID = "pledge-" + strconv.Itoa(len(r.pledges)+1)
+	stored := *pledge
+	r.pledges = append(r.pledges, &stored)
+	return nil
+}
+
+func (r *fakePledgeRepository) GetByID(ctx context.Context, id string) (*models.Pledge, error) {
+	for _, pledge := range r.pledges {
+		if pledge.ID == id {
+			result := *pledge
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePledgeRepository) ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.Pledge, error) {
+	var result []*models.Pledge
+	for _, pledge := range r.pledges {
+		if pledge.AanmeldingID == aanmeldingID {
+			copied := *pledge
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakePledgeRepository) List(ctx context.Context, filter models.PledgeFilter) ([]*models.Pledge, int64, error) {
+	var result []*models.Pledge
+	for _, pledge := range r.pledges {
+		if filter.Status == "" || pledge.Status == filter.Status {
+			copied := *pledge
+			result = append(result, &copied)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (r *fakePledgeRepository) Update(ctx context.Context, pledge *models.Pledge) error {
+	for i, existing := range r.pledges {
+		if existing.ID == pledge.ID {
+			stored := *pledge
+			r.pledges[i] = &stored
+		}
+	}
+	return nil
+}
+
+func newPledgeFixture(t *testing.T) (*services.PledgeService, *fakePledgeRepository, *models.Aanmelding, *MockEmailSender) {
+	aanmeldingRepo := mocks.NewMockAanmeldingRepository(mocks.NewMockDB())
+	participant := &models.Aanmelding{ID: "deelnemer-1", Naam: "Jan de Vries", Email: "jan@example.com", Afstand: "15 KM", Steps: 25000}
+	assert.NoError(t, aanmeldingRepo.Create(context.Background(), participant))
+
+	pledges := &fakePledgeRepository{}
+	emailSender := new(MockEmailSender)
+	emailSender.On("SendTemplateEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return services.NewPledgeService(pledges, aanmeldingRepo, emailSender), pledges, participant, emailSender
+}
+
+func TestPledgeExpectedAmounts(t *testing.T) {
+	participant := &models.Aanmelding{Afstand: "2,5 km", Steps: 34500}
+	maxAmount := 5.0
+
+	assert.Equal(t, 15.0, services.RouteKilometers("15 KM"))
+	assert.Equal(t, 2.5, services.RouteKilometers(participant.Afstand))
+	assert.Equal(t, 0.0, services.RouteKilometers("Wandeling"))
+
+	assert.Equal(t, 20.0, services.CalculatePledgeAmount(&models.Pledge{Type: models.PledgeTypeFixed, Amount: 20}, participant))
+	assert.Equal(t, 3.75, services.CalculatePledgeAmount(&models.Pledge{Type: models.PledgeTypePerKm, Amount: 1.5}, participant))
+	assert.Equal(t, 6.9, services.CalculatePledgeAmount(&models.Pledge{Type: models.PledgeTypePer10kSteps, Amount: 2}, participant))
+	assert.Equal(t, 5.0, services.CalculatePledgeAmount(&models.Pledge{Type: models.PledgeTypePer10kSteps, Amount: 2, MaxAmount: &maxAmount}, participant))
+
+	assert.Equal(t, "€ 1.234,50", services.FormatEuro(1234.5))
+	assert.Equal(t, "€ 0,05", services.FormatEuro(0.049))
+}
+
+func TestPledgeCreateValidatesAndConfirms(t *testing.T) {
+	pledgeService, pledges, _, emailSender := newPledgeFixture(t)
+
+	public, err := pledgeService.GetPublicParticipant("deelnemer-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.PledgeParticipant{Naam: "Jan V.", Route: "15 KM", Steps: 25000}, public)
+
+	_, err = pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Oma", SponsorEmail: "Oma <oma@example.com>", Amount: 10})
+	assert.ErrorIs(t, err, services.ErrInvalidPledge)
+	_, err = pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Oma", SponsorEmail: "oma@example.com", Type: "per_uur", Amount: 10})
+	assert.ErrorIs(t, err, services.ErrInvalidPledge)
+	_, err = pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Oma", SponsorEmail: "oma@example.com", Amount: 0})
+	assert.ErrorIs(t, err, services.ErrInvalidPledge)
+	_, err = pledgeService.CreatePledge("onbekend", &services.PledgeInput{SponsorNaam: "Oma", SponsorEmail: "oma@example.com", Amount: 10})
+	assert.ErrorIs(t, err, services.ErrParticipantNotFound)
+	emailSender.AssertNotCalled(t, "SendTemplateEmail", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	maxAmount := 25.0
+	pledge, err := pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{
+		SponsorNaam:  " Oma ",
+		SponsorEmail: " Oma@Example.com ",
+		Type:         models.PledgeTypePerKm,
+		Amount:       2,
+		MaxAmount:    &maxAmount,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Oma", pledge.SponsorNaam)
+	assert.Equal(t, "oma@example.com", pledge.SponsorEmail)
+	assert.Equal(t, models.PledgeStatusPending, pledge.Status)
+	assert.Equal(t, 25.0, pledge.ExpectedAmount)
+	assert.NotNil(t, pledges.pledges[0].ConfirmationSentAt)
+
+	emailSender.AssertNumberOfCalls(t, "SendTemplateEmail", 1)
+	call := emailSender.Calls[0]
+	assert.Equal(t, "oma@example.com", call.Arguments.Get(0))
+	assert.Equal(t, "pledge_confirmation", call.Arguments.Get(2))
+	data := call.Arguments.Get(3).(map[string]interface{})
+	assert.Equal(t, "Jan V.", data["Deelnemer"])
+	assert.Equal(t, "€ 2,00 per kilometer", data["Pledge"])
+	assert.Equal(t, "€ 25,00", data["MaxAmount"])
+}
+
+func TestPledgeStatusTotalsAndFinishedEmails(t *testing.T) {
+	pledgeService, pledges, participant, emailSender := newPledgeFixture(t)
+
+	fixed, err := pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Piet", SponsorEmail: "piet@example.com", Amount: 20, Anoniem: true})
+	assert.NoError(t, err)
+	perSteps, err := pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Els", SponsorEmail: "els@example.com", Type: models.PledgeTypePer10kSteps, Amount: 4})
+	assert.NoError(t, err)
+	cancelled, err := pledgeService.CreatePledge("deelnemer-1", &services.PledgeInput{SponsorNaam: "Kees", SponsorEmail: "kees@example.com", Amount: 50})
+	assert.NoError(t, err)
+
+	_, err = pledgeService.UpdateStatus(fixed.ID, "betaald", nil, "")
+	assert.ErrorIs(t, err, services.ErrInvalidPledge)
+	_, err = pledgeService.UpdateStatus("bestaat-niet", models.PledgeStatusPaid, nil, "")
+	assert.ErrorIs(t, err, services.ErrPledgeNotFound)
+
+	paid, err := pledgeService.UpdateStatus(fixed.ID, models.PledgeStatusPaid, nil, " NL-123 ")
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, *paid.PaidAmount)
+	assert.Equal(t, "NL-123", *paid.PaymentReference)
+	assert.NotNil(t, paid.PaidAt)
+	_, err = pledgeService.UpdateStatus(cancelled.ID, models.PledgeStatusCancelled, nil, "")
+	assert.NoError(t, err)
+
+	// De stappen lopen na de toezegging op; het verwachte bedrag rekent mee
+	participant.Steps = 40000
+	summary, err := pledgeService.Summary(participant)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.PledgeSummary{Count: 2, PaidCount: 1, ExpectedTotal: 36, PaidTotal: 20}, summary)
+
+	listed, _, err := pledgeService.ListByParticipant("deelnemer-1", true)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 3) {
+		assert.Equal(t, services.AnonymousSponsorName, listed[0].SponsorNaam)
+		assert.Empty(t, listed[0].SponsorEmail)
+		assert.Nil(t, listed[0].PaymentReference)
+		assert.Equal(t, "Els", listed[1].SponsorNaam)
+		assert.Equal(t, 16.0, listed[1].ExpectedAmount)
+	}
+	listed, _, err = pledgeService.ListByParticipant("deelnemer-1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Piet", listed[0].SponsorNaam)
+	assert.Equal(t, "piet@example.com", listed[0].SponsorEmail)
+
+	// Alleen de niet geannuleerde sponsors krijgen de eindmail, en maar één keer
+	confirmations := len(emailSender.Calls)
+	sent, err := pledgeService.NotifyFinished("deelnemer-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	finished := emailSender.Calls[confirmations:]
+	if assert.Len(t, finished, 2) {
+		assert.Equal(t, "pledge_finished", finished[1].Arguments.Get(2))
+		assert.Equal(t, "els@example.com", finished[1].Arguments.Get(0))
+		data := finished[1].Arguments.Get(3).(map[string]interface{})
+		assert.Equal(t, "€ 16,00", data["Amount"])
+		assert.Equal(t, "40.000", data["Steps"])
+		assert.Equal(t, false, data["Paid"])
+	}
+	assert.NotNil(t, pledges.pledges[1].FinishedEmailSentAt)
+	assert.Nil(t, pledges.pledges[2].FinishedEmailSentAt)
+
+	sent, err = pledgeService.NotifyFinished("deelnemer-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	all, total, err := pledgeService.List(models.PledgeFilter{Status: models.PledgeStatusPending})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, perSteps.ID, all[0].ID)
+	assert.WithinDuration(t, time.Now(), *all[0].FinishedEmailSentAt, time.Minute)
+}
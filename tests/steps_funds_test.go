@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeEventRepository houdt edities in het geheugen en geeft vaste route statistieken terug
+type fakeEventRepository struct {
+	events    []*models.Event
+	stats     []models.RouteStats
+	statsFrom time.Time
+	statsTo   time.Time
+}
+
+func (r *fakeEventRepository) Create(ctx context.Context, event *models.Event) error {
+	event.ID = "event-" + strconv.Itoa(event.Jaar)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeEventRepository) GetByYear(ctx context.Context, year int) (*models.Event, error) {
+	for _, event := range r.events {
+		if event.Jaar == year {
+			return event, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeEventRepository) GetLatest(ctx context.Context) (*models.Event, error) {
+	var latest *models.Event
+	for _, event := range r.events {
+		if latest == nil || event.Jaar > latest.Jaar {
+			latest = event
+		}
+	}
+	return latest, nil
+}
+
+func (r *fakeEventRepository) List(ctx context.Context) ([]*models.Event, error) {
+	return r.events, nil
+}
+
+func (r *fakeEventRepository) Update(ctx context.Context, event *models.Event) error {
+	return nil
+}
+
+func (r *fakeEventRepository) Delete(ctx context.Context, id string) error {
+	for i, event := range r.events {
+		if event.ID == id {
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *fakeEventRepository) RouteStats(ctx context.Context, from, to time.Time) ([]models.RouteStats, error) {
+	r.statsFrom, r.statsTo = from, to
+	return r.stats, nil
+}
+
+// fakeRouteFundRepository houdt route bedragen per editie in het geheugen
+type fakeRouteFundRepository struct {
+	routeFunds []*models.RouteFund
+}
+
+func (r *fakeRouteFundRepository) Create(ctx context.Context, routeFund *models.RouteFund) error {
+	r.routeFunds = append(r.routeFunds, routeFund)
+	return nil
+}
+
+func (r *fakeRouteFundRepository) GetByRoute(ctx context.Context, eventID, route string) (*models.RouteFund, error) {
+	for _, routeFund := range r.routeFunds {
+		if routeFund.EventID == eventID && routeFund.Route == route {
+			return routeFund, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRouteFundRepository) ListByEvent(ctx context.Context, eventID string) ([]*models.RouteFund, error) {
+	var result []*models.RouteFund
+	for _, routeFund := range r.routeFunds {
+		if routeFund.EventID == eventID {
+			result = append(result, routeFund)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRouteFundRepository) Update(ctx context.Context, routeFund *models.RouteFund) error {
+	return nil
+}
+
+func (r *fakeRouteFundRepository) Delete(ctx context.Context, eventID, route string) error {
+	return nil
+}
+
+func newStepsFundsFixture(t *testing.T) (*services.StepsService, *fakeEventRepository) {
+	aanmeldingRepo := mocks.NewMockAanmeldingRepository(mocks.NewMockDB())
+	events := &fakeEventRepository{}
+	steps := services.NewStepsService(nil, aanmeldingRepo, &fakeRouteFundRepository{}, &fakeStepEntryRepository{aanmelding: aanmeldingRepo}, events)
+
+	assert.NoError(t, steps.CreateEvent(&models.Event{Jaar: 2025, TotalFunds: 10000}))
+	for _, routeFund := range []models.RouteFund{{Route: "6 KM", Amount: 50}, {Route: "10 KM", Amount: 75}, {Route: "15 KM", Amount: 100}} {
+		_, err := steps.CreateRouteFund(2025, routeFund.Route, routeFund.Amount)
+		assert.NoError(t, err)
+	}
+	return steps, events
+}
+
+func TestStepsFundsDistributionStrategies(t *testing.T) {
+	steps, events := newStepsFundsFixture(t)
+	event := events.events[0]
+	assert.Equal(t, "De Koninklijke Loop 2025", event.Naam)
+	assert.Equal(t, models.AllocationStrategyParticipants, event.AllocationStrategy)
+
+	// Zonder deelnemers valt er niets naar rato te verdelen; de pot gaat gelijk over de routes
+	distribution, err := steps.GetFundsDistribution(0)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllocationStrategyEqual, distribution.Strategy)
+	assert.Equal(t, models.AllocationStrategyParticipants, distribution.ConfiguredStrategy)
+	assert.Equal(t, []int{3334, 3333, 3333}, allocated(distribution))
+
+	location, _ := time.LoadLocation(services.StepsEventTimezone)
+	assert.True(t, events.statsFrom.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, location)))
+	assert.True(t, events.statsTo.Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, location)))
+
+	events.stats = []models.RouteStats{
+		{Route: "6 KM", Participants: 1, Steps: 10000},
+		{Route: "10 KM", Participants: 2, Steps: 30000},
+		{Route: "20 KM", Participants: 5, Steps: 90000}, // Geen route in deze editie
+	}
+	distribution, err = steps.GetFundsDistribution(2025)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllocationStrategyParticipants, distribution.Strategy)
+	assert.Equal(t, []int{3333, 6667, 0}, allocated(distribution))
+	assert.Equal(t, 2, distribution.Routes[1].Participants)
+	assert.Equal(t, 75, distribution.Routes[1].Amount)
+
+	_, err = steps.UpdateEvent(2025, &models.Event{TotalFunds: 12000, AllocationStrategy: models.AllocationStrategySteps})
+	assert.NoError(t, err)
+	distribution, err = steps.GetFundsDistribution(2025)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AllocationStrategySteps, distribution.Strategy)
+	assert.Equal(t, []int{3000, 9000, 0}, allocated(distribution))
+
+	_, err = steps.UpdateEvent(2025, &models.Event{TotalFunds: 100, AllocationStrategy: "loting"})
+	assert.ErrorIs(t, err, services.ErrInvalidEvent)
+
+	_, err = steps.GetFundsDistribution(2024)
+	assert.ErrorIs(t, err, services.ErrEventNotFound)
+}
+
+func allocated(distribution *models.FundsDistribution) []int {
+	var amounts []int
+	for _, route := range distribution.Routes {
+		amounts = append(amounts, route.Allocated)
+	}
+	return amounts
+}
+
+func TestStepsAllocatedFundsPerEdition(t *testing.T) {
+	steps, _ := newStepsFundsFixture(t)
+	assert.NoError(t, steps.CreateEvent(&models.Event{Jaar: 2026, Naam: "Editie 2026", AllocationStrategy: models.AllocationStrategyEqual}))
+	_, err := steps.CreateRouteFund(2026, "10 KM", 90)
+	assert.NoError(t, err)
+
+	registered2025 := &models.Aanmelding{Afstand: "10 KM", CreatedAt: time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)}
+	registered2026 := &models.Aanmelding{Afstand: "10 KM", CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	assert.Equal(t, 75, steps.CalculateAllocatedFunds(registered2025))
+	assert.Equal(t, 90, steps.CalculateAllocatedFunds(registered2026))
+
+	// Geen hardgecodeerde bedragen meer: zonder route of editie is het 0
+	assert.Equal(t, 0, steps.CalculateAllocatedFunds(&models.Aanmelding{Afstand: "20 KM", CreatedAt: registered2025.CreatedAt}))
+	assert.Equal(t, 0, steps.CalculateAllocatedFunds(&models.Aanmelding{Afstand: "10 KM", CreatedAt: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)}))
+
+	// Zonder jaar wordt de meest recente editie gebruikt
+	routeFunds, err := steps.GetRouteFunds(0)
+	assert.NoError(t, err)
+	assert.Len(t, routeFunds, 1)
+
+	_, err = steps.UpdateRouteFund(2026, "6 KM", 10)
+	assert.ErrorIs(t, err, services.ErrRouteFundNotFound)
+	_, err = steps.CreateRouteFund(2026, "6 KM", -1)
+	assert.ErrorIs(t, err, services.ErrInvalidEvent)
+
+	assert.ErrorIs(t, steps.CreateEvent(&models.Event{Jaar: 2026}), services.ErrInvalidEvent)
+	assert.ErrorIs(t, steps.CreateEvent(&models.Event{Jaar: 2027, RouteFunds: []models.RouteFund{{Route: "6 KM"}, {Route: "6 KM"}}}), services.ErrInvalidEvent)
+}
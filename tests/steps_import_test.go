@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"bytes"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Garmin Connect" xmlns="http://www.topografix.com/GPX/1/1">
+  <metadata><time>2025-05-10T07:55:00Z</time></metadata>
+  <trk>
+    <name>Ochtendwandeling</name>
+    <trkseg>
+      <trkpt lat="52.0000" lon="5.0000"><time>2025-05-10T08:00:00Z</time></trkpt>
+      <trkpt lat="52.0045" lon="5.0000"><time>2025-05-10T08:06:00Z</time></trkpt>
+      <trkpt lat="52.0090" lon="5.0000"><time>2025-05-10T08:12:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const testTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2" xmlns:ns3="http://www.garmin.com/xmlschemas/ActivityExtension/v2">
+  <Activities>
+    <Activity Sport="Other">
+      <Id>2025-05-11T09:00:00Z</Id>
+      <Lap StartTime="2025-05-11T09:00:00Z">
+        <DistanceMeters>2500.0</DistanceMeters>
+        <Extensions><ns3:LX><ns3:Steps>3300</ns3:Steps></ns3:LX></Extensions>
+      </Lap>
+      <Lap StartTime="2025-05-11T09:30:00Z">
+        <DistanceMeters>2000.0</DistanceMeters>
+        <Extensions><ns3:LX><ns3:Steps>2700</ns3:Steps></ns3:LX></Extensions>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+// buildFIT maakt een minimaal FIT bestand met één sessie
+func buildFIT(start time.Time, sport byte, distanceCm, cycles uint32) []byte {
+	var records bytes.Buffer
+	// Definitie van lokaal bericht 0: sessie met start_time, sport, total_distance en total_cycles
+	records.Write([]byte{0x40, 0, 0, 18, 0, 4, 2, 4, 134, 5, 1, 0, 9, 4, 134, 10, 4, 134})
+	records.WriteByte(0x00)
+	_ = binary.Write(&records, binary.LittleEndian, uint32(start.Sub(time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC))/time.Second))
+	records.WriteByte(sport)
+	_ = binary.Write(&records, binary.LittleEndian, distanceCm)
+	_ = binary.Write(&records, binary.LittleEndian, cycles)
+
+	header := []byte{14, 0x10, 0x2D, 0x08, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint32(header[4:8], uint32(records.Len()))
+	return append(append(header, records.Bytes()...), 0, 0)
+}
+
+func TestParseActivityFiles(t *testing.T) {
+	location, _ := time.LoadLocation(services.StepsEventTimezone)
+
+	format, activities, err := services.ParseActivityFile("wandeling.gpx", []byte(testGPX), location)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ActivityFormatGPX, format)
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, time.Date(2025, 5, 10, 8, 0, 0, 0, time.UTC), activities[0].StartedAt.UTC())
+		assert.InDelta(t, 1001, activities[0].DistanceMeters, 2)
+		assert.True(t, activities[0].StepsEstimated)
+		assert.InDelta(t, 1335, activities[0].Steps, 3)
+	}
+
+	format, activities, err = services.ParseActivityFile("export", []byte(testTCX), location)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ActivityFormatTCX, format)
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, 6000, activities[0].Steps)
+		assert.Equal(t, 4500.0, activities[0].DistanceMeters)
+		assert.False(t, activities[0].StepsEstimated)
+	}
+
+	start := time.Date(2025, 5, 12, 18, 0, 0, 0, time.UTC)
+	format, activities, err = services.ParseActivityFile("horloge.bin", buildFIT(start, 11, 450000, 3100), location)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ActivityFormatFIT, format)
+	if assert.Len(t, activities, 1) {
+		assert.True(t, activities[0].StartedAt.Equal(start))
+		assert.Equal(t, 4500.0, activities[0].DistanceMeters)
+		assert.Equal(t, 6200, activities[0].Steps)
+	}
+
+	// Bij fietsen telt een omwenteling niet als stap; de stappen worden geschat uit de afstand
+	_, activities, err = services.ParseActivityFile("fiets.fit", buildFIT(start, 2, 150000, 900), location)
+	assert.NoError(t, err)
+	if assert.Len(t, activities, 1) {
+		assert.Equal(t, 2000, activities[0].Steps)
+		assert.True(t, activities[0].StepsEstimated)
+	}
+
+	_, _, err = services.ParseActivityFile("kapot.fit", buildFIT(start, 11, 1, 1)[:20], location)
+	assert.ErrorIs(t, err, services.ErrUnsupportedActivityFile)
+}
+
+func TestParseStepsCSVExports(t *testing.T) {
+	location, _ := time.LoadLocation(services.StepsEventTimezone)
+
+	googleFit := "\ufeffDate,Move Minutes count,Step count,Distance (m)\n" +
+		"2025-05-10,45,8123,6020.5\n" +
+		"2025-05-11,12,,1500\n" +
+		"2025-05-12,0,0,0\n"
+	format, activities, err := services.ParseActivityFile("Daily activity metrics.csv", []byte(googleFit), location)
+	assert.NoError(t, err)
+	assert.Equal(t, services.ActivityFormatCSV, format)
+	if assert.Len(t, activities, 3) {
+		assert.Equal(t, time.Date(2025, 5, 10, 0, 0, 0, 0, location), activities[0].StartedAt)
+		assert.Equal(t, 8123, activities[0].Steps)
+		assert.Equal(t, 6021.0, activities[0].DistanceMeters)
+		assert.Equal(t, 2000, activities[1].Steps)
+		assert.True(t, activities[1].StepsEstimated)
+		assert.Equal(t, 0, activities[2].Steps)
+	}
+
+	// Export van een gezondheidsapp: puntkomma's, afstand in km en meerdere regels per dag
+	health := "Datum;Stappen (count);Afstand (km)\n" +
+		"2025-05-10 08:00:00;4.000;3,1\n" +
+		"2025-05-10 18:00:00;2.500;1,9\n" +
+		"2025-05-11 09:00:00;7.250;5,0\n"
+	_, activities, err = services.ParseActivityFile("health.csv", []byte(health), location)
+	assert.NoError(t, err)
+	if assert.Len(t, activities, 2) {
+		assert.Equal(t, 6500, activities[0].Steps)
+		assert.Equal(t, 5000.0, activities[0].DistanceMeters)
+		assert.Equal(t, 7250, activities[1].Steps)
+	}
+
+	_, _, err = services.ParseActivityFile("lijst.csv", []byte("Naam,Leeftijd\nJan,40\n"), location)
+	assert.ErrorIs(t, err, services.ErrUnsupportedActivityFile)
+}
+
+func TestImportActivitiesBooksLedgerEntries(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+
+	csv := []byte("Date,Step count\n2025-05-10,8000\n2025-05-11,0\n2025-05-12,200000\n")
+	result, err := steps.ImportActivities("deelnemer-1", "fit.csv", csv, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 2, result.Rejected)
+	assert.Equal(t, 8000, result.ImportedSteps)
+	assert.Equal(t, 8000, result.TotalSteps)
+	assert.Equal(t, services.ImportStatusImported, result.Activities[0].Status)
+	assert.Equal(t, services.ImportStatusRejected, result.Activities[1].Status)
+
+	if assert.Len(t, ledger.entries, 1) {
+		entry := ledger.entries[0]
+		assert.Equal(t, models.StepSourceImport, entry.Source)
+		assert.Equal(t, "user-1", *entry.EnteredBy)
+		if assert.NotNil(t, entry.Metadata) {
+			assert.Equal(t, services.ActivityFormatCSV, entry.Metadata.Format)
+			assert.Equal(t, "fit.csv", entry.Metadata.FileName)
+			assert.Equal(t, result.Activities[0].Hash, entry.Metadata.ActivityHash)
+		}
+	}
+
+	// Dezelfde export nog een keer uploaden telt niet dubbel
+	result, err = steps.ImportActivities("deelnemer-1", "fit.csv", csv, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 1, result.Duplicates)
+	assert.Equal(t, 8000, result.TotalSteps)
+	assert.Len(t, ledger.entries, 1)
+
+	_, err = steps.ImportActivities("onbekend", "fit.csv", csv, "user-1")
+	assert.ErrorIs(t, err, services.ErrParticipantNotFound)
+}
+
+func TestImportActivitiesOverlappingCSVExports(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+
+	// Eerste export halverwege 11 mei
+	first := []byte("Date,Step count,Distance (m)\n2025-05-10,6000,4500\n2025-05-11,5000,3750\n")
+	result, err := steps.ImportActivities("deelnemer-1", "fit.csv", first, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 11000, result.TotalSteps)
+
+	// Een latere export bevat dezelfde dagen opnieuw; 11 mei is gegroeid en 12 mei is nieuw
+	second := []byte("Date,Step count,Distance (m)\n2025-05-10,6000,4510\n2025-05-11,8000,6000\n2025-05-12,3000,2250\n")
+	result, err = steps.ImportActivities("deelnemer-1", "fit.csv", second, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Duplicates)
+	assert.Equal(t, 6000, result.ImportedSteps)
+	assert.Equal(t, 17000, result.TotalSteps)
+	assert.Equal(t, services.ImportStatusDuplicate, result.Activities[0].Status)
+
+	// 11 mei telt 8.000 stappen en niet 13.000
+	day := 0
+	for _, entry := range ledger.entries {
+		if entry.RecordedAt.Day() == 11 {
+			day += entry.Delta
+		}
+	}
+	assert.Equal(t, 8000, day)
+
+	// Een oudere export met minder stappen haalt er niets af
+	result, err = steps.ImportActivities("deelnemer-1", "fit.csv", first, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 17000, result.TotalSteps)
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeLeaderboardRepository geeft een vaste ranglijst terug en onthoudt het laatste filter
+type fakeLeaderboardRepository struct {
+	participants []models.LeaderboardEntry
+	teams        []models.TeamLeaderboardEntry
+	filter       models.LeaderboardFilter
+	visibility   map[string]bool
+}
+
+func (r *fakeLeaderboardRepository) Participants(ctx context.Context, filter models.LeaderboardFilter) ([]models.LeaderboardEntry, error) {
+	r.filter = filter
+	return append([]models.LeaderboardEntry(nil), r.participants...), nil
+}
+
+func (r *fakeLeaderboardRepository) Teams(ctx context.Context, filter models.LeaderboardFilter) ([]models.TeamLeaderboardEntry, error) {
+	r.filter = filter
+	return append([]models.TeamLeaderboardEntry(nil), r.teams...), nil
+}
+
+func (r *fakeLeaderboardRepository) SetVisibility(ctx context.Context, aanmeldingID string, visible bool) error {
+	r.visibility[aanmeldingID] = visible
+	return nil
+}
+
+// fakeStepMilestoneRepository kent elke mijlpaal per deelnemer maar één keer toe
+type fakeStepMilestoneRepository struct {
+	milestones []*models.StepMilestone
+}
+
+func (r *fakeStepMilestoneRepository) Award(ctx context.Context, milestone *models.StepMilestone) (bool, error) {
+	for _, existing := range r.milestones {
+		if existing.AanmeldingID == milestone.AanmeldingID && existing.Milestone == milestone.Milestone {
+			return false, nil
+		}
+	}
+	milestone.ID = milestone.AanmeldingID + "-" + services.FormatSteps(milestone.Milestone)
+	r.milestones = append(r.milestones, milestone)
+	return true, nil
+}
+
+func (r *fakeStepMilestoneRepository) ListByAanmelding(ctx context.Context, aanmeldingID string) ([]*models.StepMilestone, error) {
+	var result []*models.StepMilestone
+	for _, milestone := range r.milestones {
+		if milestone.AanmeldingID == aanmeldingID {
+			result = append(result, milestone)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeStepMilestoneRepository) MarkEmailSent(ctx context.Context, id string, sentAt time.Time) error {
+	for _, milestone := range r.milestones {
+		if milestone.ID == id {
+			milestone.EmailSentAt = &sentAt
+		}
+	}
+	return nil
+}
+
+// fakeStepTeamRepository houdt teams in het geheugen
+type fakeStepTeamRepository struct {
+	teams   map[string]*models.StepTeam
+	members map[string]string
+}
+
+func (r *fakeStepTeamRepository) Create(ctx context.Context, team *models.StepTeam) error {
+	team.ID = "team-" + team.Naam
+	r.teams[team.ID] = team
+	return nil
+}
+
+func (r *fakeStepTeamRepository) GetByID(ctx context.Context, id string) (*models.StepTeam, error) {
+	return r.teams[id], nil
+}
+
+func (r *fakeStepTeamRepository) List(ctx context.Context) ([]*models.StepTeam, error) {
+	var teams []*models.StepTeam
+	for _, team := range r.teams {
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+func (r *fakeStepTeamRepository) Update(ctx context.Context, team *models.StepTeam) error {
+	r.teams[team.ID] = team
+	return nil
+}
+
+func (r *fakeStepTeamRepository) Delete(ctx context.Context, id string) error {
+	delete(r.teams, id)
+	return nil
+}
+
+func (r *fakeStepTeamRepository) AddMembers(ctx context.Context, teamID string, aanmeldingIDs []string) error {
+	for _, id := range aanmeldingIDs {
+		r.members[id] = teamID
+	}
+	return nil
+}
+
+func (r *fakeStepTeamRepository) RemoveMember(ctx context.Context, teamID, aanmeldingID string) error {
+	if r.members[aanmeldingID] == teamID {
+		delete(r.members, aanmeldingID)
+	}
+	return nil
+}
+
+func (r *fakeStepTeamRepository) ListMembers(ctx context.Context, teamID string) ([]*models.Aanmelding, error) {
+	return nil, nil
+}
+
+func TestStepsLeaderboardRanksAndAnonymises(t *testing.T) {
+	steps, _ := newStepsLedgerFixture(t)
+	team := "Basisschool De Linde"
+	teamID := "team-1"
+	leaderboard := &fakeLeaderboardRepository{
+		participants: []models.LeaderboardEntry{
+			{AanmeldingID: "a", Naam: "Jan de Vries", Route: "10 KM", TeamID: &teamID, TeamNaam: &team, Steps: 9000, Zichtbaar: true},
+			{AanmeldingID: "b", Naam: "Piet Jansen", Route: "10 KM", Steps: 7000},
+			{AanmeldingID: "c", Naam: "Els", Route: "6 KM", Steps: 7000, Zichtbaar: true},
+			{AanmeldingID: "d", Naam: "Kees van Dam", Route: "6 KM", Steps: 500, Zichtbaar: true},
+		},
+	}
+	steps.EnableLeaderboards(&fakeStepTeamRepository{}, leaderboard)
+
+	filter := steps.LeaderboardFilter(2025)
+	location, _ := time.LoadLocation(services.StepsEventTimezone)
+	assert.True(t, filter.RegisteredFrom.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, location)))
+	assert.True(t, filter.RegisteredTo.Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, location)))
+	assert.Equal(t, services.DefaultLeaderboardLimit, filter.Limit)
+
+	entries, err := steps.Leaderboard(filter, true)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 4) {
+		assert.Equal(t, []int{1, 2, 2, 4}, []int{entries[0].Rank, entries[1].Rank, entries[2].Rank, entries[3].Rank})
+		assert.Equal(t, "Jan V.", entries[0].Naam)
+		assert.Equal(t, services.AnonymousParticipantName, entries[1].Naam)
+		assert.Equal(t, "Els", entries[2].Naam)
+		assert.Equal(t, "Kees D.", entries[3].Naam)
+		for _, entry := range entries {
+			assert.Empty(t, entry.AanmeldingID)
+			assert.Nil(t, entry.TeamID)
+		}
+		assert.Equal(t, team, *entries[0].TeamNaam)
+	}
+
+	// Het admin klassement houdt volledige namen en ID's
+	entries, err = steps.Leaderboard(filter, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Piet Jansen", entries[1].Naam)
+	assert.Equal(t, "b", entries[1].AanmeldingID)
+
+	leaderboard.teams = []models.TeamLeaderboardEntry{
+		{TeamID: "t1", Naam: "Groot", Members: 10, Steps: 50000},
+		{TeamID: "t2", Naam: "Klein", Members: 2, Steps: 20000},
+	}
+	filter.SortByAverage = true
+	teams, err := steps.TeamLeaderboard(filter)
+	assert.NoError(t, err)
+	if assert.Len(t, teams, 2) {
+		assert.Equal(t, 5000, teams[0].AverageSteps)
+		assert.Equal(t, 10000, teams[1].AverageSteps)
+		assert.True(t, leaderboard.filter.SortByAverage)
+	}
+
+	leaderboard.visibility = map[string]bool{}
+	assert.NoError(t, steps.SetLeaderboardVisibility("deelnemer-1", true))
+	assert.True(t, leaderboard.visibility["deelnemer-1"])
+}
+
+func TestStepsMilestonesSendCongratulations(t *testing.T) {
+	steps, _ := newStepsLedgerFixture(t)
+	participant, _ := steps.GetParticipant("deelnemer-1")
+	participant.Email = "jan@example.com"
+
+	milestones := &fakeStepMilestoneRepository{}
+	emailSender := new(MockEmailSender)
+	emailSender.On("SendTemplateEmail", "jan@example.com", mock.Anything, "steps_milestone", mock.Anything, mock.Anything).Return(nil)
+	steps.EnableMilestones(milestones, emailSender)
+
+	_, err := steps.UpdateSteps("deelnemer-1", 99000, services.StepEntryOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, milestones.milestones)
+
+	// Eén grote sprong passeert twee mijlpalen tegelijk
+	_, err = steps.UpdateSteps("deelnemer-1", 160000, services.StepEntryOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, milestones.milestones, 2) {
+		assert.Equal(t, 100000, milestones.milestones[0].Milestone)
+		assert.Equal(t, 250000, milestones.milestones[1].Milestone)
+		assert.NotNil(t, milestones.milestones[0].EmailSentAt)
+	}
+	emailSender.AssertNumberOfCalls(t, "SendTemplateEmail", 2)
+	data := emailSender.Calls[0].Arguments.Get(3).(map[string]interface{})
+	assert.Equal(t, "100.000", data["Milestone"])
+	assert.Equal(t, "259.000", data["Steps"])
+
+	// Na een correctie omlaag en weer omhoog komt er geen tweede felicitatie
+	_, err = steps.RecordCorrection("deelnemer-1", -20000, "Dubbel geteld", "admin-1", nil)
+	assert.NoError(t, err)
+	_, err = steps.UpdateSteps("deelnemer-1", 20000, services.StepEntryOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, milestones.milestones, 2)
+	emailSender.AssertNumberOfCalls(t, "SendTemplateEmail", 2)
+
+	listed, err := steps.GetMilestones("deelnemer-1")
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestStepsTeamsValidation(t *testing.T) {
+	steps, _ := newStepsLedgerFixture(t)
+	teams := &fakeStepTeamRepository{teams: map[string]*models.StepTeam{}, members: map[string]string{}}
+	steps.EnableLeaderboards(teams, &fakeLeaderboardRepository{})
+
+	assert.ErrorIs(t, steps.CreateTeam(&models.StepTeam{Naam: "  "}), services.ErrInvalidTeam)
+	assert.ErrorIs(t, steps.CreateTeam(&models.StepTeam{Naam: "Club", Type: "vereniging"}), services.ErrInvalidTeam)
+
+	team := &models.StepTeam{Naam: " Zorgcentrum De Hoven "}
+	assert.NoError(t, steps.CreateTeam(team))
+	assert.Equal(t, "Zorgcentrum De Hoven", team.Naam)
+	assert.Equal(t, models.StepTeamTypeOrganisatie, team.Type)
+	assert.True(t, team.IsActief)
+
+	updated, err := steps.UpdateTeam(team.ID, &models.StepTeam{Naam: "Zorgcentrum De Hoven", Type: models.StepTeamTypeZorginstelling})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StepTeamTypeZorginstelling, updated.Type)
+	assert.False(t, updated.IsActief)
+
+	assert.NoError(t, steps.AddTeamMembers(team.ID, []string{"deelnemer-1"}))
+	assert.Equal(t, team.ID, teams.members["deelnemer-1"])
+	assert.ErrorIs(t, steps.AddTeamMembers(team.ID, []string{"onbekend"}), services.ErrParticipantNotFound)
+	assert.ErrorIs(t, steps.AddTeamMembers("bestaat-niet", []string{"deelnemer-1"}), services.ErrTeamNotFound)
+
+	assert.NoError(t, steps.RemoveTeamMember(team.ID, "deelnemer-1"))
+	assert.Empty(t, teams.members)
+	assert.NoError(t, steps.DeleteTeam(team.ID))
+	assert.ErrorIs(t, steps.DeleteTeam(team.ID), services.ErrTeamNotFound)
+}
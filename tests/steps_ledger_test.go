@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStepEntryRepository is een in-memory StepEntryRepository met dezelfde regels als de
+// PostgreSQL versie: begrenzen op nul en één regel per idempotency key
+type fakeStepEntryRepository struct {
+	mu          sync.Mutex
+	entries     []*models.StepEntry
+	aanmelding  *mocks.MockAanmeldingRepository
+	sumFrom     time.Time
+	sumTo       time.Time
+	dailyTotals []models.StepDayTotal
+}
+
+func (r *fakeStepEntryRepository) total(aanmeldingID string) int {
+	total := 0
+	for _, entry := range r.entries {
+		if entry.AanmeldingID == aanmeldingID {
+			total += entry.Delta
+		}
+	}
+	return total
+}
+
+func (r *fakeStepEntryRepository) Record(ctx context.Context, entry *models.StepEntry) (int, bool, error) {
+	return r.record(ctx, entry, nil)
+}
+
+func (r *fakeStepEntryRepository) RecordDayTotal(ctx context.Context, entry *models.StepEntry, dayTotal int) (int, bool, error) {
+	return r.record(ctx, entry, &dayTotal)
+}
+
+func (r *fakeStepEntryRepository) record(ctx context.Context, entry *models.StepEntry, dayTotal *int) (int, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := r.total(entry.AanmeldingID)
+	if entry.IdempotencyKey != nil {
+		for _, existing := range r.entries {
+			if existing.AanmeldingID == entry.AanmeldingID && existing.IdempotencyKey != nil && *existing.IdempotencyKey == *entry.IdempotencyKey {
+				*entry = *existing
+				return total, false, nil
+			}
+		}
+	}
+	if dayTotal != nil {
+		booked := 0
+		for _, existing := range r.entries {
+			if existing.AanmeldingID == entry.AanmeldingID && existing.Source == models.StepSourceImport &&
+				existing.RecordedAt.Equal(entry.RecordedAt) && existing.Metadata != nil && existing.Metadata.Format == entry.Metadata.Format {
+				booked += existing.Delta
+			}
+		}
+		entry.Delta = *dayTotal - booked
+		if entry.Delta <= 0 {
+			entry.Delta = 0
+			return total, false, nil
+		}
+	}
+	if total+entry.Delta < 0 {
+		entry.Delta = -total
+	}
+	copied := *entry
+	r.entries = append(r.entries, &copied)
+	total += entry.Delta
+
+	if aanmelding, _ := r.aanmelding.GetByID(ctx, entry.AanmeldingID); aanmelding != nil {
+		aanmelding.Steps = total
+	}
+	return total, true, nil
+}
+
+func (r *fakeStepEntryRepository) ListByAanmelding(ctx context.Context, aanmeldingID string, limit, offset int) ([]*models.StepEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*models.StepEntry
+	for _, entry := range r.entries {
+		if entry.AanmeldingID == aanmeldingID {
+			result = append(result, entry)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].RecordedAt.After(result[j].RecordedAt) })
+	return result, nil
+}
+
+func (r *fakeStepEntryRepository) DailyTotals(ctx context.Context, aanmeldingID string, from, to time.Time, timezone string) ([]models.StepDayTotal, error) {
+	return r.dailyTotals, nil
+}
+
+func (r *fakeStepEntryRepository) SumByRegistrationPeriod(ctx context.Context, from, to time.Time) (int, error) {
+	r.sumFrom, r.sumTo = from, to
+	return 4200, nil
+}
+
+func newStepsLedgerFixture(t *testing.T) (*services.StepsService, *fakeStepEntryRepository) {
+	aanmeldingRepo := mocks.NewMockAanmeldingRepository(mocks.NewMockDB())
+	assert.NoError(t, aanmeldingRepo.Create(context.Background(), &models.Aanmelding{ID: "deelnemer-1", Naam: "Jan", Afstand: "10 KM"}))
+	ledger := &fakeStepEntryRepository{aanmelding: aanmeldingRepo}
+	return services.NewStepsService(nil, aanmeldingRepo, nil, ledger, nil), ledger
+}
+
+func TestStepsLedgerRecordsEntries(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+
+	participant, err := steps.UpdateSteps("deelnemer-1", 1500, services.StepEntryOptions{EnteredBy: "user-1", IdempotencyKey: "sync-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1500, participant.Steps)
+
+	// Een herhaald verzoek van de app telt niet opnieuw
+	participant, err = steps.UpdateSteps("deelnemer-1", 1500, services.StepEntryOptions{EnteredBy: "user-1", IdempotencyKey: "sync-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1500, participant.Steps)
+	assert.Len(t, ledger.entries, 1)
+
+	// Een te grote aftrek wordt begrensd zodat het totaal niet negatief wordt
+	participant, err = steps.UpdateSteps("deelnemer-1", -2000, services.StepEntryOptions{Source: models.StepSourceAdmin, EnteredBy: "admin-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, participant.Steps)
+	if assert.Len(t, ledger.entries, 2) {
+		assert.Equal(t, -1500, ledger.entries[1].Delta)
+		assert.Equal(t, models.StepSourceAdmin, ledger.entries[1].Source)
+		assert.Equal(t, "admin-1", *ledger.entries[1].EnteredBy)
+		assert.Equal(t, models.StepSourceApp, ledger.entries[0].Source)
+	}
+
+	future := time.Now().Add(time.Hour)
+	_, err = steps.UpdateSteps("deelnemer-1", 100, services.StepEntryOptions{RecordedAt: &future})
+	assert.ErrorIs(t, err, services.ErrInvalidStepsEntry)
+
+	_, err = steps.UpdateSteps("onbekend", 100, services.StepEntryOptions{})
+	assert.ErrorIs(t, err, services.ErrParticipantNotFound)
+}
+
+func TestStepsLedgerCorrections(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+
+	_, err := steps.RecordCorrection("deelnemer-1", 500, "  ", "admin-1", nil)
+	assert.ErrorIs(t, err, services.ErrCorrectionReasonRequired)
+	_, err = steps.RecordCorrection("deelnemer-1", 0, "Niets", "admin-1", nil)
+	assert.ErrorIs(t, err, services.ErrInvalidStepsEntry)
+	assert.Empty(t, ledger.entries)
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	participant, err := steps.RecordCorrection("deelnemer-1", 500, "Stappen van de papieren lijst", "admin-1", &yesterday)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, participant.Steps)
+
+	entries, err := steps.ListEntries("deelnemer-1", 50, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, models.StepSourceCorrection, entries[0].Source)
+		assert.Equal(t, "Stappen van de papieren lijst", *entries[0].Reason)
+		assert.True(t, entries[0].RecordedAt.Equal(yesterday))
+	}
+}
+
+func TestStepsLedgerHistoryPerDay(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+	ledger.dailyTotals = []models.StepDayTotal{
+		{Date: "2025-05-10", Steps: 3000},
+		{Date: "2025-05-12", Steps: 1200},
+	}
+
+	from, _ := steps.ParseDay("2025-05-10")
+	to, _ := steps.ParseDay("2025-05-13")
+	history, err := steps.GetHistory("deelnemer-1", &from, &to)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.StepDayTotal{
+		{Date: "2025-05-10", Steps: 3000},
+		{Date: "2025-05-11", Steps: 0},
+		{Date: "2025-05-12", Steps: 1200},
+		{Date: "2025-05-13", Steps: 0},
+	}, history)
+
+	// Zonder periode komen de laatste 30 dagen terug
+	history, err = steps.GetHistory("deelnemer-1", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, history, services.DefaultStepsHistoryDays)
+
+	_, err = steps.GetHistory("deelnemer-1", &to, &from)
+	assert.ErrorIs(t, err, services.ErrInvalidStepsEntry)
+
+	longAgo := from.AddDate(-2, 0, 0)
+	_, err = steps.GetHistory("deelnemer-1", &longAgo, &to)
+	assert.ErrorIs(t, err, services.ErrInvalidStepsEntry)
+}
+
+func TestStepsLedgerTotalPerEventYear(t *testing.T) {
+	steps, ledger := newStepsLedgerFixture(t)
+
+	total, err := steps.GetTotalSteps(2025)
+	assert.NoError(t, err)
+	assert.Equal(t, 4200, total)
+
+	location, _ := time.LoadLocation(services.StepsEventTimezone)
+	assert.True(t, ledger.sumFrom.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, location)))
+	assert.True(t, ledger.sumTo.Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, location)))
+}
@@ -0,0 +1,355 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTelegramAPI onthoudt welke methodes van de Telegram Bot API zijn aangeroepen
+type fakeTelegramAPI struct {
+	mu    sync.Mutex
+	calls []telegramAPICall
+}
+
+type telegramAPICall struct {
+	Method string
+	Params map[string]string
+}
+
+func newFakeTelegramAPI(t *testing.T) (*fakeTelegramAPI, *httptest.Server) {
+	api := &fakeTelegramAPI{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		params := make(map[string]string)
+		for key := range r.PostForm {
+			params[key] = r.PostForm.Get(key)
+		}
+		api.mu.Lock()
+		api.calls = append(api.calls, telegramAPICall{Method: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], Params: params})
+		api.mu.Unlock()
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (a *fakeTelegramAPI) methodCalls(method string) []telegramAPICall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var result []telegramAPICall
+	for _, call := range a.calls {
+		if call.Method == method {
+			result = append(result, call)
+		}
+	}
+	return result
+}
+
+// quickReplyTemplateRepository geeft alle templates terug als actief
+type quickReplyTemplateRepository struct {
+	*fakeEmailTemplateRepository
+}
+
+func (r *quickReplyTemplateRepository) FindActive(ctx context.Context) ([]*models.EmailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*models.EmailTemplate
+	for _, tmpl := range r.templates {
+		if tmpl.IsActief {
+			copied := *tmpl
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+// idContactAntwoordRepository geeft antwoorden een ID zoals de database dat doet
+type idContactAntwoordRepository struct {
+	*mocks.MockContactAntwoordRepository
+}
+
+func (r *idContactAntwoordRepository) Create(ctx context.Context, antwoord *models.ContactAntwoord) error {
+	antwoord.ID = "antwoord-" + antwoord.ContactID
+	return r.MockContactAntwoordRepository.Create(ctx, antwoord)
+}
+
+// denyingPermissionService weigert alle permissies
+type denyingPermissionService struct {
+	*mocks.MockPermissionService
+}
+
+func (s *denyingPermissionService) HasPermission(ctx context.Context, userID, resource, action string) bool {
+	return false
+}
+
+// recordingReplySender onthoudt de verstuurde antwoorden
+type recordingReplySender struct {
+	mu    sync.Mutex
+	sent  []string
+	metas []services.EmailMetadata
+}
+
+func (s *recordingReplySender) SendEmailWithMetadata(to, subject, body string, meta services.EmailMetadata, fromAddress ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, to+"|"+subject+"|"+body)
+	s.metas = append(s.metas, meta)
+	return nil
+}
+
+type telegramActionsFixture struct {
+	bot         *services.TelegramBotService
+	api         *fakeTelegramAPI
+	db          *mocks.MockDB
+	contacts    *mocks.MockContactRepository
+	aanmelding  *mocks.MockAanmeldingRepository
+	gebruikers  *mocks.MockGebruikerRepository
+	antwoorden  *idContactAntwoordRepository
+	templates   *quickReplyTemplateRepository
+	sender      *recordingReplySender
+	signer      *services.TokenSigner
+	telegramID  int64
+	gebruikerID string
+}
+
+func newTelegramActionsFixture(t *testing.T, permissions services.PermissionService) *telegramActionsFixture {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	t.Setenv("TELEGRAM_CHAT_ID", "-100")
+
+	db := mocks.NewMockDB()
+	f := &telegramActionsFixture{
+		db:          db,
+		contacts:    mocks.NewMockContactRepository(db),
+		aanmelding:  mocks.NewMockAanmeldingRepository(db),
+		gebruikers:  mocks.NewMockGebruikerRepository(db),
+		antwoorden:  &idContactAntwoordRepository{mocks.NewMockContactAntwoordRepository(db)},
+		templates:   &quickReplyTemplateRepository{newFakeEmailTemplateRepository()},
+		sender:      &recordingReplySender{},
+		signer:      services.NewTokenSigner("test-secret"),
+		telegramID:  4242,
+		gebruikerID: "user-1",
+	}
+
+	api, server := newFakeTelegramAPI(t)
+	f.api = api
+	f.bot = services.NewTelegramBotService(f.contacts, f.aanmelding)
+	f.bot.SetAPIBaseURL(server.URL)
+	f.bot.EnableActions(f.gebruikers, permissions, f.signer)
+	f.bot.SetQuickReplies(f.templates, f.antwoorden, mocks.NewMockAanmeldingAntwoordRepository(db), f.sender)
+
+	ctx := context.Background()
+	assert.NoError(t, f.gebruikers.Create(ctx, &models.Gebruiker{ID: f.gebruikerID, Naam: "Jan", Email: "jan@dekoninklijkeloop.nl", IsActief: true}))
+	assert.NoError(t, f.contacts.Create(ctx, &models.ContactFormulier{ID: "contact-1", Naam: "Piet", Email: "piet@example.com", Status: "nieuw"}))
+	assert.NoError(t, f.aanmelding.Create(ctx, &models.Aanmelding{ID: "aanmelding-1", Naam: "Klaas", Email: "klaas@example.com", Status: "nieuw"}))
+	return f
+}
+
+// link koppelt het Telegram account van de fixture via een koppelcode
+func (f *telegramActionsFixture) link(t *testing.T) {
+	code, _, err := f.bot.CreateLinkCode(f.gebruikerID)
+	assert.NoError(t, err)
+	_, err = f.bot.LinkAccount(context.Background(), code, f.telegramID)
+	assert.NoError(t, err)
+}
+
+func (f *telegramActionsFixture) click(data string) (string, error) {
+	query := &services.TelegramCallbackQuery{ID: "q1", Data: data}
+	query.From.ID = f.telegramID
+	return f.bot.HandleCallbackQuery(context.Background(), query)
+}
+
+func TestTelegramActionKeyboard(t *testing.T) {
+	id := "0b4a3f8e-7c2d-4e59-9a61-5f0c2d8e1b7a"
+
+	keyboard := services.TelegramActionKeyboard(&models.Notification{Type: models.NotificationTypeContact, ReferenceID: &id})
+	assert.Len(t, keyboard, 2)
+	assert.Equal(t, "done:c:"+id, keyboard[0][0].CallbackData)
+	assert.Equal(t, "mine:c:"+id, keyboard[0][1].CallbackData)
+	assert.Equal(t, "reply:c:"+id, keyboard[1][0].CallbackData)
+
+	// Een snel antwoord met template ID past binnen de 64 bytes van Telegram
+	assert.LessOrEqual(t, len("ans:a:"+id+":0b4a3f8e"), 64)
+
+	markup, err := keyboard.Markup()
+	assert.NoError(t, err)
+	var decoded map[string][][]services.TelegramInlineButton
+	assert.NoError(t, json.Unmarshal([]byte(markup), &decoded))
+	assert.Len(t, decoded["inline_keyboard"], 2)
+
+	// Zonder verwijzing of bij andere types geen knoppen
+	assert.Nil(t, services.TelegramActionKeyboard(&models.Notification{Type: models.NotificationTypeContact}))
+	assert.Nil(t, services.TelegramActionKeyboard(&models.Notification{Type: models.NotificationTypeHealth, ReferenceID: &id}))
+}
+
+func TestTelegramLinkAccount(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	ctx := context.Background()
+
+	_, err := f.bot.LinkAccount(ctx, "ongeldig", f.telegramID)
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	// Een code voor een ander doel wordt niet geaccepteerd
+	_, err = f.bot.LinkAccount(ctx, f.signer.Sign("unsubscribe", f.gebruikerID+"|9999999999"), f.telegramID)
+	assert.ErrorIs(t, err, services.ErrInvalidSignedToken)
+
+	f.link(t)
+	gebruiker, _ := f.gebruikers.GetByTelegramUserID(ctx, f.telegramID)
+	assert.NotNil(t, gebruiker)
+	assert.Equal(t, f.gebruikerID, gebruiker.ID)
+
+	// Hetzelfde Telegram account bij een andere gebruiker verbreekt de oude koppeling
+	assert.NoError(t, f.gebruikers.Create(ctx, &models.Gebruiker{ID: "user-2", Naam: "Kees", Email: "kees@dekoninklijkeloop.nl", IsActief: true}))
+	code, _, err := f.bot.CreateLinkCode("user-2")
+	assert.NoError(t, err)
+	_, err = f.bot.LinkAccount(ctx, code, f.telegramID)
+	assert.NoError(t, err)
+
+	eerste, _ := f.gebruikers.GetByID(ctx, f.gebruikerID)
+	assert.Nil(t, eerste.TelegramUserID)
+	gebruiker, _ = f.gebruikers.GetByTelegramUserID(ctx, f.telegramID)
+	assert.Equal(t, "user-2", gebruiker.ID)
+
+	assert.NoError(t, f.bot.UnlinkAccount(ctx, "user-2"))
+	gebruiker, _ = f.gebruikers.GetByTelegramUserID(ctx, f.telegramID)
+	assert.Nil(t, gebruiker)
+}
+
+func TestTelegramCallbackRequiresLinkedAccount(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+
+	text, err := f.click("done:c:contact-1")
+	assert.NoError(t, err)
+	assert.Contains(t, text, "/koppel")
+
+	contact, _ := f.contacts.GetByID(context.Background(), "contact-1")
+	assert.Equal(t, "nieuw", contact.Status)
+}
+
+func TestTelegramCallbackChecksPermission(t *testing.T) {
+	f := newTelegramActionsFixture(t, &denyingPermissionService{mocks.NewMockPermissionService()})
+	f.link(t)
+
+	text, err := f.click("mine:a:aanmelding-1")
+	assert.NoError(t, err)
+	assert.Contains(t, text, "geen rechten")
+
+	aanmelding, _ := f.aanmelding.GetByID(context.Background(), "aanmelding-1")
+	assert.Equal(t, "nieuw", aanmelding.Status)
+	assert.Nil(t, aanmelding.BehandeldDoor)
+}
+
+func TestTelegramCallbackStatusChanges(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	f.link(t)
+	ctx := context.Background()
+
+	_, err := f.click("mine:a:aanmelding-1")
+	assert.NoError(t, err)
+	aanmelding, _ := f.aanmelding.GetByID(ctx, "aanmelding-1")
+	assert.Equal(t, "in_behandeling", aanmelding.Status)
+	if assert.NotNil(t, aanmelding.BehandeldDoor) {
+		assert.Equal(t, "jan@dekoninklijkeloop.nl", *aanmelding.BehandeldDoor)
+	}
+	assert.NotNil(t, aanmelding.BehandeldOp)
+
+	_, err = f.click("done:c:contact-1")
+	assert.NoError(t, err)
+	contact, _ := f.contacts.GetByID(ctx, "contact-1")
+	assert.Equal(t, "gesloten", contact.Status)
+	if assert.NotNil(t, contact.BehandeldDoor) {
+		assert.Equal(t, "jan@dekoninklijkeloop.nl", *contact.BehandeldDoor)
+	}
+
+	// De chat krijgt een bevestiging met de naam van de medewerker
+	messages := f.api.methodCalls("sendMessage")
+	assert.Len(t, messages, 2)
+	assert.Contains(t, messages[1].Params["text"], "door Jan")
+
+	_, err = f.click("onbekend:c:contact-1")
+	assert.Error(t, err)
+}
+
+func TestTelegramConfirmationEscapesNames(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	ctx := context.Background()
+
+	gebruiker, _ := f.gebruikers.GetByID(ctx, f.gebruikerID)
+	gebruiker.Naam = "Jan <i>"
+	assert.NoError(t, f.gebruikers.Update(ctx, gebruiker))
+	contact, _ := f.contacts.GetByID(ctx, "contact-1")
+	contact.Naam = "Piet & <b>Co</b>"
+	assert.NoError(t, f.contacts.Update(ctx, contact))
+	f.link(t)
+
+	text, err := f.click("done:c:contact-1")
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Piet & <b>Co</b>")
+
+	// De chat gebruikt parse_mode HTML; namen mogen de opmaak niet breken
+	messages := f.api.methodCalls("sendMessage")
+	if assert.Len(t, messages, 1) {
+		assert.Contains(t, messages[0].Params["text"], "Piet &amp; &lt;b&gt;Co&lt;/b&gt;")
+		assert.Contains(t, messages[0].Params["text"], "door Jan &lt;i&gt;")
+	}
+}
+
+func TestTelegramQuickReply(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	f.link(t)
+	ctx := context.Background()
+
+	reply := &models.EmailTemplate{
+		Naam:      services.TelegramQuickReplyPrefix + "ontvangen",
+		Onderwerp: "We hebben je bericht ontvangen",
+		Inhoud:    "Beste {{.Naam}}, we komen zo snel mogelijk bij je terug.",
+		IsActief:  true,
+	}
+	assert.NoError(t, f.templates.Create(ctx, reply))
+
+	query := &services.TelegramCallbackQuery{ID: "q1", Data: "reply:c:contact-1"}
+	query.From.ID = f.telegramID
+	query.Message = &struct {
+		MessageID int `json:"message_id"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	}{MessageID: 7}
+	_, err := f.bot.HandleCallbackQuery(ctx, query)
+	assert.NoError(t, err)
+
+	// De knoppen onder het bericht worden vervangen door de snelle antwoorden
+	edits := f.api.methodCalls("editMessageReplyMarkup")
+	if assert.Len(t, edits, 1) {
+		assert.Contains(t, edits[0].Params["reply_markup"], "ans:c:contact-1:"+reply.ID[:8])
+		assert.Contains(t, edits[0].Params["reply_markup"], "back:c:contact-1")
+	}
+
+	_, err = f.click("ans:c:contact-1:" + reply.ID[:8])
+	assert.NoError(t, err)
+
+	if assert.Len(t, f.sender.sent, 1) {
+		assert.Equal(t, "piet@example.com|We hebben je bericht ontvangen|Beste Piet, we komen zo snel mogelijk bij je terug.", f.sender.sent[0])
+		assert.Equal(t, "contact_antwoord", f.sender.metas[0].Type)
+	}
+
+	contact, _ := f.contacts.GetByID(ctx, "contact-1")
+	assert.Equal(t, "beantwoord", contact.Status)
+	assert.True(t, contact.Beantwoord)
+	assert.Equal(t, "jan@dekoninklijkeloop.nl", contact.AntwoordDoor)
+
+	antwoorden, _ := f.antwoorden.ListByContactID(ctx, "contact-1")
+	if assert.Len(t, antwoorden, 1) {
+		assert.True(t, antwoorden[0].EmailVerzonden)
+		assert.Equal(t, "jan@dekoninklijkeloop.nl", antwoorden[0].VerzondDoor)
+	}
+}
@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"dklautomationgo/tests/mocks"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTelegramChatRepository is een in-memory TelegramChatRepository voor tests
+type fakeTelegramChatRepository struct {
+	mu    sync.Mutex
+	chats map[string]*models.TelegramChat
+}
+
+func newFakeTelegramChatRepository() *fakeTelegramChatRepository {
+	return &fakeTelegramChatRepository{chats: make(map[string]*models.TelegramChat)}
+}
+
+func (r *fakeTelegramChatRepository) Create(ctx context.Context, chat *models.TelegramChat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chat.ID = fmt.Sprintf("chat-%d", chat.ChatID)
+	copied := *chat
+	r.chats[chat.ID] = &copied
+	return nil
+}
+
+func (r *fakeTelegramChatRepository) GetByID(ctx context.Context, id string) (*models.TelegramChat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if chat, ok := r.chats[id]; ok {
+		copied := *chat
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeTelegramChatRepository) GetByChatID(ctx context.Context, chatID int64) (*models.TelegramChat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, chat := range r.chats {
+		if chat.ChatID == chatID {
+			copied := *chat
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTelegramChatRepository) List(ctx context.Context) ([]*models.TelegramChat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*models.TelegramChat
+	for _, chat := range r.chats {
+		copied := *chat
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Naam < result[j].Naam })
+	return result, nil
+}
+
+func (r *fakeTelegramChatRepository) Update(ctx context.Context, chat *models.TelegramChat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *chat
+	r.chats[chat.ID] = &copied
+	return nil
+}
+
+func (r *fakeTelegramChatRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.chats, id)
+	return nil
+}
+
+// recordingChatClient onthoudt naar welke chats een bericht is verstuurd
+type recordingChatClient struct {
+	mu      sync.Mutex
+	chats   []string
+	failFor string
+}
+
+func (c *recordingChatClient) SendMessage(title, message string) error {
+	return c.SendMessageToChat("default", title, message, nil)
+}
+
+func (c *recordingChatClient) SendMessageWithKeyboard(title, message string, keyboard services.TelegramInlineKeyboard) error {
+	return c.SendMessageToChat("default", title, message, keyboard)
+}
+
+func (c *recordingChatClient) SendMessageToChat(chatID, title, message string, keyboard services.TelegramInlineKeyboard) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if chatID == c.failFor {
+		return errors.New("chat niet bereikbaar")
+	}
+	c.chats = append(c.chats, chatID)
+	return nil
+}
+
+func TestTelegramChannelDeliversToSubscribedChats(t *testing.T) {
+	ctx := context.Background()
+	chatRepo := newFakeTelegramChatRepository()
+	client := &recordingChatClient{}
+	channel := services.NewTelegramChannel(client)
+	channel.SetChatRepository(chatRepo)
+
+	// Zonder chats in het register gaat alles naar de standaard chat
+	assert.NoError(t, channel.Deliver(ctx, &models.Notification{Type: models.NotificationTypeHealth, Title: "Health"}))
+	assert.Equal(t, []string{"default"}, client.chats)
+
+	assert.NoError(t, chatRepo.Create(ctx, &models.TelegramChat{ChatID: -1, Naam: "Bestuur", Subscriptions: models.TelegramChatList{"*"}, IsActief: true}))
+	assert.NoError(t, chatRepo.Create(ctx, &models.TelegramChat{ChatID: -2, Naam: "Vrijwilligers", Subscriptions: models.TelegramChatList{"aanmelding"}, IsActief: true}))
+	assert.NoError(t, chatRepo.Create(ctx, &models.TelegramChat{ChatID: -3, Naam: "Techniek", Subscriptions: models.TelegramChatList{"health"}, IsActief: true}))
+	assert.NoError(t, chatRepo.Create(ctx, &models.TelegramChat{ChatID: -4, Naam: "Oud", Subscriptions: models.TelegramChatList{"*"}, IsActief: false}))
+
+	client.chats = nil
+	assert.NoError(t, channel.Deliver(ctx, &models.Notification{Type: models.NotificationTypeAanmelding, Title: "Nieuwe aanmelding"}))
+	assert.ElementsMatch(t, []string{"-1", "-2"}, client.chats)
+
+	client.chats = nil
+	assert.NoError(t, channel.Deliver(ctx, &models.Notification{Type: models.NotificationTypeHealth, Title: "Health"}))
+	assert.ElementsMatch(t, []string{"-1", "-3"}, client.chats)
+
+	// Eén chat die faalt laat de aflevering niet mislukken als een andere chat hem wel kreeg
+	client.chats = nil
+	client.failFor = "-3"
+	assert.NoError(t, channel.Deliver(ctx, &models.Notification{Type: models.NotificationTypeHealth, Title: "Health"}))
+	assert.Equal(t, []string{"-1"}, client.chats)
+
+	// Als geen enkele chat hem kreeg, probeert de router het later opnieuw
+	assert.NoError(t, chatRepo.Delete(ctx, "chat--1"))
+	assert.Error(t, channel.Deliver(ctx, &models.Notification{Type: models.NotificationTypeHealth, Title: "Health"}))
+}
+
+func TestTelegramWebhook(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	chatRepo := newFakeTelegramChatRepository()
+	f.bot.SetChatRepository(chatRepo)
+	assert.NoError(t, f.bot.EnsureDefaultChat(context.Background()))
+
+	update := func(id int, chatID int64, text string) []byte {
+		data, _ := json.Marshal(map[string]interface{}{
+			"update_id": id,
+			"message": map[string]interface{}{
+				"message_id": id,
+				"from":       map[string]interface{}{"id": f.telegramID},
+				"chat":       map[string]interface{}{"id": chatID},
+				"text":       text,
+			},
+		})
+		return data
+	}
+
+	// Zonder ingestelde webhook wordt niets geaccepteerd
+	assert.ErrorIs(t, f.bot.HandleWebhook("", update(1, -100, "/help")), services.ErrInvalidWebhookSecret)
+
+	assert.NoError(t, f.bot.ConfigureWebhook("https://example.com/api/v1/telegrambot/webhook", "geheim_123"))
+	calls := f.api.methodCalls("setWebhook")
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "geheim_123", calls[0].Params["secret_token"])
+		assert.Contains(t, calls[0].Params["allowed_updates"], "callback_query")
+	}
+
+	assert.ErrorIs(t, f.bot.HandleWebhook("fout", update(1, -100, "/help")), services.ErrInvalidWebhookSecret)
+	assert.Empty(t, f.api.methodCalls("sendMessage"))
+
+	assert.NoError(t, f.bot.HandleWebhook("geheim_123", update(1, -100, "/help@dkl_bot")))
+	messages := f.api.methodCalls("sendMessage")
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "-100", messages[0].Params["chat_id"])
+		assert.Contains(t, messages[0].Params["text"], "/subscribe")
+	}
+
+	// Een update die Telegram opnieuw aflevert wordt overgeslagen
+	assert.NoError(t, f.bot.HandleWebhook("geheim_123", update(1, -100, "/help")))
+	assert.Len(t, f.api.methodCalls("sendMessage"), 1)
+}
+
+func TestTelegramCommandsPerChat(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	chatRepo := newFakeTelegramChatRepository()
+	f.bot.SetChatRepository(chatRepo)
+	assert.NoError(t, f.bot.ConfigureWebhook("https://example.com/webhook", "geheim"))
+	ctx := context.Background()
+
+	assert.NoError(t, chatRepo.Create(ctx, &models.TelegramChat{ChatID: -200, Naam: "Vrijwilligers", AllowedCommands: models.TelegramChatList{"aanmelding", "subscribe"}, IsActief: true}))
+
+	send := func(id int, chatID int64, text string) []telegramAPICall {
+		before := len(f.api.methodCalls("sendMessage"))
+		body, _ := json.Marshal(map[string]interface{}{
+			"update_id": id,
+			"message": map[string]interface{}{
+				"from": map[string]interface{}{"id": f.telegramID},
+				"chat": map[string]interface{}{"id": chatID},
+				"text": text,
+			},
+		})
+		assert.NoError(t, f.bot.HandleWebhook("geheim", body))
+		return f.api.methodCalls("sendMessage")[before:]
+	}
+
+	// Een onbekende chat kan alleen zijn ID opvragen
+	replies := send(1, -999, "/start")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0].Params["text"], "-999")
+	}
+	assert.Empty(t, send(2, -999, "/status"))
+
+	// Een geregistreerde chat mag alleen de toegestane commando's gebruiken
+	replies = send(3, -200, "/status")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0].Params["text"], "niet beschikbaar")
+		assert.Equal(t, "-200", replies[0].Params["chat_id"])
+	}
+
+	// Abonnementen wijzigen vraagt een gekoppeld account
+	replies = send(4, -200, "/subscribe aanmelding")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0].Params["text"], "/koppel")
+	}
+
+	f.link(t)
+	send(5, -200, "/subscribe aanmelding")
+	chat, _ := chatRepo.GetByChatID(ctx, -200)
+	assert.Equal(t, models.TelegramChatList{"aanmelding"}, chat.Subscriptions)
+
+	replies = send(6, -200, "/subscribe onzin")
+	if assert.Len(t, replies, 1) {
+		assert.Contains(t, replies[0].Params["text"], "Onbekend type")
+	}
+}
+
+func TestTelegramUnsubscribeFromAll(t *testing.T) {
+	f := newTelegramActionsFixture(t, mocks.NewMockPermissionService())
+	chatRepo := newFakeTelegramChatRepository()
+	f.bot.SetChatRepository(chatRepo)
+	assert.NoError(t, f.bot.ConfigureWebhook("https://example.com/webhook", "geheim"))
+	assert.NoError(t, f.bot.EnsureDefaultChat(context.Background()))
+	f.link(t)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"update_id": 1,
+		"message": map[string]interface{}{
+			"from": map[string]interface{}{"id": f.telegramID},
+			"chat": map[string]interface{}{"id": -100},
+			"text": "/unsubscribe health",
+		},
+	})
+	assert.NoError(t, f.bot.HandleWebhook("geheim", body))
+
+	chat, _ := chatRepo.GetByChatID(context.Background(), -100)
+	assert.False(t, chat.IsSubscribed(models.NotificationTypeHealth))
+	assert.True(t, chat.IsSubscribed(models.NotificationTypeAanmelding))
+	assert.True(t, chat.AllowsCommand("/status"))
+}
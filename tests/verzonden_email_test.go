@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"context"
+	"dklautomationgo/models"
+	"dklautomationgo/services"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeVerzondEmailRepository legt verzonden emails in het geheugen vast
+type fakeVerzondEmailRepository struct {
+	mu     sync.Mutex
+	emails []*models.VerzondEmail
+}
+
+func (r *fakeVerzondEmailRepository) Create(ctx context.Context, email *models.VerzondEmail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	email.ID = "mail-" + email.Ontvanger
+	r.emails = append(r.emails, email)
+	return nil
+}
+
+func (r *fakeVerzondEmailRepository) GetByID(ctx context.Context, id string) (*models.VerzondEmail, error) {
+	return nil, nil
+}
+
+func (r *fakeVerzondEmailRepository) List(ctx context.Context, limit, offset int) ([]*models.VerzondEmail, error) {
+	return r.emails, nil
+}
+
+func (r *fakeVerzondEmailRepository) Update(ctx context.Context, email *models.VerzondEmail) error {
+	return nil
+}
+
+func (r *fakeVerzondEmailRepository) FindByContactID(ctx context.Context, contactID string) ([]*models.VerzondEmail, error) {
+	return nil, nil
+}
+
+func (r *fakeVerzondEmailRepository) FindByAanmeldingID(ctx context.Context, aanmeldingID string) ([]*models.VerzondEmail, error) {
+	return nil, nil
+}
+
+func (r *fakeVerzondEmailRepository) FindByOntvanger(ctx context.Context, ontvanger string) ([]*models.VerzondEmail, error) {
+	return nil, nil
+}
+
+func (r *fakeVerzondEmailRepository) ListFiltered(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]*models.VerzondEmail, int64, error) {
+	return r.emails, int64(len(r.emails)), nil
+}
+
+func (r *fakeVerzondEmailRepository) UpdateStatus(ctx context.Context, id, status, foutBericht string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, email := range r.emails {
+		if email.ID == id {
+			email.Status = status
+			email.FoutBericht = foutBericht
+		}
+	}
+	return nil
+}
+
+func (r *fakeVerzondEmailRepository) FindByThreadID(ctx context.Context, threadID string) ([]*models.VerzondEmail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var emails []*models.VerzondEmail
+	for _, email := range r.emails {
+		if email.ThreadID == threadID {
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+func (r *fakeVerzondEmailRepository) FindByMessageIDs(ctx context.Context, messageIDs []string) ([]*models.VerzondEmail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var emails []*models.VerzondEmail
+	for _, email := range r.emails {
+		for _, id := range messageIDs {
+			if email.MessageID == id {
+				emails = append(emails, email)
+			}
+		}
+	}
+	return emails, nil
+}
+
+func TestEmailService_RecordsVerzondenEmails(t *testing.T) {
+	t.Run("Directe verzending met koppeling naar aanmelding", func(t *testing.T) {
+		smtp := &mockSMTP{}
+		smtp.On("Send", mock.Anything).Return(nil)
+		repo := &fakeVerzondEmailRepository{}
+
+		emailService, err := services.NewTestEmailService(smtp)
+		assert.NoError(t, err)
+		emailService.SetSentEmailRepository(repo)
+
+		aanmeldingID := "aanmelding-1"
+		err = emailService.SendEmailWithMetadata("deelnemer@example.com", "Antwoord", "<p>Hallo</p>",
+			services.EmailMetadata{Type: "aanmelding_antwoord", AanmeldingID: &aanmeldingID})
+		assert.NoError(t, err)
+
+		if assert.Len(t, repo.emails, 1) {
+			record := repo.emails[0]
+			assert.Equal(t, models.VerzondEmailStatusVerzonden, record.Status)
+			assert.Equal(t, "aanmelding_antwoord", record.EmailType)
+			assert.Equal(t, &aanmeldingID, record.AanmeldingID)
+			assert.Empty(t, record.FoutBericht)
+		}
+	})
+
+	t.Run("Mislukte verzending wordt met foutmelding vastgelegd", func(t *testing.T) {
+		smtp := &mockSMTP{}
+		smtp.On("Send", mock.Anything).Return(errors.New("smtp down"))
+		repo := &fakeVerzondEmailRepository{}
+
+		emailService, err := services.NewTestEmailService(smtp)
+		assert.NoError(t, err)
+		emailService.SetSentEmailRepository(repo)
+
+		err = emailService.SendContactEmail(&models.ContactEmailData{
+			ToAdmin:    true,
+			AdminEmail: "admin@example.com",
+			Contact:    &models.ContactFormulier{ID: "contact-1", Naam: "Test", Email: "test@example.com"},
+		})
+		assert.Error(t, err)
+
+		if assert.Len(t, repo.emails, 1) {
+			record := repo.emails[0]
+			assert.Equal(t, models.VerzondEmailStatusMislukt, record.Status)
+			assert.Equal(t, "smtp down", record.FoutBericht)
+			assert.Equal(t, "contact_admin_email", record.TemplateNaam)
+			if assert.NotNil(t, record.ContactID) {
+				assert.Equal(t, "contact-1", *record.ContactID)
+			}
+		}
+	})
+}